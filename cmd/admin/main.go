@@ -1,15 +1,17 @@
 package main
 
 import (
-	"crypto/rand"
-	"crypto/sha256"
-	"encoding/hex"
+	"bufio"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"strings"
 	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/auth"
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
 )
 
 // Token represents an authentication token
@@ -21,11 +23,14 @@ type Token struct {
 	CreatedAt   time.Time `json:"created_at"`
 	ExpiresAt   time.Time `json:"expires_at"`
 	Revoked     bool      `json:"revoked"`
+
+	PathPrefixes []string `json:"path_prefixes,omitempty"`
 }
 
-// TokenStore holds all tokens
+// TokenStore holds all tokens and password users
 type TokenStore struct {
-	Tokens []Token `json:"tokens"`
+	Tokens []Token     `json:"tokens"`
+	Users  []auth.User `json:"users,omitempty"`
 }
 
 func main() {
@@ -50,6 +55,12 @@ func main() {
 		listCommand()
 	case "revoke":
 		revokeCommand()
+	case "add-user":
+		addUserCommand()
+	case "disable-user":
+		disableUserCommand()
+	case "migrate":
+		migrateCommand()
 	case "help":
 		printUsage()
 	default:
@@ -66,22 +77,34 @@ USAGE:
   goflux-lite-admin <command> [options]
 
 COMMANDS:
-  create -user <name> [-permissions <perms>] [-days <days>] [-file <tokens.json>]
+  create -user <name> [-permissions <perms>] [-days <days>] [-paths <prefixes>] [-file <tokens.json>]
   list [-file <tokens.json>]
   revoke <token_id> [-file <tokens.json>]
+  add-user -user <name> [-permissions <perms>] [-file <tokens.json>]
+  disable-user <name> [-file <tokens.json>]
+  migrate -from <storage> -to <storage>
   help
 
 OPTIONS:
-  -user string         Username for the token (required for create)
+  -user string         Username for the token or user (required for create
+                       and add-user)
   -permissions string  Permissions (comma-separated or * for all, default: *)
   -days int           Token validity in days (default: 30)
+  -paths string       Path prefixes the token is limited to (comma-separated,
+                      default: all paths)
   -file string        Token file path (default: tokens.json)
+  -from string        Source storage URI (e.g. local://data)
+  -to string          Destination storage URI (e.g. local://backup)
 
 EXAMPLES:
   goflux-lite-admin create -user alice -permissions * -days 365
   goflux-lite-admin create -user bob -permissions upload,download -days 90
+  goflux-lite-admin create -user dave -permissions download -paths docs,projects/alpha
   goflux-lite-admin list
   goflux-lite-admin revoke tok_abc123
+  goflux-lite-admin add-user -user carol -permissions upload,download,list
+  goflux-lite-admin disable-user carol
+  goflux-lite-admin migrate -from local://data -to local://new-data
 
 `)
 }
@@ -91,6 +114,7 @@ func createCommand() {
 	user := fs.String("user", "", "username for the token (required)")
 	permissions := fs.String("permissions", "*", "permissions (comma-separated or * for all)")
 	days := fs.Int("days", 30, "token validity in days")
+	paths := fs.String("paths", "", "path prefixes the token is limited to (comma-separated)")
 	file := fs.String("file", "tokens.json", "token file path")
 	fs.Parse(os.Args[2:])
 
@@ -100,8 +124,16 @@ func createCommand() {
 		os.Exit(1)
 	}
 
-	// Load or create token store
-	store := loadOrCreateTokenStore(*file)
+	if *days <= 0 {
+		fmt.Println("Error: -days must be positive")
+		os.Exit(1)
+	}
+
+	store, err := auth.NewTokenStore(*file)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Parse permissions
 	var perms []string
@@ -111,38 +143,28 @@ func createCommand() {
 		perms = strings.Split(*permissions, ",")
 	}
 
-	// Generate token
-	tokenBytes := make([]byte, 32)
-	rand.Read(tokenBytes)
-	token := hex.EncodeToString(tokenBytes)
-	tokenHash := fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
-
-	// Generate ID
-	idBytes := make([]byte, 6)
-	rand.Read(idBytes)
-	id := fmt.Sprintf("tok_%x", idBytes)
-
-	// Create token
-	newToken := Token{
-		ID:          id,
-		TokenHash:   tokenHash,
-		User:        *user,
-		Permissions: perms,
-		CreatedAt:   time.Now(),
-		ExpiresAt:   time.Now().AddDate(0, 0, *days),
-		Revoked:     false,
+	var prefixes []string
+	for _, prefix := range strings.Split(*paths, ",") {
+		if prefix = strings.Trim(strings.TrimSpace(prefix), "/"); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
 	}
 
-	// Add to store and save
-	store.Tokens = append(store.Tokens, newToken)
-	saveTokenStore(store, *file)
+	token, newToken, err := store.Create(*user, perms, time.Duration(*days)*24*time.Hour, prefixes...)
+	if err != nil {
+		fmt.Printf("Error creating token: %v\n", err)
+		os.Exit(1)
+	}
 
 	fmt.Println("Token created successfully!")
 	fmt.Println()
-	fmt.Printf("Token ID:     %s\n", id)
+	fmt.Printf("Token ID:     %s\n", newToken.ID)
 	fmt.Printf("Token:        %s\n", token)
 	fmt.Printf("User:         %s\n", *user)
 	fmt.Printf("Permissions:  %v\n", perms)
+	if len(prefixes) > 0 {
+		fmt.Printf("Paths:        %v\n", prefixes)
+	}
 	fmt.Printf("Expires:      %s\n", newToken.ExpiresAt.Format("2006-01-02 15:04:05"))
 	fmt.Println()
 	fmt.Println("⚠️  Save this token! It won't be shown again.")
@@ -155,7 +177,7 @@ func listCommand() {
 
 	store := loadOrCreateTokenStore(*file)
 
-	if len(store.Tokens) == 0 {
+	if len(store.Tokens) == 0 && len(store.Users) == 0 {
 		fmt.Println("No tokens found.")
 		return
 	}
@@ -175,6 +197,9 @@ func listCommand() {
 		}
 
 		permsStr := strings.Join(token.Permissions, ",")
+		if len(token.PathPrefixes) > 0 {
+			permsStr += " @" + strings.Join(token.PathPrefixes, ",")
+		}
 		if len(permsStr) > 28 {
 			permsStr = permsStr[:25] + "..."
 		}
@@ -186,6 +211,27 @@ func listCommand() {
 			status,
 			token.ExpiresAt.Format("2006-01-02 15:04"))
 	}
+
+	if len(store.Users) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%-16s %-30s %-10s %-20s\n", "User", "Permissions", "Status", "Created")
+	fmt.Println(strings.Repeat("─", 80))
+	for _, user := range store.Users {
+		status := "active"
+		if user.Disabled {
+			status = "disabled"
+		}
+
+		permsStr := strings.Join(user.Permissions, ",")
+		if len(permsStr) > 28 {
+			permsStr = permsStr[:25] + "..."
+		}
+
+		fmt.Printf("%-16s %-30s %-10s %-20s\n", user.Name, permsStr, status, user.CreatedAt.Format("2006-01-02 15:04"))
+	}
 }
 
 func revokeCommand() {
@@ -203,25 +249,156 @@ func revokeCommand() {
 		fs.Parse(os.Args[3:])
 	}
 
+	store, err := auth.NewTokenStore(*file)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := store.Revoke(tokenID); err != nil {
+		if errType, ok := errors.GetAuthErrorType(err); ok && errType == errors.AuthErrorInvalidToken {
+			fmt.Printf("Token not found: %s\n", tokenID)
+		} else {
+			fmt.Printf("Error revoking token: %v\n", err)
+		}
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Token %s has been revoked.\n", tokenID)
+}
+
+func addUserCommand() {
+	fs := flag.NewFlagSet("add-user", flag.ExitOnError)
+	user := fs.String("user", "", "username (required)")
+	permissions := fs.String("permissions", "*", "permissions (comma-separated or * for all)")
+	file := fs.String("file", "tokens.json", "token file path")
+	fs.Parse(os.Args[2:])
+
+	if *user == "" {
+		fmt.Println("Error: -user is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	store := loadOrCreateTokenStore(*file)
+	for _, existing := range store.Users {
+		if existing.Name == *user {
+			fmt.Printf("User already exists: %s\n", *user)
+			os.Exit(1)
+		}
+	}
+
+	// The password is read from standard input so it stays out of shell history
+	fmt.Print("Password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	password = strings.TrimRight(password, "\r\n")
+	if err != nil && password == "" {
+		fmt.Printf("\nError reading password: %v\n", err)
+		os.Exit(1)
+	}
+	if password == "" {
+		fmt.Println("Error: password must not be empty")
+		os.Exit(1)
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		fmt.Printf("Error hashing password: %v\n", err)
+		os.Exit(1)
+	}
+
+	var perms []string
+	if *permissions == "*" {
+		perms = []string{"*"}
+	} else {
+		perms = strings.Split(*permissions, ",")
+	}
+
+	store.Users = append(store.Users, auth.User{
+		Name:         *user,
+		PasswordHash: hash,
+		Permissions:  perms,
+		CreatedAt:    time.Now(),
+	})
+	saveTokenStore(store, *file)
+
+	fmt.Printf("✓ User %s added with permissions %v\n", *user, perms)
+	fmt.Println("  Sign in with POST /auth/login to get a token valid for one hour.")
+}
+
+func disableUserCommand() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: goflux-lite-admin disable-user <name> [-file <tokens.json>]")
+		os.Exit(1)
+	}
+
+	name := os.Args[2]
+	fs := flag.NewFlagSet("disable-user", flag.ExitOnError)
+	file := fs.String("file", "tokens.json", "token file path")
+	if len(os.Args) > 3 {
+		fs.Parse(os.Args[3:])
+	}
+
 	store := loadOrCreateTokenStore(*file)
 
-	// Find and revoke token
 	found := false
-	for i := range store.Tokens {
-		if store.Tokens[i].ID == tokenID {
-			store.Tokens[i].Revoked = true
+	for i := range store.Users {
+		if store.Users[i].Name == name {
+			store.Users[i].Disabled = true
 			found = true
 			break
 		}
 	}
 
 	if !found {
-		fmt.Printf("Token not found: %s\n", tokenID)
+		fmt.Printf("User not found: %s\n", name)
 		os.Exit(1)
 	}
 
 	saveTokenStore(store, *file)
-	fmt.Printf("✓ Token %s has been revoked.\n", tokenID)
+	fmt.Printf("✓ User %s has been disabled.\n", name)
+}
+
+func migrateCommand() {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	from := fs.String("from", "", "source storage URI (required)")
+	to := fs.String("to", "", "destination storage URI (required)")
+	fs.Parse(os.Args[2:])
+
+	if *from == "" || *to == "" {
+		fmt.Println("Error: -from and -to are required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	src, err := storage.Open(*from)
+	if err != nil {
+		fmt.Printf("Error opening source storage: %v\n", err)
+		os.Exit(1)
+	}
+
+	dst, err := storage.Open(*to)
+	if err != nil {
+		fmt.Printf("Error opening destination storage: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Migrating %s → %s\n", *from, *to)
+
+	stats, err := storage.Migrate(src, dst, func(path string, skipped bool) {
+		if skipped {
+			fmt.Printf("  = %s (already migrated)\n", path)
+		} else {
+			fmt.Printf("  + %s\n", path)
+		}
+	})
+	if err != nil {
+		fmt.Printf("Error: migration failed: %v\n", err)
+		fmt.Println("Run the same command again to resume.")
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Migration complete: %d copied (%d bytes), %d already present\n", stats.Copied, stats.Bytes, stats.Skipped)
 }
 
 func loadOrCreateTokenStore(filename string) *TokenStore {
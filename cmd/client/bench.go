@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/chunk"
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// benchOptions controls the synthetic transfer benchmark
+type benchOptions struct {
+	Size      int // total bytes to transfer
+	ChunkSize int // bytes per uploaded chunk
+	Parallel  int // concurrent chunk uploads
+}
+
+// benchResult holds the measurements from a benchmark run
+type benchResult struct {
+	Bytes          int
+	Chunks         int
+	UploadTime     time.Duration
+	DownloadTime   time.Duration
+	ChunkLatencies []time.Duration // per-chunk upload latency, sorted ascending
+	RemotePath     string
+}
+
+// UploadThroughput returns the upload rate in bytes per second
+func (r *benchResult) UploadThroughput() float64 {
+	if r.UploadTime <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / r.UploadTime.Seconds()
+}
+
+// DownloadThroughput returns the download rate in bytes per second
+func (r *benchResult) DownloadThroughput() float64 {
+	if r.DownloadTime <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / r.DownloadTime.Seconds()
+}
+
+// latencyPercentile returns the chunk latency at percentile p (0-100)
+func (r *benchResult) latencyPercentile(p int) time.Duration {
+	if len(r.ChunkLatencies) == 0 {
+		return 0
+	}
+	idx := (len(r.ChunkLatencies) - 1) * p / 100
+	return r.ChunkLatencies[idx]
+}
+
+// benchChunk deterministically generates the synthetic payload for a chunk
+func benchChunk(id, size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(id) + 1)).Read(data)
+	return data
+}
+
+// runBench uploads synthetic data to a temporary remote path through the
+// streaming upload endpoint, streams it back, verifies it and removes the
+// remote file. The remote file is cleaned up even if the benchmark fails part
+// way through.
+func runBench(client *transport.HTTPClient, opts benchOptions) (result *benchResult, err error) {
+	if opts.Size <= 0 {
+		return nil, fmt.Errorf("size must be positive")
+	}
+	if opts.ChunkSize <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive")
+	}
+	if opts.Parallel <= 0 {
+		opts.Parallel = 1
+	}
+
+	totalChunks := (opts.Size + opts.ChunkSize - 1) / opts.ChunkSize
+	chunkLen := func(id int) int {
+		if id == totalChunks-1 {
+			return opts.Size - id*opts.ChunkSize
+		}
+		return opts.ChunkSize
+	}
+
+	remotePath := fmt.Sprintf(".gfl-bench-%d.dat", time.Now().UnixNano())
+	result = &benchResult{
+		Bytes:      opts.Size,
+		Chunks:     totalChunks,
+		RemotePath: remotePath,
+	}
+
+	defer func() {
+		if delErr := client.Delete(remotePath); delErr != nil && err == nil {
+			err = fmt.Errorf("cleanup failed: %w", delErr)
+		}
+	}()
+
+	// Upload chunks using a fixed pool of workers
+	ids := make(chan int)
+	latencies := make([]time.Duration, totalChunks)
+	var uploadErr error
+	var errOnce sync.Once
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for w := 0; w < opts.Parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				chunkStart := time.Now()
+				data := benchChunk(id, chunkLen(id))
+				err := client.UploadChunkStream(transport.ChunkData{
+					Path:     remotePath,
+					ChunkID:  id,
+					Data:     data,
+					Checksum: chunk.Checksum(data),
+					Total:    totalChunks,
+				})
+				if err != nil {
+					errOnce.Do(func() { uploadErr = err })
+					continue
+				}
+				latencies[id] = time.Since(chunkStart)
+			}
+		}()
+	}
+	for id := 0; id < totalChunks; id++ {
+		ids <- id
+	}
+	close(ids)
+	wg.Wait()
+	result.UploadTime = time.Since(start)
+
+	if uploadErr != nil {
+		return nil, fmt.Errorf("upload failed: %w", uploadErr)
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.ChunkLatencies = latencies
+
+	// Download and verify, comparing the data chunk by chunk as it arrives.
+	// Only the time spent receiving counts towards the download time.
+	start = time.Now()
+	body, err := client.OpenDownload(context.Background(), remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	defer body.Close()
+	result.DownloadTime = time.Since(start)
+
+	buf := make([]byte, opts.ChunkSize)
+	for id := 0; id < totalChunks; id++ {
+		start = time.Now()
+		_, err := io.ReadFull(body, buf[:chunkLen(id)])
+		result.DownloadTime += time.Since(start)
+		if err != nil {
+			return nil, fmt.Errorf("download failed in chunk %d: %w", id, err)
+		}
+		if !bytes.Equal(buf[:chunkLen(id)], benchChunk(id, chunkLen(id))) {
+			return nil, fmt.Errorf("content mismatch in chunk %d", id)
+		}
+	}
+	if n, _ := io.Copy(io.Discard, body); n > 0 {
+		return nil, fmt.Errorf("size mismatch: uploaded %d bytes, downloaded %d", opts.Size, int64(opts.Size)+n)
+	}
+
+	return result, nil
+}
+
+// formatBenchResult renders a benchmark result for the terminal
+func formatBenchResult(r *benchResult, opts benchOptions) string {
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("Transferred %s in %d chunks of %s (parallel: %d)\n",
+		formatBytes(r.Bytes), r.Chunks, formatBytes(opts.ChunkSize), opts.Parallel))
+	out.WriteString(fmt.Sprintf("  Upload:   %-10s %s\n", r.UploadTime.Round(time.Millisecond), formatSpeed(r.UploadThroughput())))
+	out.WriteString(fmt.Sprintf("  Download: %-10s %s\n", r.DownloadTime.Round(time.Millisecond), formatSpeed(r.DownloadThroughput())))
+	out.WriteString(fmt.Sprintf("  Chunk latency: min %s, p50 %s, p95 %s, max %s\n",
+		r.latencyPercentile(0).Round(time.Microsecond),
+		r.latencyPercentile(50).Round(time.Microsecond),
+		r.latencyPercentile(95).Round(time.Microsecond),
+		r.latencyPercentile(100).Round(time.Microsecond)))
+	return out.String()
+}
+
+// parseSize parses a human-readable size such as "100MB", "512KB" or "4096"
+func parseSize(s string) (int, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	multipliers := []struct {
+		suffix string
+		factor int
+	}{
+		{"GB", 1024 * 1024 * 1024},
+		{"MB", 1024 * 1024},
+		{"KB", 1024},
+		{"G", 1024 * 1024 * 1024},
+		{"M", 1024 * 1024},
+		{"K", 1024},
+		{"B", 1},
+	}
+
+	factor := 1
+	for _, m := range multipliers {
+		if strings.HasSuffix(str, m.suffix) {
+			factor = m.factor
+			str = strings.TrimSpace(strings.TrimSuffix(str, m.suffix))
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid size: %q", s)
+	}
+	return int(value * float64(factor)), nil
+}
+
+func doBench(client *transport.HTTPClient, args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	size := fs.String("size", "100MB", "total amount of data to transfer")
+	chunkSize := fs.String("chunk", "1MB", "chunk size")
+	parallel := fs.Int("parallel", 1, "number of concurrent chunk uploads")
+	fs.Parse(args)
+
+	opts := benchOptions{Parallel: *parallel}
+	var err error
+	if opts.Size, err = parseSize(*size); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if opts.ChunkSize, err = parseSize(*chunkSize); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Benchmarking %s against %s...\n", formatBytes(opts.Size), client.BaseURL)
+
+	result, err := runBench(client, opts)
+	if err != nil {
+		log.Fatalf("Benchmark failed: %v", err)
+	}
+
+	fmt.Print(formatBenchResult(result, opts))
+}
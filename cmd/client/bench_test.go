@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/chunk"
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// stubServer is a minimal in-memory goflux server for client tests
+type stubServer struct {
+	mu      sync.Mutex
+	chunks  map[string]map[int][]byte
+	totals  map[string]int    // chunk count of each upload in chunks
+	hashes  map[string]string // file hash of each upload in chunks
+	files   map[string][]byte
+	deleted []string
+	dirs    []string // directories created with mkdir
+	corrupt bool     // serve downloads with the first byte altered
+
+	accepted  []int    // chunk IDs accepted, in order
+	finalized []string // uploads finalized with /upload/finalize
+	aborted   []string // uploads discarded with /upload/abort
+	streamed  int      // chunks sent to /upload/stream
+	failAfter int      // reject chunks once this many have been accepted (0 = never)
+
+	stalls map[int]time.Duration // delay before dropping the first upload of these chunk IDs
+}
+
+func newStubServer(t *testing.T) (*stubServer, *httptest.Server) {
+	t.Helper()
+
+	stub := &stubServer{
+		chunks: make(map[string]map[int][]byte),
+		totals: make(map[string]int),
+		hashes: make(map[string]string),
+		files:  make(map[string][]byte),
+	}
+
+	mux := http.NewServeMux()
+	accept := func(w http.ResponseWriter, chunk transport.ChunkData) {
+		stub.mu.Lock()
+		stall := stub.stalls[chunk.ChunkID]
+		delete(stub.stalls, chunk.ChunkID)
+		stub.mu.Unlock()
+		if stall > 0 {
+			time.Sleep(stall)
+			return
+		}
+
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		if stub.failAfter > 0 && len(stub.accepted) >= stub.failAfter {
+			http.Error(w, "connection lost", http.StatusServiceUnavailable)
+			return
+		}
+		if _, ok := stub.chunks[chunk.Path]; ok {
+			hash := stub.hashes[chunk.Path]
+			if stub.totals[chunk.Path] != chunk.Total || (chunk.FileHash != "" && hash != "" && chunk.FileHash != hash) {
+				http.Error(w, "session error: upload session mismatch", http.StatusConflict)
+				return
+			}
+		}
+		stub.accepted = append(stub.accepted, chunk.ChunkID)
+		if stub.chunks[chunk.Path] == nil {
+			stub.chunks[chunk.Path] = make(map[int][]byte)
+		}
+		stub.chunks[chunk.Path][chunk.ChunkID] = chunk.Data
+		stub.totals[chunk.Path] = chunk.Total
+		if chunk.FileHash != "" {
+			stub.hashes[chunk.Path] = chunk.FileHash
+		}
+
+		if len(stub.chunks[chunk.Path]) == chunk.Total {
+			stub.assemble(chunk.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		var chunk transport.ChunkData
+		if err := json.NewDecoder(r.Body).Decode(&chunk); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		accept(w, chunk)
+	})
+	mux.HandleFunc("/upload/stream", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, _, err := r.FormFile("data")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		id, _ := strconv.Atoi(r.FormValue("chunk_id"))
+		total, _ := strconv.Atoi(r.FormValue("total"))
+		stub.mu.Lock()
+		stub.streamed++
+		stub.mu.Unlock()
+		accept(w, transport.ChunkData{Path: r.FormValue("path"), ChunkID: id, Total: total, Data: data, FileHash: r.FormValue("file_hash")})
+	})
+	mux.HandleFunc("/upload/finalize", func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Query().Get("path")
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		if len(stub.chunks[path]) == 0 || len(stub.chunks[path]) != stub.totals[path] {
+			http.Error(w, "upload is missing chunks", http.StatusConflict)
+			return
+		}
+		stub.finalized = append(stub.finalized, path)
+		stub.assemble(path)
+	})
+	mux.HandleFunc("/upload/abort", func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Query().Get("path")
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		stub.aborted = append(stub.aborted, path)
+		stub.discard(path)
+	})
+	mux.HandleFunc("/upload/status", func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		var status transport.UploadStatusResponse
+		path := r.URL.Query().Get("path")
+		if chunks, ok := stub.chunks[path]; ok {
+			status.Exists = true
+			status.TotalChunks = stub.totals[path]
+			status.ReceivedMap = make([]bool, status.TotalChunks)
+			if r.URL.Query().Get("checksums") == "true" {
+				status.Checksums = make([]string, status.TotalChunks)
+			}
+			for i := 0; i < status.TotalChunks; i++ {
+				if data, ok := chunks[i]; ok {
+					status.ReceivedMap[i] = true
+					if status.Checksums != nil {
+						status.Checksums[i] = chunk.Checksum(data)
+					}
+				} else {
+					status.MissingChunks = append(status.MissingChunks, i)
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		data, ok := stub.files[r.URL.Query().Get("path")]
+		corrupt := stub.corrupt
+		stub.mu.Unlock()
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if corrupt && len(data) > 0 {
+			data = append([]byte{data[0] ^ 0xff}, data[1:]...)
+		}
+		w.Write(data)
+	})
+	mux.HandleFunc("/delete", func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Query().Get("path")
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		if _, ok := stub.files[path]; ok {
+			delete(stub.files, path)
+			stub.deleted = append(stub.deleted, path)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		// A directory goes with everything in it
+		found := false
+		for name := range stub.files {
+			if strings.HasPrefix(name, strings.Trim(path, "/")+"/") {
+				delete(stub.files, name)
+				found = true
+			}
+		}
+		if !found {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		stub.deleted = append(stub.deleted, path)
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/list/detailed", func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		json.NewEncoder(w).Encode(stub.entries(r.URL.Query().Get("path")))
+	})
+	mux.HandleFunc("/stat", func(w http.ResponseWriter, r *http.Request) {
+		p := strings.Trim(r.URL.Query().Get("path"), "/")
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		if data, ok := stub.files[p]; ok {
+			json.NewEncoder(w).Encode(transport.StatResponse{Path: p, Size: int64(len(data))})
+			return
+		}
+		for name := range stub.files {
+			if p == "" || strings.HasPrefix(name, p+"/") {
+				json.NewEncoder(w).Encode(transport.StatResponse{Path: p, IsDir: true})
+				return
+			}
+		}
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+	mux.HandleFunc("/mkdir", func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		stub.dirs = append(stub.dirs, r.URL.Query().Get("path"))
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return stub, ts
+}
+
+// assemble stores the chunks of the upload of path as a file
+func (s *stubServer) assemble(path string) {
+	var data []byte
+	for i := 0; i < s.totals[path]; i++ {
+		data = append(data, s.chunks[path][i]...)
+	}
+	s.files[path] = data
+	s.discard(path)
+}
+
+// discard forgets the unfinished upload of path
+func (s *stubServer) discard(path string) {
+	delete(s.chunks, path)
+	delete(s.totals, path)
+	delete(s.hashes, path)
+}
+
+// entries lists the files and directories directly inside dir, sorted by name
+func (s *stubServer) entries(dir string) []transport.ListEntry {
+	prefix := strings.Trim(dir, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	seen := make(map[string]bool)
+	var entries []transport.ListEntry
+	for name, data := range s.files {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		entry := transport.ListEntry{Name: rest, Size: int64(len(data))}
+		if first, _, nested := strings.Cut(rest, "/"); nested {
+			entry = transport.ListEntry{Name: first, IsDir: true}
+		}
+		if !seen[entry.Name] {
+			seen[entry.Name] = true
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+func TestRunBench(t *testing.T) {
+	stub, ts := newStubServer(t)
+	client := transport.NewHTTPClient(ts.URL)
+
+	opts := benchOptions{Size: 256*1024 + 100, ChunkSize: 64 * 1024, Parallel: 3}
+	result, err := runBench(client, opts)
+	if err != nil {
+		t.Fatalf("runBench failed: %v", err)
+	}
+
+	if result.Chunks != 5 {
+		t.Errorf("expected 5 chunks, got %d", result.Chunks)
+	}
+	stub.mu.Lock()
+	streamed := stub.streamed
+	stub.mu.Unlock()
+	if streamed != 5 {
+		t.Errorf("expected every chunk to go to /upload/stream, got %d", streamed)
+	}
+	if result.UploadThroughput() <= 0 {
+		t.Errorf("expected positive upload throughput, got %f", result.UploadThroughput())
+	}
+	if result.DownloadThroughput() <= 0 {
+		t.Errorf("expected positive download throughput, got %f", result.DownloadThroughput())
+	}
+	if len(result.ChunkLatencies) != result.Chunks {
+		t.Errorf("expected %d latencies, got %d", result.Chunks, len(result.ChunkLatencies))
+	}
+	if result.latencyPercentile(100) <= 0 {
+		t.Error("expected non-zero max chunk latency")
+	}
+
+	// The temporary remote file must be removed afterwards
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if len(stub.files) != 0 {
+		t.Errorf("expected no remote files after bench, found %d", len(stub.files))
+	}
+	if len(stub.deleted) != 1 || stub.deleted[0] != result.RemotePath {
+		t.Errorf("expected %s to be deleted, got %v", result.RemotePath, stub.deleted)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{input: "100MB", want: 100 * 1024 * 1024},
+		{input: "1mb", want: 1024 * 1024},
+		{input: "512K", want: 512 * 1024},
+		{input: "4096", want: 4096},
+		{input: "1.5GB", want: 1536 * 1024 * 1024},
+		{input: "abc", wantErr: true},
+		{input: "-5MB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseSize(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/config"
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// discoverOptions controls where discover looks for servers
+type discoverOptions struct {
+	Targets []string // subnets or hosts to probe (none to wait for broadcasts)
+}
+
+// parseDiscoverFlags extracts discover options from the arguments, returning the remaining arguments
+func parseDiscoverFlags(args []string) (discoverOptions, []string, error) {
+	var opts discoverOptions
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "--subnet", "-subnet":
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("%s needs a subnet or host", arg)
+			}
+			i++
+			for _, target := range strings.Split(args[i], ",") {
+				if target = strings.TrimSpace(target); target != "" {
+					opts.Targets = append(opts.Targets, target)
+				}
+			}
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return opts, rest, nil
+}
+
+func doDiscover(cfg *config.Config, args []string) {
+	opts, args, err := parseDiscoverFlags(args)
+	if err != nil {
+		log.Fatalf("Discovery failed: %v", err)
+	}
+	if len(args) > 0 {
+		log.Fatalf("Discovery failed: unexpected argument %q", args[0])
+	}
+
+	discovery := newDiscoveryClient(cfg)
+	targets := opts.Targets
+	if len(targets) == 0 && cfg != nil {
+		targets = cfg.Client.DiscoveryTargets
+	}
+	if len(targets) > 0 {
+		discovery.SetProbeTargets(targets)
+		fmt.Printf("Probing %s for GoFlux servers...\n", strings.Join(targets, ", "))
+	} else {
+		fmt.Println("Discovering GoFlux servers on local network...")
+	}
+
+	servers, err := discovery.DiscoverServers()
+	if err != nil {
+		log.Fatalf("Discovery failed: %v", err)
+	}
+
+	fmt.Print(discovery.FormatServerList(servers))
+}
+
+// newDiscoveryClient returns a discovery client looking for servers the way
+// the client config says, or the default way without one
+func newDiscoveryClient(cfg *config.Config) *transport.DiscoveryClient {
+	discovery := transport.NewDiscoveryClient()
+	if cfg != nil {
+		discovery.SetMode(cfg.Client.Discovery)
+		discovery.SetSecret(cfg.Client.DiscoverySecret)
+		discovery.SetProbeTargets(cfg.Client.DiscoveryTargets)
+	}
+	return discovery
+}
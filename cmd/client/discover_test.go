@@ -0,0 +1,24 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDiscoverFlags(t *testing.T) {
+	opts, rest, err := parseDiscoverFlags([]string{"--subnet", "192.168.1.0/24,10.0.0.5", "-subnet", "nas.local"})
+	if err != nil {
+		t.Fatalf("parseDiscoverFlags failed: %v", err)
+	}
+	want := []string{"192.168.1.0/24", "10.0.0.5", "nas.local"}
+	if !reflect.DeepEqual(opts.Targets, want) {
+		t.Errorf("expected targets %v, got %v", want, opts.Targets)
+	}
+	if len(rest) != 0 {
+		t.Errorf("expected no remaining arguments, got %v", rest)
+	}
+
+	if _, _, err := parseDiscoverFlags([]string{"--subnet"}); err == nil {
+		t.Error("expected --subnet without a value to fail")
+	}
+}
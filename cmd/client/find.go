@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// findOptions controls how find searches and shows its matches
+type findOptions struct {
+	Long  bool // show type, size and time like ls -l
+	Human bool // with Long, show sizes in KB, MB...
+	Limit int  // most matches to ask the server for (0 = the server's cap)
+}
+
+// parseFindFlags extracts find options from the arguments, returning the remaining arguments
+func parseFindFlags(args []string) (findOptions, []string, error) {
+	var opts findOptions
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "-l", "--long":
+			opts.Long = true
+		case "-h", "--human":
+			opts.Human = true
+		case "-lh", "-hl":
+			opts.Long, opts.Human = true, true
+		case "--limit", "-limit":
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("%s needs a number", arg)
+			}
+			i++
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n <= 0 {
+				return opts, nil, fmt.Errorf("invalid %s %q: must be a positive number", arg, args[i])
+			}
+			opts.Limit = n
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return opts, rest, nil
+}
+
+func doFind(ctx context.Context, client *transport.HTTPClient, args []string) {
+	opts, args, err := parseFindFlags(args)
+	if err != nil {
+		log.Fatalf("Find failed: %v", err)
+	}
+	var base, pattern string
+	switch len(args) {
+	case 1:
+		pattern = args[0]
+	case 2:
+		base, pattern = args[0], args[1]
+	default:
+		fmt.Println("Usage: find [-l [-h]] [--limit N] [path] <pattern>")
+		os.Exit(1)
+	}
+
+	result, err := client.Search(ctx, base, pattern, opts.Limit)
+	if err != nil {
+		log.Fatalf("Find failed: %v", err)
+	}
+	for _, line := range formatMatches(base, result.Matches, opts) {
+		fmt.Println(line)
+	}
+	if result.Truncated {
+		fmt.Fprintln(os.Stderr, "Search stopped early; narrow the path or pattern to see everything")
+	}
+}
+
+// formatMatches lists a search's matches by their remote path, directories
+// marked by a trailing /, or with opts.Long as an ls -l listing
+func formatMatches(base string, matches []transport.ListEntry, opts findOptions) []string {
+	if len(matches) == 0 {
+		return []string{"No matches"}
+	}
+
+	entries := make([]transport.ListEntry, len(matches))
+	for i, match := range matches {
+		match.Name = path.Join(base, match.Name)
+		entries[i] = match
+	}
+	if opts.Long {
+		return formatLongListing(entries, opts.Human)
+	}
+
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		lines[i] = entry.Name
+		if entry.IsDir {
+			lines[i] += "/"
+		}
+	}
+	return lines
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+func TestParseFindFlags(t *testing.T) {
+	opts, rest, err := parseFindFlags([]string{"-lh", "logs", "--limit", "20", "*.log"})
+	if err != nil {
+		t.Fatalf("parseFindFlags failed: %v", err)
+	}
+	if !opts.Long || !opts.Human || opts.Limit != 20 {
+		t.Errorf("unexpected options %+v", opts)
+	}
+	if !reflect.DeepEqual(rest, []string{"logs", "*.log"}) {
+		t.Errorf("unexpected remaining args: %v", rest)
+	}
+
+	for _, args := range [][]string{{"--limit"}, {"--limit", "0", "x"}, {"--limit", "many", "x"}} {
+		if _, _, err := parseFindFlags(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+func TestFormatMatches(t *testing.T) {
+	matches := []transport.ListEntry{
+		{Name: "2024/app.log", Size: 2048},
+		{Name: "old.log", IsDir: true},
+	}
+
+	got := formatMatches("logs", matches, findOptions{})
+	if want := []string{"logs/2024/app.log", "logs/old.log/"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := formatMatches("", matches[:1], findOptions{}); got[0] != "2024/app.log" {
+		t.Errorf("expected paths from the root unprefixed, got %v", got)
+	}
+
+	long := formatMatches("logs", matches, findOptions{Long: true, Human: true})
+	if len(long) != 3 || !strings.Contains(long[0], "logs/2024/app.log") || !strings.Contains(long[0], "2.0 KB") {
+		t.Errorf("expected an ls -l style listing, got %q", long)
+	}
+
+	if got := formatMatches("logs", nil, findOptions{}); !reflect.DeepEqual(got, []string{"No matches"}) {
+		t.Errorf("expected a note when nothing matches, got %v", got)
+	}
+}
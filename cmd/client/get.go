@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// getOptions controls how get downloads remote files
+type getOptions struct {
+	Recursive bool // download a whole remote directory tree
+	Force     bool // download files even if a local copy of the same size exists
+	Archive   bool // download a remote directory as a single zip or tar.gz archive
+}
+
+// parseGetFlags extracts get options from the arguments, returning the remaining arguments
+func parseGetFlags(args []string) (getOptions, []string) {
+	var opts getOptions
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch arg {
+		case "-r", "--recursive", "-recursive":
+			opts.Recursive = true
+		case "--force", "-force":
+			opts.Force = true
+		case "--archive", "-archive":
+			opts.Archive = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return opts, rest
+}
+
+// getTree downloads every file beneath the remote directory remoteRoot to the
+// same relative path under localRoot, creating local directories as it goes.
+// Files that already exist locally with the size the server reports are
+// skipped unless opts.Force is set. It returns the number of files downloaded
+// and skipped.
+func getTree(ctx context.Context, client *transport.HTTPClient, remoteRoot, localRoot string, opts getOptions) (downloaded, skipped int, err error) {
+	stat, err := client.Stat(remoteRoot)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to stat %s: %w", remoteRoot, err)
+	}
+	if !stat.IsDir {
+		return 0, 0, fmt.Errorf("%s is not a directory", remoteRoot)
+	}
+	return getDir(ctx, client, remoteRoot, localRoot, opts)
+}
+
+// archiveFormat returns the archive format the server should send for a
+// local file name: tar.gz for .tar.gz and .tgz, zip otherwise
+func archiveFormat(localPath string) string {
+	lower := strings.ToLower(localPath)
+	if strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") {
+		return "tar.gz"
+	}
+	return "zip"
+}
+
+// getArchive downloads the remote directory remoteDir as one archive file at
+// localPath, in the format its name suggests. The archive is written beside
+// localPath first, so an interrupted download never leaves a truncated
+// archive in its place. It returns the archive's size.
+func getArchive(ctx context.Context, client *transport.HTTPClient, remoteDir, localPath string) (int64, error) {
+	partPath := localPath + ".part"
+	f, err := os.Create(partPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", partPath, err)
+	}
+
+	n, err := client.DownloadArchive(ctx, remoteDir, archiveFormat(localPath), f)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write %s: %w", partPath, closeErr)
+	}
+	if err != nil {
+		os.Remove(partPath)
+		return 0, err
+	}
+	if err := os.Rename(partPath, localPath); err != nil {
+		os.Remove(partPath)
+		return 0, fmt.Errorf("failed to write file: %w", err)
+	}
+	return n, nil
+}
+
+// getDir downloads the contents of one remote directory for getTree
+func getDir(ctx context.Context, client *transport.HTTPClient, remoteDir, localDir string, opts getOptions) (downloaded, skipped int, err error) {
+	if err := os.MkdirAll(localDir, 0755); err != nil {
+		return 0, 0, fmt.Errorf("failed to create %s: %w", localDir, err)
+	}
+
+	entries, err := client.ListDetailedContext(ctx, remoteDir)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list %s: %w", remoteDir, err)
+	}
+
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return downloaded, skipped, ctx.Err()
+		}
+		if err := checkEntryName(remoteDir, entry.Name); err != nil {
+			return downloaded, skipped, err
+		}
+		remotePath := path.Join(remoteDir, entry.Name)
+		localPath := filepath.Join(localDir, filepath.FromSlash(entry.Name))
+
+		if entry.IsDir {
+			d, s, err := getDir(ctx, client, remotePath, localPath, opts)
+			downloaded += d
+			skipped += s
+			if err != nil {
+				return downloaded, skipped, err
+			}
+			continue
+		}
+
+		if !opts.Force {
+			if info, err := os.Stat(localPath); err == nil && !info.IsDir() && info.Size() == entry.Size {
+				fmt.Printf("Skipping %s: already downloaded\n", remotePath)
+				skipped++
+				continue
+			}
+		}
+		if err := downloadFile(ctx, client, remotePath, localPath); err != nil {
+			return downloaded, skipped, fmt.Errorf("%s: %w", remotePath, err)
+		}
+		downloaded++
+	}
+	return downloaded, skipped, nil
+}
+
+// checkEntryName rejects a name the server listed in dir that isn't a single
+// path element, so that a hostile server can't lead a walk outside its root
+func checkEntryName(dir, name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("server listed an invalid entry %q in %s", name, dir)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+func TestParseGetFlags(t *testing.T) {
+	opts, rest := parseGetFlags([]string{"-r", "site", "--force", "--archive", "local"})
+	if !opts.Recursive || !opts.Force || !opts.Archive {
+		t.Errorf("expected both options set, got %+v", opts)
+	}
+	if len(rest) != 2 || rest[0] != "site" || rest[1] != "local" {
+		t.Errorf("unexpected remaining args: %v", rest)
+	}
+}
+
+// newTreeServer returns a stub server holding a small directory tree
+func newTreeServer(t *testing.T) (*stubServer, *transport.HTTPClient) {
+	t.Helper()
+	stub, ts := newStubServer(t)
+	stub.files["site/index.html"] = []byte("<html>")
+	stub.files["site/css/main.css"] = []byte("body {}")
+	stub.files["site/img/icons/logo.svg"] = []byte("<svg/>")
+	stub.files["other/skip.txt"] = []byte("not part of the tree")
+	return stub, transport.NewHTTPClient(ts.URL)
+}
+
+func TestGetTree_DownloadsStructure(t *testing.T) {
+	_, client := newTreeServer(t)
+	local := filepath.Join(t.TempDir(), "copy")
+
+	downloaded, skipped, err := getTree(context.Background(), client, "site", local, getOptions{Recursive: true})
+	if err != nil {
+		t.Fatalf("getTree failed: %v", err)
+	}
+	if downloaded != 3 || skipped != 0 {
+		t.Errorf("expected 3 downloaded and 0 skipped, got %d and %d", downloaded, skipped)
+	}
+
+	want := map[string]string{
+		"index.html":         "<html>",
+		"css/main.css":       "body {}",
+		"img/icons/logo.svg": "<svg/>",
+	}
+	var found []string
+	filepath.WalkDir(local, func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(local, p)
+			found = append(found, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if len(found) != len(want) {
+		t.Errorf("expected files %v, got %v", want, found)
+	}
+	for rel, content := range want {
+		data, err := os.ReadFile(filepath.Join(local, filepath.FromSlash(rel)))
+		if err != nil || string(data) != content {
+			t.Errorf("%s: expected %q, got %q (%v)", rel, content, data, err)
+		}
+	}
+}
+
+func TestGetTree_SkipsUpToDateFiles(t *testing.T) {
+	stub, client := newTreeServer(t)
+	local := t.TempDir()
+	getTree(context.Background(), client, "site", local, getOptions{})
+
+	// A local file with a different size is downloaded again
+	os.WriteFile(filepath.Join(local, "css", "main.css"), []byte("stale"), 0644)
+	stub.files["site/index.html"] = []byte("<HTML>") // same size, so left alone
+
+	downloaded, skipped, err := getTree(context.Background(), client, "site", local, getOptions{})
+	if err != nil {
+		t.Fatalf("getTree failed: %v", err)
+	}
+	if downloaded != 1 || skipped != 2 {
+		t.Errorf("expected 1 downloaded and 2 skipped, got %d and %d", downloaded, skipped)
+	}
+	if data, _ := os.ReadFile(filepath.Join(local, "css", "main.css")); string(data) != "body {}" {
+		t.Errorf("expected stale file to be replaced, got %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(local, "index.html")); string(data) != "<html>" {
+		t.Errorf("expected same-size file to be skipped, got %q", data)
+	}
+
+	downloaded, skipped, err = getTree(context.Background(), client, "site", local, getOptions{Force: true})
+	if err != nil {
+		t.Fatalf("getTree with force failed: %v", err)
+	}
+	if downloaded != 3 || skipped != 0 {
+		t.Errorf("expected force to download all 3 files, got %d and %d skipped", downloaded, skipped)
+	}
+	if data, _ := os.ReadFile(filepath.Join(local, "index.html")); string(data) != "<HTML>" {
+		t.Errorf("expected forced download to replace file, got %q", data)
+	}
+}
+
+func TestGetTree_RequiresDirectory(t *testing.T) {
+	_, client := newTreeServer(t)
+	if _, _, err := getTree(context.Background(), client, "site/index.html", t.TempDir(), getOptions{}); err == nil {
+		t.Error("expected error for a remote file")
+	}
+}
+
+func TestArchiveFormat(t *testing.T) {
+	tests := map[string]string{
+		"site.zip":        "zip",
+		"site":            "zip",
+		"site.tar.gz":     "tar.gz",
+		"backup/SITE.TGZ": "tar.gz",
+	}
+	for name, want := range tests {
+		if got := archiveFormat(name); got != want {
+			t.Errorf("archiveFormat(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestGetArchive(t *testing.T) {
+	var format string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/download/archive" || r.URL.Query().Get("path") != "site" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		format = r.URL.Query().Get("format")
+		w.Write([]byte("archive bytes"))
+	}))
+	defer ts.Close()
+	client := transport.NewHTTPClient(ts.URL)
+	dir := t.TempDir()
+
+	local := filepath.Join(dir, "site.tgz")
+	size, err := getArchive(context.Background(), client, "site", local)
+	if err != nil {
+		t.Fatalf("getArchive failed: %v", err)
+	}
+	if format != "tar.gz" {
+		t.Errorf("expected tar.gz requested for a .tgz file, got %q", format)
+	}
+	if data, err := os.ReadFile(local); err != nil || string(data) != "archive bytes" || size != int64(len(data)) {
+		t.Errorf("expected the archive saved, got %q (%v), size %d", data, err, size)
+	}
+	if _, err := os.Stat(local + ".part"); !os.IsNotExist(err) {
+		t.Error("expected the partial file renamed into place")
+	}
+
+	// A refused archive leaves nothing behind
+	missing := filepath.Join(dir, "missing.zip")
+	if _, err := getArchive(context.Background(), client, "missing", missing); err == nil {
+		t.Fatal("expected an error for a missing directory")
+	}
+	for _, p := range []string{missing, missing + ".part"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("expected no %s after a failed download", p)
+		}
+	}
+}
+
+// newHostileServer returns a server listing name as a file in every directory
+func newHostileServer(t *testing.T, name string) *transport.HTTPClient {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stat", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(transport.StatResponse{Path: r.URL.Query().Get("path"), IsDir: true})
+	})
+	mux.HandleFunc("/list/detailed", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]transport.ListEntry{{Name: name, Size: 5}})
+	})
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("owned"))
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return transport.NewHTTPClient(ts.URL)
+}
+
+func TestTreeWalks_RejectInvalidEntryNames(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range []string{"", ".", "..", "../escape", "a/b", `..\escape`} {
+		client := newHostileServer(t, name)
+		root := t.TempDir()
+		local := filepath.Join(root, "copy")
+
+		if _, _, err := getTree(ctx, client, "site", local, getOptions{Recursive: true}); err == nil {
+			t.Errorf("get: expected %q to be rejected", name)
+		}
+		if _, err := os.Stat(filepath.Join(root, "escape")); err == nil {
+			t.Errorf("get: %q wrote outside the target directory", name)
+		}
+		if _, err := relayTree(ctx, client, client, "site", "copy", 4); err == nil {
+			t.Errorf("relay: expected %q to be rejected", name)
+		}
+		if err := remoteSizes(ctx, client, "site", "", map[string]int64{}); err == nil {
+			t.Errorf("sync: expected %q to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// testListing is a listing with a directory and files of very different sizes
+func testListing() []transport.ListEntry {
+	mod := time.Date(2024, 5, 1, 12, 30, 0, 0, time.Local)
+	return []transport.ListEntry{
+		{Name: "daily", IsDir: true, ModTime: mod},
+		{Name: "full.tar", Size: 1536 * 1024 * 1024, ModTime: mod},
+		{Name: "notes.txt", Size: 12, ModTime: mod},
+		{Name: "report.pdf", Size: 2048, ModTime: mod},
+	}
+}
+
+func TestFormatLongListing_Columns(t *testing.T) {
+	for _, human := range []bool{false, true} {
+		lines := formatLongListing(testListing(), human)
+		if len(lines) != 5 {
+			t.Fatalf("expected 4 entries and a totals line, got %q", lines)
+		}
+
+		// Every column starts at the same offset, so each entry's name does too
+		nameCol := -1
+		for _, line := range lines[:4] {
+			fields := strings.Fields(line)
+			name := fields[len(fields)-1]
+			col := utf8.RuneCountInString(line[:strings.LastIndex(line, name)])
+			if nameCol == -1 {
+				nameCol = col
+			} else if col != nameCol {
+				t.Errorf("human=%v: misaligned line %q (name at %d, expected %d)", human, line, col, nameCol)
+			}
+		}
+
+		if !strings.HasPrefix(strings.TrimSpace(lines[0]), "d---------") || !strings.HasSuffix(lines[0], "daily/") {
+			t.Errorf("human=%v: unexpected directory line %q", human, lines[0])
+		}
+		if !strings.HasPrefix(strings.TrimSpace(lines[2]), "----------") || !strings.Contains(lines[2], "2024-05-01 12:30") {
+			t.Errorf("human=%v: unexpected file line %q", human, lines[2])
+		}
+	}
+}
+
+func TestFormatLongListing_Sizes(t *testing.T) {
+	lines := formatLongListing(testListing(), false)
+	if fields := strings.Fields(lines[1]); fields[1] != "1610612736" {
+		t.Errorf("expected the size in bytes, got %q", lines[1])
+	}
+
+	lines = formatLongListing(testListing(), true)
+	if !strings.Contains(lines[1], "1.5 GB") || !strings.Contains(lines[3], "2.0 KB") || !strings.Contains(lines[2], "12 B") {
+		t.Errorf("expected human-readable sizes, got %q", lines)
+	}
+}
+
+func TestFormatLongListing_Totals(t *testing.T) {
+	lines := formatLongListing(testListing(), false)
+	want := "total 4 (3 files, 1 directories), 1610614796 bytes"
+	if got := lines[len(lines)-1]; got != want {
+		t.Errorf("expected totals line %q, got %q", want, got)
+	}
+
+	lines = formatLongListing(testListing(), true)
+	want = "total 4 (3 files, 1 directories), 1.5 GB"
+	if got := lines[len(lines)-1]; got != want {
+		t.Errorf("expected totals line %q, got %q", want, got)
+	}
+}
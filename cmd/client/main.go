@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	stderrors "errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,13 +21,13 @@ import (
 	"github.com/0xRepo-Source/goflux-lite/pkg/config"
 	"github.com/0xRepo-Source/goflux-lite/pkg/glob"
 	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
-	"github.com/0xRepo-Source/goflux-lite/pkg/updater"
 )
 
 func main() {
 	defaultConfigPath := filepath.Join(executableDir(), "goflux.json")
 
-	configFile := flag.String("config", defaultConfigPath, "path to configuration file")
+	var configFiles config.Files
+	flag.Var(&configFiles, "config", "path to configuration file (default goflux.json beside gfl); repeat to override it with further files")
 	version := flag.Bool("version", false, "print version")
 	flag.Parse()
 
@@ -35,14 +42,32 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Load configuration
-	cfg, err := loadConfig(*configFile)
+	// Load configuration, applying any overrides to the first file
+	if len(configFiles) == 0 {
+		configFiles = config.Files{defaultConfigPath}
+	}
+	cfg, err := loadConfig(configFiles[0])
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	for _, path := range configFiles[1:] {
+		if err := config.MergeFile(cfg, path); err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+	}
 
 	// Create HTTP client
-	client := transport.NewHTTPClient(cfg.Client.ServerURL)
+	client := transport.NewHTTPClientWithTimeout(cfg.Client.ServerURL, time.Duration(cfg.Client.RequestTimeoutSeconds)*time.Second)
+	if cfg.Client.UploadRetries != 0 {
+		client.SetUploadRetries(max(cfg.Client.UploadRetries, 0))
+	}
+	if cfg.Client.ChunkTimeoutSeconds != 0 {
+		client.SetChunkTimeout(time.Duration(max(cfg.Client.ChunkTimeoutSeconds, 0)) * time.Second)
+	}
+	client.SetBasePath(cfg.Client.BasePath)
+	if cfg.Client.SpeedWindowSeconds != 0 {
+		speedWindow = time.Duration(max(cfg.Client.SpeedWindowSeconds, 0)) * time.Second
+	}
 
 	// Set authentication token (environment variable takes precedence over config file)
 	token := os.Getenv("GOFLUX_TOKEN_LITE")
@@ -52,27 +77,50 @@ func main() {
 
 	if token != "" {
 		client.SetAuthToken(token)
+	} else if cfg.Client.AuthRequired {
+		fmt.Println("⚠️  This server requires authentication; set GOFLUX_TOKEN_LITE or the token in goflux.json")
 	}
 
+	// Ctrl+C cancels transfers in flight; they can be resumed by running the
+	// same command again
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	// Execute command
 	command := args[0]
 	switch command {
 	case "discover":
-		doDiscover()
+		doDiscover(cfg, args[1:])
 	case "config":
-		doConfig(args[1:])
+		if len(args) > 1 && args[1] == "show" {
+			doConfigShow(cfg, client, token)
+		} else {
+			doConfig(args[1:])
+		}
 	case "update":
-		doUpdate(args[1:])
+		doUpdate(cfg, args[1:])
 	case "get":
-		doGet(client, args[1:])
+		doGet(ctx, client, args[1:])
 	case "put":
-		doPut(client, args[1:])
+		doPut(ctx, client, args[1:], time.Duration(cfg.Client.UploadTimeoutMinutes)*time.Minute)
 	case "ls":
-		doList(client, args[1:])
+		doList(ctx, client, args[1:])
+	case "find":
+		doFind(ctx, client, args[1:])
 	case "rm":
-		doDelete(client, args[1:])
+		doDelete(ctx, client, args[1:])
 	case "mkdir":
 		doMkdir(client, args[1:])
+	case "mv":
+		doMove(client, args[1:])
+	case "publish":
+		doPublish(client, args[1:])
+	case "relay":
+		doRelay(ctx, client, args[1:])
+	case "sync":
+		doSync(ctx, client, args[1:])
+	case "bench":
+		doBench(client, args[1:])
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		printUsage()
@@ -87,31 +135,82 @@ USAGE:
   gfl [options] <command> [args...]
 
 OPTIONS:
-  -config string    Configuration file (default "goflux.json")
+  -config string    Configuration file (default "goflux.json"); repeat to
+                    override it with further files, later ones winning
   -version          Show version
 
 COMMANDS:
-  discover              Discover GoFlux servers on local network
+  discover [--subnet <cidr|host>]
+                       Discover GoFlux servers on local network (--subnet
+                       probes a subnet's broadcast address or a host instead
+                       of waiting for broadcasts; repeatable)
   config <server>       Configure client for discovered server
-  update [--local]      Check for and install updates
+  config show           Show the server, base path and other settings in use
+  update [--local]      Check for and install updates (--local finds a server
+                       on the network to update from)
+  update --rollback     Restore the version replaced by the last update
   get <remote> <local>  Download file(s) - supports wildcards (*, ?, [])
-  put <local> <remote>  Upload file(s) - supports wildcards (*, ?, [])
-  ls [path]            List files/directories
-  rm <path>            Remove file or directory
-  mkdir <path>         Create directory
+                       -r downloads a directory tree (--force re-downloads
+                       files whose local copy already has the same size)
+                       -r --archive saves it as one .zip or .tar.gz file
+  put <local> <remote>  Upload file(s) - supports wildcards (*, ?, [], **, {a,b})
+                       --delete-source removes each file once uploaded
+                       (--verify compares hashes with the server copy first)
+                       --checksum-only-resume re-sends damaged chunks on resume
+                       -r uploads a directory tree, keeping its structure
+                       -x <pattern> leaves out matching files and
+                       directories (repeatable)
+                       --chunk-timeout <d> retries a chunk taking longer
+                       --timeout <d> gives up on the whole upload after d
+  ls [-l [-h]] [path]  List files/directories (-l shows type, size and time
+                       with totals; -h shows sizes in KB, MB...)
+  find [-l] [--limit N] [path] <pattern>
+                       Find files and directories beneath path (default the
+                       root) whose names match a glob or contain the text
+  rm [-r] <path>       Remove file or directory (-r is needed for directories,
+                       which list what they contain and ask first; -y skips
+                       asking, --dry-run only lists what would be removed)
+  mkdir [-p] <path>    Create directory (-p creates missing parents)
+  mv [-f] <src> <dst>  Rename or move a remote file or directory (-f replaces
+                       an existing file at <dst>)
+  publish <name> <remote>
+                       Move files staged in .staging/<name>/ to <remote> at once
+  relay [-r] <src-server>:<path> <dst-server>:<path>
+                       Copy a file (or tree with -r) between two servers
+                       without storing it locally; --src-token and
+                       --dst-token give servers other than the configured
+                       one their tokens
+  sync <local> <remote>  Upload new and changed files so <remote> matches <local>
+                       --checksum also compares hashes of same-size files
+                       --delete removes remote files missing locally
+                       --dry-run prints the planned changes only
+  bench [--size 100MB] [--chunk 1MB] [--parallel N]
+                       Measure upload/download throughput
 
 EXAMPLES:
   gfl discover
+  gfl discover --subnet 192.168.1.0/24
   gfl config 192.168.1.100:8080
   gfl put document.pdf files/document.pdf
   gfl put *.txt uploads/          # Upload all .txt files
+  gfl put "report.{csv,pdf}" out/ # Upload each alternative
   gfl put report* archives/       # Upload files matching pattern
+  gfl put "src/**/*.go" code/     # Upload matching files in all subdirectories
+  gfl put --delete-source --verify *.log archive/  # Move files to the server
   gfl get files/document.pdf downloaded.pdf
   gfl get files/*.txt downloads/  # Download all .txt files
   gfl get logs/2024*.log ./logs/  # Download matching log files
   gfl ls files/
+  gfl find logs "*.log"           # Find log files anywhere under logs/
   gfl mkdir uploads/
+  gfl mkdir -p projects/2024/reports
   gfl rm old-file.txt
+  gfl mv drafts/report.pdf reports/2024/report.pdf
+  gfl rm -r --dry-run old-project/  # See what deleting a directory removes
+  gfl put site/* .staging/site-v2/  # Stage files out of sight...
+  gfl publish site-v2 www/v2      # ...then make them appear together
+  gfl sync --delete --dry-run ./site www  # Preview mirroring a directory
+  gfl bench --size 50MB --chunk 4MB --parallel 4
 
 `)
 }
@@ -158,30 +257,57 @@ func loadConfig(configFile string) (*config.Config, error) {
 	}, nil
 }
 
-func doGet(client *transport.HTTPClient, args []string) {
+func doGet(ctx context.Context, client *transport.HTTPClient, args []string) {
+	opts, args := parseGetFlags(args)
 	if len(args) < 2 {
-		fmt.Println("Usage: get <remote_path> <local_path>")
+		fmt.Println("Usage: get [-r [--force | --archive]] <remote_path> <local_path>")
 		os.Exit(1)
 	}
 
 	remotePath := strings.TrimSpace(args[0])
 	localPath := strings.TrimSpace(strings.Join(args[1:], " "))
 	if remotePath == "" || localPath == "" {
-		fmt.Println("Usage: get <remote_path> <local_path>")
+		fmt.Println("Usage: get [-r [--force | --archive]] <remote_path> <local_path>")
 		os.Exit(1)
 	}
 
+	if opts.Archive {
+		fmt.Printf("Downloading %s as %s...\n", remotePath, localPath)
+		size, err := getArchive(ctx, client, remotePath, localPath)
+		if err != nil {
+			log.Fatalf("Download failed: %v", err)
+		}
+		fmt.Printf("✓ Download complete: %s → %s (%s)\n", remotePath, localPath, formatBytes(int(size)))
+		return
+	}
+
+	if opts.Recursive {
+		downloaded, skipped, err := getTree(ctx, client, remotePath, localPath, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Fatalf("Download interrupted; run the same command again to resume")
+			}
+			log.Fatalf("Download failed: %v", err)
+		}
+		fmt.Printf("\n✓ Downloaded %d files from %s to %s", downloaded, remotePath, localPath)
+		if skipped > 0 {
+			fmt.Printf(" (%d already up to date)", skipped)
+		}
+		fmt.Println()
+		return
+	}
+
 	// Check if remote path contains wildcards
 	if strings.ContainsAny(remotePath, "*?[]") {
-		doBatchGet(client, remotePath, localPath)
+		doBatchGet(ctx, client, remotePath, localPath)
 		return
 	}
 
 	// Single file download
-	downloadSingleFile(client, remotePath, localPath)
+	downloadSingleFile(ctx, client, remotePath, localPath)
 }
 
-func doBatchGet(client *transport.HTTPClient, pattern, localDestDir string) {
+func doBatchGet(ctx context.Context, client *transport.HTTPClient, pattern, localDestDir string) {
 	// Parse pattern to get directory and filename pattern
 	dir := filepath.Dir(pattern)
 	filePattern := filepath.Base(pattern)
@@ -192,7 +318,7 @@ func doBatchGet(client *transport.HTTPClient, pattern, localDestDir string) {
 	}
 
 	// List files in remote directory
-	files, err := client.List(dir)
+	files, err := client.ListContext(ctx, dir)
 	if err != nil {
 		log.Fatalf("Failed to list remote directory: %v", err)
 	}
@@ -221,45 +347,76 @@ func doBatchGet(client *transport.HTTPClient, pattern, localDestDir string) {
 		localPath := filepath.Join(localDestDir, filename)
 
 		fmt.Printf("\n[%d/%d] ", i+1, len(matches))
-		downloadSingleFile(client, remotePath, localPath)
+		downloadSingleFile(ctx, client, remotePath, localPath)
 	}
 
 	fmt.Printf("\n✓ Downloaded %d files to %s\n", len(matches), localDestDir)
 }
 
-func downloadSingleFile(client *transport.HTTPClient, remotePath, localPath string) {
+func downloadSingleFile(ctx context.Context, client *transport.HTTPClient, remotePath, localPath string) {
+	if err := downloadFile(ctx, client, remotePath, localPath); err != nil {
+		if ctx.Err() != nil {
+			log.Fatalf("Download interrupted; run the same command again to resume")
+		}
+		log.Fatalf("Download failed: %v", err)
+	}
+}
+
+// downloadFile downloads remotePath into a .part file beside localPath, so an
+// interrupted transfer can be resumed by running the same command again, and
+// renames it into place once complete
+func downloadFile(ctx context.Context, client *transport.HTTPClient, remotePath, localPath string) error {
 	fmt.Printf("Downloading %s...\n", remotePath)
 
-	// For downloads, we don't have chunking yet, so just show a simple progress indicator
-	fmt.Print("Progress: ")
+	partPath := localPath + ".part"
+	if info, err := os.Stat(partPath); err == nil && info.Size() > 0 {
+		fmt.Printf("Resuming from %d bytes\n", info.Size())
+	}
 
-	data, err := client.Download(remotePath)
+	if err := client.DownloadResumeContext(ctx, remotePath, partPath); err != nil {
+		return err
+	}
+	if err := os.Rename(partPath, localPath); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	size, checksum, err := fileChecksum(localPath)
 	if err != nil {
-		log.Fatalf("Download failed: %v", err)
+		return fmt.Errorf("failed to verify file: %w", err)
 	}
 
-	// Simple progress animation during download
-	fmt.Print("████████████████████████████████████████████████████")
-	fmt.Printf("\n")
+	fmt.Printf("✓ Download complete: %s → %s (%d bytes, checksum: %s)\n", remotePath, localPath, size, checksum[:8])
+	return nil
+}
 
-	// Calculate checksum for verification
-	chunker := chunk.New(len(data))
-	chunks := chunker.Split(data)
-	var checksum string
-	if len(chunks) > 0 {
-		checksum = chunks[0].Checksum
+// fileChecksum returns the size and hex-encoded SHA-256 of a local file
+func fileChecksum(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
 	}
+	defer f.Close()
 
-	if err := os.WriteFile(localPath, data, 0644); err != nil {
-		log.Fatalf("Failed to write file: %v", err)
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return 0, "", err
 	}
-
-	fmt.Printf("✓ Download complete: %s → %s (%d bytes, checksum: %s)\n", remotePath, localPath, len(data), checksum[:8])
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
 }
 
-func doPut(client *transport.HTTPClient, args []string) {
+// putUsage is printed when put is given the wrong arguments
+const putUsage = "Usage: put [-r] [-x pattern]... [--delete-source [--verify]] [--checksum-only-resume] [--chunk-timeout d] [--timeout d] <local_path> <remote_path>"
+
+// doPut uploads files. timeout bounds the whole command unless --timeout
+// overrides it; zero means no limit.
+func doPut(ctx context.Context, client *transport.HTTPClient, args []string, timeout time.Duration) {
+	opts, args, err := parsePutFlags(args)
+	if err != nil {
+		log.Fatalf("Invalid arguments: %v", err)
+	}
 	if len(args) < 2 {
-		fmt.Println("Usage: put <local_path> <remote_path>")
+		fmt.Println(putUsage)
 		os.Exit(1)
 	}
 
@@ -268,12 +425,33 @@ func doPut(client *transport.HTTPClient, args []string) {
 	remotePath := strings.TrimSpace(strings.Join(args[1:], " "))
 
 	if remotePath == "" {
-		fmt.Println("Usage: put <local_path> <remote_path>")
+		fmt.Println(putUsage)
 		os.Exit(1)
 	}
 
+	if opts.ChunkTimeout > 0 {
+		client.SetChunkTimeout(opts.ChunkTimeout)
+	}
+	if opts.Timeout > 0 {
+		timeout = opts.Timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if opts.Recursive {
+		uploaded, err := putTree(ctx, client, localPattern, remotePath, opts)
+		if err != nil {
+			exitPut(ctx, err, timeout)
+		}
+		fmt.Printf("\n✓ Uploaded %d files from %s to %s\n", uploaded, localPattern, remotePath)
+		return
+	}
+
 	// Expand glob patterns
-	matches, err := glob.Expand([]string{localPattern})
+	matches, err := glob.ExpandWithExcludes([]string{localPattern}, opts.Excludes)
 	if err != nil {
 		log.Fatalf("Pattern expansion failed: %v", err)
 	}
@@ -282,6 +460,17 @@ func doPut(client *transport.HTTPClient, args []string) {
 		log.Fatalf("No files match pattern: %s", localPattern)
 	}
 
+	// Track the bytes sent across all the matched files
+	if len(matches) > 1 {
+		total := 0
+		for _, match := range matches {
+			if info, err := os.Stat(match.Path); err == nil {
+				total += int(info.Size())
+			}
+		}
+		opts.Progress = newBatchProgress(total)
+	}
+
 	// Upload each matched file
 	for i, match := range matches {
 		var targetPath string
@@ -292,8 +481,9 @@ func doPut(client *transport.HTTPClient, args []string) {
 			if !strings.HasSuffix(remotePath, "/") {
 				remotePath += "/"
 			}
-			// Use filename from match
-			targetPath = remotePath + filepath.Base(match.Path)
+			// Keep the match's path below the pattern's base directory, so
+			// files found by ** land in the same subdirectories remotely
+			targetPath = remotePath + filepath.ToSlash(match.RelPath)
 		} else {
 			// Single file - use remote path as-is
 			targetPath = remotePath
@@ -303,7 +493,9 @@ func doPut(client *transport.HTTPClient, args []string) {
 			fmt.Printf("\n[%d/%d] ", i+1, len(matches))
 		}
 
-		uploadSingleFile(client, match.Path, targetPath)
+		if err := putFile(ctx, client, match.Path, targetPath, opts); err != nil {
+			exitPut(ctx, err, timeout)
+		}
 	}
 
 	if len(matches) > 1 {
@@ -311,145 +503,405 @@ func doPut(client *transport.HTTPClient, args []string) {
 	}
 }
 
-func uploadSingleFile(client *transport.HTTPClient, localPath, remotePath string) {
-	// Read file data
-	data, err := os.ReadFile(localPath)
+// exitPut reports a failed upload and exits. Uploads stopped by Ctrl+C or the
+// time limit can be resumed, so the user is told how.
+func exitPut(ctx context.Context, err error, timeout time.Duration) {
+	var interrupted *interruptedUpload
+	if stderrors.As(err, &interrupted) {
+		fmt.Println()
+		fmt.Println(interrupted.Hint())
+	}
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		log.Fatalf("Upload did not finish within %v; run the same command again to resume", timeout)
+	case context.Canceled:
+		log.Fatalf("Upload interrupted; run the same command again to resume")
+	}
+	log.Fatalf("Upload failed: %v", err)
+}
+
+// uploadChunkSize is the size of each chunk sent by put
+const uploadChunkSize = 1024 * 1024 // 1MB chunks
+
+// minBarChunks is the fewest chunks a file needs for its upload to get a
+// progress bar; smaller files finish too quickly for one to be useful
+const minBarChunks = 4
+
+func uploadSingleFile(ctx context.Context, client *transport.HTTPClient, localPath, remotePath string, verifyResume bool, batch *batchProgress) error {
+	return uploadFile(ctx, client, localPath, remotePath, uploadChunkSize, verifyResume, batch)
+}
+
+// uploadFile uploads a local file in chunks of chunkSize bytes, reading one
+// chunk at a time so memory use doesn't grow with the file size. With
+// verifyResume, chunks an interrupted upload left on the server are compared
+// with the local file and any that differ are sent again. If batch is not
+// nil, the file's bytes are added to it as they are sent and the overall
+// progress is shown once the file is done.
+func uploadFile(ctx context.Context, client *transport.HTTPClient, localPath, remotePath string, chunkSize int, verifyResume bool, batch *batchProgress) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
 	if err != nil {
-		log.Fatalf("Failed to read file: %v", err)
+		return fmt.Errorf("failed to stat file: %w", err)
 	}
 
-	fileSize := len(data)
-	chunkSize := 1024 * 1024 // 1MB chunks
+	fileSize := int(info.Size())
+	totalChunks := chunk.Count(info.Size(), chunkSize)
+	chunks := chunk.NewReader(f, chunkSize)
 
 	// For small files, upload as single chunk without progress bar
-	if fileSize < chunkSize {
+	if totalChunks == 1 {
 		fmt.Printf("Uploading %s (%d bytes)...\n", filepath.Base(localPath), fileSize)
 
-		// Create chunker and generate checksum
-		chunker := chunk.New(chunkSize)
-		chunks := chunker.Split(data)
+		c, err := chunks.Next()
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
 
 		chunkData := transport.ChunkData{
 			Path:     remotePath,
 			ChunkID:  0,
-			Data:     data,
-			Checksum: chunks[0].Checksum,
+			Data:     c.Data,
+			Checksum: c.Checksum,
+			FileHash: c.Checksum, // the only chunk is the whole file
 			Total:    1,
 		}
 
-		if err := client.UploadChunk(chunkData); err != nil {
-			log.Fatalf("Upload failed: %v", err)
+		err = client.UploadChunkContext(ctx, chunkData)
+		if err != nil && abortStaleUpload(client, remotePath, err) {
+			err = client.UploadChunkContext(ctx, chunkData)
+		}
+		if err != nil {
+			return err
 		}
 
-		fmt.Printf("✓ Upload complete: %s → %s (%d bytes, checksum: %s)\n", filepath.Base(localPath), remotePath, fileSize, chunks[0].Checksum[:8])
-		return
+		fmt.Printf("✓ Upload complete: %s → %s (%d bytes, checksum: %s)\n", filepath.Base(localPath), remotePath, fileSize, c.Checksum[:8])
+		if batch != nil {
+			batch.add(fileSize)
+		}
+		printBatchProgress(batch)
+		return nil
 	}
 
-	// For larger files, use chunked upload with progress bar
-	totalChunks := (fileSize + chunkSize - 1) / chunkSize
+	// Hash the whole file up front so the server can verify the reassembled result
+	_, fileHash, err := fileChecksum(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash file: %w", err)
+	}
+
+	// Files of a few chunks are uploaded without a progress bar of their own
+	showBar := totalChunks >= minBarChunks
 	fmt.Printf("Uploading %s (%d bytes) in %d chunks...\n", filepath.Base(localPath), fileSize, totalChunks)
+	command := resumeCommand(localPath, remotePath, verifyResume)
+	if showBar {
+		fmt.Printf("This upload is resumable; if it is interrupted, run:\n  %s\n", command)
+	}
 
-	// Create chunker and split data with checksums
-	chunker := chunk.New(chunkSize)
-	chunks := chunker.Split(data)
+	// An unfinished upload of a different file at remotePath is discarded so
+	// this one can start over, once, in case another client keeps recreating it
+	for restarted := false; ; restarted = true {
+		counted, err := sendChunks(ctx, client, chunk.NewReader(f, chunkSize), remotePath, fileHash, fileSize, chunkSize, totalChunks, verifyResume, showBar, command, batch)
+		if err == nil {
+			break
+		}
+		if restarted || !abortStaleUpload(client, remotePath, err) {
+			return err
+		}
+		if batch != nil {
+			batch.add(-counted)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to rewind file: %w", err)
+		}
+	}
+
+	fmt.Printf("✓ Upload complete: %s → %s (%d bytes, verified)\n", filepath.Base(localPath), remotePath, fileSize)
+	printBatchProgress(batch)
+	return nil
+}
+
+// sendChunks sends the chunks of a file of totalChunks chunks read from
+// chunks that the server doesn't already have, then finalizes the upload
+// if every chunk was already there. It returns the bytes added to batch.
+func sendChunks(ctx context.Context, client *transport.HTTPClient, chunks *chunk.Reader, remotePath, fileHash string, fileSize, chunkSize, totalChunks int, verifyResume, showBar bool, command string, batch *batchProgress) (int, error) {
+	// Resume an interrupted upload by skipping the chunks the server already has
+	received, checksums := receivedChunks(client, remotePath, totalChunks, verifyResume)
+	remaining := fileSize
+	skipped := 0
+	for i, ok := range received {
+		if ok {
+			remaining -= chunkLen(i, fileSize, chunkSize)
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		fmt.Printf("Resuming upload: %d of %d chunks already on server\n", skipped, totalChunks)
+		if checksums != nil {
+			fmt.Println("Verifying the chunks on the server as they are read")
+		}
+	}
 
 	// Create progress bar and speed tracking
 	progressWidth := 50
-	startTime := time.Now()
+	progress := newUploadProgress(remaining)
+	hashSent := false
+	counted := 0
+
+	for i := 0; i < totalChunks; i++ {
+		c, err := chunks.Next()
+		if err == io.EOF {
+			return counted, fmt.Errorf("file changed during upload: expected %d chunks, read %d", totalChunks, i)
+		}
+		if err != nil {
+			return counted, fmt.Errorf("failed to read chunk %d: %w", i, err)
+		}
+		replace := false
+		if received[i] {
+			if checksums == nil || checksums[i] == c.Checksum {
+				if batch != nil {
+					batch.add(len(c.Data))
+					counted += len(c.Data)
+				}
+				continue
+			}
+			// The server's copy doesn't match the file, so overwrite it
+			fmt.Printf("\rChunk %d on the server is damaged, sending it again\n", c.ID)
+			progress.grow(len(c.Data))
+			replace = true
+		}
 
-	for i := 0; i < len(chunks); i++ {
 		chunkData := transport.ChunkData{
 			Path:     remotePath,
-			ChunkID:  chunks[i].ID,
-			Data:     chunks[i].Data,
-			Checksum: chunks[i].Checksum,
-			Total:    len(chunks),
+			ChunkID:  c.ID,
+			Data:     c.Data,
+			Checksum: c.Checksum,
+			Total:    totalChunks,
+			Replace:  replace,
 		}
-
-		if err := client.UploadChunk(chunkData); err != nil {
-			log.Fatalf("Upload failed: %v", err)
+		if !hashSent {
+			chunkData.FileHash = fileHash
+			hashSent = true
 		}
 
-		// Calculate speed and progress
-		elapsed := time.Since(startTime).Seconds()
-		progress := float64(i+1) / float64(len(chunks))
-		filled := int(progress * float64(progressWidth))
-
-		bar := ""
-		for j := 0; j < progressWidth; j++ {
-			if j < filled {
-				bar += "█"
-			} else {
-				bar += "░"
+		if err := client.UploadChunkContext(ctx, chunkData); err != nil {
+			return counted, &interruptedUpload{
+				RemotePath: remotePath,
+				Sent:       fileSize - progress.pending(),
+				Size:       fileSize,
+				Command:    command,
+				Err:        err,
 			}
 		}
+		progress.complete(c.ID, len(c.Data))
+		if batch != nil {
+			batch.add(len(c.Data))
+			counted += len(c.Data)
+		}
 
-		percentage := int(progress * 100)
-		uploaded := 0
-		for idx := 0; idx <= i; idx++ {
-			uploaded += len(chunks[idx].Data)
+		if showBar {
+			line := progress.render(progressWidth)
+			if batch != nil {
+				line += fmt.Sprintf(" | overall %d%%", int(batch.fraction()*100))
+			}
+			fmt.Printf("\r%s", line)
 		}
+	}
+	if showBar && skipped < totalChunks {
+		fmt.Printf("\n")
+	}
 
-		// Calculate and format speed
-		var speedStr string
-		if elapsed > 0 {
-			bytesPerSecond := float64(uploaded) / elapsed
-			speedStr = formatSpeed(bytesPerSecond)
-		} else {
-			speedStr = "calculating..."
+	// With nothing left to send, no chunk triggered the reassembly
+	if !hashSent {
+		if err := client.FinalizeUpload(remotePath); err != nil {
+			return counted, fmt.Errorf("failed to finalize upload: %w", err)
 		}
+	}
+	return counted, nil
+}
 
-		fmt.Printf("\r[%s] %d%% (%s) %s", bar, percentage, formatBytes(uploaded)+"/"+formatBytes(fileSize), speedStr)
+// abortStaleUpload reports whether err is the server refusing a chunk
+// because the unfinished upload it holds at remotePath is of a different
+// file, and if so aborts that upload so this one can start over
+func abortStaleUpload(client *transport.HTTPClient, remotePath string, err error) bool {
+	if !stderrors.Is(err, transport.ErrConflict) {
+		return false
+	}
+	// Other conflicts, such as a directory in the way, leave no session
+	status, qerr := client.QueryUploadStatus(remotePath)
+	if qerr != nil || !status.Exists {
+		return false
+	}
+	if err := client.AbortUpload(remotePath); err != nil {
+		fmt.Printf("Warning: could not discard the server's upload of %s: %v\n", remotePath, err)
+		return false
+	}
+	fmt.Printf("The server's unfinished upload of %s was of a different file; starting over\n", remotePath)
+	return true
+}
 
-		if i == len(chunks)-1 {
-			fmt.Printf("\n")
-		}
+// printBatchProgress shows the overall progress of a multi-file upload, if
+// there is one
+func printBatchProgress(batch *batchProgress) {
+	if batch != nil {
+		fmt.Printf("Overall: %s\n", batch.render(30))
 	}
+}
 
-	fmt.Printf("✓ Upload complete: %s → %s (%d bytes, verified)\n", filepath.Base(localPath), remotePath, fileSize)
+// receivedChunks asks the server which chunks of an interrupted upload to
+// remotePath it already holds. It returns all false when there is nothing to
+// resume or the server can't say. With withChecksums, it also returns the
+// checksum of each chunk as the server stored it, or nil if the server
+// doesn't report them.
+func receivedChunks(client *transport.HTTPClient, remotePath string, totalChunks int, withChecksums bool) ([]bool, []string) {
+	received := make([]bool, totalChunks)
+
+	query := client.QueryUploadStatus
+	if withChecksums {
+		query = client.QueryUploadChecksums
+	}
+	status, err := query(remotePath)
+	if err != nil {
+		fmt.Printf("Warning: could not check for an interrupted upload: %v\n", err)
+		return received, nil
+	}
+	if !status.Exists {
+		return received, nil
+	}
+	if status.TotalChunks != totalChunks || len(status.ReceivedMap) != totalChunks {
+		fmt.Printf("Warning: server has an upload of %s in %d chunks, not %d; it can't be resumed\n", remotePath, status.TotalChunks, totalChunks)
+		return received, nil
+	}
+	copy(received, status.ReceivedMap)
+
+	// The server doesn't report checksums of an upload only awaiting finalizing
+	if !withChecksums || status.Completed {
+		return received, nil
+	}
+	if len(status.Checksums) != totalChunks {
+		fmt.Println("Warning: server doesn't report chunk checksums; resuming without verifying them")
+		return received, nil
+	}
+	return received, status.Checksums
 }
 
-func doList(client *transport.HTTPClient, args []string) {
-	path := "/"
-	if len(args) > 0 {
-		joinedPath := strings.TrimSpace(strings.Join(args, " "))
-		if joinedPath != "" {
-			path = joinedPath
+// chunkLen returns the length of chunk i of a fileSize-byte file split into
+// chunkSize-byte chunks
+func chunkLen(i, fileSize, chunkSize int) int {
+	if end := (i + 1) * chunkSize; end < fileSize {
+		return chunkSize
+	}
+	return fileSize - i*chunkSize
+}
+
+func doList(ctx context.Context, client *transport.HTTPClient, args []string) {
+	long, human := false, false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch arg {
+		case "-l", "--long":
+			long = true
+		case "-h", "--human":
+			human = true
+		case "-lh", "-hl":
+			long, human = true, true
+		default:
+			rest = append(rest, arg)
 		}
 	}
 
-	files, err := client.List(path)
+	// With no path, list the base path (the storage root unless configured)
+	path := strings.TrimSpace(strings.Join(rest, " "))
+	shown := client.ResolvePath(path)
+	if shown == "" {
+		shown = "/"
+	}
+
+	if long {
+		entries, err := client.ListDetailedContext(ctx, path)
+		if err != nil {
+			log.Fatalf("List failed: %v", err)
+		}
+		if len(entries) == 0 {
+			fmt.Printf("No files in %s\n", shown)
+			return
+		}
+
+		fmt.Printf("Files in %s:\n", shown)
+		for _, line := range formatLongListing(entries, human) {
+			fmt.Println(line)
+		}
+		return
+	}
+
+	files, err := client.ListContext(ctx, path)
 	if err != nil {
 		log.Fatalf("List failed: %v", err)
 	}
 
 	if len(files) == 0 {
-		fmt.Printf("No files in %s\n", path)
+		fmt.Printf("No files in %s\n", shown)
 		return
 	}
 
-	fmt.Printf("Files in %s:\n", path)
+	fmt.Printf("Files in %s:\n", shown)
 	for _, file := range files {
 		fmt.Printf("  %s\n", file)
 	}
 }
 
-func doDiscover() {
-	fmt.Println("Discovering GoFlux servers on local network...")
+// formatLongListing formats an `ls -l` listing: a permissions placeholder
+// (the server doesn't report permissions) whose first letter marks
+// directories, the size, the modification time and the name, with
+// directories marked by a trailing /. Sizes are in bytes, or with human in
+// units from formatBytes, and are right-aligned to the widest one. A final
+// line totals the entries and the size of the files.
+func formatLongListing(entries []transport.ListEntry, human bool) []string {
+	sizes := make([]string, len(entries))
+	width := 0
+	files, dirs, total := 0, 0, int64(0)
+	for i, entry := range entries {
+		switch {
+		case entry.IsDir:
+			sizes[i] = "-"
+			dirs++
+		case human:
+			sizes[i] = formatBytes(int(entry.Size))
+		default:
+			sizes[i] = strconv.FormatInt(entry.Size, 10)
+		}
+		if !entry.IsDir {
+			files++
+			total += entry.Size
+		}
+		width = max(width, len(sizes[i]))
+	}
 
-	discovery := transport.NewDiscoveryClient()
-	servers, err := discovery.DiscoverServers()
-	if err != nil {
-		log.Fatalf("Discovery failed: %v", err)
+	lines := make([]string, 0, len(entries)+1)
+	for i, entry := range entries {
+		mode, name := "----------", entry.Name
+		if entry.IsDir {
+			mode, name = "d---------", name+"/"
+		}
+		lines = append(lines, fmt.Sprintf("  %s  %*s  %s  %s", mode, width, sizes[i], entry.ModTime.Local().Format("2006-01-02 15:04"), name))
 	}
 
-	fmt.Print(discovery.FormatServerList(servers))
+	totalSize := strconv.FormatInt(total, 10) + " bytes"
+	if human {
+		totalSize = formatBytes(int(total))
+	}
+	lines = append(lines, fmt.Sprintf("total %d (%d files, %d directories), %s", len(entries), files, dirs, totalSize))
+	return lines
 }
 
 func doConfig(args []string) {
 	if len(args) < 1 {
 		fmt.Println("Usage: config <server_address>")
 		fmt.Println("Example: config 192.168.1.100:8080")
+		fmt.Println("         config https://192.168.1.100:8443")
 		os.Exit(1)
 	}
 
@@ -462,14 +914,29 @@ func doConfig(args []string) {
 		log.Fatalf("Failed to get server config: %v", err)
 	}
 
+	// Use the scheme the server advertises so TLS servers get an https:// URL
+	scheme := "http"
+	if advertised, ok := config["scheme"].(string); ok && advertised != "" {
+		scheme = advertised
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(serverAddr, "http://"), "https://")
+	authEnabled, _ := config["auth_enabled"].(bool)
+
 	// Create goflux.json configuration
-	clientConfig := map[string]interface{}{
-		"client": map[string]interface{}{
-			"server_url": fmt.Sprintf("http://%s", serverAddr),
-			"chunk_size": 1048576,
-			"token":      "", // User must set this manually if auth is required
-		},
+	settings := map[string]interface{}{
+		"server_url": transport.ServerURL(scheme, host),
+		"chunk_size": 1048576,
+		"token":      "", // User must set this manually if auth is required
+	}
+	if authEnabled {
+		settings["auth_required"] = true
+	}
+	if serverConfig, ok := config["server"].(map[string]interface{}); ok {
+		if maxFileSize, ok := serverConfig["max_file_size"].(float64); ok && maxFileSize > 0 {
+			settings["max_file_size"] = int64(maxFileSize)
+		}
 	}
+	clientConfig := map[string]interface{}{"client": settings}
 
 	// Write configuration to file
 	configJSON, err := json.MarshalIndent(clientConfig, "", "  ")
@@ -485,14 +952,29 @@ func doConfig(args []string) {
 	fmt.Printf("✓ Configuration saved to %s\n", configPath)
 
 	// Show auth info if required
-	if serverConfig, ok := config["server"].(map[string]interface{}); ok {
-		if authEnabled, ok := serverConfig["auth_enabled"].(bool); ok && authEnabled {
-			fmt.Println()
-			fmt.Println("⚠️  This server requires authentication.")
-			fmt.Println("   Set GOFLUX_TOKEN_LITE environment variable or edit goflux.json")
-			fmt.Println("   Contact the server administrator for a token.")
-		}
+	if authEnabled {
+		fmt.Println()
+		fmt.Println("⚠️  This server requires authentication.")
+		fmt.Println("   Set GOFLUX_TOKEN_LITE environment variable or edit goflux.json")
+		fmt.Println("   Contact the server administrator for a token.")
+	}
+}
+
+// doConfigShow prints the settings the client is using
+func doConfigShow(cfg *config.Config, client *transport.HTTPClient, token string) {
+	base := "/ (storage root)"
+	if client.BasePath() != "" {
+		base = client.BasePath() + "/"
 	}
+	auth := "not set"
+	if token != "" {
+		auth = "set"
+	}
+
+	fmt.Printf("Server:     %s\n", client.BaseURL)
+	fmt.Printf("Base path:  %s\n", base)
+	fmt.Printf("Chunk size: %s\n", formatBytes(cfg.Client.ChunkSize))
+	fmt.Printf("Token:      %s\n", auth)
 }
 
 func executableDir() string {
@@ -548,44 +1030,70 @@ func formatSpeed(bytesPerSecond float64) string {
 	return fmt.Sprintf("%.1f %cB/s", bytesPerSecond/div, "KMGTPE"[exp])
 }
 
-func doDelete(client *transport.HTTPClient, args []string) {
-	if len(args) < 1 {
-		fmt.Println("Usage: rm <path>")
-		os.Exit(1)
+func doMkdir(client *transport.HTTPClient, args []string) {
+	parents := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "-p" || arg == "--parents" {
+			parents = true
+			continue
+		}
+		rest = append(rest, arg)
 	}
 
-	path := strings.TrimSpace(strings.Join(args, " "))
+	path := strings.TrimSpace(strings.Join(rest, " "))
 	if path == "" {
-		fmt.Println("Usage: rm <path>")
+		fmt.Println("Usage: mkdir [-p] <path>")
 		os.Exit(1)
 	}
-	fmt.Printf("Deleting %s...\n", path)
+	fmt.Printf("Creating directory %s...\n", path)
 
-	if err := client.Delete(path); err != nil {
-		log.Fatalf("Delete failed: %v", err)
+	if err := client.Mkdir(path, parents); err != nil {
+		log.Fatalf("Mkdir failed: %v", err)
 	}
 
-	fmt.Printf("✓ Successfully deleted: %s\n", path)
+	fmt.Printf("✓ Successfully created directory: %s\n", path)
 }
 
-func doMkdir(client *transport.HTTPClient, args []string) {
-	if len(args) < 1 {
-		fmt.Println("Usage: mkdir <path>")
+func doMove(client *transport.HTTPClient, args []string) {
+	overwrite := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "-f" || arg == "--force" || arg == "--overwrite" {
+			overwrite = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	if len(rest) < 2 {
+		fmt.Println("Usage: mv [-f] <remote_src> <remote_dst>")
 		os.Exit(1)
 	}
+	src := rest[0]
+	dst := strings.TrimSpace(strings.Join(rest[1:], " "))
+	fmt.Printf("Moving %s to %s...\n", src, dst)
 
-	path := strings.TrimSpace(strings.Join(args, " "))
-	if path == "" {
-		fmt.Println("Usage: mkdir <path>")
+	if err := client.Move(src, dst, overwrite); err != nil {
+		log.Fatalf("Move failed: %v", err)
+	}
+
+	fmt.Printf("✓ Successfully moved %s to %s\n", src, dst)
+}
+
+func doPublish(client *transport.HTTPClient, args []string) {
+	if len(args) < 2 {
+		fmt.Println("Usage: publish <staged_name> <remote_path>")
 		os.Exit(1)
 	}
-	fmt.Printf("Creating directory %s...\n", path)
+	name := args[0]
+	remotePath := strings.TrimSpace(strings.Join(args[1:], " "))
+	fmt.Printf("Publishing %s to %s...\n", name, remotePath)
 
-	if err := client.Mkdir(path); err != nil {
-		log.Fatalf("Mkdir failed: %v", err)
+	if err := client.Publish(name, remotePath); err != nil {
+		log.Fatalf("Publish failed: %v", err)
 	}
 
-	fmt.Printf("✓ Successfully created directory: %s\n", path)
+	fmt.Printf("✓ Successfully published %s to %s\n", name, remotePath)
 }
 
 func resolvePutPaths(args []string) (string, string) {
@@ -618,85 +1126,3 @@ func resolvePutPaths(args []string) (string, string) {
 	remotePath := strings.TrimSpace(trimmed[len(trimmed)-1])
 	return localPath, remotePath
 }
-
-func doUpdate(args []string) {
-	useLocal := false
-	for _, arg := range args {
-		if arg == "--local" || arg == "-l" {
-			useLocal = true
-		}
-	}
-
-	fmt.Println("Checking for updates...")
-
-	// Create updater
-	upd := updater.New(currentVersion, updateManifestURL)
-
-	// If local flag is set, try to use local server
-	if useLocal {
-		// Load config to get server URL
-		configPath := filepath.Join(executableDir(), "goflux.json")
-		if cfg, err := loadConfig(configPath); err == nil && cfg.Client.ServerURL != "" {
-			upd.SetLocalServer(cfg.Client.ServerURL)
-			fmt.Printf("Using local update server: %s\n", cfg.Client.ServerURL)
-		}
-	}
-
-	// Check for updates
-	manifest, err := upd.CheckForUpdate()
-	if err != nil {
-		log.Fatalf("Update check failed: %v", err)
-	}
-
-	if manifest == nil {
-		fmt.Printf("You are already running the latest version (%s)\n", currentVersion)
-		return
-	}
-
-	fmt.Printf("Update available: %s → %s\n", currentVersion, manifest.Version)
-	fmt.Printf("Released: %s\n", manifest.ReleaseDate)
-	if manifest.Notes != "" {
-		fmt.Printf("\nRelease notes:\n%s\n\n", manifest.Notes)
-	}
-
-	// Confirm update
-	fmt.Print("Do you want to install this update? (y/N): ")
-	var response string
-	fmt.Scanln(&response)
-	if strings.ToLower(response) != "y" {
-		fmt.Println("Update cancelled")
-		return
-	}
-
-	// Download update with progress
-	fmt.Println("Downloading update...")
-	progressBar := 50
-	var lastProgress int
-
-	downloadPath, err := upd.DownloadUpdate(manifest, func(downloaded, total int64) {
-		if total == 0 {
-			return
-		}
-		progress := int((float64(downloaded) / float64(total)) * float64(progressBar))
-		if progress != lastProgress {
-			bar := strings.Repeat("█", progress) + strings.Repeat("░", progressBar-progress)
-			fmt.Printf("\r[%s] %3d%%", bar, int((float64(downloaded)/float64(total))*100))
-			lastProgress = progress
-		}
-	})
-
-	if err != nil {
-		log.Fatalf("Download failed: %v", err)
-	}
-	fmt.Println()
-
-	// Install update
-	fmt.Println("Installing update...")
-	if err := upd.Install(downloadPath); err != nil {
-		log.Fatalf("Installation failed: %v\n\nYou can try running the update again.", err)
-	}
-
-	fmt.Printf("\n✓ Update installed successfully!\n")
-	fmt.Println("Please restart gfl to use the new version.")
-	fmt.Println("\nA backup of the previous version was saved as gfl.exe.backup")
-}
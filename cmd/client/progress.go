@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// uploadProgress tracks how many bytes of a chunked upload have been
+// confirmed by the server. Progress is measured in completed bytes rather than
+// the index of the last chunk sent, so it only moves forward when chunks finish
+// out of order, and a retried chunk is only counted once.
+type uploadProgress struct {
+	total int
+
+	mu        sync.Mutex
+	done      map[int]bool
+	completed int
+	rate      *rateEstimator
+}
+
+// newUploadProgress starts tracking an upload of total bytes
+func newUploadProgress(total int) *uploadProgress {
+	return &uploadProgress{
+		total: total,
+		done:  make(map[int]bool),
+		rate:  newRateEstimator(speedWindow, time.Now()),
+	}
+}
+
+// complete records that chunk id, holding n bytes, was accepted by the server
+// and returns the number of bytes completed so far. Completing the same chunk
+// again has no effect.
+func (p *uploadProgress) complete(id, n int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.done[id] {
+		p.done[id] = true
+		p.completed += n
+		p.rate.observe(time.Now(), p.completed)
+	}
+	return p.completed
+}
+
+// grow adds n bytes to the upload, for data that turned out to need sending
+// after all
+func (p *uploadProgress) grow(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.total += n
+}
+
+// pending returns the number of bytes still to be confirmed by the server
+func (p *uploadProgress) pending() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.total - p.completed
+}
+
+// fraction returns the share of the upload that has completed, from 0 to 1
+func (p *uploadProgress) fraction() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return progressFraction(p.completed, p.total)
+}
+
+// render formats the progress as a bar width characters wide, followed by the
+// percentage, byte counts, speed and estimated time remaining
+func (p *uploadProgress) render(width int) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return renderProgress(p.completed, p.total, p.rate.rate(time.Now()), width)
+}
+
+// batchProgress tracks the bytes sent across every file of a multi-file
+// upload. Data an interrupted attempt already left on the server counts as
+// sent, so the total reaches 100% exactly when the last file completes.
+type batchProgress struct {
+	total int
+
+	mu        sync.Mutex
+	completed int
+	rate      *rateEstimator
+}
+
+// newBatchProgress starts tracking an upload of files totalling total bytes
+func newBatchProgress(total int) *batchProgress {
+	return &batchProgress{total: total, rate: newRateEstimator(speedWindow, time.Now())}
+}
+
+// add records n more bytes as sent and returns the number sent so far
+func (b *batchProgress) add(n int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.completed += n
+	b.rate.observe(time.Now(), b.completed)
+	return b.completed
+}
+
+// fraction returns the share of the batch that has been sent, from 0 to 1
+func (b *batchProgress) fraction() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return progressFraction(b.completed, b.total)
+}
+
+// render formats the batch's progress like uploadProgress.render
+func (b *batchProgress) render(width int) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return renderProgress(b.completed, b.total, b.rate.rate(time.Now()), width)
+}
+
+// progressFraction returns completed as a share of total, treating an empty
+// total as done
+func progressFraction(completed, total int) float64 {
+	if total <= 0 {
+		return 1
+	}
+	return float64(completed) / float64(total)
+}
+
+// renderProgress draws a bar width characters wide for completed of total
+// bytes, followed by the percentage, byte counts, and the speed and the time
+// remaining at that speed
+func renderProgress(completed, total int, bytesPerSecond float64, width int) string {
+	fraction := progressFraction(completed, total)
+	filled := int(fraction * float64(width))
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+
+	speedStr, etaStr := "calculating...", ""
+	if bytesPerSecond > 0 && completed > 0 {
+		speedStr = formatSpeed(bytesPerSecond)
+		remaining := time.Duration(float64(total-completed) / bytesPerSecond * float64(time.Second))
+		etaStr = fmt.Sprintf(" ETA %v", remaining.Round(time.Second))
+	}
+
+	return fmt.Sprintf("[%s] %d%% (%s) %s%s", bar, int(fraction*100), formatBytes(completed)+"/"+formatBytes(total), speedStr, etaStr)
+}
+
+// defaultSpeedWindow is how far back transfer speeds are measured unless the
+// configuration says otherwise
+const defaultSpeedWindow = 10 * time.Second
+
+// speedWindow is how far back the speed and ETA shown for transfers are
+// measured; zero measures from the start of the transfer
+var speedWindow = defaultSpeedWindow
+
+// rateEstimator measures transfer speed over a sliding window of recent
+// progress, so the speed and ETA follow the current rate instead of the
+// average since the start, which lags behind after a slow or fast start.
+// Times are passed in so estimates can be checked against fixed sequences.
+type rateEstimator struct {
+	window  time.Duration // zero averages over the whole transfer
+	samples []rateSample  // oldest first
+}
+
+// rateSample records the bytes transferred in total by a moment in time
+type rateSample struct {
+	at    time.Time
+	total int
+}
+
+// newRateEstimator starts measuring a transfer that began at start and
+// averages its speed over window, or over the whole transfer if window is
+// zero
+func newRateEstimator(window time.Duration, start time.Time) *rateEstimator {
+	return &rateEstimator{window: window, samples: []rateSample{{at: start}}}
+}
+
+// observe records that total bytes had been transferred by at
+func (e *rateEstimator) observe(at time.Time, total int) {
+	e.samples = append(e.samples, rateSample{at: at, total: total})
+	e.trim(at)
+}
+
+// trim drops samples no longer needed at now, keeping the newest one from
+// before the window began as the baseline the rate is measured from
+func (e *rateEstimator) trim(now time.Time) {
+	if e.window <= 0 {
+		// Only the start and the latest sample matter
+		if len(e.samples) > 2 {
+			e.samples = append(e.samples[:1], e.samples[len(e.samples)-1])
+		}
+		return
+	}
+	cutoff := now.Add(-e.window)
+	drop := 0
+	for drop+1 < len(e.samples) && !e.samples[drop+1].at.After(cutoff) {
+		drop++
+	}
+	e.samples = e.samples[drop:]
+}
+
+// rate returns the transfer speed in bytes per second at now. Time without
+// progress counts, so the rate falls while a transfer is stalled.
+func (e *rateEstimator) rate(now time.Time) float64 {
+	e.trim(now)
+	first, last := e.samples[0], e.samples[len(e.samples)-1]
+	elapsed := now.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(last.total-first.total) / elapsed
+}
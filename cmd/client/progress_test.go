@@ -0,0 +1,138 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUploadProgress_OutOfOrderCompletions(t *testing.T) {
+	const chunkSize = 100
+	sizes := []int{chunkSize, chunkSize, chunkSize, chunkSize, 40}
+	total := 4*chunkSize + 40
+	p := newUploadProgress(total)
+
+	// Chunks finish out of order, and chunks 1 and 4 are retried after the
+	// server already accepted them
+	order := []int{2, 0, 4, 1, 1, 3, 4}
+	last := 0.0
+	for _, id := range order {
+		p.complete(id, sizes[id])
+		got := p.fraction()
+		if got < last {
+			t.Fatalf("progress went backwards after chunk %d: %v < %v", id, got, last)
+		}
+		if got > 1 {
+			t.Fatalf("progress exceeded 100%% after chunk %d: %v", id, got)
+		}
+		last = got
+	}
+
+	if last != 1 {
+		t.Errorf("expected progress to end at 100%%, got %v", last)
+	}
+	if line := p.render(10); !strings.Contains(line, "100%") || !strings.Contains(line, "440 B/440 B") {
+		t.Errorf("unexpected final progress line: %q", line)
+	}
+}
+
+func TestUploadProgress_Concurrent(t *testing.T) {
+	const chunks = 64
+	p := newUploadProgress(chunks * 10)
+
+	var wg sync.WaitGroup
+	for i := 0; i < chunks; i++ {
+		for attempt := 0; attempt < 2; attempt++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				p.complete(id, 10)
+			}(i)
+		}
+	}
+	wg.Wait()
+
+	if completed := p.complete(0, 10); completed != chunks*10 {
+		t.Errorf("expected %d bytes completed, got %d", chunks*10, completed)
+	}
+}
+
+// feedRate reports progress to e once a second from start, at each of the
+// given rates in bytes per second for the given number of seconds, and
+// returns the time of the last report
+func feedRate(e *rateEstimator, start time.Time, phases ...[2]int) time.Time {
+	now, total := start, 0
+	for _, phase := range phases {
+		rate, seconds := phase[0], phase[1]
+		for i := 0; i < seconds; i++ {
+			now = now.Add(time.Second)
+			total += rate
+			e.observe(now, total)
+		}
+	}
+	return now
+}
+
+func TestRateEstimator_TracksRecentRate(t *testing.T) {
+	start := time.Now()
+
+	tests := []struct {
+		name     string
+		phases   [][2]int
+		windowed float64
+		lifetime float64
+	}{
+		// 2000 bytes in 20s, then 10000 in 10s: 400 B/s overall
+		{"slow start", [][2]int{{100, 20}, {1000, 10}}, 1000, 400},
+		// 10000 bytes in 10s, then 2000 in 20s: 400 B/s overall
+		{"fast start", [][2]int{{1000, 10}, {100, 20}}, 100, 400},
+		{"steady", [][2]int{{500, 30}}, 500, 500},
+	}
+	for _, tt := range tests {
+		windowed := newRateEstimator(10*time.Second, start)
+		now := feedRate(windowed, start, tt.phases...)
+		if got := windowed.rate(now); math.Abs(got-tt.windowed) > 1 {
+			t.Errorf("%s: expected windowed rate %v, got %v", tt.name, tt.windowed, got)
+		}
+
+		lifetime := newRateEstimator(0, start)
+		now = feedRate(lifetime, start, tt.phases...)
+		if got := lifetime.rate(now); math.Abs(got-tt.lifetime) > 1 {
+			t.Errorf("%s: expected lifetime rate %v, got %v", tt.name, tt.lifetime, got)
+		}
+	}
+}
+
+func TestRateEstimator_Stall(t *testing.T) {
+	start := time.Now()
+	e := newRateEstimator(10*time.Second, start)
+	now := feedRate(e, start, [2]int{1000, 10})
+
+	// Halfway through the window without progress, the rate has halved
+	if got := e.rate(now.Add(5 * time.Second)); math.Abs(got-500) > 1 {
+		t.Errorf("expected 500 B/s five seconds into a stall, got %v", got)
+	}
+	if got := e.rate(now.Add(30 * time.Second)); got != 0 {
+		t.Errorf("expected 0 B/s after a stall longer than the window, got %v", got)
+	}
+}
+
+func TestRateEstimator_NoProgress(t *testing.T) {
+	start := time.Now()
+	e := newRateEstimator(10*time.Second, start)
+	if got := e.rate(start); got != 0 {
+		t.Errorf("expected 0 B/s at the start, got %v", got)
+	}
+	if line := renderProgress(0, 100, e.rate(start.Add(time.Second)), 10); !strings.Contains(line, "calculating...") {
+		t.Errorf("expected no speed before any progress, got %q", line)
+	}
+}
+
+func TestRenderProgress_ETA(t *testing.T) {
+	line := renderProgress(500, 1500, 100, 10)
+	if !strings.Contains(line, formatSpeed(100)) || !strings.Contains(line, "ETA 10s") {
+		t.Errorf("expected ETA 10s at 100 B/s with 1000 bytes left, got %q", line)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/glob"
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// putOptions controls what happens to local files after they are uploaded
+type putOptions struct {
+	DeleteSource bool // remove the local file once the server has confirmed the upload
+	Verify       bool // compare the server copy's hash with the local file before removing it
+	VerifyResume bool // check the chunks an interrupted upload left on the server before resuming it
+	Recursive    bool // upload a whole directory tree, recreating its structure on the server
+
+	Excludes []string // patterns of files and directories to leave out
+
+	ChunkTimeout time.Duration // limit on each attempt to upload a chunk; zero keeps the configured one
+	Timeout      time.Duration // limit on the whole command; zero keeps the configured one
+
+	Progress *batchProgress // overall progress of a multi-file upload; nil for a single file
+}
+
+// parsePutFlags extracts put options from the arguments, returning the
+// remaining arguments. -x/--exclude takes a pattern and may be repeated;
+// --chunk-timeout and --timeout take a duration such as 30s or 2h.
+func parsePutFlags(args []string) (putOptions, []string, error) {
+	var opts putOptions
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if pattern, ok := strings.CutPrefix(arg, "--exclude="); ok {
+			opts.Excludes = append(opts.Excludes, pattern)
+			continue
+		}
+		switch arg {
+		case "-x", "--exclude", "-exclude":
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("%s requires a pattern", arg)
+			}
+			i++
+			opts.Excludes = append(opts.Excludes, args[i])
+		case "--chunk-timeout", "-chunk-timeout", "--timeout", "-timeout":
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("%s requires a duration", arg)
+			}
+			i++
+			d, err := time.ParseDuration(args[i])
+			if err != nil || d <= 0 {
+				return opts, nil, fmt.Errorf("%s requires a positive duration such as 30s or 2h, got %q", arg, args[i])
+			}
+			if strings.TrimLeft(arg, "-") == "chunk-timeout" {
+				opts.ChunkTimeout = d
+			} else {
+				opts.Timeout = d
+			}
+		case "--delete-source", "-delete-source":
+			opts.DeleteSource = true
+		case "--verify", "-verify":
+			opts.Verify = true
+		case "--checksum-only-resume", "-checksum-only-resume":
+			opts.VerifyResume = true
+		case "-r", "--recursive", "-recursive":
+			opts.Recursive = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return opts, rest, nil
+}
+
+// putFile uploads a local file and, if requested, deletes it afterwards. The
+// source is never removed unless every chunk was acknowledged by the server
+// and, with Verify, the server copy hashes the same as the local file.
+func putFile(ctx context.Context, client *transport.HTTPClient, localPath, remotePath string, opts putOptions) error {
+	if err := uploadSingleFile(ctx, client, localPath, remotePath, opts.VerifyResume, opts.Progress); err != nil {
+		return err
+	}
+
+	if !opts.DeleteSource {
+		return nil
+	}
+
+	if opts.Verify {
+		if err := verifyUpload(ctx, client, localPath, remotePath); err != nil {
+			return fmt.Errorf("keeping %s: %w", localPath, err)
+		}
+	}
+
+	if err := os.Remove(localPath); err != nil {
+		return fmt.Errorf("failed to delete source: %w", err)
+	}
+	fmt.Printf("✓ Removed source: %s\n", localPath)
+	return nil
+}
+
+// putTree uploads every file beneath the local directory root to the same
+// relative path under remoteRoot, creating empty directories with mkdir so the
+// whole structure is recreated on the server. It returns the number of files
+// uploaded. Entries that aren't regular files or directories, such as
+// symlinks, are skipped, as is anything whose path below root matches one of
+// opts.Excludes.
+func putTree(ctx context.Context, client *transport.HTTPClient, root, remoteRoot string, opts putOptions) (int, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return 0, fmt.Errorf("%s is not a directory", root)
+	}
+
+	uploaded := 0
+	err = filepath.WalkDir(root, func(localPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		rel, err := filepath.Rel(root, localPath)
+		if err != nil {
+			return err
+		}
+		remotePath := path.Join(remoteRoot, filepath.ToSlash(rel))
+
+		if rel != "." {
+			excluded, err := glob.Excluded(rel, opts.Excludes)
+			if err != nil {
+				return err
+			}
+			if excluded {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		switch {
+		case d.IsDir():
+			entries, err := os.ReadDir(localPath)
+			if err != nil {
+				return err
+			}
+			// Directories with files are created by uploading them
+			if len(entries) == 0 {
+				if err := client.Mkdir(remotePath, true); err != nil {
+					return fmt.Errorf("failed to create %s: %w", remotePath, err)
+				}
+				fmt.Printf("✓ Created directory: %s\n", remotePath)
+			}
+		case d.Type().IsRegular():
+			if err := putFile(ctx, client, localPath, remotePath, opts); err != nil {
+				return err
+			}
+			uploaded++
+		default:
+			fmt.Printf("Skipping %s: not a regular file\n", localPath)
+		}
+		return nil
+	})
+	return uploaded, err
+}
+
+// verifyUpload downloads the remote file and checks it matches the local file
+func verifyUpload(ctx context.Context, client *transport.HTTPClient, localPath, remotePath string) error {
+	local, err := os.ReadFile(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	remote, err := client.DownloadContext(ctx, remotePath)
+	if err != nil {
+		return fmt.Errorf("verification download failed: %w", err)
+	}
+
+	if sha256.Sum256(local) != sha256.Sum256(remote) {
+		return fmt.Errorf("verification failed: server copy of %s does not match", remotePath)
+	}
+	return nil
+}
+
+// resumeCommand returns the gfl command that resumes an upload of localPath
+// to remotePath, quoting arguments that a shell would split
+func resumeCommand(localPath, remotePath string, verifyResume bool) string {
+	args := []string{"gfl", "put"}
+	if verifyResume {
+		args = append(args, "--checksum-only-resume")
+	}
+	for _, arg := range []string{localPath, remotePath} {
+		if arg == "" || strings.ContainsAny(arg, " \t'\"&|;<>()$`*?[]{}") {
+			arg = `"` + arg + `"`
+		}
+		args = append(args, arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// interruptedUpload is returned when a chunked upload stops part way. The
+// chunks the server accepted are kept, so Command picks up where it stopped.
+type interruptedUpload struct {
+	RemotePath string
+	Sent       int // bytes of the file the server holds
+	Size       int
+	Command    string
+	Err        error
+}
+
+func (e *interruptedUpload) Error() string { return e.Err.Error() }
+
+func (e *interruptedUpload) Unwrap() error { return e.Err }
+
+// Hint tells the user how far the upload got and how to resume it
+func (e *interruptedUpload) Hint() string {
+	return fmt.Sprintf("Upload of %s stopped at %d%% (%s of %s on the server); resume with:\n  %s",
+		e.RemotePath, int(progressFraction(e.Sent, e.Size)*100), formatBytes(e.Sent), formatBytes(e.Size), e.Command)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// writeSource creates a local file to upload
+func writeSource(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "source.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write source: %v", err)
+	}
+	return path
+}
+
+func TestParsePutFlags(t *testing.T) {
+	opts, rest, err := parsePutFlags([]string{"--delete-source", "a.txt", "--verify", "remote/a.txt"})
+	if err != nil {
+		t.Fatalf("parsePutFlags failed: %v", err)
+	}
+	if !opts.DeleteSource || !opts.Verify {
+		t.Errorf("expected both options set, got %+v", opts)
+	}
+	if len(rest) != 2 || rest[0] != "a.txt" || rest[1] != "remote/a.txt" {
+		t.Errorf("unexpected remaining args: %v", rest)
+	}
+}
+
+func TestParsePutFlags_Excludes(t *testing.T) {
+	opts, rest, err := parsePutFlags([]string{"-x", "*.tmp", "src", "--exclude", "node_modules", "--exclude=build/**", "dst"})
+	if err != nil {
+		t.Fatalf("parsePutFlags failed: %v", err)
+	}
+	want := []string{"*.tmp", "node_modules", "build/**"}
+	if len(opts.Excludes) != len(want) {
+		t.Fatalf("expected excludes %v, got %v", want, opts.Excludes)
+	}
+	for i := range want {
+		if opts.Excludes[i] != want[i] {
+			t.Errorf("expected excludes %v, got %v", want, opts.Excludes)
+		}
+	}
+	if len(rest) != 2 || rest[0] != "src" || rest[1] != "dst" {
+		t.Errorf("unexpected remaining args: %v", rest)
+	}
+
+	if _, _, err := parsePutFlags([]string{"src", "dst", "-x"}); err == nil {
+		t.Error("expected error for -x without a pattern")
+	}
+}
+
+func TestParsePutFlags_Timeouts(t *testing.T) {
+	opts, rest, err := parsePutFlags([]string{"--chunk-timeout", "30s", "big.iso", "--timeout", "2h", "isos/"})
+	if err != nil {
+		t.Fatalf("parsePutFlags failed: %v", err)
+	}
+	if opts.ChunkTimeout != 30*time.Second || opts.Timeout != 2*time.Hour {
+		t.Errorf("expected chunk timeout 30s and timeout 2h, got %v and %v", opts.ChunkTimeout, opts.Timeout)
+	}
+	if len(rest) != 2 || rest[0] != "big.iso" || rest[1] != "isos/" {
+		t.Errorf("unexpected remaining args: %v", rest)
+	}
+
+	for _, args := range [][]string{{"a", "b", "--timeout"}, {"--timeout", "soon", "a", "b"}, {"--chunk-timeout", "0s", "a", "b"}} {
+		if _, _, err := parsePutFlags(args); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
+
+func TestResumeCommand(t *testing.T) {
+	tests := []struct {
+		local, remote string
+		verify        bool
+		want          string
+	}{
+		{"big.iso", "backups/big.iso", false, "gfl put big.iso backups/big.iso"},
+		{"my files/big.iso", "backups/big copy.iso", false, `gfl put "my files/big.iso" "backups/big copy.iso"`},
+		{"big.iso", "backups/", true, "gfl put --checksum-only-resume big.iso backups/"},
+	}
+	for _, tt := range tests {
+		if got := resumeCommand(tt.local, tt.remote, tt.verify); got != tt.want {
+			t.Errorf("resumeCommand(%q, %q, %v) = %q, want %q", tt.local, tt.remote, tt.verify, got, tt.want)
+		}
+	}
+}
+
+func TestPutFile_DeleteSource(t *testing.T) {
+	stub, ts := newStubServer(t)
+	client := transport.NewHTTPClient(ts.URL)
+	source := writeSource(t, "move me")
+
+	if err := putFile(context.Background(), client, source, "moved.txt", putOptions{DeleteSource: true}); err != nil {
+		t.Fatalf("putFile failed: %v", err)
+	}
+
+	if _, err := os.Stat(source); !os.IsNotExist(err) {
+		t.Error("expected source to be deleted after upload")
+	}
+	if string(stub.files["moved.txt"]) != "move me" {
+		t.Errorf("expected remote content, got %q", stub.files["moved.txt"])
+	}
+}
+
+func TestPutFile_KeepsSourceByDefault(t *testing.T) {
+	_, ts := newStubServer(t)
+	source := writeSource(t, "keep me")
+
+	if err := putFile(context.Background(), transport.NewHTTPClient(ts.URL), source, "kept.txt", putOptions{}); err != nil {
+		t.Fatalf("putFile failed: %v", err)
+	}
+	if _, err := os.Stat(source); err != nil {
+		t.Errorf("expected source to remain: %v", err)
+	}
+}
+
+func TestPutFile_FailedUploadKeepsSource(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "disk full", http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+	source := writeSource(t, "precious")
+
+	if err := putFile(context.Background(), transport.NewHTTPClient(ts.URL), source, "x.txt", putOptions{DeleteSource: true}); err == nil {
+		t.Fatal("expected upload error")
+	}
+	if _, err := os.Stat(source); err != nil {
+		t.Errorf("expected source to remain after failed upload: %v", err)
+	}
+}
+
+func TestPutFile_VerifyGatesDeletion(t *testing.T) {
+	t.Run("matching hash deletes", func(t *testing.T) {
+		_, ts := newStubServer(t)
+		source := writeSource(t, "verified")
+
+		opts := putOptions{DeleteSource: true, Verify: true}
+		if err := putFile(context.Background(), transport.NewHTTPClient(ts.URL), source, "v.txt", opts); err != nil {
+			t.Fatalf("putFile failed: %v", err)
+		}
+		if _, err := os.Stat(source); !os.IsNotExist(err) {
+			t.Error("expected source to be deleted after verification")
+		}
+	})
+
+	t.Run("mismatched hash keeps source", func(t *testing.T) {
+		stub, ts := newStubServer(t)
+		stub.corrupt = true
+		source := writeSource(t, "verified")
+
+		opts := putOptions{DeleteSource: true, Verify: true}
+		if err := putFile(context.Background(), transport.NewHTTPClient(ts.URL), source, "v.txt", opts); err == nil {
+			t.Fatal("expected verification error")
+		}
+		if _, err := os.Stat(source); err != nil {
+			t.Errorf("expected source to remain after failed verification: %v", err)
+		}
+	})
+}
+
+func TestPutTree_RecreatesStructure(t *testing.T) {
+	stub, ts := newStubServer(t)
+	root := t.TempDir()
+	files := map[string]string{
+		"index.html":          "<html>",
+		"css/site.css":        "body {}",
+		"img/icons/logo.svg":  "<svg>",
+		"docs/guide/intro.md": "# Intro",
+	}
+	for rel, content := range files {
+		local := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(local, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, "img", "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	uploaded, err := putTree(context.Background(), transport.NewHTTPClient(ts.URL), root, "sites/web", putOptions{})
+	if err != nil {
+		t.Fatalf("putTree failed: %v", err)
+	}
+	if uploaded != len(files) {
+		t.Errorf("expected %d files uploaded, got %d", len(files), uploaded)
+	}
+	for rel, content := range files {
+		if got, ok := stub.files["sites/web/"+rel]; !ok || string(got) != content {
+			t.Errorf("expected %q at sites/web/%s, got %q (present: %v)", content, rel, got, ok)
+		}
+	}
+	if len(stub.files) != len(files) {
+		t.Errorf("unexpected extra files: %v", stub.files)
+	}
+	if len(stub.dirs) != 1 || stub.dirs[0] != "sites/web/img/empty" {
+		t.Errorf("expected only the empty directory to be created, got %v", stub.dirs)
+	}
+}
+
+func TestPutTree_RequiresDirectory(t *testing.T) {
+	_, ts := newStubServer(t)
+	source := writeSource(t, "not a dir")
+
+	if _, err := putTree(context.Background(), transport.NewHTTPClient(ts.URL), source, "x", putOptions{}); err == nil {
+		t.Error("expected error for a file source")
+	}
+}
+
+func TestPutTree_Excludes(t *testing.T) {
+	stub, ts := newStubServer(t)
+	root := t.TempDir()
+	for _, rel := range []string{"app.js", "app.tmp", "lib/util.js", "node_modules/dep/index.js"} {
+		local := filepath.Join(root, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(local), 0755)
+		os.WriteFile(local, []byte(rel), 0644)
+	}
+
+	opts := putOptions{Excludes: []string{"*.tmp", "node_modules"}}
+	uploaded, err := putTree(context.Background(), transport.NewHTTPClient(ts.URL), root, "app", opts)
+	if err != nil {
+		t.Fatalf("putTree failed: %v", err)
+	}
+	if uploaded != 2 {
+		t.Errorf("expected 2 files uploaded, got %d", uploaded)
+	}
+	for _, name := range []string{"app/app.js", "app/lib/util.js"} {
+		if _, ok := stub.files[name]; !ok {
+			t.Errorf("expected %s to be uploaded", name)
+		}
+	}
+	if len(stub.files) != 2 || len(stub.dirs) != 0 {
+		t.Errorf("expected excluded files and directories to be skipped, got files %d, dirs %v", len(stub.files), stub.dirs)
+	}
+}
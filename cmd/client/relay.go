@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/chunk"
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// parseServerPath splits a relay argument of the form <server>:<path>. The
+// path follows the last colon, so the server may include a port and scheme
+// but the path can't contain a colon.
+func parseServerPath(arg string) (server, remotePath string, err error) {
+	i := strings.LastIndex(arg, ":")
+	if i <= 0 {
+		return "", "", fmt.Errorf("expected <server>:<path>, got %q", arg)
+	}
+	server, remotePath = arg[:i], arg[i+1:]
+	if server == "http" || server == "https" {
+		return "", "", fmt.Errorf("expected <server>:<path>, got %q", arg)
+	}
+	return server, remotePath, nil
+}
+
+// relayOptions holds the flags accepted by relay
+type relayOptions struct {
+	Recursive bool   // relay a directory tree
+	SrcToken  string // token for the source server
+	DstToken  string // token for the destination server
+}
+
+// parseRelayFlags extracts relay options from the arguments, returning the remaining arguments
+func parseRelayFlags(args []string) (relayOptions, []string, error) {
+	var opts relayOptions
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "-r", "--recursive":
+			opts.Recursive = true
+		case "--src-token", "-src-token", "--dst-token", "-dst-token":
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("%s requires a token", arg)
+			}
+			i++
+			if strings.TrimLeft(arg, "-") == "src-token" {
+				opts.SrcToken = args[i]
+			} else {
+				opts.DstToken = args[i]
+			}
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return opts, rest, nil
+}
+
+func doRelay(ctx context.Context, client *transport.HTTPClient, args []string) {
+	opts, rest, err := parseRelayFlags(args)
+	if err != nil {
+		log.Fatalf("Relay failed: %v", err)
+	}
+	if len(rest) != 2 {
+		fmt.Println("Usage: relay [-r] [--src-token <token>] [--dst-token <token>] <src-server>:<path> <dst-server>:<path>")
+		os.Exit(1)
+	}
+
+	srcServer, srcPath, err := parseServerPath(rest[0])
+	if err != nil {
+		log.Fatalf("Invalid source: %v", err)
+	}
+	dstServer, dstPath, err := parseServerPath(rest[1])
+	if err != nil {
+		log.Fatalf("Invalid destination: %v", err)
+	}
+	// The configured token goes only to the configured server; others need
+	// their own
+	src, dst := client.WithServer(srcServer), client.WithServer(dstServer)
+	if opts.SrcToken != "" {
+		src.SetAuthToken(opts.SrcToken)
+	}
+	if opts.DstToken != "" {
+		dst.SetAuthToken(opts.DstToken)
+	}
+
+	if opts.Recursive {
+		relayed, err := relayTree(ctx, src, dst, srcPath, dstPath, uploadChunkSize)
+		if err != nil {
+			log.Fatalf("Relay failed: %v", err)
+		}
+		fmt.Printf("\n✓ Relayed %d files from %s to %s\n", relayed, rest[0], rest[1])
+		return
+	}
+
+	if err := relayFile(ctx, src, dst, srcPath, dstPath, uploadChunkSize); err != nil {
+		log.Fatalf("Relay failed: %v", err)
+	}
+}
+
+// relayFile copies srcPath on src to dstPath on dst, uploading each chunk as
+// soon as it has been downloaded so that no more than one chunk is held in
+// memory and nothing is written to local disk
+func relayFile(ctx context.Context, src, dst *transport.HTTPClient, srcPath, dstPath string, chunkSize int) error {
+	stat, err := src.Stat(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", srcPath, err)
+	}
+	if stat.IsDir {
+		return fmt.Errorf("%s is a directory; use -r to relay directories", srcPath)
+	}
+
+	totalChunks := chunk.Count(stat.Size, chunkSize)
+	fmt.Printf("Relaying %s (%d bytes) in %d chunks...\n", srcPath, stat.Size, totalChunks)
+
+	body, err := src.OpenDownload(ctx, srcPath)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	chunks := chunk.NewReader(body, chunkSize)
+	for i := 0; i < totalChunks; i++ {
+		c, err := chunks.Next()
+		if err == io.EOF {
+			return fmt.Errorf("source ended early: expected %d chunks, read %d", totalChunks, i)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read chunk %d: %w", i, err)
+		}
+
+		// The file hash isn't known until the last chunk has passed through, so
+		// the destination relies on the per-chunk checksums
+		chunkData := transport.ChunkData{
+			Path:     dstPath,
+			ChunkID:  i,
+			Data:     c.Data,
+			Checksum: c.Checksum,
+			Total:    totalChunks,
+		}
+		if err := dst.UploadChunkContext(ctx, chunkData); err != nil {
+			return fmt.Errorf("failed to upload chunk %d: %w", i, err)
+		}
+	}
+	if _, err := chunks.Next(); err != io.EOF {
+		return fmt.Errorf("source is larger than the %d bytes it reported", stat.Size)
+	}
+
+	fmt.Printf("✓ Relay complete: %s → %s (%d bytes)\n", srcPath, dstPath, stat.Size)
+	return nil
+}
+
+// relayTree relays every file beneath the directory srcRoot on src to the
+// same relative path under dstRoot on dst, creating empty directories with
+// mkdir. It returns the number of files relayed.
+func relayTree(ctx context.Context, src, dst *transport.HTTPClient, srcRoot, dstRoot string, chunkSize int) (int, error) {
+	stat, err := src.Stat(srcRoot)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat %s: %w", srcRoot, err)
+	}
+	if !stat.IsDir {
+		return 0, fmt.Errorf("%s is not a directory", srcRoot)
+	}
+	return relayDir(ctx, src, dst, srcRoot, dstRoot, chunkSize)
+}
+
+// relayDir relays the contents of one directory for relayTree
+func relayDir(ctx context.Context, src, dst *transport.HTTPClient, srcDir, dstDir string, chunkSize int) (int, error) {
+	entries, err := src.ListDetailedContext(ctx, srcDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list %s: %w", srcDir, err)
+	}
+	if len(entries) == 0 {
+		if err := dst.Mkdir(dstDir, true); err != nil {
+			return 0, fmt.Errorf("failed to create %s: %w", dstDir, err)
+		}
+		return 0, nil
+	}
+
+	relayed := 0
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return relayed, ctx.Err()
+		}
+		if err := checkEntryName(srcDir, entry.Name); err != nil {
+			return relayed, err
+		}
+		srcPath, dstPath := path.Join(srcDir, entry.Name), path.Join(dstDir, entry.Name)
+		if entry.IsDir {
+			n, err := relayDir(ctx, src, dst, srcPath, dstPath, chunkSize)
+			relayed += n
+			if err != nil {
+				return relayed, err
+			}
+			continue
+		}
+		if err := relayFile(ctx, src, dst, srcPath, dstPath, chunkSize); err != nil {
+			return relayed, err
+		}
+		relayed++
+	}
+	return relayed, nil
+}
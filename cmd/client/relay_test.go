@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+func TestParseServerPath(t *testing.T) {
+	tests := []struct {
+		arg    string
+		server string
+		path   string
+		ok     bool
+	}{
+		{"192.168.1.5:8080:docs/a.txt", "192.168.1.5:8080", "docs/a.txt", true},
+		{"https://files.example.com:backups", "https://files.example.com", "backups", true},
+		{"old-server:", "old-server", "", true},
+		{"docs/a.txt", "", "", false},
+		{":docs", "", "", false},
+		{"http://", "", "", false},
+	}
+	for _, tt := range tests {
+		server, path, err := parseServerPath(tt.arg)
+		if (err == nil) != tt.ok {
+			t.Errorf("%q: expected ok=%v, got %v", tt.arg, tt.ok, err)
+			continue
+		}
+		if server != tt.server || path != tt.path {
+			t.Errorf("%q: expected %q and %q, got %q and %q", tt.arg, tt.server, tt.path, server, path)
+		}
+	}
+}
+
+func TestParseRelayFlags(t *testing.T) {
+	opts, rest, err := parseRelayFlags([]string{"-r", "--src-token", "a", "src:x", "--dst-token", "b", "dst:y"})
+	if err != nil {
+		t.Fatalf("parseRelayFlags failed: %v", err)
+	}
+	if !opts.Recursive || opts.SrcToken != "a" || opts.DstToken != "b" {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+	if len(rest) != 2 || rest[0] != "src:x" || rest[1] != "dst:y" {
+		t.Errorf("unexpected remaining args: %v", rest)
+	}
+	if _, _, err := parseRelayFlags([]string{"src:x", "dst:y", "--dst-token"}); err == nil {
+		t.Error("expected an error for a missing token")
+	}
+}
+
+func TestRelayFile_CopiesBetweenServers(t *testing.T) {
+	srcStub, srcServer := newStubServer(t)
+	dstStub, dstServer := newStubServer(t)
+	content := bytes.Repeat([]byte("relay me "), 1000) // 9000 bytes
+	srcStub.files["data/big.bin"] = content
+
+	src := transport.NewHTTPClient(srcServer.URL)
+	if err := relayFile(context.Background(), src, src.WithServer(dstServer.URL), "data/big.bin", "copy/big.bin", 1024); err != nil {
+		t.Fatalf("relayFile failed: %v", err)
+	}
+	if got := dstStub.files["copy/big.bin"]; !bytes.Equal(got, content) {
+		t.Errorf("expected %d bytes at destination, got %d", len(content), len(got))
+	}
+	if len(dstStub.accepted) != 9 {
+		t.Errorf("expected 9 chunks uploaded, got %d", len(dstStub.accepted))
+	}
+}
+
+func TestRelayFile_Streams(t *testing.T) {
+	dstStub, dstServer := newStubServer(t)
+	const chunkSize = 1024
+	content := bytes.Repeat([]byte("x"), 8*chunkSize)
+
+	// The source sends two chunks and then waits until the destination has
+	// received the first, which only happens if the relay doesn't wait for
+	// the whole file before uploading
+	var streamed atomic.Bool
+	srcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stat":
+			json.NewEncoder(w).Encode(transport.StatResponse{Path: "big.bin", Size: int64(len(content))})
+		case "/download":
+			w.Header().Set("Content-Length", "8192")
+			w.Write(content[:2*chunkSize])
+			w.(http.Flusher).Flush()
+			deadline := time.Now().Add(5 * time.Second)
+			for time.Now().Before(deadline) && !streamed.Load() {
+				dstStub.mu.Lock()
+				streamed.Store(len(dstStub.accepted) > 0)
+				dstStub.mu.Unlock()
+				time.Sleep(5 * time.Millisecond)
+			}
+			w.Write(content[2*chunkSize:])
+		}
+	}))
+	defer srcServer.Close()
+
+	src := transport.NewHTTPClient(srcServer.URL)
+	if err := relayFile(context.Background(), src, src.WithServer(dstServer.URL), "big.bin", "big.bin", chunkSize); err != nil {
+		t.Fatalf("relayFile failed: %v", err)
+	}
+	if !streamed.Load() {
+		t.Error("expected chunks to be uploaded while the download was still in progress")
+	}
+	if !bytes.Equal(dstStub.files["big.bin"], content) {
+		t.Error("destination content mismatch")
+	}
+}
+
+func TestRelayTree(t *testing.T) {
+	srcStub, srcServer := newStubServer(t)
+	dstStub, dstServer := newStubServer(t)
+	srcStub.files["site/index.html"] = []byte("<html>")
+	srcStub.files["site/css/main.css"] = []byte("body {}")
+	srcStub.files["other.txt"] = []byte("not relayed")
+
+	src := transport.NewHTTPClient(srcServer.URL)
+	relayed, err := relayTree(context.Background(), src, src.WithServer(dstServer.URL), "site", "mirror/site", 1024)
+	if err != nil {
+		t.Fatalf("relayTree failed: %v", err)
+	}
+	if relayed != 2 {
+		t.Errorf("expected 2 files relayed, got %d", relayed)
+	}
+	if string(dstStub.files["mirror/site/index.html"]) != "<html>" || string(dstStub.files["mirror/site/css/main.css"]) != "body {}" {
+		t.Errorf("unexpected destination files: %v", dstStub.files)
+	}
+	if len(dstStub.files) != 2 {
+		t.Errorf("expected only the tree to be relayed, got %v", dstStub.files)
+	}
+}
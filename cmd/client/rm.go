@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// rmOptions controls what rm is allowed to delete
+type rmOptions struct {
+	Recursive bool // allow deleting a directory and everything in it
+	DryRun    bool // report what would be deleted without deleting it
+	Yes       bool // delete a directory without asking first
+}
+
+// parseRmFlags extracts rm options from the arguments, returning the remaining arguments
+func parseRmFlags(args []string) (rmOptions, []string) {
+	var opts rmOptions
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch arg {
+		case "-r", "-R", "--recursive", "-recursive":
+			opts.Recursive = true
+		case "--dry-run", "-dry-run", "-n":
+			opts.DryRun = true
+		case "-y", "--yes", "-yes":
+			opts.Yes = true
+		case "-rf", "-fr", "-ry", "-yr":
+			opts.Recursive, opts.Yes = true, true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return opts, rest
+}
+
+// rmImpact is everything deleting a remote path removes
+type rmImpact struct {
+	Path  string
+	IsDir bool
+	Files []transport.ListEntry // files removed, named by their full remote path
+	Dirs  int                   // directories removed, including Path itself
+	Size  int64                 // total size of Files
+}
+
+func doDelete(ctx context.Context, client *transport.HTTPClient, args []string) {
+	opts, args := parseRmFlags(args)
+	remotePath := strings.TrimSpace(strings.Join(args, " "))
+	if remotePath == "" {
+		fmt.Println("Usage: rm [-r] [--dry-run] [-y] <path>")
+		os.Exit(1)
+	}
+
+	impact, err := planDelete(ctx, client, remotePath, opts)
+	if err != nil {
+		log.Fatalf("Delete failed: %v", err)
+	}
+
+	if opts.DryRun {
+		fmt.Printf("Would delete %s:\n", remotePath)
+		for _, line := range formatImpact(impact) {
+			fmt.Println(line)
+		}
+		fmt.Println("\nNothing was deleted")
+		return
+	}
+
+	// Deleting a directory can't be undone, so show what goes with it first
+	if impact.IsDir && !opts.Yes {
+		fmt.Printf("Deleting %s removes:\n", remotePath)
+		for _, line := range formatImpact(impact) {
+			fmt.Println(line)
+		}
+		if !confirm(os.Stdin, "\nDelete all of this? (y/N): ") {
+			fmt.Println("Delete cancelled")
+			return
+		}
+	}
+	fmt.Printf("Deleting %s...\n", remotePath)
+
+	if err := client.Delete(remotePath); err != nil {
+		log.Fatalf("Delete failed: %v", err)
+	}
+
+	fmt.Printf("✓ Successfully deleted: %s\n", remotePath)
+}
+
+// planDelete works out what deleting remotePath would remove, listing a
+// directory's contents recursively. Directories are refused unless
+// opts.Recursive is set.
+func planDelete(ctx context.Context, client *transport.HTTPClient, remotePath string, opts rmOptions) (*rmImpact, error) {
+	stat, err := client.Stat(remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", remotePath, err)
+	}
+
+	impact := &rmImpact{Path: remotePath, IsDir: stat.IsDir}
+	if !stat.IsDir {
+		impact.Files = []transport.ListEntry{{Name: remotePath, Size: stat.Size, ModTime: stat.ModTime}}
+		impact.Size = stat.Size
+		return impact, nil
+	}
+	if !opts.Recursive {
+		return nil, fmt.Errorf("%s is a directory; use -r to delete it and everything in it", remotePath)
+	}
+
+	if err := impact.addDir(ctx, client, remotePath); err != nil {
+		return nil, err
+	}
+	return impact, nil
+}
+
+// addDir adds the contents of one remote directory to the impact for planDelete
+func (m *rmImpact) addDir(ctx context.Context, client *transport.HTTPClient, dir string) error {
+	entries, err := client.ListDetailedContext(ctx, dir)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	m.Dirs++
+
+	for _, entry := range entries {
+		entryPath := path.Join(dir, entry.Name)
+		if entry.IsDir {
+			if err := m.addDir(ctx, client, entryPath); err != nil {
+				return err
+			}
+			continue
+		}
+		entry.Name = entryPath
+		m.Files = append(m.Files, entry)
+		m.Size += entry.Size
+	}
+	return nil
+}
+
+// formatImpact lists each file an rm would remove with its size, followed
+// by a line totalling the files, directories and bytes
+func formatImpact(impact *rmImpact) []string {
+	lines := make([]string, 0, len(impact.Files)+1)
+	for _, file := range impact.Files {
+		lines = append(lines, fmt.Sprintf("  %s (%s)", file.Name, formatBytes(int(file.Size))))
+	}
+	lines = append(lines, fmt.Sprintf("%d files, %d directories, %s", len(impact.Files), impact.Dirs, formatBytes(int(impact.Size))))
+	return lines
+}
+
+// confirm prints prompt and reports whether the answer read from in is yes
+func confirm(in io.Reader, prompt string) bool {
+	fmt.Print(prompt)
+	var response string
+	fmt.Fscanln(in, &response)
+	response = strings.ToLower(strings.TrimSpace(response))
+	return response == "y" || response == "yes"
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseRmFlags(t *testing.T) {
+	opts, rest := parseRmFlags([]string{"-r", "--dry-run", "old", "site"})
+	if !opts.Recursive || !opts.DryRun || opts.Yes {
+		t.Errorf("unexpected options %+v", opts)
+	}
+	if len(rest) != 2 || rest[0] != "old" || rest[1] != "site" {
+		t.Errorf("unexpected remaining args: %v", rest)
+	}
+
+	if opts, _ := parseRmFlags([]string{"-rf"}); !opts.Recursive || !opts.Yes {
+		t.Errorf("expected -rf to set both options, got %+v", opts)
+	}
+}
+
+func TestPlanDelete_ListsDescendants(t *testing.T) {
+	_, client := newTreeServer(t)
+
+	impact, err := planDelete(context.Background(), client, "site", rmOptions{Recursive: true})
+	if err != nil {
+		t.Fatalf("planDelete failed: %v", err)
+	}
+
+	var names []string
+	for _, file := range impact.Files {
+		names = append(names, file.Name)
+	}
+	want := []string{"site/css/main.css", "site/img/icons/logo.svg", "site/index.html"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("expected files %v, got %v", want, names)
+	}
+	if !impact.IsDir || impact.Dirs != 4 || impact.Size != int64(len("body {}")+len("<svg/>")+len("<html>")) {
+		t.Errorf("unexpected impact %+v", impact)
+	}
+
+	lines := formatImpact(impact)
+	if got := lines[len(lines)-1]; got != "3 files, 4 directories, 19 B" {
+		t.Errorf("unexpected totals line %q", got)
+	}
+}
+
+func TestPlanDelete_DirectoryNeedsRecursive(t *testing.T) {
+	_, client := newTreeServer(t)
+
+	if _, err := planDelete(context.Background(), client, "site", rmOptions{}); err == nil || !strings.Contains(err.Error(), "-r") {
+		t.Errorf("expected a directory refused without -r, got %v", err)
+	}
+
+	impact, err := planDelete(context.Background(), client, "site/index.html", rmOptions{})
+	if err != nil {
+		t.Fatalf("expected a file deletable without -r: %v", err)
+	}
+	if impact.IsDir || len(impact.Files) != 1 || impact.Size != 6 {
+		t.Errorf("unexpected impact %+v", impact)
+	}
+}
+
+func TestDoDelete_DryRunDeletesNothing(t *testing.T) {
+	stub, client := newTreeServer(t)
+
+	doDelete(context.Background(), client, []string{"-r", "--dry-run", "site"})
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if len(stub.deleted) != 0 {
+		t.Errorf("expected nothing deleted by a dry run, got %v", stub.deleted)
+	}
+	if len(stub.files) != 4 {
+		t.Errorf("expected every file kept, got %d", len(stub.files))
+	}
+}
+
+func TestDoDelete_Recursive(t *testing.T) {
+	stub, client := newTreeServer(t)
+
+	doDelete(context.Background(), client, []string{"-r", "-y", "site"})
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if len(stub.files) != 1 || stub.files["other/skip.txt"] == nil {
+		t.Errorf("expected only the tree deleted, left %v", stub.files)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	for answer, want := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false} {
+		if got := confirm(strings.NewReader(answer), ""); got != want {
+			t.Errorf("confirm(%q) = %v, want %v", answer, got, want)
+		}
+	}
+}
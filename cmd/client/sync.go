@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// syncOptions controls how sync decides what to change on the server
+type syncOptions struct {
+	Checksum bool // compare the SHA-256 of files whose sizes match
+	Delete   bool // remove remote files that no longer exist locally
+	DryRun   bool // print the planned actions without performing them
+}
+
+// parseSyncFlags extracts sync options from the arguments, returning the remaining arguments
+func parseSyncFlags(args []string) (syncOptions, []string) {
+	var opts syncOptions
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch arg {
+		case "--checksum", "-checksum", "-c":
+			opts.Checksum = true
+		case "--delete", "-delete":
+			opts.Delete = true
+		case "--dry-run", "-dry-run", "-n":
+			opts.DryRun = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return opts, rest
+}
+
+// syncAction is a single change sync makes on the server. Path is relative to
+// the roots being synced.
+type syncAction struct {
+	Op     string // "upload" or "delete"
+	Path   string
+	Reason string
+}
+
+func doSync(ctx context.Context, client *transport.HTTPClient, args []string) {
+	opts, args := parseSyncFlags(args)
+	if len(args) != 2 {
+		fmt.Println("Usage: sync [--checksum] [--delete] [--dry-run] <local_dir> <remote_dir>")
+		os.Exit(1)
+	}
+	localRoot, remoteRoot := args[0], args[1]
+
+	actions, err := planSync(ctx, client, localRoot, remoteRoot, opts)
+	if err != nil {
+		log.Fatalf("Sync failed: %v", err)
+	}
+	if len(actions) == 0 {
+		fmt.Printf("✓ %s is up to date\n", remoteRoot)
+		return
+	}
+
+	if opts.DryRun {
+		for _, action := range actions {
+			fmt.Printf("Would %s %s (%s)\n", action.Op, path.Join(remoteRoot, action.Path), action.Reason)
+		}
+		fmt.Printf("\n%d changes planned; nothing was changed\n", len(actions))
+		return
+	}
+
+	uploaded, deleted, err := runSync(ctx, client, localRoot, remoteRoot, actions)
+	if err != nil {
+		if ctx.Err() != nil {
+			log.Fatalf("Upload interrupted; run the same command again to resume")
+		}
+		log.Fatalf("Sync failed: %v", err)
+	}
+	fmt.Printf("\n✓ Synced %s to %s: %d uploaded, %d deleted\n", localRoot, remoteRoot, uploaded, deleted)
+}
+
+// planSync compares the files beneath the local directory localRoot with
+// those beneath remoteRoot and returns the actions needed to make the remote
+// tree match. New files and files whose size differs are uploaded; with
+// opts.Checksum, files of the same size are downloaded and hashed as well.
+// Remote files missing locally are deleted only with opts.Delete. A remote
+// root that doesn't exist yet is treated as empty.
+func planSync(ctx context.Context, client *transport.HTTPClient, localRoot, remoteRoot string, opts syncOptions) ([]syncAction, error) {
+	local, err := localSizes(localRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := make(map[string]int64)
+	if err := remoteSizes(ctx, client, remoteRoot, "", remote); err != nil && !stderrors.Is(err, transport.ErrNotFound) {
+		return nil, err
+	}
+
+	var actions []syncAction
+	for _, rel := range sortedKeys(local) {
+		remoteSize, exists := remote[rel]
+		switch {
+		case !exists:
+			actions = append(actions, syncAction{Op: "upload", Path: rel, Reason: "new"})
+		case remoteSize != local[rel]:
+			actions = append(actions, syncAction{Op: "upload", Path: rel, Reason: "size changed"})
+		case opts.Checksum:
+			same, err := sameContent(ctx, client, filepath.Join(localRoot, filepath.FromSlash(rel)), path.Join(remoteRoot, rel))
+			if err != nil {
+				return nil, err
+			}
+			if !same {
+				actions = append(actions, syncAction{Op: "upload", Path: rel, Reason: "checksum changed"})
+			}
+		}
+	}
+
+	if opts.Delete {
+		for _, rel := range sortedKeys(remote) {
+			if _, ok := local[rel]; !ok {
+				actions = append(actions, syncAction{Op: "delete", Path: rel, Reason: "missing locally"})
+			}
+		}
+	}
+	return actions, nil
+}
+
+// runSync performs the actions planned by planSync, returning the number of
+// files uploaded and deleted
+func runSync(ctx context.Context, client *transport.HTTPClient, localRoot, remoteRoot string, actions []syncAction) (uploaded, deleted int, err error) {
+	batch := syncProgress(localRoot, actions)
+	for _, action := range actions {
+		if ctx.Err() != nil {
+			return uploaded, deleted, ctx.Err()
+		}
+		remotePath := path.Join(remoteRoot, action.Path)
+
+		switch action.Op {
+		case "upload":
+			localPath := filepath.Join(localRoot, filepath.FromSlash(action.Path))
+			if err := uploadSingleFile(ctx, client, localPath, remotePath, false, batch); err != nil {
+				return uploaded, deleted, err
+			}
+			uploaded++
+		case "delete":
+			if err := client.Delete(remotePath); err != nil {
+				return uploaded, deleted, fmt.Errorf("failed to delete %s: %w", remotePath, err)
+			}
+			fmt.Printf("✓ Deleted: %s\n", remotePath)
+			deleted++
+		}
+	}
+	return uploaded, deleted, nil
+}
+
+// syncProgress returns a batchProgress covering the files the actions
+// upload, or nil if there are fewer than two
+func syncProgress(localRoot string, actions []syncAction) *batchProgress {
+	files, total := 0, 0
+	for _, action := range actions {
+		if action.Op != "upload" {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(localRoot, filepath.FromSlash(action.Path))); err == nil {
+			files++
+			total += int(info.Size())
+		}
+	}
+	if files < 2 {
+		return nil
+	}
+	return newBatchProgress(total)
+}
+
+// localSizes returns the size of every regular file beneath root, keyed by
+// its slash-separated path relative to root
+func localSizes(root string) (map[string]int64, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	sizes := make(map[string]int64)
+	err = filepath.WalkDir(root, func(localPath string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, localPath)
+		if err != nil {
+			return err
+		}
+		sizes[filepath.ToSlash(rel)] = info.Size()
+		return nil
+	})
+	return sizes, err
+}
+
+// remoteSizes records the size of every file beneath the remote directory
+// dir in sizes, keyed by its path relative to the directory sync started from
+func remoteSizes(ctx context.Context, client *transport.HTTPClient, dir, rel string, sizes map[string]int64) error {
+	entries, err := client.ListDetailedContext(ctx, dir)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if err := checkEntryName(dir, entry.Name); err != nil {
+			return err
+		}
+		entryRel := path.Join(rel, entry.Name)
+		if entry.IsDir {
+			if err := remoteSizes(ctx, client, path.Join(dir, entry.Name), entryRel, sizes); err != nil {
+				return err
+			}
+			continue
+		}
+		sizes[entryRel] = entry.Size
+	}
+	return nil
+}
+
+// sameContent reports whether the remote file has the same SHA-256 as the
+// local one. The server doesn't expose file hashes, so the remote copy is
+// downloaded to compute it.
+func sameContent(ctx context.Context, client *transport.HTTPClient, localPath, remotePath string) (bool, error) {
+	_, localHash, err := fileChecksum(localPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash %s: %w", localPath, err)
+	}
+	data, err := client.DownloadContext(ctx, remotePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to download %s for comparison: %w", remotePath, err)
+	}
+	remoteHash := sha256.Sum256(data)
+	return hex.EncodeToString(remoteHash[:]) == localHash, nil
+}
+
+// sortedKeys returns the keys of m in order, so sync acts on files predictably
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
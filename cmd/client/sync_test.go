@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+func TestParseSyncFlags(t *testing.T) {
+	opts, rest := parseSyncFlags([]string{"--delete", "site", "--dry-run", "www", "--checksum"})
+	if !opts.Delete || !opts.DryRun || !opts.Checksum {
+		t.Errorf("expected all options set, got %+v", opts)
+	}
+	if len(rest) != 2 || rest[0] != "site" || rest[1] != "www" {
+		t.Errorf("unexpected remaining args: %v", rest)
+	}
+}
+
+// newSyncFixture returns a local tree and a stub server whose copy of it has
+// one changed file, one stale file and is missing one new file
+func newSyncFixture(t *testing.T) (*stubServer, *transport.HTTPClient, string) {
+	t.Helper()
+	local := t.TempDir()
+	os.MkdirAll(filepath.Join(local, "css"), 0755)
+	os.WriteFile(filepath.Join(local, "index.html"), []byte("<html>"), 0644)
+	os.WriteFile(filepath.Join(local, "css", "main.css"), []byte("body { color: red }"), 0644)
+	os.WriteFile(filepath.Join(local, "about.html"), []byte("about us"), 0644)
+
+	stub, ts := newStubServer(t)
+	stub.files["www/index.html"] = []byte("<html>")
+	stub.files["www/css/main.css"] = []byte("body {}")
+	stub.files["www/old.html"] = []byte("removed locally")
+	return stub, transport.NewHTTPClient(ts.URL), local
+}
+
+func TestPlanSync(t *testing.T) {
+	_, client, local := newSyncFixture(t)
+
+	actions, err := planSync(context.Background(), client, local, "www", syncOptions{Delete: true})
+	if err != nil {
+		t.Fatalf("planSync failed: %v", err)
+	}
+
+	want := []syncAction{
+		{Op: "upload", Path: "about.html", Reason: "new"},
+		{Op: "upload", Path: "css/main.css", Reason: "size changed"},
+		{Op: "delete", Path: "old.html", Reason: "missing locally"},
+	}
+	if len(actions) != len(want) {
+		t.Fatalf("expected actions %+v, got %+v", want, actions)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Errorf("action %d: expected %+v, got %+v", i, want[i], actions[i])
+		}
+	}
+
+	// Without --delete, remote-only files are left alone
+	actions, _ = planSync(context.Background(), client, local, "www", syncOptions{})
+	for _, action := range actions {
+		if action.Op == "delete" {
+			t.Errorf("unexpected delete without --delete: %+v", action)
+		}
+	}
+}
+
+func TestPlanSync_Checksum(t *testing.T) {
+	stub, client, local := newSyncFixture(t)
+	stub.files["www/index.html"] = []byte("<HTML>") // same size, different content
+
+	actions, err := planSync(context.Background(), client, local, "www", syncOptions{})
+	if err != nil {
+		t.Fatalf("planSync failed: %v", err)
+	}
+	for _, action := range actions {
+		if action.Path == "index.html" {
+			t.Errorf("expected same-size file to be skipped without --checksum, got %+v", action)
+		}
+	}
+
+	actions, err = planSync(context.Background(), client, local, "www", syncOptions{Checksum: true})
+	if err != nil {
+		t.Fatalf("planSync failed: %v", err)
+	}
+	found := false
+	for _, action := range actions {
+		if action.Path == "index.html" && action.Reason == "checksum changed" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected index.html to be uploaded with --checksum, got %+v", actions)
+	}
+}
+
+func TestRunSync_MirrorsTree(t *testing.T) {
+	stub, client, local := newSyncFixture(t)
+	ctx := context.Background()
+
+	actions, _ := planSync(ctx, client, local, "www", syncOptions{Delete: true})
+	uploaded, deleted, err := runSync(ctx, client, local, "www", actions)
+	if err != nil {
+		t.Fatalf("runSync failed: %v", err)
+	}
+	if uploaded != 2 || deleted != 1 {
+		t.Errorf("expected 2 uploaded and 1 deleted, got %d and %d", uploaded, deleted)
+	}
+
+	want := map[string]string{
+		"www/index.html":   "<html>",
+		"www/about.html":   "about us",
+		"www/css/main.css": "body { color: red }",
+	}
+	if len(stub.files) != len(want) {
+		t.Errorf("expected remote files %v, got %d files", want, len(stub.files))
+	}
+	for name, content := range want {
+		if string(stub.files[name]) != content {
+			t.Errorf("%s: expected %q, got %q", name, content, stub.files[name])
+		}
+	}
+
+	// Once mirrored, there is nothing left to do
+	actions, err = planSync(ctx, client, local, "www", syncOptions{Delete: true, Checksum: true})
+	if err != nil || len(actions) != 0 {
+		t.Errorf("expected no actions after sync, got %+v (%v)", actions, err)
+	}
+}
+
+func TestPlanSync_EmptyRemote(t *testing.T) {
+	_, client, local := newSyncFixture(t)
+
+	actions, err := planSync(context.Background(), client, local, "fresh", syncOptions{Delete: true})
+	if err != nil {
+		t.Fatalf("planSync failed: %v", err)
+	}
+	if len(actions) != 3 {
+		t.Errorf("expected every local file to be uploaded, got %+v", actions)
+	}
+	for _, action := range actions {
+		if action.Op != "upload" || action.Reason != "new" {
+			t.Errorf("unexpected action %+v", action)
+		}
+	}
+}
+
+func TestDoSync_DryRunChangesNothing(t *testing.T) {
+	stub, client, local := newSyncFixture(t)
+
+	doSync(context.Background(), client, []string{"--dry-run", "--delete", local, "www"})
+
+	if len(stub.files) != 3 || len(stub.deleted) != 0 || len(stub.chunks) != 0 {
+		t.Errorf("expected dry run to leave the server untouched, got files %d, deleted %v", len(stub.files), stub.deleted)
+	}
+	if string(stub.files["www/css/main.css"]) != "body {}" {
+		t.Errorf("expected main.css unchanged, got %q", stub.files["www/css/main.css"])
+	}
+}
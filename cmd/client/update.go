@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/config"
+	"github.com/0xRepo-Source/goflux-lite/pkg/updater"
+)
+
+// updateOptions controls where gfl update looks and what it does
+type updateOptions struct {
+	Local    bool // discover a server on the network if none is configured
+	Rollback bool // restore the previous version instead of updating
+}
+
+// parseUpdateFlags extracts update options from the arguments
+func parseUpdateFlags(args []string) (updateOptions, error) {
+	var opts updateOptions
+	for _, arg := range args {
+		switch arg {
+		case "--local", "-local", "-l":
+			opts.Local = true
+		case "--rollback", "-rollback":
+			opts.Rollback = true
+		default:
+			return opts, fmt.Errorf("unknown update option %q", arg)
+		}
+	}
+	return opts, nil
+}
+
+func doUpdate(cfg *config.Config, args []string) {
+	opts, err := parseUpdateFlags(args)
+	if err != nil {
+		fmt.Println(err)
+		fmt.Println("Usage: update [--local] | update --rollback")
+		os.Exit(1)
+	}
+
+	upd := newUpdater()
+
+	if opts.Rollback {
+		if err := upd.Rollback(); err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+		fmt.Println("✓ Previous version restored")
+		fmt.Println("Please restart gfl to use it.")
+		return
+	}
+
+	if server := localUpdateServer(cfg, opts.Local); server != "" {
+		upd.SetLocalServer(server)
+		fmt.Printf("Checking %s for updates before GitHub\n", server)
+	}
+
+	fmt.Println("Checking for updates...")
+	manifest, err := upd.CheckForUpdate()
+	if err != nil {
+		log.Fatalf("Update check failed: %v", err)
+	}
+
+	if manifest == nil {
+		fmt.Printf("You are already running the latest version (%s)\n", currentVersion)
+		return
+	}
+
+	fmt.Printf("Update available: %s → %s\n", currentVersion, manifest.Version)
+	fmt.Printf("Released: %s\n", manifest.ReleaseDate)
+	if manifest.Notes != "" {
+		fmt.Printf("\nRelease notes:\n%s\n\n", manifest.Notes)
+	}
+
+	// Confirm update
+	fmt.Print("Do you want to install this update? (y/N): ")
+	var response string
+	fmt.Scanln(&response)
+	if strings.ToLower(response) != "y" {
+		fmt.Println("Update cancelled")
+		return
+	}
+
+	fmt.Println("Downloading update...")
+	downloadPath, err := downloadUpdate(upd, manifest)
+	if err != nil {
+		log.Fatalf("Download failed: %v", err)
+	}
+
+	fmt.Println("Installing update...")
+	if err := upd.Install(downloadPath); err != nil {
+		log.Fatalf("Installation failed: %v\n\nYou can try running the update again.", err)
+	}
+
+	fmt.Printf("\n✓ Updated gfl %s → %s\n", currentVersion, manifest.Version)
+	fmt.Println("Please restart gfl to use the new version.")
+	fmt.Println("If it misbehaves, run 'gfl update --rollback' to restore the previous one.")
+}
+
+// newUpdater returns an Updater for this build, trusting manifests signed
+// with the key it was built with
+func newUpdater() *updater.Updater {
+	upd := updater.New(currentVersion, updateManifestURL)
+	if updatePublicKey != "" {
+		key, err := updater.ParsePublicKey(updatePublicKey)
+		if err != nil {
+			log.Fatalf("Invalid update signing key: %v", err)
+		}
+		upd.SetPublicKey(key)
+	}
+	return upd
+}
+
+// localUpdateServer returns the server to try for updates before GitHub: the
+// configured one or, with discover, the first server found on the network if
+// none is configured. Manifests are signed, so any server can offer them.
+func localUpdateServer(cfg *config.Config, discover bool) string {
+	if cfg != nil && cfg.Client.ServerURL != "" && cfg.Client.ServerURL != config.DefaultClientConfig().ServerURL {
+		return strings.TrimRight(cfg.Client.ServerURL, "/")
+	}
+	if !discover {
+		return ""
+	}
+
+	fmt.Println("Discovering GoFlux servers on local network...")
+	servers, err := newDiscoveryClient(cfg).DiscoverServers()
+	if err != nil || len(servers) == 0 {
+		fmt.Println("No servers found; checking GitHub only")
+		return ""
+	}
+	return servers[0].URL()
+}
+
+// downloadUpdate downloads the manifest's binary for this platform, showing
+// a progress bar with the amount transferred and the speed
+func downloadUpdate(upd *updater.Updater, manifest *updater.Manifest) (string, error) {
+	const barWidth = 40
+	rate := newRateEstimator(speedWindow, time.Now())
+	lastShown := -1
+
+	path, err := upd.DownloadUpdate(manifest, func(downloaded, total int64) {
+		if total <= 0 {
+			return
+		}
+		rate.observe(time.Now(), int(downloaded))
+		percent := int(downloaded * 100 / total)
+		if percent > 100 {
+			percent = 100
+		}
+		if percent == lastShown {
+			return
+		}
+		lastShown = percent
+
+		filled := percent * barWidth / 100
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+		fmt.Printf("\r[%s] %3d%% %s / %s  %s   ", bar, percent, formatBytes(int(downloaded)), formatBytes(int(total)), formatSpeed(rate.rate(time.Now())))
+	})
+	if lastShown >= 0 {
+		fmt.Println()
+	}
+	return path, err
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/config"
+	"github.com/0xRepo-Source/goflux-lite/pkg/updater"
+)
+
+// newUpdateServer serves a signed manifest offering binary as version 9.0.0
+// at /version.json, and the binary itself at /gfl
+func newUpdateServer(t *testing.T, binary []byte) (*httptest.Server, ed25519.PublicKey) {
+	t.Helper()
+	private := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{3}, ed25519.SeedSize))
+
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	sum := sha256.Sum256(binary)
+	manifest := &updater.Manifest{
+		Version:     "9.0.0",
+		ReleaseDate: "2025-12-06",
+		Notes:       "Faster uploads",
+		Binaries: map[string]updater.Binary{
+			updater.GetPlatform(): {URL: ts.URL + "/gfl", Checksum: hex.EncodeToString(sum[:]), Size: int64(len(binary))},
+		},
+	}
+	if err := updater.SignManifest(manifest, private); err != nil {
+		t.Fatalf("SignManifest failed: %v", err)
+	}
+
+	mux.HandleFunc("/version.json", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(manifest)
+	})
+	mux.HandleFunc("/gfl", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(binary)
+	})
+	return ts, private.Public().(ed25519.PublicKey)
+}
+
+func TestUpdate_FromLocalServer(t *testing.T) {
+	binary := bytes.Repeat([]byte("new gfl build "), 10000)
+	ts, key := newUpdateServer(t, binary)
+
+	upd := newUpdater()
+	upd.SetPublicKey(key)
+	upd.ManifestURL = ts.URL + "/missing.json" // the local server must be enough
+	upd.SetLocalServer(localUpdateServer(&config.Config{Client: config.ClientConfig{ServerURL: ts.URL + "/"}}, false))
+
+	manifest, err := upd.CheckForUpdate()
+	if err != nil {
+		t.Fatalf("CheckForUpdate failed: %v", err)
+	}
+	if manifest == nil || manifest.Version != "9.0.0" {
+		t.Fatalf("expected 9.0.0 to be offered, got %+v", manifest)
+	}
+
+	path, err := downloadUpdate(upd, manifest)
+	if err != nil {
+		t.Fatalf("downloadUpdate failed: %v", err)
+	}
+	defer os.Remove(path)
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, binary) {
+		t.Errorf("expected the downloaded binary to match, got %d bytes", len(data))
+	}
+}
+
+func TestUpdate_RejectsUnsignedManifest(t *testing.T) {
+	ts, _ := newUpdateServer(t, []byte("new gfl build"))
+
+	// An updater that doesn't know the release key trusts nothing
+	other, _, _ := ed25519.GenerateKey(nil)
+	upd := newUpdater()
+	upd.SetPublicKey(other)
+	upd.ManifestURL = ts.URL + "/version.json"
+
+	if manifest, err := upd.CheckForUpdate(); err == nil {
+		t.Errorf("expected a manifest signed with another key to be rejected, got %+v", manifest)
+	}
+}
+
+func TestParseUpdateFlags(t *testing.T) {
+	opts, err := parseUpdateFlags([]string{"--local"})
+	if err != nil || !opts.Local || opts.Rollback {
+		t.Errorf("unexpected options for --local: %+v (%v)", opts, err)
+	}
+	opts, err = parseUpdateFlags([]string{"--rollback"})
+	if err != nil || !opts.Rollback {
+		t.Errorf("unexpected options for --rollback: %+v (%v)", opts, err)
+	}
+	if _, err := parseUpdateFlags([]string{"--bogus"}); err == nil {
+		t.Error("expected an unknown option to be rejected")
+	}
+}
+
+func TestLocalUpdateServer_Configured(t *testing.T) {
+	cfg := &config.Config{Client: config.ClientConfig{ServerURL: "http://192.168.1.10:8080/"}}
+	if got := localUpdateServer(cfg, false); got != "http://192.168.1.10:8080" {
+		t.Errorf("expected the configured server, got %q", got)
+	}
+
+	// The default configuration points nowhere in particular
+	cfg = &config.Config{Client: config.DefaultClientConfig()}
+	if got := localUpdateServer(cfg, false); got != "" {
+		t.Errorf("expected no server for the default configuration, got %q", got)
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/chunk"
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+func TestUploadFile_StreamsFromDisk(t *testing.T) {
+	const (
+		fileSize  = 32 * 1024 * 1024
+		chunkSize = 256 * 1024
+	)
+
+	// Write the file in pieces so the test itself never holds all of it
+	localPath := filepath.Join(t.TempDir(), "large.bin")
+	f, err := os.Create(localPath)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	want := sha256.New()
+	buf := make([]byte, chunkSize)
+	for written := 0; written < fileSize; written += len(buf) {
+		rand.Read(buf)
+		f.Write(buf)
+		want.Write(buf)
+	}
+	f.Close()
+
+	// The server hashes chunks as they arrive and samples the heap, which is
+	// shared with the client in this process
+	var (
+		mu       sync.Mutex
+		got      hash.Hash = sha256.New()
+		nextID   int
+		received int
+		fileHash string
+		peakHeap uint64
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var c transport.ChunkData
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if c.ChunkID != nextID || len(c.Data) > chunkSize || c.Checksum != chunk.Checksum(c.Data) {
+			http.Error(w, "unexpected chunk", http.StatusBadRequest)
+			return
+		}
+		if c.ChunkID == 0 {
+			fileHash = c.FileHash
+		}
+		nextID++
+		received += len(c.Data)
+		got.Write(c.Data)
+
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		if stats.HeapAlloc > peakHeap {
+			peakHeap = stats.HeapAlloc
+		}
+	}))
+	defer ts.Close()
+
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	if err := uploadFile(context.Background(), transport.NewHTTPClient(ts.URL), localPath, "large.bin", chunkSize, false, nil); err != nil {
+		t.Fatalf("uploadFile failed: %v", err)
+	}
+
+	if received != fileSize || nextID != fileSize/chunkSize {
+		t.Fatalf("expected %d bytes in %d chunks, got %d bytes in %d chunks", fileSize, fileSize/chunkSize, received, nextID)
+	}
+	if string(got.Sum(nil)) != string(want.Sum(nil)) {
+		t.Error("uploaded bytes don't match the file")
+	}
+	if fileHash != hex.EncodeToString(want.Sum(nil)) {
+		t.Errorf("expected first chunk to carry the file hash, got %q", fileHash)
+	}
+
+	// Reading the whole file up front would put at least fileSize on the heap
+	if growth := int64(peakHeap) - int64(before.HeapAlloc); growth > fileSize/2 {
+		t.Errorf("heap grew by %d bytes uploading a %d byte file", growth, fileSize)
+	}
+}
+
+func TestUploadFile_Empty(t *testing.T) {
+	stub, ts := newStubServer(t)
+	localPath := filepath.Join(t.TempDir(), "empty.txt")
+	os.WriteFile(localPath, nil, 0644)
+
+	if err := uploadFile(context.Background(), transport.NewHTTPClient(ts.URL), localPath, "empty.txt", 1024, false, nil); err != nil {
+		t.Fatalf("uploadFile failed: %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if data, ok := stub.files["empty.txt"]; !ok || len(data) != 0 {
+		t.Errorf("expected empty file on server, got %q (present: %v)", data, ok)
+	}
+}
+
+func TestUploadFile_StalledChunkRetried(t *testing.T) {
+	stub, ts := newStubServer(t)
+	stub.stalls = map[int]time.Duration{2: 500 * time.Millisecond}
+
+	const chunkSize = 1024
+	content := make([]byte, 4*chunkSize)
+	rand.Read(content)
+	localPath := filepath.Join(t.TempDir(), "stall.bin")
+	os.WriteFile(localPath, content, 0644)
+
+	client := transport.NewHTTPClient(ts.URL)
+	client.SetChunkTimeout(50 * time.Millisecond)
+
+	// The stalled chunk is abandoned and retried well within the overall budget
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := uploadFile(ctx, client, localPath, "stall.bin", chunkSize, false, nil); err != nil {
+		t.Fatalf("uploadFile failed: %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if !bytes.Equal(stub.files["stall.bin"], content) {
+		t.Error("uploaded file doesn't match the local file")
+	}
+}
+
+func TestUploadFile_ResumesInterruptedUpload(t *testing.T) {
+	const chunkSize = 1024
+	content := make([]byte, 8*chunkSize+100)
+	rand.Read(content)
+	localPath := filepath.Join(t.TempDir(), "resume.bin")
+	os.WriteFile(localPath, content, 0644)
+
+	// The connection drops after half of the 9 chunks are sent
+	stub, ts := newStubServer(t)
+	stub.failAfter = 4
+	client := transport.NewHTTPClient(ts.URL)
+	client.SetUploadRetries(0)
+	if err := uploadFile(context.Background(), client, localPath, "resume.bin", chunkSize, false, nil); err == nil {
+		t.Fatal("expected the interrupted upload to fail")
+	}
+
+	// Running the upload again only sends what the server is missing
+	stub.mu.Lock()
+	stub.failAfter = 0
+	stub.accepted = nil
+	stub.mu.Unlock()
+	if err := uploadFile(context.Background(), client, localPath, "resume.bin", chunkSize, false, nil); err != nil {
+		t.Fatalf("resumed upload failed: %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if want := []int{4, 5, 6, 7, 8}; fmt.Sprint(stub.accepted) != fmt.Sprint(want) {
+		t.Errorf("expected only chunks %v to be re-sent, got %v", want, stub.accepted)
+	}
+	if !bytes.Equal(stub.files["resume.bin"], content) {
+		t.Error("resumed upload doesn't match the file")
+	}
+}
+
+func TestUploadFile_InterruptedUploadHint(t *testing.T) {
+	const chunkSize = 1024
+	content := make([]byte, 8*chunkSize+100)
+	rand.Read(content)
+	localPath := filepath.Join(t.TempDir(), "resume.bin")
+	os.WriteFile(localPath, content, 0644)
+
+	stub, ts := newStubServer(t)
+	stub.failAfter = 4
+	client := transport.NewHTTPClient(ts.URL)
+	client.SetUploadRetries(0)
+	err := uploadFile(context.Background(), client, localPath, "backups/resume.bin", chunkSize, false, nil)
+
+	var interrupted *interruptedUpload
+	if !errors.As(err, &interrupted) {
+		t.Fatalf("expected an interruptedUpload error, got %v", err)
+	}
+	if interrupted.RemotePath != "backups/resume.bin" || interrupted.Sent != 4*chunkSize || interrupted.Size != len(content) {
+		t.Errorf("expected 4096 of %d bytes of backups/resume.bin sent, got %+v", len(content), interrupted)
+	}
+	hint := interrupted.Hint()
+	for _, want := range []string{"backups/resume.bin stopped at 49%", "gfl put " + localPath + " backups/resume.bin"} {
+		if !strings.Contains(hint, want) {
+			t.Errorf("expected hint to contain %q, got:\n%s", want, hint)
+		}
+	}
+
+	// Progress counts the chunks an earlier attempt left on the server
+	stub.mu.Lock()
+	stub.failAfter = 2
+	stub.accepted = nil
+	stub.mu.Unlock()
+	err = uploadFile(context.Background(), client, localPath, "backups/resume.bin", chunkSize, true, nil)
+	if !errors.As(err, &interrupted) {
+		t.Fatalf("expected an interruptedUpload error, got %v", err)
+	}
+	if interrupted.Sent != 6*chunkSize {
+		t.Errorf("expected 6 chunks on the server, got %d bytes", interrupted.Sent)
+	}
+	if !strings.Contains(interrupted.Hint(), "stopped at 74%") || !strings.Contains(interrupted.Command, "--checksum-only-resume") {
+		t.Errorf("unexpected hint after resuming:\n%s", interrupted.Hint())
+	}
+}
+
+func TestUploadFile_VerifyResumeResendsDamagedChunk(t *testing.T) {
+	const chunkSize = 1024
+	content := make([]byte, 8*chunkSize+100)
+	rand.Read(content)
+	localPath := filepath.Join(t.TempDir(), "verify.bin")
+	os.WriteFile(localPath, content, 0644)
+
+	stub, ts := newStubServer(t)
+	stub.failAfter = 4
+	client := transport.NewHTTPClient(ts.URL)
+	client.SetUploadRetries(0)
+	if err := uploadFile(context.Background(), client, localPath, "verify.bin", chunkSize, false, nil); err == nil {
+		t.Fatal("expected the interrupted upload to fail")
+	}
+
+	// A chunk the server already acknowledged goes bad on its disk
+	stub.mu.Lock()
+	stub.chunks["verify.bin"][2][10] ^= 0xff
+	stub.failAfter = 0
+	stub.accepted = nil
+	stub.mu.Unlock()
+
+	if err := uploadFile(context.Background(), client, localPath, "verify.bin", chunkSize, true, nil); err != nil {
+		t.Fatalf("resumed upload failed: %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if want := []int{2, 4, 5, 6, 7, 8}; fmt.Sprint(stub.accepted) != fmt.Sprint(want) {
+		t.Errorf("expected chunks %v to be sent, got %v", want, stub.accepted)
+	}
+	if !bytes.Equal(stub.files["verify.bin"], content) {
+		t.Error("resumed upload doesn't match the file")
+	}
+}
+
+func TestUploadFile_RestartsUploadOfChangedFile(t *testing.T) {
+	const chunkSize = 1024
+	content := make([]byte, 8*chunkSize+100)
+	rand.Read(content)
+	localPath := filepath.Join(t.TempDir(), "changed.bin")
+	os.WriteFile(localPath, content, 0644)
+
+	stub, ts := newStubServer(t)
+	stub.failAfter = 4
+	client := transport.NewHTTPClient(ts.URL)
+	client.SetUploadRetries(0)
+	if err := uploadFile(context.Background(), client, localPath, "changed.bin", chunkSize, false, nil); err == nil {
+		t.Fatal("expected the interrupted upload to fail")
+	}
+
+	// The file changes before the upload is resumed, so the server refuses
+	// its chunks for the upload already under way
+	content[len(content)-1] ^= 0xff
+	os.WriteFile(localPath, content, 0644)
+	stub.mu.Lock()
+	stub.failAfter = 0
+	stub.accepted = nil
+	stub.mu.Unlock()
+	if err := uploadFile(context.Background(), client, localPath, "changed.bin", chunkSize, false, nil); err != nil {
+		t.Fatalf("restarted upload failed: %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if fmt.Sprint(stub.aborted) != "[changed.bin]" {
+		t.Errorf("expected the stale upload to be aborted, got %v", stub.aborted)
+	}
+	if want := []int{0, 1, 2, 3, 4, 5, 6, 7, 8}; fmt.Sprint(stub.accepted) != fmt.Sprint(want) {
+		t.Errorf("expected every chunk to be sent again, got %v", stub.accepted)
+	}
+	if !bytes.Equal(stub.files["changed.bin"], content) {
+		t.Error("restarted upload doesn't match the changed file")
+	}
+}
+
+func TestUploadFile_FinalizesWhenEveryChunkIsOnServer(t *testing.T) {
+	const chunkSize = 1024
+	content := make([]byte, 2*chunkSize+100)
+	rand.Read(content)
+	localPath := filepath.Join(t.TempDir(), "waiting.bin")
+	os.WriteFile(localPath, content, 0644)
+
+	// Every chunk arrived, but the upload was never finalized
+	stub, ts := newStubServer(t)
+	stub.chunks["waiting.bin"] = map[int][]byte{
+		0: content[:chunkSize],
+		1: content[chunkSize : 2*chunkSize],
+		2: content[2*chunkSize:],
+	}
+	stub.totals["waiting.bin"] = 3
+
+	client := transport.NewHTTPClient(ts.URL)
+	if err := uploadFile(context.Background(), client, localPath, "waiting.bin", chunkSize, false, nil); err != nil {
+		t.Fatalf("uploadFile failed: %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if len(stub.accepted) != 0 {
+		t.Errorf("expected no chunks to be sent, got %v", stub.accepted)
+	}
+	if fmt.Sprint(stub.finalized) != "[waiting.bin]" {
+		t.Errorf("expected the upload to be finalized, got %v", stub.finalized)
+	}
+	if !bytes.Equal(stub.files["waiting.bin"], content) {
+		t.Error("finalized upload doesn't match the file")
+	}
+}
+
+func TestUploadFile_BatchProgressCoversAllFiles(t *testing.T) {
+	const chunkSize = 1024
+	sizes := map[string]int{
+		"empty.txt": 0,
+		"tiny.txt":  10,
+		"small.bin": 2*chunkSize + 5, // several chunks, but too few for a bar
+		"large.bin": 9*chunkSize + 7,
+	}
+	dir := t.TempDir()
+	total := 0
+	for name, size := range sizes {
+		content := make([]byte, size)
+		rand.Read(content)
+		os.WriteFile(filepath.Join(dir, name), content, 0644)
+		total += size
+	}
+
+	// Half of large.bin is already on the server from an interrupted attempt
+	stub, ts := newStubServer(t)
+	client := transport.NewHTTPClient(ts.URL)
+	client.SetUploadRetries(0)
+	stub.failAfter = 4
+	uploadFile(context.Background(), client, filepath.Join(dir, "large.bin"), "large.bin", chunkSize, false, nil)
+	stub.mu.Lock()
+	stub.failAfter = 0
+	stub.mu.Unlock()
+
+	batch := newBatchProgress(total)
+	last := 0.0
+	for _, name := range []string{"tiny.txt", "large.bin", "empty.txt", "small.bin"} {
+		if err := uploadFile(context.Background(), client, filepath.Join(dir, name), name, chunkSize, false, batch); err != nil {
+			t.Fatalf("upload of %s failed: %v", name, err)
+		}
+		got := batch.fraction()
+		if got < last || got > 1 {
+			t.Fatalf("overall progress out of range after %s: %v (was %v)", name, got, last)
+		}
+		last = got
+	}
+
+	if completed := batch.add(0); completed != total {
+		t.Errorf("expected %d bytes counted, got %d", total, completed)
+	}
+	if line := batch.render(10); !strings.Contains(line, "100%") {
+		t.Errorf("expected overall progress to reach 100%%, got %q", line)
+	}
+}
@@ -6,3 +6,8 @@ const (
 	updateManifestURL  = "https://raw.githubusercontent.com/0xRepo-Source/goflux-lite/main/version.json"
 	updateCheckTimeout = 10
 )
+
+// updatePublicKey is the base64-encoded Ed25519 key update manifests must be
+// signed with. Release builds set it with
+// -ldflags "-X main.updatePublicKey=<key>"; without it, updates are refused.
+var updatePublicKey = ""
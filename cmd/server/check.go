@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/auth"
+	"github.com/0xRepo-Source/goflux-lite/pkg/config"
+	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
+)
+
+// checkResult is the outcome of a single startup self-test
+type checkResult struct {
+	Name string
+	Err  error // nil if the check passed
+}
+
+// runChecks validates the server configuration and environment without serving.
+// Every check runs even if an earlier one fails so the report is complete.
+func runChecks(cfg *config.ServerConfig) []checkResult {
+	var results []checkResult
+	add := func(name string, err error) {
+		results = append(results, checkResult{Name: name, Err: err})
+	}
+
+	add("config", cfg.Validate())
+
+	if cfg.StorageDir != "" {
+		add("storage_dir", storage.CheckWritable(cfg.StorageDir))
+	}
+	if cfg.MetaDir != "" {
+		add("meta_dir", storage.CheckWritable(cfg.MetaDir))
+	}
+
+	for _, route := range cfg.StorageRoutes {
+		if err := storage.Check(route.Storage); err != nil {
+			add("storage_route "+route.Storage, err)
+		}
+	}
+
+	if cfg.TokensFile != "" {
+		add("tokens_file", checkTokensFile(cfg.TokensFile))
+	}
+
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		_, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			err = fmt.Errorf("cannot load certificate: %w", err)
+		}
+		add("tls", err)
+	}
+
+	if cfg.Address != "" {
+		add("address", checkBindable(cfg.Address))
+	}
+
+	return results
+}
+
+// checkTokensFile verifies the token file exists and parses
+func checkTokensFile(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	if _, err := auth.NewTokenStore(path); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkBindable verifies the listen address can be bound
+func checkBindable(address string) error {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("cannot bind %s: %w", address, err)
+	}
+	return ln.Close()
+}
+
+// formatCheckReport renders check results and reports whether all passed
+func formatCheckReport(results []checkResult) (string, bool) {
+	var out strings.Builder
+	passed := true
+
+	for _, r := range results {
+		if r.Err != nil {
+			passed = false
+			out.WriteString(fmt.Sprintf("✗ %-12s %v\n", r.Name, r.Err))
+		} else {
+			out.WriteString(fmt.Sprintf("✓ %-12s ok\n", r.Name))
+		}
+	}
+
+	if passed {
+		out.WriteString("\nAll checks passed.\n")
+	} else {
+		out.WriteString("\nSome checks failed.\n")
+	}
+	return out.String(), passed
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/config"
+)
+
+// goodServerConfig returns a config that should pass every check
+func goodServerConfig(t *testing.T) config.ServerConfig {
+	t.Helper()
+	tmpDir := t.TempDir()
+	return config.ServerConfig{
+		Address:    "127.0.0.1:0",
+		StorageDir: filepath.Join(tmpDir, "data"),
+		MetaDir:    filepath.Join(tmpDir, "meta"),
+	}
+}
+
+// failedCheck returns the error of the named check, or nil if it passed or didn't run
+func failedCheck(results []checkResult, name string) error {
+	for _, r := range results {
+		if r.Name == name {
+			return r.Err
+		}
+	}
+	return nil
+}
+
+func TestRunChecks_GoodConfig(t *testing.T) {
+	cfg := goodServerConfig(t)
+
+	tokensFile := filepath.Join(t.TempDir(), "tokens.json")
+	os.WriteFile(tokensFile, []byte(`{"tokens": []}`), 0644)
+	cfg.TokensFile = tokensFile
+
+	report, passed := formatCheckReport(runChecks(&cfg))
+	if !passed {
+		t.Fatalf("expected all checks to pass:\n%s", report)
+	}
+	if !strings.Contains(report, "All checks passed") {
+		t.Errorf("unexpected report:\n%s", report)
+	}
+}
+
+func TestRunChecks_CreatesNothing(t *testing.T) {
+	cfg := goodServerConfig(t)
+	route := filepath.Join(t.TempDir(), "routed")
+	cfg.StorageRoutes = []config.StorageRoute{{Pattern: "*.jpg", Storage: "local://" + route}}
+
+	if report, passed := formatCheckReport(runChecks(&cfg)); !passed {
+		t.Fatalf("expected all checks to pass:\n%s", report)
+	}
+	for _, dir := range []string{cfg.StorageDir, cfg.MetaDir, route} {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("expected %s not to be created, got %v", dir, err)
+		}
+	}
+}
+
+func TestRunChecks_BrokenConfigs(t *testing.T) {
+	tests := []struct {
+		name      string
+		mutate    func(t *testing.T, cfg *config.ServerConfig)
+		check     string
+		wantInErr string
+	}{
+		{
+			name:      "invalid address",
+			mutate:    func(t *testing.T, cfg *config.ServerConfig) { cfg.Address = "no-port" },
+			check:     "config",
+			wantInErr: "invalid address",
+		},
+		{
+			name: "unwritable storage dir",
+			mutate: func(t *testing.T, cfg *config.ServerConfig) {
+				blocker := filepath.Join(t.TempDir(), "file")
+				os.WriteFile(blocker, []byte("x"), 0644)
+				cfg.StorageDir = filepath.Join(blocker, "data")
+			},
+			check:     "storage_dir",
+			wantInErr: "cannot create directory",
+		},
+		{
+			name: "update manifest in storage",
+			mutate: func(t *testing.T, cfg *config.ServerConfig) {
+				cfg.UpdateManifest = filepath.Join(cfg.StorageDir, "version.json")
+			},
+			check:     "config",
+			wantInErr: "update_manifest must be outside storage_dir",
+		},
+		{
+			name: "unparseable tokens file",
+			mutate: func(t *testing.T, cfg *config.ServerConfig) {
+				cfg.TokensFile = filepath.Join(t.TempDir(), "tokens.json")
+				os.WriteFile(cfg.TokensFile, []byte("{not json"), 0644)
+			},
+			check:     "tokens_file",
+			wantInErr: "error parsing token file",
+		},
+		{
+			name: "missing tokens file",
+			mutate: func(t *testing.T, cfg *config.ServerConfig) {
+				cfg.TokensFile = filepath.Join(t.TempDir(), "missing.json")
+			},
+			check:     "tokens_file",
+			wantInErr: "cannot read",
+		},
+		{
+			name: "unloadable TLS certificate",
+			mutate: func(t *testing.T, cfg *config.ServerConfig) {
+				dir := t.TempDir()
+				cfg.TLSCertFile = filepath.Join(dir, "cert.pem")
+				cfg.TLSKeyFile = filepath.Join(dir, "key.pem")
+				os.WriteFile(cfg.TLSCertFile, []byte("not a cert"), 0644)
+				os.WriteFile(cfg.TLSKeyFile, []byte("not a key"), 0644)
+			},
+			check:     "tls",
+			wantInErr: "cannot load certificate",
+		},
+		{
+			name: "TLS cert without key",
+			mutate: func(t *testing.T, cfg *config.ServerConfig) {
+				cfg.TLSCertFile = "cert.pem"
+			},
+			check:     "config",
+			wantInErr: "tls_cert and tls_key must be set together",
+		},
+		{
+			name: "port already in use",
+			mutate: func(t *testing.T, cfg *config.ServerConfig) {
+				ln, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatalf("failed to pre-bind port: %v", err)
+				}
+				t.Cleanup(func() { ln.Close() })
+				cfg.Address = ln.Addr().String()
+			},
+			check:     "address",
+			wantInErr: "cannot bind",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := goodServerConfig(t)
+			tt.mutate(t, &cfg)
+
+			results := runChecks(&cfg)
+			report, passed := formatCheckReport(results)
+			if passed {
+				t.Fatalf("expected checks to fail:\n%s", report)
+			}
+
+			err := failedCheck(results, tt.check)
+			if err == nil {
+				t.Fatalf("expected %s check to fail:\n%s", tt.check, report)
+			}
+			if !strings.Contains(err.Error(), tt.wantInErr) {
+				t.Errorf("expected %s error to contain %q, got %v", tt.check, tt.wantInErr, err)
+			}
+		})
+	}
+}
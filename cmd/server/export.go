@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/config"
+	"github.com/0xRepo-Source/goflux-lite/pkg/server"
+)
+
+const (
+	// serverVersion is reported by -version and advertised to clients
+	serverVersion = "0.1.0-lite"
+	// maxFileSize is the largest file clients are told the server accepts
+	maxFileSize = 1024 * 1024 * 1024 // 1GB
+)
+
+// newServerConfig builds the configuration served to clients at /config
+func newServerConfig(cfg *config.ServerConfig, instanceID, scheme string) *server.ServerConfig {
+	serverConfig := &server.ServerConfig{
+		Name:        cfg.ServerName,
+		InstanceID:  instanceID,
+		Scheme:      scheme,
+		Version:     serverVersion,
+		AuthEnabled: cfg.TokensFile != "",
+	}
+	serverConfig.Server.Address = cfg.Address
+	serverConfig.Server.StorageDir = cfg.StorageDir
+	serverConfig.Server.MetaDir = cfg.MetaDir
+	serverConfig.Server.TokensFile = cfg.TokensFile
+	serverConfig.Server.MaxFileSize = maxFileSize
+	return serverConfig
+}
+
+// configScheme returns the scheme the server will serve with cfg
+func configScheme(cfg *config.ServerConfig) string {
+	if cfg.TLSCertFile != "" && cfg.TLSKeyFile != "" {
+		return "https"
+	}
+	return "http"
+}
+
+// clientConfigFile is the layout of a client's goflux.json
+type clientConfigFile struct {
+	Client config.ClientConfig `json:"client"`
+}
+
+// clientConfigFor returns the client configuration `gfl config` would write
+// for a server advertising serverConfig. A wildcard listen address is
+// replaced by this machine's internal IP so clients have somewhere to connect.
+func clientConfigFor(serverConfig *server.ServerConfig) config.ClientConfig {
+	address := serverConfig.Server.Address
+	if host, port, err := net.SplitHostPort(address); err == nil {
+		if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+			address = net.JoinHostPort(getInternalIP(), port)
+		}
+	}
+
+	scheme := serverConfig.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	clientConfig := config.DefaultClientConfig()
+	clientConfig.ServerURL = scheme + "://" + address
+	clientConfig.AuthRequired = serverConfig.AuthEnabled
+	clientConfig.MaxFileSize = serverConfig.Server.MaxFileSize
+	return clientConfig
+}
+
+// doConfigExport writes a client configuration for this server to the file
+// named by -o, or to standard output, for distributing without discovery
+func doConfigExport(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("config-export", flag.ContinueOnError)
+	output := fs.String("o", "", "file to write the client configuration to (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// The instance ID isn't part of a client's configuration
+	serverConfig := newServerConfig(&cfg.Server, "", configScheme(&cfg.Server))
+	data, err := json.MarshalIndent(clientConfigFile{Client: clientConfigFor(serverConfig)}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode client config: %w", err)
+	}
+	data = append(data, '\n')
+
+	if *output == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write client config: %w", err)
+	}
+	fmt.Printf("✓ Client configuration for %s written to %s\n", serverConfig.Server.Address, *output)
+	if serverConfig.AuthEnabled {
+		fmt.Println("  This server requires authentication; give each user a token to add to it.")
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/config"
+	"github.com/0xRepo-Source/goflux-lite/pkg/server"
+	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
+)
+
+// serveConfig starts a server configured like main does and returns the
+// configuration it serves at /config
+func serveConfig(t *testing.T, cfg *config.Config) server.ServerConfig {
+	t.Helper()
+	store, err := storage.NewLocal(cfg.Server.StorageDir)
+	if err != nil {
+		t.Fatalf("NewLocal failed: %v", err)
+	}
+	srv, err := server.New(store, cfg.Server.MetaDir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	srv.SetConfig(newServerConfig(&cfg.Server, srv.InstanceID(), srv.Scheme()))
+
+	ln, err := net.Listen("tcp", cfg.Server.Address)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+
+	resp, err := http.Get("http://" + cfg.Server.Address + "/config")
+	if err != nil {
+		t.Fatalf("GET /config failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var served server.ServerConfig
+	if err := json.NewDecoder(resp.Body).Decode(&served); err != nil {
+		t.Fatalf("failed to decode /config: %v", err)
+	}
+	return served
+}
+
+// freeAddress returns a loopback address with a port nothing is listening on
+func freeAddress(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// exportConfig runs config-export and returns the client config it wrote
+func exportConfig(t *testing.T, cfg *config.Config) config.ClientConfig {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "client.json")
+	if err := doConfigExport(cfg, []string{"-o", out}); err != nil {
+		t.Fatalf("doConfigExport failed: %v", err)
+	}
+
+	// The file loads like any client configuration
+	loaded, err := config.LoadConfig(out)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	return loaded.Client
+}
+
+func TestConfigExport_MatchesServedConfig(t *testing.T) {
+	for _, authEnabled := range []bool{false, true} {
+		tmpDir := t.TempDir()
+		cfg := &config.Config{Server: config.ServerConfig{
+			Address:    freeAddress(t),
+			StorageDir: filepath.Join(tmpDir, "data"),
+			MetaDir:    filepath.Join(tmpDir, "meta"),
+		}}
+		if authEnabled {
+			cfg.Server.TokensFile = filepath.Join(tmpDir, "tokens.json")
+		}
+
+		served := serveConfig(t, cfg)
+		exported := exportConfig(t, cfg)
+
+		if want := served.Scheme + "://" + served.Server.Address; exported.ServerURL != want {
+			t.Errorf("expected server URL %s, got %s", want, exported.ServerURL)
+		}
+		if exported.AuthRequired != served.AuthEnabled || exported.AuthRequired != authEnabled {
+			t.Errorf("expected auth required %v to match served %v", exported.AuthRequired, served.AuthEnabled)
+		}
+		if exported.MaxFileSize != served.Server.MaxFileSize || exported.MaxFileSize == 0 {
+			t.Errorf("expected max file size %d, got %d", served.Server.MaxFileSize, exported.MaxFileSize)
+		}
+		if exported.ChunkSize != config.DefaultClientConfig().ChunkSize || exported.Token != "" {
+			t.Errorf("expected default chunk size and no token, got %+v", exported)
+		}
+	}
+}
+
+func TestConfigExport_TLS(t *testing.T) {
+	cfg := &config.Config{Server: config.ServerConfig{
+		Address:     "192.168.1.10:8443",
+		TLSCertFile: "cert.pem",
+		TLSKeyFile:  "key.pem",
+	}}
+	if got := exportConfig(t, cfg).ServerURL; got != "https://192.168.1.10:8443" {
+		t.Errorf("expected an https URL, got %s", got)
+	}
+}
+
+func TestConfigExport_WildcardAddress(t *testing.T) {
+	cfg := &config.Config{Server: config.ServerConfig{Address: "0.0.0.0:8080"}}
+	got := exportConfig(t, cfg).ServerURL
+	if strings.Contains(got, "0.0.0.0") || !strings.HasSuffix(got, ":8080") {
+		t.Errorf("expected the wildcard address to be replaced by a reachable one, got %s", got)
+	}
+}
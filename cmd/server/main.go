@@ -1,14 +1,22 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/0xRepo-Source/goflux-lite/pkg/audit"
 	"github.com/0xRepo-Source/goflux-lite/pkg/auth"
 	"github.com/0xRepo-Source/goflux-lite/pkg/config"
+	"github.com/0xRepo-Source/goflux-lite/pkg/resume"
 	"github.com/0xRepo-Source/goflux-lite/pkg/server"
 	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
 )
@@ -49,50 +57,175 @@ func getInternalIP() string {
 	return localAddr.IP.String()
 }
 
+// applyAddress overrides the listen port if one was given on the command
+// line, and replaces localhost with the internal IP so that the address the
+// server advertises is reachable from other machines
+func applyAddress(cfg *config.Config, port string) {
+	if port != "" {
+		internalIP := getInternalIP()
+		cfg.Server.Address = fmt.Sprintf("%s:%s", internalIP, port)
+	} else if strings.Contains(cfg.Server.Address, "localhost") {
+		// If config still has localhost, replace with internal IP
+		internalIP := getInternalIP()
+		parts := strings.Split(cfg.Server.Address, ":")
+		if len(parts) == 2 {
+			cfg.Server.Address = fmt.Sprintf("%s:%s", internalIP, parts[1])
+		} else {
+			cfg.Server.Address = fmt.Sprintf("%s:8080", internalIP)
+		}
+	}
+}
+
 func main() {
-	configFile := flag.String("config", "goflux.json", "path to configuration file")
+	var configFiles config.Files
+	flag.Var(&configFiles, "config", "path to configuration file (default goflux.json); repeat to override it with further files")
 	port := flag.String("port", "", "server port (overrides config)")
 	version := flag.Bool("version", false, "print version")
+	check := flag.Bool("check", false, "validate configuration and environment, then exit")
 	flag.Parse()
+	if len(configFiles) == 0 {
+		configFiles = config.Files{"goflux.json"}
+	}
 
 	if *version {
-		fmt.Println("goflux-lite-server version: 0.1.0-lite")
+		fmt.Println("goflux-lite-server version: " + serverVersion)
+		return
+	}
+
+	if *check {
+		cfg, err := config.LoadConfigs(configFiles...)
+		if err != nil {
+			fmt.Printf("✗ %-12s %v\n", "config", err)
+			os.Exit(1)
+		}
+		// Check the address the server would really bind
+		applyAddress(cfg, *port)
+
+		report, passed := formatCheckReport(runChecks(&cfg.Server))
+		fmt.Print(report)
+		if !passed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if args := flag.Args(); len(args) > 0 {
+		if args[0] != "config-export" {
+			log.Fatalf("Unknown command: %s", args[0])
+		}
+		cfg, err := config.LoadConfigs(configFiles...)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		applyAddress(cfg, *port)
+		if err := doConfigExport(cfg, args[1:]); err != nil {
+			log.Fatalf("Config export failed: %v", err)
+		}
 		return
 	}
 
-	// Load or create configuration
-	cfg, err := config.LoadOrCreateConfig(*configFile)
+	// Load or create the base configuration, then apply any overrides
+	cfg, err := config.LoadOrCreateConfig(configFiles[0])
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
-
-	// Override port if specified, or use internal IP for default config
-	if *port != "" {
-		internalIP := getInternalIP()
-		cfg.Server.Address = fmt.Sprintf("%s:%s", internalIP, *port)
-	} else if strings.Contains(cfg.Server.Address, "localhost") {
-		// If config still has localhost, replace with internal IP
-		internalIP := getInternalIP()
-		parts := strings.Split(cfg.Server.Address, ":")
-		if len(parts) == 2 {
-			cfg.Server.Address = fmt.Sprintf("%s:%s", internalIP, parts[1])
-		} else {
-			cfg.Server.Address = fmt.Sprintf("%s:8080", internalIP)
+	for _, path := range configFiles[1:] {
+		if err := config.MergeFile(cfg, path); err != nil {
+			log.Fatalf("Failed to load config: %v", err)
 		}
 	}
+	applyAddress(cfg, *port)
+	if err := cfg.Server.Validate(); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
 
 	// Create storage backend
-	store, err := storage.NewLocal(cfg.Server.StorageDir)
+	local, err := storage.NewLocal(cfg.Server.StorageDir)
 	if err != nil {
 		log.Fatalf("Failed to create storage: %v", err)
 	}
 
-	// Create server without web UI
-	srv, err := server.New(store, cfg.Server.MetaDir)
+	// Compress files as they are stored; range downloads only decompress the
+	// blocks they need. Each backend is compressed beneath the routing, so
+	// routes sniff the content type of the data rather than of gzip.
+	compress := func(backend storage.Storage) storage.Storage {
+		if !cfg.Server.CompressStorage {
+			return backend
+		}
+		return storage.NewCompressed(backend, storage.DefaultBlockSize)
+	}
+	if cfg.Server.CompressStorage {
+		fmt.Println("Storage compression enabled")
+	}
+
+	store := compress(local)
+	if len(cfg.Server.StorageRoutes) > 0 {
+		var routes []storage.Route
+		for _, r := range cfg.Server.StorageRoutes {
+			backend, err := storage.Open(r.Storage)
+			if err != nil {
+				log.Fatalf("Failed to open routed storage %s: %v", r.Storage, err)
+			}
+			routes = append(routes, storage.Route{Pattern: r.Pattern, ContentType: r.ContentType, Backend: compress(backend)})
+		}
+		store = storage.NewRouter(store, routes...)
+		fmt.Printf("Storage routing enabled (%d routes)\n", len(routes))
+	}
+
+	// Time storage operations for /metrics and log slow ones
+	store = storage.NewInstrumented(store, time.Duration(cfg.Server.SlowStorageMillis)*time.Millisecond)
+
+	// Create server without web UI, keeping upload sessions in a single
+	// database instead of one file each if configured
+	var srv *server.Server
+	var sessions *resume.BoltStore
+	if cfg.Server.SessionStore == "bolt" {
+		sessions, err = resume.NewBoltStore(filepath.Join(cfg.Server.MetaDir, "sessions.db"))
+		if err != nil {
+			log.Fatalf("Failed to open session store: %v", err)
+		}
+		srv, err = server.NewWithSessionStore(store, sessions, cfg.Server.MetaDir)
+		fmt.Println("Upload sessions stored in sessions.db")
+	} else {
+		srv, err = server.New(store, cfg.Server.MetaDir)
+	}
 	if err != nil {
 		log.Fatalf("Failed to create server: %v", err)
 	}
 
+	if cfg.Server.MaxConnections > 0 {
+		srv.SetMaxConnections(cfg.Server.MaxConnections)
+	}
+	srv.SetMaxSessionsPerUser(cfg.Server.MaxSessionsPerUser)
+	srv.SetMaxFilesPerDir(cfg.Server.MaxFilesPerDir)
+	srv.SetMaxFileSize(maxFileSize)
+	srv.SetMaxTotalBytes(cfg.Server.MaxTotalBytes)
+	srv.SetOverwritePolicy(cfg.Server.OverwritePolicy)
+	srv.SetReadinessThresholds(cfg.Server.ReadyMaxSessions, cfg.Server.ReadyMaxConnections)
+
+	// Unfinished uploads are discarded once abandoned, so their chunks don't
+	// pile up in the metadata directory
+	if cfg.Server.SessionMaxAgeHours != 0 || cfg.Server.AbandonedUploadGraceMinutes != 0 {
+		maxAge, grace := server.DefaultSessionMaxAge, server.DefaultAbandonedUploadGrace
+		if cfg.Server.SessionMaxAgeHours != 0 {
+			maxAge = time.Duration(max(cfg.Server.SessionMaxAgeHours, 0)) * time.Hour
+		}
+		if cfg.Server.AbandonedUploadGraceMinutes != 0 {
+			grace = time.Duration(max(cfg.Server.AbandonedUploadGraceMinutes, 0)) * time.Minute
+		}
+		srv.SetSessionCleanup(maxAge, grace)
+	}
+
+	var auditLog *audit.FileLogger
+	if cfg.Server.AuditLog != "" {
+		auditLog, err = audit.NewFileLogger(cfg.Server.AuditLog)
+		if err != nil {
+			log.Fatalf("Failed to open audit log: %v", err)
+		}
+		srv.SetAuditLogger(auditLog)
+		fmt.Printf("Audit log: %s\n", cfg.Server.AuditLog)
+	}
+
 	// Enable authentication if token file provided
 	if cfg.Server.TokensFile != "" {
 		tokenStore, err := auth.NewTokenStore(cfg.Server.TokensFile)
@@ -101,23 +234,84 @@ func main() {
 		}
 		srv.EnableAuth(tokenStore)
 		fmt.Printf("Authentication enabled: %s\n", cfg.Server.TokensFile)
+
+		if cfg.Server.AuthMaxFailures != 0 || cfg.Server.AuthFailureWindowSeconds > 0 {
+			maxFailures, window := auth.DefaultMaxFailures, auth.DefaultFailureWindow
+			if cfg.Server.AuthMaxFailures != 0 {
+				maxFailures = cfg.Server.AuthMaxFailures
+			}
+			if cfg.Server.AuthFailureWindowSeconds > 0 {
+				window = time.Duration(cfg.Server.AuthFailureWindowSeconds) * time.Second
+			}
+			srv.SetAuthFailureLimit(maxFailures, window)
+		}
+
+		if cfg.Server.MaxChallenges > 0 {
+			srv.SetMaxChallenges(cfg.Server.MaxChallenges)
+		}
+		if cfg.Server.ChallengesFile != "" {
+			if err := srv.PersistChallenges(cfg.Server.ChallengesFile); err != nil {
+				log.Fatalf("Failed to load challenges: %v", err)
+			}
+		}
 	}
 
-	// Create server config for sharing with clients
-	serverConfig := &server.ServerConfig{
-		Version:     "0.1.0-lite",
-		AuthEnabled: cfg.Server.TokensFile != "",
+	srv.SetName(cfg.Server.ServerName)
+
+	if f := cfg.Server.UploadFilter; f != nil {
+		srv.SetUploadFilter(server.UploadFilter{
+			AllowExtensions: f.AllowExtensions,
+			DenyExtensions:  f.DenyExtensions,
+			AllowTypes:      f.AllowTypes,
+			DenyTypes:       f.DenyTypes,
+		})
+		fmt.Println("Upload filter enabled")
+	}
+
+	if len(cfg.Server.ResponseHeaders) > 0 {
+		srv.SetResponseHeaders(cfg.Server.ResponseHeaders)
+	}
+
+	if len(cfg.Server.CompletionActions) > 0 {
+		actions := make([]server.CompletionAction, len(cfg.Server.CompletionActions))
+		for i, action := range cfg.Server.CompletionActions {
+			actions[i] = server.CompletionAction{Pattern: action.Pattern, Target: action.Target}
+		}
+		if err := srv.SetCompletionActions(actions); err != nil {
+			log.Fatalf("Invalid completion actions: %v", err)
+		}
+		fmt.Printf("Completion actions enabled: %d\n", len(actions))
+	}
+
+	if len(cfg.Server.TrustedProxies) > 0 {
+		if err := srv.SetTrustedProxies(cfg.Server.TrustedProxies, cfg.Server.ProxyHeaders); err != nil {
+			log.Fatalf("Invalid trusted proxies: %v", err)
+		}
+		fmt.Printf("Trusting client addresses forwarded by: %s\n", strings.Join(cfg.Server.TrustedProxies, ", "))
 	}
-	serverConfig.Server.Address = cfg.Server.Address
-	serverConfig.Server.StorageDir = cfg.Server.StorageDir
-	serverConfig.Server.MetaDir = cfg.Server.MetaDir
-	serverConfig.Server.TokensFile = cfg.Server.TokensFile
-	serverConfig.Server.MaxFileSize = 1024 * 1024 * 1024 // 1GB default
-	srv.SetConfig(serverConfig)
+
+	srv.SetShowHidden(cfg.Server.ShowHidden)
+	srv.SetUpdateManifest(cfg.Server.UpdateManifest)
+
+	// Serve HTTPS if a certificate is configured
+	if cfg.Server.TLSCertFile != "" && cfg.Server.TLSKeyFile != "" {
+		srv.EnableTLS(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+		fmt.Printf("TLS enabled: %s\n", cfg.Server.TLSCertFile)
+	}
+
+	// Create server config for sharing with clients
+	srv.SetConfig(newServerConfig(&cfg.Server, srv.InstanceID(), srv.Scheme()))
 
 	// Enable discovery service
-	if err := srv.EnableDiscovery(cfg.Server.Address, "0.1.0-lite"); err != nil {
-		fmt.Printf("Warning: Failed to enable discovery: %v\n", err)
+	srv.SetDiscoveryOptions(server.DiscoveryOptions{
+		Port:     cfg.Server.DiscoveryPort,
+		Required: cfg.Server.DiscoveryRequired,
+		Mode:     cfg.Server.DiscoveryMode,
+		Secret:   cfg.Server.DiscoverySecret,
+		Address:  cfg.Server.DiscoveryAddress,
+	})
+	if err := srv.EnableDiscovery(cfg.Server.Address, serverVersion); err != nil {
+		log.Fatalf("Failed to enable discovery: %v", err)
 	}
 
 	// Enable automatic firewall configuration
@@ -125,10 +319,36 @@ func main() {
 
 	fmt.Printf("Starting goflux-lite server on %s\n", cfg.Server.Address)
 	fmt.Printf("Storage directory: %s\n", cfg.Server.StorageDir)
-	fmt.Printf("Configuration: %s\n", *configFile)
+	fmt.Printf("Configuration: %s\n", strings.Join(configFiles, " + "))
 
 	// Start server
-	if err := srv.Start(cfg.Server.Address); err != nil {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Start(cfg.Server.Address)
+	}()
+
+	// Shut down cleanly on Ctrl+C or SIGTERM
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case err := <-errCh:
 		log.Fatalf("Server failed: %v", err)
+	case sig := <-sigCh:
+		fmt.Printf("\nReceived %s, shutting down...\n", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Fatalf("Shutdown failed: %v", err)
+		}
+		if sessions != nil {
+			if err := sessions.Close(); err != nil {
+				log.Fatalf("Failed to close session store: %v", err)
+			}
+		}
+		if auditLog != nil {
+			auditLog.Close()
+		}
+		fmt.Println("Server stopped")
 	}
 }
@@ -1,3 +1,9 @@
 module github.com/0xRepo-Source/goflux-lite
 
 go 1.21
+
+require (
+	go.etcd.io/bbolt v1.3.10
+	golang.org/x/crypto v0.17.0
+	golang.org/x/sys v0.15.0
+)
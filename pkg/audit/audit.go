@@ -0,0 +1,82 @@
+// Package audit records who authenticated, uploaded, downloaded and deleted
+// what on a goflux server.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Actions recorded in Event.Action. Permission denials by the auth
+// middleware are recorded under the name of the permission required.
+const (
+	ActionAuth     = "auth"
+	ActionUpload   = "upload"
+	ActionDownload = "download"
+	ActionDelete   = "delete"
+)
+
+// Results recorded in Event.Result
+const (
+	ResultOK     = "ok"
+	ResultDenied = "denied" // refused for lack of credentials, permission or path scope
+	ResultFailed = "failed" // allowed, but the operation itself went wrong
+)
+
+// Event is one audited request
+type Event struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user,omitempty"` // empty for unauthenticated requests
+	Action   string    `json:"action"`
+	Path     string    `json:"path,omitempty"`
+	RemoteIP string    `json:"remote_ip"`
+	Result   string    `json:"result"`
+	Detail   string    `json:"detail,omitempty"` // why a request was denied or failed
+}
+
+// Logger records audit events. Implementations must be safe for concurrent
+// use and should not block the request for long.
+type Logger interface {
+	Log(event Event)
+}
+
+// FileLogger appends events to a file as JSON lines
+type FileLogger struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewFileLogger opens filename for appending, creating it readable only by
+// its owner if it doesn't exist
+func NewFileLogger(filename string) (*FileLogger, error) {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &FileLogger{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Log appends event as one line. Events without a time are stamped with the
+// current time. A failed write is reported but doesn't stop the server.
+func (l *FileLogger) Log(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(event); err != nil {
+		fmt.Printf("Warning: failed to write audit log: %v\n", err)
+	}
+}
+
+// Close closes the file
+func (l *FileLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
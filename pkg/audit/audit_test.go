@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileLogger(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+
+	logger, err := NewFileLogger(filename)
+	if err != nil {
+		t.Fatalf("NewFileLogger failed: %v", err)
+	}
+	logger.Log(Event{User: "alice", Action: ActionUpload, Path: "docs/a.txt", RemoteIP: "10.0.0.1", Result: ResultOK})
+	logger.Close()
+
+	// Reopening appends rather than truncating
+	logger, err = NewFileLogger(filename)
+	if err != nil {
+		t.Fatalf("NewFileLogger failed: %v", err)
+	}
+	logger.Log(Event{Action: ActionAuth, RemoteIP: "10.0.0.2", Result: ResultDenied, Detail: "Invalid token ID"})
+	logger.Close()
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("line %q is not a JSON event: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if e := events[0]; e.User != "alice" || e.Action != ActionUpload || e.Path != "docs/a.txt" || e.Result != ResultOK {
+		t.Errorf("unexpected first event: %+v", e)
+	}
+	if e := events[1]; e.Action != ActionAuth || e.Result != ResultDenied || e.Detail != "Invalid token ID" {
+		t.Errorf("unexpected second event: %+v", e)
+	}
+	for _, e := range events {
+		if time.Since(e.Time) > time.Minute {
+			t.Errorf("expected events to be stamped with the current time, got %v", e.Time)
+		}
+	}
+}
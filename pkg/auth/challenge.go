@@ -1,31 +1,50 @@
 package auth
 
 import (
+	"container/list"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 )
 
+const (
+	// DefaultMaxChallenges is how many unanswered challenges are kept before
+	// the oldest are evicted
+	DefaultMaxChallenges = 10000
+	// ChallengeTTL is how long a client has to answer a challenge
+	ChallengeTTL = 5 * time.Minute
+)
+
 // Challenge represents an authentication challenge
 type Challenge struct {
 	Nonce     string    `json:"nonce"`
 	ExpiresAt time.Time `json:"expires_at"`
 }
 
-// ChallengeStore manages active authentication challenges
+// ChallengeStore manages active authentication challenges. It holds a
+// limited number, evicting the oldest to make room, so a flood of requests
+// for challenges can't exhaust memory.
 type ChallengeStore struct {
-	challenges map[string]*Challenge // nonce -> challenge
-	mu         sync.RWMutex
+	challenges map[string]*list.Element // nonce -> element of order
+	order      *list.List               // *Challenge values, oldest first
+	max        int
+	filename   string // file challenges are saved to by Flush (empty = memory only)
+	dirty      bool   // challenges changed since the last save
+	mu         sync.Mutex
 }
 
 // NewChallengeStore creates a new challenge store
 func NewChallengeStore() *ChallengeStore {
 	store := &ChallengeStore{
-		challenges: make(map[string]*Challenge),
+		challenges: make(map[string]*list.Element),
+		order:      list.New(),
+		max:        DefaultMaxChallenges,
 	}
 
 	// Start cleanup goroutine
@@ -34,6 +53,42 @@ func NewChallengeStore() *ChallengeStore {
 	return store
 }
 
+// SetMaxChallenges sets how many unanswered challenges are kept. Generating
+// another evicts the oldest, whose client then has to ask again. Values
+// below one are ignored.
+func (cs *ChallengeStore) SetMaxChallenges(max int) {
+	if max < 1 {
+		return
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.max = max
+	cs.evict(max)
+}
+
+// evict removes the oldest challenges until at most n remain. The caller
+// must hold cs.mu.
+func (cs *ChallengeStore) evict(n int) {
+	for cs.order.Len() > n {
+		cs.remove(cs.order.Front())
+	}
+}
+
+// remove forgets the challenge held by elem. The caller must hold cs.mu.
+func (cs *ChallengeStore) remove(elem *list.Element) {
+	delete(cs.challenges, cs.order.Remove(elem).(*Challenge).Nonce)
+	cs.dirty = true
+}
+
+// add stores challenge as the newest, evicting the oldest if the store is
+// full. The caller must hold cs.mu.
+func (cs *ChallengeStore) add(challenge *Challenge) {
+	cs.evict(cs.max - 1)
+	cs.challenges[challenge.Nonce] = cs.order.PushBack(challenge)
+	cs.dirty = true
+}
+
 // GenerateChallenge creates a new random challenge
 func (cs *ChallengeStore) GenerateChallenge() (*Challenge, error) {
 	// Generate 32 random bytes
@@ -45,30 +100,31 @@ func (cs *ChallengeStore) GenerateChallenge() (*Challenge, error) {
 	nonce := hex.EncodeToString(nonceBytes)
 	challenge := &Challenge{
 		Nonce:     nonce,
-		ExpiresAt: time.Now().Add(5 * time.Minute), // 5 minute expiry
+		ExpiresAt: time.Now().Add(ChallengeTTL),
 	}
 
 	cs.mu.Lock()
-	cs.challenges[nonce] = challenge
+	cs.add(challenge)
 	cs.mu.Unlock()
 
 	return challenge, nil
 }
 
-// ValidateResponse validates an HMAC response against a challenge
+// ValidateResponse validates an HMAC response against a challenge. Each
+// challenge can be answered once, so it is removed whatever the outcome.
 func (cs *ChallengeStore) ValidateResponse(nonce, response, token string) (bool, error) {
-	cs.mu.RLock()
-	challenge, exists := cs.challenges[nonce]
-	cs.mu.RUnlock()
+	cs.mu.Lock()
+	elem, exists := cs.challenges[nonce]
+	if exists {
+		cs.remove(elem)
+	}
+	cs.mu.Unlock()
 
 	if !exists {
 		return false, fmt.Errorf("invalid or expired nonce")
 	}
 
-	if time.Now().After(challenge.ExpiresAt) {
-		cs.mu.Lock()
-		delete(cs.challenges, nonce)
-		cs.mu.Unlock()
+	if time.Now().After(elem.Value.(*Challenge).ExpiresAt) {
 		return false, fmt.Errorf("challenge expired")
 	}
 
@@ -78,14 +134,66 @@ func (cs *ChallengeStore) ValidateResponse(nonce, response, token string) (bool,
 	expectedResponse := hex.EncodeToString(h.Sum(nil))
 
 	// Compare using constant-time comparison
-	valid := hmac.Equal([]byte(response), []byte(expectedResponse))
+	return hmac.Equal([]byte(response), []byte(expectedResponse)), nil
+}
+
+// Persist keeps the challenges in filename, so clients part way through
+// authenticating aren't turned away after a restart. Unexpired challenges
+// already in the file are loaded; a missing file is not an error. The file
+// is written by Flush and after each cleanup of expired challenges.
+func (cs *ChallengeStore) Persist(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error reading challenge file: %w", err)
+	}
+	var saved []Challenge
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return fmt.Errorf("error parsing challenge file: %w", err)
+		}
+	}
 
-	// Delete used challenge (prevent replay)
 	cs.mu.Lock()
-	delete(cs.challenges, nonce)
-	cs.mu.Unlock()
+	defer cs.mu.Unlock()
 
-	return valid, nil
+	now := time.Now()
+	for i := range saved {
+		if _, exists := cs.challenges[saved[i].Nonce]; !exists && now.Before(saved[i].ExpiresAt) {
+			cs.add(&saved[i])
+		}
+	}
+	cs.filename = filename
+	return nil
+}
+
+// Flush saves the challenges to the file given to Persist, if any
+func (cs *ChallengeStore) Flush() error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	return cs.save()
+}
+
+// save writes the challenges to cs.filename, oldest first, if they changed.
+// The caller must hold cs.mu.
+func (cs *ChallengeStore) save() error {
+	if cs.filename == "" || !cs.dirty {
+		return nil
+	}
+
+	saved := make([]Challenge, 0, cs.order.Len())
+	for elem := cs.order.Front(); elem != nil; elem = elem.Next() {
+		saved = append(saved, *elem.Value.(*Challenge))
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return fmt.Errorf("error encoding challenge file: %w", err)
+	}
+	if err := writeFileAtomic(cs.filename, data); err != nil {
+		return fmt.Errorf("error writing challenge file: %w", err)
+	}
+	cs.dirty = false
+	return nil
 }
 
 // cleanupExpired removes expired challenges periodically
@@ -95,11 +203,13 @@ func (cs *ChallengeStore) cleanupExpired() {
 
 	for range ticker.C {
 		cs.mu.Lock()
+		// Every challenge lives for ChallengeTTL, so the oldest expire first
 		now := time.Now()
-		for nonce, challenge := range cs.challenges {
-			if now.After(challenge.ExpiresAt) {
-				delete(cs.challenges, nonce)
-			}
+		for elem := cs.order.Front(); elem != nil && now.After(elem.Value.(*Challenge).ExpiresAt); elem = cs.order.Front() {
+			cs.remove(elem)
+		}
+		if err := cs.save(); err != nil {
+			fmt.Printf("Warning: failed to save challenges: %v\n", err)
 		}
 		cs.mu.Unlock()
 	}
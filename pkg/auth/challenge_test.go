@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// answer returns the response to nonce for a token with the given hash
+func answer(nonce, tokenHash string) string {
+	h := hmac.New(sha256.New, []byte(tokenHash))
+	h.Write([]byte(nonce))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestChallengeStore_ValidateResponse(t *testing.T) {
+	cs := NewChallengeStore()
+	challenge, err := cs.GenerateChallenge()
+	if err != nil {
+		t.Fatalf("GenerateChallenge failed: %v", err)
+	}
+
+	valid, err := cs.ValidateResponse(challenge.Nonce, answer(challenge.Nonce, "hash"), "hash")
+	if err != nil || !valid {
+		t.Fatalf("expected a valid response, got %v, %v", valid, err)
+	}
+
+	// A challenge can only be answered once
+	if _, err := cs.ValidateResponse(challenge.Nonce, answer(challenge.Nonce, "hash"), "hash"); err == nil {
+		t.Error("expected a replayed nonce to be rejected")
+	}
+}
+
+func TestChallengeStore_Expired(t *testing.T) {
+	cs := NewChallengeStore()
+	challenge, err := cs.GenerateChallenge()
+	if err != nil {
+		t.Fatalf("GenerateChallenge failed: %v", err)
+	}
+
+	cs.mu.Lock()
+	cs.challenges[challenge.Nonce].Value.(*Challenge).ExpiresAt = time.Now().Add(-time.Second)
+	cs.mu.Unlock()
+
+	valid, err := cs.ValidateResponse(challenge.Nonce, answer(challenge.Nonce, "hash"), "hash")
+	if err == nil || valid {
+		t.Errorf("expected an expired nonce to be rejected, got %v, %v", valid, err)
+	}
+}
+
+func TestChallengeStore_EvictsOldest(t *testing.T) {
+	cs := NewChallengeStore()
+	cs.SetMaxChallenges(3)
+
+	var nonces []string
+	for i := 0; i < 4; i++ {
+		challenge, err := cs.GenerateChallenge()
+		if err != nil {
+			t.Fatalf("GenerateChallenge failed: %v", err)
+		}
+		nonces = append(nonces, challenge.Nonce)
+	}
+
+	cs.mu.Lock()
+	held := len(cs.challenges)
+	cs.mu.Unlock()
+	if held != 3 {
+		t.Errorf("expected 3 challenges held, got %d", held)
+	}
+
+	if _, err := cs.ValidateResponse(nonces[0], answer(nonces[0], "hash"), "hash"); err == nil {
+		t.Error("expected the oldest challenge to have been evicted")
+	}
+	for _, nonce := range nonces[1:] {
+		if valid, err := cs.ValidateResponse(nonce, answer(nonce, "hash"), "hash"); err != nil || !valid {
+			t.Errorf("expected challenge %s to be kept, got %v, %v", nonce[:8], valid, err)
+		}
+	}
+}
+
+func TestChallengeStore_Persist(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "challenges.json")
+
+	cs := NewChallengeStore()
+	if err := cs.Persist(filename); err != nil {
+		t.Fatalf("Persist failed: %v", err)
+	}
+	kept, _ := cs.GenerateChallenge()
+	expired, _ := cs.GenerateChallenge()
+	cs.mu.Lock()
+	cs.challenges[expired.Nonce].Value.(*Challenge).ExpiresAt = time.Now().Add(-time.Second)
+	cs.mu.Unlock()
+	if err := cs.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatalf("challenge file not written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	// A restarted server still accepts answers to outstanding challenges
+	restarted := NewChallengeStore()
+	if err := restarted.Persist(filename); err != nil {
+		t.Fatalf("Persist failed: %v", err)
+	}
+	if valid, err := restarted.ValidateResponse(kept.Nonce, answer(kept.Nonce, "hash"), "hash"); err != nil || !valid {
+		t.Errorf("expected the saved challenge to be accepted, got %v, %v", valid, err)
+	}
+	if _, err := restarted.ValidateResponse(expired.Nonce, answer(expired.Nonce, "hash"), "hash"); err == nil {
+		t.Error("expected the expired challenge not to be loaded")
+	}
+}
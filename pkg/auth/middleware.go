@@ -5,15 +5,27 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/audit"
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
 )
 
 // Middleware provides authentication middleware for HTTP handlers
 type Middleware struct {
 	store          *TokenStore
 	challengeStore *ChallengeStore
+	loginTokens    *LoginTokens // tokens issued by /auth/login
+
+	failures *FailureLimiter              // failed attempts per client address
+	clientIP func(r *http.Request) string // address failures are counted against
+
+	auditLog audit.Logger // nil if auditing is off
 }
 
 // NewMiddleware creates a new auth middleware
@@ -21,7 +33,101 @@ func NewMiddleware(store *TokenStore) *Middleware {
 	return &Middleware{
 		store:          store,
 		challengeStore: NewChallengeStore(),
+		loginTokens:    NewLoginTokens(LoginTokenTTL),
+		failures:       NewFailureLimiter(DefaultMaxFailures, DefaultFailureWindow),
+		clientIP:       remoteIP,
+	}
+}
+
+// SetFailureLimit throttles a client with 429 Too Many Requests once it has
+// failed to authenticate max times within window; a successful attempt
+// clears its count. A max of zero or less turns throttling off.
+func (m *Middleware) SetFailureLimit(max int, window time.Duration) {
+	m.failures = NewFailureLimiter(max, window)
+}
+
+// SetClientIP sets how the client address failures are counted against is
+// found, for servers behind a proxy. The default is the request's peer.
+func (m *Middleware) SetClientIP(clientIP func(r *http.Request) string) {
+	m.clientIP = clientIP
+}
+
+// SetMaxChallenges sets how many unanswered challenges are kept; see
+// ChallengeStore.SetMaxChallenges
+func (m *Middleware) SetMaxChallenges(max int) {
+	m.challengeStore.SetMaxChallenges(max)
+}
+
+// PersistChallenges keeps outstanding challenges in filename across
+// restarts; see ChallengeStore.Persist
+func (m *Middleware) PersistChallenges(filename string) error {
+	return m.challengeStore.Persist(filename)
+}
+
+// Flush saves outstanding challenges, if they are persisted
+func (m *Middleware) Flush() error {
+	return m.challengeStore.Flush()
+}
+
+// SetAuditLogger records failed authentication, denied permissions and
+// password logins to logger. nil turns auditing off.
+func (m *Middleware) SetAuditLogger(logger audit.Logger) {
+	m.auditLog = logger
+}
+
+// audit records an authentication event for r from client, if auditing is on
+func (m *Middleware) audit(r *http.Request, client, user, action, result, detail string) {
+	if m.auditLog == nil {
+		return
 	}
+	m.auditLog.Log(audit.Event{
+		Time:     time.Now(),
+		User:     user,
+		Action:   action,
+		Path:     r.URL.Query().Get("path"),
+		RemoteIP: client,
+		Result:   result,
+		Detail:   detail,
+	})
+}
+
+// throttled writes 429 Too Many Requests and returns true if client has
+// failed to authenticate too often recently
+func (m *Middleware) throttled(w http.ResponseWriter, client string) bool {
+	blocked, retryAfter := m.failures.Blocked(client)
+	if !blocked {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+	errors.WriteJSON(w, http.StatusTooManyRequests, stderrors.New("Too many failed authentication attempts; try again later"))
+	return true
+}
+
+// refreshStore picks up changes made to the tokens file since it was loaded
+func (m *Middleware) refreshStore() {
+	if err := m.store.reloadIfChanged(); err != nil {
+		fmt.Printf("Warning: failed to reload tokens file: %v\n", err)
+	}
+}
+
+// validateBearer checks a bearer token against the token store and then
+// against the tokens issued by /auth/login, returning its user, permissions
+// and path scope
+func (m *Middleware) validateBearer(token string) (string, []string, PathScope, error) {
+	m.refreshStore()
+	stored, err := m.store.validate(token)
+	if errType, ok := errors.GetAuthErrorType(err); ok && errType == errors.AuthErrorInvalidToken {
+		user, permissions, err := m.loginTokens.Validate(token)
+		if err == nil && !m.store.activeUser(user) {
+			m.loginTokens.RevokeUser(user)
+			return "", nil, nil, errors.NewAuthError(errors.AuthErrorRevokedToken, "user has been disabled or removed")
+		}
+		return user, permissions, nil, err
+	}
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return stored.User, stored.Permissions, stored.PathPrefixes, nil
 }
 
 // RequireAuth wraps a handler to require authentication
@@ -31,12 +137,26 @@ func (m *Middleware) RequireAuth(requiredPermission string, next http.HandlerFun
 		// Extract token from Authorization header
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
+			errors.WriteJSON(w, http.StatusUnauthorized, errors.NewAuthError(errors.AuthErrorInvalidToken, "Authorization header required"))
 			return
 		}
 
+		client := m.clientIP(r)
+		if m.throttled(w, client) {
+			return
+		}
+		unauthorized := func(err error) {
+			m.failures.Fail(client)
+			m.audit(r, client, "", audit.ActionAuth, audit.ResultDenied, err.Error())
+			errors.WriteJSON(w, http.StatusUnauthorized, err)
+		}
+		invalid := func(message string) error {
+			return errors.NewAuthError(errors.AuthErrorInvalidToken, message)
+		}
+
 		var user string
 		var permissions []string
+		var scope PathScope
 		var err error
 
 		// Check if it's challenge-response format: "Challenge <response>;<nonce>;<token_id>"
@@ -45,7 +165,7 @@ func (m *Middleware) RequireAuth(requiredPermission string, next http.HandlerFun
 			parts := strings.Split(challengeData, ";")
 
 			if len(parts) != 3 {
-				http.Error(w, "Invalid challenge format. Expected: Challenge <response>;<nonce>;<token_id>", http.StatusUnauthorized)
+				unauthorized(invalid("Invalid challenge format. Expected: Challenge <response>;<nonce>;<token_id>"))
 				return
 			}
 
@@ -54,7 +174,7 @@ func (m *Middleware) RequireAuth(requiredPermission string, next http.HandlerFun
 			// Get token by ID
 			token := m.store.GetTokenByID(tokenID)
 			if token == nil {
-				http.Error(w, "Invalid token ID", http.StatusUnauthorized)
+				unauthorized(invalid("Invalid token ID"))
 				return
 			}
 
@@ -66,48 +186,53 @@ func (m *Middleware) RequireAuth(requiredPermission string, next http.HandlerFun
 			// Validate nonce expiry and prevent replay
 			_, err := m.challengeStore.ValidateResponse(nonce, response, token.TokenHash)
 			if err != nil {
-				http.Error(w, fmt.Sprintf("Challenge validation failed: %v", err), http.StatusUnauthorized)
+				unauthorized(errors.NewAuthErrorWithCause(errors.AuthErrorInvalidToken, "Challenge validation failed", err))
 				return
 			}
 
 			// Compare responses using constant-time comparison
 			if !hmac.Equal([]byte(response), []byte(expectedResponse)) {
-				http.Error(w, "Invalid challenge response", http.StatusUnauthorized)
+				unauthorized(invalid("Invalid challenge response"))
 				return
 			}
 
 			user = token.User
 			permissions = token.Permissions
+			scope = token.PathPrefixes
 
 		} else {
 			// Fall back to Bearer token (backward compatibility)
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) != 2 || parts[0] != "Bearer" {
-				http.Error(w, "Invalid authorization header format. Use: Bearer <token> or Challenge <data>", http.StatusUnauthorized)
+				unauthorized(invalid("Invalid authorization header format. Use: Bearer <token> or Challenge <data>"))
 				return
 			}
 
 			token := parts[1]
 
 			// Validate token
-			user, permissions, err = m.store.Validate(token)
+			user, permissions, scope, err = m.validateBearer(token)
 			if err != nil {
-				http.Error(w, fmt.Sprintf("Authentication failed: %v", err), http.StatusUnauthorized)
+				unauthorized(err)
 				return
 			}
 		}
 
+		m.failures.Reset(client)
+
 		// Check permission
 		if requiredPermission != "" && !HasPermission(permissions, requiredPermission) {
-			http.Error(w, fmt.Sprintf("Permission denied. Required: %s", requiredPermission), http.StatusForbidden)
+			m.audit(r, client, user, requiredPermission, audit.ResultDenied, "missing permission "+requiredPermission)
+			errors.WriteJSON(w, http.StatusForbidden, errors.NewAuthError(errors.AuthErrorInsufficientPermissions,
+				fmt.Sprintf("Permission denied. Required: %s", requiredPermission)))
 			return
 		}
 
 		// Set user in request context (optional, for logging)
 		r.Header.Set("X-Authenticated-User", user)
 
-		// Call the next handler
-		next(w, r)
+		// Handlers check their target paths against the token's scope
+		next(w, withScope(r, scope))
 	}
 }
 
@@ -115,13 +240,13 @@ func (m *Middleware) RequireAuth(requiredPermission string, next http.HandlerFun
 func (m *Middleware) HandleChallenge(w http.ResponseWriter, r *http.Request) {
 	challenge, err := m.challengeStore.GenerateChallenge()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to generate challenge: %v", err), http.StatusInternalServerError)
+		errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("Failed to generate challenge: %w", err))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(challenge); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to encode challenge: %v", err), http.StatusInternalServerError)
+		errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("Failed to encode challenge: %w", err))
 		return
 	}
 }
@@ -133,7 +258,7 @@ func (m *Middleware) OptionalAuth(next http.HandlerFunc) http.HandlerFunc {
 		if authHeader != "" {
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) == 2 && parts[0] == "Bearer" {
-				user, _, err := m.store.Validate(parts[1])
+				user, _, _, err := m.validateBearer(parts[1])
 				if err == nil {
 					r.Header.Set("X-Authenticated-User", user)
 				}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/audit"
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+const (
+	// PasswordCost is the bcrypt work factor for new password hashes
+	PasswordCost = 12
+	// LoginTokenTTL is how long a token issued by /auth/login stays valid
+	LoginTokenTTL = time.Hour
+)
+
+// User is an account that can sign in with a password at /auth/login to
+// obtain a short-lived token carrying the user's permissions.
+type User struct {
+	Name         string    `json:"name"`
+	PasswordHash string    `json:"password_hash"` // from HashPassword
+	Permissions  []string  `json:"permissions"`
+	CreatedAt    time.Time `json:"created_at"`
+	Disabled     bool      `json:"disabled"`
+}
+
+// HashPassword returns a salted bcrypt hash of password, for storing in a
+// User. Passwords longer than 72 bytes are refused, as bcrypt would ignore
+// the rest.
+func HashPassword(password string) (string, error) {
+	return hashPassword(password, PasswordCost)
+}
+
+func hashPassword(password string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// VerifyPassword reports whether password matches a hash from HashPassword.
+// Malformed hashes never match.
+func VerifyPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+var (
+	// dummyPasswordHash is checked against when a login names an unknown
+	// user, so that the response takes as long as for a known user
+	dummyPasswordHash     string
+	dummyPasswordHashOnce sync.Once
+)
+
+// Authenticate checks a username and password against the store's users and
+// returns the user's permissions. Unknown users, disabled users and wrong
+// passwords all return AuthErrorInvalidCredentials, so a failed login doesn't
+// reveal which usernames exist.
+func (ts *TokenStore) Authenticate(name, password string) ([]string, error) {
+	ts.mu.RLock()
+	user, exists := ts.users[name]
+	ts.mu.RUnlock()
+
+	if !exists {
+		dummyPasswordHashOnce.Do(func() { dummyPasswordHash, _ = HashPassword("") })
+		VerifyPassword(dummyPasswordHash, password)
+		return nil, errors.NewAuthError(errors.AuthErrorInvalidCredentials, "invalid username or password")
+	}
+	if !VerifyPassword(user.PasswordHash, password) || user.Disabled {
+		return nil, errors.NewAuthError(errors.AuthErrorInvalidCredentials, "invalid username or password")
+	}
+	return user.Permissions, nil
+}
+
+// issuedToken is a token handed out by /auth/login
+type issuedToken struct {
+	user        string
+	permissions []string
+	expiresAt   time.Time
+}
+
+// LoginTokens holds the short-lived tokens issued to users who signed in with
+// a password. They are kept in memory only, so a restart signs everyone out.
+type LoginTokens struct {
+	mu     sync.Mutex
+	tokens map[string]*issuedToken // key is token hash
+	ttl    time.Duration
+}
+
+// NewLoginTokens creates a store of login tokens valid for ttl
+func NewLoginTokens(ttl time.Duration) *LoginTokens {
+	return &LoginTokens{tokens: make(map[string]*issuedToken), ttl: ttl}
+}
+
+// Issue returns a new token for user carrying permissions, and when it expires
+func (lt *LoginTokens) Issue(user string, permissions []string) (string, time.Time, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+	expiresAt := time.Now().Add(lt.ttl)
+
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	// Drop expired tokens so the map doesn't grow with every login
+	now := time.Now()
+	for hash, issued := range lt.tokens {
+		if now.After(issued.expiresAt) {
+			delete(lt.tokens, hash)
+		}
+	}
+	lt.tokens[hashToken(token)] = &issuedToken{user: user, permissions: permissions, expiresAt: expiresAt}
+	return token, expiresAt, nil
+}
+
+// Validate returns the user and permissions of an issued token. Returns
+// AuthErrorInvalidToken for tokens that were never issued and
+// AuthErrorExpiredToken for tokens past their lifetime.
+func (lt *LoginTokens) Validate(token string) (string, []string, error) {
+	hash := hashToken(token)
+
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	issued, exists := lt.tokens[hash]
+	if !exists {
+		return "", nil, errors.NewAuthError(errors.AuthErrorInvalidToken, "invalid token")
+	}
+	if time.Now().After(issued.expiresAt) {
+		delete(lt.tokens, hash)
+		return "", nil, errors.NewAuthError(errors.AuthErrorExpiredToken, "token has expired")
+	}
+	return issued.user, issued.permissions, nil
+}
+
+// RevokeUser invalidates every token issued to user, once the user has been
+// disabled or removed
+func (lt *LoginTokens) RevokeUser(user string) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	for hash, issued := range lt.tokens {
+		if issued.user == user {
+			delete(lt.tokens, hash)
+		}
+	}
+}
+
+// LoginRequest is the body of a POST to /auth/login
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginResponse is returned by /auth/login for valid credentials. The token
+// is sent as "Authorization: Bearer <token>" like any other.
+type LoginResponse struct {
+	Token       string    `json:"token"`
+	User        string    `json:"user"`
+	Permissions []string  `json:"permissions"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// HandleLogin exchanges a username and password for a short-lived token
+func (m *Middleware) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.WriteJSON(w, http.StatusMethodNotAllowed, stderrors.New("method not allowed"))
+		return
+	}
+
+	var req LoginRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		errors.WriteJSON(w, http.StatusBadRequest, fmt.Errorf("invalid login request: %w", err))
+		return
+	}
+
+	client := m.clientIP(r)
+	if m.throttled(w, client) {
+		return
+	}
+	m.refreshStore()
+	permissions, err := m.store.Authenticate(req.Username, req.Password)
+	if err != nil {
+		m.failures.Fail(client)
+		m.audit(r, client, req.Username, audit.ActionAuth, audit.ResultDenied, "password login failed")
+		errors.WriteJSON(w, http.StatusUnauthorized, err)
+		return
+	}
+	m.failures.Reset(client)
+	m.audit(r, client, req.Username, audit.ActionAuth, audit.ResultOK, "password login")
+
+	token, expiresAt, err := m.loginTokens.Issue(req.Username, permissions)
+	if err != nil {
+		errors.WriteJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LoginResponse{
+		Token:       token,
+		User:        req.Username,
+		Permissions: permissions,
+		ExpiresAt:   expiresAt,
+	})
+}
@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := hashPassword("correct horse", bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hashPassword failed: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$2a$04$") {
+		t.Errorf("unexpected hash format: %s", hash)
+	}
+	if !VerifyPassword(hash, "correct horse") {
+		t.Error("expected the password to verify")
+	}
+	if VerifyPassword(hash, "correct horse!") {
+		t.Error("expected a different password to be rejected")
+	}
+
+	// Salts differ, so the same password hashes differently each time
+	again, _ := hashPassword("correct horse", bcrypt.MinCost)
+	if again == hash {
+		t.Error("expected a fresh salt for every hash")
+	}
+
+	for _, malformed := range []string{"", "plaintext", "$2a$04$short", "pbkdf2-sha256$1$c2FsdA$aGFzaA"} {
+		if VerifyPassword(malformed, "") {
+			t.Errorf("expected malformed hash %q never to match", malformed)
+		}
+	}
+}
+
+// newLoginMiddleware returns a middleware whose token file holds an active
+// user alice and a disabled user mallory, both with password "s3cret"
+func newLoginMiddleware(t *testing.T) *Middleware {
+	t.Helper()
+	hash, err := hashPassword("s3cret", bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hashPassword failed: %v", err)
+	}
+	storeFile := TokenStoreFile{
+		Tokens: []Token{},
+		Users: []User{
+			{Name: "alice", PasswordHash: hash, Permissions: []string{"download", "list"}, CreatedAt: time.Now()},
+			{Name: "mallory", PasswordHash: hash, Permissions: []string{"*"}, CreatedAt: time.Now(), Disabled: true},
+		},
+	}
+	data, _ := json.Marshal(storeFile)
+	tokenFile := filepath.Join(t.TempDir(), "tokens.json")
+	os.WriteFile(tokenFile, data, 0644)
+
+	store, err := NewTokenStore(tokenFile)
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+	return NewMiddleware(store)
+}
+
+// login posts credentials to the login handler
+func login(m *Middleware, username, password string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(LoginRequest{Username: username, Password: password})
+	rec := httptest.NewRecorder()
+	m.HandleLogin(rec, httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body)))
+	return rec
+}
+
+func TestHandleLogin_Success(t *testing.T) {
+	m := newLoginMiddleware(t)
+
+	rec := login(m, "alice", "s3cret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp LoginResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode login response: %v", err)
+	}
+	if resp.Token == "" || resp.User != "alice" {
+		t.Fatalf("unexpected login response: %+v", resp)
+	}
+	if ttl := time.Until(resp.ExpiresAt); ttl <= 0 || ttl > LoginTokenTTL {
+		t.Errorf("expected the token to expire within %v, got %v", LoginTokenTTL, ttl)
+	}
+
+	// The issued token authenticates requests with the user's permissions
+	handler := func(permission string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/list", nil)
+		req.Header.Set("Authorization", "Bearer "+resp.Token)
+		rec := httptest.NewRecorder()
+		m.RequireAuth(permission, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Header.Get("X-Authenticated-User")))
+		})(rec, req)
+		return rec
+	}
+	if rec := handler("list"); rec.Code != http.StatusOK || rec.Body.String() != "alice" {
+		t.Errorf("expected the issued token to be accepted for alice, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := handler("upload"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a permission alice lacks, got %d", rec.Code)
+	}
+}
+
+func TestHandleLogin_InvalidCredentials(t *testing.T) {
+	m := newLoginMiddleware(t)
+
+	tests := []struct{ name, username, password string }{
+		{"wrong password", "alice", "guess"},
+		{"unknown user", "bob", "s3cret"},
+		{"disabled user", "mallory", "s3cret"},
+	}
+	for _, tt := range tests {
+		rec := login(m, tt.username, tt.password)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d", tt.name, rec.Code)
+		}
+		if resp, ok := errors.ParseResponse(rec.Body.Bytes()); !ok || resp.Type != "auth.invalid_credentials" {
+			t.Errorf("%s: expected an auth.invalid_credentials body, got %s", tt.name, rec.Body.String())
+		}
+
+		_, err := m.store.Authenticate(tt.username, tt.password)
+		if errType, ok := errors.GetAuthErrorType(err); !ok || errType != errors.AuthErrorInvalidCredentials {
+			t.Errorf("%s: expected AuthErrorInvalidCredentials, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestHandleLogin_BadRequest(t *testing.T) {
+	m := newLoginMiddleware(t)
+
+	rec := httptest.NewRecorder()
+	m.HandleLogin(rec, httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader("not json")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed body, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	m.HandleLogin(rec, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for GET, got %d", rec.Code)
+	}
+}
+
+func TestLoginTokens_Expiry(t *testing.T) {
+	tokens := NewLoginTokens(-time.Second) // already expired when issued
+
+	token, _, err := tokens.Issue("alice", []string{"*"})
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	_, _, err = tokens.Validate(token)
+	if errType, ok := errors.GetAuthErrorType(err); !ok || errType != errors.AuthErrorExpiredToken {
+		t.Errorf("expected AuthErrorExpiredToken, got %v", err)
+	}
+
+	_, _, err = tokens.Validate("never-issued")
+	if errType, ok := errors.GetAuthErrorType(err); !ok || errType != errors.AuthErrorInvalidToken {
+		t.Errorf("expected AuthErrorInvalidToken, got %v", err)
+	}
+}
+
+func TestHashPassword_TooLong(t *testing.T) {
+	if _, err := hashPassword(strings.Repeat("x", 73), bcrypt.MinCost); err == nil {
+		t.Error("expected a password beyond bcrypt's 72 bytes to be refused")
+	}
+}
+
+func TestLoginTokens_RevokedWithUser(t *testing.T) {
+	for _, change := range []string{"disabled", "removed"} {
+		m := newLoginMiddleware(t)
+		var resp LoginResponse
+		json.NewDecoder(login(m, "alice", "s3cret").Body).Decode(&resp)
+
+		check := func() int {
+			req := httptest.NewRequest(http.MethodGet, "/list", nil)
+			req.Header.Set("Authorization", "Bearer "+resp.Token)
+			rec := httptest.NewRecorder()
+			m.RequireAuth("list", func(w http.ResponseWriter, r *http.Request) {})(rec, req)
+			return rec.Code
+		}
+		if code := check(); code != http.StatusOK {
+			t.Fatalf("%s: expected the issued token to be accepted, got %d", change, code)
+		}
+
+		// gfl-admin edits the tokens file behind the running server
+		var storeFile TokenStoreFile
+		data, _ := os.ReadFile(m.store.filename)
+		json.Unmarshal(data, &storeFile)
+		users := storeFile.Users[:0]
+		for _, user := range storeFile.Users {
+			if user.Name == "alice" {
+				if change == "removed" {
+					continue
+				}
+				user.Disabled = true
+			}
+			users = append(users, user)
+		}
+		storeFile.Users = users
+		data, _ = json.Marshal(storeFile)
+		if err := writeFileAtomic(m.store.filename, data); err != nil {
+			t.Fatalf("failed to rewrite tokens file: %v", err)
+		}
+
+		if code := check(); code != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401 once the user is %s, got %d", change, change, code)
+		}
+		if _, _, err := m.loginTokens.Validate(resp.Token); err == nil {
+			t.Errorf("%s: expected the token to be revoked", change)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxFailures is how many failed authentication attempts a client
+	// may make within DefaultFailureWindow before being throttled
+	DefaultMaxFailures = 10
+	// DefaultFailureWindow is the sliding window failed attempts are counted in
+	DefaultFailureWindow = time.Minute
+	// MaxTrackedClients is how many clients' failures are tracked at once.
+	// Failures of further clients are counted together, so a flood of
+	// addresses can't grow memory without bound.
+	MaxTrackedClients = 10000
+)
+
+// overflowClient is the key the failures of clients beyond the tracking cap
+// are counted under
+const overflowClient = ""
+
+// FailureLimiter counts failed authentication attempts per client address
+// over a sliding window, so tokens and passwords can't be guessed at speed.
+type FailureLimiter struct {
+	mu       sync.Mutex
+	failures map[string][]time.Time // client address -> failure times, oldest first
+	max      int                    // failures allowed within window (0 = unlimited)
+	window   time.Duration
+
+	maxClients int       // clients tracked separately at once
+	nextPrune  time.Time // when clients whose failures aged out are next dropped
+}
+
+// NewFailureLimiter creates a limiter that throttles a client after max
+// failures within window. A max of zero or less never throttles.
+func NewFailureLimiter(max int, window time.Duration) *FailureLimiter {
+	if max < 0 {
+		max = 0
+	}
+	return &FailureLimiter{
+		failures:   make(map[string][]time.Time),
+		max:        max,
+		window:     window,
+		maxClients: MaxTrackedClients,
+	}
+}
+
+// key returns the key the failures of client are counted under: its own,
+// unless it isn't tracked yet and the cap is reached. The caller must hold
+// fl.mu.
+func (fl *FailureLimiter) key(client string) string {
+	if _, ok := fl.failures[client]; ok || len(fl.failures) < fl.maxClients {
+		return client
+	}
+	return overflowClient
+}
+
+// recent returns the failures of client still inside the window. The caller
+// must hold fl.mu.
+func (fl *FailureLimiter) recent(client string, now time.Time) []time.Time {
+	times := fl.failures[client]
+	for len(times) > 0 && now.Sub(times[0]) >= fl.window {
+		times = times[1:]
+	}
+	if len(times) == 0 {
+		delete(fl.failures, client)
+		return nil
+	}
+	fl.failures[client] = times
+	return times
+}
+
+// prune drops clients whose failures have all aged out, at most once per
+// window so a stream of failures doesn't rescan every client each time. The
+// caller must hold fl.mu.
+func (fl *FailureLimiter) prune(now time.Time) {
+	if now.Before(fl.nextPrune) {
+		return
+	}
+	for client := range fl.failures {
+		fl.recent(client, now)
+	}
+	fl.nextPrune = now.Add(fl.window)
+}
+
+// Blocked reports whether client has used up its failed attempts, and if so
+// how long until the oldest of them leaves the window
+func (fl *FailureLimiter) Blocked(client string) (bool, time.Duration) {
+	if fl.max == 0 {
+		return false, 0
+	}
+	now := time.Now()
+
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	times := fl.recent(fl.key(client), now)
+	if len(times) < fl.max {
+		return false, 0
+	}
+	return true, fl.window - now.Sub(times[0])
+}
+
+// Fail records a failed attempt by client
+func (fl *FailureLimiter) Fail(client string) {
+	if fl.max == 0 {
+		return
+	}
+	now := time.Now()
+
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	fl.prune(now)
+	key := fl.key(client)
+	times := append(fl.failures[key], now)
+	if len(times) > fl.max {
+		times = times[len(times)-fl.max:]
+	}
+	fl.failures[key] = times
+}
+
+// Reset forgets the failed attempts of client, after it authenticates
+func (fl *FailureLimiter) Reset(client string) {
+	fl.mu.Lock()
+	delete(fl.failures, client)
+	fl.mu.Unlock()
+}
+
+// remoteIP returns the address of the peer that sent r
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
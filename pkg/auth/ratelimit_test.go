@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newLimitedMiddleware returns a middleware allowing max failures per window
+// and a valid token for it
+func newLimitedMiddleware(t *testing.T, max int, window time.Duration) (*Middleware, string) {
+	t.Helper()
+
+	store, err := NewTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+	token, _, err := store.Create("alice", []string{"*"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	m := NewMiddleware(store)
+	m.SetFailureLimit(max, window)
+	return m, token
+}
+
+// authAs sends a request with a bearer token from addr through RequireAuth
+func authAs(m *Middleware, addr, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/list", nil)
+	req.RemoteAddr = addr
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	m.RequireAuth("list", func(w http.ResponseWriter, r *http.Request) {})(rec, req)
+	return rec
+}
+
+func TestRequireAuth_ThrottlesFailures(t *testing.T) {
+	m, token := newLimitedMiddleware(t, 3, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if rec := authAs(m, "10.0.0.1:1234", "wrong"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, rec.Code)
+		}
+	}
+
+	rec := authAs(m, "10.0.0.1:1234", "wrong")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 after 3 failures, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header")
+	}
+
+	// Throttled clients are refused even with a valid token
+	if rec := authAs(m, "10.0.0.1:5678", token); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 for a valid token from a throttled address, got %d", rec.Code)
+	}
+
+	// Other clients are unaffected
+	if rec := authAs(m, "10.0.0.2:1234", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 from another address, got %d", rec.Code)
+	}
+	if rec := authAs(m, "10.0.0.2:1234", token); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 from another address, got %d", rec.Code)
+	}
+}
+
+func TestRequireAuth_SuccessResetsFailures(t *testing.T) {
+	m, token := newLimitedMiddleware(t, 3, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		authAs(m, "10.0.0.1:1234", "wrong")
+	}
+	if rec := authAs(m, "10.0.0.1:1234", token); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	for i := 0; i < 3; i++ {
+		if rec := authAs(m, "10.0.0.1:1234", "wrong"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d after success: expected 401, got %d", i+1, rec.Code)
+		}
+	}
+}
+
+func TestRequireAuth_FailureWindowSlides(t *testing.T) {
+	m, _ := newLimitedMiddleware(t, 2, 100*time.Millisecond)
+
+	authAs(m, "10.0.0.1:1234", "wrong")
+	authAs(m, "10.0.0.1:1234", "wrong")
+	if rec := authAs(m, "10.0.0.1:1234", "wrong"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if rec := authAs(m, "10.0.0.1:1234", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 once the failures left the window, got %d", rec.Code)
+	}
+}
+
+func TestRequireAuth_FailureLimitDisabled(t *testing.T) {
+	m, _ := newLimitedMiddleware(t, 0, time.Minute)
+
+	for i := 0; i < DefaultMaxFailures+1; i++ {
+		if rec := authAs(m, "10.0.0.1:1234", "wrong"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401 with throttling off, got %d", i+1, rec.Code)
+		}
+	}
+}
+
+func TestFailureLimiter_PrunesAgedOutClients(t *testing.T) {
+	fl := NewFailureLimiter(3, 50*time.Millisecond)
+	for _, client := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		fl.Fail(client)
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	fl.Fail("10.0.0.4")
+	if len(fl.failures) != 1 {
+		t.Errorf("expected only the latest client to be tracked, got %d", len(fl.failures))
+	}
+}
+
+func TestFailureLimiter_CapsTrackedClients(t *testing.T) {
+	fl := NewFailureLimiter(2, time.Minute)
+	fl.maxClients = 2
+
+	fl.Fail("10.0.0.1")
+	fl.Fail("10.0.0.2")
+
+	// Further clients share one count once the cap is reached
+	fl.Fail("10.0.0.3")
+	fl.Fail("10.0.0.4")
+	if blocked, _ := fl.Blocked("10.0.0.5"); !blocked {
+		t.Error("expected untracked clients to be throttled together")
+	}
+	if blocked, _ := fl.Blocked("10.0.0.1"); blocked {
+		t.Error("expected a tracked client to keep its own count")
+	}
+	if len(fl.failures) > fl.maxClients+1 {
+		t.Errorf("expected at most %d entries, got %d", fl.maxClients+1, len(fl.failures))
+	}
+}
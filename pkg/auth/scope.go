@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+// PathScope is the set of path prefixes a token may touch. A path is in scope
+// if it is one of the prefixes or lies beneath one, so "docs" covers
+// "docs/a.txt" but not "docs-private". An empty scope allows every path.
+type PathScope []string
+
+// scopeKey is the request context key for the authenticated token's scope
+type scopeKey struct{}
+
+// cleanPath reduces a remote path to its slash-separated form without
+// leading or trailing slashes, resolving . and .. elements
+func cleanPath(p string) string {
+	return strings.Trim(path.Clean("/"+strings.ReplaceAll(p, "\\", "/")), "/")
+}
+
+// Allows reports whether p is one of the scope's prefixes or lies beneath one
+func (s PathScope) Allows(p string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	p = cleanPath(p)
+	for _, prefix := range s {
+		prefix = cleanPath(prefix)
+		if prefix == "" || p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Leads reports whether p is a directory above one of the scope's prefixes,
+// such as "projects" for the prefix "projects/alpha". Listing such a
+// directory shows only the entries leading to the prefixes.
+func (s PathScope) Leads(p string) bool {
+	p = cleanPath(p)
+	for _, prefix := range s {
+		prefix = cleanPath(prefix)
+		if (p == "" && prefix != "") || strings.HasPrefix(prefix, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// withScope returns r carrying the authenticated token's scope
+func withScope(r *http.Request, scope PathScope) *http.Request {
+	if len(scope) == 0 {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), scopeKey{}, scope))
+}
+
+// RequestScope returns the scope of the token that authenticated r. Requests
+// to servers without authentication, and tokens without path prefixes, get
+// an empty scope that allows every path.
+func RequestScope(r *http.Request) PathScope {
+	scope, _ := r.Context().Value(scopeKey{}).(PathScope)
+	return scope
+}
+
+// CheckPath returns AuthErrorInsufficientPermissions if the token that
+// authenticated r may not touch p
+func CheckPath(r *http.Request, p string) error {
+	if RequestScope(r).Allows(p) {
+		return nil
+	}
+	return errors.NewAuthError(errors.AuthErrorInsufficientPermissions,
+		fmt.Sprintf("token may not access %s", cleanPath(p)))
+}
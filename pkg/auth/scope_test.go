@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+func TestPathScope_Allows(t *testing.T) {
+	scope := PathScope{"docs", "projects/alpha/"}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"docs", true},
+		{"/docs/", true},
+		{"docs/a.txt", true},
+		{"docs\\sub\\b.txt", true},
+		{"docs-private", false},
+		{"docs-private/a.txt", false},
+		{"doc", false},
+		{"docs/../secret.txt", false},
+		{"projects/alpha/x", true},
+		{"projects/alphabet", false},
+		{"projects", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := scope.Allows(tt.path); got != tt.want {
+			t.Errorf("Allows(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if !PathScope(nil).Allows("anything/at/all") {
+		t.Error("expected an empty scope to allow every path")
+	}
+}
+
+func TestPathScope_Leads(t *testing.T) {
+	scope := PathScope{"projects/alpha"}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"", true},
+		{"/", true},
+		{"projects", true},
+		{"projects/alpha", false},
+		{"projects/alp", false},
+		{"other", false},
+	}
+	for _, tt := range tests {
+		if got := scope.Leads(tt.path); got != tt.want {
+			t.Errorf("Leads(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if PathScope(nil).Leads("") {
+		t.Error("expected an empty scope to lead nowhere")
+	}
+}
+
+func TestRequireAuth_PathScope(t *testing.T) {
+	store, err := NewTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+	scoped, _, err := store.Create("alice", []string{"download"}, time.Hour, "docs")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	unscoped, _, err := store.Create("bob", []string{"download"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	check := func(token, path string) error {
+		var checkErr error
+		handler := NewMiddleware(store).RequireAuth("download", func(w http.ResponseWriter, r *http.Request) {
+			checkErr = CheckPath(r, path)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/download", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		return checkErr
+	}
+
+	if err := check(scoped, "docs/a.txt"); err != nil {
+		t.Errorf("expected docs/a.txt to be allowed, got %v", err)
+	}
+	err = check(scoped, "docs-private/a.txt")
+	if typ, _ := errors.GetAuthErrorType(err); typ != errors.AuthErrorInsufficientPermissions {
+		t.Errorf("expected AuthErrorInsufficientPermissions for docs-private/a.txt, got %v", err)
+	}
+	if err := check(unscoped, "docs-private/a.txt"); err != nil {
+		t.Errorf("expected a token without prefixes to allow any path, got %v", err)
+	}
+}
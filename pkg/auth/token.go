@@ -4,11 +4,14 @@
 package auth
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -26,6 +29,8 @@ type Token struct {
 	CreatedAt   time.Time `json:"created_at"`
 	ExpiresAt   time.Time `json:"expires_at"`
 	Revoked     bool      `json:"revoked"`
+
+	PathPrefixes []string `json:"path_prefixes,omitempty"` // paths the token may touch; empty for all
 }
 
 // TokenStore manages authentication tokens with thread-safe access.
@@ -34,13 +39,17 @@ type Token struct {
 type TokenStore struct {
 	mu       sync.RWMutex
 	tokens   map[string]*Token // key is token hash
+	users    map[string]*User  // key is user name
 	filename string
+
+	loaded os.FileInfo // the file as last loaded or saved, to notice outside changes
 }
 
 // TokenStoreFile represents the JSON file format for persisting tokens.
 // This structure is used for serialization and deserialization of the token store.
 type TokenStoreFile struct {
 	Tokens []Token `json:"tokens"`
+	Users  []User  `json:"users,omitempty"`
 }
 
 // NewTokenStore creates a new token store that persists to the specified file.
@@ -49,6 +58,7 @@ type TokenStoreFile struct {
 func NewTokenStore(filename string) (*TokenStore, error) {
 	ts := &TokenStore{
 		tokens:   make(map[string]*Token),
+		users:    make(map[string]*User),
 		filename: filename,
 	}
 
@@ -59,7 +69,7 @@ func NewTokenStore(filename string) (*TokenStore, error) {
 	return ts, nil
 }
 
-// Load reads tokens from the configured file and populates the token store.
+// Load reads tokens and password users from the configured file and populates the token store.
 // If the file doesn't exist, this is not an error and returns nil.
 // Returns an error if the file cannot be read or contains invalid JSON.
 func (ts *TokenStore) Load() error {
@@ -74,8 +84,10 @@ func (ts *TokenStore) Load() error {
 		}
 		return fmt.Errorf("error reading token file: %w", err)
 	}
+	info, _ := os.Stat(ts.filename)
 
 	if len(data) == 0 {
+		ts.loaded = info
 		return nil
 	}
 
@@ -91,6 +103,13 @@ func (ts *TokenStore) Load() error {
 		ts.tokens[token.TokenHash] = token
 	}
 
+	ts.users = make(map[string]*User)
+	for i := range storeFile.Users {
+		user := &storeFile.Users[i]
+		ts.users[user.Name] = user
+	}
+
+	ts.loaded = info
 	return nil
 }
 
@@ -100,47 +119,243 @@ func (ts *TokenStore) Reload() error {
 	return ts.Load()
 }
 
-// GetTokenByID retrieves a token by its ID for challenge-response authentication.
-// Returns nil if the token is not found, revoked, or expired.
-func (ts *TokenStore) GetTokenByID(tokenID string) *Token {
+// reloadIfChanged reloads the file if it has changed since it was last loaded
+// or saved, as when gfl-admin revokes a token or disables a user
+func (ts *TokenStore) reloadIfChanged() error {
+	info, err := os.Stat(ts.filename)
+	if err != nil {
+		return nil // Nothing to load, or nothing that Load could read either
+	}
+	ts.mu.RLock()
+	loaded := ts.loaded
+	ts.mu.RUnlock()
+	if loaded != nil && os.SameFile(loaded, info) && loaded.ModTime().Equal(info.ModTime()) && loaded.Size() == info.Size() {
+		return nil
+	}
+	return ts.Load()
+}
+
+// activeUser reports whether name is a password user who isn't disabled
+func (ts *TokenStore) activeUser(name string) bool {
 	ts.mu.RLock()
 	defer ts.mu.RUnlock()
+	user, exists := ts.users[name]
+	return exists && !user.Disabled
+}
+
+// Create generates a token for user with the given permissions, valid for
+// validity from now, and saves it to the store's file. With pathPrefixes the
+// token may only touch paths under them. The returned raw token is the only
+// copy of the secret; the store keeps just its hash.
+func (ts *TokenStore) Create(user string, perms []string, validity time.Duration, pathPrefixes ...string) (string, Token, error) {
+	if user == "" {
+		return "", Token{}, fmt.Errorf("user is required")
+	}
+	if validity <= 0 {
+		return "", Token{}, fmt.Errorf("token validity must be positive, got %v", validity)
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", Token{}, fmt.Errorf("failed to generate token: %w", err)
+	}
+	idBytes := make([]byte, 6)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", Token{}, fmt.Errorf("failed to generate token ID: %w", err)
+	}
+	rawToken := hex.EncodeToString(tokenBytes)
+
+	now := time.Now()
+	token := &Token{
+		ID:          "tok_" + hex.EncodeToString(idBytes),
+		TokenHash:   hashToken(rawToken),
+		User:        user,
+		Permissions: perms,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(validity),
+
+		PathPrefixes: pathPrefixes,
+	}
+
+	if err := ts.Add(*token); err != nil {
+		return "", Token{}, err
+	}
+
+	return rawToken, *token, nil
+}
+
+// Add stores a token created elsewhere and saves the store's file. Returns an
+// error if the token has no ID or hash, or either is already in use.
+func (ts *TokenStore) Add(t Token) error {
+	if t.ID == "" || t.TokenHash == "" {
+		return fmt.Errorf("token must have an ID and a hash")
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if _, exists := ts.tokens[t.TokenHash]; exists {
+		return fmt.Errorf("token %s duplicates an existing token", t.ID)
+	}
+	if ts.tokenByID(t.ID) != nil {
+		return fmt.Errorf("token ID already in use: %s", t.ID)
+	}
+
+	token := t
+	ts.tokens[token.TokenHash] = &token
+	if err := ts.save(); err != nil {
+		delete(ts.tokens, token.TokenHash)
+		return err
+	}
+	return nil
+}
+
+// Revoke marks the token with the given ID as revoked and saves the store's
+// file, so the token stops validating here and in any store that reloads it.
+// Revoking a revoked token is not an error. Returns AuthErrorInvalidToken if
+// no token has the ID.
+func (ts *TokenStore) Revoke(tokenID string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	token := ts.tokenByID(tokenID)
+	if token == nil {
+		return errors.NewAuthError(errors.AuthErrorInvalidToken, "token not found: "+tokenID)
+	}
+	if token.Revoked {
+		return nil
+	}
+
+	token.Revoked = true
+	if err := ts.save(); err != nil {
+		token.Revoked = false
+		return err
+	}
+	return nil
+}
+
+// Save writes the tokens and users to the store's file
+func (ts *TokenStore) Save() error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.save()
+}
 
+// tokenByID returns the token with the given ID whatever its state, or nil.
+// The caller must hold ts.mu.
+func (ts *TokenStore) tokenByID(tokenID string) *Token {
 	for _, token := range ts.tokens {
 		if token.ID == tokenID {
-			if token.Revoked || time.Now().After(token.ExpiresAt) {
-				return nil
-			}
 			return token
 		}
 	}
 	return nil
 }
 
+// save writes the tokens and users to the store's file, oldest token first.
+// The file is replaced atomically, so a crash or a concurrent Load never sees
+// it half written. The caller must hold ts.mu.
+func (ts *TokenStore) save() error {
+	var storeFile TokenStoreFile
+	storeFile.Tokens = make([]Token, 0, len(ts.tokens))
+	for _, token := range ts.tokens {
+		storeFile.Tokens = append(storeFile.Tokens, *token)
+	}
+	sort.Slice(storeFile.Tokens, func(i, j int) bool {
+		a, b := storeFile.Tokens[i], storeFile.Tokens[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	for _, user := range ts.users {
+		storeFile.Users = append(storeFile.Users, *user)
+	}
+	sort.Slice(storeFile.Users, func(i, j int) bool {
+		return storeFile.Users[i].Name < storeFile.Users[j].Name
+	})
+
+	data, err := json.MarshalIndent(storeFile, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding token file: %w", err)
+	}
+
+	if err := writeFileAtomic(ts.filename, data); err != nil {
+		return fmt.Errorf("error writing token file: %w", err)
+	}
+	ts.loaded, _ = os.Stat(ts.filename)
+	return nil
+}
+
+// writeFileAtomic replaces filename with data readable only by its owner. The
+// data is written to a temporary file beside it and renamed into place, so a
+// crash never leaves a partly written file.
+func writeFileAtomic(filename string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once renamed into place
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0600)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, filename)
+	}
+	return err
+}
+
+// GetTokenByID retrieves a token by its ID for challenge-response authentication.
+// Returns nil if the token is not found, revoked, or expired.
+func (ts *TokenStore) GetTokenByID(tokenID string) *Token {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	token := ts.tokenByID(tokenID)
+	if token == nil || token.Revoked || time.Now().After(token.ExpiresAt) {
+		return nil
+	}
+	return token
+}
+
 // Validate checks if a token string is valid and returns the associated user and permissions.
 // The token is hashed before lookup. Returns AuthError types for invalid, revoked, or expired tokens.
 func (ts *TokenStore) Validate(tokenStr string) (string, []string, error) {
+	token, err := ts.validate(tokenStr)
+	if err != nil {
+		return "", nil, err
+	}
+	return token.User, token.Permissions, nil
+}
+
+// validate is Validate, returning a copy of the whole token
+func (ts *TokenStore) validate(tokenStr string) (Token, error) {
 	// Hash the provided token
-	hash := sha256.Sum256([]byte(tokenStr))
-	tokenHash := hex.EncodeToString(hash[:])
+	tokenHash := hashToken(tokenStr)
 
 	ts.mu.RLock()
 	defer ts.mu.RUnlock()
 
 	token, exists := ts.tokens[tokenHash]
 	if !exists {
-		return "", nil, errors.NewAuthError(errors.AuthErrorInvalidToken, "invalid token")
+		return Token{}, errors.NewAuthError(errors.AuthErrorInvalidToken, "invalid token")
 	}
 
 	if token.Revoked {
-		return "", nil, errors.NewAuthError(errors.AuthErrorRevokedToken, "token has been revoked")
+		return Token{}, errors.NewAuthError(errors.AuthErrorRevokedToken, "token has been revoked")
 	}
 
 	if time.Now().After(token.ExpiresAt) {
-		return "", nil, errors.NewAuthError(errors.AuthErrorExpiredToken, "token has expired")
+		return Token{}, errors.NewAuthError(errors.AuthErrorExpiredToken, "token has expired")
 	}
 
-	return token.User, token.Permissions, nil
+	return *token, nil
 }
 
 // HasPermission checks if a user has a specific permission.
@@ -153,3 +368,9 @@ func HasPermission(permissions []string, required string) bool {
 	}
 	return false
 }
+
+// hashToken returns the hex-encoded SHA-256 of a token, as tokens are stored
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -327,6 +327,224 @@ func TestTokenStore_Validate_Revoked(t *testing.T) {
 	}
 }
 
+func TestTokenStore_Create(t *testing.T) {
+	tmpDir := t.TempDir()
+	tokenFile := filepath.Join(tmpDir, "tokens.json")
+
+	store, err := NewTokenStore(tokenFile)
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+
+	rawToken, token, err := store.Create("alice", []string{"upload", "list"}, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if len(token.ID) < 5 || token.ID[:4] != "tok_" {
+		t.Errorf("expected a tok_ ID, got %q", token.ID)
+	}
+	if token.TokenHash == rawToken {
+		t.Error("expected the token to be stored hashed")
+	}
+	if d := token.ExpiresAt.Sub(token.CreatedAt); d != 24*time.Hour {
+		t.Errorf("expected validity of 24h, got %v", d)
+	}
+
+	user, perms, err := store.Validate(rawToken)
+	if err != nil {
+		t.Fatalf("Validate failed for created token: %v", err)
+	}
+	if user != "alice" {
+		t.Errorf("expected user alice, got %s", user)
+	}
+	if len(perms) != 2 || perms[0] != "upload" || perms[1] != "list" {
+		t.Errorf("unexpected permissions: %v", perms)
+	}
+	if got := store.GetTokenByID(token.ID); got == nil {
+		t.Error("expected created token to be found by ID")
+	}
+}
+
+func TestTokenStore_Create_Persists(t *testing.T) {
+	tmpDir := t.TempDir()
+	tokenFile := filepath.Join(tmpDir, "tokens.json")
+
+	// An existing file with a password user, which must survive the save
+	storeFile := TokenStoreFile{
+		Users: []User{{Name: "carol", PasswordHash: "pbkdf2-sha256$1$c2FsdA$aGFzaA", Permissions: []string{"*"}}},
+	}
+	data, _ := json.Marshal(storeFile)
+	if err := os.WriteFile(tokenFile, data, 0644); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+
+	store, err := NewTokenStore(tokenFile)
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+	first, _, err := store.Create("alice", []string{"*"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	second, _, err := store.Create("bob", []string{"download"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	reloaded, err := NewTokenStore(tokenFile)
+	if err != nil {
+		t.Fatalf("NewTokenStore failed on reload: %v", err)
+	}
+	for raw, want := range map[string]string{first: "alice", second: "bob"} {
+		user, _, err := reloaded.Validate(raw)
+		if err != nil {
+			t.Errorf("Validate failed after reload for %s: %v", want, err)
+		} else if user != want {
+			t.Errorf("expected user %s, got %s", want, user)
+		}
+	}
+	if _, ok := reloaded.users["carol"]; !ok {
+		t.Error("expected existing user to be kept when saving tokens")
+	}
+}
+
+func TestTokenStore_Create_Invalid(t *testing.T) {
+	store, err := NewTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+
+	if _, _, err := store.Create("", []string{"*"}, time.Hour); err == nil {
+		t.Error("expected error for empty user")
+	}
+	if _, _, err := store.Create("alice", []string{"*"}, 0); err == nil {
+		t.Error("expected error for non-positive validity")
+	}
+	if len(store.tokens) != 0 {
+		t.Errorf("expected no tokens after failed creates, got %d", len(store.tokens))
+	}
+}
+
+func TestTokenStore_Revoke(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "tokens.json")
+	store, err := NewTokenStore(tokenFile)
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+
+	rawToken, token, err := store.Create("alice", []string{"*"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if err := store.Revoke(token.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, _, err := store.Validate(rawToken); err == nil {
+		t.Error("expected revoked token to fail validation")
+	} else if errType, ok := errors.GetAuthErrorType(err); !ok || errType != errors.AuthErrorRevokedToken {
+		t.Errorf("expected AuthErrorRevokedToken, got %v", err)
+	}
+
+	// Revoking again is harmless
+	if err := store.Revoke(token.ID); err != nil {
+		t.Errorf("expected revoking twice to succeed, got %v", err)
+	}
+
+	reloaded, err := NewTokenStore(tokenFile)
+	if err != nil {
+		t.Fatalf("NewTokenStore failed on reload: %v", err)
+	}
+	if _, _, err := reloaded.Validate(rawToken); err == nil {
+		t.Error("expected revocation to persist across reload")
+	} else if errType, ok := errors.GetAuthErrorType(err); !ok || errType != errors.AuthErrorRevokedToken {
+		t.Errorf("expected AuthErrorRevokedToken after reload, got %v", err)
+	}
+}
+
+func TestTokenStore_Revoke_NotFound(t *testing.T) {
+	store, err := NewTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+
+	err = store.Revoke("tok_missing")
+	if errType, ok := errors.GetAuthErrorType(err); !ok || errType != errors.AuthErrorInvalidToken {
+		t.Errorf("expected AuthErrorInvalidToken, got %v", err)
+	}
+}
+
+func TestTokenStore_Add(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "tokens.json")
+	store, err := NewTokenStore(tokenFile)
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+
+	hash := sha256.Sum256([]byte("secret"))
+	token := Token{
+		ID:          "tok_added",
+		TokenHash:   hex.EncodeToString(hash[:]),
+		User:        "bob",
+		Permissions: []string{"list"},
+		CreatedAt:   time.Now(),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	if err := store.Add(token); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := store.Add(token); err == nil {
+		t.Error("expected error adding a duplicate token")
+	}
+	if err := store.Add(Token{ID: "tok_nohash"}); err == nil {
+		t.Error("expected error adding a token without a hash")
+	}
+
+	reloaded, err := NewTokenStore(tokenFile)
+	if err != nil {
+		t.Fatalf("NewTokenStore failed on reload: %v", err)
+	}
+	if user, _, err := reloaded.Validate("secret"); err != nil || user != "bob" {
+		t.Errorf("expected added token to persist, got user %q, err %v", user, err)
+	}
+}
+
+func TestTokenStore_Save_Atomic(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "tokens.json")
+	store, err := NewTokenStore(tokenFile)
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+	if _, _, err := store.Create("alice", []string{"*"}, time.Hour); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "tokens.json" {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("expected only tokens.json to remain, got %v", names)
+	}
+
+	info, err := os.Stat(tokenFile)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected token file mode 0600, got %o", perm)
+	}
+}
+
 func TestHasPermission(t *testing.T) {
 	tests := []struct {
 		name        string
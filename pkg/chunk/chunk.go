@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"io"
 )
 
 // Chunker is responsible for splitting data into resumable chunks of a specified size.
@@ -32,12 +33,31 @@ func New(size int) *Chunker {
 	return &Chunker{Size: size}
 }
 
+// Checksum returns the SHA-256 of data in the hex format used by Chunk.Checksum
+func Checksum(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// Count returns the number of chunks a file of size bytes is split into.
+// An empty file is a single empty chunk, so the count is never zero.
+func Count(size int64, chunkSize int) int {
+	if size <= 0 {
+		return 1
+	}
+	return int((size + int64(chunkSize) - 1) / int64(chunkSize))
+}
+
 // Split divides data into chunks of the configured size.
 // Each chunk is assigned a sequential ID and a SHA-256 checksum for integrity verification.
-// Returns an empty slice if data is empty.
+// Empty data yields a single empty chunk, as counted by Count.
 func (c *Chunker) Split(data []byte) []Chunk {
-	var chunks []Chunk
 	totalSize := len(data)
+	if totalSize == 0 {
+		return []Chunk{{ID: 0, Data: []byte{}, Checksum: Checksum(nil)}}
+	}
+
+	var chunks []Chunk
 
 	for i := 0; i < totalSize; i += c.Size {
 		end := i + c.Size
@@ -46,12 +66,11 @@ func (c *Chunker) Split(data []byte) []Chunk {
 		}
 
 		chunkData := data[i:end]
-		hash := sha256.Sum256(chunkData)
 
 		chunks = append(chunks, Chunk{
 			ID:       len(chunks),
 			Data:     chunkData,
-			Checksum: hex.EncodeToString(hash[:]),
+			Checksum: Checksum(chunkData),
 		})
 	}
 
@@ -101,3 +120,46 @@ func (c *Chunker) Reassemble(chunks []Chunk) ([]byte, error) {
 	}
 	return result, nil
 }
+
+// Reader splits a stream into chunks of a fixed size as it is read, so that
+// large files can be chunked without holding them in memory.
+type Reader struct {
+	r    io.Reader
+	buf  []byte
+	next int
+}
+
+// NewReader creates a Reader that reads chunks of up to size bytes from r.
+// If size is zero or negative, a default of 1MB is used.
+func NewReader(r io.Reader, size int) *Reader {
+	if size <= 0 {
+		size = 1024 * 1024 // 1MB default
+	}
+	return &Reader{r: r, buf: make([]byte, size)}
+}
+
+// Next reads the next chunk and computes its SHA-256 checksum. Only the last
+// chunk may be shorter than the chunk size. The returned Data is reused by the
+// following call to Next. Returns io.EOF once the stream is exhausted; an
+// empty stream yields a single empty chunk first, matching Split.
+func (r *Reader) Next() (Chunk, error) {
+	n, err := io.ReadFull(r.r, r.buf)
+	if err == io.EOF {
+		if r.next > 0 {
+			return Chunk{}, io.EOF
+		}
+		err = nil
+	}
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return Chunk{}, err
+	}
+
+	data := r.buf[:n]
+	chunk := Chunk{
+		ID:       r.next,
+		Data:     data,
+		Checksum: Checksum(data),
+	}
+	r.next++
+	return chunk, nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"io"
 	"testing"
 )
 
@@ -78,8 +79,39 @@ func TestChunker_Split_EmptyData(t *testing.T) {
 	data := []byte("")
 	chunks := c.Split(data)
 
-	if len(chunks) != 0 {
-		t.Errorf("expected 0 chunks for empty data, got %d", len(chunks))
+	if len(chunks) != 1 {
+		t.Fatalf("expected 1 chunk for empty data, got %d", len(chunks))
+	}
+	if chunks[0].ID != 0 || len(chunks[0].Data) != 0 || chunks[0].Checksum != calculateChecksum(nil) {
+		t.Errorf("expected a single empty chunk, got ID %d with %d bytes", chunks[0].ID, len(chunks[0].Data))
+	}
+
+	reassembled, err := c.Reassemble(chunks)
+	if err != nil {
+		t.Fatalf("Reassemble failed: %v", err)
+	}
+	if len(reassembled) != 0 {
+		t.Errorf("expected empty result, got %d bytes", len(reassembled))
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		size      int64
+		chunkSize int
+		expected  int
+	}{
+		{0, 10, 1},
+		{1, 10, 1},
+		{10, 10, 1},
+		{11, 10, 2},
+		{25, 10, 3},
+	}
+
+	for _, tt := range tests {
+		if got := Count(tt.size, tt.chunkSize); got != tt.expected {
+			t.Errorf("Count(%d, %d) = %d, want %d", tt.size, tt.chunkSize, got, tt.expected)
+		}
 	}
 }
 
@@ -231,6 +263,40 @@ func TestChunker_RoundTrip(t *testing.T) {
 	}
 }
 
+func TestReader_MatchesSplit(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000) // 10000 bytes, last chunk short
+	expected := New(1024).Split(data)
+
+	r := NewReader(bytes.NewReader(data), 1024)
+	for i, want := range expected {
+		got, err := r.Next()
+		if err != nil {
+			t.Fatalf("chunk %d: unexpected error: %v", i, err)
+		}
+		if got.ID != want.ID || got.Checksum != want.Checksum || !bytes.Equal(got.Data, want.Data) {
+			t.Errorf("chunk %d: got ID %d (%d bytes), want ID %d (%d bytes)", i, got.ID, len(got.Data), want.ID, len(want.Data))
+		}
+	}
+
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF after last chunk, got %v", err)
+	}
+}
+
+func TestReader_Empty(t *testing.T) {
+	r := NewReader(bytes.NewReader(nil), 1024)
+	got, err := r.Next()
+	if err != nil {
+		t.Fatalf("expected a single empty chunk for empty stream, got %v", err)
+	}
+	if got.ID != 0 || len(got.Data) != 0 || got.Checksum != calculateChecksum(nil) {
+		t.Errorf("expected empty chunk 0, got ID %d with %d bytes", got.ID, len(got.Data))
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF after the empty chunk, got %v", err)
+	}
+}
+
 // Helper function
 func calculateChecksum(data []byte) string {
 	hash := sha256.Sum256(data)
@@ -6,6 +6,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // ServerConfig holds server configuration
@@ -16,6 +17,206 @@ type ServerConfig struct {
 	TokensFile  string `json:"tokens_file"` // Path to tokens file (empty to disable auth)
 	TLSCertFile string `json:"tls_cert"`    // TLS certificate file (empty for HTTP)
 	TLSKeyFile  string `json:"tls_key"`     // TLS key file (empty for HTTP)
+
+	MaxConnections     int            `json:"max_connections,omitempty"`       // Simultaneous connection cap (0 for default)
+	MaxSessionsPerUser int            `json:"max_sessions_per_user,omitempty"` // Unfinished uploads allowed per user (0 for unlimited)
+	MaxFilesPerDir     int            `json:"max_files_per_dir,omitempty"`     // Entries a directory may hold before uploads into it are refused (0 for unlimited)
+	MaxTotalBytes      int64          `json:"max_total_bytes,omitempty"`       // Total size of stored files before new uploads are refused (0 for unlimited)
+	SessionStore       string         `json:"session_store,omitempty"`         // Upload session backend: "json" (default) or "bolt"
+	StorageRoutes      []StorageRoute `json:"storage_routes,omitempty"`        // Optional per-pattern/content-type backends
+	CompressStorage    bool           `json:"compress_storage,omitempty"`      // Gzip stored files in seekable blocks
+
+	DiscoveryPort     int  `json:"discovery_port,omitempty"`     // UDP port for discovery announcements (0 for default)
+	DiscoveryRequired bool `json:"discovery_required,omitempty"` // Refuse to start if discovery cannot bind a port
+
+	DiscoveryMode   string `json:"discovery_mode,omitempty"`   // How the server announces itself: "broadcast" (default), "mdns" or "both"
+	DiscoverySecret string `json:"discovery_secret,omitempty"` // Shared network secret announcements are signed with (empty for unsigned)
+
+	DiscoveryAddress string `json:"discovery_address,omitempty"` // host:port announced to clients (empty for address; required with discovery_secret unless address names a routable host)
+
+	ServerName string `json:"server_name,omitempty"` // Name shown to clients by gfl discover
+
+	SlowStorageMillis int `json:"slow_storage_ms,omitempty"` // Log storage operations slower than this (0 for default)
+
+	UploadFilter *UploadFilter `json:"upload_filter,omitempty"` // Optional allow/deny lists for uploaded file types
+
+	CompletionActions []CompletionAction `json:"completion_actions,omitempty"` // Moves applied to files once their upload completes
+
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"` // Extra headers on every response; an empty value removes a default
+
+	TrustedProxies []string `json:"trusted_proxies,omitempty"` // Proxy addresses or CIDR ranges whose forwarded client IPs are believed
+	ProxyHeaders   []string `json:"proxy_headers,omitempty"`   // Headers naming the client behind a trusted proxy (default X-Forwarded-For, X-Real-IP)
+
+	ShowHidden bool `json:"show_hidden,omitempty"` // Include dotfiles in directory listings
+
+	UpdateManifest string `json:"update_manifest,omitempty"` // Signed update manifest served at /version.json, outside storage_dir (empty for none)
+
+	AuthMaxFailures          int `json:"auth_max_failures,omitempty"`           // Failed auth attempts per client before 429 (0 for default, -1 for unlimited)
+	AuthFailureWindowSeconds int `json:"auth_failure_window_seconds,omitempty"` // Sliding window failed attempts are counted in (0 for default)
+
+	MaxChallenges  int    `json:"max_challenges,omitempty"`  // Unanswered auth challenges kept before the oldest are evicted (0 for default)
+	ChallengesFile string `json:"challenges_file,omitempty"` // File keeping unanswered auth challenges across restarts (empty for memory only)
+
+	AuditLog string `json:"audit_log,omitempty"` // JSON-lines file recording authentication and transfers (empty to disable)
+
+	SessionMaxAgeHours          int `json:"session_max_age_hours,omitempty"`          // Discard unfinished uploads idle this long (0 for default, -1 to keep them)
+	AbandonedUploadGraceMinutes int `json:"abandoned_upload_grace_minutes,omitempty"` // Discard uploads whose client disconnected mid-chunk after this (0 for default, -1 to keep them)
+
+	OverwritePolicy string `json:"overwrite_policy,omitempty"` // Uploads onto an existing file: "replace" (default), "version" or "error"
+
+	ReadyMaxSessions    int `json:"ready_max_sessions,omitempty"`    // Unfinished uploads above which /ready answers 503 (0 for no limit)
+	ReadyMaxConnections int `json:"ready_max_connections,omitempty"` // Open connections above which /ready answers 503 (0 for no limit)
+}
+
+// UploadFilter limits uploads by file extension or sniffed content type
+type UploadFilter struct {
+	AllowExtensions []string `json:"allow_extensions,omitempty"` // Extensions accepted (e.g. ["jpg", "png"]); empty allows all
+	DenyExtensions  []string `json:"deny_extensions,omitempty"`  // Extensions rejected (e.g. ["exe"])
+	AllowTypes      []string `json:"allow_types,omitempty"`      // Content type prefixes accepted (e.g. ["image/"]); empty allows all
+	DenyTypes       []string `json:"deny_types,omitempty"`       // Content type prefixes rejected
+}
+
+// CompletionAction moves completed uploads whose path matches Pattern to Target
+type CompletionAction struct {
+	Pattern string `json:"pattern"` // Path pattern (e.g. "incoming/*.csv" or "incoming/**")
+	Target  string `json:"target"`  // Final path template (e.g. "ready/{rel}")
+}
+
+// StorageRoute sends files matching a path pattern or content type to another backend
+type StorageRoute struct {
+	Pattern     string `json:"pattern,omitempty"`      // Path pattern (e.g. "*.jpg" or "media/*")
+	ContentType string `json:"content_type,omitempty"` // Content type prefix (e.g. "image/")
+	Storage     string `json:"storage"`                // Backend URI (e.g. "local://./images")
+}
+
+// Validate checks the server configuration for values the server cannot start with.
+func (c *ServerConfig) Validate() error {
+	if c.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+	if _, port, err := net.SplitHostPort(c.Address); err != nil {
+		return fmt.Errorf("invalid address %q: %w", c.Address, err)
+	} else if port == "" {
+		return fmt.Errorf("invalid address %q: missing port", c.Address)
+	}
+
+	if c.StorageDir == "" {
+		return fmt.Errorf("storage_dir is required")
+	}
+	if c.MetaDir == "" {
+		return fmt.Errorf("meta_dir is required")
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("tls_cert and tls_key must be set together")
+	}
+
+	if c.MaxConnections < 0 {
+		return fmt.Errorf("max_connections must not be negative")
+	}
+	if c.MaxSessionsPerUser < 0 {
+		return fmt.Errorf("max_sessions_per_user must not be negative")
+	}
+	if c.MaxTotalBytes < 0 {
+		return fmt.Errorf("max_total_bytes must not be negative")
+	}
+	if c.ReadyMaxSessions < 0 || c.ReadyMaxConnections < 0 {
+		return fmt.Errorf("ready_max_sessions and ready_max_connections must not be negative")
+	}
+
+	switch c.SessionStore {
+	case "", "json", "bolt":
+	default:
+		return fmt.Errorf("session_store must be \"json\" or \"bolt\", got %q", c.SessionStore)
+	}
+
+	switch c.OverwritePolicy {
+	case "", "replace", "version", "error":
+	default:
+		return fmt.Errorf("overwrite_policy must be \"replace\", \"version\" or \"error\", got %q", c.OverwritePolicy)
+	}
+
+	if c.SlowStorageMillis < 0 {
+		return fmt.Errorf("slow_storage_ms must not be negative")
+	}
+
+	if c.DiscoveryPort < 0 || c.DiscoveryPort > 65535 {
+		return fmt.Errorf("discovery_port must be between 0 and 65535")
+	}
+
+	switch c.DiscoveryMode {
+	case "", "broadcast", "mdns", "both":
+	default:
+		return fmt.Errorf("discovery_mode must be \"broadcast\", \"mdns\" or \"both\", got %q", c.DiscoveryMode)
+	}
+	if c.DiscoverySecret != "" {
+		announced := c.DiscoveryAddress
+		if announced == "" {
+			announced = c.Address
+		}
+		if !routableAddress(announced) {
+			return fmt.Errorf("discovery_secret requires a routable address to announce; set discovery_address to the host:port clients should use")
+		}
+	}
+
+	for i, route := range c.StorageRoutes {
+		if route.Storage == "" {
+			return fmt.Errorf("storage_routes[%d]: storage is required", i)
+		}
+		if route.Pattern == "" && route.ContentType == "" {
+			return fmt.Errorf("storage_routes[%d]: pattern or content_type is required", i)
+		}
+	}
+
+	for i, action := range c.CompletionActions {
+		if action.Pattern == "" || action.Target == "" {
+			return fmt.Errorf("completion_actions[%d]: pattern and target are required", i)
+		}
+	}
+
+	for i, proxy := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("trusted_proxies[%d]: %q is not an IP address or CIDR range", i, proxy)
+		}
+	}
+	if len(c.ProxyHeaders) > 0 && len(c.TrustedProxies) == 0 {
+		return fmt.Errorf("proxy_headers requires trusted_proxies")
+	}
+
+	// A manifest in storage could be replaced by anyone allowed to upload
+	if c.UpdateManifest != "" && withinDir(c.UpdateManifest, c.StorageDir) {
+		return fmt.Errorf("update_manifest must be outside storage_dir")
+	}
+
+	return nil
+}
+
+// withinDir reports whether path is dir or lies beneath it
+func withinDir(path, dir string) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// routableAddress reports whether address names a host other machines can
+// reach, rather than none, a loopback or an unspecified one
+func routableAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	if host == "" || host == "localhost" {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip == nil || !(ip.IsLoopback() || ip.IsUnspecified())
 }
 
 // ClientConfig holds client configuration
@@ -23,6 +224,22 @@ type ClientConfig struct {
 	ServerURL string `json:"server_url"` // Server URL (e.g., "http://95.145.216.175")
 	ChunkSize int    `json:"chunk_size"` // Chunk size in bytes
 	Token     string `json:"token"`      // Authentication token (optional)
+	BasePath  string `json:"base_path"`  // Prefix of relative remote paths (optional)
+
+	RequestTimeoutSeconds int `json:"request_timeout_seconds,omitempty"` // Wait for a connection or response headers (0 for default)
+	UploadRetries         int `json:"upload_retries,omitempty"`          // Retries of a chunk that failed with a network error (0 for default, -1 to disable)
+	ChunkTimeoutSeconds   int `json:"chunk_timeout_seconds,omitempty"`   // Limit on each attempt to upload a chunk (0 for default, -1 to disable)
+	UploadTimeoutMinutes  int `json:"upload_timeout_minutes,omitempty"`  // Limit on a whole put command (0 for none)
+
+	SpeedWindowSeconds int `json:"speed_window_seconds,omitempty"` // Measure transfer speed and ETA over the last N seconds (0 for default, -1 for the whole transfer)
+
+	AuthRequired bool  `json:"auth_required,omitempty"` // Server requires a token, as it advertised when the config was written
+	MaxFileSize  int64 `json:"max_file_size,omitempty"` // Largest file the server advertised it accepts (0 if unknown)
+
+	Discovery       string `json:"discovery,omitempty"`        // How gfl discover looks for servers: "broadcast" (default), "mdns" or "both"
+	DiscoverySecret string `json:"discovery_secret,omitempty"` // Ignore servers whose announcements aren't signed with this shared secret
+
+	DiscoveryTargets []string `json:"discovery_targets,omitempty"` // Subnets (CIDR) or hosts to probe instead of waiting for broadcasts
 }
 
 // Config holds both server and client configuration
@@ -99,17 +316,51 @@ func DefaultConfig() Config {
 
 // LoadConfig loads configuration from a file
 func LoadConfig(path string) (*Config, error) {
+	return LoadConfigs(path)
+}
+
+// LoadConfigs loads configuration from an ordered list of files, such as a
+// shared base followed by a personal override. Each file is read over the
+// result of the ones before it, see MergeFile.
+func LoadConfigs(paths ...string) (*Config, error) {
+	var cfg Config
+	for _, path := range paths {
+		if err := MergeFile(&cfg, path); err != nil {
+			return nil, err
+		}
+	}
+	return &cfg, nil
+}
+
+// MergeFile reads the configuration file at path over cfg. Only the fields
+// the file sets are replaced, at any depth: a file setting just
+// client.token leaves the rest of cfg alone. Lists are replaced as a whole
+// while maps such as response_headers gain the file's keys.
+func MergeFile(cfg *Config, path string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	// Decoding into an existing value only touches the fields present
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
 	}
+	return nil
+}
 
-	return &cfg, nil
+// Files collects the paths given by repeating a -config flag, for use with
+// flag.Var
+type Files []string
+
+func (f *Files) String() string {
+	return strings.Join(*f, ",")
+}
+
+// Set adds a path after those already given
+func (f *Files) Set(path string) error {
+	*f = append(*f, path)
+	return nil
 }
 
 // SaveConfig saves configuration to a file
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a config file into dir and returns its path
+func writeConfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadConfigs_OverrideWinsOnlyWhereSet(t *testing.T) {
+	dir := t.TempDir()
+	base := writeConfig(t, dir, "base.json", `{
+		"server": {
+			"address": "0.0.0.0:8080",
+			"storage_dir": "/srv/goflux",
+			"max_connections": 50,
+			"response_headers": {"X-Team": "files", "Cache-Control": "no-store"},
+			"trusted_proxies": ["10.0.0.1", "10.0.0.2"],
+			"upload_filter": {"deny_extensions": ["exe"], "allow_types": ["image/"]}
+		},
+		"client": {"server_url": "http://files.example.com", "chunk_size": 1048576, "base_path": "team"}
+	}`)
+	override := writeConfig(t, dir, "user.json", `{
+		"server": {
+			"max_connections": 10,
+			"response_headers": {"Cache-Control": "max-age=60"},
+			"trusted_proxies": ["192.168.1.1"],
+			"upload_filter": {"deny_extensions": ["exe", "bat"]}
+		},
+		"client": {"token": "alice-token", "base_path": ""}
+	}`)
+
+	cfg, err := LoadConfigs(base, override)
+	if err != nil {
+		t.Fatalf("LoadConfigs failed: %v", err)
+	}
+
+	// Fields the override sets win, even when set to an empty value
+	if cfg.Server.MaxConnections != 10 || cfg.Client.Token != "alice-token" || cfg.Client.BasePath != "" {
+		t.Errorf("expected the override's values, got %+v / %+v", cfg.Server, cfg.Client)
+	}
+	// Fields it leaves out keep the base's values, at any depth
+	if cfg.Server.Address != "0.0.0.0:8080" || cfg.Server.StorageDir != "/srv/goflux" {
+		t.Errorf("expected the base's server fields kept, got %+v", cfg.Server)
+	}
+	if cfg.Client.ServerURL != "http://files.example.com" || cfg.Client.ChunkSize != 1048576 {
+		t.Errorf("expected the base's client fields kept, got %+v", cfg.Client)
+	}
+	if f := cfg.Server.UploadFilter; f == nil || !reflect.DeepEqual(f.AllowTypes, []string{"image/"}) || !reflect.DeepEqual(f.DenyExtensions, []string{"exe", "bat"}) {
+		t.Errorf("expected the upload filter merged field by field, got %+v", f)
+	}
+
+	// Maps gain keys; lists are replaced
+	wantHeaders := map[string]string{"X-Team": "files", "Cache-Control": "max-age=60"}
+	if !reflect.DeepEqual(cfg.Server.ResponseHeaders, wantHeaders) {
+		t.Errorf("expected headers %v, got %v", wantHeaders, cfg.Server.ResponseHeaders)
+	}
+	if !reflect.DeepEqual(cfg.Server.TrustedProxies, []string{"192.168.1.1"}) {
+		t.Errorf("expected the override's proxy list, got %v", cfg.Server.TrustedProxies)
+	}
+}
+
+func TestLoadConfigs_Errors(t *testing.T) {
+	dir := t.TempDir()
+	base := writeConfig(t, dir, "base.json", `{"client": {"chunk_size": 1024}}`)
+	broken := writeConfig(t, dir, "broken.json", `{"client": `)
+
+	if _, err := LoadConfigs(base, filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected an error for a missing override")
+	}
+	if _, err := LoadConfigs(base, broken); err == nil || !strings.Contains(err.Error(), "broken.json") {
+		t.Errorf("expected the broken file named in the error, got %v", err)
+	}
+}
+
+func TestFiles_RepeatedFlag(t *testing.T) {
+	var files Files
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&files, "config", "")
+	if err := fs.Parse([]string{"-config", "base.json", "-config", "user.json"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !reflect.DeepEqual([]string(files), []string{"base.json", "user.json"}) {
+		t.Errorf("expected both files in order, got %v", files)
+	}
+}
@@ -6,6 +6,8 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"io/fs"
+	"net/http"
 )
 
 // Error categories for goflux-lite
@@ -231,3 +233,62 @@ func GetStorageErrorType(err error) (StorageErrorType, bool) {
 	}
 	return 0, false
 }
+
+// GetNetworkErrorType extracts the NetworkErrorType from an error.
+// Returns the type and true if the error is a NetworkError, otherwise returns zero value and false.
+func GetNetworkErrorType(err error) (NetworkErrorType, bool) {
+	var netErr *NetworkError
+	if errors.As(err, &netErr) {
+		return netErr.Type, true
+	}
+	return 0, false
+}
+
+// HTTPStatus returns the HTTP status code a server should answer with for err:
+// 404 for a missing file, 409 for one already there, 400 for an invalid path
+// or request, 401 for bad credentials, 403 for a permission problem, 502 or
+// 504 for a failing upstream server, and 500 for anything else, including
+// errors of no type defined here other than a missing file reported by the
+// operating system. A nil error is 200 OK.
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+
+	if errType, ok := GetAuthErrorType(err); ok {
+		if errType == AuthErrorInsufficientPermissions {
+			return http.StatusForbidden
+		}
+		return http.StatusUnauthorized
+	}
+
+	if IsValidationError(err) {
+		return http.StatusBadRequest
+	}
+
+	if errType, ok := GetStorageErrorType(err); ok {
+		switch errType {
+		case StorageErrorNotFound:
+			return http.StatusNotFound
+		case StorageErrorPathTraversal, StorageErrorInvalidPath:
+			return http.StatusBadRequest
+		case StorageErrorAlreadyExists:
+			return http.StatusConflict
+		case StorageErrorPermissionDenied:
+			return http.StatusForbidden
+		}
+		return http.StatusInternalServerError
+	}
+
+	if errType, ok := GetNetworkErrorType(err); ok {
+		if errType == NetworkErrorTimeout {
+			return http.StatusGatewayTimeout
+		}
+		return http.StatusBadGateway
+	}
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -1,7 +1,12 @@
 package errors
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"reflect"
 	"testing"
 )
 
@@ -149,6 +154,23 @@ func TestIsNetworkError(t *testing.T) {
 	}
 }
 
+func TestGetNetworkErrorType(t *testing.T) {
+	err := fmt.Errorf("download: %w", NewNetworkError(NetworkErrorTimeout, "stalled"))
+
+	errType, ok := GetNetworkErrorType(err)
+	if !ok {
+		t.Error("expected GetNetworkErrorType to succeed")
+	}
+
+	if errType != NetworkErrorTimeout {
+		t.Errorf("expected %v, got %v", NetworkErrorTimeout, errType)
+	}
+
+	if _, ok := GetNetworkErrorType(errors.New("regular error")); ok {
+		t.Error("expected GetNetworkErrorType to fail for regular error")
+	}
+}
+
 func TestValidationError(t *testing.T) {
 	err := NewValidationError("username", "must not be empty")
 
@@ -191,3 +213,88 @@ func TestErrorWrapping(t *testing.T) {
 		t.Error("expected errors.As to find AuthError")
 	}
 }
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, http.StatusOK},
+		{"plain error", errors.New("boom"), http.StatusInternalServerError},
+
+		{"invalid token", NewAuthError(AuthErrorInvalidToken, "x"), http.StatusUnauthorized},
+		{"expired token", NewAuthError(AuthErrorExpiredToken, "x"), http.StatusUnauthorized},
+		{"revoked token", NewAuthError(AuthErrorRevokedToken, "x"), http.StatusUnauthorized},
+		{"invalid credentials", NewAuthError(AuthErrorInvalidCredentials, "x"), http.StatusUnauthorized},
+		{"insufficient permissions", NewAuthError(AuthErrorInsufficientPermissions, "x"), http.StatusForbidden},
+
+		{"not found", NewStorageError(StorageErrorNotFound, "a", "x"), http.StatusNotFound},
+		{"path traversal", NewStorageError(StorageErrorPathTraversal, "a", "x"), http.StatusBadRequest},
+		{"permission denied", NewStorageError(StorageErrorPermissionDenied, "a", "x"), http.StatusForbidden},
+		{"already exists", NewStorageError(StorageErrorAlreadyExists, "a", "x"), http.StatusConflict},
+		{"invalid path", NewStorageError(StorageErrorInvalidPath, "a", "x"), http.StatusBadRequest},
+		{"storage I/O", NewStorageError(StorageErrorIO, "a", "x"), http.StatusInternalServerError},
+
+		{"connection", NewNetworkError(NetworkErrorConnection, "x"), http.StatusBadGateway},
+		{"timeout", NewNetworkError(NetworkErrorTimeout, "x"), http.StatusGatewayTimeout},
+		{"invalid response", NewNetworkError(NetworkErrorInvalidResponse, "x"), http.StatusBadGateway},
+		{"server unavailable", NewNetworkError(NetworkErrorServerUnavailable, "x"), http.StatusBadGateway},
+		{"bad request upstream", NewNetworkError(NetworkErrorBadRequest, "x"), http.StatusBadGateway},
+
+		{"validation", NewValidationError("size", "x"), http.StatusBadRequest},
+
+		{"wrapped", fmt.Errorf("delete failed: %w", NewStorageError(StorageErrorNotFound, "a", "x")), http.StatusNotFound},
+		{"missing file", fmt.Errorf("read failed: %w", fs.ErrNotExist), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if got := HTTPStatus(tt.err); got != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestResponse_RoundTrip(t *testing.T) {
+	tests := []error{
+		NewAuthError(AuthErrorRevokedToken, "token revoked"),
+		NewStorageError(StorageErrorPathTraversal, "../etc/passwd", "path traversal attempt detected"),
+		NewNetworkError(NetworkErrorTimeout, "upstream timed out"),
+		NewValidationError("chunk_id", "must not be negative"),
+	}
+	for _, sent := range tests {
+		body, err := json.Marshal(NewResponse(fmt.Errorf("handler failed: %w", sent), HTTPStatus(sent)))
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		parsed, ok := ParseResponse(body)
+		if !ok {
+			t.Fatalf("%v: expected %s to parse", sent, body)
+		}
+		if got := parsed.Err(nil); !reflect.DeepEqual(got, sent) {
+			t.Errorf("expected %#v, got %#v", sent, got)
+		}
+	}
+
+	cause := errors.New("cause")
+	if got := (ResponseError{Type: "validation", Field: "q"}).Err(cause); !errors.Is(got, cause) {
+		t.Errorf("expected the reconstructed error to wrap its cause, got %v", got)
+	}
+}
+
+func TestNewResponse_Untyped(t *testing.T) {
+	resp := NewResponse(errors.New("too many unfinished uploads"), http.StatusTooManyRequests)
+	if resp.Error.Type != "too_many_requests" || resp.Error.Message != "too many unfinished uploads" {
+		t.Errorf("unexpected response: %+v", resp.Error)
+	}
+	if err := resp.Error.Err(nil); err != nil {
+		t.Errorf("expected no typed error, got %v", err)
+	}
+}
+
+func TestParseResponse_NotAResponse(t *testing.T) {
+	for _, body := range []string{"", "not found\n", `{"status":"ok"}`, `{"error":"boom"}`} {
+		if _, ok := ParseResponse([]byte(body)); ok {
+			t.Errorf("expected %q not to parse", body)
+		}
+	}
+}
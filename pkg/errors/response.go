@@ -0,0 +1,138 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Response is the JSON body a server answers a failed request with, so that
+// clients can tell error categories apart without parsing messages
+type Response struct {
+	Error ResponseError `json:"error"`
+}
+
+// ResponseError describes the error in a Response
+type ResponseError struct {
+	Type    string `json:"type"`            // such as "storage.not_found"; see NewResponse
+	Message string `json:"message"`         // human-readable description
+	Field   string `json:"field,omitempty"` // field that failed validation
+	Path    string `json:"path,omitempty"`  // path a storage error concerns
+}
+
+// Names of the error types in a Response, by category
+var (
+	authTypeNames = map[AuthErrorType]string{
+		AuthErrorInvalidToken:            "auth.invalid_token",
+		AuthErrorExpiredToken:            "auth.expired_token",
+		AuthErrorRevokedToken:            "auth.revoked_token",
+		AuthErrorInsufficientPermissions: "auth.insufficient_permissions",
+		AuthErrorInvalidCredentials:      "auth.invalid_credentials",
+	}
+	storageTypeNames = map[StorageErrorType]string{
+		StorageErrorNotFound:         "storage.not_found",
+		StorageErrorPathTraversal:    "storage.path_traversal",
+		StorageErrorPermissionDenied: "storage.permission_denied",
+		StorageErrorAlreadyExists:    "storage.already_exists",
+		StorageErrorInvalidPath:      "storage.invalid_path",
+		StorageErrorIO:               "storage.io",
+	}
+	networkTypeNames = map[NetworkErrorType]string{
+		NetworkErrorConnection:        "network.connection",
+		NetworkErrorTimeout:           "network.timeout",
+		NetworkErrorInvalidResponse:   "network.invalid_response",
+		NetworkErrorServerUnavailable: "network.server_unavailable",
+		NetworkErrorBadRequest:        "network.bad_request",
+	}
+)
+
+// validationTypeName is the Response type of a ValidationError
+const validationTypeName = "validation"
+
+// NewResponse describes err for a response sent with status. Errors of the
+// types defined here keep their own message, field and path; any other error
+// is given its full text and a type named after status, such as "conflict".
+func NewResponse(err error, status int) Response {
+	var (
+		authErr    *AuthError
+		storageErr *StorageError
+		netErr     *NetworkError
+		valErr     *ValidationError
+	)
+	switch {
+	case errors.As(err, &authErr):
+		return Response{ResponseError{Type: authTypeNames[authErr.Type], Message: authErr.Message}}
+	case errors.As(err, &storageErr):
+		return Response{ResponseError{Type: storageTypeNames[storageErr.Type], Message: storageErr.Message, Path: storageErr.Path}}
+	case errors.As(err, &valErr):
+		return Response{ResponseError{Type: validationTypeName, Message: valErr.Message, Field: valErr.Field}}
+	case errors.As(err, &netErr):
+		return Response{ResponseError{Type: networkTypeNames[netErr.Type], Message: netErr.Message}}
+	}
+	return Response{ResponseError{Type: statusTypeName(status), Message: err.Error()}}
+}
+
+// statusTypeName names the type of an untyped error after the status it was
+// sent with: 409 Conflict is "conflict", 413 is "request_entity_too_large"
+func statusTypeName(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+// WriteJSON answers a request with status and err described as a Response
+func WriteJSON(w http.ResponseWriter, status int, err error) {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(NewResponse(err, status))
+}
+
+// Err reconstructs the error a Response describes, wrapping cause if it
+// isn't nil: an AuthError, StorageError, NetworkError or ValidationError for
+// the types named in NewResponse, or nil for an untyped error
+func (r ResponseError) Err(cause error) error {
+	category, _, _ := strings.Cut(r.Type, ".")
+	switch category {
+	case "auth":
+		for errType, name := range authTypeNames {
+			if name == r.Type {
+				return NewAuthErrorWithCause(errType, r.Message, cause)
+			}
+		}
+	case "storage":
+		for errType, name := range storageTypeNames {
+			if name == r.Type {
+				return NewStorageErrorWithCause(errType, r.Path, r.Message, cause)
+			}
+		}
+	case "network":
+		for errType, name := range networkTypeNames {
+			if name == r.Type {
+				return NewNetworkErrorWithCause(errType, r.Message, cause)
+			}
+		}
+	case validationTypeName:
+		return &ValidationError{Field: r.Field, Message: r.Message, Err: cause}
+	}
+	return nil
+}
+
+// ParseResponse decodes a Response from the body of a failed request,
+// reporting false if body isn't one, as from a server predating them or a
+// proxy in between
+func ParseResponse(body []byte) (ResponseError, bool) {
+	var resp Response
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Error.Type == "" {
+		return ResponseError{}, false
+	}
+	return resp.Error, true
+}
@@ -15,14 +15,16 @@ type Match struct {
 }
 
 // Expand expands glob patterns into a list of matching files.
-// It supports standard wildcards: *, ?, and [...].
+// It supports standard wildcards: *, ?, and [...], plus ** as a whole path
+// segment, which matches any number of directories (including none), and
+// shell-style {a,b,c} alternations, which may be nested.
 // Patterns without wildcards are returned as-is if they exist.
 // Returns an error if a pattern is malformed or if no files match.
 func Expand(patterns []string) ([]Match, error) {
 	var matches []Match
 	seen := make(map[string]bool) // Prevent duplicates
 
-	for _, pattern := range patterns {
+	for _, pattern := range expandAllBraces(patterns) {
 		// Check if pattern contains wildcards
 		if !containsWildcard(pattern) {
 			// No wildcard - treat as literal path
@@ -47,19 +49,25 @@ func Expand(patterns []string) ([]Match, error) {
 			continue
 		}
 
-		// Pattern contains wildcards - use filepath.Glob
+		// Pattern contains wildcards - use filepath.Glob, or walk the tree
+		// for recursive patterns
 		absPattern, err := filepath.Abs(pattern)
 		if err != nil {
 			return nil, err
 		}
 
-		globMatches, err := filepath.Glob(absPattern)
+		var globMatches []string
+		if isRecursive(absPattern) {
+			globMatches, err = globRecursive(absPattern)
+		} else {
+			globMatches, err = filepath.Glob(absPattern)
+		}
 		if err != nil {
 			return nil, err
 		}
 
 		// Calculate base directory for relative paths
-		baseDir := filepath.Dir(strings.Split(absPattern, "*")[0])
+		baseDir := filepath.Dir(absPattern[:strings.IndexAny(absPattern, wildcardChars)])
 		baseDir = strings.TrimSuffix(baseDir, string(filepath.Separator))
 
 		for _, match := range globMatches {
@@ -91,9 +99,206 @@ func Expand(patterns []string) ([]Match, error) {
 	return matches, nil
 }
 
-// containsWildcard checks if a pattern contains wildcard characters.
+// ExpandWithExcludes expands patterns like Expand, then drops every match
+// whose path matches one of the exclude patterns, as decided by Excluded.
+func ExpandWithExcludes(patterns, excludes []string) ([]Match, error) {
+	for _, exclude := range excludes {
+		if _, err := filepath.Match(exclude, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	matches, err := Expand(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := matches[:0]
+	for _, match := range matches {
+		excluded, err := Excluded(match.RelPath, excludes)
+		if err != nil {
+			return nil, err
+		}
+		if !excluded {
+			kept = append(kept, match)
+		}
+	}
+	return kept, nil
+}
+
+// Excluded reports whether relPath matches any of the exclude patterns.
+// A pattern without a separator, such as "*.tmp" or "node_modules", matches
+// if it matches any single segment of relPath, so it excludes both files and
+// everything inside directories of that name. A pattern with a separator is
+// matched against the whole of relPath and may use ** segments.
+func Excluded(relPath string, excludes []string) (bool, error) {
+	segments := strings.Split(filepath.Clean(relPath), string(filepath.Separator))
+	for _, exclude := range excludes {
+		pattern := filepath.Clean(filepath.FromSlash(exclude))
+		if !strings.Contains(pattern, string(filepath.Separator)) {
+			for _, segment := range segments {
+				ok, err := filepath.Match(pattern, segment)
+				if err != nil {
+					return false, err
+				}
+				if ok {
+					return true, nil
+				}
+			}
+			continue
+		}
+
+		patternSegments := strings.Split(pattern, string(filepath.Separator))
+		for _, segment := range patternSegments {
+			if _, err := filepath.Match(segment, ""); err != nil {
+				return false, err
+			}
+		}
+		if matchSegments(patternSegments, segments) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// wildcardChars are the characters that make a pattern more than a literal path
+const wildcardChars = "*?[]{}"
+
+// containsWildcard checks if a pattern contains wildcard characters,
+// including the recursive ** wildcard and brace alternations.
 func containsWildcard(pattern string) bool {
-	return strings.ContainsAny(pattern, "*?[]")
+	return strings.ContainsAny(pattern, wildcardChars)
+}
+
+// expandAllBraces returns the brace expansions of every pattern, in order.
+func expandAllBraces(patterns []string) []string {
+	var expanded []string
+	for _, pattern := range patterns {
+		expanded = append(expanded, expandBraces(pattern)...)
+	}
+	return expanded
+}
+
+// expandBraces expands {a,b,c} alternations in a pattern the way a shell
+// does, left to right and including nested groups, so "f.{txt,c{s,p}v}"
+// becomes f.txt, f.csv and f.cpv. Braces without a matching close or
+// without a comma between them are left as they are.
+func expandBraces(pattern string) []string {
+	for open := 0; open < len(pattern); open++ {
+		if pattern[open] != '{' {
+			continue
+		}
+
+		// Find the matching close brace and the commas at this level
+		depth, close := 0, -1
+		var commas []int
+	scan:
+		for i := open; i < len(pattern); i++ {
+			switch pattern[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					close = i
+					break scan
+				}
+			case ',':
+				if depth == 1 {
+					commas = append(commas, i)
+				}
+			}
+		}
+		if close < 0 || len(commas) == 0 {
+			continue
+		}
+
+		prefix, suffix := pattern[:open], pattern[close+1:]
+		bounds := append(append([]int{open}, commas...), close)
+		var expanded []string
+		for i := 0; i+1 < len(bounds); i++ {
+			alternative := pattern[bounds[i]+1 : bounds[i+1]]
+			expanded = append(expanded, expandBraces(prefix+alternative+suffix)...)
+		}
+		return expanded
+	}
+	return []string{pattern}
+}
+
+// isRecursive checks if a pattern has a ** path segment.
+func isRecursive(pattern string) bool {
+	for _, segment := range strings.Split(pattern, string(filepath.Separator)) {
+		if segment == "**" {
+			return true
+		}
+	}
+	return false
+}
+
+// globRecursive expands an absolute pattern containing ** segments by walking
+// the directory tree below the pattern's literal prefix and matching each
+// path's segments against the rest of the pattern. Like filepath.Glob, it
+// ignores I/O errors and returns matches in lexical order.
+func globRecursive(pattern string) ([]string, error) {
+	segments := strings.Split(pattern, string(filepath.Separator))
+
+	// The walk starts from the segments before the first wildcard
+	literal := 0
+	for literal < len(segments) && !containsWildcard(segments[literal]) {
+		literal++
+	}
+	root := filepath.Clean(strings.Join(segments[:literal], string(filepath.Separator)) + string(filepath.Separator))
+	rest := segments[literal:]
+
+	// Reject malformed segments up front, as filepath.Glob does
+	for _, segment := range rest {
+		if _, err := filepath.Match(segment, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	var matches []string
+	filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() && p != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." {
+			return nil
+		}
+		if matchSegments(rest, strings.Split(rel, string(filepath.Separator))) {
+			matches = append(matches, p)
+		}
+		return nil
+	})
+	return matches, nil
+}
+
+// matchSegments reports whether the path segments match the pattern
+// segments, where a ** pattern segment matches zero or more path segments.
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		// Let ** swallow 0, 1, 2... leading segments
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], path[1:])
 }
 
 // ExpandSingle expands a single pattern and returns the first match.
@@ -128,6 +128,9 @@ func TestContainsWildcard(t *testing.T) {
 		{"*.txt", true},
 		{"file?.txt", true},
 		{"file[123].txt", true},
+		{"src/**/*.go", true},
+		{"**", true},
+		{"report.{txt,csv}", true},
 		{"file.txt", false},
 		{"/path/to/file.txt", false},
 	}
@@ -165,3 +168,283 @@ func TestExpandNoDuplicates(t *testing.T) {
 		t.Errorf("Expand() returned %d matches (expected 1 due to deduplication)", len(matches))
 	}
 }
+
+func TestExpandRecursive(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := []string{
+		"b.txt",
+		"a/b.txt",
+		"a/x/b.txt",
+		"a/x/y/b.txt",
+		"a/x/c.txt",
+		"app.log",
+		"logs/2024/jan.log",
+		"logs/2024/feb/daily.log",
+		"other/b.txt",
+	}
+	for _, f := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	// A directory whose name matches must not be returned
+	os.MkdirAll(filepath.Join(tmpDir, "dirs", "trap.log"), 0755)
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string // relative paths
+	}{
+		{
+			name:    "double star in the middle",
+			pattern: "a/**/b.txt",
+			want:    []string{"b.txt", "x/b.txt", "x/y/b.txt"},
+		},
+		{
+			name:    "leading double star",
+			pattern: "**/*.log",
+			want:    []string{"app.log", "logs/2024/feb/daily.log", "logs/2024/jan.log"},
+		},
+		{
+			name:    "trailing double star",
+			pattern: "logs/**",
+			want:    []string{"2024/feb/daily.log", "2024/jan.log"},
+		},
+		{
+			name:    "no matches",
+			pattern: "a/**/*.pdf",
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern := filepath.Join(tmpDir, filepath.FromSlash(tt.pattern))
+			matches, err := Expand([]string{pattern})
+			if err != nil {
+				t.Fatalf("Expand() error = %v", err)
+			}
+
+			var got []string
+			for _, match := range matches {
+				got = append(got, filepath.ToSlash(match.RelPath))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expand(%s) got %v, want %v", tt.pattern, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Expand(%s) got %v, want %v", tt.pattern, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+
+	t.Run("deduplicates overlapping patterns", func(t *testing.T) {
+		matches, err := Expand([]string{
+			filepath.Join(tmpDir, "a", "**", "b.txt"),
+			filepath.Join(tmpDir, "a", "x", "*.txt"),
+		})
+		if err != nil {
+			t.Fatalf("Expand() error = %v", err)
+		}
+		if len(matches) != 4 {
+			t.Errorf("Expand() returned %d matches, want 4", len(matches))
+		}
+	})
+
+	t.Run("malformed pattern", func(t *testing.T) {
+		if _, err := Expand([]string{filepath.Join(tmpDir, "**", "[")}); err == nil {
+			t.Error("Expand() expected error for malformed pattern")
+		}
+	})
+}
+
+func TestExpandWithExcludes(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := []string{
+		"index.js",
+		"notes.tmp",
+		"lib/util.js",
+		"lib/cache.tmp",
+		"node_modules/left-pad/index.js",
+		"build/out/app.js",
+	}
+	for _, f := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		pattern  string
+		excludes []string
+		want     []string // relative paths
+	}{
+		{
+			name:     "flat pattern",
+			pattern:  "*",
+			excludes: []string{"*.tmp"},
+			want:     []string{"index.js"},
+		},
+		{
+			name:     "excludes apply after recursive expansion",
+			pattern:  "**/*",
+			excludes: []string{"node_modules", "*.tmp"},
+			want:     []string{"build/out/app.js", "index.js", "lib/util.js"},
+		},
+		{
+			name:     "path pattern",
+			pattern:  "**/*.js",
+			excludes: []string{"build/**"},
+			want:     []string{"index.js", "lib/util.js", "node_modules/left-pad/index.js"},
+		},
+		{
+			name:     "no excludes",
+			pattern:  "lib/*",
+			excludes: nil,
+			want:     []string{"cache.tmp", "util.js"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern := filepath.Join(tmpDir, filepath.FromSlash(tt.pattern))
+			matches, err := ExpandWithExcludes([]string{pattern}, tt.excludes)
+			if err != nil {
+				t.Fatalf("ExpandWithExcludes() error = %v", err)
+			}
+
+			var got []string
+			for _, match := range matches {
+				got = append(got, filepath.ToSlash(match.RelPath))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ExpandWithExcludes(%s, %v) got %v, want %v", tt.pattern, tt.excludes, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ExpandWithExcludes(%s, %v) got %v, want %v", tt.pattern, tt.excludes, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+
+	t.Run("malformed exclude", func(t *testing.T) {
+		if _, err := ExpandWithExcludes([]string{filepath.Join(tmpDir, "*")}, []string{"["}); err == nil {
+			t.Error("ExpandWithExcludes() expected error for malformed exclude")
+		}
+	})
+}
+
+func TestExcluded(t *testing.T) {
+	tests := []struct {
+		relPath  string
+		excludes []string
+		want     bool
+	}{
+		{"a.tmp", []string{"*.tmp"}, true},
+		{"src/a.tmp", []string{"*.tmp"}, true},
+		{"node_modules/x/index.js", []string{"node_modules"}, true},
+		{"src/node_modules/x.js", []string{"node_modules"}, true},
+		{"src/main.go", []string{"*.tmp", "node_modules"}, false},
+		{"build/out/app.js", []string{"build/*"}, false},
+		{"build/out/app.js", []string{"build/**"}, true},
+		{"src/build/app.js", []string{"build/**"}, false},
+		{"src/main.go", nil, false},
+	}
+
+	for _, tt := range tests {
+		got, err := Excluded(filepath.FromSlash(tt.relPath), tt.excludes)
+		if err != nil {
+			t.Errorf("Excluded(%s, %v) error = %v", tt.relPath, tt.excludes, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Excluded(%s, %v) = %v, want %v", tt.relPath, tt.excludes, got, tt.want)
+		}
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"report.{txt,csv,pdf}", []string{"report.txt", "report.csv", "report.pdf"}},
+		{"{a,b}/{x,y}.txt", []string{"a/x.txt", "a/y.txt", "b/x.txt", "b/y.txt"}},
+		{"f.{txt,c{s,p}v}", []string{"f.txt", "f.csv", "f.cpv"}},
+		{"{,old-}log.txt", []string{"log.txt", "old-log.txt"}},
+		{"plain.txt", []string{"plain.txt"}},
+		{"{single}.txt", []string{"{single}.txt"}},
+		{"{unclosed,x.txt", []string{"{unclosed,x.txt"}},
+		{"{a{b,c}", []string{"{ab", "{ac"}},
+		{"*.{go,md}", []string{"*.go", "*.md"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got := expandBraces(tt.pattern)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expandBraces(%s) = %v, want %v", tt.pattern, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expandBraces(%s) = %v, want %v", tt.pattern, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestExpandWithBraces(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	for _, f := range []string{"report.txt", "report.csv", "report.pdf", "report.doc", "data/a.json", "data/b.yaml"} {
+		path := filepath.Join(tmpDir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		wantLen int
+	}{
+		{"single group", "report.{txt,csv,pdf}", 3},
+		{"group with wildcard", "{report,data/*}.{json,txt}", 2},
+		{"nested group", "{report.{txt,doc},data/*.y{a,}ml}", 3},
+		{"missing alternatives", "report.{txt,xls}", 1},
+		{"overlapping alternatives", "report.{txt,t*}", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := Expand([]string{filepath.Join(tmpDir, filepath.FromSlash(tt.pattern))})
+			if err != nil {
+				t.Fatalf("Expand() error = %v", err)
+			}
+			if len(matches) != tt.wantLen {
+				t.Errorf("Expand(%s) got %d matches, want %d", tt.pattern, len(matches), tt.wantLen)
+			}
+		})
+	}
+}
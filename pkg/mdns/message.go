@@ -0,0 +1,338 @@
+// Package mdns implements the small part of multicast DNS (RFC 6762) and
+// DNS-based service discovery (RFC 6763) goflux-lite needs: a responder
+// advertising one service instance and a browser finding instances of a
+// service. Only the A, AAAA, PTR, SRV and TXT record types are understood.
+package mdns
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Record types
+const (
+	TypeA    uint16 = 1
+	TypePTR  uint16 = 12
+	TypeTXT  uint16 = 16
+	TypeAAAA uint16 = 28
+	TypeSRV  uint16 = 33
+	TypeANY  uint16 = 255
+)
+
+const (
+	classIN    uint16 = 1
+	cacheFlush uint16 = 1 << 15 // in a record's class: replaces cached records of its name and type
+
+	flagResponse      uint16 = 1 << 15
+	flagAuthoritative uint16 = 1 << 10
+
+	maxPointers = 32 // compression pointers followed in one name before it is rejected
+)
+
+var errTruncated = errors.New("mdns: message truncated")
+
+// Question asks for the records of one name and type
+type Question struct {
+	Name string // fully qualified, ending in "."
+	Type uint16
+}
+
+// Record is a resource record. Which data fields are used depends on Type:
+// Target for PTR and SRV, Port for SRV, Text for TXT and IP for A and AAAA.
+type Record struct {
+	Name string // fully qualified, ending in "."
+	Type uint16
+	TTL  uint32 // seconds; 0 withdraws the record
+
+	Target string
+	Port   uint16
+	Text   []string
+	IP     net.IP
+}
+
+// Message is a DNS message. Decoding gathers the answer, authority and
+// additional sections into Answers; encoding sends Answers as answers and
+// Extra as additional records.
+type Message struct {
+	ID        uint16
+	Response  bool
+	Questions []Question
+	Answers   []Record
+	Extra     []Record
+}
+
+// Encode returns m in DNS wire format. Names are written in full, without
+// compression.
+func (m *Message) Encode() ([]byte, error) {
+	var flags uint16
+	if m.Response {
+		flags = flagResponse | flagAuthoritative
+	}
+	b := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(b[0:], m.ID)
+	binary.BigEndian.PutUint16(b[2:], flags)
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.Questions)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(m.Answers)))
+	binary.BigEndian.PutUint16(b[10:], uint16(len(m.Extra)))
+
+	var err error
+	for _, q := range m.Questions {
+		if b, err = appendName(b, q.Name); err != nil {
+			return nil, err
+		}
+		b = binary.BigEndian.AppendUint16(b, q.Type)
+		b = binary.BigEndian.AppendUint16(b, classIN)
+	}
+	for _, r := range append(append([]Record(nil), m.Answers...), m.Extra...) {
+		if b, err = appendRecord(b, r); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// appendRecord appends one resource record to b
+func appendRecord(b []byte, r Record) ([]byte, error) {
+	b, err := appendName(b, r.Name)
+	if err != nil {
+		return nil, err
+	}
+	class := classIN
+	if r.Type != TypePTR {
+		// Only this host answers for its SRV, TXT and address records
+		class |= cacheFlush
+	}
+	b = binary.BigEndian.AppendUint16(b, r.Type)
+	b = binary.BigEndian.AppendUint16(b, class)
+	b = binary.BigEndian.AppendUint32(b, r.TTL)
+
+	lengthAt := len(b)
+	b = append(b, 0, 0)
+	switch r.Type {
+	case TypePTR:
+		b, err = appendName(b, r.Target)
+	case TypeSRV:
+		b = append(b, 0, 0, 0, 0) // priority and weight
+		b = binary.BigEndian.AppendUint16(b, r.Port)
+		b, err = appendName(b, r.Target)
+	case TypeTXT:
+		if len(r.Text) == 0 {
+			b = append(b, 0) // a TXT record holds at least one, empty, string
+		}
+		for _, s := range r.Text {
+			if len(s) > 255 {
+				return nil, fmt.Errorf("mdns: TXT string longer than 255 bytes: %.20q...", s)
+			}
+			b = append(b, byte(len(s)))
+			b = append(b, s...)
+		}
+	case TypeA:
+		ip := r.IP.To4()
+		if ip == nil {
+			return nil, fmt.Errorf("mdns: A record for %s needs an IPv4 address", r.Name)
+		}
+		b = append(b, ip...)
+	case TypeAAAA:
+		ip := r.IP.To16()
+		if ip == nil || r.IP.To4() != nil {
+			return nil, fmt.Errorf("mdns: AAAA record for %s needs an IPv6 address", r.Name)
+		}
+		b = append(b, ip...)
+	default:
+		return nil, fmt.Errorf("mdns: cannot encode record type %d", r.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint16(b[lengthAt:], uint16(len(b)-lengthAt-2))
+	return b, nil
+}
+
+// appendName appends name as a sequence of labels. Dots inside a label, as
+// in an instance name like "Backups v2", may be escaped as "\.".
+func appendName(b []byte, name string) ([]byte, error) {
+	for _, label := range splitName(name) {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("mdns: invalid label %q in %q", label, name)
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0), nil
+}
+
+// splitName splits a fully qualified name into its labels, unescaping "\."
+// and "\\"
+func splitName(name string) []string {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return nil
+	}
+	var labels []string
+	var label strings.Builder
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case c == '\\' && i+1 < len(name):
+			i++
+			label.WriteByte(name[i])
+		case c == '.':
+			labels = append(labels, label.String())
+			label.Reset()
+		default:
+			label.WriteByte(c)
+		}
+	}
+	return append(labels, label.String())
+}
+
+// escapeLabel escapes the dots and backslashes in a label so it can be part
+// of a name
+func escapeLabel(label string) string {
+	return strings.NewReplacer(`\`, `\\`, ".", `\.`).Replace(label)
+}
+
+// Decode parses a DNS message. Records of types this package doesn't
+// understand are skipped.
+func Decode(b []byte) (*Message, error) {
+	if len(b) < 12 {
+		return nil, errTruncated
+	}
+	m := &Message{
+		ID:       binary.BigEndian.Uint16(b[0:]),
+		Response: binary.BigEndian.Uint16(b[2:])&flagResponse != 0,
+	}
+	questions := int(binary.BigEndian.Uint16(b[4:]))
+	records := int(binary.BigEndian.Uint16(b[6:])) + int(binary.BigEndian.Uint16(b[8:])) + int(binary.BigEndian.Uint16(b[10:]))
+
+	off := 12
+	for i := 0; i < questions; i++ {
+		name, next, err := readName(b, off)
+		if err != nil {
+			return nil, err
+		}
+		if next+4 > len(b) {
+			return nil, errTruncated
+		}
+		m.Questions = append(m.Questions, Question{Name: name, Type: binary.BigEndian.Uint16(b[next:])})
+		off = next + 4
+	}
+
+	for i := 0; i < records; i++ {
+		r, next, known, err := readRecord(b, off)
+		if err != nil {
+			return nil, err
+		}
+		if known {
+			m.Answers = append(m.Answers, r)
+		}
+		off = next
+	}
+	return m, nil
+}
+
+// readRecord reads the resource record at off, reporting whether its type is
+// one this package understands
+func readRecord(b []byte, off int) (Record, int, bool, error) {
+	name, off, err := readName(b, off)
+	if err != nil {
+		return Record{}, 0, false, err
+	}
+	if off+10 > len(b) {
+		return Record{}, 0, false, errTruncated
+	}
+	r := Record{
+		Name: name,
+		Type: binary.BigEndian.Uint16(b[off:]),
+		TTL:  binary.BigEndian.Uint32(b[off+4:]),
+	}
+	length := int(binary.BigEndian.Uint16(b[off+8:]))
+	start, end := off+10, off+10+length
+	if end > len(b) {
+		return Record{}, 0, false, errTruncated
+	}
+	data := b[start:end]
+
+	switch r.Type {
+	case TypePTR:
+		r.Target, _, err = readName(b, start)
+	case TypeSRV:
+		if length < 7 {
+			return Record{}, 0, false, errTruncated
+		}
+		r.Port = binary.BigEndian.Uint16(data[4:])
+		r.Target, _, err = readName(b, start+6)
+	case TypeTXT:
+		for i := 0; i < len(data); {
+			n := int(data[i])
+			if i+1+n > len(data) {
+				return Record{}, 0, false, errTruncated
+			}
+			if n > 0 {
+				r.Text = append(r.Text, string(data[i+1:i+1+n]))
+			}
+			i += 1 + n
+		}
+	case TypeA:
+		if length != net.IPv4len {
+			return Record{}, 0, false, fmt.Errorf("mdns: A record of %d bytes", length)
+		}
+		r.IP = net.IP(append([]byte(nil), data...))
+	case TypeAAAA:
+		if length != net.IPv6len {
+			return Record{}, 0, false, fmt.Errorf("mdns: AAAA record of %d bytes", length)
+		}
+		r.IP = net.IP(append([]byte(nil), data...))
+	default:
+		return Record{}, end, false, nil
+	}
+	if err != nil {
+		return Record{}, 0, false, err
+	}
+	return r, end, true, nil
+}
+
+// readName reads the possibly compressed name at off, returning it and the
+// offset just past it
+func readName(b []byte, off int) (string, int, error) {
+	var name strings.Builder
+	next := -1 // where reading continues, once a pointer has been followed
+	for pointers := 0; ; {
+		if off >= len(b) {
+			return "", 0, errTruncated
+		}
+		n := int(b[off])
+		switch {
+		case n == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			if name.Len() == 0 {
+				return ".", next, nil
+			}
+			return name.String(), next, nil
+		case n&0xC0 == 0xC0:
+			if off+1 >= len(b) {
+				return "", 0, errTruncated
+			}
+			if pointers++; pointers > maxPointers {
+				return "", 0, errors.New("mdns: too many compression pointers")
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(b[off:]) & 0x3FFF)
+		case n&0xC0 != 0:
+			return "", 0, fmt.Errorf("mdns: unsupported label type %#x", n&0xC0)
+		default:
+			if off+1+n > len(b) {
+				return "", 0, errTruncated
+			}
+			name.WriteString(escapeLabel(string(b[off+1 : off+1+n])))
+			name.WriteByte('.')
+			off += 1 + n
+		}
+	}
+}
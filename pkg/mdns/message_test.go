@@ -0,0 +1,106 @@
+package mdns
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestMessage_RoundTrip(t *testing.T) {
+	m := &Message{
+		ID:        7,
+		Response:  true,
+		Questions: []Question{{Name: "_goflux._tcp.local.", Type: TypePTR}},
+		Answers: []Record{
+			{Name: "_goflux._tcp.local.", Type: TypePTR, TTL: 4500, Target: `Backups\.v2._goflux._tcp.local.`},
+		},
+		Extra: []Record{
+			{Name: `Backups\.v2._goflux._tcp.local.`, Type: TypeSRV, TTL: 120, Target: "nas.local.", Port: 8080},
+			{Name: `Backups\.v2._goflux._tcp.local.`, Type: TypeTXT, TTL: 4500, Text: []string{"a=1", "b=2"}},
+			{Name: "nas.local.", Type: TypeA, TTL: 120, IP: net.ParseIP("192.168.1.10").To4()},
+			{Name: "nas.local.", Type: TypeAAAA, TTL: 120, IP: net.ParseIP("fe80::1")},
+		},
+	}
+	data, err := m.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	if got.ID != 7 || !got.Response {
+		t.Errorf("expected ID 7 response, got ID %d response %v", got.ID, got.Response)
+	}
+	if !reflect.DeepEqual(got.Questions, m.Questions) {
+		t.Errorf("questions: expected %+v, got %+v", m.Questions, got.Questions)
+	}
+	want := append(append([]Record(nil), m.Answers...), m.Extra...)
+	if !reflect.DeepEqual(got.Answers, want) {
+		t.Errorf("records:\nexpected %+v\ngot      %+v", want, got.Answers)
+	}
+}
+
+func TestDecode_CompressedNames(t *testing.T) {
+	// A response whose PTR target points back at the question's name
+	data := []byte{
+		0, 0, 0x84, 0, 0, 1, 0, 1, 0, 0, 0, 0,
+		// Question at offset 12: _goflux._tcp.local. PTR IN
+		7, '_', 'g', 'o', 'f', 'l', 'u', 'x', 4, '_', 't', 'c', 'p', 5, 'l', 'o', 'c', 'a', 'l', 0,
+		0, 12, 0, 1,
+		// Answer: pointer to offset 12, PTR IN, TTL 120
+		0xC0, 12, 0, 12, 0, 1, 0, 0, 0, 120,
+		// RDATA: "nas" followed by a pointer to the question's name
+		0, 6, 3, 'n', 'a', 's', 0xC0, 12,
+	}
+	m, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(m.Answers) != 1 {
+		t.Fatalf("expected 1 answer, got %d", len(m.Answers))
+	}
+	r := m.Answers[0]
+	if r.Name != "_goflux._tcp.local." || r.Target != "nas._goflux._tcp.local." {
+		t.Errorf("unexpected names: %q -> %q", r.Name, r.Target)
+	}
+}
+
+func TestDecode_PointerLoop(t *testing.T) {
+	data := []byte{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0xC0, 12, 0, 1, 0, 1}
+	if _, err := Decode(data); err == nil {
+		t.Error("expected a name pointing at itself to be rejected")
+	}
+}
+
+func TestDecode_Truncated(t *testing.T) {
+	data, err := (&Message{Questions: []Question{{Name: "nas.local.", Type: TypeA}}}).Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	for n := 0; n < len(data); n++ {
+		if _, err := Decode(data[:n]); err == nil {
+			t.Errorf("expected %d of %d bytes to be rejected", n, len(data))
+		}
+	}
+}
+
+func TestTXT_RoundTrip(t *testing.T) {
+	text := EncodeTXT(map[string]string{"port": "8080", "auth": "true", "name": "Backups"})
+	want := []string{"auth=true", "name=Backups", "port=8080"}
+	if !reflect.DeepEqual(text, want) {
+		t.Errorf("expected %v, got %v", want, text)
+	}
+
+	got := DecodeTXT([]string{"Port=8080", "port=9090", "flag", "empty="})
+	if got["port"] != "8080" {
+		t.Errorf("expected the first port to win, got %q", got["port"])
+	}
+	if v, ok := got["flag"]; !ok || v != "" {
+		t.Errorf("expected a key without = to be present and empty, got %q, %v", v, ok)
+	}
+	if v, ok := got["empty"]; !ok || v != "" {
+		t.Errorf("expected empty to be present and empty, got %q, %v", v, ok)
+	}
+}
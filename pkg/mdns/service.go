@@ -0,0 +1,375 @@
+package mdns
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Port is the UDP port multicast DNS is served on
+const Port = 5353
+
+// groupAddr is the IPv4 multicast group mDNS queries and answers are sent to
+var groupAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: Port}
+
+const (
+	// servicesName lists the service types on the network, for browsers
+	// enumerating everything offered
+	servicesName = "_services._dns-sd._udp.local."
+
+	hostTTL    = 120  // seconds, for records naming this host
+	serviceTTL = 4500 // seconds, for records describing the service
+)
+
+// Service is one instance of a service advertised by a Responder
+type Service struct {
+	Instance string   // human-readable instance name, such as "Backups"
+	Service  string   // service type, such as "_goflux._tcp"
+	Host     string   // host name without domain, such as "nas"
+	Port     uint16   // port the service listens on
+	IPs      []net.IP // addresses of the host
+	Text     []string // TXT record strings, see EncodeTXT
+}
+
+// serviceName returns the fully qualified name of the service type
+func (s Service) serviceName() string {
+	return s.Service + ".local."
+}
+
+// instanceName returns the fully qualified name of the instance
+func (s Service) instanceName() string {
+	return escapeLabel(s.Instance) + "." + s.serviceName()
+}
+
+// hostName returns the fully qualified name of the host
+func (s Service) hostName() string {
+	return escapeLabel(s.Host) + ".local."
+}
+
+// records returns the records describing the service: its PTR, SRV and TXT
+// records and one address record per IP, all with ttl if it isn't negative
+func (s Service) records(ttl int) (ptr, srv, txt Record, addrs []Record) {
+	pick := func(def uint32) uint32 {
+		if ttl >= 0 {
+			return uint32(ttl)
+		}
+		return def
+	}
+	ptr = Record{Name: s.serviceName(), Type: TypePTR, TTL: pick(serviceTTL), Target: s.instanceName()}
+	srv = Record{Name: s.instanceName(), Type: TypeSRV, TTL: pick(hostTTL), Target: s.hostName(), Port: s.Port}
+	txt = Record{Name: s.instanceName(), Type: TypeTXT, TTL: pick(serviceTTL), Text: s.Text}
+	for _, ip := range s.IPs {
+		addr := Record{Name: s.hostName(), Type: TypeA, TTL: pick(hostTTL), IP: ip}
+		if ip.To4() == nil {
+			addr.Type = TypeAAAA
+		}
+		addrs = append(addrs, addr)
+	}
+	return ptr, srv, txt, addrs
+}
+
+// Responder answers multicast DNS queries for one service instance
+type Responder struct {
+	service Service
+	conn    *net.UDPConn
+	group   *net.UDPAddr // where multicast answers and announcements go
+
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewResponder joins the mDNS multicast group to answer queries for service
+func NewResponder(service Service) (*Responder, error) {
+	conn, err := net.ListenMulticastUDP("udp4", nil, groupAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to join the mDNS group: %w", err)
+	}
+	return newResponder(service, conn, groupAddr), nil
+}
+
+// newResponder returns a responder reading queries from conn and multicasting
+// to group
+func newResponder(service Service, conn *net.UDPConn, group *net.UDPAddr) *Responder {
+	return &Responder{service: service, conn: conn, group: group, stopChan: make(chan struct{})}
+}
+
+// Start announces the service and answers queries until Stop is called
+func (r *Responder) Start() {
+	go r.serve()
+	go r.announce()
+}
+
+// Stop withdraws the service from the network and stops answering
+func (r *Responder) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+		// A goodbye tells browsers to forget the service now rather than
+		// when its records expire
+		r.send(r.unsolicited(0), r.group)
+		r.conn.Close()
+	})
+}
+
+// announce sends the service's records unasked, twice, a second apart, as
+// RFC 6762 asks of a responder starting up
+func (r *Responder) announce() {
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(time.Second):
+			case <-r.stopChan:
+				return
+			}
+		}
+		r.send(r.unsolicited(-1), r.group)
+	}
+}
+
+// unsolicited returns a response carrying every record of the service, with
+// ttl as in records
+func (r *Responder) unsolicited(ttl int) *Message {
+	ptr, srv, txt, addrs := r.service.records(ttl)
+	return &Message{Response: true, Answers: append([]Record{ptr, srv, txt}, addrs...)}
+}
+
+// serve answers queries until the connection is closed
+func (r *Responder) serve() {
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := r.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		query, err := Decode(buf[:n])
+		if err != nil || query.Response {
+			continue
+		}
+		resp := r.answer(query)
+		if resp == nil {
+			continue
+		}
+
+		// A query from a port other than 5353 comes from a simple resolver
+		// expecting a unicast DNS answer (RFC 6762 section 6.7)
+		to := r.group
+		if from.Port != Port {
+			resp.ID = query.ID
+			resp.Questions = query.Questions
+			to = from
+		}
+		r.send(resp, to)
+	}
+}
+
+// answer returns the response to query, or nil if it asks about nothing
+// this responder knows
+func (r *Responder) answer(query *Message) *Message {
+	ptr, srv, txt, addrs := r.service.records(-1)
+	resp := &Message{Response: true}
+	add := func(records ...Record) {
+		resp.Answers = append(resp.Answers, records...)
+	}
+	wants := func(q Question, types ...uint16) bool {
+		for _, t := range types {
+			if q.Type == t || q.Type == TypeANY {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, q := range query.Questions {
+		switch {
+		case strings.EqualFold(q.Name, ptr.Name) && wants(q, TypePTR):
+			add(ptr)
+		case strings.EqualFold(q.Name, servicesName) && wants(q, TypePTR):
+			add(Record{Name: servicesName, Type: TypePTR, TTL: serviceTTL, Target: ptr.Name})
+		case strings.EqualFold(q.Name, srv.Name):
+			if wants(q, TypeSRV) {
+				add(srv)
+			}
+			if wants(q, TypeTXT) {
+				add(txt)
+			}
+		case strings.EqualFold(q.Name, r.service.hostName()):
+			for _, addr := range addrs {
+				if wants(q, addr.Type) {
+					add(addr)
+				}
+			}
+		}
+	}
+	if len(resp.Answers) == 0 {
+		return nil
+	}
+
+	// Send along what the asker will need next so it doesn't have to ask
+	for _, extra := range append([]Record{srv, txt}, addrs...) {
+		if !hasRecord(resp.Answers, extra) {
+			resp.Extra = append(resp.Extra, extra)
+		}
+	}
+	return resp
+}
+
+// hasRecord reports whether records holds a record of r's name and type
+func hasRecord(records []Record, r Record) bool {
+	for _, record := range records {
+		if record.Type == r.Type && strings.EqualFold(record.Name, r.Name) && record.IP.Equal(r.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// send encodes m and writes it to addr, dropping it on error as a lost
+// packet would be
+func (r *Responder) send(m *Message, addr *net.UDPAddr) {
+	data, err := m.Encode()
+	if err != nil {
+		return
+	}
+	r.conn.WriteToUDP(data, addr)
+}
+
+// Entry is a service instance found by Browse
+type Entry struct {
+	Instance string   // human-readable instance name
+	Host     string   // fully qualified host name
+	Port     uint16   // port the service listens on
+	IPs      []net.IP // addresses of the host, when the responder sent them
+	Text     []string // TXT record strings, see DecodeTXT
+	Source   net.IP   // address the answer came from
+}
+
+// Browse asks the network for instances of service (such as "_goflux._tcp")
+// and returns those that answer within timeout. Instances are only returned
+// once their SRV record has arrived.
+func Browse(service string, timeout time.Duration) ([]*Entry, error) {
+	return browse(groupAddr, service, timeout)
+}
+
+// browse is Browse, sending the query to addr
+func browse(addr *net.UDPAddr, service string, timeout time.Duration) ([]*Entry, error) {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mDNS socket: %w", err)
+	}
+	defer conn.Close()
+
+	name := service + ".local."
+	query, err := (&Message{Questions: []Question{{Name: name, Type: TypePTR}}}).Encode()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteToUDP(query, addr); err != nil {
+		return nil, fmt.Errorf("failed to send mDNS query: %w", err)
+	}
+
+	// Ask again after a second, in case the first query or its answers
+	// were lost
+	deadline := time.Now().Add(timeout)
+	retry := time.AfterFunc(time.Second, func() { conn.WriteToUDP(query, addr) })
+	defer retry.Stop()
+	conn.SetReadDeadline(deadline)
+
+	found := newBrowseResults(name)
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			break // the deadline passed
+		}
+		if resp, err := Decode(buf[:n]); err == nil && resp.Response {
+			found.add(resp.Answers, from.IP)
+		}
+	}
+	return found.entries(), nil
+}
+
+// browseResults gathers the records answering a browse, which may arrive
+// spread over several packets and in any order
+type browseResults struct {
+	service   string            // fully qualified service name browsed for
+	instances map[string]*Entry // by lower-cased instance name
+	hosts     map[string][]net.IP
+	order     []string // instance keys, in the order they were found
+}
+
+func newBrowseResults(service string) *browseResults {
+	return &browseResults{service: service, instances: make(map[string]*Entry), hosts: make(map[string][]net.IP)}
+}
+
+// instance returns the entry for a fully qualified instance name, creating it
+func (b *browseResults) instance(name string, source net.IP) *Entry {
+	key := strings.ToLower(name)
+	entry, ok := b.instances[key]
+	if !ok {
+		label := name
+		if labels := splitName(name); len(labels) > 0 {
+			label = labels[0]
+		}
+		entry = &Entry{Instance: label, Source: source}
+		b.instances[key] = entry
+		b.order = append(b.order, key)
+	}
+	return entry
+}
+
+// add records the answers of one response that came from source
+func (b *browseResults) add(records []Record, source net.IP) {
+	suffix := "." + strings.ToLower(b.service)
+	for _, r := range records {
+		isInstance := strings.HasSuffix(strings.ToLower(r.Name), suffix)
+		switch {
+		case r.Type == TypePTR && strings.EqualFold(r.Name, b.service):
+			if r.TTL == 0 {
+				// A goodbye: the instance is leaving
+				delete(b.instances, strings.ToLower(r.Target))
+				continue
+			}
+			b.instance(r.Target, source)
+		case r.Type == TypeSRV && isInstance:
+			entry := b.instance(r.Name, source)
+			entry.Host, entry.Port = r.Target, r.Port
+		case r.Type == TypeTXT && isInstance:
+			b.instance(r.Name, source).Text = r.Text
+		case r.Type == TypeA || r.Type == TypeAAAA:
+			host := strings.ToLower(r.Name)
+			if !hasIP(b.hosts[host], r.IP) {
+				b.hosts[host] = append(b.hosts[host], r.IP)
+			}
+		}
+	}
+}
+
+// hasIP reports whether ips holds ip
+func hasIP(ips []net.IP, ip net.IP) bool {
+	for _, have := range ips {
+		if have.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// entries returns the instances found whose SRV record arrived, with the
+// addresses of their hosts
+func (b *browseResults) entries() []*Entry {
+	var entries []*Entry
+	for _, key := range b.order {
+		entry, ok := b.instances[key]
+		if !ok || entry.Host == "" {
+			continue
+		}
+		entry.IPs = b.hosts[strings.ToLower(entry.Host)]
+		entries = append(entries, entry)
+	}
+	return entries
+}
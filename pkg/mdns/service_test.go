@@ -0,0 +1,141 @@
+package mdns
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func testService() Service {
+	return Service{
+		Instance: "Backups v2.1",
+		Service:  "_goflux._tcp",
+		Host:     "nas",
+		Port:     8080,
+		IPs:      []net.IP{net.ParseIP("192.168.1.10")},
+		Text:     []string{"txtvers=1", "scheme=http"},
+	}
+}
+
+// startResponder runs a responder for service on a loopback socket, returning
+// the address queries should be sent to
+func startResponder(t *testing.T, service Service) *net.UDPAddr {
+	t.Helper()
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := conn.LocalAddr().(*net.UDPAddr)
+	// Announcements loop back to the responder itself, which ignores them
+	r := newResponder(service, conn, addr)
+	r.Start()
+	t.Cleanup(r.Stop)
+	return addr
+}
+
+func TestResponder_AnswerPTR(t *testing.T) {
+	r := newResponder(testService(), nil, nil)
+	resp := r.answer(&Message{Questions: []Question{{Name: "_GOFLUX._tcp.local.", Type: TypePTR}}})
+	if resp == nil {
+		t.Fatal("expected an answer")
+	}
+	if len(resp.Answers) != 1 || resp.Answers[0].Target != `Backups v2\.1._goflux._tcp.local.` {
+		t.Fatalf("unexpected answers: %+v", resp.Answers)
+	}
+
+	// SRV, TXT and A records come along so the browser needn't ask again
+	types := map[uint16]bool{}
+	for _, r := range resp.Extra {
+		types[r.Type] = true
+	}
+	for _, want := range []uint16{TypeSRV, TypeTXT, TypeA} {
+		if !types[want] {
+			t.Errorf("expected an extra record of type %d, got %+v", want, resp.Extra)
+		}
+	}
+}
+
+func TestResponder_AnswerOthers(t *testing.T) {
+	r := newResponder(testService(), nil, nil)
+	tests := []struct {
+		question Question
+		want     []uint16
+	}{
+		{Question{Name: `Backups v2\.1._goflux._tcp.local.`, Type: TypeANY}, []uint16{TypeSRV, TypeTXT}},
+		{Question{Name: `Backups v2\.1._goflux._tcp.local.`, Type: TypeTXT}, []uint16{TypeTXT}},
+		{Question{Name: "nas.local.", Type: TypeA}, []uint16{TypeA}},
+		{Question{Name: servicesName, Type: TypePTR}, []uint16{TypePTR}},
+	}
+	for _, tt := range tests {
+		resp := r.answer(&Message{Questions: []Question{tt.question}})
+		if resp == nil {
+			t.Errorf("%+v: expected an answer", tt.question)
+			continue
+		}
+		var got []uint16
+		for _, a := range resp.Answers {
+			got = append(got, a.Type)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%+v: expected answer types %v, got %v", tt.question, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%+v: expected answer types %v, got %v", tt.question, tt.want, got)
+				break
+			}
+		}
+	}
+
+	for _, q := range []Question{
+		{Name: "_http._tcp.local.", Type: TypePTR},
+		{Name: "nas.local.", Type: TypeAAAA},
+		{Name: "other.local.", Type: TypeA},
+	} {
+		if resp := r.answer(&Message{Questions: []Question{q}}); resp != nil {
+			t.Errorf("%+v: expected no answer, got %+v", q, resp.Answers)
+		}
+	}
+}
+
+func TestBrowse_FindsResponder(t *testing.T) {
+	addr := startResponder(t, testService())
+
+	entries, err := browse(addr, "_goflux._tcp", 300*time.Millisecond)
+	if err != nil {
+		t.Fatalf("browse failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Instance != "Backups v2.1" || e.Host != "nas.local." || e.Port != 8080 {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+	if len(e.IPs) != 1 || !e.IPs[0].Equal(net.ParseIP("192.168.1.10")) {
+		t.Errorf("unexpected IPs: %v", e.IPs)
+	}
+	if DecodeTXT(e.Text)["scheme"] != "http" {
+		t.Errorf("unexpected text: %v", e.Text)
+	}
+	if !e.Source.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("expected the answer to come from loopback, got %v", e.Source)
+	}
+}
+
+func TestBrowse_Goodbye(t *testing.T) {
+	service := testService()
+	b := newBrowseResults("_goflux._tcp.local.")
+	ptr, srv, txt, addrs := service.records(-1)
+	b.add(append([]Record{ptr, srv, txt}, addrs...), net.IPv4(127, 0, 0, 1))
+	if len(b.entries()) != 1 {
+		t.Fatalf("expected 1 entry before the goodbye")
+	}
+
+	ptr, _, _, _ = service.records(0)
+	b.add([]Record{ptr}, net.IPv4(127, 0, 0, 1))
+	if entries := b.entries(); len(entries) != 0 {
+		t.Errorf("expected the goodbye to remove the entry, got %+v", entries[0])
+	}
+}
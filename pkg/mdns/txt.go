@@ -0,0 +1,41 @@
+package mdns
+
+import (
+	"sort"
+	"strings"
+)
+
+// EncodeTXT turns key/value pairs into the strings of a DNS-SD TXT record,
+// "key=value" each, sorted by key so the record is the same every time
+func EncodeTXT(pairs map[string]string) []string {
+	keys := make([]string, 0, len(pairs))
+	for key := range pairs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	text := make([]string, 0, len(keys))
+	for _, key := range keys {
+		text = append(text, key+"="+pairs[key])
+	}
+	return text
+}
+
+// DecodeTXT reads the key/value pairs of a DNS-SD TXT record. Keys are
+// compared without regard to case and returned lower-cased; as RFC 6763
+// says, only the first of repeated keys counts, and a key without "=" has an
+// empty value.
+func DecodeTXT(text []string) map[string]string {
+	pairs := make(map[string]string, len(text))
+	for _, s := range text {
+		key, value, _ := strings.Cut(s, "=")
+		key = strings.ToLower(key)
+		if key == "" {
+			continue
+		}
+		if _, seen := pairs[key]; !seen {
+			pairs[key] = value
+		}
+	}
+	return pairs
+}
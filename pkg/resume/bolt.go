@@ -0,0 +1,311 @@
+package resume
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// sessionsBucket holds one JSON-encoded UploadSession per session ID
+var sessionsBucket = []byte("sessions")
+
+// BoltStore keeps upload sessions in a single bbolt database file. Each update
+// is an atomic transaction and nothing is scanned on startup, which suits
+// servers with many open sessions better than one JSON file per session.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens the session database at path, creating it if needed
+func NewBoltStore(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create metadata directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(sessionsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize session database: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// getSession decodes the session stored under sessionID, or returns nil if there is none
+func getSession(bucket *bolt.Bucket, sessionID string) (*UploadSession, error) {
+	data := bucket.Get([]byte(sessionID))
+	if data == nil {
+		return nil, nil
+	}
+	var session UploadSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", sessionID, err)
+	}
+	return &session, nil
+}
+
+// putSession encodes and stores session under sessionID
+func putSession(bucket *bolt.Bucket, sessionID string, session *UploadSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(sessionID), data)
+}
+
+// GetOrCreateSession gets an existing session or creates a new one owned by owner.
+// fileHash, if not empty, is the expected SHA-256 of the complete file; it may
+// arrive with any chunk but must not change once set.
+func (s *BoltStore) GetOrCreateSession(path, owner, fileHash string, totalChunks, chunkSize int) (*UploadSession, error) {
+	sessionID := makeSessionID(path)
+
+	var result *UploadSession
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sessionsBucket)
+		session, err := getSession(bucket, sessionID)
+		if err != nil {
+			return err
+		}
+
+		if session != nil {
+			// Validate session matches request
+			if session.TotalChunks != totalChunks {
+				return fmt.Errorf("%w: chunk count: session has %d, request has %d", ErrSessionMismatch, session.TotalChunks, totalChunks)
+			}
+			if fileHash != "" && session.FileHash != fileHash {
+				if session.FileHash != "" {
+					return fmt.Errorf("%w: file hash: session has %s, request has %s", ErrSessionMismatch, session.FileHash, fileHash)
+				}
+				session.FileHash = fileHash
+				if err := putSession(bucket, sessionID, session); err != nil {
+					return fmt.Errorf("failed to save session: %w", err)
+				}
+			}
+			result = session
+			return nil
+		}
+
+		session = &UploadSession{
+			Path:         path,
+			Owner:        owner,
+			FileHash:     fileHash,
+			TotalChunks:  totalChunks,
+			ChunkSize:    chunkSize,
+			ReceivedMap:  make([]bool, totalChunks),
+			CreatedAt:    time.Now(),
+			LastModified: time.Now(),
+			Completed:    false,
+		}
+		if err := putSession(bucket, sessionID, session); err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+		result = session
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// MarkChunkReceived marks a chunk as received
+func (s *BoltStore) MarkChunkReceived(path string, chunkID int) error {
+	sessionID := makeSessionID(path)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sessionsBucket)
+		session, err := getSession(bucket, sessionID)
+		if err != nil {
+			return err
+		}
+		if session == nil {
+			return fmt.Errorf("session not found for path: %s", path)
+		}
+
+		if chunkID < 0 || chunkID >= session.TotalChunks {
+			return fmt.Errorf("invalid chunk ID: %d (total: %d)", chunkID, session.TotalChunks)
+		}
+
+		session.ReceivedMap[chunkID] = true
+		session.LastModified = time.Now()
+
+		// Check if all chunks received
+		allReceived := true
+		for _, received := range session.ReceivedMap {
+			if !received {
+				allReceived = false
+				break
+			}
+		}
+		session.Completed = allReceived
+
+		return putSession(bucket, sessionID, session)
+	})
+}
+
+// MarkChunkMissing marks a chunk as not received
+func (s *BoltStore) MarkChunkMissing(path string, chunkID int) error {
+	sessionID := makeSessionID(path)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sessionsBucket)
+		session, err := getSession(bucket, sessionID)
+		if err != nil {
+			return err
+		}
+		if session == nil {
+			return fmt.Errorf("session not found for path: %s", path)
+		}
+
+		if chunkID < 0 || chunkID >= session.TotalChunks {
+			return fmt.Errorf("invalid chunk ID: %d (total: %d)", chunkID, session.TotalChunks)
+		}
+
+		session.ReceivedMap[chunkID] = false
+		session.Completed = false
+
+		return putSession(bucket, sessionID, session)
+	})
+}
+
+// Sessions returns every readable session in the database
+func (s *BoltStore) Sessions() []*UploadSession {
+	var sessions []*UploadSession
+	s.forEach(func(session *UploadSession) {
+		sessions = append(sessions, session)
+	})
+	return sessions
+}
+
+// GetSession retrieves a session by path
+func (s *BoltStore) GetSession(path string) (*UploadSession, bool) {
+	var session *UploadSession
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		session, err = getSession(tx.Bucket(sessionsBucket), makeSessionID(path))
+		return err
+	})
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return nil, false
+	}
+	return session, session != nil
+}
+
+// DeleteSession removes a completed session
+func (s *BoltStore) DeleteSession(path string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Delete([]byte(makeSessionID(path)))
+	})
+}
+
+// CountOpenSessions returns how many incomplete sessions belong to owner
+func (s *BoltStore) CountOpenSessions(owner string) int {
+	count := 0
+	s.forEach(func(session *UploadSession) {
+		if session.Owner == owner && !session.Completed {
+			count++
+		}
+	})
+	return count
+}
+
+// GetMissingChunks returns a list of chunk IDs that haven't been received
+func (s *BoltStore) GetMissingChunks(path string) ([]int, error) {
+	session, exists := s.GetSession(path)
+	if !exists {
+		return nil, fmt.Errorf("session not found for path: %s", path)
+	}
+
+	missing := []int{}
+	for i, received := range session.ReceivedMap {
+		if !received {
+			missing = append(missing, i)
+		}
+	}
+
+	return missing, nil
+}
+
+// CleanupOldSessions removes sessions older than the specified duration
+func (s *BoltStore) CleanupOldSessions(maxAge time.Duration) error {
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sessionsBucket)
+
+		// Keys can't be deleted while iterating, so collect them first
+		var toDelete [][]byte
+		err := bucket.ForEach(func(key, data []byte) error {
+			var session UploadSession
+			if err := json.Unmarshal(data, &session); err != nil {
+				return nil // leave unreadable entries for inspection
+			}
+			if session.LastModified.Before(cutoff) && !session.Completed {
+				toDelete = append(toDelete, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range toDelete {
+			if err := bucket.Delete(key); err != nil {
+				return fmt.Errorf("failed to delete session: %w", err)
+			}
+		}
+		removed = len(toDelete)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if removed > 0 {
+		fmt.Printf("Cleaned up %d old sessions\n", removed)
+	}
+
+	return nil
+}
+
+// Flush is a no-op: every update is committed to disk as it happens
+func (s *BoltStore) Flush() error {
+	return nil
+}
+
+// Close closes the session database
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// forEach calls fn for every readable session in the database
+func (s *BoltStore) forEach(fn func(session *UploadSession)) {
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).ForEach(func(key, data []byte) error {
+			var session UploadSession
+			if err := json.Unmarshal(data, &session); err != nil {
+				fmt.Printf("Warning: failed to parse session %s: %v\n", key, err)
+				return nil
+			}
+			fn(&session)
+			return nil
+		})
+	})
+	if err != nil {
+		fmt.Printf("Warning: failed to read sessions: %v\n", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,17 +14,50 @@ import (
 
 // UploadSession tracks the state of a partial upload
 type UploadSession struct {
-	Path         string    `json:"path"`          // destination path
-	TotalChunks  int       `json:"total_chunks"`  // expected number of chunks
-	ChunkSize    int       `json:"chunk_size"`    // size of each chunk
-	FileHash     string    `json:"file_hash"`     // SHA-256 of complete file (optional)
-	ReceivedMap  []bool    `json:"received_map"`  // bitmap of received chunks
-	CreatedAt    time.Time `json:"created_at"`    // when upload started
-	LastModified time.Time `json:"last_modified"` // last chunk received
-	Completed    bool      `json:"completed"`     // upload completed
+	Path         string    `json:"path"`            // destination path
+	Owner        string    `json:"owner,omitempty"` // user or client that started the upload
+	TotalChunks  int       `json:"total_chunks"`    // expected number of chunks
+	ChunkSize    int       `json:"chunk_size"`      // size of each chunk
+	FileHash     string    `json:"file_hash"`       // SHA-256 of complete file (optional)
+	ReceivedMap  []bool    `json:"received_map"`    // bitmap of received chunks
+	CreatedAt    time.Time `json:"created_at"`      // when upload started
+	LastModified time.Time `json:"last_modified"`   // last chunk received
+	Completed    bool      `json:"completed"`       // upload completed
 }
 
-// SessionStore manages upload sessions with persistence
+// ErrSessionMismatch reports a chunk whose file hash or chunk count differs
+// from those of the unfinished upload already under way at its path
+var ErrSessionMismatch = errors.New("upload session mismatch")
+
+// Store persists upload sessions. Sessions returned by a Store must not be
+// modified by the caller; use the Store's methods to update them.
+type Store interface {
+	// GetOrCreateSession gets the session for path or creates one owned by owner
+	GetOrCreateSession(path, owner, fileHash string, totalChunks, chunkSize int) (*UploadSession, error)
+	// MarkChunkReceived marks a chunk as received, completing the session once all have arrived
+	MarkChunkReceived(path string, chunkID int) error
+	// MarkChunkMissing marks a chunk as not received, so it will be asked for again
+	MarkChunkMissing(path string, chunkID int) error
+	// GetSession retrieves the session for path
+	GetSession(path string) (*UploadSession, bool)
+	// Sessions returns every stored session
+	Sessions() []*UploadSession
+	// DeleteSession removes the session for path
+	DeleteSession(path string) error
+	// CountOpenSessions returns how many incomplete sessions belong to owner
+	CountOpenSessions(owner string) int
+	// GetMissingChunks returns the IDs of chunks that haven't been received
+	GetMissingChunks(path string) ([]int, error)
+	// CleanupOldSessions removes incomplete sessions untouched for longer than maxAge
+	CleanupOldSessions(maxAge time.Duration) error
+	// Flush writes any buffered session state to durable storage
+	Flush() error
+	// Close flushes and releases the store
+	Close() error
+}
+
+// SessionStore manages upload sessions as one JSON file per session. It is
+// the default Store.
 type SessionStore struct {
 	sessions map[string]*UploadSession // keyed by upload ID (hash of path)
 	metaDir  string                    // directory for metadata files
@@ -50,18 +84,29 @@ func NewSessionStore(metaDir string) (*SessionStore, error) {
 	return store, nil
 }
 
-// GetOrCreateSession gets an existing session or creates a new one
-func (s *SessionStore) GetOrCreateSession(path string, totalChunks, chunkSize int) (*UploadSession, error) {
+// GetOrCreateSession gets an existing session or creates a new one owned by owner.
+// fileHash, if not empty, is the expected SHA-256 of the complete file; it may
+// arrive with any chunk but must not change once set.
+func (s *SessionStore) GetOrCreateSession(path, owner, fileHash string, totalChunks, chunkSize int) (*UploadSession, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	sessionID := s.makeSessionID(path)
+	sessionID := makeSessionID(path)
 
 	// Check if session exists
 	if session, exists := s.sessions[sessionID]; exists {
 		// Validate session matches request
 		if session.TotalChunks != totalChunks {
-			return nil, fmt.Errorf("chunk count mismatch: session has %d, request has %d", session.TotalChunks, totalChunks)
+			return nil, fmt.Errorf("%w: chunk count: session has %d, request has %d", ErrSessionMismatch, session.TotalChunks, totalChunks)
+		}
+		if fileHash != "" && session.FileHash != fileHash {
+			if session.FileHash != "" {
+				return nil, fmt.Errorf("%w: file hash: session has %s, request has %s", ErrSessionMismatch, session.FileHash, fileHash)
+			}
+			session.FileHash = fileHash
+			if err := s.saveSession(sessionID, session); err != nil {
+				return nil, fmt.Errorf("failed to save session: %w", err)
+			}
 		}
 		return session, nil
 	}
@@ -69,6 +114,8 @@ func (s *SessionStore) GetOrCreateSession(path string, totalChunks, chunkSize in
 	// Create new session
 	session := &UploadSession{
 		Path:         path,
+		Owner:        owner,
+		FileHash:     fileHash,
 		TotalChunks:  totalChunks,
 		ChunkSize:    chunkSize,
 		ReceivedMap:  make([]bool, totalChunks),
@@ -92,7 +139,7 @@ func (s *SessionStore) MarkChunkReceived(path string, chunkID int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	sessionID := s.makeSessionID(path)
+	sessionID := makeSessionID(path)
 	session, exists := s.sessions[sessionID]
 	if !exists {
 		return fmt.Errorf("session not found for path: %s", path)
@@ -119,12 +166,45 @@ func (s *SessionStore) MarkChunkReceived(path string, chunkID int) error {
 	return s.saveSession(sessionID, session)
 }
 
+// MarkChunkMissing marks a chunk as not received
+func (s *SessionStore) MarkChunkMissing(path string, chunkID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessionID := makeSessionID(path)
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found for path: %s", path)
+	}
+
+	if chunkID < 0 || chunkID >= session.TotalChunks {
+		return fmt.Errorf("invalid chunk ID: %d (total: %d)", chunkID, session.TotalChunks)
+	}
+
+	session.ReceivedMap[chunkID] = false
+	session.Completed = false
+
+	return s.saveSession(sessionID, session)
+}
+
+// Sessions returns every session in the store
+func (s *SessionStore) Sessions() []*UploadSession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := make([]*UploadSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
 // GetSession retrieves a session by path
 func (s *SessionStore) GetSession(path string) (*UploadSession, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	sessionID := s.makeSessionID(path)
+	sessionID := makeSessionID(path)
 	session, exists := s.sessions[sessionID]
 	return session, exists
 }
@@ -134,7 +214,7 @@ func (s *SessionStore) DeleteSession(path string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	sessionID := s.makeSessionID(path)
+	sessionID := makeSessionID(path)
 	delete(s.sessions, sessionID)
 
 	// Delete metadata file
@@ -146,12 +226,26 @@ func (s *SessionStore) DeleteSession(path string) error {
 	return nil
 }
 
+// CountOpenSessions returns how many incomplete sessions belong to owner
+func (s *SessionStore) CountOpenSessions(owner string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, session := range s.sessions {
+		if session.Owner == owner && !session.Completed {
+			count++
+		}
+	}
+	return count
+}
+
 // GetMissingChunks returns a list of chunk IDs that haven't been received
 func (s *SessionStore) GetMissingChunks(path string) ([]int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	sessionID := s.makeSessionID(path)
+	sessionID := makeSessionID(path)
 	session, exists := s.sessions[sessionID]
 	if !exists {
 		return nil, fmt.Errorf("session not found for path: %s", path)
@@ -197,13 +291,33 @@ func (s *SessionStore) CleanupOldSessions(maxAge time.Duration) error {
 	return nil
 }
 
+// Flush writes every in-memory session to disk
+func (s *SessionStore) Flush() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for sessionID, session := range s.sessions {
+		if err := s.saveSession(sessionID, session); err != nil {
+			return fmt.Errorf("failed to save session %s: %w", session.Path, err)
+		}
+	}
+	return nil
+}
+
+// Close flushes sessions to disk. The store holds no other resources.
+func (s *SessionStore) Close() error {
+	return s.Flush()
+}
+
 // makeSessionID creates a unique session ID from the path
-func (s *SessionStore) makeSessionID(path string) string {
+func makeSessionID(path string) string {
 	hash := sha256.Sum256([]byte(path))
 	return hex.EncodeToString(hash[:])[:16] // Use first 16 chars
 }
 
-// saveSession persists a session to disk
+// saveSession persists a session to disk. The file is written under a
+// temporary name and renamed into place, so a crash mid-write leaves the
+// previous version intact.
 func (s *SessionStore) saveSession(sessionID string, session *UploadSession) error {
 	metaFile := filepath.Join(s.metaDir, sessionID+".json")
 
@@ -212,7 +326,64 @@ func (s *SessionStore) saveSession(sessionID string, session *UploadSession) err
 		return err
 	}
 
-	return os.WriteFile(metaFile, data, 0644)
+	tmp, err := os.CreateTemp(s.metaDir, sessionID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, 0644); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := os.Rename(tmpName, metaFile); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+// validate checks that a session loaded from sessionID's file is usable
+func (session *UploadSession) validate(sessionID string) error {
+	if session.Path == "" {
+		return fmt.Errorf("missing path")
+	}
+	if makeSessionID(session.Path) != sessionID {
+		return fmt.Errorf("path %s does not belong to this session", session.Path)
+	}
+	if session.TotalChunks <= 0 {
+		return fmt.Errorf("invalid chunk count %d", session.TotalChunks)
+	}
+	if len(session.ReceivedMap) != session.TotalChunks {
+		return fmt.Errorf("received map has %d entries for %d chunks", len(session.ReceivedMap), session.TotalChunks)
+	}
+	return nil
+}
+
+// quarantine renames an unreadable session file aside so it is kept for
+// inspection instead of being retried or silently lost
+func quarantine(metaFile string, reason error) {
+	corruptFile := metaFile + ".corrupt"
+	if err := os.Rename(metaFile, corruptFile); err != nil {
+		fmt.Printf("Warning: session file %s is unusable (%v) and could not be moved aside: %v\n", metaFile, reason, err)
+		return
+	}
+	fmt.Printf("Warning: session file %s is unusable (%v); moved to %s\n", metaFile, reason, corruptFile)
 }
 
 // loadSessions loads all sessions from disk
@@ -226,6 +397,12 @@ func (s *SessionStore) loadSessions() error {
 	}
 
 	for _, file := range files {
+		// Leftovers from a write interrupted by a crash; the previous
+		// version of the session, if any, is still in its .json file
+		if filepath.Ext(file.Name()) == ".tmp" {
+			os.Remove(filepath.Join(s.metaDir, file.Name()))
+			continue
+		}
 		if filepath.Ext(file.Name()) != ".json" {
 			continue
 		}
@@ -241,7 +418,11 @@ func (s *SessionStore) loadSessions() error {
 
 		var session UploadSession
 		if err := json.Unmarshal(data, &session); err != nil {
-			fmt.Printf("Warning: failed to parse session file %s: %v\n", metaFile, err)
+			quarantine(metaFile, err)
+			continue
+		}
+		if err := session.validate(sessionID); err != nil {
+			quarantine(metaFile, err)
 			continue
 		}
 
@@ -0,0 +1,88 @@
+package resume
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSessionStore_PartialWriteKeepsPreviousSession(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := NewSessionStore(dir)
+	store.GetOrCreateSession("file.bin", "", "", 2, 10)
+	store.MarkChunkReceived("file.bin", 0)
+
+	// A crash while saving the next update leaves a truncated temp file
+	sessionID := makeSessionID("file.bin")
+	tmpFile := filepath.Join(dir, sessionID+".123.tmp")
+	os.WriteFile(tmpFile, []byte(`{"path": "file.bin", "total_ch`), 0644)
+
+	store, err := NewSessionStore(dir)
+	if err != nil {
+		t.Fatalf("NewSessionStore failed: %v", err)
+	}
+	session, ok := store.GetSession("file.bin")
+	if !ok {
+		t.Fatal("expected the last good session to survive")
+	}
+	if !session.ReceivedMap[0] || session.ReceivedMap[1] {
+		t.Errorf("unexpected received map: %v", session.ReceivedMap)
+	}
+	if _, err := os.Stat(tmpFile); !os.IsNotExist(err) {
+		t.Error("expected leftover temp file to be removed")
+	}
+}
+
+func TestSessionStore_SaveLeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := NewSessionStore(dir)
+	store.GetOrCreateSession("file.bin", "", "", 2, 10)
+	store.MarkChunkReceived("file.bin", 1)
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != makeSessionID("file.bin")+".json" {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("expected a single session file, got %v", names)
+	}
+}
+
+func TestSessionStore_CorruptSessionQuarantined(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := NewSessionStore(dir)
+	store.GetOrCreateSession("good.bin", "", "", 2, 10)
+
+	truncated := filepath.Join(dir, makeSessionID("truncated.bin")+".json")
+	os.WriteFile(truncated, []byte(`{"path": "truncated.bin", "total_chunks": 2, "rece`), 0644)
+
+	// Valid JSON that doesn't describe a usable session is rejected too
+	mismatched := filepath.Join(dir, makeSessionID("short.bin")+".json")
+	os.WriteFile(mismatched, []byte(`{"path": "short.bin", "total_chunks": 3, "received_map": [true]}`), 0644)
+
+	store, err := NewSessionStore(dir)
+	if err != nil {
+		t.Fatalf("NewSessionStore failed: %v", err)
+	}
+	if _, ok := store.GetSession("good.bin"); !ok {
+		t.Error("expected good session to load")
+	}
+
+	for _, file := range []string{truncated, mismatched} {
+		name := filepath.Base(file)
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("%s: expected corrupt file to be moved aside", name)
+		}
+		data, err := os.ReadFile(file + ".corrupt")
+		if err != nil {
+			t.Errorf("%s: expected quarantined copy: %v", name, err)
+		} else if !strings.Contains(string(data), `"path"`) {
+			t.Errorf("%s: quarantined copy lost its contents: %q", name, data)
+		}
+	}
+	if _, ok := store.GetSession("truncated.bin"); ok {
+		t.Error("expected corrupt session not to load")
+	}
+}
@@ -0,0 +1,212 @@
+package resume
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// backends opens each Store implementation in a fresh directory
+var backends = map[string]func(t *testing.T, dir string) Store{
+	"json": func(t *testing.T, dir string) Store {
+		store, err := NewSessionStore(dir)
+		if err != nil {
+			t.Fatalf("NewSessionStore failed: %v", err)
+		}
+		return store
+	},
+	"bolt": func(t *testing.T, dir string) Store {
+		store, err := NewBoltStore(filepath.Join(dir, "sessions.db"))
+		if err != nil {
+			t.Fatalf("NewBoltStore failed: %v", err)
+		}
+		return store
+	},
+}
+
+// forEachBackend runs test against every Store implementation
+func forEachBackend(t *testing.T, test func(t *testing.T, open func() Store)) {
+	for name, newStore := range backends {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			var opened []Store
+			t.Cleanup(func() {
+				for _, store := range opened {
+					store.Close()
+				}
+			})
+			test(t, func() Store {
+				// Close the previous store so it can be reopened, as after a restart
+				if n := len(opened); n > 0 {
+					opened[n-1].Close()
+				}
+				store := newStore(t, dir)
+				opened = append(opened, store)
+				return store
+			})
+		})
+	}
+}
+
+func TestStore_CreateAndMark(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() Store) {
+		store := open()
+
+		session, err := store.GetOrCreateSession("dir/file.bin", "user:alice", "", 3, 1024)
+		if err != nil {
+			t.Fatalf("GetOrCreateSession failed: %v", err)
+		}
+		if session.Path != "dir/file.bin" || session.Owner != "user:alice" || session.TotalChunks != 3 || session.ChunkSize != 1024 {
+			t.Errorf("unexpected session: %+v", session)
+		}
+
+		if _, err := store.GetOrCreateSession("dir/file.bin", "user:alice", "", 4, 1024); !errors.Is(err, ErrSessionMismatch) {
+			t.Errorf("expected chunk count mismatch to be rejected with ErrSessionMismatch, got %v", err)
+		}
+
+		// A file hash may be added later but not changed
+		if _, err := store.GetOrCreateSession("dir/file.bin", "user:alice", "abc", 3, 1024); err != nil {
+			t.Fatalf("adding file hash failed: %v", err)
+		}
+		if _, err := store.GetOrCreateSession("dir/file.bin", "user:alice", "def", 3, 1024); !errors.Is(err, ErrSessionMismatch) {
+			t.Errorf("expected file hash change to be rejected with ErrSessionMismatch, got %v", err)
+		}
+
+		for _, id := range []int{2, 0} {
+			if err := store.MarkChunkReceived("dir/file.bin", id); err != nil {
+				t.Fatalf("MarkChunkReceived(%d) failed: %v", id, err)
+			}
+		}
+		if err := store.MarkChunkReceived("dir/file.bin", 3); err == nil {
+			t.Error("expected out-of-range chunk to be rejected")
+		}
+		if err := store.MarkChunkReceived("other.bin", 0); err == nil {
+			t.Error("expected unknown session to be rejected")
+		}
+
+		missing, err := store.GetMissingChunks("dir/file.bin")
+		if err != nil || !reflect.DeepEqual(missing, []int{1}) {
+			t.Errorf("expected chunk 1 missing, got %v (%v)", missing, err)
+		}
+		if n := store.CountOpenSessions("user:alice"); n != 1 {
+			t.Errorf("expected 1 open session, got %d", n)
+		}
+
+		// Sessions survive a restart
+		store = open()
+		session, ok := store.GetSession("dir/file.bin")
+		if !ok {
+			t.Fatal("expected session to persist")
+		}
+		if session.FileHash != "abc" || !reflect.DeepEqual(session.ReceivedMap, []bool{true, false, true}) || session.Completed {
+			t.Errorf("unexpected session after reopen: %+v", session)
+		}
+
+		if err := store.MarkChunkReceived("dir/file.bin", 1); err != nil {
+			t.Fatalf("MarkChunkReceived failed: %v", err)
+		}
+		if session, _ := store.GetSession("dir/file.bin"); !session.Completed {
+			t.Error("expected session to be completed")
+		}
+		if n := store.CountOpenSessions("user:alice"); n != 0 {
+			t.Errorf("expected completed session not to count as open, got %d", n)
+		}
+		if missing, _ := store.GetMissingChunks("dir/file.bin"); len(missing) != 0 || missing == nil {
+			t.Errorf("expected empty missing list, got %#v", missing)
+		}
+
+		if err := store.DeleteSession("dir/file.bin"); err != nil {
+			t.Fatalf("DeleteSession failed: %v", err)
+		}
+		if _, ok := store.GetSession("dir/file.bin"); ok {
+			t.Error("expected session to be deleted")
+		}
+		if err := store.DeleteSession("dir/file.bin"); err != nil {
+			t.Errorf("deleting a missing session should succeed, got %v", err)
+		}
+		if _, err := store.GetMissingChunks("dir/file.bin"); err == nil {
+			t.Error("expected missing chunks of a deleted session to fail")
+		}
+	})
+}
+
+func TestStore_CountOpenSessionsByOwner(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() Store) {
+		store := open()
+		store.GetOrCreateSession("a.bin", "user:alice", "", 2, 10)
+		store.GetOrCreateSession("b.bin", "user:alice", "", 2, 10)
+		store.GetOrCreateSession("c.bin", "user:bob", "", 2, 10)
+
+		if n := store.CountOpenSessions("user:alice"); n != 2 {
+			t.Errorf("expected 2 sessions for alice, got %d", n)
+		}
+		if n := store.CountOpenSessions("user:bob"); n != 1 {
+			t.Errorf("expected 1 session for bob, got %d", n)
+		}
+		if n := store.CountOpenSessions("user:carol"); n != 0 {
+			t.Errorf("expected no sessions for carol, got %d", n)
+		}
+	})
+}
+
+func TestStore_CleanupOldSessions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() Store) {
+		store := open()
+		store.GetOrCreateSession("old.bin", "", "", 2, 10)
+		store.GetOrCreateSession("done.bin", "", "", 1, 10)
+		store.MarkChunkReceived("done.bin", 0)
+
+		time.Sleep(20 * time.Millisecond)
+		store.GetOrCreateSession("new.bin", "", "", 2, 10)
+
+		if err := store.CleanupOldSessions(10 * time.Millisecond); err != nil {
+			t.Fatalf("CleanupOldSessions failed: %v", err)
+		}
+
+		for path, want := range map[string]bool{"old.bin": false, "done.bin": true, "new.bin": true} {
+			if _, ok := store.GetSession(path); ok != want {
+				t.Errorf("%s: expected present=%v, got %v", path, want, ok)
+			}
+		}
+
+		// Cleanup is persisted
+		store = open()
+		if _, ok := store.GetSession("old.bin"); ok {
+			t.Error("expected cleaned up session to stay deleted after reopen")
+		}
+	})
+}
+
+func TestStore_MarkChunkMissing(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() Store) {
+		store := open()
+		store.GetOrCreateSession("a.bin", "", "", 2, 10)
+		store.GetOrCreateSession("b.bin", "", "", 1, 10)
+		store.MarkChunkReceived("a.bin", 0)
+		store.MarkChunkReceived("a.bin", 1)
+
+		if err := store.MarkChunkMissing("a.bin", 1); err != nil {
+			t.Fatalf("MarkChunkMissing failed: %v", err)
+		}
+		if err := store.MarkChunkMissing("a.bin", 2); err == nil {
+			t.Error("expected out-of-range chunk to be rejected")
+		}
+
+		// The change is persisted
+		store = open()
+		session, _ := store.GetSession("a.bin")
+		if session.Completed || !reflect.DeepEqual(session.ReceivedMap, []bool{true, false}) {
+			t.Errorf("unexpected session after marking chunk missing: %+v", session)
+		}
+
+		paths := map[string]bool{}
+		for _, session := range store.Sessions() {
+			paths[session.Path] = true
+		}
+		if !reflect.DeepEqual(paths, map[string]bool{"a.bin": true, "b.bin": true}) {
+			t.Errorf("unexpected sessions: %v", paths)
+		}
+	})
+}
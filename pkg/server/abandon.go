@@ -0,0 +1,211 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+const (
+	// DefaultAbandonedUploadGrace is how long an upload whose client
+	// disconnected mid-chunk is kept for the client to resume it
+	DefaultAbandonedUploadGrace = 10 * time.Minute
+	// DefaultSessionMaxAge is how long any unfinished upload is kept after
+	// its last chunk arrived
+	DefaultSessionMaxAge = 24 * time.Hour
+)
+
+// SetSessionCleanup sets how long unfinished uploads are kept. Uploads whose
+// client disconnected partway through a chunk are discarded abandonedGrace
+// after the disconnect unless another chunk arrives first; every other
+// unfinished upload is discarded maxAge after its last chunk. Zero disables
+// either.
+func (s *Server) SetSessionCleanup(maxAge, abandonedGrace time.Duration) {
+	s.sessionMaxAge = maxAge
+	s.abandonGrace = abandonedGrace
+}
+
+// markAbandoned records that the client uploading path went away partway
+// through a chunk. Paths are only known once enough of a request arrived,
+// and only uploads that already have a session leave anything behind.
+func (s *Server) markAbandoned(path string) {
+	if path == "" || s.abandonGrace <= 0 {
+		return
+	}
+	if _, exists := s.sessionStore.GetSession(path); !exists {
+		return
+	}
+	s.abandonedMu.Lock()
+	if s.abandoned == nil {
+		s.abandoned = make(map[string]time.Time)
+	}
+	s.abandoned[path] = time.Now()
+	s.abandonedMu.Unlock()
+}
+
+// clearAbandoned forgets a disconnect on path once its client resumes
+func (s *Server) clearAbandoned(path string) {
+	s.abandonedMu.Lock()
+	delete(s.abandoned, path)
+	s.abandonedMu.Unlock()
+}
+
+// abandonedSince returns when the client uploading path disconnected
+func (s *Server) abandonedSince(path string) (time.Time, bool) {
+	s.abandonedMu.Lock()
+	defer s.abandonedMu.Unlock()
+	at, ok := s.abandoned[path]
+	return at, ok
+}
+
+// discardSession deletes the chunks received for path and its session
+func (s *Server) discardSession(path string) {
+	os.RemoveAll(s.sessionChunksDir(path))
+	if err := s.sessionStore.DeleteSession(path); err != nil {
+		fmt.Printf("Warning: failed to delete session metadata: %v\n", err)
+	}
+	s.releaseQuota(path)
+	s.clearAbandoned(path)
+}
+
+// cleanupSessions discards uploads abandoned for longer than the grace
+// period and unfinished uploads idle for longer than the maximum age, as of
+// now, and returns how many it discarded
+func (s *Server) cleanupSessions(now time.Time) int {
+	discarded := 0
+
+	if s.abandonGrace > 0 {
+		s.abandonedMu.Lock()
+		var expired []string
+		for path, at := range s.abandoned {
+			if now.Sub(at) >= s.abandonGrace {
+				expired = append(expired, path)
+			}
+		}
+		s.abandonedMu.Unlock()
+
+		for _, path := range expired {
+			unlock := s.uploadLocks.lock(sessionKey(path))
+			// The client may have resumed since the paths were collected
+			if at, ok := s.abandonedSince(path); ok && now.Sub(at) >= s.abandonGrace {
+				if _, exists := s.sessionStore.GetSession(path); exists {
+					discarded++
+				}
+				s.discardSession(path)
+			}
+			unlock()
+		}
+	}
+
+	if s.sessionMaxAge > 0 {
+		for _, session := range s.sessionStore.Sessions() {
+			path := session.Path
+			unlock := s.uploadLocks.lock(sessionKey(path))
+			// Sessions only change under their upload lock, so it is read
+			// again. Completed sessions left waiting for /upload/finalize
+			// expire too.
+			if current, ok := s.sessionStore.GetSession(path); ok && now.Sub(current.LastModified) >= s.sessionMaxAge {
+				s.discardSession(path)
+				discarded++
+			}
+			unlock()
+		}
+	}
+
+	if discarded > 0 {
+		fmt.Printf("Discarded %d abandoned uploads\n", discarded)
+	}
+	return discarded
+}
+
+// cleanupInterval is how often abandoned uploads are looked for, often
+// enough that none outlives its grace period by much
+func (s *Server) cleanupInterval() time.Duration {
+	interval := time.Minute
+	if s.abandonGrace > 0 && s.abandonGrace/2 < interval {
+		interval = s.abandonGrace / 2
+	}
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	return interval
+}
+
+// runSessionCleanup discards abandoned uploads until stop is closed
+func (s *Server) runSessionCleanup(stop <-chan struct{}) {
+	if s.abandonGrace <= 0 && s.sessionMaxAge <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.cleanupInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			s.cleanupSessions(now)
+		}
+	}
+}
+
+// partialChunkPath returns the path of a JSON chunk whose body was cut off,
+// if it arrived before the connection dropped. Clients send the path first.
+func partialChunkPath(body []byte) string {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return ""
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		if key == "path" {
+			var path string
+			if err := dec.Decode(&path); err != nil {
+				return ""
+			}
+			return normalizePath(path)
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return ""
+		}
+	}
+	return ""
+}
+
+// handleUploadAbort discards an unfinished upload straight away, for clients
+// that give up on it rather than resuming later
+func (s *Server) handleUploadAbort(w http.ResponseWriter, r *http.Request) {
+	path := normalizePath(r.URL.Query().Get("path"))
+	if path == "" {
+		writeJSONError(w, errors.NewValidationError("path", "path required"))
+		return
+	}
+	if !s.allowPath(w, r, path) {
+		return
+	}
+
+	defer s.uploadLocks.lock(sessionKey(path))()
+
+	session, exists := s.sessionStore.GetSession(path)
+	if !exists {
+		writeJSONError(w, errors.NewStorageError(errors.StorageErrorNotFound, path, "no unfinished upload"))
+		return
+	}
+	if session.Owner != "" && session.Owner != s.sessionOwner(r) {
+		writeJSONError(w, errors.NewAuthError(errors.AuthErrorInsufficientPermissions,
+			fmt.Sprintf("the upload of %s was started by someone else", path)))
+		return
+	}
+
+	s.discardSession(path)
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "upload of %s aborted", path)
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// startUpload sends the first of two chunks of path so it has a session and
+// a chunk directory
+func startUpload(t *testing.T, srv *Server, path string) {
+	t.Helper()
+	rec := postChunk(t, srv, transport.ChunkData{Path: path, ChunkID: 0, Data: []byte("aaaa"), Total: 2})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// uploadLeft reports whether anything of the upload of path remains
+func uploadLeft(srv *Server, path string) bool {
+	_, exists := srv.sessionStore.GetSession(path)
+	_, err := os.Stat(srv.sessionChunksDir(path))
+	return exists || err == nil
+}
+
+func TestAbandonedUpload_CutOffBody(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetSessionCleanup(0, time.Minute)
+	startUpload(t, srv, "cut.bin")
+
+	// The client disconnects partway through the second chunk
+	body, _ := json.Marshal(transport.ChunkData{Path: "cut.bin", ChunkID: 1, Data: []byte("bbbb"), Total: 2})
+	req := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(body[:len(body)/2]))
+	req.ContentLength = int64(len(body))
+	rec := httptest.NewRecorder()
+	srv.handleUpload(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a cut-off body, got %d", rec.Code)
+	}
+
+	// The client may still come back within the grace period
+	if n := srv.cleanupSessions(time.Now()); n != 0 || !uploadLeft(srv, "cut.bin") {
+		t.Fatalf("expected the upload kept within the grace period, discarded %d", n)
+	}
+
+	if n := srv.cleanupSessions(time.Now().Add(time.Minute)); n != 1 {
+		t.Errorf("expected 1 upload discarded, got %d", n)
+	}
+	if uploadLeft(srv, "cut.bin") {
+		t.Error("expected the session and chunks removed after the grace period")
+	}
+}
+
+func TestAbandonedUpload_CancelledRequest(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetSessionCleanup(0, time.Minute)
+	startUpload(t, srv, "gone.bin")
+
+	// A client that went away before its chunk was answered
+	body, _ := json.Marshal(transport.ChunkData{Path: "gone.bin", ChunkID: 0, Data: []byte("aaaa"), Total: 2, Replace: true})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(body)).WithContext(ctx)
+	srv.handleUpload(httptest.NewRecorder(), req)
+
+	srv.cleanupSessions(time.Now().Add(time.Minute))
+	if uploadLeft(srv, "gone.bin") {
+		t.Error("expected the session and chunks removed after the grace period")
+	}
+}
+
+func TestAbandonedUpload_ResumeWithinGrace(t *testing.T) {
+	srv, store := newTestServer(t)
+	srv.SetSessionCleanup(0, time.Minute)
+	startUpload(t, srv, "back.bin")
+	srv.markAbandoned("back.bin")
+
+	// The client reconnects and sends another chunk before the grace runs out
+	rec := postChunk(t, srv, transport.ChunkData{Path: "back.bin", ChunkID: 0, Data: []byte("aaaa"), Total: 2, Replace: true})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if n := srv.cleanupSessions(time.Now().Add(time.Hour)); n != 0 || !uploadLeft(srv, "back.bin") {
+		t.Fatalf("expected a resumed upload to be kept, discarded %d", n)
+	}
+
+	rec = postChunk(t, srv, transport.ChunkData{Path: "back.bin", ChunkID: 1, Data: []byte("bb"), Total: 2})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if data, err := store.Get("back.bin"); err != nil || string(data) != "aaaabb" {
+		t.Errorf("expected the resumed upload stored, got %q (%v)", data, err)
+	}
+}
+
+func TestAbandonedUpload_ServerCleansUpWithinGrace(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetSessionCleanup(0, 100*time.Millisecond)
+	startUpload(t, srv, "dropped.bin")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go srv.Serve(ln)
+	defer srv.Shutdown(context.Background())
+
+	// Drop the connection partway through the second chunk
+	body, _ := json.Marshal(transport.ChunkData{Path: "dropped.bin", ChunkID: 1, Data: bytes.Repeat([]byte("b"), 1024), Total: 2})
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	fmt.Fprintf(conn, "POST /upload HTTP/1.1\r\nHost: test\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n", len(body))
+	conn.Write(body[:len(body)/2])
+	conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for uploadLeft(srv, "dropped.bin") {
+		if time.Now().After(deadline) {
+			t.Fatal("abandoned upload was never cleaned up")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestCleanupSessions_MaxAge(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetSessionCleanup(time.Hour, 0)
+	startUpload(t, srv, "idle.bin")
+
+	if n := srv.cleanupSessions(time.Now()); n != 0 {
+		t.Fatalf("expected a recent upload kept, discarded %d", n)
+	}
+	if n := srv.cleanupSessions(time.Now().Add(2 * time.Hour)); n != 1 || uploadLeft(srv, "idle.bin") {
+		t.Errorf("expected the idle upload discarded, discarded %d", n)
+	}
+}
+
+func TestHandleUploadAbort(t *testing.T) {
+	srv, _ := newTestServer(t)
+	enableTestAuth(t, srv, "alice", "bob")
+
+	rec := postChunkAs(srv, "alice", transport.ChunkData{Path: "mine.bin", ChunkID: 0, Data: []byte("aaaa"), Total: 2})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	abort := func(user string) int {
+		req := httptest.NewRequest(http.MethodPost, "/upload/abort?path=mine.bin", nil)
+		req.Header.Set("Authorization", "Bearer "+user)
+		rec := httptest.NewRecorder()
+		srv.authMiddle.RequireAuth("upload", srv.handleUploadAbort)(rec, req)
+		return rec.Code
+	}
+
+	if code := abort("bob"); code != http.StatusForbidden {
+		t.Errorf("expected 403 aborting another user's upload, got %d", code)
+	}
+	if !uploadLeft(srv, "mine.bin") {
+		t.Fatal("expected the upload kept after a refused abort")
+	}
+
+	if code := abort("alice"); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if uploadLeft(srv, "mine.bin") {
+		t.Error("expected the session and chunks removed by the abort")
+	}
+	if code := abort("alice"); code != http.StatusNotFound {
+		t.Errorf("expected 404 once nothing is left to abort, got %d", code)
+	}
+}
+
+func TestPartialChunkPath(t *testing.T) {
+	body, _ := json.Marshal(transport.ChunkData{Path: "dir\\file.bin", ChunkID: 3, Data: []byte("data"), Total: 4})
+
+	tests := []struct {
+		body []byte
+		want string
+	}{
+		{body, "dir/file.bin"},
+		{body[:len(body)/2], "dir/file.bin"},
+		{body[:5], ""},
+		{[]byte(`{"chunk_id":1,"path":"late.bin","data":"`), "late.bin"},
+		{[]byte(`not json`), ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := partialChunkPath(tt.body); got != tt.want {
+			t.Errorf("partialChunkPath(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
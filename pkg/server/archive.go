@@ -0,0 +1,206 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/audit"
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
+)
+
+// Archive formats served by /download/archive
+const (
+	ArchiveZip   = "zip"
+	ArchiveTarGz = "tar.gz"
+)
+
+// archiveBlockSize is how much of a file is read at a time while archiving,
+// on backends that can read part of a file
+const archiveBlockSize = 1 << 20
+
+// archiveEntry is a file to be written to an archive
+type archiveEntry struct {
+	path    string // full storage path
+	name    string // slash-separated path inside the archive
+	size    int64
+	modTime time.Time
+}
+
+// handleDownloadArchive streams a directory's files as a zip or tar.gz
+// archive, named relative to the directory. Entries are written straight to
+// the response one file at a time, so the archive is never held in memory.
+// The files' total size is limited like an upload's.
+func (s *Server) handleDownloadArchive(w http.ResponseWriter, r *http.Request) {
+	dir := normalizePath(r.URL.Query().Get("path"))
+	if dir == "" {
+		writeJSONError(w, errors.NewValidationError("path", "path parameter required"))
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = ArchiveZip
+	}
+	if format != ArchiveZip && format != ArchiveTarGz {
+		errors.WriteJSON(w, http.StatusBadRequest, fmt.Errorf("unsupported archive format %q: use %s or %s", format, ArchiveZip, ArchiveTarGz))
+		return
+	}
+	if !s.allowPath(w, r, dir) {
+		return
+	}
+
+	walker, ok := s.storage.(storage.Walker)
+	if !ok {
+		errors.WriteJSON(w, http.StatusNotImplemented, stderrors.New("archive failed: storage backend cannot walk directories"))
+		return
+	}
+	info, err := storage.Stat(s.storage, dir)
+	if err != nil {
+		s.audit(r, audit.ActionDownload, dir, audit.ResultFailed, err)
+		writeJSONError(w, fmt.Errorf("archive failed: %w", err))
+		return
+	}
+	if !info.IsDir {
+		errors.WriteJSON(w, http.StatusBadRequest, fmt.Errorf("%s is not a directory; download it with /download", dir))
+		return
+	}
+
+	// Everything is sized up before the first byte is sent, while the
+	// request can still be refused with a proper status
+	entries, total, err := s.archiveEntries(walker, dir)
+	if err != nil {
+		s.audit(r, audit.ActionDownload, dir, audit.ResultFailed, err)
+		writeJSONError(w, fmt.Errorf("archive failed: %w", err))
+		return
+	}
+	if s.maxFileSize > 0 && total > s.maxFileSize {
+		errors.WriteJSON(w, http.StatusRequestEntityTooLarge, fmt.Errorf("archive is too large: %d bytes, limit %d", total, s.maxFileSize))
+		return
+	}
+
+	name := path.Base(dir) + "." + format
+	if format == ArchiveZip {
+		w.Header().Set("Content-Type", "application/zip")
+	} else {
+		w.Header().Set("Content-Type", "application/gzip")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+
+	if format == ArchiveZip {
+		err = s.writeZip(w, entries)
+	} else {
+		err = s.writeTarGz(w, entries)
+	}
+	if err != nil {
+		// The status has been sent, so all that can be done is to stop;
+		// the client sees a truncated archive
+		s.audit(r, audit.ActionDownload, dir, audit.ResultFailed, err)
+		return
+	}
+	s.audit(r, audit.ActionDownload, dir, audit.ResultOK, nil)
+}
+
+// archiveEntries lists the files beneath dir for an archive, with their
+// total size
+func (s *Server) archiveEntries(walker storage.Walker, dir string) ([]archiveEntry, int64, error) {
+	var entries []archiveEntry
+	var total int64
+	err := walker.Walk(dir, func(p string) error {
+		info, err := storage.Stat(s.storage, p)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, archiveEntry{
+			path:    p,
+			name:    strings.TrimPrefix(p, strings.Trim(dir, "/")+"/"),
+			size:    info.Size,
+			modTime: info.ModTime,
+		})
+		total += info.Size
+		return nil
+	})
+	return entries, total, err
+}
+
+// writeZip writes entries to w as a zip archive
+func (s *Server) writeZip(w io.Writer, entries []archiveEntry) error {
+	zw := zip.NewWriter(w)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate, Modified: entry.modTime}
+		header.SetMode(0644)
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if err := s.copyFile(fw, entry); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeTarGz writes entries to w as a gzip-compressed tar archive
+func (s *Server) writeTarGz(w io.Writer, entries []archiveEntry) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     entry.name,
+			Size:     entry.size,
+			Mode:     0644,
+			ModTime:  entry.modTime,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if err := s.copyFile(tw, entry); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// copyFile writes one file's content to an archive, a block at a time on
+// backends that can read part of a file. A file whose size changed since it
+// was listed is reported, since tar headers have already promised its size.
+func (s *Server) copyFile(w io.Writer, entry archiveEntry) error {
+	rangeGetter, ok := s.storage.(storage.RangeGetter)
+	if !ok {
+		data, err := s.storage.Get(entry.path)
+		if err != nil {
+			return err
+		}
+		if int64(len(data)) != entry.size {
+			return fmt.Errorf("%s changed while it was being archived", entry.path)
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	for offset := int64(0); offset < entry.size; {
+		block, err := rangeGetter.GetRange(entry.path, offset, min(archiveBlockSize, entry.size-offset))
+		if err != nil {
+			return err
+		}
+		if len(block) == 0 {
+			return fmt.Errorf("%s changed while it was being archived", entry.path)
+		}
+		if _, err := w.Write(block); err != nil {
+			return err
+		}
+		offset += int64(len(block))
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/auth"
+	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
+)
+
+// archiveTree is the directory tree the archive tests download
+var archiveTree = map[string]string{
+	"index.html":         "<html>",
+	"css/main.css":       "body {}",
+	"img/icons/logo.svg": strings.Repeat("<svg/>", 1000),
+	"empty.txt":          "",
+}
+
+// newArchiveServer returns a test server holding archiveTree under site/
+func newArchiveServer(t *testing.T) (*Server, *storage.Local) {
+	t.Helper()
+	srv, store := newTestServer(t)
+	for name, content := range archiveTree {
+		if err := store.Put("site/"+name, []byte(content)); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+	store.Put("other/skip.txt", []byte("not part of the tree"))
+	return srv, store
+}
+
+// downloadArchive calls the archive handler with the given query
+func downloadArchive(srv *Server, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/download/archive?"+query, nil)
+	rec := httptest.NewRecorder()
+	srv.handleDownloadArchive(rec, req)
+	return rec
+}
+
+func TestHandleDownloadArchive_Zip(t *testing.T) {
+	srv, _ := newArchiveServer(t)
+
+	rec := downloadArchive(srv, "path=site")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("expected application/zip, got %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, `"site.zip"`) {
+		t.Errorf("expected site.zip as the file name, got %q", cd)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("failed to open zip: %v", err)
+	}
+	got := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		got[f.Name] = string(data)
+	}
+	if !reflect.DeepEqual(got, archiveTree) {
+		t.Errorf("expected the archive to match the tree, got %v", got)
+	}
+}
+
+func TestHandleDownloadArchive_TarGz(t *testing.T) {
+	srv, _ := newArchiveServer(t)
+
+	rec := downloadArchive(srv, "path=/site/&format=tar.gz")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	gr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("failed to open gzip: %v", err)
+	}
+	tr := tar.NewReader(gr)
+	got := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read tar: %v", err)
+		}
+		data, _ := io.ReadAll(tr)
+		got[header.Name] = string(data)
+	}
+	if !reflect.DeepEqual(got, archiveTree) {
+		t.Errorf("expected the archive to match the tree, got %v", got)
+	}
+}
+
+func TestHandleDownloadArchive_MaxFileSize(t *testing.T) {
+	srv, _ := newArchiveServer(t)
+	srv.SetMaxFileSize(100)
+
+	// Every file is under the limit, but not all of them together
+	rec := downloadArchive(srv, "path=site")
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := downloadArchive(srv, "path=site/css"); rec.Code != http.StatusOK {
+		t.Errorf("expected a smaller directory still served, got %d", rec.Code)
+	}
+}
+
+func TestHandleDownloadArchive_Errors(t *testing.T) {
+	srv, _ := newArchiveServer(t)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", http.StatusBadRequest},
+		{"path=site&format=rar", http.StatusBadRequest},
+		{"path=site/index.html", http.StatusBadRequest},
+		{"path=missing", http.StatusNotFound},
+		{"path=../etc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := downloadArchive(srv, tt.query); rec.Code != tt.want {
+			t.Errorf("%q: expected %d, got %d: %s", tt.query, tt.want, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestHandleDownloadArchive_PathScope(t *testing.T) {
+	srv, _ := newArchiveServer(t)
+
+	tokenStore, err := auth.NewTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+	token, _, err := tokenStore.Create("alice", []string{"download"}, time.Hour, "site/css")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	srv.EnableAuth(tokenStore)
+
+	call := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, "/download/archive?path="+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		srv.authMiddle.RequireAuth("download", srv.handleDownloadArchive)(rec, req)
+		return rec.Code
+	}
+	if code := call("site"); code != http.StatusForbidden {
+		t.Errorf("expected 403 archiving a directory containing the scope, got %d", code)
+	}
+	if code := call("site/css"); code != http.StatusOK {
+		t.Errorf("expected 200 archiving the scope, got %d", code)
+	}
+}
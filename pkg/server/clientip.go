@@ -0,0 +1,109 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// DefaultProxyHeaders are the headers a trusted proxy is believed to report
+// the client's address in, in order of preference
+var DefaultProxyHeaders = []string{"X-Forwarded-For", "X-Real-IP"}
+
+// SetTrustedProxies resolves client addresses from proxy headers on requests
+// whose immediate peer is one of the given proxies, each an IP address or a
+// CIDR range such as 10.0.0.0/8. Headers are consulted in order; nil uses
+// DefaultProxyHeaders. Requests from any other peer are attributed to the
+// peer itself, so clients can't spoof their address by sending the headers.
+func (s *Server) SetTrustedProxies(proxies []string, headers []string) error {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		ipNet, err := parseProxy(proxy)
+		if err != nil {
+			return err
+		}
+		nets = append(nets, ipNet)
+	}
+	if headers == nil {
+		headers = DefaultProxyHeaders
+	}
+	s.trustedProxies = nets
+	s.proxyHeaders = headers
+	return nil
+}
+
+// parseProxy parses a trusted proxy given as a CIDR range or a single address
+func parseProxy(proxy string) (*net.IPNet, error) {
+	proxy = strings.TrimSpace(proxy)
+	if strings.Contains(proxy, "/") {
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		return ipNet, nil
+	}
+	ip := net.ParseIP(proxy)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid trusted proxy %q: not an IP address or CIDR range", proxy)
+	}
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// isTrustedProxy reports whether ip belongs to a configured trusted proxy
+func (s *Server) isTrustedProxy(ip net.IP) bool {
+	for _, ipNet := range s.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client that made r. When the peer is
+// a trusted proxy, the first configured header naming a valid address
+// supplies it; X-Forwarded-For is read from the right, skipping any further
+// trusted proxies the request passed through.
+func (s *Server) clientIP(r *http.Request) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	peerIP := net.ParseIP(peer)
+	if peerIP == nil || !s.isTrustedProxy(peerIP) {
+		return peer
+	}
+
+	for _, header := range s.proxyHeaders {
+		values := r.Header.Values(header)
+		if len(values) == 0 {
+			continue
+		}
+		if ip := s.forwardedIP(strings.Split(strings.Join(values, ","), ",")); ip != nil {
+			return ip.String()
+		}
+	}
+	return peer
+}
+
+// forwardedIP picks the client from a list of addresses each proxy appended
+// to: the rightmost one that isn't a trusted proxy, or the leftmost if they
+// all are. Entries to the left of an untrusted one may have been forged by
+// the client and are never used.
+func (s *Server) forwardedIP(hops []string) net.IP {
+	var ip net.IP
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip = net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			return nil
+		}
+		if !s.isTrustedProxy(ip) {
+			return ip
+		}
+	}
+	return ip
+}
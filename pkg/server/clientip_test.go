@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// proxiedRequest returns a request from peer carrying the given headers
+func proxiedRequest(peer string, headers map[string]string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/list", nil)
+	r.RemoteAddr = peer
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+	return r
+}
+
+func TestClientIP(t *testing.T) {
+	srv, _ := newTestServer(t)
+	if err := srv.SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.5"}, nil); err != nil {
+		t.Fatalf("SetTrustedProxies failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		peer    string
+		headers map[string]string
+		want    string
+	}{
+		{"direct client", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:5000", map[string]string{"X-Forwarded-For": "203.0.113.7"}, "203.0.113.7"},
+		{"trusted single address", "192.168.1.5:5000", map[string]string{"X-Real-IP": "203.0.113.7"}, "203.0.113.7"},
+		{"untrusted peer", "203.0.113.9:5000", map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"}, "203.0.113.9"},
+		{"chained proxies", "10.1.2.3:5000", map[string]string{"X-Forwarded-For": "203.0.113.7, 10.4.4.4"}, "203.0.113.7"},
+		{"forged entry", "10.1.2.3:5000", map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7"}, "203.0.113.7"},
+		{"forwarded for preferred", "10.1.2.3:5000", map[string]string{"X-Forwarded-For": "203.0.113.7", "X-Real-IP": "198.51.100.2"}, "203.0.113.7"},
+		{"invalid forwarded for", "10.1.2.3:5000", map[string]string{"X-Forwarded-For": "unknown", "X-Real-IP": "203.0.113.7"}, "203.0.113.7"},
+		{"trusted proxy without headers", "10.1.2.3:5000", nil, "10.1.2.3"},
+		{"ipv6 client", "10.1.2.3:5000", map[string]string{"X-Forwarded-For": "2001:db8::1"}, "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		if got := srv.clientIP(proxiedRequest(tt.peer, tt.headers)); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestClientIP_NoTrustedProxies(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	r := proxiedRequest("10.1.2.3:5000", map[string]string{"X-Forwarded-For": "203.0.113.7"})
+	if got := srv.clientIP(r); got != "10.1.2.3" {
+		t.Errorf("expected forwarded headers to be ignored by default, got %s", got)
+	}
+}
+
+func TestClientIP_CustomHeaders(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetTrustedProxies([]string{"10.0.0.0/8"}, []string{"CF-Connecting-IP"})
+
+	r := proxiedRequest("10.1.2.3:5000", map[string]string{"X-Forwarded-For": "198.51.100.1", "CF-Connecting-IP": "203.0.113.7"})
+	if got := srv.clientIP(r); got != "203.0.113.7" {
+		t.Errorf("expected the configured header to be used, got %s", got)
+	}
+
+	r = proxiedRequest("10.1.2.3:5000", map[string]string{"X-Forwarded-For": "198.51.100.1"})
+	if got := srv.clientIP(r); got != "10.1.2.3" {
+		t.Errorf("expected headers that aren't configured to be ignored, got %s", got)
+	}
+}
+
+func TestSetTrustedProxies_Invalid(t *testing.T) {
+	srv, _ := newTestServer(t)
+	for _, proxy := range []string{"10.0.0.0/33", "proxy.local", ""} {
+		if err := srv.SetTrustedProxies([]string{proxy}, nil); err == nil {
+			t.Errorf("expected %q to be rejected", proxy)
+		}
+	}
+}
+
+func TestSessionOwner_BehindProxy(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetTrustedProxies([]string{"10.0.0.1"}, nil)
+
+	a := proxiedRequest("10.0.0.1:5000", map[string]string{"X-Forwarded-For": "203.0.113.7"})
+	b := proxiedRequest("10.0.0.1:5001", map[string]string{"X-Forwarded-For": "203.0.113.8"})
+	if srv.sessionOwner(a) == srv.sessionOwner(b) {
+		t.Errorf("expected clients behind the proxy to own separate sessions, both got %s", srv.sessionOwner(a))
+	}
+	if got := srv.sessionOwner(a); got != "addr:203.0.113.7" {
+		t.Errorf("expected addr:203.0.113.7, got %s", got)
+	}
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
+)
+
+// CompletionAction moves a file to a final path once its upload has been
+// reassembled, e.g. from incoming/ to ready/ so that consumers only ever see
+// complete files.
+//
+// Pattern is matched against the uploaded path with path.Match; a pattern
+// ending in "/**" matches everything beneath that directory. Target is the
+// final path, in which these placeholders are replaced:
+//
+//	{path}  the uploaded path, e.g. incoming/2024/report.csv
+//	{dir}   its directory, e.g. incoming/2024
+//	{name}  its base name, e.g. report.csv
+//	{stem}  the base name without its extension, e.g. report
+//	{ext}   the extension including the dot, e.g. .csv
+//	{rel}   the path below the pattern's fixed leading directories, e.g.
+//	        2024/report.csv for the pattern incoming/**
+type CompletionAction struct {
+	Pattern string
+	Target  string
+}
+
+// placeholderPattern finds {placeholders} in a completion target
+var placeholderPattern = regexp.MustCompile(`\{[a-z]*\}`)
+
+// completionPlaceholders are the placeholders a target may use
+var completionPlaceholders = map[string]bool{
+	"{path}": true, "{dir}": true, "{name}": true, "{stem}": true, "{ext}": true, "{rel}": true,
+}
+
+// SetCompletionActions moves completed uploads as the actions describe. The
+// first action whose pattern matches a file's path is applied; files no
+// action matches stay where they were uploaded.
+func (s *Server) SetCompletionActions(actions []CompletionAction) error {
+	for i, action := range actions {
+		if err := action.validate(); err != nil {
+			return fmt.Errorf("completion action %d: %w", i, err)
+		}
+	}
+	s.completions = actions
+	return nil
+}
+
+// validate rejects actions that could never be applied
+func (a CompletionAction) validate() error {
+	if a.Pattern == "" {
+		return fmt.Errorf("pattern is required")
+	}
+	if _, err := path.Match(strings.TrimSuffix(a.Pattern, "/**"), ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", a.Pattern, err)
+	}
+	if a.Target == "" {
+		return fmt.Errorf("target is required")
+	}
+	for _, placeholder := range placeholderPattern.FindAllString(a.Target, -1) {
+		if !completionPlaceholders[placeholder] {
+			return fmt.Errorf("unknown placeholder %s in target %q", placeholder, a.Target)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the action applies to the uploaded path p
+func (a CompletionAction) matches(p string) bool {
+	if dir, ok := strings.CutSuffix(a.Pattern, "/**"); ok {
+		prefix := strings.Split(p, "/")
+		depth := len(strings.Split(dir, "/"))
+		if len(prefix) <= depth {
+			return false
+		}
+		matched, _ := path.Match(dir, strings.Join(prefix[:depth], "/"))
+		return matched
+	}
+	matched, _ := path.Match(a.Pattern, p)
+	return matched
+}
+
+// finalPath renders the action's target for the uploaded path p. It fails if
+// the result would be empty or climb out of the storage root.
+func (a CompletionAction) finalPath(p string) (string, error) {
+	name := path.Base(p)
+	ext := path.Ext(name)
+	dir := path.Dir(p)
+	if dir == "." {
+		dir = ""
+	}
+
+	// {rel} is relative to the leading pattern segments without wildcards
+	segments := strings.Split(p, "/")
+	fixed := 0
+	for _, segment := range strings.Split(a.Pattern, "/") {
+		if fixed >= len(segments)-1 || strings.ContainsAny(segment, "*?[") {
+			break
+		}
+		fixed++
+	}
+
+	replacer := strings.NewReplacer(
+		"{path}", p,
+		"{dir}", dir,
+		"{name}", name,
+		"{stem}", strings.TrimSuffix(name, ext),
+		"{ext}", ext,
+		"{rel}", strings.Join(segments[fixed:], "/"),
+	)
+	target := normalizePath(replacer.Replace(a.Target))
+
+	for _, segment := range strings.Split(target, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("target %q escapes the storage root", target)
+		}
+	}
+	target = strings.Trim(path.Clean("/"+target), "/")
+	if target == "" {
+		return "", fmt.Errorf("target for %s is empty", p)
+	}
+	return target, nil
+}
+
+// completeUpload applies the first completion action matching the uploaded
+// path p, returning where the file ended up. Files no action matches, and
+// files whose move fails, stay at p.
+func (s *Server) completeUpload(p string) (string, error) {
+	for _, action := range s.completions {
+		if !action.matches(p) {
+			continue
+		}
+
+		dst, err := action.finalPath(p)
+		if err != nil {
+			return p, err
+		}
+		if dst == p {
+			return p, nil
+		}
+		if inTree(dst, StagingDir) {
+			return p, fmt.Errorf("cannot move %s into %s", p, StagingDir)
+		}
+
+		renamer, ok := s.storage.(storage.Renamer)
+		if !ok {
+			return p, fmt.Errorf("storage backend cannot rename")
+		}
+		if err := renamer.Rename(p, dst); err != nil {
+			return p, fmt.Errorf("failed to move %s to %s: %w", p, dst, err)
+		}
+		s.hashes.rename(p, dst)
+		return dst, nil
+	}
+	return p, nil
+}
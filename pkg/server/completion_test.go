@@ -0,0 +1,105 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+func TestCompletionAction_MovesCompletedUpload(t *testing.T) {
+	srv, store := newTestServer(t)
+	err := srv.SetCompletionActions([]CompletionAction{
+		{Pattern: "incoming/*.tmp", Target: "trash/{name}"},
+		{Pattern: "incoming/**", Target: "ready/{rel}"},
+	})
+	if err != nil {
+		t.Fatalf("SetCompletionActions failed: %v", err)
+	}
+
+	// The file stays out of ready/ until its last chunk arrives
+	postChunk(t, srv, transport.ChunkData{Path: "incoming/2024/report.csv", ChunkID: 0, Data: []byte("a,b\n"), Total: 2})
+	if store.Exists("ready/2024/report.csv") {
+		t.Fatal("expected an incomplete upload not to be moved")
+	}
+	postChunk(t, srv, transport.ChunkData{Path: "incoming/2024/report.csv", ChunkID: 1, Data: []byte("1,2\n"), Total: 2})
+
+	if data, err := store.Get("ready/2024/report.csv"); err != nil || string(data) != "a,b\n1,2\n" {
+		t.Errorf("expected the file at ready/2024/report.csv, got %q (%v)", data, err)
+	}
+	if store.Exists("incoming/2024/report.csv") {
+		t.Error("expected the file to be moved out of incoming/")
+	}
+
+	// The first matching action wins
+	postChunk(t, srv, transport.ChunkData{Path: "incoming/scratch.tmp", ChunkID: 0, Data: []byte("x"), Total: 1})
+	if !store.Exists("trash/scratch.tmp") || store.Exists("ready/scratch.tmp") {
+		t.Error("expected scratch.tmp to be moved by the first matching action")
+	}
+
+	// Uploads outside the watched prefix stay where they are
+	postChunk(t, srv, transport.ChunkData{Path: "other/notes.txt", ChunkID: 0, Data: []byte("x"), Total: 1})
+	if !store.Exists("other/notes.txt") {
+		t.Error("expected an unmatched upload to stay in place")
+	}
+}
+
+func TestCompletionAction_ExistingTargetKeepsUpload(t *testing.T) {
+	srv, store := newTestServer(t)
+	srv.SetCompletionActions([]CompletionAction{{Pattern: "incoming/*", Target: "ready/{name}"}})
+	store.Put("ready/data.bin", []byte("old"))
+
+	postChunk(t, srv, transport.ChunkData{Path: "incoming/data.bin", ChunkID: 0, Data: []byte("new"), Total: 1})
+
+	if data, _ := store.Get("ready/data.bin"); string(data) != "old" {
+		t.Errorf("expected the existing file untouched, got %q", data)
+	}
+	if data, _ := store.Get("incoming/data.bin"); string(data) != "new" {
+		t.Errorf("expected the upload to stay at its upload path, got %q", data)
+	}
+}
+
+func TestCompletionAction_FinalPath(t *testing.T) {
+	tests := []struct {
+		pattern, target, path string
+		want                  string
+		wantErr               bool
+	}{
+		{"incoming/**", "ready/{rel}", "incoming/a/b.csv", "ready/a/b.csv", false},
+		{"incoming/*.csv", "ready/{stem}-done{ext}", "incoming/x.csv", "ready/x-done.csv", false},
+		{"*/upload/*", "{dir}/../done/{name}", "team/upload/f.txt", "", true},
+		{"in/*", "../{name}", "in/f.txt", "", true},
+		{"in/*", "/abs/{name}", "in/f.txt", "abs/f.txt", false},
+		{"in/*", "{path}.processed", "in/f.txt", "in/f.txt.processed", false},
+	}
+
+	for _, tt := range tests {
+		action := CompletionAction{Pattern: tt.pattern, Target: tt.target}
+		if !action.matches(tt.path) {
+			t.Errorf("expected %q to match %q", tt.pattern, tt.path)
+			continue
+		}
+		got, err := action.finalPath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("finalPath(%q, %q): unexpected error %v", tt.target, tt.path, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("finalPath(%q, %q) = %q, want %q", tt.target, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSetCompletionActions_Invalid(t *testing.T) {
+	srv, _ := newTestServer(t)
+	invalid := []CompletionAction{
+		{Pattern: "", Target: "ready/{name}"},
+		{Pattern: "in/[", Target: "ready/{name}"},
+		{Pattern: "in/*", Target: ""},
+		{Pattern: "in/*", Target: "ready/{nmae}"},
+	}
+	for _, action := range invalid {
+		if err := srv.SetCompletionActions([]CompletionAction{action}); err == nil {
+			t.Errorf("expected error for %+v", action)
+		}
+	}
+}
@@ -1,95 +1,239 @@
 package server
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/mdns"
 )
 
 // DiscoveryInfo represents server information broadcast on the network
 type DiscoveryInfo struct {
 	Name        string `json:"name"`
+	InstanceID  string `json:"instance_id"`
+	Scheme      string `json:"scheme"`
 	Version     string `json:"version"`
 	Address     string `json:"address"`
 	Port        string `json:"port"`
 	AuthEnabled bool   `json:"auth_enabled"`
 	Timestamp   int64  `json:"timestamp"`
+
+	DiscoveryPort int `json:"discovery_port"` // UDP port the announcements are sent from
 }
 
-// DiscoveryService handles UDP broadcast announcements
+// DiscoveryService announces the server over UDP broadcast, mDNS or both
 type DiscoveryService struct {
 	info     DiscoveryInfo
-	conn     *net.UDPConn
+	conn     *net.UDPConn // nil if not broadcasting
+	port     int
 	stopChan chan struct{}
+	stopOnce sync.Once
+
+	responder *mdns.Responder // nil if not answering mDNS queries
+	secret    string          // shared secret announcements are signed with (empty for unsigned)
+
+	infoMu sync.Mutex // guards info's timestamp, set by both broadcasts and probe replies
+}
+
+// DiscoveryOptions controls how the discovery service identifies itself and
+// binds its UDP port
+type DiscoveryOptions struct {
+	Name         string // announced server name (empty for DefaultServerName)
+	InstanceID   string // stable identifier distinguishing this server from others
+	Scheme       string // URL scheme clients should use (empty for "http")
+	Port         int    // preferred UDP port (0 for DiscoveryPort)
+	PortAttempts int    // consecutive ports to try while the port is in use (0 for DefaultDiscoveryPortAttempts)
+	Required     bool   // fail instead of running without discovery when no port can be bound
+	Mode         string // DiscoveryModeBroadcast (default), DiscoveryModeMDNS or DiscoveryModeBoth
+	Secret       string // shared network secret to sign announcements with (empty for unsigned)
+	Address      string // host:port announced to clients (empty for the listen address)
 }
 
 const (
 	DiscoveryPort     = 8081
 	BroadcastInterval = 30 * time.Second
 	DiscoveryMagic    = "GOFLUX-LITE-DISCOVERY"
+	ProbeMagic        = "GOFLUX-LITE-PROBE" // clients asking servers to announce themselves now
+
+	StartupBroadcasts        = 3           // broadcasts sent when the service starts
+	StartupBroadcastInterval = time.Second // between the startup broadcasts
+
+	ProbeReplyInterval = time.Second // least time between replies to one source
+	maxProbeSources    = 1024        // sources the probe limiter tracks at once
+
+	DefaultDiscoveryPortAttempts = 10
+	DefaultServerName            = "GoFlux Lite Server"
 )
 
-// NewDiscoveryService creates a new discovery service
+// NewDiscoveryService creates a new discovery service on the default port
 func NewDiscoveryService(serverAddress, version string, authEnabled bool) (*DiscoveryService, error) {
+	return NewDiscoveryServiceWithOptions(serverAddress, version, authEnabled, DiscoveryOptions{})
+}
+
+// NewDiscoveryServiceWithOptions creates a new discovery service. If the
+// preferred port is already in use, the following ports are tried in turn and
+// the one bound is advertised in the announcements. In mDNS mode the service
+// broadcasts instead when the mDNS group can't be joined.
+func NewDiscoveryServiceWithOptions(serverAddress, version string, authEnabled bool, opts DiscoveryOptions) (*DiscoveryService, error) {
+	announced := serverAddress
+	if opts.Address != "" {
+		announced = opts.Address
+	}
+	// Clients fall back to the sender's IP for unroutable addresses, which
+	// anyone replaying a signed announcement could choose
+	if opts.Secret != "" && !routableAddress(announced) {
+		return nil, fmt.Errorf("signed discovery needs a routable address to announce, not %q; set discovery_address", announced)
+	}
+
 	// Parse server address to get port
-	parts := strings.Split(serverAddress, ":")
-	var port string
-	if len(parts) == 2 {
-		port = parts[1]
-	} else {
-		port = "8080" // default
+	port := "8080" // default
+	if _, p, err := net.SplitHostPort(announced); err == nil && p != "" {
+		port = p
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = DefaultServerName
+	}
+
+	scheme := opts.Scheme
+	if scheme == "" {
+		scheme = "http"
 	}
 
 	info := DiscoveryInfo{
-		Name:        "GoFlux Lite Server",
+		Name:        name,
+		InstanceID:  opts.InstanceID,
+		Scheme:      scheme,
 		Version:     version,
-		Address:     serverAddress,
+		Address:     announced,
 		Port:        port,
 		AuthEnabled: authEnabled,
 	}
 
+	d := &DiscoveryService{
+		info:     info,
+		port:     mdns.Port,
+		stopChan: make(chan struct{}),
+		secret:   opts.Secret,
+	}
+
+	if opts.Mode == DiscoveryModeMDNS || opts.Mode == DiscoveryModeBoth {
+		responder, err := newMDNSResponder(info, opts.Secret)
+		if err != nil {
+			fmt.Printf("Warning: mDNS discovery unavailable: %v\n", err)
+		} else {
+			d.responder = responder
+		}
+	}
+	if d.responder != nil && opts.Mode == DiscoveryModeMDNS {
+		return d, nil
+	}
+
 	// Create UDP connection for broadcasting
-	addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf(":%d", DiscoveryPort))
+	conn, err := listenDiscovery(opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve UDP address: %w", err)
+		if d.responder == nil {
+			return nil, err
+		}
+		// mDNS alone still lets clients find the server
+		fmt.Printf("Warning: broadcast discovery unavailable: %v\n", err)
+		return d, nil
 	}
 
-	conn, err := net.ListenUDP("udp", addr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create UDP connection: %w", err)
+	d.conn = conn
+	d.port = conn.LocalAddr().(*net.UDPAddr).Port
+	d.info.DiscoveryPort = d.port
+	return d, nil
+}
+
+// listenDiscovery binds the first free UDP port starting at the preferred port
+func listenDiscovery(opts DiscoveryOptions) (*net.UDPConn, error) {
+	port := opts.Port
+	if port == 0 {
+		port = DiscoveryPort
+	}
+	attempts := opts.PortAttempts
+	if attempts <= 0 {
+		attempts = DefaultDiscoveryPortAttempts
 	}
 
-	return &DiscoveryService{
-		info:     info,
-		conn:     conn,
-		stopChan: make(chan struct{}),
-	}, nil
+	for i := 0; i < attempts; i++ {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: port + i})
+		if err == nil {
+			return conn, nil
+		}
+		if !errors.Is(err, syscall.EADDRINUSE) {
+			return nil, fmt.Errorf("failed to create UDP connection: %w", err)
+		}
+	}
+
+	return nil, fmt.Errorf("UDP ports %d-%d are all in use; stop the process holding port %d "+
+		"(perhaps another goflux-lite server) or set discovery_port in the config", port, port+attempts-1, port)
+}
+
+// Port returns the UDP port the service is bound to: the broadcast port, or
+// the mDNS port if the service only answers mDNS queries
+func (d *DiscoveryService) Port() int {
+	return d.port
 }
 
-// Start begins broadcasting server information
+// Start begins announcing server information
 func (d *DiscoveryService) Start() {
-	go d.broadcastLoop()
-	fmt.Printf("Discovery service started on UDP port %d\n", DiscoveryPort)
+	if d.responder != nil {
+		d.responder.Start()
+		fmt.Printf("mDNS discovery started, advertising %s\n", MDNSService)
+	}
+	if d.conn != nil {
+		go d.broadcastLoop()
+		go d.answerProbes()
+		fmt.Printf("Discovery service started on UDP port %d\n", d.port)
+	}
 }
 
 // Stop halts the discovery service
 func (d *DiscoveryService) Stop() {
-	close(d.stopChan)
-	if d.conn != nil {
-		d.conn.Close()
-	}
+	d.stopOnce.Do(func() {
+		close(d.stopChan)
+		if d.responder != nil {
+			d.responder.Stop()
+		}
+		if d.conn != nil {
+			d.conn.Close()
+		}
+	})
 }
 
 // broadcastLoop continuously broadcasts server information
 func (d *DiscoveryService) broadcastLoop() {
+	// Announce a few times in quick succession at startup, so clients
+	// already looking find the server without waiting for the interval
+	for i := 0; i < StartupBroadcasts; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(StartupBroadcastInterval):
+			case <-d.stopChan:
+				return
+			}
+		}
+		d.broadcast()
+	}
+
 	ticker := time.NewTicker(BroadcastInterval)
 	defer ticker.Stop()
 
-	// Send initial broadcast
-	d.broadcast()
-
 	for {
 		select {
 		case <-ticker.C:
@@ -100,18 +244,156 @@ func (d *DiscoveryService) broadcastLoop() {
 	}
 }
 
-// broadcast sends server information to the network
-func (d *DiscoveryService) broadcast() {
-	// Update timestamp
-	d.info.Timestamp = time.Now().Unix()
+// answerProbes replies to each probe arriving on the discovery port with an
+// announcement sent straight back to the prober, so clients on networks that
+// filter broadcasts can still find the server. The source of a UDP packet is
+// easily forged, so that the server can't be used to flood a third party
+// with replies, only probes from local networks that are at least as large as
+// the reply are answered, and each source at most once per ProbeReplyInterval.
+func (d *DiscoveryService) answerProbes() {
+	buf := make([]byte, 2048)
+	local := localNetworks()
+	limiter := newProbeLimiter(ProbeReplyInterval, maxProbeSources)
+	for {
+		n, from, err := d.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		if !isProbe(buf[:n]) {
+			continue // our own broadcasts, or noise
+		}
+		if !fromLocalNetwork(from.IP, local) {
+			continue
+		}
 
-	// Create broadcast message
+		data, err := d.message()
+		if err != nil || len(data) > n {
+			continue // older clients' unpadded probes get the broadcasts instead
+		}
+		if !limiter.allow(from.IP.String(), time.Now()) {
+			continue
+		}
+		d.conn.WriteToUDP(data, from)
+	}
+}
+
+// localNetworks returns the networks of the machine's interface addresses
+func localNetworks() []*net.IPNet {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	var nets []*net.IPNet
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok {
+			nets = append(nets, ipnet)
+		}
+	}
+	return nets
+}
+
+// fromLocalNetwork reports whether ip is a loopback, private or link-local
+// address, or one on a network the machine is attached to
+func fromLocalNetwork(ip net.IP, local []*net.IPNet) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() {
+		return true
+	}
+	for _, ipnet := range local {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// probeLimiter allows each source one probe reply per interval, tracking at
+// most max sources at once
+type probeLimiter struct {
+	interval time.Duration
+	max      int
+	last     map[string]time.Time // when each source was last answered
+}
+
+func newProbeLimiter(interval time.Duration, max int) *probeLimiter {
+	return &probeLimiter{interval: interval, max: max, last: make(map[string]time.Time)}
+}
+
+// allow reports whether source may be answered at now, recording it if so
+func (l *probeLimiter) allow(source string, now time.Time) bool {
+	if at, ok := l.last[source]; ok && now.Sub(at) < l.interval {
+		return false
+	}
+	if len(l.last) >= l.max {
+		for s, at := range l.last {
+			if now.Sub(at) >= l.interval {
+				delete(l.last, s)
+			}
+		}
+		if len(l.last) >= l.max {
+			return false // too many sources at once to be anything but a flood
+		}
+	}
+	l.last[source] = now
+	return true
+}
+
+// isProbe reports whether packet is a discovery probe
+func isProbe(packet []byte) bool {
+	var probe struct {
+		Magic string `json:"magic"`
+	}
+	return json.Unmarshal(packet, &probe) == nil && probe.Magic == ProbeMagic
+}
+
+// message builds the announcement payload with a fresh timestamp, signed
+// when the service has a secret
+func (d *DiscoveryService) message() ([]byte, error) {
+	d.infoMu.Lock()
+	d.info.Timestamp = time.Now().Unix()
+	data, err := json.Marshal(d.info)
+	d.infoMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
 	message := map[string]interface{}{
 		"magic": DiscoveryMagic,
-		"data":  d.info,
+		"data":  json.RawMessage(data),
 	}
+	if d.secret != "" {
+		// The signature covers the data exactly as sent, so clients check
+		// it before parsing anything from it
+		message["signature"] = signDiscovery(d.secret, data)
+	}
+	return json.Marshal(message)
+}
+
+// routableAddress reports whether clients on other machines can reach
+// address: one naming a host that is neither loopback nor unspecified
+func routableAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	if host == "" || host == "localhost" {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip == nil || !(ip.IsLoopback() || ip.IsUnspecified())
+}
+
+// signDiscovery returns the hex HMAC-SHA256 of data keyed with secret
+func signDiscovery(secret string, data []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
 
-	data, err := json.Marshal(message)
+// broadcast sends server information to the network
+func (d *DiscoveryService) broadcast() {
+	data, err := d.message()
 	if err != nil {
 		fmt.Printf("Failed to marshal discovery data: %v\n", err)
 		return
@@ -134,17 +416,18 @@ func (d *DiscoveryService) broadcast() {
 			continue
 		}
 
+		// IPv6 has no broadcast; announce to every node on the link instead.
+		// The socket is dual-stack where the system allows; elsewhere the
+		// write fails and is skipped like any other.
+		if iface.Flags&net.FlagMulticast != 0 && hasIPv6(addrs) {
+			d.conn.WriteToUDP(data, &net.UDPAddr{IP: allNodesIPv6, Port: DiscoveryPort, Zone: iface.Name})
+		}
+
 		for _, addr := range addrs {
 			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
-				// Calculate broadcast address
-				broadcast := make(net.IP, 4)
-				for i := 0; i < 4; i++ {
-					broadcast[i] = ipnet.IP[i] | ^ipnet.Mask[i]
-				}
-
 				// Send broadcast
 				broadcastAddr := &net.UDPAddr{
-					IP:   broadcast,
+					IP:   broadcastIP(ipnet),
 					Port: DiscoveryPort,
 				}
 
@@ -157,3 +440,55 @@ func (d *DiscoveryService) broadcast() {
 		}
 	}
 }
+
+// broadcastIP returns the broadcast address of an IPv4 network
+func broadcastIP(ipnet *net.IPNet) net.IP {
+	// Interface addresses are often 16-byte IPv4-in-IPv6 forms with 4-byte
+	// masks, so the bytes are lined up first
+	ip, mask := ipnet.IP.To4(), ipnet.Mask
+	if len(mask) == net.IPv6len {
+		mask = mask[12:]
+	}
+	broadcast := make(net.IP, net.IPv4len)
+	for i := range broadcast {
+		broadcast[i] = ip[i] | ^mask[i]
+	}
+	return broadcast
+}
+
+// allNodesIPv6 is the link-local multicast group every IPv6 node belongs to
+var allNodesIPv6 = net.ParseIP("ff02::1")
+
+// hasIPv6 reports whether addrs holds an IPv6 address
+func hasIPv6(addrs []net.Addr) bool {
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() == nil && ipnet.IP.To16() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// loadInstanceID returns the server's instance ID stored in metaDir, generating
+// and saving a new random one the first time.
+func loadInstanceID(metaDir string) (string, error) {
+	path := filepath.Join(metaDir, "instance_id")
+	if data, err := os.ReadFile(path); err == nil {
+		if id := strings.TrimSpace(string(data)); id != "" {
+			return id, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read instance ID: %w", err)
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate instance ID: %w", err)
+	}
+	id := hex.EncodeToString(buf)
+
+	if err := os.WriteFile(path, []byte(id+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to save instance ID: %w", err)
+	}
+	return id, nil
+}
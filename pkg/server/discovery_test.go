@@ -0,0 +1,339 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
+)
+
+// holdUDPPort binds a free UDP port for the duration of the test
+func holdUDPPort(t *testing.T) int {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		t.Fatalf("failed to bind UDP port: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func TestNewDiscoveryService_FallsBackToAlternatePort(t *testing.T) {
+	taken := holdUDPPort(t)
+
+	d, err := NewDiscoveryServiceWithOptions("127.0.0.1:8080", "test", false, DiscoveryOptions{Port: taken, PortAttempts: 5})
+	if err != nil {
+		t.Fatalf("expected fallback to an alternate port, got %v", err)
+	}
+	defer d.Stop()
+
+	if d.Port() == taken {
+		t.Fatalf("expected a port other than %d", taken)
+	}
+	if d.Port() < taken || d.Port() >= taken+5 {
+		t.Errorf("expected port in [%d, %d), got %d", taken, taken+5, d.Port())
+	}
+	if d.info.DiscoveryPort != d.Port() {
+		t.Errorf("expected advertised port %d, got %d", d.Port(), d.info.DiscoveryPort)
+	}
+}
+
+func TestNewDiscoveryService_PortInUse(t *testing.T) {
+	taken := holdUDPPort(t)
+
+	_, err := NewDiscoveryServiceWithOptions("127.0.0.1:8080", "test", false, DiscoveryOptions{Port: taken, PortAttempts: 1})
+	if err == nil {
+		t.Fatal("expected error when the only allowed port is in use")
+	}
+	if !strings.Contains(err.Error(), "in use") || !strings.Contains(err.Error(), "discovery_port") {
+		t.Errorf("expected an actionable error, got %v", err)
+	}
+}
+
+func TestEnableDiscovery_PortInUse(t *testing.T) {
+	taken := holdUDPPort(t)
+
+	t.Run("degrades by default", func(t *testing.T) {
+		srv, _ := newTestServer(t)
+		srv.SetDiscoveryOptions(DiscoveryOptions{Port: taken, PortAttempts: 1})
+
+		if err := srv.EnableDiscovery("127.0.0.1:8080", "test"); err != nil {
+			t.Fatalf("expected server to continue without discovery, got %v", err)
+		}
+		if srv.discovery != nil {
+			t.Error("expected discovery to be disabled")
+		}
+	})
+
+	t.Run("fails when required", func(t *testing.T) {
+		srv, _ := newTestServer(t)
+		srv.SetDiscoveryOptions(DiscoveryOptions{Port: taken, PortAttempts: 1, Required: true})
+
+		if err := srv.EnableDiscovery("127.0.0.1:8080", "test"); err == nil {
+			t.Fatal("expected error when discovery is required")
+		}
+	})
+}
+
+// decodeAnnouncement unpacks the data section of a discovery message
+func decodeAnnouncement(t *testing.T, payload []byte) DiscoveryInfo {
+	t.Helper()
+	var message struct {
+		Magic string        `json:"magic"`
+		Data  DiscoveryInfo `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &message); err != nil {
+		t.Fatalf("invalid announcement: %v", err)
+	}
+	if message.Magic != DiscoveryMagic {
+		t.Fatalf("unexpected magic %q", message.Magic)
+	}
+	return message.Data
+}
+
+func TestDiscoveryService_NameAndInstanceID(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetName("Backups")
+	srv.SetDiscoveryOptions(DiscoveryOptions{Port: holdUDPPort(t), PortAttempts: 5, Required: true})
+
+	if err := srv.EnableDiscovery("127.0.0.1:8080", "test"); err != nil {
+		t.Fatalf("EnableDiscovery failed: %v", err)
+	}
+	defer srv.discovery.Stop()
+
+	first, err := srv.discovery.message()
+	if err != nil {
+		t.Fatalf("message failed: %v", err)
+	}
+	second, _ := srv.discovery.message()
+
+	a, b := decodeAnnouncement(t, first), decodeAnnouncement(t, second)
+	if a.Name != "Backups" {
+		t.Errorf("expected name Backups, got %q", a.Name)
+	}
+	if a.InstanceID == "" || a.InstanceID != srv.InstanceID() {
+		t.Errorf("expected instance ID %q, got %q", srv.InstanceID(), a.InstanceID)
+	}
+	if a.InstanceID != b.InstanceID {
+		t.Errorf("instance ID changed between broadcasts: %q then %q", a.InstanceID, b.InstanceID)
+	}
+}
+
+func TestDiscoveryService_DefaultName(t *testing.T) {
+	d, err := NewDiscoveryServiceWithOptions("127.0.0.1:8080", "test", false, DiscoveryOptions{Port: holdUDPPort(t), PortAttempts: 5})
+	if err != nil {
+		t.Fatalf("NewDiscoveryServiceWithOptions failed: %v", err)
+	}
+	defer d.Stop()
+
+	if d.info.Name != DefaultServerName {
+		t.Errorf("expected default name, got %q", d.info.Name)
+	}
+}
+
+func TestInstanceID_StableAcrossRestarts(t *testing.T) {
+	metaDir := t.TempDir()
+	store, _ := storage.NewLocal(t.TempDir())
+
+	first, err := New(store, metaDir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	second, err := New(store, metaDir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if first.InstanceID() == "" {
+		t.Fatal("expected a generated instance ID")
+	}
+	if first.InstanceID() != second.InstanceID() {
+		t.Errorf("instance ID changed across restarts: %q then %q", first.InstanceID(), second.InstanceID())
+	}
+
+	other, _ := New(store, t.TempDir())
+	if other.InstanceID() == first.InstanceID() {
+		t.Error("expected different servers to have different instance IDs")
+	}
+}
+
+func TestDiscoveryService_AdvertisesScheme(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.EnableTLS("cert.pem", "key.pem")
+	srv.SetDiscoveryOptions(DiscoveryOptions{Port: holdUDPPort(t), PortAttempts: 5, Required: true})
+
+	if err := srv.EnableDiscovery("127.0.0.1:8443", "test"); err != nil {
+		t.Fatalf("EnableDiscovery failed: %v", err)
+	}
+	defer srv.discovery.Stop()
+
+	payload, _ := srv.discovery.message()
+	if info := decodeAnnouncement(t, payload); info.Scheme != "https" {
+		t.Errorf("expected https scheme, got %q", info.Scheme)
+	}
+}
+
+func TestBroadcastIP(t *testing.T) {
+	tests := []struct {
+		ipnet *net.IPNet
+		want  string
+	}{
+		// Interface addresses usually come as 16-byte IPs with 4-byte masks
+		{&net.IPNet{IP: net.IPv4(192, 168, 1, 10), Mask: net.CIDRMask(24, 32)}, "192.168.1.255"},
+		{&net.IPNet{IP: net.IPv4(10, 1, 2, 3).To4(), Mask: net.CIDRMask(8, 32)}, "10.255.255.255"},
+		{&net.IPNet{IP: net.IPv4(172, 16, 5, 4), Mask: net.CIDRMask(120, 128)}, "172.16.5.255"},
+	}
+	for _, tt := range tests {
+		if got := broadcastIP(tt.ipnet).String(); got != tt.want {
+			t.Errorf("%v: expected %s, got %s", tt.ipnet, tt.want, got)
+		}
+	}
+}
+
+func TestNewDiscoveryService_IPv6Address(t *testing.T) {
+	d, err := NewDiscoveryServiceWithOptions("[::]:8443", "test", false, DiscoveryOptions{Port: holdUDPPort(t), PortAttempts: 5})
+	if err != nil {
+		t.Fatalf("NewDiscoveryServiceWithOptions failed: %v", err)
+	}
+	defer d.Stop()
+
+	if d.info.Port != "8443" {
+		t.Errorf("expected port 8443, got %q", d.info.Port)
+	}
+}
+
+func TestDiscoveryService_SignsAnnouncements(t *testing.T) {
+	opts := DiscoveryOptions{Port: holdUDPPort(t), PortAttempts: 5, Secret: "s3cret"}
+	if _, err := NewDiscoveryServiceWithOptions(":8080", "test", false, opts); err == nil {
+		t.Fatal("expected a signed service without a routable address to be refused")
+	}
+
+	opts.Address = "192.168.1.10:8080"
+	d, err := NewDiscoveryServiceWithOptions(":8080", "test", false, opts)
+	if err != nil {
+		t.Fatalf("NewDiscoveryServiceWithOptions failed: %v", err)
+	}
+	defer d.Stop()
+
+	payload, err := d.message()
+	if err != nil {
+		t.Fatalf("message failed: %v", err)
+	}
+	var message struct {
+		Data      json.RawMessage `json:"data"`
+		Signature string          `json:"signature"`
+	}
+	if err := json.Unmarshal(payload, &message); err != nil {
+		t.Fatalf("invalid announcement: %v", err)
+	}
+	if message.Signature == "" || message.Signature != signDiscovery("s3cret", message.Data) {
+		t.Errorf("expected the data to be signed with the secret, got signature %q", message.Signature)
+	}
+	if decodeAnnouncement(t, payload).Address != "192.168.1.10:8080" {
+		t.Error("expected a signed announcement to carry the announced address")
+	}
+
+	d.secret = ""
+	payload, _ = d.message()
+	if strings.Contains(string(payload), "signature") {
+		t.Errorf("expected an unsigned announcement without a secret: %s", payload)
+	}
+}
+
+func TestDiscoveryService_AnswersProbes(t *testing.T) {
+	d, err := NewDiscoveryServiceWithOptions("0.0.0.0:8080", "test", false, DiscoveryOptions{Port: holdUDPPort(t), PortAttempts: 5})
+	if err != nil {
+		t.Fatalf("NewDiscoveryServiceWithOptions failed: %v", err)
+	}
+	go d.answerProbes()
+	defer d.Stop()
+
+	conn, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: d.Port()})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	// Anything but a probe goes unanswered, as does a probe smaller than the
+	// reply it asks for
+	conn.Write([]byte(`{"magic":"GOFLUX-LITE-DISCOVERY","data":{}}`))
+	conn.Write([]byte("not json"))
+	conn.Write([]byte(`{"magic":"GOFLUX-LITE-PROBE"}`))
+	probe := `{"magic":"GOFLUX-LITE-PROBE","pad":"` + strings.Repeat("0", 1024) + `"}`
+	if _, err := conn.Write([]byte(probe)); err != nil {
+		t.Fatalf("failed to send probe: %v", err)
+	}
+
+	buf := make([]byte, 2048)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("expected a reply to the probe: %v", err)
+	}
+	if info := decodeAnnouncement(t, buf[:n]); info.Address != "0.0.0.0:8080" || info.Port != "8080" {
+		t.Errorf("unexpected reply: %+v", info)
+	}
+
+	// Probes coming faster than the reply interval go unanswered
+	conn.Write([]byte(probe))
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, err := conn.Read(buf); err == nil {
+		t.Error("expected a single reply")
+	}
+}
+
+func TestProbeLimiter(t *testing.T) {
+	l := newProbeLimiter(time.Second, 2)
+	now := time.Now()
+
+	if !l.allow("a", now) || l.allow("a", now.Add(500*time.Millisecond)) {
+		t.Error("expected one reply per source per interval")
+	}
+	if !l.allow("a", now.Add(time.Second)) {
+		t.Error("expected a reply once the interval has passed")
+	}
+	if !l.allow("b", now.Add(time.Second)) || l.allow("c", now.Add(time.Second)) {
+		t.Error("expected new sources to be refused while the limiter is full")
+	}
+	if !l.allow("c", now.Add(3*time.Second)) {
+		t.Error("expected sources answered long ago to make room")
+	}
+}
+
+func TestFromLocalNetwork(t *testing.T) {
+	_, attached, _ := net.ParseCIDR("203.0.113.0/24")
+	local := []*net.IPNet{attached}
+	tests := map[string]bool{
+		"127.0.0.1":    true,
+		"192.168.1.20": true,
+		"10.1.2.3":     true,
+		"fe80::1":      true,
+		"203.0.113.9":  true,
+		"198.51.100.7": false,
+		"2001:db8::1":  false,
+	}
+	for ip, want := range tests {
+		if got := fromLocalNetwork(net.ParseIP(ip), local); got != want {
+			t.Errorf("fromLocalNetwork(%s) = %v, want %v", ip, got, want)
+		}
+	}
+}
+
+func TestIsProbe(t *testing.T) {
+	tests := map[string]bool{
+		`{"magic":"GOFLUX-LITE-PROBE"}`:             true,
+		`{"magic":"GOFLUX-LITE-PROBE","nonce":"x"}`: true,
+		`{"magic":"GOFLUX-LITE-DISCOVERY"}`:         false,
+		`{"magic":"goflux-lite-probe"}`:             false,
+		`GOFLUX-LITE-PROBE`:                         false,
+		``:                                          false,
+	}
+	for packet, want := range tests {
+		if got := isProbe([]byte(packet)); got != want {
+			t.Errorf("isProbe(%q) = %v, want %v", packet, got, want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+// UploadFilter restricts which files may be uploaded. Extensions are matched
+// case-insensitively with or without a leading dot; content types are
+// prefixes (e.g. "image/") of the type sniffed from the first chunk.
+// Empty allow lists permit everything; deny lists win over allow lists.
+type UploadFilter struct {
+	AllowExtensions []string
+	DenyExtensions  []string
+	AllowTypes      []string
+	DenyTypes       []string
+}
+
+// sniffLen is how many leading bytes http.DetectContentType considers
+const sniffLen = 512
+
+// checkPath rejects uploads whose file extension is not permitted
+func (f UploadFilter) checkPath(remotePath string) error {
+	if len(f.AllowExtensions) == 0 && len(f.DenyExtensions) == 0 {
+		return nil
+	}
+
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(remotePath), "."))
+	if matchExtension(f.DenyExtensions, ext) {
+		return fmt.Errorf("file extension %q is not allowed", ext)
+	}
+	if len(f.AllowExtensions) > 0 && !matchExtension(f.AllowExtensions, ext) {
+		return fmt.Errorf("file extension %q is not allowed", ext)
+	}
+	return nil
+}
+
+// checksContent reports whether the first chunk needs to be sniffed
+func (f UploadFilter) checksContent() bool {
+	return len(f.AllowTypes) > 0 || len(f.DenyTypes) > 0
+}
+
+// checkContent rejects uploads whose sniffed content type is not permitted
+func (f UploadFilter) checkContent(head []byte) error {
+	contentType := http.DetectContentType(head)
+	if matchType(f.DenyTypes, contentType) {
+		return fmt.Errorf("content type %q is not allowed", contentType)
+	}
+	if len(f.AllowTypes) > 0 && !matchType(f.AllowTypes, contentType) {
+		return fmt.Errorf("content type %q is not allowed", contentType)
+	}
+	return nil
+}
+
+func matchExtension(list []string, ext string) bool {
+	for _, e := range list {
+		if strings.ToLower(strings.TrimPrefix(e, ".")) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+func matchType(list []string, contentType string) bool {
+	for _, t := range list {
+		if strings.HasPrefix(contentType, strings.ToLower(t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// readHead returns up to sniffLen leading bytes of a chunk file
+func readHead(chunkPath string) ([]byte, error) {
+	f, err := os.Open(chunkPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// rejectUpload answers 415 and discards everything received for path so far
+func (s *Server) rejectUpload(w http.ResponseWriter, path string, reason error) {
+	s.discardSession(path)
+	errors.WriteJSON(w, http.StatusUnsupportedMediaType, reason)
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestHandleUpload_FilterAllowsType(t *testing.T) {
+	srv, store := newTestServer(t)
+	srv.SetUploadFilter(UploadFilter{AllowTypes: []string{"image/"}})
+
+	data := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0}, 100)...)
+	rec := postChunk(t, srv, transport.ChunkData{Path: "photo.png", ChunkID: 0, Data: data, Total: 1})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !store.Exists("photo.png") {
+		t.Error("expected allowed upload to be stored")
+	}
+}
+
+func TestHandleUpload_FilterRejectsType(t *testing.T) {
+	srv, store := newTestServer(t)
+	srv.SetUploadFilter(UploadFilter{AllowTypes: []string{"image/"}})
+
+	// A later chunk arriving first must be discarded along with the session
+	rec := postChunk(t, srv, transport.ChunkData{Path: "notes.png", ChunkID: 1, Data: []byte("more"), Total: 2})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for chunk 1, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = postChunk(t, srv, transport.ChunkData{Path: "notes.png", ChunkID: 0, Data: []byte("plain text!"), Total: 2})
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if _, ok := srv.sessionStore.GetSession("notes.png"); ok {
+		t.Error("expected session to be removed")
+	}
+	if _, err := os.Stat(srv.sessionChunksDir("notes.png")); !os.IsNotExist(err) {
+		t.Errorf("expected chunks directory to be removed, stat err: %v", err)
+	}
+	if store.Exists("notes.png") {
+		t.Error("expected rejected upload not to be stored")
+	}
+}
+
+func TestHandleUpload_FilterExtensions(t *testing.T) {
+	srv, store := newTestServer(t)
+	srv.SetUploadFilter(UploadFilter{DenyExtensions: []string{".exe"}})
+
+	rec := postChunk(t, srv, transport.ChunkData{Path: "setup.EXE", ChunkID: 0, Data: []byte("MZ"), Total: 1})
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, ok := srv.sessionStore.GetSession("setup.EXE"); ok {
+		t.Error("expected no session for rejected extension")
+	}
+
+	rec = postChunk(t, srv, transport.ChunkData{Path: "readme.txt", ChunkID: 0, Data: []byte("hi"), Total: 1})
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !store.Exists("readme.txt") {
+		t.Error("expected allowed upload to be stored")
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/audit"
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+	"github.com/0xRepo-Source/goflux-lite/pkg/resume"
+)
+
+// finalizeUpload reassembles the completed upload of path, stores it where
+// the overwrite policy says and removes its session. It writes an error response and returns false if the
+// file couldn't be stored. The caller holds the path's upload lock.
+func (s *Server) finalizeUpload(w http.ResponseWriter, r *http.Request, path string, session *resume.UploadSession) bool {
+	// The upload can't be stored at all if the overwrite policy refuses it
+	target, err := s.uploadTarget(path)
+	if err != nil {
+		s.audit(r, audit.ActionUpload, path, audit.ResultFailed, err)
+		s.discardSession(path)
+		errors.WriteJSON(w, http.StatusConflict, err)
+		return false
+	}
+
+	sessionChunksDir := s.sessionChunksDir(path)
+	if err := s.reassembleFromDisk(sessionChunksDir, target, session.TotalChunks, session.FileHash); err != nil {
+		s.audit(r, audit.ActionUpload, path, audit.ResultFailed, err)
+		if stderrors.Is(err, errFileHashMismatch) {
+			// The chunks can't produce the right file, so start the upload over
+			s.discardSession(path)
+			errors.WriteJSON(w, http.StatusBadRequest, err)
+			return false
+		}
+		writeJSONError(w, fmt.Errorf("reassembly failed: %w", err))
+		return false
+	}
+
+	// Clean up chunks directory and session
+	os.RemoveAll(sessionChunksDir)
+	if err := s.sessionStore.DeleteSession(path); err != nil {
+		fmt.Printf("Warning: failed to delete session metadata: %v\n", err)
+	}
+	s.releaseQuota(path)
+
+	// The upload itself succeeded, so a failed move only leaves the file
+	// where it was uploaded
+	if target != path {
+		fmt.Printf("File kept as a new version: %s → %s\n", path, target)
+	}
+	final, err := s.completeUpload(target)
+	if err != nil {
+		fmt.Printf("Warning: completion action for %s failed: %v\n", target, err)
+	} else if final != target {
+		fmt.Printf("File moved: %s → %s\n", target, final)
+	}
+	s.audit(r, audit.ActionUpload, final, audit.ResultOK, nil)
+	return true
+}
+
+// handleUploadFinalize reassembles an upload whose chunks were sent with
+// defer_finalize, once the client says it is done. Finalizing before every
+// chunk has arrived is refused with the number still missing, and the
+// upload is kept so they can be sent.
+func (s *Server) handleUploadFinalize(w http.ResponseWriter, r *http.Request) {
+	path := normalizePath(r.URL.Query().Get("path"))
+	if path == "" {
+		writeJSONError(w, errors.NewValidationError("path", "path required"))
+		return
+	}
+	if !s.allowPath(w, r, path) {
+		return
+	}
+
+	defer s.uploadLocks.lock(sessionKey(path))()
+
+	session, exists := s.sessionStore.GetSession(path)
+	if !exists {
+		writeJSONError(w, errors.NewStorageError(errors.StorageErrorNotFound, path, "no unfinished upload"))
+		return
+	}
+	if session.Owner != "" && session.Owner != s.sessionOwner(r) {
+		writeJSONError(w, errors.NewAuthError(errors.AuthErrorInsufficientPermissions,
+			fmt.Sprintf("the upload of %s was started by someone else", path)))
+		return
+	}
+	if !session.Completed {
+		missing, _ := s.sessionStore.GetMissingChunks(path)
+		errors.WriteJSON(w, http.StatusConflict, fmt.Errorf("upload of %s is missing %d of %d chunks", path, len(missing), session.TotalChunks))
+		return
+	}
+
+	if !s.finalizeUpload(w, r, path, session) {
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "upload of %s finalized", path)
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// finalize calls the finalize handler for path
+func finalize(srv *Server, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/upload/finalize?path="+path, nil)
+	rec := httptest.NewRecorder()
+	srv.handleUploadFinalize(rec, req)
+	return rec
+}
+
+func TestHandleUploadFinalize(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	chunks := []transport.ChunkData{
+		{Path: "later.txt", ChunkID: 1, Data: []byte("world"), Total: 2, DeferFinalize: true},
+		{Path: "later.txt", ChunkID: 0, Data: []byte("hello"), Total: 2, DeferFinalize: true},
+	}
+	for _, chunk := range chunks {
+		if rec := postChunk(t, srv, chunk); rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	// Every chunk has arrived, but the client hasn't said it is done
+	if store.Exists("later.txt") {
+		t.Fatal("expected no file before the upload is finalized")
+	}
+	if !uploadLeft(srv, "later.txt") {
+		t.Fatal("expected the chunks kept until the upload is finalized")
+	}
+
+	if rec := finalize(srv, "later.txt"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if data, err := store.Get("later.txt"); err != nil || string(data) != "helloworld" {
+		t.Errorf("expected helloworld, got %q (%v)", data, err)
+	}
+	if uploadLeft(srv, "later.txt") {
+		t.Error("expected the session and chunks removed once finalized")
+	}
+	if rec := finalize(srv, "later.txt"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 finalizing again, got %d", rec.Code)
+	}
+}
+
+func TestHandleUploadFinalize_MissingChunks(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	rec := postChunk(t, srv, transport.ChunkData{Path: "early.txt", ChunkID: 0, Data: []byte("aaaa"), Total: 3, DeferFinalize: true})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = finalize(srv, "early.txt")
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 finalizing with chunks missing, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "missing 2 of 3 chunks") {
+		t.Errorf("expected the missing chunks reported, got %q", rec.Body.String())
+	}
+	if store.Exists("early.txt") || !uploadLeft(srv, "early.txt") {
+		t.Error("expected the upload kept so the missing chunks can be sent")
+	}
+
+	if rec := finalize(srv, "nothing.txt"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without an upload, got %d", rec.Code)
+	}
+}
+
+func TestHandleUploadFinalize_AutoByDefault(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	// The last chunk decides: one that doesn't defer finalizes straight away
+	postChunk(t, srv, transport.ChunkData{Path: "auto.txt", ChunkID: 0, Data: []byte("ab"), Total: 2, DeferFinalize: true})
+	if rec := postChunk(t, srv, transport.ChunkData{Path: "auto.txt", ChunkID: 1, Data: []byte("c"), Total: 2}); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if data, err := store.Get("auto.txt"); err != nil || string(data) != "abc" {
+		t.Errorf("expected abc, got %q (%v)", data, err)
+	}
+}
+
+func TestHandleUploadFinalize_Owner(t *testing.T) {
+	srv, store := newTestServer(t)
+	enableTestAuth(t, srv, "alice", "bob")
+
+	postChunkAs(srv, "alice", transport.ChunkData{Path: "mine.txt", ChunkID: 0, Data: []byte("x"), Total: 1, DeferFinalize: true})
+
+	finalizeAs := func(user string) int {
+		req := httptest.NewRequest(http.MethodPost, "/upload/finalize?path=mine.txt", nil)
+		req.Header.Set("Authorization", "Bearer "+user)
+		rec := httptest.NewRecorder()
+		srv.authMiddle.RequireAuth("upload", srv.handleUploadFinalize)(rec, req)
+		return rec.Code
+	}
+	if code := finalizeAs("bob"); code != http.StatusForbidden {
+		t.Errorf("expected 403 finalizing another user's upload, got %d", code)
+	}
+	if code := finalizeAs("alice"); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if !store.Exists("mine.txt") {
+		t.Error("expected the file stored")
+	}
+}
+
+func TestCleanupSessions_UnfinalizedExpire(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetSessionCleanup(time.Hour, 0)
+
+	postChunk(t, srv, transport.ChunkData{Path: "forgotten.txt", ChunkID: 0, Data: []byte("x"), Total: 1, DeferFinalize: true})
+
+	if n := srv.cleanupSessions(time.Now()); n != 0 || !uploadLeft(srv, "forgotten.txt") {
+		t.Fatalf("expected a fresh upload kept, discarded %d", n)
+	}
+	if n := srv.cleanupSessions(time.Now().Add(2 * time.Hour)); n != 1 || uploadLeft(srv, "forgotten.txt") {
+		t.Errorf("expected an upload never finalized discarded after the maximum age, discarded %d", n)
+	}
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/auth"
+	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
+)
+
+// hashIndex maps SHA-256 content hashes to the stored paths holding that
+// content. It is built from storage once at startup and kept current as the
+// server writes, moves and deletes files, so lookups never touch storage.
+type hashIndex struct {
+	mu     sync.Mutex
+	byHash map[string]map[string]struct{} // hex sha256 -> paths
+	byPath map[string]string              // path -> hex sha256
+}
+
+func newHashIndex() *hashIndex {
+	return &hashIndex{
+		byHash: make(map[string]map[string]struct{}),
+		byPath: make(map[string]string),
+	}
+}
+
+// contentHash returns the hex-encoded SHA-256 of data.
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// validHash reports whether hash looks like a hex-encoded SHA-256.
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// record notes that path now holds content with the given hash, replacing
+// whatever the index knew about path before.
+func (h *hashIndex) record(path, hash string) {
+	path = strings.TrimPrefix(path, "/")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.forgetLocked(path)
+	h.recordLocked(path, hash)
+}
+
+func (h *hashIndex) recordLocked(path, hash string) {
+	paths, ok := h.byHash[hash]
+	if !ok {
+		paths = make(map[string]struct{})
+		h.byHash[hash] = paths
+	}
+	paths[path] = struct{}{}
+	h.byPath[path] = hash
+}
+
+// forget drops path, and everything beneath it, from the index.
+// An empty path clears the whole index.
+func (h *hashIndex) forget(path string) {
+	path = strings.TrimPrefix(path, "/")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for p := range h.byPath {
+		if path == "" || inTree(p, path) {
+			h.forgetLocked(p)
+		}
+	}
+}
+
+// rename moves the entries for src, and everything beneath it, to dst, after
+// dropping whatever dst held before
+func (h *hashIndex) rename(src, dst string) {
+	src = strings.TrimPrefix(src, "/")
+	dst = strings.TrimPrefix(dst, "/")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for p := range h.byPath {
+		if inTree(p, dst) {
+			h.forgetLocked(p)
+		}
+	}
+	moved := make(map[string]string)
+	for p, hash := range h.byPath {
+		if inTree(p, src) {
+			moved[dst+strings.TrimPrefix(p, src)] = hash
+			h.forgetLocked(p)
+		}
+	}
+	for p, hash := range moved {
+		h.recordLocked(p, hash)
+	}
+}
+
+func (h *hashIndex) forgetLocked(path string) {
+	old, ok := h.byPath[path]
+	if !ok {
+		return
+	}
+	delete(h.byPath, path)
+	delete(h.byHash[old], path)
+	if len(h.byHash[old]) == 0 {
+		delete(h.byHash, old)
+	}
+}
+
+// lookup returns the paths known to hold content with the given hash, sorted
+func (h *hashIndex) lookup(hash string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	paths := make([]string, 0, len(h.byHash[hash]))
+	for p := range h.byHash[hash] {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// rebuild replaces the index with the hashes of every file in store.
+// Backends that cannot be walked keep only what uploads have recorded.
+func (h *hashIndex) rebuild(store storage.Storage) error {
+	walker, ok := store.(storage.Walker)
+	if !ok {
+		return nil
+	}
+
+	index := newHashIndex()
+	err := walker.Walk("", func(path string) error {
+		data, err := store.Get(path)
+		if err != nil {
+			return err
+		}
+		index.recordLocked(path, contentHash(data))
+		return nil
+	})
+	if stderrors.Is(err, stderrors.ErrUnsupported) {
+		return nil // a wrapper around a backend that can't be walked
+	}
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.byHash = index.byHash
+	h.byPath = index.byPath
+	h.mu.Unlock()
+	return nil
+}
+
+// resolveHash returns a stored path within scope whose content has the given
+// hash. Only the index is consulted: a miss is a miss, so requests for unknown
+// hashes cost no more than a map lookup. Entries whose file has gone are
+// dropped. Paths outside scope are never considered, so a scoped token can't
+// learn whether content exists elsewhere.
+func (s *Server) resolveHash(hash string, scope auth.PathScope) (string, bool) {
+	for _, path := range s.hashes.lookup(hash) {
+		if !scope.Allows(path) {
+			continue
+		}
+		if s.storage.Exists(path) {
+			return path, true
+		}
+		s.hashes.forget(path)
+	}
+	return "", false
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/auth"
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+func getByHash(srv *Server, hash string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/download?hash="+hash, nil)
+	rec := httptest.NewRecorder()
+	srv.handleDownload(rec, req)
+	return rec
+}
+
+func TestHandleDownload_ByHash(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	// Uploaded through the server, so recorded in the index directly
+	rec := postChunk(t, srv, transport.ChunkData{Path: "uploads/a.txt", ChunkID: 0, Data: []byte("uploaded blob"), Total: 1})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upload failed: %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = getByHash(srv, contentHash([]byte("uploaded blob")))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Body.String() != "uploaded blob" {
+		t.Errorf("expected uploaded blob, got %q", rec.Body.String())
+	}
+
+	// Written behind the server's back: not found until the server restarts
+	// and indexes storage, since a miss never rescans it
+	store.Put("existing/b.txt", []byte("existing blob"))
+	hash := strings.ToUpper(contentHash([]byte("existing blob")))
+	if rec := getByHash(srv, hash); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 before a restart, got %d", rec.Code)
+	}
+	restarted, err := New(store, filepath.Join(filepath.Dir(store.Root), "meta"))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	rec = getByHash(restarted, hash)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Body.String() != "existing blob" {
+		t.Errorf("expected existing blob, got %q", rec.Body.String())
+	}
+}
+
+func TestHandleDownload_ByHashAfterMove(t *testing.T) {
+	srv, _ := newTestServer(t)
+	postChunk(t, srv, transport.ChunkData{Path: "docs/a.txt", ChunkID: 0, Data: []byte("moved"), Total: 1})
+
+	req := httptest.NewRequest(http.MethodPost, "/move?src=docs&dst=archive", nil)
+	rec := httptest.NewRecorder()
+	srv.handleMove(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("move failed: %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = getByHash(srv, contentHash([]byte("moved")))
+	if rec.Code != http.StatusOK || rec.Body.String() != "moved" {
+		t.Errorf("expected the moved file to be found by hash, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHashIndex_DuplicateContent(t *testing.T) {
+	h := newHashIndex()
+	h.record("a.txt", "abc")
+	h.record("b.txt", "abc")
+	h.forget("a.txt")
+	if paths := h.lookup("abc"); len(paths) != 1 || paths[0] != "b.txt" {
+		t.Errorf("expected the other copy to remain indexed, got %v", paths)
+	}
+
+	h.rename("b.txt", "c.txt")
+	if paths := h.lookup("abc"); len(paths) != 1 || paths[0] != "c.txt" {
+		t.Errorf("expected the renamed path, got %v", paths)
+	}
+}
+
+func TestHandleDownload_ByHashMissing(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("other.txt", []byte("other"))
+
+	rec := getByHash(srv, contentHash([]byte("never stored")))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = getByHash(srv, "not-a-hash")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for malformed hash, got %d", rec.Code)
+	}
+}
+
+func TestHandleDownload_ByHashAfterDelete(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	postChunk(t, srv, transport.ChunkData{Path: "gone.txt", ChunkID: 0, Data: []byte("gone"), Total: 1})
+	rec := callPathHandler(srv.handleDelete, http.MethodDelete, "/delete", "gone.txt")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("delete failed: %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = getByHash(srv, contentHash([]byte("gone")))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 after delete, got %d", rec.Code)
+	}
+}
+
+func TestHandleDownload_ByHashWithinScope(t *testing.T) {
+	srv, _ := newTestServer(t)
+	postChunk(t, srv, transport.ChunkData{Path: "private/secret.txt", ChunkID: 0, Data: []byte("shared"), Total: 1})
+
+	tokenStore, err := auth.NewTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+	token, _, err := tokenStore.Create("alice", []string{"download"}, time.Hour, "docs")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	srv.EnableAuth(tokenStore)
+
+	call := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/download?hash="+contentHash([]byte("shared")), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		srv.authMiddle.RequireAuth("download", srv.handleDownload)(rec, req)
+		return rec
+	}
+
+	// Only a copy outside the scope exists, so the hash is unknown to alice
+	if rec := call(); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for content held only outside the scope, got %d", rec.Code)
+	}
+
+	postChunk(t, srv, transport.ChunkData{Path: "docs/copy.txt", ChunkID: 0, Data: []byte("shared"), Total: 1})
+	if rec := call(); rec.Code != http.StatusOK || rec.Body.String() != "shared" {
+		t.Errorf("expected the copy within the scope, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// hstsHeader is only sent over TLS; browsers ignore it on plain HTTP
+const hstsHeader = "Strict-Transport-Security"
+
+// DefaultResponseHeaders are added to every response unless overridden
+var DefaultResponseHeaders = map[string]string{
+	"X-Content-Type-Options": "nosniff",
+}
+
+// SetResponseHeaders adds headers to every response on top of
+// DefaultResponseHeaders. A header given an empty value is not sent at all,
+// which also disables a default. Strict-Transport-Security is only sent on
+// TLS connections.
+func (s *Server) SetResponseHeaders(headers map[string]string) {
+	merged := make(map[string]string, len(DefaultResponseHeaders)+len(headers))
+	for name, value := range DefaultResponseHeaders {
+		merged[http.CanonicalHeaderKey(name)] = value
+	}
+	for name, value := range headers {
+		merged[http.CanonicalHeaderKey(strings.TrimSpace(name))] = value
+	}
+	for name, value := range merged {
+		if value == "" {
+			delete(merged, name)
+		}
+	}
+	s.responseHeaders = merged
+}
+
+// withResponseHeaders sets the configured response headers before calling next
+func (s *Server) withResponseHeaders(next http.Handler) http.Handler {
+	headers := s.responseHeaders
+	if headers == nil {
+		headers = DefaultResponseHeaders
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, value := range headers {
+			if name == hstsHeader && r.TLS == nil {
+				continue
+			}
+			w.Header().Set(name, value)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
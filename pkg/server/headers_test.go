@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// headersFor returns the headers srv's middleware adds to a request for target
+func headersFor(srv *Server, target string) http.Header {
+	handler := srv.withResponseHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec.Header()
+}
+
+func TestResponseHeaders_Default(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() { ln.Close() })
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get("http://" + ln.Addr().String() + "/config")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := resp.Header.Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("expected nosniff, got %q", got)
+	}
+	if got := resp.Header.Get("Cache-Control"); got != "" {
+		t.Errorf("expected no Cache-Control by default, got %q", got)
+	}
+}
+
+func TestResponseHeaders_Custom(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetResponseHeaders(map[string]string{
+		"cache-control":             "no-store",
+		"X-Content-Type-Options":    "",
+		"Strict-Transport-Security": "max-age=31536000",
+	})
+
+	headers := headersFor(srv, "http://example.com/list")
+	if got := headers.Get("Cache-Control"); got != "no-store" {
+		t.Errorf("expected Cache-Control no-store, got %q", got)
+	}
+	if _, ok := headers["X-Content-Type-Options"]; ok {
+		t.Error("expected disabled default header not to be sent")
+	}
+	if got := headers.Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("expected no HSTS over plain HTTP, got %q", got)
+	}
+
+	headers = headersFor(srv, "https://example.com/list")
+	if got := headers.Get("Strict-Transport-Security"); got != "max-age=31536000" {
+		t.Errorf("expected HSTS over TLS, got %q", got)
+	}
+}
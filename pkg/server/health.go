@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+// ReadinessResponse is returned by /ready
+type ReadinessResponse struct {
+	Ready       bool     `json:"ready"`
+	Reasons     []string `json:"reasons,omitempty"` // why the server isn't ready
+	Sessions    int      `json:"sessions"`          // unfinished uploads
+	Connections int64    `json:"connections"`       // open client connections
+}
+
+// SetReadinessThresholds sets when /ready reports the server overloaded:
+// with more than maxSessions unfinished uploads or more than maxConnections
+// open client connections. Zero disables either check. /ready also fails
+// while the storage can't be read, whatever the thresholds.
+func (s *Server) SetReadinessThresholds(maxSessions, maxConnections int) {
+	s.readyMaxSessions = maxSessions
+	s.readyMaxConns = maxConnections
+}
+
+// trackConn counts open connections for /ready; it is the http.Server's
+// ConnState hook
+func (s *Server) trackConn(_ net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		s.openConns.Add(1)
+	case http.StateHijacked, http.StateClosed:
+		s.openConns.Add(-1)
+	}
+}
+
+// readiness reports whether the server should be sent more traffic
+func (s *Server) readiness() ReadinessResponse {
+	resp := ReadinessResponse{Connections: s.openConns.Load()}
+	for _, session := range s.sessionStore.Sessions() {
+		if !session.Completed {
+			resp.Sessions++
+		}
+	}
+
+	if s.readyMaxSessions > 0 && resp.Sessions > s.readyMaxSessions {
+		resp.Reasons = append(resp.Reasons, fmt.Sprintf("%d unfinished uploads (threshold %d)", resp.Sessions, s.readyMaxSessions))
+	}
+	if s.readyMaxConns > 0 && resp.Connections > int64(s.readyMaxConns) {
+		resp.Reasons = append(resp.Reasons, fmt.Sprintf("%d open connections (threshold %d)", resp.Connections, s.readyMaxConns))
+	}
+	if _, err := s.storage.List("/"); err != nil {
+		resp.Reasons = append(resp.Reasons, fmt.Sprintf("storage unavailable: %v", err))
+	}
+	resp.Ready = len(resp.Reasons) == 0
+	return resp
+}
+
+// handleHealth reports that the process is alive and serving requests, for
+// liveness checks. Overload doesn't affect it; see handleReady.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReady answers 503 Service Unavailable while the server is
+// overloaded or its storage is unavailable, so load balancers send traffic
+// elsewhere until it recovers
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	resp := s.readiness()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !resp.Ready {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("encode failed: %w", err))
+		return
+	}
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// probe calls /health and /ready, failing the test unless /health is 200
+func probe(t *testing.T, srv *Server) (int, ReadinessResponse) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	srv.handleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected /health to stay 200, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.handleReady(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	var resp ReadinessResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode /ready: %v", err)
+	}
+	return rec.Code, resp
+}
+
+func TestHandleReady_Sessions(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetReadinessThresholds(1, 0)
+
+	if code, resp := probe(t, srv); code != http.StatusOK || !resp.Ready {
+		t.Fatalf("expected ready when idle, got %d %+v", code, resp)
+	}
+
+	// Two unfinished uploads is one more than the threshold
+	for _, p := range []string{"a.txt", "b.txt"} {
+		if rec := postChunk(t, srv, transport.ChunkData{Path: p, ChunkID: 0, Data: []byte("x"), Total: 2}); rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	code, resp := probe(t, srv)
+	if code != http.StatusServiceUnavailable || resp.Ready || resp.Sessions != 2 || len(resp.Reasons) != 1 {
+		t.Fatalf("expected 503 with 2 sessions, got %d %+v", code, resp)
+	}
+
+	// Finishing one brings the server back under the threshold
+	if rec := postChunk(t, srv, transport.ChunkData{Path: "a.txt", ChunkID: 1, Data: []byte("y"), Total: 2}); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if code, resp := probe(t, srv); code != http.StatusOK || resp.Sessions != 1 {
+		t.Errorf("expected ready again with 1 session, got %d %+v", code, resp)
+	}
+}
+
+func TestHandleReady_Connections(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetReadinessThresholds(0, 2)
+
+	for i := 0; i < 3; i++ {
+		srv.trackConn(nil, http.StateNew)
+	}
+	srv.trackConn(nil, http.StateActive)
+	if code, resp := probe(t, srv); code != http.StatusServiceUnavailable || resp.Connections != 3 {
+		t.Fatalf("expected 503 with 3 connections, got %d %+v", code, resp)
+	}
+
+	srv.trackConn(nil, http.StateClosed)
+	if code, resp := probe(t, srv); code != http.StatusOK || resp.Connections != 2 {
+		t.Errorf("expected ready again with 2 connections, got %d %+v", code, resp)
+	}
+}
+
+func TestHandleReady_Unlimited(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	for i := 0; i < 100; i++ {
+		srv.trackConn(nil, http.StateNew)
+	}
+	if code, resp := probe(t, srv); code != http.StatusOK {
+		t.Errorf("expected no thresholds by default, got %d %+v", code, resp)
+	}
+}
+
+func TestHandleReady_StorageUnavailable(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	if err := os.RemoveAll(store.Root); err != nil {
+		t.Fatalf("failed to remove storage root: %v", err)
+	}
+	code, resp := probe(t, srv)
+	if code != http.StatusServiceUnavailable || resp.Ready {
+		t.Fatalf("expected 503 without storage, got %d %+v", code, resp)
+	}
+
+	if err := os.MkdirAll(store.Root, 0755); err != nil {
+		t.Fatalf("failed to restore storage root: %v", err)
+	}
+	if code, resp := probe(t, srv); code != http.StatusOK {
+		t.Errorf("expected ready once storage is back, got %d %+v", code, resp)
+	}
+}
+
+func TestServe_TracksConnections(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	ts := httptest.NewUnstartedServer(srv.handler())
+	ts.Config.ConnState = srv.trackConn
+	ts.Start()
+
+	client := ts.Client()
+	resp, err := client.Get(ts.URL + "/ready")
+	if err != nil {
+		t.Fatalf("GET /ready failed: %v", err)
+	}
+	var ready ReadinessResponse
+	json.NewDecoder(resp.Body).Decode(&ready)
+	resp.Body.Close()
+	if ready.Connections != 1 {
+		t.Errorf("expected the request's own connection counted, got %d", ready.Connections)
+	}
+
+	client.CloseIdleConnections()
+	ts.Close()
+	if n := srv.openConns.Load(); n != 0 {
+		t.Errorf("expected no open connections after close, got %d", n)
+	}
+}
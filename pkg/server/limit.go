@@ -0,0 +1,112 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultMaxConnections is the default cap on simultaneous client connections
+const DefaultMaxConnections = 1024
+
+// capacityResponse is written to connections accepted while the server is full
+const capacityResponse = "HTTP/1.1 503 Service Unavailable\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Retry-After: 1\r\n" +
+	"Connection: close\r\n" +
+	"Content-Length: 19\r\n" +
+	"\r\n" +
+	"server at capacity\n"
+
+// rejectTimeout bounds how long answering a connection beyond the cap may
+// take, including the TLS handshake when the server uses TLS
+const rejectTimeout = 5 * time.Second
+
+// limitListener wraps a net.Listener and caps the number of simultaneously
+// open connections. Connections accepted beyond the cap receive a 503
+// response and are closed immediately instead of queueing indefinitely.
+type limitListener struct {
+	net.Listener
+	slots chan struct{}
+
+	rejectTLS *tls.Config   // encrypts the 503 when the server uses TLS (nil for plain HTTP)
+	rejecting chan struct{} // connections being answered with 503
+}
+
+// newLimitListener returns a listener that allows at most max open connections
+func newLimitListener(l net.Listener, max int) *limitListener {
+	return &limitListener{
+		Listener:  l,
+		slots:     make(chan struct{}, max),
+		rejecting: make(chan struct{}, max),
+	}
+}
+
+// answerOverTLS makes connections beyond the cap receive their 503 over TLS
+// with config, as a client of a TLS server expects. Only HTTP/1.1 is
+// offered, since that is what the 503 is written in.
+func (l *limitListener) answerOverTLS(config *tls.Config) {
+	l.rejectTLS = config.Clone()
+	l.rejectTLS.NextProtos = []string{"http/1.1"}
+}
+
+// Accept waits for the next connection that fits within the limit.
+// Excess connections are answered with 503 and closed.
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		select {
+		case l.slots <- struct{}{}:
+			return &limitConn{Conn: conn, release: l.release}, nil
+		default:
+			l.reject(conn)
+		}
+	}
+}
+
+// reject answers a connection beyond the cap with 503 and closes it. The
+// answer is written in the background, since over TLS a handshake comes
+// first, so the accept loop never waits on the client. Once as many
+// connections are being answered as the cap allows, further ones are closed
+// without an answer.
+func (l *limitListener) reject(conn net.Conn) {
+	select {
+	case l.rejecting <- struct{}{}:
+	default:
+		conn.Close()
+		return
+	}
+
+	go func() {
+		defer func() { <-l.rejecting }()
+		if l.rejectTLS != nil {
+			conn = tls.Server(conn, l.rejectTLS)
+		}
+		conn.SetDeadline(time.Now().Add(rejectTimeout))
+		conn.Write([]byte(capacityResponse))
+		conn.Close()
+	}()
+}
+
+// release frees a connection slot
+func (l *limitListener) release() {
+	<-l.slots
+}
+
+// limitConn releases its listener slot exactly once when closed
+type limitConn struct {
+	net.Conn
+	release func()
+	once    sync.Once
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
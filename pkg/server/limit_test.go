@@ -0,0 +1,161 @@
+package server
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// sendRequest writes a minimal GET request on conn and parses the response
+func sendRequest(t *testing.T, conn net.Conn) *http.Response {
+	t.Helper()
+
+	fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: test\r\n\r\n")
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	return resp
+}
+
+func TestLimitListener_RejectsExcessConnections(t *testing.T) {
+	base, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	ln := newLimitListener(base, 2)
+	defer ln.Close()
+
+	entered := make(chan struct{}, 2)
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+	go http.Serve(ln, handler)
+
+	// Occupy both slots with requests that block in the handler
+	var held []net.Conn
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", base.Addr().String())
+		if err != nil {
+			t.Fatalf("dial %d failed: %v", i, err)
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: test\r\n\r\n")
+		held = append(held, conn)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-entered:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for held requests")
+		}
+	}
+
+	// A third connection exceeds the limit and is refused with 503
+	extra, err := net.Dial("tcp", base.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer extra.Close()
+
+	resp := sendRequest(t, extra)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 for excess connection, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("expected Retry-After header on 503")
+	}
+
+	// Once the held connections finish, capacity is available again
+	close(release)
+	for _, conn := range held {
+		conn.Close()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", base.Addr().String())
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		resp := sendRequest(t, conn)
+		resp.Body.Close()
+		conn.Close()
+
+		if resp.StatusCode == http.StatusOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected capacity to recover, last status %d", resp.StatusCode)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLimitListener_RejectsExcessConnectionsOverTLS(t *testing.T) {
+	certFile, keyFile, pool := writeSelfSignedCert(t)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("failed to load certificate: %v", err)
+	}
+
+	base, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	ln := newLimitListener(base, 2)
+	defer ln.Close()
+
+	entered := make(chan struct{}, 2)
+	release := make(chan struct{})
+	defer close(release)
+	httpServer := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entered <- struct{}{}
+			<-release
+			w.WriteHeader(http.StatusOK)
+		}),
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+	}
+	ln.answerOverTLS(httpServer.TLSConfig)
+	go httpServer.ServeTLS(ln, "", "")
+
+	clientConfig := &tls.Config{RootCAs: pool}
+	for i := 0; i < 2; i++ {
+		conn, err := tls.Dial("tcp", base.Addr().String(), clientConfig)
+		if err != nil {
+			t.Fatalf("dial %d failed: %v", i, err)
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: test\r\n\r\n")
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-entered:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for held requests")
+		}
+	}
+
+	// The excess connection completes a handshake and reads the 503 over TLS
+	extra, err := tls.Dial("tcp", base.Addr().String(), clientConfig)
+	if err != nil {
+		t.Fatalf("TLS handshake for excess connection failed: %v", err)
+	}
+	defer extra.Close()
+
+	resp := sendRequest(t, extra)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 for excess connection, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/mdns"
+)
+
+// Discovery modes, choosing how the server announces itself
+const (
+	DiscoveryModeBroadcast = "broadcast" // JSON announcements broadcast to UDP port 8081
+	DiscoveryModeMDNS      = "mdns"      // an mDNS/DNS-SD responder, falling back to broadcast
+	DiscoveryModeBoth      = "both"      // both at once
+)
+
+// MDNSService is the DNS-SD service type goflux-lite servers register
+const MDNSService = "_goflux._tcp"
+
+// txtVersion is the version of the TXT record layout in infoTXT
+const txtVersion = "1"
+
+// infoTXT encodes server information as DNS-SD TXT record strings, carrying
+// the same fields as a broadcast announcement. With a secret, a sig string is
+// added holding the signature of the others, sorted and joined by newlines.
+func infoTXT(info DiscoveryInfo, secret string) []string {
+	text := mdns.EncodeTXT(map[string]string{
+		"txtvers": txtVersion,
+		"name":    info.Name,
+		"id":      info.InstanceID,
+		"scheme":  info.Scheme,
+		"version": info.Version,
+		"address": info.Address,
+		"port":    info.Port,
+		"auth":    strconv.FormatBool(info.AuthEnabled),
+	})
+	if secret != "" {
+		text = append(text, "sig="+signDiscovery(secret, []byte(strings.Join(text, "\n"))))
+	}
+	return text
+}
+
+// newMDNSResponder returns a responder advertising the server described by
+// info as an instance of MDNSService, signing its TXT record with secret if
+// there is one
+func newMDNSResponder(info DiscoveryInfo, secret string) (*mdns.Responder, error) {
+	port, err := strconv.ParseUint(info.Port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server port %q", info.Port)
+	}
+
+	// Instance names must be unique on the network, and several servers may
+	// keep the default name
+	instance := info.Name
+	if id := info.InstanceID; id != "" {
+		instance = fmt.Sprintf("%s (%s)", instance, id[:min(len(id), 6)])
+	}
+
+	return mdns.NewResponder(mdns.Service{
+		Instance: instance,
+		Service:  MDNSService,
+		Host:     mdnsHostname(),
+		Port:     uint16(port),
+		IPs:      localIPv4s(),
+		Text:     infoTXT(info, secret),
+	})
+}
+
+// mdnsHostname returns the first label of the machine's host name, for
+// naming it in the .local domain
+func mdnsHostname() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "goflux"
+	}
+	host, _, _ = strings.Cut(host, ".")
+	return host
+}
+
+// localIPv4s returns the IPv4 addresses of the machine's network interfaces
+// that are up, other than loopback
+func localIPv4s() []net.IP {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	var ips []net.IP
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+				ips = append(ips, ipnet.IP.To4())
+			}
+		}
+	}
+	return ips
+}
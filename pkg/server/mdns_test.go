@@ -0,0 +1,71 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/mdns"
+)
+
+func TestInfoTXT(t *testing.T) {
+	info := DiscoveryInfo{
+		Name:        "Backups = NAS",
+		InstanceID:  "0123456789abcdef",
+		Scheme:      "https",
+		Version:     "0.1.0-lite",
+		Address:     "192.168.1.10:8443",
+		Port:        "8443",
+		AuthEnabled: true,
+	}
+	text := infoTXT(info, "")
+	for _, s := range text {
+		if len(s) > 255 {
+			t.Errorf("TXT string longer than 255 bytes: %q", s)
+		}
+	}
+
+	got := mdns.DecodeTXT(text)
+	want := map[string]string{
+		"txtvers": "1",
+		"name":    "Backups = NAS",
+		"id":      "0123456789abcdef",
+		"scheme":  "https",
+		"version": "0.1.0-lite",
+		"address": "192.168.1.10:8443",
+		"port":    "8443",
+		"auth":    "true",
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s: expected %q, got %q", key, value, got[key])
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected %d keys, got %v", len(want), got)
+	}
+
+	info.AuthEnabled = false
+	if got := mdns.DecodeTXT(infoTXT(info, "")); got["auth"] != "false" {
+		t.Errorf("expected auth=false, got %q", got["auth"])
+	}
+}
+
+func TestNewMDNSResponder_InvalidPort(t *testing.T) {
+	if _, err := newMDNSResponder(DiscoveryInfo{Name: "Backups", Port: "http"}, ""); err == nil {
+		t.Error("expected a non-numeric port to be rejected")
+	}
+}
+
+func TestInfoTXT_Signed(t *testing.T) {
+	info := DiscoveryInfo{Name: "Backups", Port: "8080"}
+	text := infoTXT(info, "s3cret")
+
+	sig := mdns.DecodeTXT(text)["sig"]
+	unsigned := infoTXT(info, "")
+	if sig == "" || sig != signDiscovery("s3cret", []byte(strings.Join(unsigned, "\n"))) {
+		t.Errorf("expected sig to sign the other strings, got %q", sig)
+	}
+	if len(text) != len(unsigned)+1 {
+		t.Errorf("expected only a sig string to be added, got %v", text)
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/audit"
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
+)
+
+// handleMove renames or moves a file or directory tree on the server, so
+// clients don't have to download, upload and delete it. An existing file at
+// the destination is only replaced with overwrite=true; an existing
+// directory never is.
+func (s *Server) handleMove(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	src := strings.Trim(path.Clean("/"+normalizePath(query.Get("src"))), "/")
+	dst := strings.Trim(path.Clean("/"+normalizePath(query.Get("dst"))), "/")
+	if src == "" || dst == "" {
+		writeJSONError(w, errors.NewValidationError("src", "src and dst parameters required"))
+		return
+	}
+	if inTree(dst, src) {
+		errors.WriteJSON(w, http.StatusBadRequest, fmt.Errorf("cannot move %s into itself", src))
+		return
+	}
+	if !s.allowPath(w, r, src) || !s.allowPath(w, r, dst) {
+		return
+	}
+
+	renamer, ok := s.storage.(storage.Renamer)
+	if !ok {
+		errors.WriteJSON(w, http.StatusNotImplemented, stderrors.New("move failed: storage backend cannot rename"))
+		return
+	}
+
+	// Moving files that are still arriving would strand their uploads
+	for _, session := range s.sessionStore.Sessions() {
+		if inTree(session.Path, src) || inTree(session.Path, dst) {
+			errors.WriteJSON(w, http.StatusConflict, fmt.Errorf("move failed: upload of %s is still in progress", session.Path))
+			return
+		}
+	}
+
+	if query.Get("overwrite") == "true" {
+		if info, err := storage.Stat(s.storage, dst); err == nil {
+			if info.IsDir {
+				writeJSONError(w, errors.NewStorageError(errors.StorageErrorAlreadyExists, dst, "move failed: destination is an existing directory"))
+				return
+			}
+			if !s.storage.Exists(src) {
+				writeJSONError(w, errors.NewStorageError(errors.StorageErrorNotFound, src, "move failed: source does not exist"))
+				return
+			}
+			if err := s.storage.Delete(dst); err != nil {
+				s.audit(r, audit.ActionDelete, dst, audit.ResultFailed, err)
+				writeJSONError(w, fmt.Errorf("move failed: %w", err))
+				return
+			}
+			s.hashes.forget(dst)
+			s.audit(r, audit.ActionDelete, dst, audit.ResultOK, nil)
+		} else if errType, ok := errors.GetStorageErrorType(err); !ok || errType != errors.StorageErrorNotFound {
+			writeJSONError(w, fmt.Errorf("move failed: %w", err))
+			return
+		}
+	}
+
+	if err := renamer.Rename(src, dst); err != nil {
+		status := errors.HTTPStatus(err)
+		if stderrors.Is(err, stderrors.ErrUnsupported) {
+			status = http.StatusNotImplemented
+		}
+		errors.WriteJSON(w, status, fmt.Errorf("move failed: %w", err))
+		return
+	}
+	s.hashes.rename(src, dst)
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Successfully moved %s to %s", src, dst)
+}
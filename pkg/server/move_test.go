@@ -0,0 +1,123 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// move calls the move handler to move src to dst
+func move(srv *Server, src, dst string, overwrite bool) int {
+	query := url.Values{"src": {src}, "dst": {dst}}
+	if overwrite {
+		query.Set("overwrite", "true")
+	}
+	req := httptest.NewRequest(http.MethodPost, "/move?"+query.Encode(), nil)
+	rec := httptest.NewRecorder()
+	srv.handleMove(rec, req)
+	return rec.Code
+}
+
+func TestHandleMove_SameDirectory(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("docs/draft.txt", []byte("text"))
+
+	if code := move(srv, "docs/draft.txt", "docs/final.txt", false); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if data, err := store.Get("docs/final.txt"); err != nil || string(data) != "text" {
+		t.Errorf("expected renamed file, got %q (%v)", data, err)
+	}
+	if store.Exists("docs/draft.txt") {
+		t.Error("expected the old name gone")
+	}
+}
+
+func TestHandleMove_AcrossDirectories(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("inbox/report.pdf", []byte("pdf"))
+	store.Put("inbox/photos/a.jpg", []byte("jpg"))
+
+	if code := move(srv, "inbox/report.pdf", "archive/2024/report.pdf", false); code != http.StatusOK {
+		t.Fatalf("expected 200 moving a file, got %d", code)
+	}
+	if code := move(srv, "inbox/photos", "archive/photos", false); code != http.StatusOK {
+		t.Fatalf("expected 200 moving a directory, got %d", code)
+	}
+
+	for p, want := range map[string]string{"archive/2024/report.pdf": "pdf", "archive/photos/a.jpg": "jpg"} {
+		if data, err := store.Get(p); err != nil || string(data) != want {
+			t.Errorf("%s: expected %q, got %q (%v)", p, want, data, err)
+		}
+	}
+	if store.Exists("inbox/report.pdf") || store.Exists("inbox/photos") {
+		t.Error("expected the sources gone")
+	}
+}
+
+func TestHandleMove_Errors(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("a.txt", []byte("a"))
+	store.Put("b.txt", []byte("b"))
+	store.Put("dir/c.txt", []byte("c"))
+
+	tests := []struct {
+		name      string
+		src, dst  string
+		overwrite bool
+		want      int
+	}{
+		{"missing source", "missing.txt", "new.txt", false, http.StatusNotFound},
+		{"missing source with overwrite", "missing.txt", "b.txt", true, http.StatusNotFound},
+		{"existing destination", "a.txt", "b.txt", false, http.StatusConflict},
+		{"directory destination with overwrite", "a.txt", "dir", true, http.StatusConflict},
+		{"into itself", "dir", "dir/sub", false, http.StatusBadRequest},
+		{"missing parameter", "a.txt", "", false, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if code := move(srv, tt.src, tt.dst, tt.overwrite); code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, code)
+		}
+	}
+
+	for p, want := range map[string]string{"a.txt": "a", "b.txt": "b", "dir/c.txt": "c"} {
+		if data, _ := store.Get(p); string(data) != want {
+			t.Errorf("expected %s untouched, got %q", p, data)
+		}
+	}
+}
+
+func TestHandleMove_Overwrite(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("new.txt", []byte("new"))
+	store.Put("current.txt", []byte("old"))
+
+	if code := move(srv, "new.txt", "current.txt", true); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if data, _ := store.Get("current.txt"); string(data) != "new" {
+		t.Errorf("expected the file replaced, got %q", data)
+	}
+	if store.Exists("new.txt") {
+		t.Error("expected the source gone")
+	}
+}
+
+func TestHandleMove_UploadInProgress(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("docs/a.txt", []byte("a"))
+	postChunk(t, srv, transport.ChunkData{Path: "docs/big.bin", ChunkID: 0, Data: []byte("xx"), Total: 2})
+
+	if code := move(srv, "docs", "archive", false); code != http.StatusConflict {
+		t.Errorf("expected 409 moving a directory with an upload in progress, got %d", code)
+	}
+	if code := move(srv, "docs/a.txt", "docs/big.bin", true); code != http.StatusConflict {
+		t.Errorf("expected 409 moving onto an upload in progress, got %d", code)
+	}
+	if !store.Exists("docs/a.txt") {
+		t.Error("expected nothing moved")
+	}
+}
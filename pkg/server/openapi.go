@@ -0,0 +1,135 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+// OpenAPIDocument is the OpenAPI 3 description served at /openapi.json. Only
+// the parts of the specification the server's endpoints need are modelled.
+type OpenAPIDocument struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Info       OpenAPIInfo                            `json:"info"`
+	Paths      map[string]map[string]OpenAPIOperation `json:"paths"` // path -> lower-case method -> operation
+	Components *OpenAPIComponents                     `json:"components,omitempty"`
+}
+
+// OpenAPIInfo names the API and its version
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenAPIOperation describes one method of an endpoint
+type OpenAPIOperation struct {
+	Summary     string                     `json:"summary"`
+	Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+	Permission  string                     `json:"x-permission,omitempty"` // token permission the endpoint requires
+}
+
+// OpenAPIParameter describes a query parameter
+type OpenAPIParameter struct {
+	Name        string            `json:"name"`
+	In          string            `json:"in"`
+	Required    bool              `json:"required,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Schema      map[string]string `json:"schema"`
+}
+
+// OpenAPIRequestBody describes the body an operation accepts
+type OpenAPIRequestBody struct {
+	Required bool                           `json:"required"`
+	Content  map[string]map[string]struct{} `json:"content"`
+}
+
+// OpenAPIResponse describes a response status
+type OpenAPIResponse struct {
+	Description string `json:"description"`
+}
+
+// OpenAPIComponents holds the security schemes operations refer to
+type OpenAPIComponents struct {
+	SecuritySchemes map[string]OpenAPISecurityScheme `json:"securitySchemes"`
+}
+
+// OpenAPISecurityScheme describes how clients authenticate
+type OpenAPISecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+// openAPIVersion is the version of the OpenAPI specification followed
+const openAPIVersion = "3.0.3"
+
+// OpenAPI describes the endpoints the server serves with its current
+// settings. Endpoints needing a token carry a bearer security requirement
+// and the permission they check as x-permission.
+func (s *Server) OpenAPI() *OpenAPIDocument {
+	doc := &OpenAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: OpenAPIInfo{
+			Title:       "goflux-lite",
+			Description: s.name,
+			Version:     "unknown",
+		},
+		Paths: make(map[string]map[string]OpenAPIOperation),
+	}
+	if s.serverConfig != nil && s.serverConfig.Version != "" {
+		doc.Info.Version = s.serverConfig.Version
+	}
+	if s.authMiddle != nil {
+		doc.Components = &OpenAPIComponents{SecuritySchemes: map[string]OpenAPISecurityScheme{
+			"bearerAuth": {Type: "http", Scheme: "bearer"},
+		}}
+	}
+
+	for _, rt := range s.routes() {
+		op := OpenAPIOperation{
+			Summary: rt.summary,
+			Responses: map[string]OpenAPIResponse{
+				"200":     {Description: "Success"},
+				"default": {Description: `Failure, described by a JSON body {"error": {"type", "message", "field", "path"}}`},
+			},
+		}
+		for _, p := range rt.params {
+			op.Parameters = append(op.Parameters, OpenAPIParameter{
+				Name:        p.name,
+				In:          "query",
+				Required:    p.required,
+				Description: p.description,
+				Schema:      map[string]string{"type": "string"},
+			})
+		}
+		if rt.body != "" {
+			op.RequestBody = &OpenAPIRequestBody{Required: true, Content: map[string]map[string]struct{}{rt.body: {}}}
+		}
+		if s.authMiddle != nil && rt.permission != "" {
+			op.Security = []map[string][]string{{"bearerAuth": {}}}
+			op.Permission = rt.permission
+			op.Responses["401"] = OpenAPIResponse{Description: "Missing or invalid token"}
+			op.Responses["403"] = OpenAPIResponse{Description: fmt.Sprintf("Token lacks the %s permission or may not access the path", rt.permission)}
+		}
+
+		if doc.Paths[rt.path] == nil {
+			doc.Paths[rt.path] = make(map[string]OpenAPIOperation)
+		}
+		doc.Paths[rt.path][strings.ToLower(rt.method)] = op
+	}
+	return doc
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.OpenAPI()); err != nil {
+		errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("encode failed: %w", err))
+		return
+	}
+}
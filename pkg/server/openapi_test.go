@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fetchOpenAPI serves srv and returns its /openapi.json decoded generically,
+// as an integrator's tooling would see it
+func fetchOpenAPI(t *testing.T, srv *Server) map[string]interface{} {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go srv.Serve(ln)
+	defer srv.Shutdown(context.Background())
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/openapi.json")
+	if err != nil {
+		t.Fatalf("GET /openapi.json failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var doc map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("spec is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+func TestOpenAPI_ListsRoutes(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetConfig(&ServerConfig{Version: "1.2.3"})
+	doc := fetchOpenAPI(t, srv)
+
+	if doc["openapi"] != openAPIVersion {
+		t.Errorf("expected openapi %s, got %v", openAPIVersion, doc["openapi"])
+	}
+	if info, _ := doc["info"].(map[string]interface{}); info["version"] != "1.2.3" {
+		t.Errorf("expected the server version in info, got %v", doc["info"])
+	}
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	for _, want := range []string{"/upload", "/upload/stream", "/upload/status", "/upload/finalize", "/upload/abort", "/download", "/download/archive", "/list", "/list/detailed", "/search", "/stat", "/delete", "/mkdir", "/move", "/publish", "/config", "/health", "/ready", "/openapi.json"} {
+		if _, ok := paths[want]; !ok {
+			t.Errorf("expected %s in the spec", want)
+		}
+	}
+	for _, rt := range srv.routes() {
+		methods, _ := paths[rt.path].(map[string]interface{})
+		if _, ok := methods[strings.ToLower(rt.method)]; !ok {
+			t.Errorf("expected %s %s in the spec", rt.method, rt.path)
+		}
+	}
+
+	// Without authentication nothing asks for a token
+	if _, ok := paths["/auth/login"]; ok {
+		t.Error("expected no login endpoint without authentication")
+	}
+	if _, ok := doc["components"]; ok {
+		t.Error("expected no security schemes without authentication")
+	}
+}
+
+func TestOpenAPI_Auth(t *testing.T) {
+	srv, _ := newTestServer(t)
+	enableTestAuth(t, srv, "alice")
+	doc := fetchOpenAPI(t, srv)
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	for _, want := range []string{"/auth/challenge", "/auth/login"} {
+		if _, ok := paths[want]; !ok {
+			t.Errorf("expected %s in the spec", want)
+		}
+	}
+
+	del, _ := paths["/delete"].(map[string]interface{})["delete"].(map[string]interface{})
+	if del["x-permission"] != "delete" {
+		t.Errorf("expected /delete to require the delete permission, got %v", del["x-permission"])
+	}
+	if _, ok := del["security"]; !ok {
+		t.Error("expected /delete to require a bearer token")
+	}
+
+	config, _ := paths["/config"].(map[string]interface{})["get"].(map[string]interface{})
+	if _, ok := config["security"]; ok {
+		t.Error("expected /config to stay public")
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	stderrors "errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
+)
+
+// Overwrite policies decide what happens to an upload whose path already
+// holds a file, including one stored by a concurrent upload of the same path
+const (
+	OverwriteReplace = "replace" // the upload replaces the file (default)
+	OverwriteVersion = "version" // the upload is stored beside it as "name (1).ext", "name (2).ext", ...
+	OverwriteError   = "error"   // the upload is refused with 409 Conflict
+)
+
+// maxVersions bounds how many versions of one path OverwriteVersion keeps
+// numbering before refusing further uploads
+const maxVersions = 1000
+
+// errUploadConflict reports an upload refused by the overwrite policy
+var errUploadConflict = stderrors.New("file already exists")
+
+// SetOverwritePolicy sets what happens to an upload whose path already holds
+// a file: OverwriteReplace (the default, also used for ""), OverwriteVersion
+// or OverwriteError. Uploads of the same path are handled one at a time, so
+// of two clients racing to store it, the second is versioned or refused.
+func (s *Server) SetOverwritePolicy(policy string) {
+	s.overwritePolicy = policy
+}
+
+// replacesFiles reports whether uploads replace files already at their path
+func (s *Server) replacesFiles() bool {
+	return s.overwritePolicy != OverwriteVersion && s.overwritePolicy != OverwriteError
+}
+
+// uploadTarget returns where a completed upload of p is stored under the
+// overwrite policy. The caller holds p's upload lock.
+func (s *Server) uploadTarget(p string) (string, error) {
+	if s.replacesFiles() || !s.storage.Exists(p) {
+		return p, nil
+	}
+	if s.overwritePolicy == OverwriteError {
+		return "", fmt.Errorf("%w: %s", errUploadConflict, p)
+	}
+
+	for n := 1; n <= maxVersions; n++ {
+		if candidate := versionedPath(p, n); !s.storage.Exists(candidate) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s already has %d versions", errUploadConflict, p, maxVersions)
+}
+
+// checkConflict refuses a new upload of p up front under OverwriteError, so
+// the client finds out before sending the whole file. uploadTarget checks
+// again once the file is complete, in case another upload stored it first.
+func (s *Server) checkConflict(p string) error {
+	if s.overwritePolicy != OverwriteError {
+		return nil
+	}
+	if info, err := storage.Stat(s.storage, p); err == nil && !info.IsDir {
+		return fmt.Errorf("%w: %s", errUploadConflict, p)
+	}
+	return nil
+}
+
+// versionedPath returns the nth version of p: docs/report.pdf becomes
+// docs/report (n).pdf, keeping the extension so the type is still known
+func versionedPath(p string, n int) string {
+	dir, name := path.Split(p)
+	ext := path.Ext(name)
+	if ext == name {
+		// A dotfile such as .env has no extension to keep
+		ext = ""
+	}
+	return dir + fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), n, ext)
+}
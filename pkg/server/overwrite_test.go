@@ -0,0 +1,130 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+func TestVersionedPath(t *testing.T) {
+	tests := []struct {
+		path string
+		n    int
+		want string
+	}{
+		{"report.pdf", 1, "report (1).pdf"},
+		{"docs/archive.tar.gz", 2, "docs/archive.tar (2).gz"},
+		{"docs/README", 3, "docs/README (3)"},
+		{"config/.env", 1, "config/.env (1)"},
+	}
+	for _, tt := range tests {
+		if got := versionedPath(tt.path, tt.n); got != tt.want {
+			t.Errorf("versionedPath(%q, %d) = %q, want %q", tt.path, tt.n, got, tt.want)
+		}
+	}
+}
+
+// racePut uploads two different single-chunk files to path at the same time
+// and returns both contents and the status each upload got
+func racePut(t *testing.T, srv *Server, path string) ([2][]byte, [2]int) {
+	t.Helper()
+	contents := [2][]byte{bytes.Repeat([]byte("a"), 256<<10), bytes.Repeat([]byte("b"), 256<<10)}
+	var codes [2]int
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range contents {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			codes[i] = postChunk(t, srv, transport.ChunkData{Path: path, ChunkID: 0, Data: contents[i], Total: 1}).Code
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return contents, codes
+}
+
+// isOneOf reports whether data is exactly one of contents
+func isOneOf(data []byte, contents [2][]byte) bool {
+	return bytes.Equal(data, contents[0]) || bytes.Equal(data, contents[1])
+}
+
+func TestOverwritePolicy_VersionConcurrentWriters(t *testing.T) {
+	srv, store := newTestServer(t)
+	srv.SetOverwritePolicy(OverwriteVersion)
+
+	contents, codes := racePut(t, srv, "shared/notes.txt")
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK {
+		t.Fatalf("expected both uploads stored, got %v", codes)
+	}
+
+	first, err := store.Get("shared/notes.txt")
+	if err != nil || !isOneOf(first, contents) {
+		t.Fatalf("expected one writer's complete file, got %d bytes (%v)", len(first), err)
+	}
+	second, err := store.Get("shared/notes (1).txt")
+	if err != nil || !isOneOf(second, contents) {
+		t.Fatalf("expected the other writer's complete file as a version, got %d bytes (%v)", len(second), err)
+	}
+	if bytes.Equal(first, second) {
+		t.Error("expected each writer's content kept once")
+	}
+}
+
+func TestOverwritePolicy_ErrorConcurrentWriters(t *testing.T) {
+	srv, store := newTestServer(t)
+	srv.SetOverwritePolicy(OverwriteError)
+
+	contents, codes := racePut(t, srv, "shared/notes.txt")
+	if !(codes[0] == http.StatusOK && codes[1] == http.StatusConflict) && !(codes[0] == http.StatusConflict && codes[1] == http.StatusOK) {
+		t.Fatalf("expected one upload stored and the other refused with 409, got %v", codes)
+	}
+
+	data, err := store.Get("shared/notes.txt")
+	if err != nil || !isOneOf(data, contents) {
+		t.Errorf("expected the winner's complete file, got %d bytes (%v)", len(data), err)
+	}
+	if store.Exists("shared/notes (1).txt") {
+		t.Error("expected no version stored")
+	}
+	if uploadLeft(srv, "shared/notes.txt") {
+		t.Error("expected the refused upload discarded")
+	}
+}
+
+func TestOverwritePolicy_ErrorRefusedUpFront(t *testing.T) {
+	srv, store := newTestServer(t)
+	srv.SetOverwritePolicy(OverwriteError)
+	store.Put("big.iso", []byte("existing"))
+
+	rec := postChunk(t, srv, transport.ChunkData{Path: "big.iso", ChunkID: 0, Data: []byte("aaaa"), Total: 3})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 on the first chunk, got %d", rec.Code)
+	}
+	if uploadLeft(srv, "big.iso") {
+		t.Error("expected no session started for a refused upload")
+	}
+	if data, _ := store.Get("big.iso"); string(data) != "existing" {
+		t.Errorf("expected the existing file untouched, got %q", data)
+	}
+}
+
+func TestOverwritePolicy_ReplaceByDefault(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("notes.txt", []byte("old"))
+
+	if rec := postChunk(t, srv, transport.ChunkData{Path: "notes.txt", ChunkID: 0, Data: []byte("new"), Total: 1}); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if data, _ := store.Get("notes.txt"); string(data) != "new" {
+		t.Errorf("expected the file replaced, got %q", data)
+	}
+	if store.Exists("notes (1).txt") {
+		t.Error("expected no version stored")
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import "sync"
+
+// pathLocks hands out one mutex per upload session, so chunks for different
+// files are stored in parallel while chunks for the same file are serialized.
+// A session's mutex is dropped once nobody holds or waits for it.
+type pathLocks struct {
+	mu    sync.Mutex
+	locks map[string]*pathLock
+}
+
+// pathLock is a session mutex and the number of requests holding or waiting for it
+type pathLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newPathLocks() *pathLocks {
+	return &pathLocks{locks: make(map[string]*pathLock)}
+}
+
+// lock blocks until the mutex for key is held and returns a function that releases it
+func (l *pathLocks) lock(key string) (unlock func()) {
+	l.mu.Lock()
+	pl, ok := l.locks[key]
+	if !ok {
+		pl = &pathLock{}
+		l.locks[key] = pl
+	}
+	pl.refs++
+	l.mu.Unlock()
+
+	pl.Lock()
+	return func() {
+		pl.Unlock()
+
+		l.mu.Lock()
+		pl.refs--
+		if pl.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
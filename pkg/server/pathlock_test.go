@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+func TestStoreChunk_DistinctPathsDontBlock(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	// Hold a.bin's session lock as if a slow chunk write were in progress
+	unlock := srv.uploadLocks.lock(sessionKey("a.bin"))
+
+	other := make(chan int, 1)
+	go func() {
+		other <- postChunk(t, srv, transport.ChunkData{Path: "b.bin", ChunkID: 0, Data: []byte("bbb"), Total: 1}).Code
+	}()
+	select {
+	case code := <-other:
+		if code != http.StatusOK {
+			t.Fatalf("expected 200 for b.bin, got %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("upload to b.bin blocked on a.bin's lock")
+	}
+
+	// A chunk for the locked session waits its turn
+	same := make(chan int, 1)
+	go func() {
+		same <- postChunk(t, srv, transport.ChunkData{Path: "a.bin", ChunkID: 0, Data: []byte("aaa"), Total: 1}).Code
+	}()
+	select {
+	case <-same:
+		t.Fatal("upload to a.bin finished while its session was locked")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	if code := <-same; code != http.StatusOK {
+		t.Fatalf("expected 200 for a.bin once unlocked, got %d", code)
+	}
+	if !store.Exists("a.bin") || !store.Exists("b.bin") {
+		t.Error("expected both files to be stored")
+	}
+}
+
+func TestStoreChunk_ConcurrentChunksSameFile(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	const total = 16
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			postChunk(t, srv, transport.ChunkData{Path: "same.bin", ChunkID: id, Data: []byte{byte('a' + id)}, Total: total})
+		}(i)
+	}
+	wg.Wait()
+
+	got, err := store.Get("same.bin")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(got) != "abcdefghijklmnop" {
+		t.Errorf("unexpected assembled content: %q", got)
+	}
+	if n := len(srv.uploadLocks.locks); n != 0 {
+		t.Errorf("expected session locks to be released, %d remain", n)
+	}
+}
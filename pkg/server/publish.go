@@ -0,0 +1,81 @@
+package server
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
+)
+
+// StagingDir is the hidden top-level directory for staged uploads. Files
+// uploaded to StagingDir/<name>/... stay out of sight until /publish moves
+// the whole <name> tree into place in one step.
+const StagingDir = ".staging"
+
+// stagedPath returns the storage path of the staged tree name, or "" if name
+// doesn't identify a tree inside StagingDir
+func stagedPath(name string) string {
+	name = strings.Trim(path.Clean("/"+normalizePath(name)), "/")
+	if name == "" {
+		return ""
+	}
+	return path.Join(StagingDir, name)
+}
+
+// inTree reports whether p is root or lies beneath it
+func inTree(p, root string) bool {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	return p == root || strings.HasPrefix(p, root+"/")
+}
+
+// handlePublish moves a staged tree to its destination with a single rename,
+// so its files appear together or not at all
+func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
+	src := stagedPath(r.URL.Query().Get("src"))
+	if src == "" {
+		writeJSONError(w, errors.NewValidationError("src", "src parameter required"))
+		return
+	}
+	dst := strings.Trim(path.Clean("/"+normalizePath(r.URL.Query().Get("dst"))), "/")
+	if dst == "" {
+		writeJSONError(w, errors.NewValidationError("dst", "dst parameter required"))
+		return
+	}
+	if inTree(dst, StagingDir) {
+		errors.WriteJSON(w, http.StatusBadRequest, fmt.Errorf("cannot publish into %s", StagingDir))
+		return
+	}
+	if !s.allowPath(w, r, src) || !s.allowPath(w, r, dst) {
+		return
+	}
+
+	// Publishing while files are still arriving would expose a partial tree
+	for _, session := range s.sessionStore.Sessions() {
+		if !session.Completed && inTree(session.Path, src) {
+			errors.WriteJSON(w, http.StatusConflict, fmt.Errorf("publish failed: upload of %s is still in progress", session.Path))
+			return
+		}
+	}
+
+	renamer, ok := s.storage.(storage.Renamer)
+	if !ok {
+		errors.WriteJSON(w, http.StatusNotImplemented, stderrors.New("publish failed: storage backend cannot rename"))
+		return
+	}
+	if err := renamer.Rename(src, dst); err != nil {
+		status := errors.HTTPStatus(err)
+		if stderrors.Is(err, stderrors.ErrUnsupported) {
+			status = http.StatusNotImplemented
+		}
+		errors.WriteJSON(w, status, fmt.Errorf("publish failed: %w", err))
+		return
+	}
+	s.hashes.rename(src, dst)
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Successfully published %s to %s", src, dst)
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// publish calls the publish handler to move staged tree src to dst
+func publish(srv *Server, src, dst string) int {
+	query := url.Values{"src": {src}, "dst": {dst}}
+	req := httptest.NewRequest(http.MethodPost, "/publish?"+query.Encode(), nil)
+	rec := httptest.NewRecorder()
+	srv.handlePublish(rec, req)
+	return rec.Code
+}
+
+func TestHandlePublish_StagedTreeAppearsTogether(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	postChunk(t, srv, transport.ChunkData{Path: ".staging/release/index.html", ChunkID: 0, Data: []byte("<html>"), Total: 1})
+	postChunk(t, srv, transport.ChunkData{Path: ".staging/release/assets/app.js", ChunkID: 0, Data: []byte("app"), Total: 2})
+
+	// Nothing staged is visible at the target or in the root listing
+	if store.Exists("site") {
+		t.Fatal("expected staged files not to appear at the target")
+	}
+	var names []string
+	json.Unmarshal(listPath(srv, "/").Body.Bytes(), &names)
+	for _, name := range names {
+		if name == StagingDir {
+			t.Errorf("expected %s to be hidden from the root listing, got %v", StagingDir, names)
+		}
+	}
+
+	// A tree with an unfinished upload can't be published
+	if code := publish(srv, "release", "site"); code != http.StatusConflict {
+		t.Fatalf("expected 409 while an upload is in progress, got %d", code)
+	}
+	if store.Exists("site") {
+		t.Fatal("expected a refused publish to leave the target untouched")
+	}
+
+	postChunk(t, srv, transport.ChunkData{Path: ".staging/release/assets/app.js", ChunkID: 1, Data: []byte(".js"), Total: 2})
+	if code := publish(srv, "release", "site"); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	for path, want := range map[string]string{"site/index.html": "<html>", "site/assets/app.js": "app.js"} {
+		if data, err := store.Get(path); err != nil || string(data) != want {
+			t.Errorf("%s: expected %q, got %q (%v)", path, want, data, err)
+		}
+	}
+	if store.Exists(".staging/release") {
+		t.Error("expected the staged tree to be moved")
+	}
+}
+
+func TestHandlePublish_ExistingTargetUntouched(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("site/index.html", []byte("live"))
+	store.Put(".staging/next/index.html", []byte("next"))
+
+	if code := publish(srv, "next", "site"); code != http.StatusConflict {
+		t.Fatalf("expected 409 for an existing target, got %d", code)
+	}
+	if data, _ := store.Get("site/index.html"); string(data) != "live" {
+		t.Errorf("expected live site untouched, got %q", data)
+	}
+	if !store.Exists(".staging/next/index.html") {
+		t.Error("expected staged tree to remain")
+	}
+}
+
+func TestHandlePublish_InvalidPaths(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("outside.txt", []byte("x"))
+
+	tests := []struct {
+		src, dst string
+		code     int
+	}{
+		{"", "site", http.StatusBadRequest},
+		{"next", "", http.StatusBadRequest},
+		{"next", ".staging/other", http.StatusBadRequest},
+		{"../outside.txt", "moved.txt", http.StatusNotFound}, // stays inside the staging directory
+		{"missing", "site", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if code := publish(srv, tt.src, tt.dst); code != tt.code {
+			t.Errorf("src=%q dst=%q: expected %d, got %d", tt.src, tt.dst, tt.code, code)
+		}
+	}
+	if !store.Exists("outside.txt") {
+		t.Error("expected files outside the staging directory not to be moved")
+	}
+}
+
+func TestHandlePublish_UnsupportedBackend(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put(".staging/next/a.txt", []byte("a"))
+	srv.storage = storage.NewInstrumented(storage.NewRouter(store), 0)
+
+	if code := publish(srv, "next", "site"); code != http.StatusNotImplemented {
+		t.Errorf("expected 501, got %d", code)
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// errUnsatisfiableRange is returned when a Range header cannot be served
+var errUnsatisfiableRange = errors.New("range not satisfiable")
+
+// errMalformedRange reports a range spec that isn't valid syntax, which
+// makes the whole Range header ignored
+var errMalformedRange = errors.New("malformed range")
+
+// maxRangeSpecs bounds the number of ranges accepted in a single header
+const maxRangeSpecs = 16
+
+// byteRange is a resolved, inclusive-exclusive span of a file
+type byteRange struct {
+	start  int64
+	length int64
+}
+
+// contentRange formats the Content-Range header value for the range
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+// parseRange parses an HTTP Range header against a file of the given size.
+// It returns nil with no error when the whole file should be served: when the
+// header is absent, malformed, uses a unit other than bytes, names more than
+// maxRangeSpecs ranges, or names several disjoint ranges (multipart responses
+// are not supported). Ranges that start past the end of the file are
+// dropped, and the rest are coalesced if they overlap or touch. As RFC 9110
+// says, errUnsatisfiableRange is returned only when no range is left.
+func parseRange(header string, size int64) (*byteRange, error) {
+	if header == "" {
+		return nil, nil
+	}
+
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return nil, nil
+	}
+
+	specs := strings.Split(strings.TrimPrefix(header, prefix), ",")
+	if len(specs) > maxRangeSpecs {
+		return nil, nil
+	}
+
+	var ranges []byteRange
+	for _, spec := range specs {
+		r, err := parseRangeSpec(strings.TrimSpace(spec), size)
+		if err == errMalformedRange {
+			return nil, nil
+		}
+		if err == nil {
+			ranges = append(ranges, r)
+		}
+	}
+	if len(ranges) == 0 {
+		return nil, errUnsatisfiableRange
+	}
+
+	ranges = coalesceRanges(ranges)
+	if len(ranges) != 1 {
+		return nil, nil
+	}
+
+	return &ranges[0], nil
+}
+
+// parseRangeSpec parses a single "start-end", "start-" or "-suffix" spec. It
+// returns errMalformedRange for a spec that isn't valid syntax and
+// errUnsatisfiableRange for one that selects nothing of the file.
+func parseRangeSpec(spec string, size int64) (byteRange, error) {
+	startStr, endStr, ok := strings.Cut(spec, "-")
+	if !ok {
+		return byteRange{}, errMalformedRange
+	}
+	startStr = strings.TrimSpace(startStr)
+	endStr = strings.TrimSpace(endStr)
+
+	// Suffix range: the last N bytes
+	if startStr == "" {
+		n, err := parseRangeInt(endStr)
+		if err != nil {
+			return byteRange{}, errMalformedRange
+		}
+		if n == 0 || size == 0 {
+			return byteRange{}, errUnsatisfiableRange
+		}
+		if n > size {
+			n = size
+		}
+		return byteRange{start: size - n, length: n}, nil
+	}
+
+	start, err := parseRangeInt(startStr)
+	if err != nil {
+		return byteRange{}, errMalformedRange
+	}
+
+	end := size - 1
+	if endStr != "" {
+		end, err = parseRangeInt(endStr)
+		if err != nil || end < start {
+			return byteRange{}, errMalformedRange
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return byteRange{}, errUnsatisfiableRange
+	}
+
+	return byteRange{start: start, length: end - start + 1}, nil
+}
+
+// parseRangeInt parses a non-negative decimal position
+func parseRangeInt(s string) (int64, error) {
+	if s == "" || strings.ContainsAny(s, "+-") {
+		return 0, errMalformedRange
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// coalesceRanges merges overlapping and adjacent ranges
+func coalesceRanges(ranges []byteRange) []byteRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.start <= last.start+last.length {
+			if end := r.start + r.length; end > last.start+last.length {
+				last.length = end - last.start
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
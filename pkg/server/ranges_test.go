@@ -0,0 +1,229 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		size      int64
+		wantStart int64
+		wantLen   int64
+		wantFull  bool
+		wantErr   bool
+	}{
+		{name: "no header", header: "", size: 100, wantFull: true},
+		{name: "other unit", header: "items=0-5", size: 100, wantFull: true},
+		{name: "single range", header: "bytes=10-19", size: 100, wantStart: 10, wantLen: 10},
+		{name: "open-ended range", header: "bytes=90-", size: 100, wantStart: 90, wantLen: 10},
+		{name: "end past size is clamped", header: "bytes=95-500", size: 100, wantStart: 95, wantLen: 5},
+		{name: "suffix range", header: "bytes=-30", size: 100, wantStart: 70, wantLen: 30},
+		{name: "suffix larger than file", header: "bytes=-500", size: 100, wantStart: 0, wantLen: 100},
+		{name: "overlapping ranges coalesce", header: "bytes=0-10,5-20", size: 100, wantStart: 0, wantLen: 21},
+		{name: "adjacent ranges coalesce", header: "bytes=0-9,10-19", size: 100, wantStart: 0, wantLen: 20},
+		{name: "disjoint ranges serve full file", header: "bytes=0-9,50-59", size: 100, wantFull: true},
+		{name: "unsatisfiable ranges are dropped", header: "bytes=500-600,10-19", size: 100, wantStart: 10, wantLen: 10},
+		{name: "start past size", header: "bytes=100-", size: 100, wantErr: true},
+		{name: "zero suffix", header: "bytes=-0", size: 100, wantErr: true},
+		{name: "empty file", header: "bytes=0-", size: 0, wantErr: true},
+		{name: "end before start is ignored", header: "bytes=20-10", size: 100, wantFull: true},
+		{name: "negative start is ignored", header: "bytes=-5-10", size: 100, wantFull: true},
+		{name: "garbage is ignored", header: "bytes=abc", size: 100, wantFull: true},
+		{name: "one malformed spec ignores all", header: "bytes=0-9,x", size: 100, wantFull: true},
+		{name: "too many ranges are ignored", header: "bytes=" + strings.Repeat("0-1,", maxRangeSpecs) + "0-1", size: 100, wantFull: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng, err := parseRange(tt.header, tt.size)
+			if tt.wantErr {
+				if err != errUnsatisfiableRange {
+					t.Fatalf("expected errUnsatisfiableRange, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantFull {
+				if rng != nil {
+					t.Errorf("expected full file, got range %+v", *rng)
+				}
+				return
+			}
+			if rng == nil {
+				t.Fatal("expected a range, got full file")
+			}
+			if rng.start != tt.wantStart || rng.length != tt.wantLen {
+				t.Errorf("expected start=%d len=%d, got start=%d len=%d", tt.wantStart, tt.wantLen, rng.start, rng.length)
+			}
+		})
+	}
+}
+
+// getWithRange requests a file from the download handler with the given Range header
+func getWithRange(srv *Server, path, rangeHeader string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/download?path="+path, nil)
+	if rangeHeader != "" {
+		req.Header.Set("Range", rangeHeader)
+	}
+	rec := httptest.NewRecorder()
+	srv.handleDownload(rec, req)
+	return rec
+}
+
+func TestHandleDownload_Ranges(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	content := bytes.Repeat([]byte("0123456789"), 100) // 1000 bytes
+	if err := store.Put("file.bin", content); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	t.Run("valid single range", func(t *testing.T) {
+		rec := getWithRange(srv, "file.bin", "bytes=100-199")
+		if rec.Code != http.StatusPartialContent {
+			t.Fatalf("expected 206, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("Content-Range"); got != "bytes 100-199/1000" {
+			t.Errorf("unexpected Content-Range: %s", got)
+		}
+		if !bytes.Equal(rec.Body.Bytes(), content[100:200]) {
+			t.Error("range body mismatch")
+		}
+	})
+
+	t.Run("suffix range", func(t *testing.T) {
+		rec := getWithRange(srv, "file.bin", "bytes=-500")
+		if rec.Code != http.StatusPartialContent {
+			t.Fatalf("expected 206, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("Content-Range"); got != "bytes 500-999/1000" {
+			t.Errorf("unexpected Content-Range: %s", got)
+		}
+		if !bytes.Equal(rec.Body.Bytes(), content[500:]) {
+			t.Error("suffix body mismatch")
+		}
+	})
+
+	t.Run("unsatisfiable range", func(t *testing.T) {
+		rec := getWithRange(srv, "file.bin", "bytes=5000-6000")
+		if rec.Code != http.StatusRequestedRangeNotSatisfiable {
+			t.Fatalf("expected 416, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("Content-Range"); got != "bytes */1000" {
+			t.Errorf("unexpected Content-Range: %s", got)
+		}
+	})
+
+	t.Run("malformed range serves full file", func(t *testing.T) {
+		rec := getWithRange(srv, "file.bin", "bytes=200-100")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		if !bytes.Equal(rec.Body.Bytes(), content) {
+			t.Error("full body mismatch")
+		}
+	})
+
+	t.Run("multi-range serves full file", func(t *testing.T) {
+		rec := getWithRange(srv, "file.bin", "bytes=0-9,500-509")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		if !bytes.Equal(rec.Body.Bytes(), content) {
+			t.Error("full body mismatch")
+		}
+	})
+
+	t.Run("no range", func(t *testing.T) {
+		rec := getWithRange(srv, "file.bin", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		if rec.Header().Get("Accept-Ranges") != "bytes" {
+			t.Error("expected Accept-Ranges: bytes")
+		}
+		if rec.Body.Len() != len(content) {
+			t.Errorf("expected %d bytes, got %d", len(content), rec.Body.Len())
+		}
+	})
+}
+
+// countingStorage records full-file reads made through Get
+type countingStorage struct {
+	*storage.Local
+	gets int
+}
+
+func (c *countingStorage) Get(path string) ([]byte, error) {
+	c.gets++
+	return c.Local.Get(path)
+}
+
+func TestHandleDownload_RangeSeeksInsteadOfLoading(t *testing.T) {
+	srv, local := newTestServer(t)
+	counting := &countingStorage{Local: local}
+	srv.storage = counting
+
+	content := bytes.Repeat([]byte("abcdefghij"), 100)
+	local.Put("seek.bin", content)
+
+	rec := getWithRange(srv, "seek.bin", "bytes=900-")
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("expected 206, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Range"); got != "bytes 900-999/1000" {
+		t.Errorf("unexpected Content-Range: %s", got)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), content[900:]) {
+		t.Error("open-ended range body mismatch")
+	}
+	if counting.gets != 0 {
+		t.Errorf("expected ranged download to avoid reading the whole file, got %d Get calls", counting.gets)
+	}
+
+	rec = getWithRange(srv, "seek.bin", "bytes=2000-")
+	if rec.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("expected 416, got %d", rec.Code)
+	}
+
+	rec = getWithRange(srv, "missing.bin", "bytes=0-10")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for missing file, got %d", rec.Code)
+	}
+}
+
+func TestHandleDownload_RangeOfCompressedFile(t *testing.T) {
+	srv, local := newTestServer(t)
+	srv.storage = storage.NewInstrumented(storage.NewCompressed(local, 100), 0)
+
+	content := bytes.Repeat([]byte("compressible "), 100) // 1300 bytes
+	if err := srv.storage.Put("packed.txt", content); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	rec := getWithRange(srv, "packed.txt", "bytes=250-549")
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("expected 206, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Range"); got != "bytes 250-549/1300" {
+		t.Errorf("unexpected Content-Range: %s", got)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), content[250:550]) {
+		t.Errorf("range body mismatch: %q", rec.Body.String())
+	}
+
+	rec = getWithRange(srv, "packed.txt", "")
+	if !bytes.Equal(rec.Body.Bytes(), content) {
+		t.Error("full body mismatch")
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// reconcileSessions repairs upload state left inconsistent by a crash. Chunk
+// directories that no session refers to are deleted, and chunks a session
+// records as received but that are no longer on disk are marked missing so
+// the client sends them again.
+func (s *Server) reconcileSessions() {
+	sessions := s.sessionStore.Sessions()
+	known := make(map[string]bool, len(sessions))
+	for _, session := range sessions {
+		known[sessionKey(session.Path)] = true
+	}
+
+	entries, err := os.ReadDir(s.chunksDir)
+	if err != nil {
+		fmt.Printf("Warning: failed to read chunks directory: %v\n", err)
+		return
+	}
+
+	orphans := 0
+	for _, entry := range entries {
+		if known[entry.Name()] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(s.chunksDir, entry.Name())); err != nil {
+			fmt.Printf("Warning: failed to remove orphaned chunks %s: %v\n", entry.Name(), err)
+			continue
+		}
+		orphans++
+	}
+
+	repaired := 0
+	for _, session := range sessions {
+		dir := s.sessionChunksDir(session.Path)
+		lost := 0
+		for chunkID, received := range session.ReceivedMap {
+			if !received {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(dir, fmt.Sprintf("chunk_%06d.dat", chunkID))); err == nil {
+				continue
+			}
+			if err := s.sessionStore.MarkChunkMissing(session.Path, chunkID); err != nil {
+				fmt.Printf("Warning: failed to repair session for %s: %v\n", session.Path, err)
+				break
+			}
+			lost++
+		}
+		if lost > 0 {
+			fmt.Printf("Upload of %s lost %d chunks; they will be requested again\n", session.Path, lost)
+			repaired++
+		}
+	}
+
+	if orphans > 0 || repaired > 0 {
+		fmt.Printf("Reconciled uploads: removed %d orphaned chunk directories, repaired %d sessions\n", orphans, repaired)
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// restartServer creates a new server over the same storage and metadata
+// directory as srv, as after a crash
+func restartServer(t *testing.T, srv *Server, store *storage.Local) *Server {
+	t.Helper()
+
+	if err := srv.sessionStore.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	restarted, err := New(store, filepath.Dir(srv.chunksDir))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return restarted
+}
+
+func TestReconcile_RemovesOrphanedChunkDirs(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	rec := postChunk(t, srv, transport.ChunkData{Path: "kept.bin", ChunkID: 0, Data: []byte("aaaa"), Total: 2})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Chunks whose session was lost, and a temp file from an older server
+	orphan := filepath.Join(srv.chunksDir, sessionKey("lost.bin"))
+	os.MkdirAll(orphan, 0755)
+	os.WriteFile(filepath.Join(orphan, "chunk_000000.dat"), []byte("bbbb"), 0644)
+	stray := filepath.Join(srv.chunksDir, "temp_lost.bin")
+	os.WriteFile(stray, []byte("bbbb"), 0644)
+
+	srv = restartServer(t, srv, store)
+
+	for _, path := range []string{orphan, stray} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", filepath.Base(path))
+		}
+	}
+	if _, err := os.Stat(filepath.Join(srv.sessionChunksDir("kept.bin"), "chunk_000000.dat")); err != nil {
+		t.Errorf("expected chunks of a live session to be kept: %v", err)
+	}
+	if missing, _ := srv.sessionStore.GetMissingChunks("kept.bin"); !reflect.DeepEqual(missing, []int{1}) {
+		t.Errorf("expected only chunk 1 missing, got %v", missing)
+	}
+}
+
+func TestReconcile_RepairsSessionsWithLostChunks(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	for id := 0; id < 2; id++ {
+		rec := postChunk(t, srv, transport.ChunkData{Path: "partial.bin", ChunkID: id, Data: []byte("aaaa"), Total: 3})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("chunk %d: expected 200, got %d: %s", id, rec.Code, rec.Body.String())
+		}
+	}
+
+	// A chunk the session counts as received is gone from disk
+	os.Remove(filepath.Join(srv.sessionChunksDir("partial.bin"), "chunk_000001.dat"))
+
+	srv = restartServer(t, srv, store)
+
+	missing, err := srv.sessionStore.GetMissingChunks("partial.bin")
+	if err != nil {
+		t.Fatalf("GetMissingChunks failed: %v", err)
+	}
+	if !reflect.DeepEqual(missing, []int{1, 2}) {
+		t.Errorf("expected chunks 1 and 2 missing, got %v", missing)
+	}
+
+	// The upload completes once the client resends what's missing
+	for _, id := range missing {
+		rec := postChunk(t, srv, transport.ChunkData{Path: "partial.bin", ChunkID: id, Data: []byte("aaaa"), Total: 3})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("chunk %d: expected 200, got %d: %s", id, rec.Code, rec.Body.String())
+		}
+	}
+	if data, err := store.Get("partial.bin"); err != nil || len(data) != 12 {
+		t.Errorf("expected file to be assembled, got %d bytes (%v)", len(data), err)
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strings"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+// route describes one endpoint. Serve builds its mux from the route table
+// and /openapi.json describes the same table, so the two can't drift apart.
+// Handlers can rely on the request using the route's method and, when
+// authentication is enabled, carrying a token with the route's permission.
+type route struct {
+	path       string
+	method     string
+	permission string // permission a token needs ("" for public endpoints)
+	summary    string
+	params     []routeParam // query parameters
+	body       string       // request body content type ("" for none)
+	handler    http.HandlerFunc
+}
+
+// routeParam describes a query parameter of a route
+type routeParam struct {
+	name        string
+	required    bool
+	description string
+}
+
+// pathParam is the path query parameter most endpoints take
+var pathParam = routeParam{name: "path", required: true, description: "Remote path"}
+
+// routes returns every endpoint the server serves with its current settings
+func (s *Server) routes() []route {
+	routes := []route{
+		{path: "/config", method: http.MethodGet, summary: "Server configuration for client setup", handler: s.handleConfig},
+		{path: "/version.json", method: http.MethodGet, summary: "Signed update manifest for gfl update", handler: s.handleUpdateManifest},
+		{path: "/health", method: http.MethodGet, summary: "Liveness: 200 while the process is serving", handler: s.handleHealth},
+		{path: "/ready", method: http.MethodGet, summary: "Readiness: 503 while overloaded or storage is unavailable", handler: s.handleReady},
+		{path: "/openapi.json", method: http.MethodGet, summary: "OpenAPI description of this server", handler: s.handleOpenAPI},
+
+		{path: "/metrics", method: http.MethodGet, permission: "metrics", summary: "Storage operation metrics in Prometheus text format", handler: s.handleMetrics},
+		{path: "/upload", method: http.MethodPost, permission: "upload", summary: "Upload a file chunk as JSON", body: "application/json", handler: s.handleUpload},
+		{path: "/upload/stream", method: http.MethodPost, permission: "upload", summary: "Upload a file chunk as multipart/form-data", body: "multipart/form-data", handler: s.handleUploadStream},
+		{path: "/upload/status", method: http.MethodGet, permission: "upload", summary: "Check which chunks of an upload have arrived", handler: s.handleUploadStatus, params: []routeParam{
+			pathParam,
+			{name: "checksums", description: "Set to true to list the SHA-256 of each received chunk"},
+		}},
+		{path: "/upload/finalize", method: http.MethodPost, permission: "upload", summary: "Reassemble an upload sent with defer_finalize", params: []routeParam{pathParam}, handler: s.handleUploadFinalize},
+		{path: "/upload/abort", method: http.MethodPost, permission: "upload", summary: "Discard an unfinished upload", params: []routeParam{pathParam}, handler: s.handleUploadAbort},
+		{path: "/download", method: http.MethodGet, permission: "download", summary: "Download a file", handler: s.handleDownload, params: []routeParam{
+			{name: "path", description: "Remote path"},
+			{name: "hash", description: "SHA-256 of the content to download, instead of a path"},
+		}},
+		{path: "/download/archive", method: http.MethodGet, permission: "download", summary: "Download a directory as a zip or tar.gz archive", handler: s.handleDownloadArchive, params: []routeParam{
+			{name: "path", description: "Remote directory"},
+			{name: "format", description: "Archive format: zip (default) or tar.gz"},
+		}},
+		{path: "/list", method: http.MethodGet, permission: "list", summary: "List a directory", handler: s.handleList, params: []routeParam{
+			{name: "path", description: "Remote directory (default the storage root)"},
+		}},
+		{path: "/list/detailed", method: http.MethodGet, permission: "list", summary: "List a directory with sizes and times", handler: s.handleListDetailed, params: []routeParam{
+			{name: "path", description: "Remote directory (default the storage root)"},
+		}},
+		{path: "/search", method: http.MethodGet, permission: "list", summary: "Find files and directories by name", handler: s.handleSearch, params: []routeParam{
+			{name: "path", description: "Remote directory to search beneath (default the storage root)"},
+			{name: "q", description: "Glob pattern, or a substring of the name"},
+			{name: "limit", description: "Most matches to return (at most 1000)"},
+			{name: "depth", description: "Most levels of directories to search (at most 32)"},
+		}},
+		{path: "/stat", method: http.MethodGet, permission: "list", summary: "Describe a file or directory", params: []routeParam{pathParam}, handler: s.handleStat},
+		{path: "/delete", method: http.MethodDelete, permission: "delete", summary: "Delete a file or directory", params: []routeParam{pathParam}, handler: s.handleDelete},
+		{path: "/mkdir", method: http.MethodPost, permission: "write", summary: "Create a directory", handler: s.handleMkdir, params: []routeParam{
+			pathParam,
+			{name: "parents", description: "Set to false to require the parent directory to exist"},
+		}},
+		{path: "/move", method: http.MethodPost, permission: "write", summary: "Rename or move a file or directory", handler: s.handleMove, params: []routeParam{
+			{name: "src", required: true, description: "Remote path to move"},
+			{name: "dst", required: true, description: "Remote path to move it to"},
+			{name: "overwrite", description: "Set to true to replace an existing file at dst"},
+		}},
+		{path: "/publish", method: http.MethodPost, permission: "upload", summary: "Move a staged tree into place in one step", handler: s.handlePublish, params: []routeParam{
+			{name: "src", required: true, description: "Staged tree, relative to the staging directory"},
+			{name: "dst", required: true, description: "Remote path to publish it at"},
+		}},
+	}
+
+	// Tokens are obtained without one
+	if s.authMiddle != nil {
+		routes = append(routes,
+			route{path: "/auth/challenge", method: http.MethodGet, summary: "Get a nonce for challenge-response authentication", handler: s.authMiddle.HandleChallenge},
+			route{path: "/auth/login", method: http.MethodPost, summary: "Exchange a username and password for a token", body: "application/json", handler: s.authMiddle.HandleLogin},
+		)
+	}
+	return routes
+}
+
+// handler builds the server's request handler from the route table
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, rt := range s.routes() {
+		mux.Handle(rt.path, s.routeHandler(rt))
+	}
+	return s.withResponseHeaders(mux)
+}
+
+// routeHandler wraps a route's handler in the checks its declaration asks
+// for. The method is checked first, so a wrong method never counts as a
+// failed authentication attempt.
+func (s *Server) routeHandler(rt route) http.Handler {
+	handler := rt.handler
+	if s.authMiddle != nil && rt.permission != "" {
+		handler = s.authMiddle.RequireAuth(rt.permission, handler)
+	}
+	return allowMethods(handler, rt.method)
+}
+
+// allowMethods answers 405 Method Not Allowed to requests using none of
+// methods. GET routes answer HEAD too.
+func allowMethods(next http.HandlerFunc, methods ...string) http.HandlerFunc {
+	for _, method := range methods {
+		if method == http.MethodGet {
+			methods = append(methods, http.MethodHead)
+			break
+		}
+	}
+	allow := strings.Join(methods, ", ")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, method := range methods {
+			if r.Method == method {
+				next(w, r)
+				return
+			}
+		}
+		w.Header().Set("Allow", allow)
+		errors.WriteJSON(w, http.StatusMethodNotAllowed, stderrors.New("method not allowed"))
+	}
+}
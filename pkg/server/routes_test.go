@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// serveRoute sends a request through the server's handler, with token as the
+// bearer token if it is set
+func serveRoute(handler http.Handler, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRoutes_GuardedByPermission(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetConfig(&ServerConfig{})
+	srv.SetAuthFailureLimit(0, 0)
+
+	// A token for each permission, plus one holding none of them
+	permissions := map[string][]string{"nothing": {"none"}}
+	for _, rt := range srv.routes() {
+		if rt.permission != "" {
+			permissions[rt.permission] = []string{rt.permission}
+		}
+	}
+	enableTestAuthPermissions(t, srv, permissions)
+	handler := srv.handler()
+
+	for _, rt := range srv.routes() {
+		if rt.permission == "" {
+			// Public routes answer without a token
+			if rec := serveRoute(handler, rt.method, rt.path, ""); rec.Code == http.StatusUnauthorized || rec.Code == http.StatusForbidden {
+				t.Errorf("%s %s: expected a public route, got %d", rt.method, rt.path, rec.Code)
+			}
+			continue
+		}
+
+		if rec := serveRoute(handler, rt.method, rt.path, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without a token: expected 401, got %d", rt.method, rt.path, rec.Code)
+		}
+		if rec := serveRoute(handler, rt.method, rt.path, "nothing"); rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s without the %s permission: expected 403, got %d", rt.method, rt.path, rt.permission, rec.Code)
+		}
+
+		// The handler itself is reached, even if it rejects the empty request
+		rec := serveRoute(handler, rt.method, rt.path, rt.permission)
+		if rec.Code == http.StatusUnauthorized || rec.Code == http.StatusForbidden || rec.Code == http.StatusMethodNotAllowed {
+			t.Errorf("%s %s with the %s permission: expected to reach the handler, got %d: %s", rt.method, rt.path, rt.permission, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestRoutes_Reachable(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetConfig(&ServerConfig{})
+	handler := srv.handler()
+
+	for _, rt := range srv.routes() {
+		rec := serveRoute(handler, rt.method, rt.path, "")
+		// The mux answers unregistered paths with its own 404 page
+		if rec.Code == http.StatusMethodNotAllowed || rec.Body.String() == "404 page not found\n" {
+			t.Errorf("%s %s: expected the route to be registered, got %d", rt.method, rt.path, rec.Code)
+		}
+	}
+}
+
+func TestRoutes_MethodChecked(t *testing.T) {
+	srv, _ := newTestServer(t)
+	enableTestAuth(t, srv, "alice")
+	handler := srv.handler()
+
+	// Wrong methods are refused before authentication
+	rec := serveRoute(handler, http.MethodGet, "/delete?path=x", "")
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rec.Code)
+	}
+	if allow := rec.Header().Get("Allow"); allow != http.MethodDelete {
+		t.Errorf("expected Allow: DELETE, got %q", allow)
+	}
+
+	rec = serveRoute(handler, http.MethodPost, "/config", "")
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rec.Code)
+	}
+	if allow := rec.Header().Get("Allow"); allow != "GET, HEAD" {
+		t.Errorf("expected Allow: GET, HEAD, got %q", allow)
+	}
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/auth"
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
+)
+
+const (
+	// maxSearchResults caps the matches one search returns; clients may
+	// ask for fewer with limit
+	maxSearchResults = 1000
+	// maxSearchDepth caps how many levels of directories a search looks
+	// through, the base being the first; clients may ask for fewer with depth
+	maxSearchDepth = 32
+)
+
+// SearchResponse is returned by /search
+type SearchResponse struct {
+	Matches   []ListEntry `json:"matches"`   // Name is the path relative to the search base
+	Truncated bool        `json:"truncated"` // the result or depth limit stopped the search early
+}
+
+// searchQuery decides which entries a search matches
+type searchQuery struct {
+	pattern string // glob pattern, or "" for a substring search
+	substr  string // lower-cased substring to look for in names
+}
+
+// newSearchQuery parses q: a query with glob wildcards is matched against
+// entry names, or against the path below the base if it contains a /, and
+// anything else is a case-insensitive substring of the name
+func newSearchQuery(q string) (searchQuery, error) {
+	if !strings.ContainsAny(q, "*?[") {
+		return searchQuery{substr: strings.ToLower(q)}, nil
+	}
+	if _, err := path.Match(q, ""); err != nil {
+		return searchQuery{}, fmt.Errorf("invalid pattern %q: %w", q, err)
+	}
+	return searchQuery{pattern: q}, nil
+}
+
+// matches reports whether the entry at rel, relative to the search base, matches
+func (q searchQuery) matches(rel string) bool {
+	if q.pattern == "" {
+		return strings.Contains(strings.ToLower(path.Base(rel)), q.substr)
+	}
+	target := path.Base(rel)
+	if strings.Contains(q.pattern, "/") {
+		target = rel
+	}
+	ok, _ := path.Match(q.pattern, target)
+	return ok
+}
+
+// handleSearch finds files and directories beneath a base path whose names
+// match a glob pattern or contain a substring, so clients don't have to list
+// the whole tree. Entries a listing would hide are not searched.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	base := normalizePath(r.URL.Query().Get("path"))
+	if base == "" {
+		base = "/"
+	}
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		writeJSONError(w, errors.NewValidationError("q", "q parameter required"))
+		return
+	}
+	query, err := newSearchQuery(q)
+	if err != nil {
+		errors.WriteJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	limit, err := boundedParam(r, "limit", maxSearchResults)
+	if err != nil {
+		errors.WriteJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	depth, err := boundedParam(r, "depth", maxSearchDepth)
+	if err != nil {
+		errors.WriteJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	if !s.allowListing(w, r, base) {
+		return
+	}
+
+	if info, err := storage.Stat(s.storage, base); err != nil {
+		writeJSONError(w, err)
+		return
+	} else if !info.IsDir {
+		errors.WriteJSON(w, http.StatusBadRequest, fmt.Errorf("%s is not a directory", base))
+		return
+	}
+
+	job := &searchJob{scope: auth.RequestScope(r), base: strings.Trim(base, "/"), query: query, limit: limit, depth: depth}
+	resp := SearchResponse{Matches: []ListEntry{}}
+	if err := s.searchDir(job, "", 1, &resp); err != nil {
+		writeJSONError(w, fmt.Errorf("search failed: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("encode failed: %w", err))
+		return
+	}
+}
+
+// boundedParam returns the positive integer query parameter name, capped at
+// ceiling, or ceiling if it isn't given
+func boundedParam(r *http.Request, name string, ceiling int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return ceiling, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number", name)
+	}
+	return min(n, ceiling), nil
+}
+
+// searchJob is one /search request being carried out
+type searchJob struct {
+	scope auth.PathScope
+	base  string // directory searched, relative to the storage root
+	query searchQuery
+	limit int // most matches to return
+	depth int // most levels of directories to look through
+}
+
+// searchDir adds the matches in the directory rel below the search base,
+// level directories deep, to resp, then searches its subdirectories until
+// the search's limits are reached
+func (s *Server) searchDir(job *searchJob, rel string, level int, resp *SearchResponse) error {
+	dir := strings.TrimPrefix(job.base+"/"+rel, "/")
+	infos, err := storage.ListDetailed(s.storage, dir)
+	if err != nil {
+		// A symlink out of the storage root is skipped, not searched
+		if errType, ok := errors.GetStorageErrorType(err); ok && errType == errors.StorageErrorPathTraversal {
+			return nil
+		}
+		return err
+	}
+
+	var subdirs []string
+	for _, info := range infos {
+		if !s.listed(job.scope, dir, info.Name) {
+			continue
+		}
+		entryRel := strings.TrimPrefix(rel+"/"+info.Name, "/")
+		if info.IsDir {
+			subdirs = append(subdirs, entryRel)
+		}
+		// Directories leading to a path-scoped token's scope are searched
+		// through, but only what is inside the scope is reported
+		if !job.scope.Allows(strings.TrimPrefix(dir+"/"+info.Name, "/")) || !job.query.matches(entryRel) {
+			continue
+		}
+		if len(resp.Matches) == job.limit {
+			resp.Truncated = true
+			return nil
+		}
+		resp.Matches = append(resp.Matches, ListEntry{Name: entryRel, Size: info.Size, IsDir: info.IsDir, ModTime: info.ModTime})
+	}
+
+	if len(subdirs) > 0 && level == job.depth {
+		resp.Truncated = true
+		return nil
+	}
+	for _, subdir := range subdirs {
+		if err := s.searchDir(job, subdir, level+1, resp); err != nil {
+			return err
+		}
+		if resp.Truncated && len(resp.Matches) == job.limit {
+			return nil
+		}
+	}
+	return nil
+}
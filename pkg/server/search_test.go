@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/auth"
+)
+
+// searchFor calls the search handler with the given query and decodes a
+// successful response
+func searchFor(t *testing.T, srv *Server, query url.Values) (*httptest.ResponseRecorder, SearchResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/search?"+query.Encode(), nil)
+	rec := httptest.NewRecorder()
+	srv.handleSearch(rec, req)
+
+	var resp SearchResponse
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+	}
+	return rec, resp
+}
+
+// matchNames returns the sorted names of a search's matches
+func matchNames(resp SearchResponse) []string {
+	names := make([]string, 0, len(resp.Matches))
+	for _, match := range resp.Matches {
+		names = append(names, match.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestHandleSearch_Glob(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("logs/app.log", []byte("app"))
+	store.Put("logs/2024/jan/web.log", []byte("web log"))
+	store.Put("logs/2024/jan/web.txt", []byte("not a log"))
+	store.Put("logs/.hidden.log", []byte("hidden"))
+	store.Put("other/outside.log", []byte("outside the base"))
+	store.Mkdir("logs/archive.log")
+
+	rec, resp := searchFor(t, srv, url.Values{"path": {"logs"}, "q": {"*.log"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	want := []string{"2024/jan/web.log", "app.log", "archive.log"}
+	if got := matchNames(resp); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if resp.Truncated {
+		t.Error("expected a complete search")
+	}
+	for _, match := range resp.Matches {
+		if match.Name == "2024/jan/web.log" && (match.Size != 7 || match.IsDir || match.ModTime.IsZero()) {
+			t.Errorf("unexpected metadata %+v", match)
+		}
+		if match.Name == "archive.log" && !match.IsDir {
+			t.Error("expected archive.log reported as a directory")
+		}
+	}
+
+	// A pattern with a / is matched against the whole relative path
+	_, resp = searchFor(t, srv, url.Values{"path": {"logs"}, "q": {"2024/*/*.txt"}})
+	if got := matchNames(resp); !reflect.DeepEqual(got, []string{"2024/jan/web.txt"}) {
+		t.Errorf("expected the path pattern to match web.txt, got %v", got)
+	}
+}
+
+func TestHandleSearch_Substring(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("docs/Annual Report.pdf", []byte("a"))
+	store.Put("docs/reports/q1.pdf", []byte("b"))
+	store.Put("docs/notes.txt", []byte("c"))
+
+	_, resp := searchFor(t, srv, url.Values{"q": {"report"}})
+	want := []string{"docs/Annual Report.pdf", "docs/reports"}
+	if got := matchNames(resp); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected a case-insensitive substring search from the root, got %v", got)
+	}
+}
+
+func TestHandleSearch_Limits(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("a/1.log", []byte("1"))
+	store.Put("a/2.log", []byte("2"))
+	store.Put("a/b/3.log", []byte("3"))
+	store.Put("a/b/c/4.log", []byte("4"))
+
+	_, resp := searchFor(t, srv, url.Values{"path": {"a"}, "q": {"*.log"}, "limit": {"2"}})
+	if len(resp.Matches) != 2 || !resp.Truncated {
+		t.Errorf("expected 2 matches and a truncated search, got %v (truncated %v)", matchNames(resp), resp.Truncated)
+	}
+
+	_, resp = searchFor(t, srv, url.Values{"path": {"a"}, "q": {"*.log"}, "depth": {"2"}})
+	if got := matchNames(resp); !reflect.DeepEqual(got, []string{"1.log", "2.log", "b/3.log"}) || !resp.Truncated {
+		t.Errorf("expected the search stopped two levels down, got %v (truncated %v)", got, resp.Truncated)
+	}
+
+	_, resp = searchFor(t, srv, url.Values{"path": {"a"}, "q": {"*.log"}, "limit": {"4"}})
+	if len(resp.Matches) != 4 || resp.Truncated {
+		t.Errorf("expected exactly the limit found without truncation, got %v (truncated %v)", matchNames(resp), resp.Truncated)
+	}
+}
+
+func TestHandleSearch_Errors(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("docs/a.txt", []byte("a"))
+
+	tests := []struct {
+		query url.Values
+		want  int
+	}{
+		{url.Values{"path": {"docs"}}, http.StatusBadRequest},
+		{url.Values{"path": {"docs"}, "q": {"[a"}}, http.StatusBadRequest},
+		{url.Values{"path": {"docs"}, "q": {"a"}, "limit": {"0"}}, http.StatusBadRequest},
+		{url.Values{"path": {"docs"}, "q": {"a"}, "depth": {"deep"}}, http.StatusBadRequest},
+		{url.Values{"path": {"docs/a.txt"}, "q": {"a"}}, http.StatusBadRequest},
+		{url.Values{"path": {"missing"}, "q": {"a"}}, http.StatusNotFound},
+		{url.Values{"path": {"../etc"}, "q": {"passwd"}}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec, _ := searchFor(t, srv, tt.query); rec.Code != tt.want {
+			t.Errorf("%v: expected %d, got %d: %s", tt.query, tt.want, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestHandleSearch_PathScope(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("projects/alpha/a.log", []byte("a"))
+	store.Put("projects/beta/b.log", []byte("b"))
+	store.Put("top.log", []byte("t"))
+
+	tokenStore, err := auth.NewTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+	token, _, err := tokenStore.Create("alice", []string{"list"}, time.Hour, "projects/alpha")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	srv.EnableAuth(tokenStore)
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=*.log", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	srv.authMiddle.RequireAuth("list", srv.handleSearch)(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp SearchResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if got := matchNames(resp); !reflect.DeepEqual(got, []string{"projects/alpha/a.log"}) {
+		t.Errorf("expected only matches inside the token's scope, got %v", got)
+	}
+}
@@ -1,15 +1,28 @@
 package server
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/0xRepo-Source/goflux-lite/pkg/audit"
 	"github.com/0xRepo-Source/goflux-lite/pkg/auth"
+	"github.com/0xRepo-Source/goflux-lite/pkg/chunk"
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
 	"github.com/0xRepo-Source/goflux-lite/pkg/resume"
 	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
 	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
@@ -24,6 +37,9 @@ type ServerConfig struct {
 		TokensFile  string `json:"tokens_file,omitempty"`
 		MaxFileSize int64  `json:"max_file_size"`
 	} `json:"server"`
+	Name        string `json:"name,omitempty"`
+	InstanceID  string `json:"instance_id,omitempty"`
+	Scheme      string `json:"scheme,omitempty"` // "https" when TLS is enabled, otherwise "http"
 	Version     string `json:"version"`
 	AuthEnabled bool   `json:"auth_enabled"`
 }
@@ -31,46 +47,384 @@ type ServerConfig struct {
 // Server is a goflux server instance.
 type Server struct {
 	storage      storage.Storage
-	chunksDir    string               // directory for temporary chunk storage
-	sessionStore *resume.SessionStore // tracks upload sessions for resume
-	mu           sync.Mutex
+	chunksDir    string       // directory for temporary chunk storage
+	sessionStore resume.Store // tracks upload sessions for resume
+	uploadLocks  *pathLocks   // serializes chunk writes within each upload session
+	// sessionsMu makes the per-user session cap check atomic with session creation
+	sessionsMu   sync.Mutex
 	authMiddle   *auth.Middleware  // nil if auth disabled
 	discovery    *DiscoveryService // nil if discovery disabled
+	discoveryOpt DiscoveryOptions  // how discovery binds its port
 	serverConfig *ServerConfig     // configuration to share with clients
 	firewall     *FirewallManager  // manages firewall rules
+	maxConns     int               // simultaneous connection cap (0 = unlimited)
+	maxSessions  int               // open upload sessions allowed per user (0 = unlimited)
+	name         string            // human-readable server name announced to clients
+	instanceID   string            // stable identifier persisted in the metadata directory
+	tlsCertFile  string            // TLS certificate (empty for plain HTTP)
+	tlsKeyFile   string            // TLS private key (empty for plain HTTP)
+	httpServer   *http.Server      // set while serving, used by Shutdown
+	httpMu       sync.Mutex        // guards httpServer
+	hashes       *hashIndex        // content hashes of stored files for /download?hash=
+	uploadFilter UploadFilter      // file types accepted for upload (zero value allows all)
+
+	responseHeaders map[string]string  // headers added to every response (nil for DefaultResponseHeaders)
+	completions     []CompletionAction // moves applied to files once their upload completes
+
+	trustedProxies []*net.IPNet // peers whose proxy headers are believed
+	proxyHeaders   []string     // headers naming the client behind a trusted proxy
+
+	showHidden bool // list dotfiles in directory listings
+
+	updateManifest string // file served at /version.json (empty for none)
+
+	maxFilesPerDir int // entries a directory may hold before uploads into it are refused (0 = unlimited)
+
+	auditLog audit.Logger // records authentication and transfers (nil if auditing is off)
+
+	maxFileSize   int64 // largest file accepted for upload, in bytes (0 = unlimited)
+	maxTotalBytes int64 // total size of stored files allowed, in bytes (0 = unlimited)
+
+	reserved map[string]int64 // smallest sizes of unfinished uploads, by path, counted against maxTotalBytes; guarded by sessionsMu
+
+	sessionMaxAge time.Duration        // unfinished uploads idle this long are discarded (0 = never)
+	abandonGrace  time.Duration        // uploads whose client disconnected are discarded after this (0 = never)
+	abandoned     map[string]time.Time // paths whose client disconnected mid-chunk -> when
+	abandonedMu   sync.Mutex           // guards abandoned
+
+	overwritePolicy string // what happens to uploads onto an existing file (OverwriteReplace if empty)
+
+	readyMaxSessions int          // unfinished uploads above which /ready fails (0 = no limit)
+	readyMaxConns    int          // open connections above which /ready fails (0 = no limit)
+	openConns        atomic.Int64 // client connections currently open
 }
 
-// New creates a new Server.
+// New creates a new Server that keeps upload sessions as JSON files in metaDir.
 func New(store storage.Storage, metaDir string) (*Server, error) {
 	sessionStore, err := resume.NewSessionStore(metaDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session store: %w", err)
 	}
+	return NewWithSessionStore(store, sessionStore, metaDir)
+}
 
+// NewWithSessionStore creates a new Server that tracks upload sessions in
+// sessionStore. The caller remains responsible for closing the session store.
+// Chunk directories and sessions left inconsistent by a crash are repaired
+// before it returns.
+func NewWithSessionStore(store storage.Storage, sessionStore resume.Store, metaDir string) (*Server, error) {
 	// Create chunks directory for temporary storage
 	chunksDir := filepath.Join(metaDir, "chunks")
 	if err := os.MkdirAll(chunksDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create chunks directory: %w", err)
 	}
 
-	return &Server{
+	instanceID, err := loadInstanceID(metaDir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
 		storage:      store,
 		chunksDir:    chunksDir,
 		sessionStore: sessionStore,
-	}, nil
+		uploadLocks:  newPathLocks(),
+		maxConns:     DefaultMaxConnections,
+		instanceID:   instanceID,
+		hashes:       newHashIndex(),
+		reserved:     make(map[string]int64),
+
+		sessionMaxAge: DefaultSessionMaxAge,
+		abandonGrace:  DefaultAbandonedUploadGrace,
+	}
+	s.reconcileSessions()
+	// Uploads left unfinished before a restart still hold their space
+	for _, session := range s.sessionStore.Sessions() {
+		s.reserved[session.Path] = minFileSize(session.TotalChunks, session.ChunkSize, 0, session.ChunkSize)
+	}
+	if err := s.hashes.rebuild(store); err != nil {
+		fmt.Printf("Warning: failed to index stored files by hash: %v\n", err)
+	}
+	return s, nil
 }
 
-// EnableAuth enables authentication on the server
+// EnableAuth enables authentication on the server. Failed attempts are
+// counted against the client address, resolved as for SetTrustedProxies.
 func (s *Server) EnableAuth(tokenStore *auth.TokenStore) {
 	s.authMiddle = auth.NewMiddleware(tokenStore)
+	s.authMiddle.SetClientIP(s.clientIP)
+	s.authMiddle.SetAuditLogger(s.auditLog)
+}
+
+// SetAuditLogger records authentication failures, permission denials,
+// completed uploads, downloads and deletes to logger. nil turns auditing off.
+func (s *Server) SetAuditLogger(logger audit.Logger) {
+	s.auditLog = logger
+	if s.authMiddle != nil {
+		s.authMiddle.SetAuditLogger(logger)
+	}
+}
+
+// audit records the outcome of r acting on path, if auditing is on. err is
+// the reason for a denial or failure.
+func (s *Server) audit(r *http.Request, action, path, result string, err error) {
+	if s.auditLog == nil {
+		return
+	}
+	event := audit.Event{
+		Time:     time.Now(),
+		Action:   action,
+		Path:     path,
+		RemoteIP: s.clientIP(r),
+		Result:   result,
+	}
+	// The header is only trustworthy once the auth middleware has set it
+	if s.authMiddle != nil {
+		event.User = r.Header.Get("X-Authenticated-User")
+	}
+	if err != nil {
+		event.Detail = err.Error()
+	}
+	s.auditLog.Log(event)
+}
+
+// auditAction names the action r performs in the audit log
+func auditAction(r *http.Request) string {
+	if strings.HasPrefix(r.URL.Path, "/upload") {
+		return audit.ActionUpload
+	}
+	if strings.HasPrefix(r.URL.Path, "/download") {
+		return audit.ActionDownload
+	}
+	return strings.TrimPrefix(r.URL.Path, "/")
+}
+
+// SetAuthFailureLimit answers 429 Too Many Requests to clients that fail to
+// authenticate max times within window, until the oldest failure leaves the
+// window. A max of zero or less turns throttling off. Call after EnableAuth;
+// auth.DefaultMaxFailures and auth.DefaultFailureWindow apply otherwise.
+func (s *Server) SetAuthFailureLimit(max int, window time.Duration) {
+	if s.authMiddle != nil {
+		s.authMiddle.SetFailureLimit(max, window)
+	}
+}
+
+// EnableTLS serves HTTPS using the given certificate and key files
+func (s *Server) EnableTLS(certFile, keyFile string) {
+	s.tlsCertFile = certFile
+	s.tlsKeyFile = keyFile
+}
+
+// Scheme returns the URL scheme clients should use to reach the server
+func (s *Server) Scheme() string {
+	if s.tlsCertFile != "" && s.tlsKeyFile != "" {
+		return "https"
+	}
+	return "http"
+}
+
+// SetShowHidden controls whether directory listings include dotfiles. They
+// are left out by default; a hidden path named explicitly is still listed.
+func (s *Server) SetShowHidden(show bool) {
+	s.showHidden = show
+}
+
+// SetUpdateManifest serves the file at path, outside the storage root, at
+// /version.json so clients on the network can update from this server with
+// gfl update. The file is read on each request, so it can be replaced while
+// the server runs. An empty path serves no manifest.
+func (s *Server) SetUpdateManifest(path string) {
+	s.updateManifest = path
+}
+
+// SetMaxChallenges caps how many unanswered authentication challenges are
+// kept, evicting the oldest when full. Has no effect unless auth is enabled;
+// auth.DefaultMaxChallenges applies otherwise.
+func (s *Server) SetMaxChallenges(max int) {
+	if s.authMiddle != nil {
+		s.authMiddle.SetMaxChallenges(max)
+	}
+}
+
+// PersistChallenges keeps unanswered authentication challenges in filename,
+// so clients part way through authenticating survive a restart. Challenges
+// are saved on Shutdown and as expired ones are cleaned up. Has no effect
+// unless auth is enabled.
+func (s *Server) PersistChallenges(filename string) error {
+	if s.authMiddle == nil {
+		return nil
+	}
+	return s.authMiddle.PersistChallenges(filename)
+}
+
+// SetMaxFilesPerDir caps how many entries a directory may hold. An upload
+// that would add a file or subdirectory to a directory already at the cap is
+// refused with 409 when it starts; replacing an existing file is always
+// allowed. Zero disables the limit.
+func (s *Server) SetMaxFilesPerDir(max int) {
+	s.maxFilesPerDir = max
+}
+
+// SetMaxFileSize caps the size of uploaded files, in bytes. A chunk that
+// shows the file must be larger is refused with 413 and everything received
+// for the file so far is discarded. Zero disables the limit.
+func (s *Server) SetMaxFileSize(max int64) {
+	s.maxFileSize = max
+}
+
+// checkFileSize returns an error if the file a session is uploading exceeds
+// the size cap, judging by the session's chunk layout and a chunk of size
+// bytes arriving as chunkID
+func (s *Server) checkFileSize(session *resume.UploadSession, chunkID, size int) error {
+	if s.maxFileSize <= 0 {
+		return nil
+	}
+	if n := minFileSize(session.TotalChunks, session.ChunkSize, chunkID, size); n > s.maxFileSize {
+		return fmt.Errorf("file is too large: at least %d bytes, limit %d", n, s.maxFileSize)
+	}
+	return nil
+}
+
+// minFileSize returns the smallest a file of total chunks of chunkSize bytes
+// can be, given a chunk of size bytes arriving as chunkID. Every chunk but
+// the last is a full chunk, and the last holds at least a byte, so the total
+// is known exactly once the last chunk arrives.
+func minFileSize(total, chunkSize, chunkID, size int) int64 {
+	if total <= 1 {
+		return int64(size)
+	}
+	full, last := int64(chunkSize), int64(1)
+	if chunkID == total-1 {
+		last = int64(size)
+	} else {
+		full = int64(size)
+	}
+	return full*int64(total-1) + last
+}
+
+// SetMaxTotalBytes caps the total size of every stored file, in bytes. A new
+// upload that would take storage past the cap is refused with 507 when it
+// starts. Zero disables the quota; it needs a backend that reports its usage.
+func (s *Server) SetMaxTotalBytes(max int64) {
+	s.maxTotalBytes = max
+}
+
+// checkQuota returns an error if storing a file of at least size bytes at
+// path would take storage past the quota, counting the space reserved for
+// other unfinished uploads. A file it replaces frees its space, unless the
+// overwrite policy keeps it. The caller holds sessionsMu.
+func (s *Server) checkQuota(path string, size int64) error {
+	if s.maxTotalBytes <= 0 {
+		return nil
+	}
+	reporter, ok := s.storage.(storage.UsageReporter)
+	if !ok {
+		return nil
+	}
+	used, err := reporter.Usage()
+	if err != nil {
+		return err
+	}
+	if info, err := storage.Stat(s.storage, path); err == nil && !info.IsDir && s.replacesFiles() {
+		used -= info.Size
+	}
+	for p, n := range s.reserved {
+		if p != path {
+			used += n
+		}
+	}
+	if used+size > s.maxTotalBytes {
+		return fmt.Errorf("%w: %d of %d bytes used, upload needs at least %d", errQuotaExceeded, used, s.maxTotalBytes, size)
+	}
+	return nil
+}
+
+// releaseQuota frees the space reserved for the unfinished upload of path,
+// once it has been stored or discarded
+func (s *Server) releaseQuota(path string) {
+	s.sessionsMu.Lock()
+	delete(s.reserved, path)
+	s.sessionsMu.Unlock()
+}
+
+// errQuotaExceeded reports an upload refused by the storage quota
+var errQuotaExceeded = stderrors.New("storage quota exceeded")
+
+// checkDirLimit returns an error if storing a file at path would add an
+// entry to a directory already holding maxFilesPerDir. The entry added is the
+// file itself or, if its parents don't exist yet, the first of them.
+func (s *Server) checkDirLimit(path string) error {
+	if s.maxFilesPerDir <= 0 {
+		return nil
+	}
+
+	dir := ""
+	for _, elem := range strings.Split(strings.Trim(path, "/"), "/") {
+		if elem == "" || elem == "." {
+			continue
+		}
+		entry := strings.TrimPrefix(dir+"/"+elem, "/")
+		if s.storage.Exists(entry) {
+			dir = entry
+			continue
+		}
+
+		names, err := s.storage.List("/" + dir)
+		if err != nil {
+			// A directory that can't be listed is left to fail the upload itself
+			return nil
+		}
+		count := 0
+		for _, name := range names {
+			if dir != "" || name != StagingDir {
+				count++
+			}
+		}
+		if count >= s.maxFilesPerDir {
+			return fmt.Errorf("directory /%s is full (limit %d entries); upload into a subdirectory instead", dir, s.maxFilesPerDir)
+		}
+		return nil
+	}
+	// The file already exists and is being replaced
+	return nil
 }
 
-// EnableDiscovery enables the discovery service
+// SetName sets the server name announced in discovery and /config
+func (s *Server) SetName(name string) {
+	s.name = name
+}
+
+// InstanceID returns the server's stable instance identifier
+func (s *Server) InstanceID() string {
+	return s.instanceID
+}
+
+// SetUploadFilter restricts uploads to the file types permitted by filter
+func (s *Server) SetUploadFilter(filter UploadFilter) {
+	s.uploadFilter = filter
+}
+
+// SetDiscoveryOptions sets how the discovery service binds its UDP port
+// and whether failing to do so should stop the server.
+func (s *Server) SetDiscoveryOptions(opts DiscoveryOptions) {
+	s.discoveryOpt = opts
+}
+
+// EnableDiscovery enables the discovery service. If no UDP port can be bound
+// the server keeps running without discovery, unless discovery is required.
 func (s *Server) EnableDiscovery(serverAddress, version string) error {
 	authEnabled := s.authMiddle != nil
-	discovery, err := NewDiscoveryService(serverAddress, version, authEnabled)
+	opts := s.discoveryOpt
+	opts.Name = s.name
+	opts.InstanceID = s.instanceID
+	opts.Scheme = s.Scheme()
+	discovery, err := NewDiscoveryServiceWithOptions(serverAddress, version, authEnabled, opts)
 	if err != nil {
-		return fmt.Errorf("failed to create discovery service: %w", err)
+		if s.discoveryOpt.Required {
+			return fmt.Errorf("failed to create discovery service: %w", err)
+		}
+		fmt.Printf("Warning: discovery disabled, clients must be configured manually: %v\n", err)
+		return nil
 	}
 	s.discovery = discovery
 	return nil
@@ -84,36 +438,40 @@ func (s *Server) SetConfig(config *ServerConfig) {
 // EnableFirewall enables automatic firewall configuration
 func (s *Server) EnableFirewall(serverAddress string) {
 	serverPort := parsePortFromAddress(serverAddress)
-	s.firewall = NewFirewallManager(serverPort, DiscoveryPort)
+	discoveryPort := DiscoveryPort
+	if s.discovery != nil {
+		discoveryPort = s.discovery.Port()
+	}
+	s.firewall = NewFirewallManager(serverPort, discoveryPort)
+}
+
+// SetMaxConnections sets the maximum number of simultaneous client connections.
+// Connections beyond the limit are answered with 503. Zero disables the limit.
+func (s *Server) SetMaxConnections(max int) {
+	s.maxConns = max
+}
+
+// SetMaxSessionsPerUser caps how many incomplete uploads each user may have
+// open at once. New uploads beyond the cap are answered with 429. Zero
+// disables the limit.
+func (s *Server) SetMaxSessionsPerUser(max int) {
+	s.maxSessions = max
 }
 
 // Start starts the HTTP server.
 func (s *Server) Start(addr string) error {
-	// Create a new ServeMux to avoid conflicts with default mux
-	mux := http.NewServeMux()
-
-	// Config endpoint (no auth required for auto-discovery)
-	mux.HandleFunc("/config", s.handleConfig)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
 
-	// Register handlers with authentication if enabled
+// Serve serves requests on an existing listener, using TLS if it is enabled.
+func (s *Server) Serve(ln net.Listener) error {
 	if s.authMiddle != nil {
-		// Challenge-response endpoint (no auth required to get challenge)
-		mux.HandleFunc("/auth/challenge", s.authMiddle.HandleChallenge)
-
-		mux.HandleFunc("/upload", s.authMiddle.RequireAuth("upload", s.handleUpload))
-		mux.HandleFunc("/upload/status", s.authMiddle.RequireAuth("upload", s.handleUploadStatus))
-		mux.HandleFunc("/download", s.authMiddle.RequireAuth("download", s.handleDownload))
-		mux.HandleFunc("/list", s.authMiddle.RequireAuth("list", s.handleList))
-		mux.HandleFunc("/delete", s.authMiddle.RequireAuth("delete", s.handleDelete))
-		mux.HandleFunc("/mkdir", s.authMiddle.RequireAuth("mkdir", s.handleMkdir))
 		fmt.Println("\033[32mAuthentication enabled (challenge-response supported)\033[0m")
 	} else {
-		mux.HandleFunc("/upload", s.handleUpload)
-		mux.HandleFunc("/upload/status", s.handleUploadStatus)
-		mux.HandleFunc("/download", s.handleDownload)
-		mux.HandleFunc("/list", s.handleList)
-		mux.HandleFunc("/delete", s.handleDelete)
-		mux.HandleFunc("/mkdir", s.handleMkdir)
 		fmt.Println("\033[31m⚠️ Authentication disabled - all endpoints are public!\033[0m")
 		fmt.Println("\033[31mIt is recommended to enable authentication in production environments.\033[0m")
 		fmt.Println("\033[31mPlease run gfl-admin to create token files and enable auth.\033[0m")
@@ -130,82 +488,432 @@ func (s *Server) Start(addr string) error {
 		defer s.discovery.Stop()
 	}
 
-	fmt.Printf("goflux server listening on %s\n", addr)
-	return http.ListenAndServe(addr, mux)
+	// Uploads whose clients went away are discarded while serving
+	stopCleanup := make(chan struct{})
+	defer close(stopCleanup)
+	go s.runSessionCleanup(stopCleanup)
+
+	httpServer := &http.Server{Handler: s.handler(), ConnState: s.trackConn}
+	if s.Scheme() == "https" {
+		cert, err := tls.LoadX509KeyPair(s.tlsCertFile, s.tlsKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		httpServer.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	if s.maxConns > 0 {
+		limited := newLimitListener(ln, s.maxConns)
+		if httpServer.TLSConfig != nil {
+			limited.answerOverTLS(httpServer.TLSConfig)
+		}
+		ln = limited
+	}
+
+	s.httpMu.Lock()
+	s.httpServer = httpServer
+	s.httpMu.Unlock()
+
+	if httpServer.TLSConfig != nil {
+		fmt.Printf("goflux server listening on https://%s\n", ln.Addr())
+		return httpServer.ServeTLS(ln, "", "")
+	}
+
+	fmt.Println("\033[31m⚠️ TLS disabled - traffic is not encrypted. Set tls_cert and tls_key to enable HTTPS.\033[0m")
+	fmt.Printf("goflux server listening on %s\n", ln.Addr())
+	return httpServer.Serve(ln)
 }
 
-func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
+// Shutdown stops accepting connections, waits for in-flight requests to finish
+// or ctx to expire, stops the discovery service and saves upload session
+// metadata so interrupted uploads can resume after a restart. Serve returns
+// http.ErrServerClosed once Shutdown has been called.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.httpMu.Lock()
+	httpServer := s.httpServer
+	s.httpMu.Unlock()
+
+	var shutdownErr error
+	if httpServer != nil {
+		shutdownErr = httpServer.Shutdown(ctx)
 	}
 
+	if s.discovery != nil {
+		s.discovery.Stop()
+	}
+
+	flushErr := s.sessionStore.Flush()
+	var challengeErr error
+	if s.authMiddle != nil {
+		challengeErr = s.authMiddle.Flush()
+	}
+
+	if shutdownErr != nil {
+		return fmt.Errorf("failed to drain connections: %w", shutdownErr)
+	}
+	if flushErr != nil {
+		return fmt.Errorf("failed to save sessions: %w", flushErr)
+	}
+	if challengeErr != nil {
+		return fmt.Errorf("failed to save challenges: %w", challengeErr)
+	}
+	return nil
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	// A client that disconnects partway through a chunk leaves its upload
+	// to be discarded after the grace period unless it comes back
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		s.markAbandoned(partialChunkPath(body))
+		errors.WriteJSON(w, http.StatusBadRequest, fmt.Errorf("failed to read request body: %w", err))
+		return
+	}
+
+	// A body shorter than its declared length was cut off in transit, and
+	// could still decode to a chunk with missing data
+	if r.ContentLength >= 0 && int64(len(body)) != r.ContentLength {
+		s.markAbandoned(partialChunkPath(body))
+		errors.WriteJSON(w, http.StatusBadRequest, fmt.Errorf("request body truncated: got %d of %d bytes", len(body), r.ContentLength))
 		return
 	}
 
 	var chunkData transport.ChunkData
 	if err := json.Unmarshal(body, &chunkData); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		errors.WriteJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	chunkData.Path = normalizePath(chunkData.Path)
+	if !s.allowPath(w, r, chunkData.Path) {
+		return
+	}
+
+	if err := validateChunkID(chunkData.ChunkID, chunkData.Total); err != nil {
+		errors.WriteJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := verifyChecksum(chunkData.Path, chunkData.ChunkID, chunk.Checksum(chunkData.Data), chunkData.Checksum); err != nil {
+		errors.WriteJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.storeChunk(w, r, chunkData.Path, chunkData.FileHash, chunkData.ChunkID, chunkData.Total, len(chunkData.Data), chunkData.Replace, chunkData.DeferFinalize, func(chunkPath string) error {
+		return os.WriteFile(chunkPath, chunkData.Data, 0644)
+	})
+	s.checkDisconnect(r, chunkData.Path)
+}
+
+// checkDisconnect marks the upload of path abandoned if the client went away
+// before its chunk was answered
+func (s *Server) checkDisconnect(r *http.Request, path string) {
+	select {
+	case <-r.Context().Done():
+		s.markAbandoned(path)
+	default:
+	}
+}
+
+// validateChunkID rejects out-of-range chunk IDs before anything touches the disk
+func validateChunkID(chunkID, total int) error {
+	if total <= 0 {
+		return fmt.Errorf("invalid total: %d", total)
+	}
+	if chunkID < 0 || chunkID >= total {
+		return fmt.Errorf("invalid chunk ID: %d (total: %d)", chunkID, total)
+	}
+	return nil
+}
+
+// sessionOwner identifies who an upload session belongs to: the authenticated
+// user, or the client's address when authentication is disabled
+func (s *Server) sessionOwner(r *http.Request) string {
+	// The header is only trustworthy once the auth middleware has set it
+	if s.authMiddle != nil {
+		return "user:" + r.Header.Get("X-Authenticated-User")
+	}
+	return "addr:" + s.clientIP(r)
+}
+
+// verifyChecksum compares the checksum computed from a received chunk with
+// the one the client sent. Chunks from clients that send no checksum are
+// accepted with a warning.
+func verifyChecksum(path string, chunkID int, computed, sent string) error {
+	if sent == "" {
+		fmt.Printf("Warning: chunk %d of %s has no checksum, skipping verification\n", chunkID, path)
+		return nil
+	}
+	if !strings.EqualFold(computed, sent) {
+		return fmt.Errorf("chunk %d checksum mismatch: data was corrupted in transit", chunkID)
+	}
+	return nil
+}
+
+// storeChunk records a chunk of size bytes in the upload session of r's
+// owner, calling
+// write to place the chunk data at its final location, and reassembles the
+// file once every chunk has arrived, checking it against fileHash if the
+// client sent one, unless deferFinalize leaves that to /upload/finalize. A
+// chunk that was already received is only written again if replace is set.
+// It writes the HTTP response for the chunk.
+func (s *Server) storeChunk(w http.ResponseWriter, r *http.Request, path, fileHash string, chunkID, total, size int, replace, deferFinalize bool, write func(chunkPath string) error) {
+	owner := s.sessionOwner(r)
+
+	// Chunks for other files are stored in parallel; the session store does
+	// its own locking, so only this session's chunk directory needs guarding
+	defer s.uploadLocks.lock(sessionKey(path))()
+
+	// A chunk arriving means the client is still working on the upload
+	s.clearAbandoned(path)
+
+	if err := s.uploadFilter.checkPath(path); err != nil {
+		s.rejectUpload(w, path, err)
 		return
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	// Catch uploads onto a directory before any chunks are written, rather
+	// than failing when the file is finally stored
+	if dc, ok := s.storage.(storage.DirChecker); ok && dc.IsDir(path) {
+		writeJSONError(w, errors.NewStorageError(errors.StorageErrorAlreadyExists, path, "cannot upload onto an existing directory"))
+		return
+	}
+
+	// The last chunk of a multi-chunk upload is usually short, so it can't
+	// establish the session's chunk size if it happens to arrive first
+	chunkSize := size
+	if total > 1 && chunkID == total-1 {
+		chunkSize = 0
+	}
+
+	// Starting another upload counts against the owner's open session cap,
+	// its directory's entry cap and the storage quota
+	s.sessionsMu.Lock()
+	_, exists := s.sessionStore.GetSession(path)
+	if !exists && s.maxSessions > 0 && s.sessionStore.CountOpenSessions(owner) >= s.maxSessions {
+		s.sessionsMu.Unlock()
+		errors.WriteJSON(w, http.StatusTooManyRequests, fmt.Errorf("too many unfinished uploads (limit %d); complete or abandon some first", s.maxSessions))
+		return
+	}
+	need := minFileSize(total, chunkSize, chunkID, size)
+	if !exists {
+		if err := s.checkDirLimit(path); err != nil {
+			s.sessionsMu.Unlock()
+			errors.WriteJSON(w, http.StatusConflict, err)
+			return
+		}
+		if err := s.checkConflict(path); err != nil {
+			s.sessionsMu.Unlock()
+			errors.WriteJSON(w, http.StatusConflict, err)
+			return
+		}
+		if err := s.checkQuota(path, need); err != nil {
+			s.sessionsMu.Unlock()
+			status := http.StatusInternalServerError
+			if stderrors.Is(err, errQuotaExceeded) {
+				status = http.StatusInsufficientStorage
+			}
+			errors.WriteJSON(w, status, err)
+			return
+		}
+	}
 
 	// Get or create upload session
-	session, err := s.sessionStore.GetOrCreateSession(chunkData.Path, chunkData.Total, len(chunkData.Data))
+	session, err := s.sessionStore.GetOrCreateSession(path, owner, fileHash, total, chunkSize)
+	if err == nil && !exists {
+		// The space is held for the upload until it is stored or discarded
+		s.reserved[path] = need
+	}
+	s.sessionsMu.Unlock()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("session error: %v", err), http.StatusInternalServerError)
+		// A client that changed the file since the upload began has to abort
+		// it and start over
+		status := http.StatusInternalServerError
+		if stderrors.Is(err, resume.ErrSessionMismatch) {
+			status = http.StatusConflict
+		}
+		errors.WriteJSON(w, status, fmt.Errorf("session error: %w", err))
+		return
+	}
+
+	if err := validateChunkSize(session, chunkID, size); err != nil {
+		errors.WriteJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Nothing more of a file over the size cap is worth keeping
+	if err := s.checkFileSize(session, chunkID, size); err != nil {
+		s.discardSession(path)
+		errors.WriteJSON(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+
+	// A retried chunk that is already on disk is acknowledged without rewriting
+	// it, unless the client found the stored copy damaged
+	if session.ReceivedMap[chunkID] && !replace {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "chunk %d/%d already received", chunkID+1, total)
 		return
 	}
 
 	// Create session-specific chunks directory using path hash
-	sessionHash := fmt.Sprintf("%x", []byte(chunkData.Path))
-	sessionChunksDir := filepath.Join(s.chunksDir, sessionHash[:16])
+	sessionChunksDir := s.sessionChunksDir(path)
 	if err := os.MkdirAll(sessionChunksDir, 0755); err != nil {
-		http.Error(w, fmt.Sprintf("failed to create session chunks dir: %v", err), http.StatusInternalServerError)
+		errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to create session chunks dir: %w", err))
 		return
 	}
 
 	// Write chunk to disk
-	chunkPath := filepath.Join(sessionChunksDir, fmt.Sprintf("chunk_%06d.dat", chunkData.ChunkID))
-	if err := os.WriteFile(chunkPath, chunkData.Data, 0644); err != nil {
-		http.Error(w, fmt.Sprintf("failed to write chunk: %v", err), http.StatusInternalServerError)
+	chunkPath := filepath.Join(sessionChunksDir, fmt.Sprintf("chunk_%06d.dat", chunkID))
+	if err := write(chunkPath); err != nil {
+		errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to write chunk: %w", err))
 		return
 	}
 
+	// The file type is sniffed from the start of the first chunk
+	if chunkID == 0 && s.uploadFilter.checksContent() {
+		head, err := readHead(chunkPath)
+		if err != nil {
+			errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to read chunk: %w", err))
+			return
+		}
+		if err := s.uploadFilter.checkContent(head); err != nil {
+			s.rejectUpload(w, path, err)
+			return
+		}
+	}
+
 	// Mark chunk as received in session
-	if err := s.sessionStore.MarkChunkReceived(chunkData.Path, chunkData.ChunkID); err != nil {
-		http.Error(w, fmt.Sprintf("failed to mark chunk: %v", err), http.StatusInternalServerError)
+	if err := s.sessionStore.MarkChunkReceived(path, chunkID); err != nil {
+		errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to mark chunk: %w", err))
 		return
 	}
 
-	// Check if upload is complete
-	if session.Completed {
-		// Reassemble file from disk chunks
-		if err := s.reassembleFromDisk(sessionChunksDir, chunkData.Path, chunkData.Total); err != nil {
-			http.Error(w, fmt.Sprintf("reassembly failed: %v", err), http.StatusInternalServerError)
+	// Check if upload is complete. Stores may hand out copies, so the session
+	// is read again to see the chunk just marked. A client that asked to
+	// finalize the upload itself is left to call /upload/finalize.
+	if session, _ = s.sessionStore.GetSession(path); session != nil && session.Completed && !deferFinalize {
+		if !s.finalizeUpload(w, r, path, session) {
 			return
 		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "chunk %d/%d received", chunkID+1, total)
+}
+
+// normalizePath converts a client-supplied remote path to forward slashes so
+// that Windows clients sending `files\doc.pdf` address the same file as
+// everyone else.
+func normalizePath(path string) string {
+	return strings.ReplaceAll(path, "\\", "/")
+}
+
+// writeJSONError answers a request with err as an errors.Response, with the
+// status errors.HTTPStatus maps it to. Handlers whose status depends on more
+// than the error's type call errors.WriteJSON directly.
+func writeJSONError(w http.ResponseWriter, err error) {
+	errors.WriteJSON(w, errors.HTTPStatus(err), err)
+}
+
+// allowPath writes 403 Forbidden and returns false if the token that
+// authenticated r may not touch path
+func (s *Server) allowPath(w http.ResponseWriter, r *http.Request, path string) bool {
+	if err := auth.CheckPath(r, path); err != nil {
+		s.audit(r, auditAction(r), path, audit.ResultDenied, err)
+		writeJSONError(w, err)
+		return false
+	}
+	return true
+}
+
+// allowListing is allowPath for requests that only describe path, which are
+// also allowed for the directories leading to the token's scope
+func (s *Server) allowListing(w http.ResponseWriter, r *http.Request, path string) bool {
+	if auth.RequestScope(r).Leads(path) {
+		return true
+	}
+	return s.allowPath(w, r, path)
+}
+
+// parentDir returns the parent of a slash-separated remote path, or "" for
+// paths at the storage root
+func parentDir(path string) string {
+	path = strings.Trim(path, "/")
+	if i := strings.LastIndex(path, "/"); i > 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// sessionKey identifies the upload session for a path. It is derived from a
+// SHA-256 of the path so that arbitrary remote paths map to fixed-length,
+// collision-resistant names.
+func sessionKey(remotePath string) string {
+	hash := sha256.Sum256([]byte(remotePath))
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+// sessionChunksDir returns the temporary chunk directory for an upload path
+func (s *Server) sessionChunksDir(remotePath string) string {
+	return filepath.Join(s.chunksDir, sessionKey(remotePath))
+}
+
+// chunkChecksums returns the SHA-256 of each received chunk of an upload as it
+// is stored on disk. Chunks that haven't arrived or can't be read are left empty.
+func (s *Server) chunkChecksums(remotePath string, received []bool) []string {
+	defer s.uploadLocks.lock(sessionKey(remotePath))()
+
+	dir := s.sessionChunksDir(remotePath)
+	checksums := make([]string, len(received))
+	for chunkID, ok := range received {
+		if !ok {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, fmt.Sprintf("chunk_%06d.dat", chunkID)))
+		if err != nil {
+			continue
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, f)
+		f.Close()
+		if err == nil {
+			checksums[chunkID] = hex.EncodeToString(hash.Sum(nil))
+		}
+	}
+	return checksums
+}
 
-		// Clean up chunks directory and session
-		os.RemoveAll(sessionChunksDir)
-		if err := s.sessionStore.DeleteSession(chunkData.Path); err != nil {
-			fmt.Printf("Warning: failed to delete session metadata: %v\n", err)
+// validateChunkSize checks a chunk's length against the chunk size recorded for
+// its session. Every chunk except the last must match exactly; the last chunk may
+// be shorter but never longer. Sessions with an unknown chunk size are not checked.
+func validateChunkSize(session *resume.UploadSession, chunkID, size int) error {
+	if session.ChunkSize <= 0 {
+		return nil
+	}
+
+	if chunkID == session.TotalChunks-1 {
+		if size > session.ChunkSize {
+			return fmt.Errorf("chunk %d size mismatch: %d bytes exceeds session chunk size %d", chunkID, size, session.ChunkSize)
 		}
+		return nil
 	}
 
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "chunk %d/%d received", chunkData.ChunkID+1, chunkData.Total)
+	if size != session.ChunkSize {
+		return fmt.Errorf("chunk %d size mismatch: got %d bytes, session chunk size is %d", chunkID, size, session.ChunkSize)
+	}
+	return nil
 }
 
-// reassembleFromDisk reads chunks from disk and assembles the final file
-func (s *Server) reassembleFromDisk(chunksDir, remotePath string, totalChunks int) error {
-	// Open output file for writing
-	tempPath := filepath.Join(s.chunksDir, "temp_"+filepath.Base(remotePath))
+// errFileHashMismatch reports a reassembled file that doesn't hash to the
+// value the client sent
+var errFileHashMismatch = stderrors.New("file hash mismatch")
+
+// reassembleFromDisk reads chunks from disk and assembles the final file. If
+// expectedHash is set, the file is only stored when its SHA-256 matches.
+func (s *Server) reassembleFromDisk(chunksDir, remotePath string, totalChunks int, expectedHash string) error {
+	// Open output file for writing. It lives in the session's own directory so
+	// uploads of files with the same name in different directories can't collide.
+	tempPath := filepath.Join(chunksDir, ".assembled.tmp")
 	outFile, err := os.Create(tempPath)
 	if err != nil {
 		return fmt.Errorf("failed to create temp file: %w", err)
@@ -233,9 +941,16 @@ func (s *Server) reassembleFromDisk(chunksDir, remotePath string, totalChunks in
 		return fmt.Errorf("failed to read assembled file: %w", err)
 	}
 
+	hash := contentHash(finalData)
+	if expectedHash != "" && !strings.EqualFold(hash, expectedHash) {
+		os.Remove(tempPath)
+		return fmt.Errorf("%w: %s should hash to %s, got %s", errFileHashMismatch, remotePath, expectedHash, hash)
+	}
+
 	if err := s.storage.Put(remotePath, finalData); err != nil {
 		return fmt.Errorf("storage failed: %w", err)
 	}
+	s.hashes.record(remotePath, hash)
 
 	// Clean up temp file
 	os.Remove(tempPath)
@@ -251,17 +966,17 @@ type UploadStatusResponse struct {
 	ReceivedMap   []bool `json:"received_map"`   // bitmap of received chunks
 	MissingChunks []int  `json:"missing_chunks"` // list of missing chunk IDs
 	Completed     bool   `json:"completed"`      // upload completed
+
+	Checksums []string `json:"checksums,omitempty"` // SHA-256 of each received chunk on disk, with ?checksums=true
 }
 
 func (s *Server) handleUploadStatus(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	path := normalizePath(r.URL.Query().Get("path"))
+	if path == "" {
+		writeJSONError(w, errors.NewValidationError("path", "path required"))
 		return
 	}
-
-	path := r.URL.Query().Get("path")
-	if path == "" {
-		http.Error(w, "path required", http.StatusBadRequest)
+	if !s.allowPath(w, r, path) {
 		return
 	}
 
@@ -274,7 +989,7 @@ func (s *Server) handleUploadStatus(w http.ResponseWriter, r *http.Request) {
 	if exists {
 		missing, err := s.sessionStore.GetMissingChunks(path)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("failed to get missing chunks: %v", err), http.StatusInternalServerError)
+			errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to get missing chunks: %w", err))
 			return
 		}
 
@@ -282,111 +997,316 @@ func (s *Server) handleUploadStatus(w http.ResponseWriter, r *http.Request) {
 		response.ReceivedMap = session.ReceivedMap
 		response.MissingChunks = missing
 		response.Completed = session.Completed
+
+		if r.URL.Query().Get("checksums") == "true" && !session.Completed {
+			response.Checksums = s.chunkChecksums(path, session.ReceivedMap)
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		http.Error(w, fmt.Sprintf("encode failed: %v", err), http.StatusInternalServerError)
+		errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("encode failed: %w", err))
 		return
 	}
 }
 
 func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Query().Get("path")
+	path := normalizePath(r.URL.Query().Get("path"))
+
+	// A content hash stands in for the path of any file holding that content
+	if hash := strings.ToLower(r.URL.Query().Get("hash")); hash != "" {
+		if !validHash(hash) {
+			errors.WriteJSON(w, http.StatusBadRequest, stderrors.New("hash must be a hex-encoded sha256"))
+			return
+		}
+		resolved, ok := s.resolveHash(hash, auth.RequestScope(r))
+		if !ok {
+			errors.WriteJSON(w, http.StatusNotFound, stderrors.New("no file with that hash"))
+			return
+		}
+		path = resolved
+	}
+
 	if path == "" {
-		http.Error(w, "path required", http.StatusBadRequest)
+		writeJSONError(w, errors.NewValidationError("path", "path or hash required"))
+		return
+	}
+	if !s.allowPath(w, r, path) {
+		return
+	}
+
+	// Backends that can seek serve ranges without loading the whole file
+	rangeHeader := r.Header.Get("Range")
+	rangeGetter, canSeek := s.storage.(storage.RangeGetter)
+	seek := canSeek && rangeHeader != ""
+
+	var data []byte
+	var size int64
+	var err error
+	if seek {
+		size, err = rangeGetter.Size(path)
+	} else {
+		data, err = s.storage.Get(path)
+		size = int64(len(data))
+	}
+	if err != nil {
+		s.audit(r, audit.ActionDownload, path, audit.ResultFailed, err)
+		writeJSONError(w, err)
+		return
+	}
+
+	rng, err := parseRange(rangeHeader, size)
+	if err != nil {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		errors.WriteJSON(w, http.StatusRequestedRangeNotSatisfiable, err)
 		return
 	}
 
-	data, err := s.storage.Get(path)
+	switch {
+	case seek && rng != nil:
+		data, err = rangeGetter.GetRange(path, rng.start, rng.length)
+	case seek:
+		// The Range header resolved to the whole file
+		data, err = s.storage.Get(path)
+	case rng != nil:
+		data = data[rng.start : rng.start+rng.length]
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeJSONError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Length", strconv.FormatInt(int64(len(data)), 10))
+	if rng != nil {
+		w.Header().Set("Content-Range", rng.contentRange(size))
+		w.WriteHeader(http.StatusPartialContent)
+	}
 	if _, err := w.Write(data); err != nil {
-		http.Error(w, fmt.Sprintf("write failed: %v", err), http.StatusInternalServerError)
+		s.audit(r, audit.ActionDownload, path, audit.ResultFailed, err)
+		errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("write failed: %w", err))
 		return
 	}
+	s.audit(r, audit.ActionDownload, path, audit.ResultOK, nil)
+}
+
+// handleMetrics reports storage operation timings when the storage is instrumented
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	instrumented, ok := s.storage.(*storage.Instrumented)
+	if !ok {
+		errors.WriteJSON(w, http.StatusNotFound, stderrors.New("metrics not enabled"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := instrumented.WriteMetrics(w); err != nil {
+		fmt.Printf("Warning: failed to write metrics: %v\n", err)
+	}
 }
 
 func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Query().Get("path")
+	path := normalizePath(r.URL.Query().Get("path"))
 	if path == "" {
 		path = "/"
 	}
+	if !s.allowListing(w, r, path) {
+		return
+	}
 
 	files, err := s.storage.List(path)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeJSONError(w, err)
 		return
 	}
 
+	scope := auth.RequestScope(r)
+	visible := files[:0]
+	for _, name := range files {
+		if s.listed(scope, path, name) {
+			visible = append(visible, name)
+		}
+	}
+	files = visible
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(files); err != nil {
-		http.Error(w, fmt.Sprintf("encode failed: %v", err), http.StatusInternalServerError)
+		errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("encode failed: %w", err))
 		return
 	}
 }
 
-func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+// listed reports whether the entry name belongs in a listing of dir. Staged
+// uploads stay hidden until they are published, entries outside the token's
+// scope unless they lead to it, and dotfiles unless SetShowHidden is on or
+// dir itself is hidden, as when listing a dotfile by name.
+func (s *Server) listed(scope auth.PathScope, dir, name string) bool {
+	dir = strings.Trim(dir, "/")
+	if dir == "" && name == StagingDir {
+		return false
+	}
+	if !scope.Allows(dir) {
+		entry := strings.TrimPrefix(dir+"/"+name, "/")
+		if !scope.Allows(entry) && !scope.Leads(entry) {
+			return false
+		}
+	}
+	if s.showHidden || !strings.HasPrefix(name, ".") {
+		return true
+	}
+	return strings.HasPrefix(dir[strings.LastIndex(dir, "/")+1:], ".")
+}
+
+// ListEntry describes one entry of a detailed directory listing
+type ListEntry struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	IsDir   bool      `json:"is_dir"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+func (s *Server) handleListDetailed(w http.ResponseWriter, r *http.Request) {
+	path := normalizePath(r.URL.Query().Get("path"))
+	if path == "" {
+		path = "/"
+	}
+
+	if !s.allowListing(w, r, path) {
+		return
+	}
+
+	infos, err := storage.ListDetailed(s.storage, path)
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+
+	scope := auth.RequestScope(r)
+	entries := make([]ListEntry, 0, len(infos))
+	for _, info := range infos {
+		if !s.listed(scope, path, info.Name) {
+			continue
+		}
+		entries = append(entries, ListEntry{Name: info.Name, Size: info.Size, IsDir: info.IsDir, ModTime: info.ModTime})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("encode failed: %w", err))
+		return
+	}
+}
+
+// StatResponse describes a stored file or directory
+type StatResponse struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	IsDir   bool      `json:"is_dir"`
+}
+
+func (s *Server) handleStat(w http.ResponseWriter, r *http.Request) {
+	path := normalizePath(r.URL.Query().Get("path"))
+	if path == "" {
+		path = "/"
+	}
+	if !s.allowListing(w, r, path) {
+		return
+	}
+
+	info, err := storage.Stat(s.storage, path)
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := StatResponse{Path: path, Size: info.Size, ModTime: info.ModTime, IsDir: info.IsDir}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("encode failed: %w", err))
 		return
 	}
+}
 
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 	if s.serverConfig == nil {
-		http.Error(w, "server config not available", http.StatusInternalServerError)
+		errors.WriteJSON(w, http.StatusInternalServerError, stderrors.New("server config not available"))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*") // Allow cross-origin for discovery
 	if err := json.NewEncoder(w).Encode(s.serverConfig); err != nil {
-		http.Error(w, fmt.Sprintf("encode failed: %v", err), http.StatusInternalServerError)
+		errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("encode failed: %w", err))
 		return
 	}
 }
 
-func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+func (s *Server) handleUpdateManifest(w http.ResponseWriter, r *http.Request) {
+	if s.updateManifest == "" {
+		errors.WriteJSON(w, http.StatusNotFound, stderrors.New("no update manifest available"))
 		return
 	}
+	data, err := os.ReadFile(s.updateManifest)
+	if err != nil {
+		fmt.Printf("Warning: failed to read update manifest: %v\n", err)
+		errors.WriteJSON(w, http.StatusNotFound, stderrors.New("no update manifest available"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
 
-	path := r.URL.Query().Get("path")
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	path := normalizePath(r.URL.Query().Get("path"))
 	if path == "" {
-		http.Error(w, "path parameter required", http.StatusBadRequest)
+		writeJSONError(w, errors.NewValidationError("path", "path parameter required"))
+		return
+	}
+	if !s.allowPath(w, r, path) {
 		return
 	}
 
 	if err := s.storage.Delete(path); err != nil {
-		http.Error(w, fmt.Sprintf("delete failed: %v", err), http.StatusInternalServerError)
+		s.audit(r, audit.ActionDelete, path, audit.ResultFailed, err)
+		writeJSONError(w, fmt.Errorf("delete failed: %w", err))
 		return
 	}
+	s.hashes.forget(path)
+	s.audit(r, audit.ActionDelete, path, audit.ResultOK, nil)
 
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprintf(w, "Successfully deleted: %s", path)
 }
 
 func (s *Server) handleMkdir(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	path := normalizePath(r.URL.Query().Get("path"))
+	if path == "" {
+		writeJSONError(w, errors.NewValidationError("path", "path parameter required"))
 		return
 	}
-
-	path := r.URL.Query().Get("path")
-	if path == "" {
-		http.Error(w, "path parameter required", http.StatusBadRequest)
+	if !s.allowPath(w, r, path) {
 		return
 	}
 
+	// Parents are created unless the client explicitly asks for a strict
+	// single-level create, which older clients never do
+	if r.URL.Query().Get("parents") == "false" {
+		if s.storage.Exists(path) {
+			writeJSONError(w, errors.NewStorageError(errors.StorageErrorAlreadyExists, path, "mkdir failed: path already exists"))
+			return
+		}
+		if parent := parentDir(path); parent != "" && !s.storage.Exists(parent) {
+			writeJSONError(w, errors.NewStorageError(errors.StorageErrorNotFound, parent, "mkdir failed: parent directory does not exist"))
+			return
+		}
+	}
+
 	if err := s.storage.Mkdir(path); err != nil {
-		http.Error(w, fmt.Sprintf("mkdir failed: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, fmt.Errorf("mkdir failed: %w", err))
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusOK)
 	fmt.Fprintf(w, "Successfully created directory: %s", path)
 }
@@ -0,0 +1,1357 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/audit"
+	"github.com/0xRepo-Source/goflux-lite/pkg/auth"
+	"github.com/0xRepo-Source/goflux-lite/pkg/chunk"
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+	"github.com/0xRepo-Source/goflux-lite/pkg/resume"
+	"github.com/0xRepo-Source/goflux-lite/pkg/storage"
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// newTestServer creates a server backed by temporary storage and metadata directories
+func newTestServer(t *testing.T) (*Server, *storage.Local) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	store, err := storage.NewLocal(filepath.Join(tmpDir, "data"))
+	if err != nil {
+		t.Fatalf("NewLocal failed: %v", err)
+	}
+
+	srv, err := New(store, filepath.Join(tmpDir, "meta"))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	return srv, store
+}
+
+// postChunk sends a chunk to the upload handler and returns the recorded response
+func postChunk(t *testing.T, srv *Server, chunk transport.ChunkData) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body, err := json.Marshal(chunk)
+	if err != nil {
+		t.Fatalf("failed to marshal chunk: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	srv.handleUpload(rec, req)
+	return rec
+}
+
+func TestHandleUpload_SingleChunk(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	rec := postChunk(t, srv, transport.ChunkData{Path: "hello.txt", ChunkID: 0, Data: []byte("hello"), Total: 1})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	data, err := store.Get("hello.txt")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("expected hello, got %s", data)
+	}
+}
+
+func TestHandleUpload_EmptyFile(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	empty := chunk.Checksum(nil)
+	rec := postChunk(t, srv, transport.ChunkData{Path: "empty.txt", ChunkID: 0, Data: []byte{}, Checksum: empty, FileHash: empty, Total: 1})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	info, err := store.Stat("empty.txt")
+	if err != nil {
+		t.Fatalf("expected empty file to be stored: %v", err)
+	}
+	if info.Size != 0 {
+		t.Errorf("expected size 0, got %d", info.Size)
+	}
+
+	rec = getWithRange(srv, "empty.txt", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 downloading empty file, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Body.Len() != 0 || rec.Header().Get("Content-Length") != "0" {
+		t.Errorf("expected empty download, got %d bytes (Content-Length %q)", rec.Body.Len(), rec.Header().Get("Content-Length"))
+	}
+
+	// A file with no chunks at all is malformed, not empty
+	rec = postChunk(t, srv, transport.ChunkData{Path: "none.txt", ChunkID: 0, Total: 0})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for total of 0, got %d", rec.Code)
+	}
+}
+
+func TestHandleUpload_SingleChunkRetries(t *testing.T) {
+	srv, store := newTestServer(t)
+	old := []byte("old version")
+	postChunk(t, srv, transport.ChunkData{Path: "small.bin", ChunkID: 0, Data: old, Total: 1})
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256*1024)
+	upload := transport.ChunkData{Path: "small.bin", ChunkID: 0, Data: data, Checksum: chunk.Checksum(data), FileHash: chunk.Checksum(data), Total: 1}
+
+	// Readers must only ever see the previous file or the complete new one
+	stop := make(chan struct{})
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			got, err := store.Get("small.bin")
+			if err != nil {
+				t.Errorf("Get failed during retries: %v", err)
+				return
+			}
+			if !bytes.Equal(got, old) && !bytes.Equal(got, data) {
+				t.Errorf("read a partial file of %d bytes", len(got))
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rec := postChunk(t, srv, upload); rec.Code != http.StatusOK {
+				t.Errorf("expected 200 for retried upload, got %d: %s", rec.Code, rec.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	<-readerDone
+
+	got, err := store.Get("small.bin")
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("expected the uploaded file intact, got %d bytes (%v)", len(got), err)
+	}
+	names, _ := store.List("")
+	if len(names) != 1 {
+		t.Errorf("expected only small.bin in storage, got %v", names)
+	}
+	if _, exists := srv.sessionStore.GetSession("small.bin"); exists {
+		t.Error("expected no session left after the retries")
+	}
+	if entries, _ := os.ReadDir(srv.chunksDir); len(entries) != 0 {
+		t.Errorf("expected no chunks left after the retries, got %d entries", len(entries))
+	}
+}
+
+func TestHandleUpload_ChunkIDOutOfRange(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	tests := []struct {
+		name    string
+		chunkID int
+		total   int
+	}{
+		{name: "negative chunk ID", chunkID: -1, total: 2},
+		{name: "chunk ID equals total", chunkID: 2, total: 2},
+		{name: "huge chunk ID", chunkID: 999999, total: 2},
+		{name: "zero total", chunkID: 0, total: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postChunk(t, srv, transport.ChunkData{Path: "bad.bin", ChunkID: tt.chunkID, Data: []byte("data"), Total: tt.total})
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	// Nothing should have been written for the rejected chunks
+	entries, err := os.ReadDir(srv.chunksDir)
+	if err != nil {
+		t.Fatalf("failed to read chunks dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no chunk directories, found %d", len(entries))
+	}
+	if _, exists := srv.sessionStore.GetSession("bad.bin"); exists {
+		t.Error("expected no session for rejected chunks")
+	}
+}
+
+func TestHandleUpload_InconsistentChunkSize(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	rec := postChunk(t, srv, transport.ChunkData{Path: "sized.bin", ChunkID: 0, Data: []byte("0123456789"), Total: 3})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for first chunk, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// A middle chunk must match the session chunk size exactly
+	rec = postChunk(t, srv, transport.ChunkData{Path: "sized.bin", ChunkID: 1, Data: []byte("short"), Total: 3})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for short middle chunk, got %d", rec.Code)
+	}
+
+	// The last chunk may be shorter but never longer
+	rec = postChunk(t, srv, transport.ChunkData{Path: "sized.bin", ChunkID: 2, Data: []byte("0123456789ABC"), Total: 3})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for oversized last chunk, got %d", rec.Code)
+	}
+
+	// Correctly sized chunks still complete the upload
+	postChunk(t, srv, transport.ChunkData{Path: "sized.bin", ChunkID: 1, Data: []byte("abcdefghij"), Total: 3})
+	rec = postChunk(t, srv, transport.ChunkData{Path: "sized.bin", ChunkID: 2, Data: []byte("xyz"), Total: 3})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for last chunk, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	data, err := store.Get("sized.bin")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(data) != "0123456789abcdefghijxyz" {
+		t.Errorf("unexpected assembled content: %s", data)
+	}
+}
+
+func TestHandleUpload_DuplicateChunk(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	postChunk(t, srv, transport.ChunkData{Path: "dup.bin", ChunkID: 0, Data: []byte("aaaa"), Total: 2})
+
+	// Resending chunk 0 is acknowledged but doesn't overwrite what's on disk
+	rec := postChunk(t, srv, transport.ChunkData{Path: "dup.bin", ChunkID: 0, Data: []byte("bbbb"), Total: 2})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for duplicate chunk, got %d", rec.Code)
+	}
+
+	postChunk(t, srv, transport.ChunkData{Path: "dup.bin", ChunkID: 1, Data: []byte("cc"), Total: 2})
+
+	data, err := store.Get("dup.bin")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(data) != "aaaacc" {
+		t.Errorf("expected aaaacc, got %s", data)
+	}
+}
+
+func TestHandleUpload_MaxFilesPerDir(t *testing.T) {
+	srv, store := newTestServer(t)
+	srv.SetMaxFilesPerDir(3)
+
+	upload := func(path string, chunkID, total int) *httptest.ResponseRecorder {
+		data := []byte("data")
+		return postChunk(t, srv, transport.ChunkData{Path: path, ChunkID: chunkID, Total: total, Data: data, Checksum: chunk.Checksum(data)})
+	}
+
+	// A multi-chunk upload started below the cap may finish after it is reached
+	if rec := upload("docs/big.bin", 0, 2); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 starting docs/big.bin, got %d: %s", rec.Code, rec.Body.String())
+	}
+	for _, name := range []string{"docs/a.txt", "docs/b.txt", "docs/c.txt"} {
+		if rec := upload(name, 0, 1); rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 uploading %s, got %d: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := upload("docs/d.txt", 0, 1)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 past the cap, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "subdirectory") {
+		t.Errorf("expected the error to suggest a subdirectory, got %q", rec.Body.String())
+	}
+	if store.Exists("docs/d.txt") {
+		t.Error("rejected file was stored")
+	}
+
+	// New subdirectories are entries too
+	if rec := upload("docs/sub/e.txt", 0, 1); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 creating a subdirectory past the cap, got %d", rec.Code)
+	}
+
+	// Replacing a file doesn't add an entry, and other directories have room
+	if rec := upload("docs/a.txt", 0, 1); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 replacing docs/a.txt, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := upload("other/f.txt", 0, 1); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 uploading into another directory, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := upload("docs/big.bin", 1, 2); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 finishing docs/big.bin, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleUpload_MaxFileSize(t *testing.T) {
+	srv, store := newTestServer(t)
+	srv.SetMaxFileSize(10)
+
+	upload := func(path string, chunks ...string) int {
+		code := http.StatusOK
+		for i, data := range chunks {
+			rec := postChunk(t, srv, transport.ChunkData{Path: path, ChunkID: i, Data: []byte(data), Total: len(chunks)})
+			if code = rec.Code; code != http.StatusOK {
+				break
+			}
+		}
+		return code
+	}
+
+	// Just under and at the limit
+	if code := upload("nine.bin", "aaaa", "bbbb", "c"); code != http.StatusOK {
+		t.Fatalf("expected 200 for a 9-byte file, got %d", code)
+	}
+	if code := upload("ten.bin", "aaaa", "bbbb", "cc"); code != http.StatusOK {
+		t.Fatalf("expected 200 for a 10-byte file, got %d", code)
+	}
+	if data, err := store.Get("ten.bin"); err != nil || string(data) != "aaaabbbbcc" {
+		t.Errorf("expected ten.bin stored, got %q (%v)", data, err)
+	}
+
+	// Just over: the size is only known once the last chunk arrives
+	if code := upload("eleven.bin", "aaaa", "bbbb", "ccc"); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for an 11-byte file, got %d", code)
+	}
+	if _, ok := srv.sessionStore.GetSession("eleven.bin"); ok {
+		t.Error("expected the oversized upload's session to be discarded")
+	}
+	if _, err := os.Stat(srv.sessionChunksDir("eleven.bin")); !os.IsNotExist(err) {
+		t.Errorf("expected the oversized upload's chunks to be removed, got %v", err)
+	}
+	if store.Exists("eleven.bin") {
+		t.Error("expected the oversized file not to be stored")
+	}
+
+	// Chunk layouts that can't fit are refused at the first chunk
+	rec := postChunk(t, srv, transport.ChunkData{Path: "many.bin", ChunkID: 0, Data: []byte("aaaa"), Total: 4})
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for four 4-byte chunks, got %d", rec.Code)
+	}
+	rec = postChunk(t, srv, transport.ChunkData{Path: "single.bin", ChunkID: 0, Data: []byte("0123456789a"), Total: 1})
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for an 11-byte single chunk, got %d", rec.Code)
+	}
+}
+
+func TestHandleUpload_MaxTotalBytes(t *testing.T) {
+	srv, store := newTestServer(t)
+	srv.SetMaxTotalBytes(10)
+
+	upload := func(path, data string) int {
+		return postChunk(t, srv, transport.ChunkData{Path: path, ChunkID: 0, Data: []byte(data), Total: 1}).Code
+	}
+
+	if code := upload("a.txt", "12345678"); code != http.StatusOK {
+		t.Fatalf("expected 200 within the quota, got %d", code)
+	}
+	if code := upload("b.txt", "1234"); code != http.StatusInsufficientStorage {
+		t.Fatalf("expected 507 beyond the quota, got %d", code)
+	}
+	if _, ok := srv.sessionStore.GetSession("b.txt"); ok {
+		t.Error("expected no session for a refused upload")
+	}
+
+	// Replacing a file only needs room for the difference
+	if code := upload("a.txt", "1234567890"); code != http.StatusOK {
+		t.Errorf("expected 200 replacing a file within the quota, got %d", code)
+	}
+
+	if err := store.Delete("a.txt"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if code := upload("b.txt", "1234"); code != http.StatusOK {
+		t.Errorf("expected 200 after a delete freed space, got %d", code)
+	}
+}
+
+func TestHandleUpload_MaxTotalBytesCountsOpenUploads(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetMaxTotalBytes(10)
+
+	// The first chunk of an upload of at least 9 bytes holds that space until
+	// it finishes
+	rec := postChunk(t, srv, transport.ChunkData{Path: "a.bin", ChunkID: 0, Data: []byte("1234"), Total: 3})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 within the quota, got %d", rec.Code)
+	}
+	rec = postChunk(t, srv, transport.ChunkData{Path: "b.bin", ChunkID: 0, Data: []byte("1234"), Total: 1})
+	if rec.Code != http.StatusInsufficientStorage {
+		t.Fatalf("expected 507 while another upload holds the space, got %d", rec.Code)
+	}
+
+	// Stored, the file counts as usage instead of a reservation
+	postChunk(t, srv, transport.ChunkData{Path: "a.bin", ChunkID: 1, Data: []byte("5678"), Total: 3})
+	rec = postChunk(t, srv, transport.ChunkData{Path: "a.bin", ChunkID: 2, Data: []byte("9"), Total: 3})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 completing the upload, got %d", rec.Code)
+	}
+	if rec = postChunk(t, srv, transport.ChunkData{Path: "c.bin", ChunkID: 0, Data: []byte("0"), Total: 1}); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for the remaining space, got %d", rec.Code)
+	}
+
+	// A discarded upload gives its space back
+	srv.SetMaxTotalBytes(100)
+	postChunk(t, srv, transport.ChunkData{Path: "d.bin", ChunkID: 0, Data: []byte("1"), Total: 3})
+	srv.discardSession("d.bin")
+	if len(srv.reserved) != 0 {
+		t.Errorf("expected no reservations left, got %v", srv.reserved)
+	}
+}
+
+func TestHandleUploadStatus_Checksums(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	chunks := [][]byte{[]byte("aaaa"), []byte("bbbb"), []byte("cc")}
+	for id := 0; id < 2; id++ {
+		postChunk(t, srv, transport.ChunkData{Path: "damaged.bin", ChunkID: id, Data: chunks[id], Total: 3})
+	}
+	os.WriteFile(filepath.Join(srv.sessionChunksDir("damaged.bin"), "chunk_000001.dat"), []byte("bxbb"), 0644)
+
+	req := httptest.NewRequest(http.MethodGet, "/upload/status?path=damaged.bin&checksums=true", nil)
+	rec := httptest.NewRecorder()
+	srv.handleUploadStatus(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var status UploadStatusResponse
+	json.Unmarshal(rec.Body.Bytes(), &status)
+	want := []string{chunk.Checksum(chunks[0]), chunk.Checksum([]byte("bxbb")), ""}
+	if fmt.Sprint(status.Checksums) != fmt.Sprint(want) {
+		t.Errorf("expected checksums %v, got %v", want, status.Checksums)
+	}
+
+	// The damaged chunk can be replaced, which a plain resend would not do
+	rec = postChunk(t, srv, transport.ChunkData{Path: "damaged.bin", ChunkID: 1, Data: chunks[1], Total: 3, Replace: true})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	postChunk(t, srv, transport.ChunkData{Path: "damaged.bin", ChunkID: 2, Data: chunks[2], Total: 3})
+
+	data, err := store.Get("damaged.bin")
+	if err != nil || string(data) != "aaaabbbbcc" {
+		t.Errorf("expected aaaabbbbcc, got %q (%v)", data, err)
+	}
+}
+
+func TestHandleUpload_BoltSessionStore(t *testing.T) {
+	tmpDir := t.TempDir()
+	store, _ := storage.NewLocal(filepath.Join(tmpDir, "data"))
+	sessions, err := resume.NewBoltStore(filepath.Join(tmpDir, "meta", "sessions.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStore failed: %v", err)
+	}
+	defer sessions.Close()
+	srv, err := NewWithSessionStore(store, sessions, filepath.Join(tmpDir, "meta"))
+	if err != nil {
+		t.Fatalf("NewWithSessionStore failed: %v", err)
+	}
+
+	postChunk(t, srv, transport.ChunkData{Path: "bolt.bin", ChunkID: 1, Data: []byte("cc"), Total: 2})
+	if _, ok := sessions.GetSession("bolt.bin"); !ok {
+		t.Fatal("expected session in the bolt store")
+	}
+	rec := postChunk(t, srv, transport.ChunkData{Path: "bolt.bin", ChunkID: 0, Data: []byte("aaaa"), Total: 2})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if data, err := store.Get("bolt.bin"); err != nil || string(data) != "aaaacc" {
+		t.Errorf("expected aaaacc, got %q (%v)", data, err)
+	}
+	if _, ok := sessions.GetSession("bolt.bin"); ok {
+		t.Error("expected completed session to be removed")
+	}
+}
+
+func TestHandleUpload_Checksum(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	// A chunk altered in transit no longer matches the checksum the client computed
+	data := []byte("0123456789")
+	corrupted := []byte("0123456788")
+	rec := postChunk(t, srv, transport.ChunkData{Path: "sum.bin", ChunkID: 0, Data: corrupted, Checksum: chunk.Checksum(data), Total: 2})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for corrupted chunk, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, ok := srv.sessionStore.GetSession("sum.bin"); ok {
+		t.Error("expected corrupted chunk not to open a session")
+	}
+
+	rec = postChunk(t, srv, transport.ChunkData{Path: "sum.bin", ChunkID: 0, Data: data, Checksum: chunk.Checksum(data), Total: 2})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for matching checksum, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Clients that send no checksum are still accepted
+	rec = postChunk(t, srv, transport.ChunkData{Path: "sum.bin", ChunkID: 1, Data: []byte("ab"), Total: 2})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 without checksum, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	got, err := store.Get("sum.bin")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(got) != "0123456789ab" {
+		t.Errorf("unexpected assembled content: %s", got)
+	}
+}
+
+func TestHandleUpload_FileHash(t *testing.T) {
+	srv, store := newTestServer(t)
+	want := contentHash([]byte("0123456789ab"))
+
+	postChunk(t, srv, transport.ChunkData{Path: "good.bin", ChunkID: 0, Data: []byte("0123456789"), FileHash: want, Total: 2})
+	rec := postChunk(t, srv, transport.ChunkData{Path: "good.bin", ChunkID: 1, Data: []byte("ab"), Total: 2})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for matching file hash, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got, err := store.Get("good.bin"); err != nil || string(got) != "0123456789ab" {
+		t.Errorf("expected assembled file to be stored, got %q (%v)", got, err)
+	}
+
+	// Chunks that each pass their checksum can still assemble the wrong file
+	postChunk(t, srv, transport.ChunkData{Path: "bad.bin", ChunkID: 0, Data: []byte("0123456789"), FileHash: want, Total: 2})
+	rec = postChunk(t, srv, transport.ChunkData{Path: "bad.bin", ChunkID: 1, Data: []byte("zz"), Total: 2})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for mismatched file hash, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if store.Exists("bad.bin") {
+		t.Error("expected mismatched file not to be stored")
+	}
+	if _, ok := srv.sessionStore.GetSession("bad.bin"); ok {
+		t.Error("expected session to be discarded so the upload can start over")
+	}
+	if _, err := os.Stat(srv.sessionChunksDir("bad.bin")); !os.IsNotExist(err) {
+		t.Errorf("expected chunks directory to be removed, got %v", err)
+	}
+}
+
+func TestHandleUpload_SessionMismatch(t *testing.T) {
+	srv, _ := newTestServer(t)
+	postChunk(t, srv, transport.ChunkData{Path: "f.bin", ChunkID: 0, Data: []byte("0123456789"), FileHash: contentHash([]byte("0123456789ab")), Total: 3})
+
+	// A changed file declares another hash or chunk count than the upload
+	// under way, which the client has to abort and start over
+	for _, c := range []transport.ChunkData{
+		{Path: "f.bin", ChunkID: 1, Data: []byte("0123456789"), FileHash: contentHash([]byte("0123456789cd")), Total: 3},
+		{Path: "f.bin", ChunkID: 1, Data: []byte("0123456789"), Total: 2},
+	} {
+		rec := postChunk(t, srv, c)
+		if rec.Code != http.StatusConflict {
+			t.Errorf("expected 409 for a chunk of another file, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestHandleUpload_ExistingDirectory(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Mkdir("docs")
+
+	rec := postChunk(t, srv, transport.ChunkData{Path: "docs", ChunkID: 0, Data: []byte("0123456789"), Total: 2})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 uploading onto a directory, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "existing directory") {
+		t.Errorf("expected a clear error, got %q", rec.Body.String())
+	}
+	if _, ok := srv.sessionStore.GetSession("docs"); ok {
+		t.Error("expected no session for rejected upload")
+	}
+	if _, err := os.Stat(srv.sessionChunksDir("docs")); !os.IsNotExist(err) {
+		t.Errorf("expected no chunks to be written, got %v", err)
+	}
+
+	// Files inside the directory upload normally
+	rec = postChunk(t, srv, transport.ChunkData{Path: "docs/readme.txt", ChunkID: 0, Data: []byte("hello"), Total: 1})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a file upload, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got, err := store.Get("docs/readme.txt"); err != nil || string(got) != "hello" {
+		t.Errorf("expected stored file, got %q (%v)", got, err)
+	}
+}
+
+func TestHandleUpload_TruncatedBody(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	body, _ := json.Marshal(transport.ChunkData{Path: "cut.bin", ChunkID: 0, Data: []byte("0123456789"), Total: 2})
+
+	// A body shorter than its declared length is rejected even if it decodes
+	req := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(body))
+	req.ContentLength = int64(len(body) + 16)
+	rec := httptest.NewRecorder()
+	srv.handleUpload(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for short body, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// A connection that ends partway through the body is rejected too
+	ts := httptest.NewServer(http.HandlerFunc(srv.handleUpload))
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "POST /upload HTTP/1.1\r\nHost: test\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n", len(body))
+	conn.Write(body[:len(body)/2])
+	conn.(*net.TCPConn).CloseWrite()
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("ReadResponse failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for truncated stream, got %d", resp.StatusCode)
+	}
+
+	if _, ok := srv.sessionStore.GetSession("cut.bin"); ok {
+		t.Error("expected no session for truncated uploads")
+	}
+}
+
+func TestHandleMetrics(t *testing.T) {
+	srv, _ := newTestServer(t)
+	rec := httptest.NewRecorder()
+	srv.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without instrumented storage, got %d", rec.Code)
+	}
+
+	local, _ := storage.NewLocal(t.TempDir())
+	srv, err := New(storage.NewInstrumented(local, time.Second), t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	postChunk(t, srv, transport.ChunkData{Path: "m.txt", ChunkID: 0, Data: []byte("hi"), Total: 1})
+
+	rec = httptest.NewRecorder()
+	srv.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `goflux_storage_operation_duration_seconds_count{op="put"} 1`) {
+		t.Errorf("expected put to be counted:\n%s", rec.Body.String())
+	}
+}
+
+func TestHandleMetrics_RequiresPermission(t *testing.T) {
+	local, _ := storage.NewLocal(t.TempDir())
+	srv, err := New(storage.NewInstrumented(local, time.Second), t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	enableTestAuthPermissions(t, srv, map[string][]string{"alice": {"upload"}, "prometheus": {"metrics"}})
+	handler := srv.handler()
+
+	if rec := serveRoute(handler, http.MethodGet, "/metrics", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", rec.Code)
+	}
+	if rec := serveRoute(handler, http.MethodGet, "/metrics", "alice"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without the metrics permission, got %d", rec.Code)
+	}
+	if rec := serveRoute(handler, http.MethodGet, "/metrics", "prometheus"); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 with the metrics permission, got %d", rec.Code)
+	}
+}
+
+// listPath calls the list handler for a path and returns the recorded response
+func listPath(srv *Server, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/list?path="+path, nil)
+	rec := httptest.NewRecorder()
+	srv.handleList(rec, req)
+	return rec
+}
+
+func TestHandleList_FilePath(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("docs/readme.txt", []byte("hi"))
+
+	rec := listPath(srv, "docs/readme.txt")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var names []string
+	if err := json.Unmarshal(rec.Body.Bytes(), &names); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(names) != 1 || names[0] != "readme.txt" {
+		t.Errorf("expected [readme.txt], got %v", names)
+	}
+}
+
+func TestHandleList_Directory(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("docs/a.txt", []byte("a"))
+	store.Put("docs/b.txt", []byte("b"))
+
+	rec := listPath(srv, "docs")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var names []string
+	json.Unmarshal(rec.Body.Bytes(), &names)
+	if len(names) != 2 {
+		t.Errorf("expected 2 entries, got %v", names)
+	}
+}
+
+func TestHandleList_Missing(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	rec := listPath(srv, "nope")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestHandleList_HiddenFiles(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("docs/a.txt", []byte("a"))
+	store.Put("docs/.env", []byte("secret"))
+	store.Put("docs/.cache/x", []byte("x"))
+
+	names := func(rec *httptest.ResponseRecorder) []string {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var names []string
+		json.Unmarshal(rec.Body.Bytes(), &names)
+		return names
+	}
+	detailed := func(rec *httptest.ResponseRecorder) []string {
+		t.Helper()
+		var entries []ListEntry
+		json.Unmarshal(rec.Body.Bytes(), &entries)
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		return names
+	}
+
+	// Hidden by default
+	if got := names(listPath(srv, "docs")); len(got) != 1 || got[0] != "a.txt" {
+		t.Errorf("expected dotfiles to be hidden, got %v", got)
+	}
+	if got := detailed(callPathHandler(srv.handleListDetailed, http.MethodGet, "/list/detailed", "docs")); len(got) != 1 || got[0] != "a.txt" {
+		t.Errorf("expected dotfiles to be hidden from detailed listing, got %v", got)
+	}
+
+	// A hidden path named explicitly is still listed
+	if got := names(listPath(srv, "docs/.env")); len(got) != 1 || got[0] != ".env" {
+		t.Errorf("expected [.env] listing the file by name, got %v", got)
+	}
+
+	srv.SetShowHidden(true)
+	if got := names(listPath(srv, "docs")); len(got) != 3 {
+		t.Errorf("expected dotfiles with show_hidden, got %v", got)
+	}
+	if got := detailed(callPathHandler(srv.handleListDetailed, http.MethodGet, "/list/detailed", "docs")); len(got) != 3 {
+		t.Errorf("expected dotfiles in detailed listing with show_hidden, got %v", got)
+	}
+
+	// The staging directory stays hidden even so
+	store.Put(StagingDir+"/next/a.txt", []byte("a"))
+	if got := names(listPath(srv, "")); len(got) != 1 || got[0] != "docs" {
+		t.Errorf("expected staging directory to stay hidden with show_hidden, got %v", got)
+	}
+}
+
+// callPathHandler invokes a handler with the path query parameter set
+func TestHandleStat(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("docs/readme.txt", []byte("hello"))
+
+	tests := []struct {
+		path  string
+		code  int
+		size  int64
+		isDir bool
+	}{
+		{"docs/readme.txt", http.StatusOK, 5, false},
+		{"docs", http.StatusOK, 0, true},
+		{"docs/missing.txt", http.StatusNotFound, 0, false},
+	}
+	for _, tt := range tests {
+		rec := callPathHandler(srv.handleStat, http.MethodGet, "/stat", tt.path)
+		if rec.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d: %s", tt.path, tt.code, rec.Code, rec.Body.String())
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+
+		var stat StatResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &stat); err != nil {
+			t.Fatalf("%s: invalid JSON: %v", tt.path, err)
+		}
+		if stat.Path != tt.path || stat.Size != tt.size || stat.IsDir != tt.isDir || stat.ModTime.IsZero() {
+			t.Errorf("%s: unexpected response %+v", tt.path, stat)
+		}
+	}
+}
+
+func TestHandleListDetailed(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("docs/readme.txt", []byte("hello"))
+	store.Put("docs/img/logo.png", []byte("png"))
+	store.Put(StagingDir+"/next/a.txt", []byte("a"))
+
+	rec := callPathHandler(srv.handleListDetailed, http.MethodGet, "/list/detailed", "docs")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var entries []ListEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	if e := entries[0]; e.Name != "img" || !e.IsDir || e.Size != 0 {
+		t.Errorf("expected img directory, got %+v", e)
+	}
+	if e := entries[1]; e.Name != "readme.txt" || e.IsDir || e.Size != 5 || e.ModTime.IsZero() {
+		t.Errorf("expected 5-byte readme.txt, got %+v", e)
+	}
+
+	rec = callPathHandler(srv.handleListDetailed, http.MethodGet, "/list/detailed", "")
+	if strings.Contains(rec.Body.String(), StagingDir) {
+		t.Errorf("expected staging directory to be hidden, got %s", rec.Body.String())
+	}
+
+	rec = callPathHandler(srv.handleListDetailed, http.MethodGet, "/list/detailed", "missing")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for missing path, got %d", rec.Code)
+	}
+}
+
+func callPathHandler(handler http.HandlerFunc, method, endpoint, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, endpoint+"?path="+path, nil)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestHandleDelete(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("docs/old.txt", []byte("old"))
+
+	rec := callPathHandler(srv.handleDelete, http.MethodDelete, "/delete", "docs/old.txt")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if store.Exists("docs/old.txt") {
+		t.Error("expected file to be deleted")
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{name: "not found", path: "docs/missing.txt", status: http.StatusNotFound},
+		{name: "path traversal", path: "../outside.txt", status: http.StatusBadRequest},
+		{name: "missing path", path: "", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := callPathHandler(srv.handleDelete, http.MethodDelete, "/delete", tt.path)
+			if rec.Code != tt.status {
+				t.Errorf("expected %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		path    string
+		status  int
+		want    errors.ResponseError
+	}{
+		{"traversal", srv.handleDelete, "../outside.txt", http.StatusBadRequest, errors.ResponseError{Type: "storage.path_traversal", Path: "../outside.txt"}},
+		{"missing file", srv.handleDelete, "missing.txt", http.StatusNotFound, errors.ResponseError{Type: "storage.not_found", Path: "missing.txt"}},
+		{"missing parameter", srv.handleDelete, "", http.StatusBadRequest, errors.ResponseError{Type: "validation", Field: "path"}},
+		{"no upload", srv.handleUploadFinalize, "nothing.bin", http.StatusNotFound, errors.ResponseError{Type: "storage.not_found", Path: "nothing.bin"}},
+		{"untyped", srv.handleMetrics, "", http.StatusNotFound, errors.ResponseError{Type: "not_found"}},
+	}
+	for _, tt := range tests {
+		rec := callPathHandler(tt.handler, http.MethodPost, "/", tt.path)
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected %d, got %d: %s", tt.name, tt.status, rec.Code, rec.Body.String())
+			continue
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: expected a JSON body, got Content-Type %q", tt.name, ct)
+		}
+		got, ok := errors.ParseResponse(rec.Body.Bytes())
+		if !ok {
+			t.Errorf("%s: expected an error response, got %s", tt.name, rec.Body.String())
+			continue
+		}
+		if got.Type != tt.want.Type || got.Field != tt.want.Field || got.Path != tt.want.Path || got.Message == "" {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestJSONErrors_ReachClientTyped(t *testing.T) {
+	srv, _ := newTestServer(t)
+	ts := httptest.NewServer(srv.handler())
+	defer ts.Close()
+	client := transport.NewHTTPClient(ts.URL)
+
+	err := client.Delete("../outside.txt")
+	if errType, ok := errors.GetStorageErrorType(err); !ok || errType != errors.StorageErrorPathTraversal {
+		t.Fatalf("expected a path traversal StorageError, got %v", err)
+	}
+	var storageErr *errors.StorageError
+	if stderrors.As(err, &storageErr); storageErr.Path != "../outside.txt" {
+		t.Errorf("expected the path to come across, got %q", storageErr.Path)
+	}
+	if !errors.IsNetworkError(err) {
+		t.Errorf("expected the StorageError to stay wrapped in a NetworkError, got %v", err)
+	}
+
+	err = client.Mkdir("", true)
+	if !errors.IsValidationError(err) {
+		t.Errorf("expected a ValidationError, got %v", err)
+	}
+
+	_, err = client.Download("missing.txt")
+	if errType, ok := errors.GetStorageErrorType(err); !ok || errType != errors.StorageErrorNotFound {
+		t.Errorf("expected a not found StorageError, got %v", err)
+	}
+	if !stderrors.Is(err, transport.ErrNotFound) {
+		t.Errorf("expected ErrNotFound to stay wrapped, got %v", err)
+	}
+}
+
+func TestHandleMkdir(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	rec := callPathHandler(srv.handleMkdir, http.MethodPost, "/mkdir", "projects/new")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	info, err := os.Stat(filepath.Join(store.Root, "projects", "new"))
+	if err != nil || !info.IsDir() {
+		t.Errorf("expected directory to be created: %v", err)
+	}
+
+	rec = callPathHandler(srv.handleMkdir, http.MethodPost, "/mkdir", "../escape")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for traversal, got %d", rec.Code)
+	}
+
+	rec = callPathHandler(srv.handler().ServeHTTP, http.MethodGet, "/mkdir", "projects/other")
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for GET, got %d", rec.Code)
+	}
+	if allow := rec.Header().Get("Allow"); allow != http.MethodPost {
+		t.Errorf("expected Allow: POST, got %q", allow)
+	}
+}
+
+func TestBackslashPathsNormalized(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	// A Windows client uploads with backslash separators
+	rec := postChunk(t, srv, transport.ChunkData{Path: `files\reports\doc.pdf`, ChunkID: 0, Data: []byte("pdf"), Total: 1})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The file lands where a forward-slash client would put it
+	data, err := store.Get("files/reports/doc.pdf")
+	if err != nil {
+		t.Fatalf("expected file at forward-slash path: %v", err)
+	}
+	if string(data) != "pdf" {
+		t.Errorf("unexpected content: %s", data)
+	}
+	if _, err := os.Stat(filepath.Join(store.Root, `files\reports\doc.pdf`)); err == nil {
+		t.Error("backslash path created a separate file")
+	}
+
+	// Both separators resolve to the same file for other operations
+	rec = listPath(srv, `files\reports`)
+	var names []string
+	json.Unmarshal(rec.Body.Bytes(), &names)
+	if rec.Code != http.StatusOK || len(names) != 1 || names[0] != "doc.pdf" {
+		t.Errorf("expected [doc.pdf] listing backslash dir, got %d %v", rec.Code, names)
+	}
+
+	rec = getWithRange(srv, `files\reports\doc.pdf`, "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "pdf" {
+		t.Errorf("expected download via backslash path, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = callPathHandler(srv.handleMkdir, http.MethodPost, "/mkdir", `files\archive`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if info, err := os.Stat(filepath.Join(store.Root, "files", "archive")); err != nil || !info.IsDir() {
+		t.Errorf("expected files/archive directory: %v", err)
+	}
+
+	rec = callPathHandler(srv.handleDelete, http.MethodDelete, "/delete", `files\reports\doc.pdf`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if store.Exists("files/reports/doc.pdf") {
+		t.Error("expected file deleted via backslash path")
+	}
+}
+
+func TestHandleMkdir_Parents(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	// Strict create fails when the parent is missing
+	rec := callPathHandler(srv.handleMkdir, http.MethodPost, "/mkdir", "a/b/c&parents=false")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing parent, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(store.Root, "a")); !os.IsNotExist(err) {
+		t.Error("strict mkdir should not create parents")
+	}
+
+	// parents=true creates the full chain
+	rec = callPathHandler(srv.handleMkdir, http.MethodPost, "/mkdir", "a/b/c&parents=true")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if info, err := os.Stat(filepath.Join(store.Root, "a", "b", "c")); err != nil || !info.IsDir() {
+		t.Fatalf("expected a/b/c to exist: %v", err)
+	}
+
+	// Strict create succeeds once the parent exists, but not twice
+	rec = callPathHandler(srv.handleMkdir, http.MethodPost, "/mkdir", "a/b/d&parents=false")
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 with existing parent, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = callPathHandler(srv.handleMkdir, http.MethodPost, "/mkdir", "a/b/d&parents=false")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for existing directory, got %d", rec.Code)
+	}
+
+	// Top-level strict create has no parent to check
+	rec = callPathHandler(srv.handleMkdir, http.MethodPost, "/mkdir", "top&parents=false")
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for top-level directory, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPathScopedToken(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("docs/a.txt", []byte("a"))
+	store.Put("docs-private/b.txt", []byte("b"))
+	store.Put("projects/alpha/c.txt", []byte("c"))
+	store.Put("projects/beta/d.txt", []byte("d"))
+
+	tokenStore, err := auth.NewTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+	token, _, err := tokenStore.Create("alice", []string{"upload", "download"}, time.Hour, "docs", "projects/alpha")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	srv.EnableAuth(tokenStore)
+
+	call := func(perm string, handler http.HandlerFunc, method, endpoint, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, endpoint+"?path="+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		srv.authMiddle.RequireAuth(perm, handler)(rec, req)
+		return rec
+	}
+
+	if rec := call("download", srv.handleDownload, http.MethodGet, "/download", "docs/a.txt"); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 downloading docs/a.txt, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := call("download", srv.handleDownload, http.MethodGet, "/download", "docs-private/b.txt"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 downloading docs-private/b.txt, got %d", rec.Code)
+	}
+	if rec := call("download", srv.handleDownload, http.MethodGet, "/download", "docs/../docs-private/b.txt"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 escaping docs with .., got %d", rec.Code)
+	}
+	if rec := call("upload", srv.handleDelete, http.MethodDelete, "/delete", "projects/beta/d.txt"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 deleting outside the scope, got %d", rec.Code)
+	}
+	if rec := call("upload", srv.handleMkdir, http.MethodPost, "/mkdir", "projects/alpha/new"); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 creating a directory in scope, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	body, _ := json.Marshal(transport.ChunkData{Path: "docs-private/e.txt", ChunkID: 0, Total: 1, Data: []byte("e")})
+	req := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	srv.authMiddle.RequireAuth("upload", srv.handleUpload)(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 uploading outside the scope, got %d", rec.Code)
+	}
+
+	// Directories above the prefixes list only the entries leading to them
+	listed := func(path string) []string {
+		t.Helper()
+		rec := call("download", srv.handleList, http.MethodGet, "/list", path)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 listing %q, got %d: %s", path, rec.Code, rec.Body.String())
+		}
+		var names []string
+		json.Unmarshal(rec.Body.Bytes(), &names)
+		return names
+	}
+	if got := listed("/"); strings.Join(got, ",") != "docs,projects" {
+		t.Errorf("expected [docs projects] at the root, got %v", got)
+	}
+	if got := listed("projects"); strings.Join(got, ",") != "alpha" {
+		t.Errorf("expected [alpha] in projects, got %v", got)
+	}
+	if rec := call("download", srv.handleList, http.MethodGet, "/list", "projects/beta"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 listing projects/beta, got %d", rec.Code)
+	}
+}
+
+// recordingLogger keeps the audit events it is given
+type recordingLogger struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (l *recordingLogger) Log(event audit.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+func TestAuditLog(t *testing.T) {
+	srv, _ := newTestServer(t)
+	logger := &recordingLogger{}
+	srv.SetAuditLogger(logger)
+
+	tokenStore, err := auth.NewTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+	uploader, _, _ := tokenStore.Create("alice", []string{"upload"}, time.Hour)
+	reader, _, _ := tokenStore.Create("bob", []string{"download"}, time.Hour)
+	srv.EnableAuth(tokenStore)
+
+	upload := func(token, path string) int {
+		data := []byte("audited")
+		body, _ := json.Marshal(transport.ChunkData{Path: path, ChunkID: 0, Total: 1, Data: data, Checksum: chunk.Checksum(data)})
+		req := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		srv.authMiddle.RequireAuth("upload", srv.handleUpload)(rec, req)
+		return rec.Code
+	}
+
+	if code := upload(uploader, "docs/a.txt"); code != http.StatusOK {
+		t.Fatalf("expected 200 uploading, got %d", code)
+	}
+	if code := upload(reader, "docs/b.txt"); code != http.StatusForbidden {
+		t.Fatalf("expected 403 uploading without permission, got %d", code)
+	}
+	if code := upload("not-a-token", "docs/c.txt"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with a bad token, got %d", code)
+	}
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	want := []audit.Event{
+		{User: "alice", Action: audit.ActionUpload, Path: "docs/a.txt", RemoteIP: "192.0.2.1", Result: audit.ResultOK},
+		{User: "bob", Action: audit.ActionUpload, RemoteIP: "192.0.2.1", Result: audit.ResultDenied},
+		{Action: audit.ActionAuth, RemoteIP: "192.0.2.1", Result: audit.ResultDenied},
+	}
+	if len(logger.events) != len(want) {
+		t.Fatalf("expected %d audit events, got %+v", len(want), logger.events)
+	}
+	for i, got := range logger.events {
+		if got.User != want[i].User || got.Action != want[i].Action || got.Path != want[i].Path ||
+			got.RemoteIP != want[i].RemoteIP || got.Result != want[i].Result {
+			t.Errorf("event %d: expected %+v, got %+v", i, want[i], got)
+		}
+		if got.Time.IsZero() {
+			t.Errorf("event %d has no time", i)
+		}
+	}
+}
+
+func TestClientRoundTrip_EmptyFile(t *testing.T) {
+	srv, _ := newTestServer(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() { ln.Close() })
+
+	client := transport.NewHTTPClient(ln.Addr().String())
+
+	// Splitting an empty file gives the single empty chunk the server expects
+	chunks := chunk.New(1024).Split(nil)
+	for _, c := range chunks {
+		if err := client.UploadChunk(transport.ChunkData{Path: "empty.txt", ChunkID: c.ID, Data: c.Data, Checksum: c.Checksum, FileHash: c.Checksum, Total: len(chunks)}); err != nil {
+			t.Fatalf("UploadChunk failed: %v", err)
+		}
+	}
+
+	stat, err := client.Stat("empty.txt")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if stat.IsDir || stat.Size != 0 {
+		t.Errorf("expected an empty file, got %+v", stat)
+	}
+
+	localPath := filepath.Join(t.TempDir(), "empty.txt")
+	if err := client.DownloadResume("empty.txt", localPath); err != nil {
+		t.Fatalf("DownloadResume failed: %v", err)
+	}
+	info, err := os.Stat(localPath)
+	if err != nil {
+		t.Fatalf("expected downloaded file: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("expected downloaded size 0, got %d", info.Size())
+	}
+}
+
+func TestClientRoundTrip_SpecialCharacters(t *testing.T) {
+	srv, _ := newTestServer(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() { ln.Close() })
+
+	const remotePath = "docs/my report &v2.txt"
+	client := transport.NewHTTPClient(ln.Addr().String())
+
+	first, second := []byte("quarterly "), []byte("numbers")
+	if err := client.UploadChunk(transport.ChunkData{Path: remotePath, ChunkID: 0, Data: first, Checksum: chunk.Checksum(first), Total: 2}); err != nil {
+		t.Fatalf("UploadChunk failed: %v", err)
+	}
+
+	status, err := client.QueryUploadStatus(remotePath)
+	if err != nil {
+		t.Fatalf("QueryUploadStatus failed: %v", err)
+	}
+	if !status.Exists || len(status.MissingChunks) != 1 || status.MissingChunks[0] != 1 {
+		t.Errorf("expected chunk 1 missing, got %+v", status)
+	}
+
+	if err := client.UploadChunk(transport.ChunkData{Path: remotePath, ChunkID: 1, Data: second, Checksum: chunk.Checksum(second), Total: 2}); err != nil {
+		t.Fatalf("UploadChunk failed: %v", err)
+	}
+
+	for _, path := range []string{"docs", remotePath} {
+		names, err := client.List(path)
+		if err != nil {
+			t.Fatalf("List(%q) failed: %v", path, err)
+		}
+		if len(names) != 1 || names[0] != "my report &v2.txt" {
+			t.Errorf("List(%q): expected [my report &v2.txt], got %q", path, names)
+		}
+	}
+
+	stat, err := client.Stat(remotePath)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if stat.Path != remotePath || stat.Size != 17 || stat.IsDir {
+		t.Errorf("unexpected stat %+v", stat)
+	}
+
+	entries, err := client.ListDetailed("docs")
+	if err != nil {
+		t.Fatalf("ListDetailed failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "my report &v2.txt" || entries[0].Size != 17 || entries[0].IsDir {
+		t.Errorf("unexpected detailed listing %+v", entries)
+	}
+
+	data, err := client.Download(remotePath)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if string(data) != "quarterly numbers" {
+		t.Errorf("unexpected content %q", data)
+	}
+}
+
+func TestHandleUpdateManifest(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	srv.handleUpdateManifest(rec, httptest.NewRequest(http.MethodGet, "/version.json", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without a manifest, got %d", rec.Code)
+	}
+
+	// A file users can upload is never served as the manifest
+	store.Put("version.json", []byte(`{"version":"9.9.9"}`))
+	rec = httptest.NewRecorder()
+	srv.handleUpdateManifest(rec, httptest.NewRequest(http.MethodGet, "/version.json", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a stored version.json, got %d", rec.Code)
+	}
+
+	manifest := filepath.Join(t.TempDir(), "version.json")
+	os.WriteFile(manifest, []byte(`{"version":"0.3.0"}`), 0644)
+	srv.SetUpdateManifest(manifest)
+	rec = httptest.NewRecorder()
+	srv.handleUpdateManifest(rec, httptest.NewRequest(http.MethodGet, "/version.json", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"version":"0.3.0"}` {
+		t.Errorf("expected the configured manifest, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %q", ct)
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/auth"
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+// enableTestAuth turns on authentication with an upload token for each user,
+// where each user's token is their name
+func enableTestAuth(t *testing.T, srv *Server, users ...string) {
+	t.Helper()
+
+	permissions := make(map[string][]string)
+	for _, user := range users {
+		permissions[user] = []string{"upload"}
+	}
+	enableTestAuthPermissions(t, srv, permissions)
+}
+
+// enableTestAuthPermissions turns on authentication with a token for each
+// user holding the given permissions, where each user's token is their name
+func enableTestAuthPermissions(t *testing.T, srv *Server, permissions map[string][]string) {
+	t.Helper()
+
+	var file auth.TokenStoreFile
+	for user, perms := range permissions {
+		hash := sha256.Sum256([]byte(user))
+		file.Tokens = append(file.Tokens, auth.Token{
+			ID:          user,
+			TokenHash:   hex.EncodeToString(hash[:]),
+			User:        user,
+			Permissions: perms,
+			CreatedAt:   time.Now(),
+			ExpiresAt:   time.Now().Add(time.Hour),
+		})
+	}
+
+	tokensFile := filepath.Join(t.TempDir(), "tokens.json")
+	data, _ := json.Marshal(file)
+	if err := os.WriteFile(tokensFile, data, 0644); err != nil {
+		t.Fatalf("failed to write tokens: %v", err)
+	}
+
+	tokenStore, err := auth.NewTokenStore(tokensFile)
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+	srv.EnableAuth(tokenStore)
+}
+
+// postChunkAs uploads a chunk through the auth middleware as user
+func postChunkAs(srv *Server, user string, chunk transport.ChunkData) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(chunk)
+	req := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+user)
+	rec := httptest.NewRecorder()
+	srv.authMiddle.RequireAuth("upload", srv.handleUpload)(rec, req)
+	return rec
+}
+
+func TestMaxSessionsPerUser(t *testing.T) {
+	srv, _ := newTestServer(t)
+	enableTestAuth(t, srv, "alice", "bob")
+	srv.SetMaxSessionsPerUser(2)
+
+	first := func(path string) transport.ChunkData {
+		return transport.ChunkData{Path: path, ChunkID: 0, Data: []byte("aaaa"), Total: 2}
+	}
+
+	for _, path := range []string{"a.bin", "b.bin"} {
+		if rec := postChunkAs(srv, "alice", first(path)); rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 opening %s, got %d: %s", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := postChunkAs(srv, "alice", first("c.bin"))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 beyond the cap, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, ok := srv.sessionStore.GetSession("c.bin"); ok {
+		t.Error("expected no session for rejected upload")
+	}
+
+	// Chunks for sessions that are already open are still accepted
+	rec = postChunkAs(srv, "alice", transport.ChunkData{Path: "a.bin", ChunkID: 1, Data: []byte("bb"), Total: 2})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 completing a.bin, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Another user has their own allowance
+	if rec := postChunkAs(srv, "bob", first("x.bin")); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for another user, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Completing a.bin freed one of alice's slots
+	if rec := postChunkAs(srv, "alice", first("c.bin")); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 after a session completed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := postChunkAs(srv, "alice", first("d.bin")); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 once the cap is reached again, got %d", rec.Code)
+	}
+}
+
+func TestMaxSessionsPerUser_ByAddressWithoutAuth(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetMaxSessionsPerUser(1)
+
+	post := func(remoteAddr, path string) int {
+		body, _ := json.Marshal(transport.ChunkData{Path: path, ChunkID: 0, Data: []byte("aaaa"), Total: 2})
+		req := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		// Without auth the header is client-controlled and must be ignored
+		req.Header.Set("X-Authenticated-User", path)
+		rec := httptest.NewRecorder()
+		srv.handleUpload(rec, req)
+		return rec.Code
+	}
+
+	if code := post("10.0.0.1:5000", "one.bin"); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if code := post("10.0.0.1:5001", "two.bin"); code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 for the same address, got %d", code)
+	}
+	if code := post("10.0.0.2:5000", "three.bin"); code != http.StatusOK {
+		t.Errorf("expected 200 for another address, got %d", code)
+	}
+}
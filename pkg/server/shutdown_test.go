@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+func TestShutdown_DrainsInFlightRequests(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.Serve(ln) }()
+
+	// Start an upload whose body arrives slowly
+	payload, _ := json.Marshal(transport.ChunkData{Path: "inflight.txt", ChunkID: 0, Data: []byte("still arriving"), Total: 1})
+	body, pipe := io.Pipe()
+	respCh := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Post("http://"+ln.Addr().String()+"/upload", "application/json", body)
+		if err != nil {
+			respCh <- nil
+			return
+		}
+		respCh <- resp
+	}()
+	pipe.Write(payload[:10])
+
+	// Give the request time to reach the handler, then begin shutting down
+	time.Sleep(100 * time.Millisecond)
+	shutdownErr := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownErr <- srv.Shutdown(ctx)
+	}()
+
+	select {
+	case err := <-shutdownErr:
+		t.Fatalf("Shutdown returned before the in-flight request finished: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// Finish the request; it must complete and Shutdown must then return
+	pipe.Write(payload[10:])
+	pipe.Close()
+
+	resp := <-respCh
+	if resp == nil {
+		t.Fatal("in-flight request failed during shutdown")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 for in-flight request, got %d", resp.StatusCode)
+	}
+
+	if err := <-shutdownErr; err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if err := <-serveErr; err != http.ErrServerClosed {
+		t.Errorf("expected ErrServerClosed from Serve, got %v", err)
+	}
+	if data, err := store.Get("inflight.txt"); err != nil || string(data) != "still arriving" {
+		t.Errorf("expected uploaded file, got %q (%v)", data, err)
+	}
+
+	// New connections are refused once shut down
+	if _, err := net.DialTimeout("tcp", ln.Addr().String(), time.Second); err == nil {
+		t.Error("expected connections to be refused after shutdown")
+	}
+}
+
+func TestShutdown_NotServing(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Errorf("expected Shutdown without Serve to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+// maxStreamFieldSize bounds the metadata form fields of a streamed upload
+const maxStreamFieldSize = 4096
+
+// handleUploadStream accepts a chunk as multipart/form-data. The path, chunk_id
+// and total fields must precede the data part, whose bytes are copied straight
+// to disk rather than decoded from base64 JSON in memory.
+func (s *Server) handleUploadStream(w http.ResponseWriter, r *http.Request) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		errors.WriteJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	fields := make(map[string]string)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			errors.WriteJSON(w, http.StatusBadRequest, stderrors.New("missing data part"))
+			return
+		}
+		if err != nil {
+			errors.WriteJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if part.FormName() != "data" {
+			value, err := io.ReadAll(io.LimitReader(part, maxStreamFieldSize))
+			part.Close()
+			if err != nil {
+				errors.WriteJSON(w, http.StatusBadRequest, err)
+				return
+			}
+			fields[part.FormName()] = string(value)
+			continue
+		}
+
+		s.streamChunk(w, r, fields, part)
+		part.Close()
+		return
+	}
+}
+
+// streamChunk validates the chunk metadata, spools the chunk data to a
+// temporary file and hands it to storeChunk.
+func (s *Server) streamChunk(w http.ResponseWriter, r *http.Request, fields map[string]string, data io.Reader) {
+	path := normalizePath(fields["path"])
+	if path == "" {
+		writeJSONError(w, errors.NewValidationError("path", "path field required"))
+		return
+	}
+	if !s.allowPath(w, r, path) {
+		return
+	}
+	chunkID, err := strconv.Atoi(fields["chunk_id"])
+	if err != nil {
+		errors.WriteJSON(w, http.StatusBadRequest, fmt.Errorf("invalid chunk_id: %q", fields["chunk_id"]))
+		return
+	}
+	total, err := strconv.Atoi(fields["total"])
+	if err != nil {
+		errors.WriteJSON(w, http.StatusBadRequest, fmt.Errorf("invalid total: %q", fields["total"]))
+		return
+	}
+	if err := validateChunkID(chunkID, total); err != nil {
+		errors.WriteJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tmp, err := os.CreateTemp(s.chunksDir, "stream_*.part")
+	if err != nil {
+		errors.WriteJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to create temp file: %w", err))
+		return
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once renamed into place
+
+	// A chunk can't be larger than the whole file may be, so spooling stops
+	// one byte past the cap rather than filling the disk
+	if s.maxFileSize > 0 {
+		data = io.LimitReader(data, s.maxFileSize+1)
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		s.markAbandoned(path)
+		errors.WriteJSON(w, http.StatusBadRequest, fmt.Errorf("failed to receive chunk: %w", err))
+		return
+	}
+	if s.maxFileSize > 0 && size > s.maxFileSize {
+		unlock := s.uploadLocks.lock(sessionKey(path))
+		s.discardSession(path)
+		unlock()
+		errors.WriteJSON(w, http.StatusRequestEntityTooLarge, fmt.Errorf("file is too large: limit %d bytes", s.maxFileSize))
+		return
+	}
+
+	if err := verifyChecksum(path, chunkID, hex.EncodeToString(hash.Sum(nil)), fields["checksum"]); err != nil {
+		errors.WriteJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.storeChunk(w, r, path, fields["file_hash"], chunkID, total, int(size), fields["replace"] == "true", fields["defer_finalize"] == "true", func(chunkPath string) error {
+		return os.Rename(tmpPath, chunkPath)
+	})
+	s.checkDisconnect(r, path)
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/chunk"
+	"github.com/0xRepo-Source/goflux-lite/pkg/transport"
+)
+
+func TestUploadChunkStream_RoundTrip(t *testing.T) {
+	srv, store := newTestServer(t)
+	ts := httptest.NewServer(http.HandlerFunc(srv.handleUploadStream))
+	defer ts.Close()
+
+	data := make([]byte, 10*1024*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	client := transport.NewHTTPClient(ts.URL)
+	if err := client.UploadChunkStream(transport.ChunkData{Path: "big/stream.bin", ChunkID: 0, Data: data, Total: 1}); err != nil {
+		t.Fatalf("UploadChunkStream failed: %v", err)
+	}
+
+	got, err := store.Get("big/stream.bin")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("round-tripped data mismatch: got %d bytes, want %d", len(got), len(data))
+	}
+}
+
+func TestHandleUploadStream_WritesBeforeBodyComplete(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	body, pipe := io.Pipe()
+	form := multipart.NewWriter(pipe)
+	req := httptest.NewRequest(http.MethodPost, "/upload/stream", body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		srv.handleUploadStream(rec, req)
+		close(done)
+	}()
+
+	half := bytes.Repeat([]byte("a"), 1024*1024)
+	form.WriteField("path", "streamed.bin")
+	form.WriteField("chunk_id", "0")
+	form.WriteField("total", "1")
+	part, _ := form.CreateFormFile("data", "chunk")
+	part.Write(half)
+
+	// With the body only half sent, the handler must already be writing to disk
+	if !waitForSpool(srv.chunksDir, int64(len(half)/2), 5*time.Second) {
+		pipe.CloseWithError(io.ErrUnexpectedEOF)
+		<-done
+		t.Fatal("chunk data was not streamed to disk before the request body completed")
+	}
+
+	part.Write(half)
+	form.Close()
+	pipe.Close()
+	<-done
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	got, err := store.Get("streamed.bin")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(got) != 2*len(half) {
+		t.Errorf("expected %d bytes, got %d", 2*len(half), len(got))
+	}
+}
+
+// waitForSpool polls the chunks directory for a streamed upload spool file of at least size bytes
+func waitForSpool(dir string, size int64, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		matches, _ := filepath.Glob(filepath.Join(dir, "stream_*.part"))
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.Size() >= size {
+				return true
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestHandleUploadStream_InvalidMetadata(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+	}{
+		{name: "missing path", fields: map[string]string{"chunk_id": "0", "total": "1"}},
+		{name: "bad chunk ID", fields: map[string]string{"path": "x", "chunk_id": "abc", "total": "1"}},
+		{name: "chunk ID out of range", fields: map[string]string{"path": "x", "chunk_id": "3", "total": "1"}},
+		{name: "checksum mismatch", fields: map[string]string{"path": "x", "chunk_id": "0", "total": "1", "checksum": chunk.Checksum([]byte("atad"))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			form := multipart.NewWriter(&buf)
+			for k, v := range tt.fields {
+				form.WriteField(k, v)
+			}
+			part, _ := form.CreateFormFile("data", "chunk")
+			part.Write([]byte("data"))
+			form.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/upload/stream", &buf)
+			req.Header.Set("Content-Type", form.FormDataContentType())
+			rec := httptest.NewRecorder()
+			srv.handleUploadStream(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleUploadStream_MaxFileSize(t *testing.T) {
+	srv, store := newTestServer(t)
+	srv.SetMaxFileSize(1024)
+	ts := httptest.NewServer(http.HandlerFunc(srv.handleUploadStream))
+	defer ts.Close()
+	client := transport.NewHTTPClient(ts.URL)
+
+	if err := client.UploadChunkStream(transport.ChunkData{Path: "fits.bin", ChunkID: 0, Data: make([]byte, 1024), Total: 1}); err != nil {
+		t.Fatalf("expected a file at the limit to be accepted: %v", err)
+	}
+	if !store.Exists("fits.bin") {
+		t.Error("expected fits.bin stored")
+	}
+
+	err := client.UploadChunkStream(transport.ChunkData{Path: "big.bin", ChunkID: 0, Data: make([]byte, 1025), Total: 1})
+	if err == nil || !strings.Contains(err.Error(), "413") {
+		t.Fatalf("expected 413 for a file over the limit, got %v", err)
+	}
+	if store.Exists("big.bin") {
+		t.Error("expected the oversized file not to be stored")
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedCert creates a certificate for 127.0.0.1 and returns the cert
+// and key file paths along with a pool trusting the certificate
+func writeSelfSignedCert(t *testing.T) (string, string, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "goflux-test"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return certFile, keyFile, pool
+}
+
+func TestServe_TLS(t *testing.T) {
+	srv, store := newTestServer(t)
+	store.Put("secure.txt", []byte("over tls"))
+
+	certFile, keyFile, pool := writeSelfSignedCert(t)
+	srv.EnableTLS(certFile, keyFile)
+	if srv.Scheme() != "https" {
+		t.Fatalf("expected https scheme, got %s", srv.Scheme())
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() { ln.Close() })
+
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+	}
+	resp, err := client.Get("https://" + ln.Addr().String() + "/download?path=secure.txt")
+	if err != nil {
+		t.Fatalf("HTTPS download failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "over tls" {
+		t.Errorf("unexpected body: %q", body)
+	}
+	if resp.TLS == nil {
+		t.Error("expected response over TLS")
+	}
+}
+
+func TestScheme_PlainHTTP(t *testing.T) {
+	srv, _ := newTestServer(t)
+	if srv.Scheme() != "http" {
+		t.Errorf("expected http scheme without TLS, got %s", srv.Scheme())
+	}
+
+	// A certificate without a key doesn't enable TLS
+	srv.EnableTLS("cert.pem", "")
+	if srv.Scheme() != "http" {
+		t.Errorf("expected http scheme with only a certificate, got %s", srv.Scheme())
+	}
+}
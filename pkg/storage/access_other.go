@@ -0,0 +1,10 @@
+//go:build !unix
+
+package storage
+
+// canWrite reports whether the server may create files in the directory dir.
+// Permissions can't be checked without creating a file here, so any
+// directory passes.
+func canWrite(dir string) error {
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build unix
+
+package storage
+
+import "golang.org/x/sys/unix"
+
+// canWrite reports whether the server may create files in the directory dir
+func canWrite(dir string) error {
+	return unix.Access(dir, unix.W_OK|unix.X_OK)
+}
@@ -0,0 +1,384 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+// DefaultBlockSize is how many uncompressed bytes go into each compressed block
+const DefaultBlockSize = 64 * 1024
+
+// Compressed files are a sequence of gzip members, one per block, followed by
+// the compressed length of each block (uint32) and a fixed-size footer.
+const (
+	blockMagic = "GFLZBLK1"
+	footerSize = len(blockMagic) + 8 + 4 + 4 // magic, uncompressed size, block size, block count
+)
+
+// Compressed is a Storage decorator that gzips files as they are stored and
+// decompresses them as they are read. Each file is compressed in independent
+// blocks with an index at the end, so a range read only decompresses the
+// blocks it overlaps. Files that weren't stored compressed, such as those
+// written before compression was enabled, are read as they are.
+type Compressed struct {
+	backend   Storage
+	blockSize int
+}
+
+// NewCompressed wraps backend, compressing files in blocks of blockSize
+// uncompressed bytes. A zero or negative size uses DefaultBlockSize.
+func NewCompressed(backend Storage, blockSize int) *Compressed {
+	if blockSize <= 0 {
+		blockSize = DefaultBlockSize
+	}
+	return &Compressed{backend: backend, blockSize: blockSize}
+}
+
+// blockIndex locates the blocks of a compressed file
+type blockIndex struct {
+	size      int64   // uncompressed length of the file
+	blockSize int64   // uncompressed length of every block but the last
+	offsets   []int64 // where each block starts in the stored file, plus where the index starts
+}
+
+// compressBlocks encodes data in the block format
+func compressBlocks(data []byte, blockSize int) ([]byte, error) {
+	var out bytes.Buffer
+	var lengths []uint32
+	zw := gzip.NewWriter(&out)
+	for start := 0; start < len(data); start += blockSize {
+		end := start + blockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		before := out.Len()
+		zw.Reset(&out)
+		if _, err := zw.Write(data[start:end]); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		lengths = append(lengths, uint32(out.Len()-before))
+	}
+
+	for _, n := range lengths {
+		binary.Write(&out, binary.BigEndian, n)
+	}
+	out.WriteString(blockMagic)
+	binary.Write(&out, binary.BigEndian, uint64(len(data)))
+	binary.Write(&out, binary.BigEndian, uint32(blockSize))
+	binary.Write(&out, binary.BigEndian, uint32(len(lengths)))
+	return out.Bytes(), nil
+}
+
+// parseFooter reads the footer at the end of a stored file of storedSize
+// bytes, reporting false if the file isn't in the block format
+func parseFooter(footer []byte, storedSize int64) (size, blockSize int64, count int, ok bool) {
+	if len(footer) != footerSize || string(footer[:len(blockMagic)]) != blockMagic {
+		return 0, 0, 0, false
+	}
+	rest := footer[len(blockMagic):]
+	size = int64(binary.BigEndian.Uint64(rest[0:8]))
+	blockSize = int64(binary.BigEndian.Uint32(rest[8:12]))
+	count = int(binary.BigEndian.Uint32(rest[12:16]))
+
+	if blockSize <= 0 || size < 0 || int64(count) != (size+blockSize-1)/blockSize {
+		return 0, 0, 0, false
+	}
+	if int64(count)*4 > storedSize-int64(footerSize) {
+		return 0, 0, 0, false
+	}
+	return size, blockSize, count, true
+}
+
+// parseIndex builds the block index from the per-block lengths, reporting false
+// if they don't add up to the compressed data preceding them
+func parseIndex(lengths []byte, size, blockSize int64, dataSize int64) (*blockIndex, bool) {
+	idx := &blockIndex{size: size, blockSize: blockSize, offsets: make([]int64, 0, len(lengths)/4+1)}
+	var offset int64
+	for i := 0; i < len(lengths); i += 4 {
+		idx.offsets = append(idx.offsets, offset)
+		offset += int64(binary.BigEndian.Uint32(lengths[i : i+4]))
+	}
+	idx.offsets = append(idx.offsets, offset)
+	return idx, offset == dataSize
+}
+
+// index reads the block index of the file at path, returning nil if the file
+// isn't stored compressed
+func (c *Compressed) index(path string) (*blockIndex, error) {
+	storedSize, err := storedSize(c.backend, path)
+	if err != nil {
+		return nil, err
+	}
+	if storedSize < int64(footerSize) {
+		return nil, nil
+	}
+
+	footer, err := readStored(c.backend, path, storedSize-int64(footerSize), int64(footerSize))
+	if err != nil {
+		return nil, err
+	}
+	size, blockSize, count, ok := parseFooter(footer, storedSize)
+	if !ok {
+		return nil, nil
+	}
+
+	indexStart := storedSize - int64(footerSize) - int64(count)*4
+	lengths, err := readStored(c.backend, path, indexStart, int64(count)*4)
+	if err != nil {
+		return nil, err
+	}
+	idx, ok := parseIndex(lengths, size, blockSize, indexStart)
+	if !ok {
+		return nil, nil
+	}
+	return idx, nil
+}
+
+// decompressBlock inflates one block, checking it has the expected length
+func decompressBlock(path string, block []byte, want int64) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(block))
+	if err != nil {
+		return nil, errors.NewStorageErrorWithCause(errors.StorageErrorIO, path, "compressed block is damaged", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, errors.NewStorageErrorWithCause(errors.StorageErrorIO, path, "compressed block is damaged", err)
+	}
+	if int64(len(data)) != want {
+		return nil, errors.NewStorageError(errors.StorageErrorIO, path, fmt.Sprintf("compressed block holds %d bytes, expected %d", len(data), want))
+	}
+	return data, nil
+}
+
+// blockLen returns the uncompressed length of block i
+func (idx *blockIndex) blockLen(i int) int64 {
+	if end := int64(i+1) * idx.blockSize; end < idx.size {
+		return idx.blockSize
+	}
+	return idx.size - int64(i)*idx.blockSize
+}
+
+// decompressRange inflates blocks first through last, which start at
+// offsets[first] in stored
+func (idx *blockIndex) decompressRange(path string, stored []byte, first, last int) ([]byte, error) {
+	base := idx.offsets[first]
+	if int64(len(stored)) != idx.offsets[last+1]-base {
+		return nil, errors.NewStorageError(errors.StorageErrorIO, path, "compressed file is truncated")
+	}
+	data := make([]byte, 0, int64(last-first+1)*idx.blockSize)
+	for i := first; i <= last; i++ {
+		block, err := decompressBlock(path, stored[idx.offsets[i]-base:idx.offsets[i+1]-base], idx.blockLen(i))
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, block...)
+	}
+	return data, nil
+}
+
+// Put compresses data and stores it in the backend
+func (c *Compressed) Put(path string, data []byte) error {
+	encoded, err := compressBlocks(data, c.blockSize)
+	if err != nil {
+		return fmt.Errorf("failed to compress: %w", err)
+	}
+	return c.backend.Put(path, encoded)
+}
+
+// Get reads a file from the backend, decompressing it if it was stored compressed
+func (c *Compressed) Get(path string) ([]byte, error) {
+	stored, err := c.backend.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(stored) < footerSize {
+		return stored, nil
+	}
+
+	dataSize := int64(len(stored) - footerSize)
+	size, blockSize, count, ok := parseFooter(stored[dataSize:], int64(len(stored)))
+	if !ok {
+		return stored, nil
+	}
+	indexStart := dataSize - int64(count)*4
+	idx, ok := parseIndex(stored[indexStart:dataSize], size, blockSize, indexStart)
+	if !ok {
+		return stored, nil
+	}
+	if count == 0 {
+		return []byte{}, nil
+	}
+	return idx.decompressRange(path, stored[:indexStart], 0, count-1)
+}
+
+// Size returns the uncompressed size of the file at path
+func (c *Compressed) Size(path string) (int64, error) {
+	idx, err := c.index(path)
+	if err != nil {
+		return 0, err
+	}
+	if idx == nil {
+		return storedSize(c.backend, path)
+	}
+	return idx.size, nil
+}
+
+// GetRange returns up to length uncompressed bytes starting at offset,
+// reading and decompressing only the blocks that overlap the range
+func (c *Compressed) GetRange(path string, offset, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, errors.NewStorageError(errors.StorageErrorInvalidPath, path, fmt.Sprintf("invalid range: offset %d, length %d", offset, length))
+	}
+
+	idx, err := c.index(path)
+	if err != nil {
+		return nil, err
+	}
+	if idx == nil {
+		return readStored(c.backend, path, offset, length)
+	}
+
+	if offset >= idx.size || length == 0 {
+		return []byte{}, nil
+	}
+	if offset+length > idx.size {
+		length = idx.size - offset
+	}
+
+	first := int(offset / idx.blockSize)
+	last := int((offset + length - 1) / idx.blockSize)
+	stored, err := readStored(c.backend, path, idx.offsets[first], idx.offsets[last+1]-idx.offsets[first])
+	if err != nil {
+		return nil, err
+	}
+	data, err := idx.decompressRange(path, stored, first, last)
+	if err != nil {
+		return nil, err
+	}
+	skip := offset - int64(first)*idx.blockSize
+	return data[skip : skip+length], nil
+}
+
+// Stat describes a path in the backend, reporting files' uncompressed size
+func (c *Compressed) Stat(path string) (FileInfo, error) {
+	info, err := Stat(c.backend, path)
+	if err != nil || info.IsDir {
+		return info, err
+	}
+	info.Size, err = c.Size(path)
+	return info, err
+}
+
+// ListDetailed describes a directory's entries in the backend, reporting
+// files' uncompressed size
+func (c *Compressed) ListDetailed(dir string) ([]FileInfo, error) {
+	entries, err := ListDetailed(c.backend, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Listing a file describes just that file
+	dc, ok := c.backend.(DirChecker)
+	listedFile := ok && !dc.IsDir(dir)
+	for i, entry := range entries {
+		if entry.IsDir {
+			continue
+		}
+		entryPath := path.Join(dir, entry.Name)
+		if listedFile {
+			entryPath = dir
+		}
+		if entries[i].Size, err = c.Size(entryPath); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// Exists passes through to the backend
+func (c *Compressed) Exists(path string) bool {
+	return c.backend.Exists(path)
+}
+
+// IsDir passes through to the backend. Backends that can't tell directories
+// apart from files report false.
+func (c *Compressed) IsDir(path string) bool {
+	dc, ok := c.backend.(DirChecker)
+	return ok && dc.IsDir(path)
+}
+
+// List passes through to the backend
+func (c *Compressed) List(path string) ([]string, error) {
+	return c.backend.List(path)
+}
+
+// Delete passes through to the backend
+func (c *Compressed) Delete(path string) error {
+	return c.backend.Delete(path)
+}
+
+// Mkdir passes through to the backend
+func (c *Compressed) Mkdir(path string) error {
+	return c.backend.Mkdir(path)
+}
+
+// Walk passes through to the backend. Returns an error wrapping
+// errors.ErrUnsupported if the backend can't be walked.
+func (c *Compressed) Walk(root string, fn func(path string) error) error {
+	walker, ok := c.backend.(Walker)
+	if !ok {
+		return fmt.Errorf("storage backend cannot be walked: %w", stderrors.ErrUnsupported)
+	}
+	return walker.Walk(root, fn)
+}
+
+// Rename passes through to the backend; compressed files are moved as they
+// are. Returns an error wrapping errors.ErrUnsupported if the backend can't
+// rename atomically.
+func (c *Compressed) Rename(oldPath, newPath string) error {
+	renamer, ok := c.backend.(Renamer)
+	if !ok {
+		return fmt.Errorf("storage backend cannot rename: %w", stderrors.ErrUnsupported)
+	}
+	return renamer.Rename(oldPath, newPath)
+}
+
+// storedSize returns the length of the file at path as stored in backend,
+// reading it whole if the backend can't seek
+func storedSize(backend Storage, path string) (int64, error) {
+	if rg, ok := backend.(RangeGetter); ok {
+		return rg.Size(path)
+	}
+	data, err := backend.Get(path)
+	return int64(len(data)), err
+}
+
+// readStored reads part of the file at path as stored in backend, reading it
+// whole if the backend can't seek
+func readStored(backend Storage, path string, offset, length int64) ([]byte, error) {
+	if rg, ok := backend.(RangeGetter); ok {
+		return rg.GetRange(path, offset, length)
+	}
+	data, err := backend.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	end := offset + length
+	if end > int64(len(data)) {
+		end = int64(len(data))
+	}
+	return data[offset:end], nil
+}
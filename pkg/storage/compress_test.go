@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// compressibleData returns n bytes of repetitive text
+func compressibleData(n int) []byte {
+	var buf bytes.Buffer
+	for i := 0; buf.Len() < n; i++ {
+		fmt.Fprintf(&buf, "line %d: the quick brown fox jumps over the lazy dog\n", i)
+	}
+	return buf.Bytes()[:n]
+}
+
+func TestCompressed_RoundTrip(t *testing.T) {
+	local, _ := NewLocal(t.TempDir())
+	c := NewCompressed(local, 1000)
+	content := compressibleData(10500)
+
+	if err := c.Put("logs/app.log", content); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	stored, _ := local.Get("logs/app.log")
+	if len(stored) >= len(content) {
+		t.Errorf("expected stored file to be compressed, got %d bytes for %d", len(stored), len(content))
+	}
+
+	data, err := c.Get("logs/app.log")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Error("decompressed content mismatch")
+	}
+
+	size, err := c.Size("logs/app.log")
+	if err != nil || size != int64(len(content)) {
+		t.Errorf("expected size %d, got %d (%v)", len(content), size, err)
+	}
+}
+
+func TestCompressed_GetRange(t *testing.T) {
+	local, _ := NewLocal(t.TempDir())
+	c := NewCompressed(local, 1000)
+	content := compressibleData(10500)
+	c.Put("big.txt", content)
+
+	tests := []struct {
+		offset, length int64
+	}{
+		{0, 10},      // start of the first block
+		{995, 10},    // across a block boundary
+		{2000, 1000}, // exactly one block
+		{1500, 5000}, // several blocks
+		{10400, 500}, // runs past the end of the last, short block
+		{10500, 10},  // at the end
+		{20000, 10},  // beyond the end
+		{300, 0},     // empty
+		{0, 10500},   // the whole file
+		{10499, 1},   // the last byte
+		{9999, 2},    // into the last block
+	}
+	for _, tt := range tests {
+		got, err := c.GetRange("big.txt", tt.offset, tt.length)
+		if err != nil {
+			t.Fatalf("GetRange(%d, %d) failed: %v", tt.offset, tt.length, err)
+		}
+
+		start, end := tt.offset, tt.offset+tt.length
+		if start > int64(len(content)) {
+			start = int64(len(content))
+		}
+		if end > int64(len(content)) {
+			end = int64(len(content))
+		}
+		if !bytes.Equal(got, content[start:end]) {
+			t.Errorf("GetRange(%d, %d): got %d bytes that don't match", tt.offset, tt.length, len(got))
+		}
+	}
+
+	if _, err := c.GetRange("big.txt", -1, 10); err == nil {
+		t.Error("expected error for negative offset")
+	}
+}
+
+func TestCompressed_ReadsUncompressedFiles(t *testing.T) {
+	local, _ := NewLocal(t.TempDir())
+	c := NewCompressed(local, 1000)
+	content := []byte("written before compression was enabled")
+	local.Put("old.txt", content)
+
+	if data, err := c.Get("old.txt"); err != nil || !bytes.Equal(data, content) {
+		t.Errorf("expected plain content, got %q (%v)", data, err)
+	}
+	if data, err := c.GetRange("old.txt", 8, 6); err != nil || string(data) != "before" {
+		t.Errorf("expected plain range, got %q (%v)", data, err)
+	}
+	if size, err := c.Size("old.txt"); err != nil || size != int64(len(content)) {
+		t.Errorf("expected size %d, got %d (%v)", len(content), size, err)
+	}
+}
+
+func TestCompressed_EmptyFile(t *testing.T) {
+	local, _ := NewLocal(t.TempDir())
+	c := NewCompressed(local, 1000)
+
+	if err := c.Put("empty.txt", nil); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if data, err := c.Get("empty.txt"); err != nil || len(data) != 0 {
+		t.Errorf("expected empty file, got %q (%v)", data, err)
+	}
+	if size, err := c.Size("empty.txt"); err != nil || size != 0 {
+		t.Errorf("expected size 0, got %d (%v)", size, err)
+	}
+}
+
+func TestCompressed_ReportsUncompressedSizes(t *testing.T) {
+	local, _ := NewLocal(t.TempDir())
+	c := NewCompressed(local, 1000)
+	c.Put("docs/a.txt", compressibleData(5000))
+	c.Mkdir("docs/sub")
+
+	info, err := c.Stat("docs/a.txt")
+	if err != nil || info.Size != 5000 {
+		t.Errorf("expected Stat size 5000, got %+v (%v)", info, err)
+	}
+
+	entries, err := c.ListDetailed("docs")
+	if err != nil {
+		t.Fatalf("ListDetailed failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Size != 5000 || !entries[1].IsDir {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+
+	entries, err = c.ListDetailed("docs/a.txt")
+	if err != nil || len(entries) != 1 || entries[0].Size != 5000 {
+		t.Errorf("expected single 5000-byte entry, got %+v (%v)", entries, err)
+	}
+}
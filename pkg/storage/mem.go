@@ -0,0 +1,404 @@
+package storage
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+// MemStorage is an in-memory storage implementation for tests. It sanitizes
+// paths and reports errors the way Local does, without touching the disk.
+type MemStorage struct {
+	mu      sync.RWMutex
+	entries map[string]*memEntry // cleaned path -> file or directory; the root is implicit
+}
+
+// memEntry is a stored file or directory
+type memEntry struct {
+	data    []byte
+	isDir   bool
+	modTime time.Time
+}
+
+// NewMemStorage creates an empty in-memory storage backend.
+func NewMemStorage() *MemStorage {
+	return &MemStorage{entries: make(map[string]*memEntry)}
+}
+
+// sanitizePath reduces p to a key relative to the root ("" for the root
+// itself), rejecting paths that would escape it
+func (m *MemStorage) sanitizePath(p string) (string, error) {
+	clean := path.Clean(p)
+	clean = strings.TrimPrefix(clean, "/")
+	clean = strings.TrimPrefix(clean, "\\")
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", errors.NewStorageError(errors.StorageErrorPathTraversal, p, "path traversal attempt detected")
+	}
+	if err := checkInternal(p, clean); err != nil {
+		return "", err
+	}
+	if clean == "." {
+		clean = ""
+	}
+	return clean, nil
+}
+
+// lookup returns the entry at key, with a directory entry for the root. The
+// caller must hold m.mu.
+func (m *MemStorage) lookup(key string) (*memEntry, bool) {
+	if key == "" {
+		return &memEntry{isDir: true}, true
+	}
+	entry, ok := m.entries[key]
+	return entry, ok
+}
+
+// mkdirAll creates key and its parents as directories. The caller must hold
+// m.mu for writing.
+func (m *MemStorage) mkdirAll(key, p string) error {
+	if key == "" {
+		return nil
+	}
+	if err := m.mkdirAll(parentKey(key), p); err != nil {
+		return err
+	}
+	if entry, ok := m.entries[key]; ok {
+		if !entry.isDir {
+			return errors.NewStorageError(errors.StorageErrorInvalidPath, p, fmt.Sprintf("%s is a file", key))
+		}
+		return nil
+	}
+	m.entries[key] = &memEntry{isDir: true, modTime: time.Now()}
+	return nil
+}
+
+// parentKey returns the key of the directory holding key
+func parentKey(key string) string {
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+// children returns the keys of the entries directly inside the directory
+// key, sorted by name. The caller must hold m.mu.
+func (m *MemStorage) children(key string) []string {
+	var keys []string
+	for k := range m.entries {
+		if k != key && parentKey(k) == key {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Put stores a copy of data at the specified path, creating parent
+// directories. Returns StorageError if the path is invalid or attempts
+// directory traversal, or StorageErrorAlreadyExists if it is an existing
+// directory.
+func (m *MemStorage) Put(p string, data []byte) error {
+	key, err := m.sanitizePath(p)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entry, ok := m.lookup(key); ok && entry.isDir {
+		return errors.NewStorageError(errors.StorageErrorAlreadyExists, p, "path is an existing directory")
+	}
+	if err := m.mkdirAll(parentKey(key), p); err != nil {
+		return err
+	}
+	m.entries[key] = &memEntry{data: append([]byte(nil), data...), modTime: time.Now()}
+	return nil
+}
+
+// file returns the file at p. The caller must hold m.mu.
+func (m *MemStorage) file(p string) (*memEntry, error) {
+	key, err := m.sanitizePath(p)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+	entry, ok := m.lookup(key)
+	if !ok {
+		return nil, errors.NewStorageError(errors.StorageErrorNotFound, p, "path does not exist")
+	}
+	if entry.isDir {
+		return nil, errors.NewStorageError(errors.StorageErrorInvalidPath, p, "path is a directory")
+	}
+	return entry, nil
+}
+
+// Get returns a copy of the file at the specified path.
+// Returns StorageErrorNotFound if the path doesn't exist.
+func (m *MemStorage) Get(p string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, err := m.file(p)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), entry.data...), nil
+}
+
+// Size returns the size of the file at the specified path.
+// Returns StorageErrorNotFound if the path doesn't exist.
+func (m *MemStorage) Size(p string) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, err := m.file(p)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(entry.data)), nil
+}
+
+// GetRange returns up to length bytes of the file starting at offset. Fewer
+// bytes are returned if the file ends first. Returns StorageErrorNotFound if
+// the path doesn't exist.
+func (m *MemStorage) GetRange(p string, offset, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, errors.NewStorageError(errors.StorageErrorInvalidPath, p, fmt.Sprintf("invalid range: offset %d, length %d", offset, length))
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, err := m.file(p)
+	if err != nil {
+		return nil, err
+	}
+	size := int64(len(entry.data))
+	if offset > size {
+		offset = size
+	}
+	end := offset + length
+	if end > size {
+		end = size
+	}
+	return append([]byte(nil), entry.data[offset:end]...), nil
+}
+
+// Stat returns the name, size, type and modification time of the file or
+// directory at the specified path. Returns StorageErrorNotFound if the path
+// doesn't exist.
+func (m *MemStorage) Stat(p string) (FileInfo, error) {
+	key, err := m.sanitizePath(p)
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("invalid path: %w", err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, ok := m.lookup(key)
+	if !ok {
+		return FileInfo{}, errors.NewStorageError(errors.StorageErrorNotFound, p, "path does not exist")
+	}
+	return entry.info(key), nil
+}
+
+// info describes the entry stored at key
+func (e *memEntry) info(key string) FileInfo {
+	info := FileInfo{Name: path.Base("/" + key), IsDir: e.isDir, ModTime: e.modTime}
+	if !e.isDir {
+		info.Size = int64(len(e.data))
+	}
+	return info
+}
+
+// Exists checks if a file or directory exists at the specified path.
+// Returns false if the path is invalid or attempts directory traversal.
+func (m *MemStorage) Exists(p string) bool {
+	key, err := m.sanitizePath(p)
+	if err != nil {
+		return false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.lookup(key)
+	return ok
+}
+
+// IsDir reports whether the specified path is an existing directory.
+// Returns false if the path is invalid or attempts directory traversal.
+func (m *MemStorage) IsDir(p string) bool {
+	key, err := m.sanitizePath(p)
+	if err != nil {
+		return false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, ok := m.lookup(key)
+	return ok && entry.isDir
+}
+
+// List returns the names of all entries in the specified directory, leaving
+// out internal temporary files. If the path names a file, a single-entry
+// listing with the file's name is returned. Returns StorageErrorNotFound if
+// the path doesn't exist.
+func (m *MemStorage) List(p string) ([]string, error) {
+	entries, err := m.ListDetailed(p)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	return names, nil
+}
+
+// ListDetailed returns the name, size, type and modification time of each
+// entry in the specified directory, or of the file the path names. Returns
+// StorageErrorNotFound if the path doesn't exist.
+func (m *MemStorage) ListDetailed(p string) ([]FileInfo, error) {
+	key, err := m.sanitizePath(p)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, ok := m.lookup(key)
+	if !ok {
+		return nil, errors.NewStorageError(errors.StorageErrorNotFound, p, "path does not exist")
+	}
+	if !entry.isDir {
+		return []FileInfo{entry.info(key)}, nil
+	}
+
+	result := []FileInfo{}
+	for _, child := range m.children(key) {
+		info := m.entries[child].info(child)
+		if IsInternal(info.Name) {
+			continue
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// Delete removes a file or directory at the specified path.
+// Directories are removed recursively. Returns StorageErrorNotFound if the path doesn't exist.
+func (m *MemStorage) Delete(p string) error {
+	key, err := m.sanitizePath(p)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.lookup(key); !ok {
+		return errors.NewStorageError(errors.StorageErrorNotFound, p, "path does not exist")
+	}
+	for k := range m.entries {
+		if key == "" || k == key || strings.HasPrefix(k, key+"/") {
+			delete(m.entries, k)
+		}
+	}
+	return nil
+}
+
+// Rename moves the file or directory at oldPath to newPath, creating
+// newPath's parent directories first. Returns StorageErrorNotFound if oldPath
+// doesn't exist and StorageErrorAlreadyExists if newPath does.
+func (m *MemStorage) Rename(oldPath, newPath string) error {
+	oldKey, err := m.sanitizePath(oldPath)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	newKey, err := m.sanitizePath(newPath)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.lookup(oldKey); !ok || oldKey == "" {
+		return errors.NewStorageError(errors.StorageErrorNotFound, oldPath, "path does not exist")
+	}
+	if _, ok := m.lookup(newKey); ok {
+		return errors.NewStorageError(errors.StorageErrorAlreadyExists, newPath, "path already exists")
+	}
+	if strings.HasPrefix(newKey, oldKey+"/") {
+		return errors.NewStorageError(errors.StorageErrorInvalidPath, newPath, "cannot move a directory inside itself")
+	}
+	if err := m.mkdirAll(parentKey(newKey), newPath); err != nil {
+		return err
+	}
+
+	var moved []string
+	for k := range m.entries {
+		if k == oldKey || strings.HasPrefix(k, oldKey+"/") {
+			moved = append(moved, k)
+		}
+	}
+	for _, k := range moved {
+		m.entries[newKey+strings.TrimPrefix(k, oldKey)] = m.entries[k]
+		delete(m.entries, k)
+	}
+	return nil
+}
+
+// Mkdir creates a directory at the specified path, including any necessary parent directories.
+// Returns StorageError if the path is invalid or attempts directory traversal.
+func (m *MemStorage) Mkdir(p string) error {
+	key, err := m.sanitizePath(p)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mkdirAll(key, p)
+}
+
+// Walk calls fn for every file under root, in path order, passing its
+// slash-separated path. Internal temporary files are skipped. Returns
+// StorageErrorNotFound if root doesn't exist.
+func (m *MemStorage) Walk(root string, fn func(path string) error) error {
+	key, err := m.sanitizePath(root)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+
+	m.mu.RLock()
+	if _, ok := m.lookup(key); !ok {
+		m.mu.RUnlock()
+		return errors.NewStorageError(errors.StorageErrorNotFound, root, "path does not exist")
+	}
+	var files []string
+	for k, entry := range m.entries {
+		if entry.isDir || IsInternal(path.Base(k)) {
+			continue
+		}
+		if key == "" || k == key || strings.HasPrefix(k, key+"/") {
+			files = append(files, k)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Strings(files)
+	for _, f := range files {
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Usage returns the total size of the stored files
+func (m *MemStorage) Usage() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var total int64
+	for _, entry := range m.entries {
+		total += int64(len(entry.data))
+	}
+	return total, nil
+}
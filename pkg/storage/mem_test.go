@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+// conformingBackend is what the conformance tests exercise: the methods
+// Local and MemStorage share
+type conformingBackend interface {
+	Storage
+	RangeGetter
+	DirChecker
+	Statter
+	Renamer
+}
+
+// backends returns a fresh Local and MemStorage, so each test can check the
+// two behave the same
+func backends(t *testing.T) map[string]conformingBackend {
+	local, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal failed: %v", err)
+	}
+	return map[string]conformingBackend{"local": local, "mem": NewMemStorage()}
+}
+
+// storageErrorType returns the StorageError type of err, or -1 if it isn't one
+func storageErrorType(err error) errors.StorageErrorType {
+	if errType, ok := errors.GetStorageErrorType(err); ok {
+		return errType
+	}
+	return -1
+}
+
+func TestConformance_PutGet(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := backend.Put("docs/sub/a.txt", []byte("one")); err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+			if err := backend.Put("docs/sub/a.txt", []byte("two")); err != nil {
+				t.Fatalf("Put replacing a file failed: %v", err)
+			}
+			if data, err := backend.Get("docs/sub/a.txt"); err != nil || string(data) != "two" {
+				t.Errorf("expected the replaced content, got %q (%v)", data, err)
+			}
+			if data, err := backend.Get("/docs/sub/a.txt"); err != nil || string(data) != "two" {
+				t.Errorf("expected a leading slash to be ignored, got %q (%v)", data, err)
+			}
+			if !backend.IsDir("docs/sub") {
+				t.Error("expected parent directories to be created")
+			}
+
+			err := backend.Put("docs", []byte("x"))
+			if storageErrorType(err) != errors.StorageErrorAlreadyExists {
+				t.Errorf("expected StorageErrorAlreadyExists putting onto a directory, got %v", err)
+			}
+			if _, err := backend.Get("missing.txt"); err == nil {
+				t.Error("expected an error getting a missing file")
+			}
+		})
+	}
+}
+
+func TestConformance_PathTraversal(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, p := range []string{"../etc/passwd", "../../secrets.txt", "subdir/../../etc/passwd"} {
+				err := backend.Put(p, []byte("malicious"))
+				if storageErrorType(err) != errors.StorageErrorPathTraversal {
+					t.Errorf("Put(%q): expected StorageErrorPathTraversal, got %v", p, err)
+				}
+				if _, err := backend.Get(p); err == nil {
+					t.Errorf("Get(%q): expected an error", p)
+				}
+				if backend.Exists(p) {
+					t.Errorf("Exists(%q): expected false", p)
+				}
+				if storageErrorType(backend.Delete(p)) != errors.StorageErrorPathTraversal {
+					t.Errorf("Delete(%q): expected StorageErrorPathTraversal", p)
+				}
+			}
+
+			// Paths that only pass through .. inside the root are fine
+			if err := backend.Put("a/../b.txt", []byte("b")); err != nil {
+				t.Errorf("expected a path staying inside the root to work: %v", err)
+			}
+			if !backend.Exists("b.txt") {
+				t.Error("expected a/../b.txt to be stored as b.txt")
+			}
+		})
+	}
+}
+
+func TestConformance_List(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			backend.Put("dir/b.txt", []byte("b"))
+			backend.Put("dir/a.txt", []byte("a"))
+			backend.Mkdir("dir/sub")
+
+			// Names of internal temporary files are reserved
+			if err := backend.Put("dir/"+InternalPrefix+"a.txt.123", []byte("partial")); storageErrorType(err) != errors.StorageErrorInvalidPath {
+				t.Errorf("expected StorageErrorInvalidPath for a reserved name, got %v", err)
+			}
+
+			names, err := backend.List("dir")
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			if want := []string{"a.txt", "b.txt", "sub"}; !reflect.DeepEqual(names, want) {
+				t.Errorf("expected %v, got %v", want, names)
+			}
+
+			if names, err := backend.List("dir/a.txt"); err != nil || !reflect.DeepEqual(names, []string{"a.txt"}) {
+				t.Errorf("expected a file to list as itself, got %v (%v)", names, err)
+			}
+			if names, err := backend.List("dir/sub"); err != nil || len(names) != 0 {
+				t.Errorf("expected an empty directory to list nothing, got %v (%v)", names, err)
+			}
+			if _, err := backend.List("missing"); storageErrorType(err) != errors.StorageErrorNotFound {
+				t.Errorf("expected StorageErrorNotFound, got %v", err)
+			}
+
+			entries, err := ListDetailed(backend, "dir")
+			if err != nil || len(entries) != 3 {
+				t.Fatalf("expected 3 detailed entries, got %v (%v)", entries, err)
+			}
+			if entries[0].Size != 1 || entries[2].Name != "sub" || !entries[2].IsDir {
+				t.Errorf("unexpected detailed entries: %+v", entries)
+			}
+		})
+	}
+}
+
+func TestConformance_StatAndRange(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			backend.Put("docs/range.txt", []byte("0123456789"))
+
+			info, err := backend.Stat("docs/range.txt")
+			if err != nil || info.Name != "range.txt" || info.Size != 10 || info.IsDir || info.ModTime.IsZero() {
+				t.Errorf("unexpected file info %+v (%v)", info, err)
+			}
+			if info, err := backend.Stat("docs"); err != nil || !info.IsDir || info.Size != 0 {
+				t.Errorf("unexpected directory info %+v (%v)", info, err)
+			}
+			if _, err := backend.Stat("missing"); storageErrorType(err) != errors.StorageErrorNotFound {
+				t.Errorf("expected StorageErrorNotFound, got %v", err)
+			}
+
+			if data, err := backend.GetRange("docs/range.txt", 8, 100); err != nil || string(data) != "89" {
+				t.Errorf("expected a truncated range, got %q (%v)", data, err)
+			}
+			if data, err := backend.GetRange("docs/range.txt", 20, 5); err != nil || len(data) != 0 {
+				t.Errorf("expected an empty range past the end, got %q (%v)", data, err)
+			}
+			if _, err := backend.GetRange("docs/range.txt", -1, 5); err == nil {
+				t.Error("expected an error for a negative offset")
+			}
+			if _, err := backend.GetRange("missing.txt", 0, 5); storageErrorType(err) != errors.StorageErrorNotFound {
+				t.Errorf("expected StorageErrorNotFound, got %v", err)
+			}
+			if size, err := backend.Size("docs/range.txt"); err != nil || size != 10 {
+				t.Errorf("expected size 10, got %d (%v)", size, err)
+			}
+		})
+	}
+}
+
+func TestConformance_DeleteAndMkdir(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := backend.Mkdir("a/b/c"); err != nil {
+				t.Fatalf("Mkdir failed: %v", err)
+			}
+			if !backend.IsDir("a/b") || !backend.IsDir("a/b/c") {
+				t.Error("expected nested directories to be created")
+			}
+			if err := backend.Mkdir("a/b"); err != nil {
+				t.Errorf("expected Mkdir of an existing directory to succeed: %v", err)
+			}
+
+			backend.Put("a/b/file.txt", []byte("x"))
+			if err := backend.Mkdir("a/b/file.txt/sub"); err == nil {
+				t.Error("expected an error creating a directory beneath a file")
+			}
+
+			if err := backend.Delete("a/b/file.txt"); err != nil {
+				t.Fatalf("Delete of a file failed: %v", err)
+			}
+			if backend.Exists("a/b/file.txt") || !backend.Exists("a/b") {
+				t.Error("expected only the file to be removed")
+			}
+			if err := backend.Delete("a"); err != nil {
+				t.Fatalf("Delete of a directory failed: %v", err)
+			}
+			if backend.Exists("a/b/c") {
+				t.Error("expected directories to be removed recursively")
+			}
+			if storageErrorType(backend.Delete("a")) != errors.StorageErrorNotFound {
+				t.Error("expected StorageErrorNotFound deleting a missing path")
+			}
+		})
+	}
+}
+
+func TestConformance_Rename(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			backend.Put("staged/a.txt", []byte("a"))
+			backend.Put("staged/sub/b.txt", []byte("b"))
+			backend.Put("live/keep.txt", []byte("keep"))
+
+			if err := backend.Rename("staged", "site/v2"); err != nil {
+				t.Fatalf("Rename failed: %v", err)
+			}
+			if data, err := backend.Get("site/v2/sub/b.txt"); err != nil || string(data) != "b" {
+				t.Errorf("expected moved file, got %q (%v)", data, err)
+			}
+			if backend.Exists("staged") {
+				t.Error("expected source to be gone")
+			}
+
+			backend.Put("other/a.txt", []byte("a"))
+			if err := backend.Rename("other", "live"); storageErrorType(err) != errors.StorageErrorAlreadyExists {
+				t.Errorf("expected StorageErrorAlreadyExists, got %v", err)
+			}
+			if err := backend.Rename("missing", "elsewhere"); storageErrorType(err) != errors.StorageErrorNotFound {
+				t.Errorf("expected StorageErrorNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMemStorage_CopiesData(t *testing.T) {
+	mem := NewMemStorage()
+	data := []byte("original")
+	mem.Put("a.txt", data)
+	data[0] = 'X'
+
+	got, _ := mem.Get("a.txt")
+	got[1] = 'X'
+	if again, _ := mem.Get("a.txt"); string(again) != "original" {
+		t.Errorf("expected stored data to be isolated from callers, got %q", again)
+	}
+}
+
+func TestMemStorage_WalkAndUsage(t *testing.T) {
+	mem := NewMemStorage()
+	mem.Put("b/two.txt", []byte("22"))
+	mem.Put("a.txt", []byte("1"))
+	mem.Put("b/.two.txt.tmp", []byte("user"))
+
+	var walked []string
+	if err := mem.Walk("", func(p string) error {
+		walked = append(walked, p)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	if want := []string{"a.txt", "b/.two.txt.tmp", "b/two.txt"}; !reflect.DeepEqual(walked, want) {
+		t.Errorf("expected %v, got %v", want, walked)
+	}
+
+	mem.Delete("b/.two.txt.tmp")
+	if usage, err := mem.Usage(); err != nil || usage != 3 {
+		t.Errorf("expected 3 bytes used, got %d (%v)", usage, err)
+	}
+}
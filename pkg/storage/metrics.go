@@ -0,0 +1,285 @@
+package storage
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultSlowThreshold is how long a storage operation may take before it is logged
+const DefaultSlowThreshold = time.Second
+
+// LatencyBuckets are the upper bounds of the operation duration histogram
+var LatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// OpStats summarizes the timings of one kind of storage operation
+type OpStats struct {
+	Count   uint64        // operations performed
+	Errors  uint64        // operations that returned an error
+	Total   time.Duration // sum of all durations
+	Max     time.Duration // slowest operation
+	Buckets []uint64      // operations at or under each of LatencyBuckets (cumulative)
+}
+
+// Instrumented is a Storage decorator that times every operation on the
+// wrapped backend, keeps a latency histogram per operation and logs
+// operations slower than a threshold. It works with any backend and passes
+// range reads and walks through to backends that support them.
+type Instrumented struct {
+	backend Storage
+	slow    time.Duration
+	logf    func(format string, args ...interface{})
+
+	mu  sync.Mutex
+	ops map[string]*OpStats
+}
+
+// NewInstrumented wraps backend, logging operations that take longer than
+// slow. A zero or negative threshold uses DefaultSlowThreshold.
+func NewInstrumented(backend Storage, slow time.Duration) *Instrumented {
+	if slow <= 0 {
+		slow = DefaultSlowThreshold
+	}
+	return &Instrumented{
+		backend: backend,
+		slow:    slow,
+		logf: func(format string, args ...interface{}) {
+			fmt.Printf(format+"\n", args...)
+		},
+		ops: make(map[string]*OpStats),
+	}
+}
+
+// observe records how long op took on path since start
+func (m *Instrumented) observe(op, path string, start time.Time, err error) {
+	elapsed := time.Since(start)
+
+	m.mu.Lock()
+	stats, ok := m.ops[op]
+	if !ok {
+		stats = &OpStats{Buckets: make([]uint64, len(LatencyBuckets))}
+		m.ops[op] = stats
+	}
+	stats.Count++
+	if err != nil {
+		stats.Errors++
+	}
+	stats.Total += elapsed
+	if elapsed > stats.Max {
+		stats.Max = elapsed
+	}
+	for i, bound := range LatencyBuckets {
+		if elapsed <= bound {
+			stats.Buckets[i]++
+		}
+	}
+	m.mu.Unlock()
+
+	if elapsed >= m.slow {
+		m.logf("Warning: slow storage operation: %s %s took %v", op, path, elapsed.Round(time.Millisecond))
+	}
+}
+
+// Put stores a file in the backend, timing the call
+func (m *Instrumented) Put(path string, data []byte) error {
+	start := time.Now()
+	err := m.backend.Put(path, data)
+	m.observe("put", path, start, err)
+	return err
+}
+
+// Get reads a file from the backend, timing the call
+func (m *Instrumented) Get(path string) ([]byte, error) {
+	start := time.Now()
+	data, err := m.backend.Get(path)
+	m.observe("get", path, start, err)
+	return data, err
+}
+
+// Exists checks the backend for a path, timing the call
+func (m *Instrumented) Exists(path string) bool {
+	start := time.Now()
+	exists := m.backend.Exists(path)
+	m.observe("exists", path, start, nil)
+	return exists
+}
+
+// IsDir checks whether a path is a directory in the backend, timing the call.
+// Backends that can't tell directories apart from files report false.
+func (m *Instrumented) IsDir(path string) bool {
+	dc, ok := m.backend.(DirChecker)
+	if !ok {
+		return false
+	}
+	start := time.Now()
+	isDir := dc.IsDir(path)
+	m.observe("is_dir", path, start, nil)
+	return isDir
+}
+
+// Stat describes a path in the backend, timing the call
+func (m *Instrumented) Stat(path string) (FileInfo, error) {
+	start := time.Now()
+	info, err := Stat(m.backend, path)
+	m.observe("stat", path, start, err)
+	return info, err
+}
+
+// List lists a directory in the backend, timing the call
+func (m *Instrumented) List(path string) ([]string, error) {
+	start := time.Now()
+	names, err := m.backend.List(path)
+	m.observe("list", path, start, err)
+	return names, err
+}
+
+// ListDetailed describes a directory's entries in the backend, timing the call
+func (m *Instrumented) ListDetailed(path string) ([]FileInfo, error) {
+	start := time.Now()
+	entries, err := ListDetailed(m.backend, path)
+	m.observe("list_detailed", path, start, err)
+	return entries, err
+}
+
+// Delete removes a path from the backend, timing the call
+func (m *Instrumented) Delete(path string) error {
+	start := time.Now()
+	err := m.backend.Delete(path)
+	m.observe("delete", path, start, err)
+	return err
+}
+
+// Mkdir creates a directory in the backend, timing the call
+func (m *Instrumented) Mkdir(path string) error {
+	start := time.Now()
+	err := m.backend.Mkdir(path)
+	m.observe("mkdir", path, start, err)
+	return err
+}
+
+// Size returns the size of a file, reading it whole if the backend can't seek
+func (m *Instrumented) Size(path string) (int64, error) {
+	start := time.Now()
+	var size int64
+	var err error
+	if rg, ok := m.backend.(RangeGetter); ok {
+		size, err = rg.Size(path)
+	} else {
+		var data []byte
+		data, err = m.backend.Get(path)
+		size = int64(len(data))
+	}
+	m.observe("size", path, start, err)
+	return size, err
+}
+
+// GetRange reads part of a file, reading it whole if the backend can't seek
+func (m *Instrumented) GetRange(path string, offset, length int64) ([]byte, error) {
+	start := time.Now()
+	var data []byte
+	var err error
+	if rg, ok := m.backend.(RangeGetter); ok {
+		data, err = rg.GetRange(path, offset, length)
+	} else {
+		data, err = m.backend.Get(path)
+		if err == nil {
+			if offset > int64(len(data)) {
+				offset = int64(len(data))
+			}
+			end := offset + length
+			if end > int64(len(data)) {
+				end = int64(len(data))
+			}
+			data = data[offset:end]
+		}
+	}
+	m.observe("get_range", path, start, err)
+	return data, err
+}
+
+// Walk passes through to the backend. Walks aren't timed because their
+// duration mostly reflects the caller's callback. Returns an error wrapping
+// errors.ErrUnsupported if the backend can't be walked.
+func (m *Instrumented) Walk(root string, fn func(path string) error) error {
+	walker, ok := m.backend.(Walker)
+	if !ok {
+		return fmt.Errorf("storage backend cannot be walked: %w", stderrors.ErrUnsupported)
+	}
+	return walker.Walk(root, fn)
+}
+
+// Rename moves a path in the backend, timing the call. Returns an error
+// wrapping errors.ErrUnsupported if the backend can't rename atomically.
+func (m *Instrumented) Rename(oldPath, newPath string) error {
+	renamer, ok := m.backend.(Renamer)
+	if !ok {
+		return fmt.Errorf("storage backend cannot rename: %w", stderrors.ErrUnsupported)
+	}
+	start := time.Now()
+	err := renamer.Rename(oldPath, newPath)
+	m.observe("rename", oldPath, start, err)
+	return err
+}
+
+// Snapshot returns a copy of the statistics for each operation performed so far
+func (m *Instrumented) Snapshot() map[string]OpStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]OpStats, len(m.ops))
+	for op, stats := range m.ops {
+		copied := *stats
+		copied.Buckets = append([]uint64(nil), stats.Buckets...)
+		snapshot[op] = copied
+	}
+	return snapshot
+}
+
+// WriteMetrics writes the statistics in the Prometheus text exposition format
+func (m *Instrumented) WriteMetrics(w io.Writer) error {
+	snapshot := m.Snapshot()
+	ops := make([]string, 0, len(snapshot))
+	for op := range snapshot {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	printf("# HELP goflux_storage_operation_duration_seconds Time taken by storage operations.\n")
+	printf("# TYPE goflux_storage_operation_duration_seconds histogram\n")
+	for _, op := range ops {
+		stats := snapshot[op]
+		for i, bound := range LatencyBuckets {
+			printf("goflux_storage_operation_duration_seconds_bucket{op=%q,le=\"%g\"} %d\n", op, bound.Seconds(), stats.Buckets[i])
+		}
+		printf("goflux_storage_operation_duration_seconds_bucket{op=%q,le=\"+Inf\"} %d\n", op, stats.Count)
+		printf("goflux_storage_operation_duration_seconds_sum{op=%q} %g\n", op, stats.Total.Seconds())
+		printf("goflux_storage_operation_duration_seconds_count{op=%q} %d\n", op, stats.Count)
+	}
+
+	printf("# HELP goflux_storage_operation_errors_total Storage operations that returned an error.\n")
+	printf("# TYPE goflux_storage_operation_errors_total counter\n")
+	for _, op := range ops {
+		printf("goflux_storage_operation_errors_total{op=%q} %d\n", op, snapshot[op].Errors)
+	}
+
+	return err
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowStorage delays every Get to simulate a slow disk or backend
+type slowStorage struct {
+	Storage
+	delay time.Duration
+}
+
+func (s *slowStorage) Get(path string) ([]byte, error) {
+	time.Sleep(s.delay)
+	return s.Storage.Get(path)
+}
+
+func TestInstrumented_SlowOperationLogged(t *testing.T) {
+	local, _ := NewLocal(t.TempDir())
+	local.Put("file.txt", []byte("data"))
+
+	m := NewInstrumented(&slowStorage{Storage: local, delay: 60 * time.Millisecond}, 50*time.Millisecond)
+	var logged []string
+	m.logf = func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+
+	if _, err := m.Get("file.txt"); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if err := m.Put("other.txt", []byte("fast")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	if len(logged) != 1 || !strings.Contains(logged[0], "get file.txt") {
+		t.Fatalf("expected one slow-operation log for the get, got %q", logged)
+	}
+
+	stats := m.Snapshot()
+	get := stats["get"]
+	if get.Count != 1 || get.Max < 60*time.Millisecond || get.Total < 60*time.Millisecond {
+		t.Errorf("expected one get of at least 60ms, got %+v", get)
+	}
+	// 60ms lands above the 10ms bucket and within the 5s one
+	if get.Buckets[2] != 0 || get.Buckets[len(LatencyBuckets)-1] != 1 {
+		t.Errorf("unexpected histogram buckets: %v", get.Buckets)
+	}
+	if stats["put"].Count != 1 {
+		t.Errorf("expected one put, got %+v", stats["put"])
+	}
+}
+
+func TestInstrumented_ErrorsAndMetrics(t *testing.T) {
+	local, _ := NewLocal(t.TempDir())
+	m := NewInstrumented(local, time.Hour)
+
+	if _, err := m.Get("missing.txt"); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+	if errs := m.Snapshot()["get"].Errors; errs != 1 {
+		t.Errorf("expected 1 get error, got %d", errs)
+	}
+
+	var out strings.Builder
+	if err := m.WriteMetrics(&out); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+	for _, want := range []string{
+		`goflux_storage_operation_duration_seconds_count{op="get"} 1`,
+		`goflux_storage_operation_duration_seconds_bucket{op="get",le="+Inf"} 1`,
+		`goflux_storage_operation_errors_total{op="get"} 1`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestInstrumented_PassesThroughCapabilities(t *testing.T) {
+	local, _ := NewLocal(t.TempDir())
+	local.Put("dir/file.txt", []byte("0123456789"))
+	m := NewInstrumented(local, time.Hour)
+
+	data, err := m.GetRange("dir/file.txt", 2, 3)
+	if err != nil || string(data) != "234" {
+		t.Errorf("expected 234, got %q (%v)", data, err)
+	}
+
+	if !m.IsDir("dir") || m.IsDir("dir/file.txt") {
+		t.Error("expected IsDir to pass through to the backend")
+	}
+
+	if info, err := m.Stat("dir/file.txt"); err != nil || info.Size != 10 || info.ModTime.IsZero() {
+		t.Errorf("expected Stat to pass through to the backend, got %+v (%v)", info, err)
+	}
+
+	if entries, err := m.ListDetailed("dir"); err != nil || len(entries) != 1 || entries[0].Size != 10 {
+		t.Errorf("expected ListDetailed to pass through to the backend, got %+v (%v)", entries, err)
+	}
+
+	var walked []string
+	if err := m.Walk("", func(path string) error {
+		walked = append(walked, path)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	if len(walked) != 1 || walked[0] != "dir/file.txt" {
+		t.Errorf("expected dir/file.txt, got %v", walked)
+	}
+
+	// Backends that can't be walked report it as unsupported
+	router := NewRouter(local)
+	err = NewInstrumented(router, time.Hour).Walk("", func(string) error { return nil })
+	if !stderrors.Is(err, stderrors.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
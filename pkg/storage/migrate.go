@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// Walker is implemented by backends that can enumerate every file beneath a directory.
+type Walker interface {
+	// Walk calls fn with the slash-separated storage path of each file under root.
+	Walk(root string, fn func(path string) error) error
+}
+
+// Open creates a storage backend from a URI such as "local://./data".
+// A URI without a scheme is treated as a local directory.
+func Open(uri string) (Storage, error) {
+	root, err := localRoot(uri)
+	if err != nil {
+		return nil, err
+	}
+	return NewLocal(root)
+}
+
+// Check verifies that Open could open the backend at uri, without creating
+// its directory as Open would.
+func Check(uri string) error {
+	root, err := localRoot(uri)
+	if err != nil {
+		return err
+	}
+	return CheckWritable(root)
+}
+
+// localRoot returns the directory a local storage URI names
+func localRoot(uri string) (string, error) {
+	scheme, location, found := strings.Cut(uri, "://")
+	if !found {
+		return uri, nil
+	}
+
+	switch scheme {
+	case "local", "file":
+		if location == "" {
+			return "", fmt.Errorf("local storage URI requires a directory: %s", uri)
+		}
+		return location, nil
+	default:
+		return "", fmt.Errorf("unsupported storage backend: %s", scheme)
+	}
+}
+
+// Walk calls fn for every regular file under root, passing its slash-separated
+// path relative to the storage root. Internal temporary files are skipped.
+// Returns StorageError if root is invalid.
+func (l *Local) Walk(root string, fn func(path string) error) error {
+	fullRoot, err := l.sanitizePath(root)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+
+	return filepath.WalkDir(fullRoot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || IsInternal(d.Name()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.Root, p)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel))
+	})
+}
+
+// MigrateStats summarizes a migration run.
+type MigrateStats struct {
+	Copied  int   // files copied to the destination
+	Skipped int   // files already present with identical content
+	Bytes   int64 // bytes copied
+}
+
+// MigrateFunc is called after each file is processed.
+type MigrateFunc func(path string, skipped bool)
+
+// Migrate copies every file from src to dst, preserving paths. Each copy is
+// verified by reading it back and comparing SHA-256 hashes. Files that already
+// exist at the destination with identical content are skipped, so an
+// interrupted migration can be resumed by running it again.
+func Migrate(src Storage, dst Storage, progress MigrateFunc) (MigrateStats, error) {
+	var stats MigrateStats
+
+	walker, ok := src.(Walker)
+	if !ok {
+		return stats, fmt.Errorf("source storage does not support walking")
+	}
+
+	err := walker.Walk("", func(path string) error {
+		data, err := src.Get(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		srcHash := sha256.Sum256(data)
+
+		// Resume support: skip files that were already migrated intact
+		if dst.Exists(path) {
+			if existing, err := dst.Get(path); err == nil && sha256.Sum256(existing) == srcHash {
+				stats.Skipped++
+				if progress != nil {
+					progress(path, true)
+				}
+				return nil
+			}
+		}
+
+		if err := dst.Put(path, data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+
+		written, err := dst.Get(path)
+		if err != nil {
+			return fmt.Errorf("failed to verify %s: %w", path, err)
+		}
+		if sha256.Sum256(written) != srcHash {
+			return fmt.Errorf("hash mismatch after copying %s", path)
+		}
+
+		stats.Copied++
+		stats.Bytes += int64(len(data))
+		if progress != nil {
+			progress(path, false)
+		}
+		return nil
+	})
+	return stats, err
+}
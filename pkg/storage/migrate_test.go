@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		uri     string
+		wantErr bool
+	}{
+		{name: "local scheme", uri: "local://" + filepath.Join(tmpDir, "a")},
+		{name: "plain path", uri: filepath.Join(tmpDir, "b")},
+		{name: "empty local path", uri: "local://", wantErr: true},
+		{name: "unsupported scheme", uri: "s3://bucket", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := Open(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open(%q) error = %v, wantErr %v", tt.uri, err, tt.wantErr)
+			}
+			if !tt.wantErr && store == nil {
+				t.Error("expected non-nil storage")
+			}
+		})
+	}
+}
+
+func TestLocal_Walk(t *testing.T) {
+	local, _ := NewLocal(t.TempDir())
+	local.Put("a.txt", []byte("a"))
+	local.Put("sub/b.txt", []byte("b"))
+	local.Put("sub/deeper/c.txt", []byte("c"))
+	local.Mkdir("empty")
+
+	found := map[string]bool{}
+	if err := local.Walk("", func(path string) error {
+		found[path] = true
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+
+	for _, want := range []string{"a.txt", "sub/b.txt", "sub/deeper/c.txt"} {
+		if !found[want] {
+			t.Errorf("expected Walk to visit %s", want)
+		}
+	}
+	if len(found) != 3 {
+		t.Errorf("expected 3 files, got %d: %v", len(found), found)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	src, _ := NewLocal(t.TempDir())
+	dst, _ := NewLocal(t.TempDir())
+
+	files := map[string]string{
+		"root.txt":           "root file",
+		"docs/report.pdf":    "pdf bytes",
+		"docs/2024/jan.csv":  "a,b,c",
+		"media/img/logo.png": "\x89PNG",
+	}
+	for path, content := range files {
+		if err := src.Put(path, []byte(content)); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	stats, err := Migrate(src, dst, nil)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if stats.Copied != len(files) || stats.Skipped != 0 {
+		t.Errorf("expected %d copied and 0 skipped, got %+v", len(files), stats)
+	}
+
+	for path, content := range files {
+		data, err := dst.Get(path)
+		if err != nil {
+			t.Errorf("missing %s in destination: %v", path, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("content mismatch for %s", path)
+		}
+	}
+}
+
+func TestMigrate_Resume(t *testing.T) {
+	src, _ := NewLocal(t.TempDir())
+	dst, _ := NewLocal(t.TempDir())
+
+	src.Put("one.txt", []byte("one"))
+	src.Put("two.txt", []byte("two"))
+	src.Put("dir/three.txt", []byte("three"))
+
+	// Simulate an interrupted run: one file copied intact, one left truncated
+	dst.Put("one.txt", []byte("one"))
+	dst.Put("two.txt", []byte("tw"))
+
+	var skipped []string
+	stats, err := Migrate(src, dst, func(path string, wasSkipped bool) {
+		if wasSkipped {
+			skipped = append(skipped, path)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	if stats.Skipped != 1 || len(skipped) != 1 || skipped[0] != "one.txt" {
+		t.Errorf("expected only one.txt to be skipped, got %v (%+v)", skipped, stats)
+	}
+	if stats.Copied != 2 {
+		t.Errorf("expected 2 files copied, got %d", stats.Copied)
+	}
+
+	data, _ := dst.Get("two.txt")
+	if string(data) != "two" {
+		t.Errorf("expected truncated file to be repaired, got %q", data)
+	}
+}
@@ -0,0 +1,312 @@
+package storage
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+// Route sends matching files to a specific backend.
+// A route matches when its Pattern matches the file path or its ContentType
+// is a prefix of the content type sniffed from the file's data.
+type Route struct {
+	// Pattern is a path.Match pattern. Patterns without a slash match the
+	// file's base name (e.g. "*.jpg"); others match the full path (e.g. "media/*").
+	Pattern string
+	// ContentType is a content type prefix such as "image/" or "application/pdf".
+	ContentType string
+	// Backend receives files matching this route.
+	Backend Storage
+}
+
+// matchesPath reports whether the route's pattern matches the storage path
+func (r Route) matchesPath(p string) bool {
+	if r.Pattern == "" {
+		return false
+	}
+	p = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(p, "\\", "/")), "/")
+	target := p
+	if !strings.Contains(r.Pattern, "/") {
+		target = path.Base(p)
+	}
+	matched, err := path.Match(r.Pattern, target)
+	return err == nil && matched
+}
+
+// matchesContent reports whether the route's content type matches the sniffed data
+func (r Route) matchesContent(data []byte) bool {
+	if r.ContentType == "" {
+		return false
+	}
+	return strings.HasPrefix(http.DetectContentType(data), r.ContentType)
+}
+
+// Router is a composite Storage that places files in different backends
+// according to a list of routes. Files matching no route go to the fallback.
+// Reads consult the same routing: path-matched backends are checked first,
+// then backends that may hold content-routed files, then the fallback.
+type Router struct {
+	routes   []Route
+	fallback Storage
+}
+
+// NewRouter creates a routing storage. Routes are evaluated in order and the
+// first match wins. With no routes, every operation goes to the fallback.
+func NewRouter(fallback Storage, routes ...Route) *Router {
+	return &Router{
+		routes:   routes,
+		fallback: fallback,
+	}
+}
+
+// routeForPut selects the backend that should store the data
+func (r *Router) routeForPut(p string, data []byte) Storage {
+	for _, route := range r.routes {
+		if route.matchesPath(p) || route.matchesContent(data) {
+			return route.Backend
+		}
+	}
+	return r.fallback
+}
+
+// candidates returns the backends that might hold a path, most likely first
+func (r *Router) candidates(p string) []Storage {
+	var result []Storage
+	seen := make(map[Storage]bool)
+	add := func(s Storage) {
+		if !seen[s] {
+			seen[s] = true
+			result = append(result, s)
+		}
+	}
+
+	for _, route := range r.routes {
+		if route.matchesPath(p) {
+			add(route.Backend)
+		}
+	}
+	for _, route := range r.routes {
+		if route.ContentType != "" {
+			add(route.Backend)
+		}
+	}
+	add(r.fallback)
+	return result
+}
+
+// locate returns the first backend that holds the path
+func (r *Router) locate(p string) (Storage, bool) {
+	for _, backend := range r.candidates(p) {
+		if backend.Exists(p) {
+			return backend, true
+		}
+	}
+	return nil, false
+}
+
+// Put stores data in the backend selected by the routes, then removes any
+// copy of the path another backend holds, as when an overwrite is sniffed as
+// a different content type, so that the old copy can't shadow the new one.
+func (r *Router) Put(p string, data []byte) error {
+	target := r.routeForPut(p, data)
+	if err := target.Put(p, data); err != nil {
+		return err
+	}
+	for _, backend := range r.backends() {
+		if backend == target || !backend.Exists(p) {
+			continue
+		}
+		if dc, ok := backend.(DirChecker); ok && dc.IsDir(p) {
+			continue
+		}
+		if err := backend.Delete(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get retrieves data from whichever routed backend holds the path.
+func (r *Router) Get(p string) ([]byte, error) {
+	if backend, ok := r.locate(p); ok {
+		return backend.Get(p)
+	}
+	return r.routeForPut(p, nil).Get(p)
+}
+
+// Size returns the file size from whichever routed backend holds the path.
+func (r *Router) Size(p string) (int64, error) {
+	backend, ok := r.locate(p)
+	if !ok {
+		return 0, errors.NewStorageError(errors.StorageErrorNotFound, p, "path does not exist")
+	}
+	if rg, ok := backend.(RangeGetter); ok {
+		return rg.Size(p)
+	}
+	data, err := backend.Get(p)
+	return int64(len(data)), err
+}
+
+// GetRange reads part of a file from whichever routed backend holds the path,
+// falling back to a full read for backends that can't seek.
+func (r *Router) GetRange(p string, offset, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, errors.NewStorageError(errors.StorageErrorInvalidPath, p, fmt.Sprintf("invalid range: offset %d, length %d", offset, length))
+	}
+
+	backend, ok := r.locate(p)
+	if !ok {
+		return nil, errors.NewStorageError(errors.StorageErrorNotFound, p, "path does not exist")
+	}
+	if rg, ok := backend.(RangeGetter); ok {
+		return rg.GetRange(p, offset, length)
+	}
+
+	data, err := backend.Get(p)
+	if err != nil {
+		return nil, err
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	end := offset + length
+	if end > int64(len(data)) {
+		end = int64(len(data))
+	}
+	return data[offset:end], nil
+}
+
+// Stat describes the path in whichever routed backend holds it.
+func (r *Router) Stat(p string) (FileInfo, error) {
+	backend, ok := r.locate(p)
+	if !ok {
+		return FileInfo{}, errors.NewStorageError(errors.StorageErrorNotFound, p, "path does not exist")
+	}
+	return Stat(backend, p)
+}
+
+// Exists checks whether any routed backend holds the path.
+func (r *Router) Exists(p string) bool {
+	_, ok := r.locate(p)
+	return ok
+}
+
+// IsDir reports whether the path is a directory in any routed backend.
+func (r *Router) IsDir(p string) bool {
+	for _, backend := range r.candidates(p) {
+		if dc, ok := backend.(DirChecker); ok && dc.IsDir(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// List merges the directory listings of all backends.
+func (r *Router) List(p string) ([]string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	var firstErr error
+	found := false
+
+	for _, backend := range r.backends() {
+		entries, err := backend.List(p)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		found = true
+		for _, name := range entries {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	if !found {
+		return nil, firstErr
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ListDetailed merges the detailed directory listings of all backends. An
+// entry present in several backends is described by the first one holding it.
+func (r *Router) ListDetailed(p string) ([]FileInfo, error) {
+	seen := make(map[string]bool)
+	var entries []FileInfo
+	var firstErr error
+	found := false
+
+	for _, backend := range r.backends() {
+		listed, err := ListDetailed(backend, p)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		found = true
+		for _, entry := range listed {
+			if !seen[entry.Name] {
+				seen[entry.Name] = true
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	if !found {
+		return nil, firstErr
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// Delete removes the path from every backend that holds it.
+// Returns StorageErrorNotFound if no backend has it.
+func (r *Router) Delete(p string) error {
+	deleted := false
+	for _, backend := range r.backends() {
+		if !backend.Exists(p) {
+			continue
+		}
+		if err := backend.Delete(p); err != nil {
+			return err
+		}
+		deleted = true
+	}
+
+	if !deleted {
+		return errors.NewStorageError(errors.StorageErrorNotFound, p, "path does not exist")
+	}
+	return nil
+}
+
+// Mkdir creates the directory in every backend so routed files can land in it.
+func (r *Router) Mkdir(p string) error {
+	for _, backend := range r.backends() {
+		if err := backend.Mkdir(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backends returns each distinct backend once, fallback first
+func (r *Router) backends() []Storage {
+	result := []Storage{r.fallback}
+	seen := map[Storage]bool{r.fallback: true}
+	for _, route := range r.routes {
+		if !seen[route.Backend] {
+			seen[route.Backend] = true
+			result = append(result, route.Backend)
+		}
+	}
+	return result
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+// pngHeader is enough of a PNG signature for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func newTestRouter(t *testing.T) (*Router, *Local, *Local, *Local) {
+	t.Helper()
+
+	fallback, _ := NewLocal(t.TempDir())
+	images, _ := NewLocal(t.TempDir())
+	docs, _ := NewLocal(t.TempDir())
+
+	router := NewRouter(fallback,
+		Route{ContentType: "image/", Backend: images},
+		Route{Pattern: "*.pdf", Backend: docs},
+		Route{Pattern: "reports/*", Backend: docs},
+	)
+	return router, fallback, images, docs
+}
+
+func TestRouter_PutRoutesToBackend(t *testing.T) {
+	router, fallback, images, docs := newTestRouter(t)
+
+	router.Put("photos/cat.bin", pngHeader)     // sniffed as image/png
+	router.Put("papers/paper.pdf", []byte("x")) // base name pattern
+	router.Put("reports/q1.txt", []byte("q1"))  // full path pattern
+	router.Put("notes.txt", []byte("plain"))    // no match
+
+	if !images.Exists("photos/cat.bin") {
+		t.Error("expected image to land in images backend")
+	}
+	if !docs.Exists("papers/paper.pdf") || !docs.Exists("reports/q1.txt") {
+		t.Error("expected pattern-matched files to land in docs backend")
+	}
+	if !fallback.Exists("notes.txt") {
+		t.Error("expected unmatched file to land in fallback backend")
+	}
+	if fallback.Exists("photos/cat.bin") || fallback.Exists("papers/paper.pdf") {
+		t.Error("routed files should not be written to the fallback")
+	}
+}
+
+func TestRouter_OverwriteMovesBetweenBackends(t *testing.T) {
+	router, fallback, images, _ := newTestRouter(t)
+
+	router.Put("upload.bin", pngHeader)
+	if err := router.Put("upload.bin", []byte("now plain text")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if images.Exists("upload.bin") {
+		t.Error("expected the old copy to be removed from the images backend")
+	}
+	if !fallback.Exists("upload.bin") {
+		t.Error("expected the new copy in the fallback backend")
+	}
+	if data, err := router.Get("upload.bin"); err != nil || string(data) != "now plain text" {
+		t.Errorf("expected the new content, got %q (%v)", data, err)
+	}
+}
+
+func TestRouter_CompressedBackendsRouteByContent(t *testing.T) {
+	fallback, _ := NewLocal(t.TempDir())
+	images, _ := NewLocal(t.TempDir())
+	router := NewRouter(NewCompressed(fallback, DefaultBlockSize),
+		Route{ContentType: "image/", Backend: NewCompressed(images, DefaultBlockSize)})
+
+	if err := router.Put("photos/cat.bin", pngHeader); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if !images.Exists("photos/cat.bin") || fallback.Exists("photos/cat.bin") {
+		t.Error("expected the image to be routed by its own content, not its compressed form")
+	}
+	if data, err := router.Get("photos/cat.bin"); err != nil || string(data) != string(pngHeader) {
+		t.Errorf("expected the image back, got %q (%v)", data, err)
+	}
+}
+
+func TestRouter_ReadsFollowRouting(t *testing.T) {
+	router, _, _, _ := newTestRouter(t)
+
+	router.Put("photos/cat.bin", pngHeader)
+	router.Put("papers/paper.pdf", []byte("pdf"))
+	router.Put("notes.txt", []byte("plain"))
+
+	for path, want := range map[string]string{
+		"photos/cat.bin":   string(pngHeader),
+		"papers/paper.pdf": "pdf",
+		"notes.txt":        "plain",
+	} {
+		data, err := router.Get(path)
+		if err != nil {
+			t.Errorf("Get(%s) failed: %v", path, err)
+			continue
+		}
+		if string(data) != want {
+			t.Errorf("Get(%s) content mismatch", path)
+		}
+		if !router.Exists(path) {
+			t.Errorf("Exists(%s) should be true", path)
+		}
+	}
+
+	if err := router.Delete("photos/cat.bin"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if router.Exists("photos/cat.bin") {
+		t.Error("expected routed file to be deleted")
+	}
+
+	err := router.Delete("photos/cat.bin")
+	if errType, ok := errors.GetStorageErrorType(err); !ok || errType != errors.StorageErrorNotFound {
+		t.Errorf("expected StorageErrorNotFound, got %v", err)
+	}
+}
+
+func TestRouter_ListMergesBackends(t *testing.T) {
+	router, _, _, _ := newTestRouter(t)
+
+	router.Put("mixed/a.pdf", []byte("a"))
+	router.Put("mixed/b.txt", []byte("b"))
+	router.Put("mixed/c.bin", pngHeader)
+
+	names, err := router.List("mixed")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(names) != 3 {
+		t.Errorf("expected 3 merged entries, got %v", names)
+	}
+}
+
+func TestRouter_ListDetailedMergesBackends(t *testing.T) {
+	router, _, _, _ := newTestRouter(t)
+
+	router.Put("mixed/a.pdf", []byte("a"))
+	router.Put("mixed/b.txt", []byte("bb"))
+	router.Put("mixed/sub/c.txt", []byte("c"))
+
+	entries, err := router.ListDetailed("mixed")
+	if err != nil {
+		t.Fatalf("ListDetailed failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 merged entries, got %+v", entries)
+	}
+	if e := entries[0]; e.Name != "a.pdf" || e.Size != 1 || e.IsDir {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if e := entries[1]; e.Name != "b.txt" || e.Size != 2 || e.IsDir {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if e := entries[2]; e.Name != "sub" || !e.IsDir {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
+
+func TestRouter_NoRoutes(t *testing.T) {
+	fallback, _ := NewLocal(t.TempDir())
+	router := NewRouter(fallback)
+
+	router.Put("img.png", pngHeader)
+	if !fallback.Exists("img.png") {
+		t.Error("expected all files in fallback when no routes are configured")
+	}
+}
@@ -4,10 +4,15 @@
 package storage
 
 import (
+	stderrors "errors"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
 )
@@ -24,12 +29,116 @@ type Storage interface {
 	Mkdir(path string) error
 }
 
+// RangeGetter is implemented by backends that can read part of a file without
+// loading the whole file into memory.
+type RangeGetter interface {
+	// Size returns the length of the file at path in bytes.
+	Size(path string) (int64, error)
+	// GetRange returns up to length bytes of the file starting at offset.
+	GetRange(path string, offset, length int64) ([]byte, error)
+}
+
+// DirChecker is implemented by backends that can tell directories apart from files.
+type DirChecker interface {
+	// IsDir reports whether path is an existing directory.
+	IsDir(path string) bool
+}
+
+// Renamer is implemented by backends that can move a file or directory tree
+// in a single atomic step, so it appears at its new path all at once.
+type Renamer interface {
+	// Rename moves oldPath to newPath, which must not already exist.
+	Rename(oldPath, newPath string) error
+}
+
+// FileInfo describes a stored file or directory.
+type FileInfo struct {
+	Name    string    // base name of the entry
+	Size    int64     // length in bytes; zero for directories
+	IsDir   bool      // whether the entry is a directory
+	ModTime time.Time // last modification time; zero if the backend doesn't track it
+}
+
+// Statter is implemented by backends that can describe a path without reading it.
+type Statter interface {
+	// Stat returns information about the file or directory at path.
+	Stat(path string) (FileInfo, error)
+}
+
+// Stat describes the file or directory at p in backend. Backends that don't
+// implement Statter are described from their other methods, which may mean
+// reading the whole file to learn its size and leaves ModTime zero.
+// Returns StorageErrorNotFound if the path doesn't exist.
+func Stat(backend Storage, p string) (FileInfo, error) {
+	if st, ok := backend.(Statter); ok {
+		return st.Stat(p)
+	}
+
+	if !backend.Exists(p) {
+		return FileInfo{}, errors.NewStorageError(errors.StorageErrorNotFound, p, "path does not exist")
+	}
+	info := FileInfo{Name: path.Base(p)}
+	if dc, ok := backend.(DirChecker); ok && dc.IsDir(p) {
+		info.IsDir = true
+		return info, nil
+	}
+
+	if rg, ok := backend.(RangeGetter); ok {
+		size, err := rg.Size(p)
+		info.Size = size
+		return info, err
+	}
+	data, err := backend.Get(p)
+	info.Size = int64(len(data))
+	return info, err
+}
+
+// DetailedLister is implemented by backends that can describe every entry of
+// a directory in one call.
+type DetailedLister interface {
+	// ListDetailed returns information about each entry in the directory at path.
+	ListDetailed(path string) ([]FileInfo, error)
+}
+
+// ListDetailed describes each entry in the directory at p in backend. Backends
+// that don't implement DetailedLister have each listed entry described by Stat.
+func ListDetailed(backend Storage, p string) ([]FileInfo, error) {
+	if dl, ok := backend.(DetailedLister); ok {
+		return dl.ListDetailed(p)
+	}
+
+	names, err := backend.List(p)
+	if err != nil {
+		return nil, err
+	}
+	// Listing a file returns just its name
+	if dc, ok := backend.(DirChecker); ok && !dc.IsDir(p) {
+		info, err := Stat(backend, p)
+		if err != nil {
+			return nil, err
+		}
+		return []FileInfo{info}, nil
+	}
+
+	entries := make([]FileInfo, 0, len(names))
+	for _, name := range names {
+		info, err := Stat(backend, path.Join(p, name))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, info)
+	}
+	return entries, nil
+}
+
 // Local is a local filesystem storage implementation.
 // It stores files under a root directory and validates all paths to prevent
 // directory traversal attacks.
 type Local struct {
 	// Root is the base directory for all storage operations
 	Root string
+
+	usage usageCache // total size of the files under Root, once Usage has walked them
 }
 
 // NewLocal creates a new local filesystem storage backend rooted at the specified directory.
@@ -42,6 +151,44 @@ func NewLocal(root string) (*Local, error) {
 	return &Local{Root: root}, nil
 }
 
+// CheckWritable verifies that dir is a directory the server can create
+// files in without creating or changing anything: an existing dir must be
+// writable, and a missing one must have a writable directory as its nearest
+// existing ancestor so that it can be created.
+func CheckWritable(dir string) error {
+	info, err := os.Stat(dir)
+	if err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+		if err := canWrite(dir); err != nil {
+			return fmt.Errorf("cannot create files: %w", err)
+		}
+		return nil
+	}
+
+	// Find the directory the missing path would be created in. Paths beneath
+	// a file fail to stat too, and are reported when the file is reached.
+	parent := filepath.Dir(dir)
+	for {
+		info, statErr := os.Stat(parent)
+		if statErr == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("cannot create directory: %s is not a directory", parent)
+			}
+			if err := canWrite(parent); err != nil {
+				return fmt.Errorf("cannot create directory: %w", err)
+			}
+			return nil
+		}
+		next := filepath.Dir(parent)
+		if next == parent {
+			return fmt.Errorf("cannot create directory: %w", err)
+		}
+		parent = next
+	}
+}
+
 // sanitizePath ensures the path cannot escape the root directory
 func (l *Local) sanitizePath(path string) (string, error) {
 	// Clean the path to resolve . and .. components
@@ -50,6 +197,9 @@ func (l *Local) sanitizePath(path string) (string, error) {
 	// Remove leading slash to make it relative
 	cleanPath = strings.TrimPrefix(cleanPath, "/")
 	cleanPath = strings.TrimPrefix(cleanPath, "\\")
+	if err := checkInternal(path, cleanPath); err != nil {
+		return "", err
+	}
 
 	// Join with root and clean again
 	fullPath := filepath.Join(l.Root, cleanPath)
@@ -66,36 +216,242 @@ func (l *Local) sanitizePath(path string) (string, error) {
 	}
 
 	// Ensure the resolved path is still within the root directory
-	if !strings.HasPrefix(absPath, absRoot+string(filepath.Separator)) && absPath != absRoot {
+	if !withinRoot(absPath, absRoot) {
 		return "", errors.NewStorageError(errors.StorageErrorPathTraversal, path, "path traversal attempt detected")
 	}
 
+	// A symlink inside the root may still point outside it, so compare
+	// where the path really leads as well
+	realRoot, err := resolveSymlinks(absRoot)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve root path: %w", err)
+	}
+	realPath, err := resolveSymlinks(absPath)
+	if err != nil {
+		return "", errors.NewStorageError(errors.StorageErrorPathTraversal, path, err.Error())
+	}
+	if !withinRoot(realPath, realRoot) {
+		return "", errors.NewStorageError(errors.StorageErrorPathTraversal, path, "symlink points outside the storage root")
+	}
+
 	return fullPath, nil
 }
 
+// withinRoot reports whether absPath is absRoot or beneath it
+func withinRoot(absPath, absRoot string) bool {
+	return absPath == absRoot || strings.HasPrefix(absPath, strings.TrimSuffix(absRoot, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// maxSymlinks bounds how many symlinks resolveSymlinks follows, so a cycle
+// of links can't keep it going forever
+const maxSymlinks = 255
+
+// resolveSymlinks returns the absolute path absPath leads to once every
+// symlink in it is followed. Unlike filepath.EvalSymlinks it works for paths
+// that don't exist yet, such as a file about to be stored: the part that
+// doesn't exist is appended to wherever its deepest existing parent leads,
+// and a dangling symlink is followed to where its target would be.
+func resolveSymlinks(absPath string) (string, error) {
+	p := absPath
+	var rest []string // components below p that don't exist, outermost first
+	for links := 0; links <= maxSymlinks; {
+		if resolved, err := filepath.EvalSymlinks(p); err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...), nil
+		}
+
+		if target, err := os.Readlink(p); err == nil {
+			// p is a symlink to something that doesn't exist (yet)
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(p), target)
+			}
+			p = target
+			links++
+			continue
+		}
+
+		parent := filepath.Dir(p)
+		if parent == p {
+			return filepath.Join(append([]string{p}, rest...)...), nil
+		}
+		rest = append([]string{filepath.Base(p)}, rest...)
+		p = parent
+	}
+	return "", fmt.Errorf("too many levels of symbolic links in %s", absPath)
+}
+
 // Put stores data at the specified path within the storage root.
-// Parent directories are created automatically. Returns StorageError if the path
-// is invalid or attempts directory traversal.
+// Parent directories are created automatically. The data is written to a
+// temporary file beside the target and renamed over it, so readers and
+// concurrent writers only ever see the old file or the complete new one. A
+// replaced file keeps its permissions.
+// Returns StorageError if the path is invalid or attempts directory
+// traversal, or StorageErrorAlreadyExists if it is an existing directory.
 func (l *Local) Put(path string, data []byte) error {
 	fullPath, err := l.sanitizePath(path)
 	if err != nil {
 		return fmt.Errorf("invalid path: %w", err)
 	}
+	var oldSize int64
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(fullPath); err == nil {
+		if info.IsDir() {
+			return errors.NewStorageError(errors.StorageErrorAlreadyExists, path, "path is an existing directory")
+		}
+		oldSize = info.Size()
+		perm = info.Mode().Perm()
+	}
 	dir := filepath.Dir(fullPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
-	return os.WriteFile(fullPath, data, 0644)
+	if err := writeAtomic(fullPath, data, perm); err != nil {
+		return err
+	}
+	l.usage.add(int64(len(data)) - oldSize)
+	return nil
+}
+
+// InternalPrefix begins the names of the temporary files goflux creates
+// while writing. Paths with a component of that name are refused, so no
+// stored file can be mistaken for one.
+const InternalPrefix = ".goflux-tmp-"
+
+// IsInternal reports whether name is a temporary file of the kind goflux
+// creates while writing. Such files are never listed or walked, even if they
+// are left behind.
+func IsInternal(name string) bool {
+	return strings.HasPrefix(name, InternalPrefix)
+}
+
+// checkInternal returns StorageErrorInvalidPath if any component of the
+// cleaned path clean is reserved for internal files
+func checkInternal(p, clean string) error {
+	for _, name := range strings.FieldsFunc(clean, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if IsInternal(name) {
+			return errors.NewStorageError(errors.StorageErrorInvalidPath, p, fmt.Sprintf("names beginning with %s are reserved", InternalPrefix))
+		}
+	}
+	return nil
+}
+
+// writeAtomic writes data to an internal temporary file in fullPath's directory
+// and renames it to fullPath with permissions perm. The temporary file is
+// removed on failure.
+func writeAtomic(fullPath string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), InternalPrefix+filepath.Base(fullPath)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once renamed into place
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, perm)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmpPath, fullPath); err != nil {
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+	return nil
 }
 
 // Get retrieves data from the specified path within the storage root.
-// Returns StorageError if the path is invalid or attempts directory traversal.
+// Returns StorageError if the path is invalid or attempts directory traversal,
+// and StorageErrorNotFound if the path doesn't exist.
 func (l *Local) Get(path string) ([]byte, error) {
 	fullPath, err := l.sanitizePath(path)
 	if err != nil {
 		return nil, fmt.Errorf("invalid path: %w", err)
 	}
-	return os.ReadFile(fullPath)
+	data, err := os.ReadFile(fullPath)
+	if os.IsNotExist(err) {
+		return nil, errors.NewStorageError(errors.StorageErrorNotFound, path, "path does not exist")
+	}
+	return data, err
+}
+
+// Size returns the size of the file at the specified path.
+// Returns StorageErrorNotFound if the path doesn't exist.
+func (l *Local) Size(path string) (int64, error) {
+	fullPath, err := l.sanitizePath(path)
+	if err != nil {
+		return 0, fmt.Errorf("invalid path: %w", err)
+	}
+
+	info, err := os.Stat(fullPath)
+	if os.IsNotExist(err) {
+		return 0, errors.NewStorageError(errors.StorageErrorNotFound, path, "path does not exist")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat path: %w", err)
+	}
+	if info.IsDir() {
+		return 0, errors.NewStorageError(errors.StorageErrorInvalidPath, path, "path is a directory")
+	}
+	return info.Size(), nil
+}
+
+// GetRange reads up to length bytes starting at offset from the file at the
+// specified path, seeking rather than reading the whole file. Fewer bytes are
+// returned if the file ends first. Returns StorageErrorNotFound if the path
+// doesn't exist.
+func (l *Local) GetRange(path string, offset, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, errors.NewStorageError(errors.StorageErrorInvalidPath, path, fmt.Sprintf("invalid range: offset %d, length %d", offset, length))
+	}
+
+	fullPath, err := l.sanitizePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+
+	f, err := os.Open(fullPath)
+	if os.IsNotExist(err) {
+		return nil, errors.NewStorageError(errors.StorageErrorNotFound, path, "path does not exist")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek: %w", err)
+	}
+	return io.ReadAll(io.LimitReader(f, length))
+}
+
+// Stat returns the name, size, type and modification time of the file or
+// directory at the specified path. Returns StorageErrorNotFound if the path
+// doesn't exist.
+func (l *Local) Stat(path string) (FileInfo, error) {
+	fullPath, err := l.sanitizePath(path)
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("invalid path: %w", err)
+	}
+
+	info, err := os.Stat(fullPath)
+	if os.IsNotExist(err) {
+		return FileInfo{}, errors.NewStorageError(errors.StorageErrorNotFound, path, "path does not exist")
+	}
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("failed to stat path: %w", err)
+	}
+	return fileInfo(info), nil
+}
+
+// fileInfo converts an os.FileInfo, reporting directories with zero size
+func fileInfo(info os.FileInfo) FileInfo {
+	result := FileInfo{Name: info.Name(), IsDir: info.IsDir(), ModTime: info.ModTime()}
+	if !info.IsDir() {
+		result.Size = info.Size()
+	}
+	return result
 }
 
 // Exists checks if a file or directory exists at the specified path.
@@ -109,24 +465,94 @@ func (l *Local) Exists(path string) bool {
 	return err == nil
 }
 
-// List returns the names of all entries in the specified directory.
-// Returns StorageError if the path is invalid or the directory cannot be read.
+// IsDir reports whether the specified path is an existing directory.
+// Returns false if the path is invalid or attempts directory traversal.
+func (l *Local) IsDir(path string) bool {
+	fullPath, err := l.sanitizePath(path)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(fullPath)
+	return err == nil && info.IsDir()
+}
+
+// List returns the names of all entries in the specified directory, leaving
+// out internal temporary files. If the path names a file, a single-entry listing with the file's name is returned.
+// Returns StorageErrorNotFound if the path doesn't exist, or StorageError if it is invalid.
 func (l *Local) List(path string) ([]string, error) {
 	fullPath, err := l.sanitizePath(path)
 	if err != nil {
 		return nil, fmt.Errorf("invalid path: %w", err)
 	}
+
+	info, err := os.Stat(fullPath)
+	if os.IsNotExist(err) {
+		return nil, errors.NewStorageError(errors.StorageErrorNotFound, path, "path does not exist")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat path: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{info.Name()}, nil
+	}
+
 	entries, err := os.ReadDir(fullPath)
 	if err != nil {
 		return nil, err
 	}
 	var names []string
 	for _, e := range entries {
+		if IsInternal(e.Name()) {
+			continue
+		}
 		names = append(names, e.Name())
 	}
 	return names, nil
 }
 
+// ListDetailed returns the name, size, type and modification time of each
+// entry in the specified directory. Like List, it leaves out internal
+// temporary files, and a file path gives a
+// single-entry listing of that file. Returns StorageErrorNotFound if the path
+// doesn't exist.
+func (l *Local) ListDetailed(path string) ([]FileInfo, error) {
+	fullPath, err := l.sanitizePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+
+	info, err := os.Stat(fullPath)
+	if os.IsNotExist(err) {
+		return nil, errors.NewStorageError(errors.StorageErrorNotFound, path, "path does not exist")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat path: %w", err)
+	}
+	if !info.IsDir() {
+		return []FileInfo{fileInfo(info)}, nil
+	}
+
+	entries, err := os.ReadDir(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]FileInfo, 0, len(entries))
+	for _, e := range entries {
+		if IsInternal(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if os.IsNotExist(err) {
+			continue // removed since the directory was read
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", e.Name(), err)
+		}
+		result = append(result, fileInfo(info))
+	}
+	return result, nil
+}
+
 // Delete removes a file or directory at the specified path.
 // Directories are removed recursively. Returns StorageErrorNotFound if the path doesn't exist.
 func (l *Local) Delete(path string) error {
@@ -146,9 +572,94 @@ func (l *Local) Delete(path string) error {
 
 	// Remove file or directory (recursively)
 	if info.IsDir() {
-		return os.RemoveAll(fullPath)
+		size, _ := dirSize(fullPath)
+		if err := os.RemoveAll(fullPath); err != nil {
+			return err
+		}
+		l.usage.add(-size)
+		return nil
+	}
+	if err := os.Remove(fullPath); err != nil {
+		return err
+	}
+	l.usage.add(-info.Size())
+	return nil
+}
+
+// Rename moves the file or directory at oldPath to newPath with a single
+// rename, creating newPath's parent directories first. If the two are on
+// different filesystems mounted under the root, oldPath is copied and then
+// deleted instead, which is not atomic. Returns
+// StorageErrorNotFound if oldPath doesn't exist and StorageErrorAlreadyExists
+// if newPath does.
+func (l *Local) Rename(oldPath, newPath string) error {
+	fullOld, err := l.sanitizePath(oldPath)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	fullNew, err := l.sanitizePath(newPath)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
 	}
-	return os.Remove(fullPath)
+
+	if _, err := os.Stat(fullOld); os.IsNotExist(err) {
+		return errors.NewStorageError(errors.StorageErrorNotFound, oldPath, "path does not exist")
+	}
+	// os.Rename would silently replace an existing file or empty directory
+	if _, err := os.Lstat(fullNew); err == nil {
+		return errors.NewStorageError(errors.StorageErrorAlreadyExists, newPath, "path already exists")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fullNew), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+	if err := renameFile(fullOld, fullNew); err != nil {
+		// Part of the root may be another filesystem mounted inside it
+		if !stderrors.Is(err, syscall.EXDEV) {
+			return errors.NewStorageErrorWithCause(errors.StorageErrorIO, oldPath, "rename failed", err)
+		}
+		if err := copyTree(fullOld, fullNew); err != nil {
+			os.RemoveAll(fullNew)
+			return errors.NewStorageErrorWithCause(errors.StorageErrorIO, oldPath, "copy across filesystems failed", err)
+		}
+		if err := os.RemoveAll(fullOld); err != nil {
+			return errors.NewStorageErrorWithCause(errors.StorageErrorIO, oldPath, "copied but failed to remove the original", err)
+		}
+	}
+	return nil
+}
+
+// renameFile is os.Rename, replaceable so tests can simulate a move across
+// filesystems
+var renameFile = os.Rename
+
+// copyTree copies the file or directory tree src to dst, which must not
+// exist, keeping file permissions. Files are written with writeAtomic, so
+// none is left half-written.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return writeAtomic(target, data, info.Mode().Perm())
+	})
 }
 
 // Mkdir creates a directory at the specified path, including any necessary parent directories.
@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
+	"syscall"
 	"testing"
 
 	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
@@ -27,6 +30,39 @@ func TestNewLocal(t *testing.T) {
 	}
 }
 
+func TestCheckWritable(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := CheckWritable(tmpDir); err != nil {
+		t.Errorf("expected an existing directory to pass, got %v", err)
+	}
+
+	missing := filepath.Join(tmpDir, "a", "b")
+	if err := CheckWritable(missing); err != nil {
+		t.Errorf("expected a directory that can be created to pass, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "a")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be created, got %v", err)
+	}
+
+	blocker := filepath.Join(tmpDir, "file")
+	os.WriteFile(blocker, []byte("x"), 0644)
+	if err := CheckWritable(blocker); err == nil {
+		t.Error("expected a file to fail")
+	}
+	if err := CheckWritable(filepath.Join(blocker, "data")); err == nil {
+		t.Error("expected a directory beneath a file to fail")
+	}
+
+	// Permissions don't stop root
+	if os.Geteuid() > 0 {
+		readOnly := filepath.Join(tmpDir, "ro")
+		os.Mkdir(readOnly, 0555)
+		if err := CheckWritable(readOnly); err == nil {
+			t.Error("expected a read-only directory to fail")
+		}
+	}
+}
+
 func TestLocal_Put(t *testing.T) {
 	tmpDir := t.TempDir()
 	local, _ := NewLocal(tmpDir)
@@ -49,6 +85,120 @@ func TestLocal_Put(t *testing.T) {
 	}
 }
 
+func TestLocal_Put_Replace(t *testing.T) {
+	tmpDir := t.TempDir()
+	local, _ := NewLocal(tmpDir)
+
+	if err := local.Put("docs/test.txt", []byte("first version")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := local.Put("docs/test.txt", []byte("second")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	data, err := local.Get("docs/test.txt")
+	if err != nil || string(data) != "second" {
+		t.Errorf("expected second, got %q (%v)", data, err)
+	}
+
+	// The temporary file the data was written to is renamed into place
+	entries, _ := os.ReadDir(filepath.Join(tmpDir, "docs"))
+	if len(entries) != 1 {
+		t.Errorf("expected only test.txt in docs, got %v", entries)
+	}
+}
+
+func TestLocal_Put_ConcurrentReaders(t *testing.T) {
+	tmpDir := t.TempDir()
+	local, _ := NewLocal(tmpDir)
+
+	old := bytes.Repeat([]byte("a"), 4<<20)
+	replacement := bytes.Repeat([]byte("b"), 6<<20)
+	if err := local.Put("big.bin", old); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	// Readers racing the replacement see one version or the other, never a mix
+	done := make(chan struct{})
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			data, err := local.Get("big.bin")
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !bytes.Equal(data, old) && !bytes.Equal(data, replacement) {
+				errs <- fmt.Errorf("read %d bytes of partial content", len(data))
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 5; i++ {
+		data := replacement
+		if i%2 == 1 {
+			data = old
+		}
+		if err := local.Put("big.bin", data); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+	close(done)
+	if err := <-errs; err != nil {
+		t.Error(err)
+	}
+
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 1 {
+		t.Errorf("expected only big.bin left, got %v", entries)
+	}
+}
+
+func TestLocal_Put_KeepsPermissions(t *testing.T) {
+	tmpDir := t.TempDir()
+	local, _ := NewLocal(tmpDir)
+
+	local.Put("script.sh", []byte("#!/bin/sh"))
+	if info, _ := os.Stat(filepath.Join(tmpDir, "script.sh")); info.Mode().Perm() != 0644 {
+		t.Errorf("expected a new file to be 0644, got %v", info.Mode().Perm())
+	}
+
+	os.Chmod(filepath.Join(tmpDir, "script.sh"), 0750)
+	if err := local.Put("script.sh", []byte("#!/bin/sh\necho hi")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if info, _ := os.Stat(filepath.Join(tmpDir, "script.sh")); info.Mode().Perm() != 0750 {
+		t.Errorf("expected the replaced file to keep 0750, got %v", info.Mode().Perm())
+	}
+}
+
+func TestWriteAtomic_CleansUpOnError(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "target")
+	os.MkdirAll(filepath.Join(target, "keep"), 0755)
+
+	// A file can't be renamed over a non-empty directory, so the write fails
+	// after the temporary file is written
+	if err := writeAtomic(target, []byte("data"), 0644); err == nil {
+		t.Fatal("expected an error renaming over a directory")
+	}
+
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 1 || entries[0].Name() != "target" {
+		t.Errorf("expected the temporary file removed, got %v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(target, "keep")); err != nil {
+		t.Errorf("expected the existing directory untouched: %v", err)
+	}
+}
+
 func TestLocal_Put_WithSubdirectory(t *testing.T) {
 	tmpDir := t.TempDir()
 	local, _ := NewLocal(tmpDir)
@@ -71,6 +221,28 @@ func TestLocal_Put_WithSubdirectory(t *testing.T) {
 	}
 }
 
+func TestLocal_Put_ExistingDirectory(t *testing.T) {
+	tmpDir := t.TempDir()
+	local, _ := NewLocal(tmpDir)
+	local.Mkdir("docs")
+
+	if !local.IsDir("docs") {
+		t.Fatal("expected docs to be a directory")
+	}
+
+	err := local.Put("docs", []byte("data"))
+	if errType, ok := errors.GetStorageErrorType(err); !ok || errType != errors.StorageErrorAlreadyExists {
+		t.Fatalf("expected StorageErrorAlreadyExists, got %v", err)
+	}
+
+	if err := local.Put("docs/file.txt", []byte("data")); err != nil {
+		t.Fatalf("Put into directory failed: %v", err)
+	}
+	if local.IsDir("docs/file.txt") || local.IsDir("missing") {
+		t.Error("expected files and missing paths not to be directories")
+	}
+}
+
 func TestLocal_Get(t *testing.T) {
 	tmpDir := t.TempDir()
 	local, _ := NewLocal(tmpDir)
@@ -152,6 +324,44 @@ func TestLocal_List(t *testing.T) {
 	}
 }
 
+func TestLocal_List_SkipsInternalFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	local, _ := NewLocal(tmpDir)
+
+	local.Put("docs/a.txt", []byte("a"))
+	local.Put("docs/.env", []byte("secret"))
+	local.Put("docs/.notes.tmp", []byte("mine"))
+	// A temporary file left behind by an interrupted write
+	os.WriteFile(filepath.Join(tmpDir, "docs", InternalPrefix+"a.txt.123"), []byte("partial"), 0644)
+
+	names, err := local.List("docs")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(names) != 3 || names[0] != ".env" || names[1] != ".notes.tmp" || names[2] != "a.txt" {
+		t.Errorf("expected [.env .notes.tmp a.txt], got %v", names)
+	}
+
+	infos, err := local.ListDetailed("docs")
+	if err != nil {
+		t.Fatalf("ListDetailed failed: %v", err)
+	}
+	if len(infos) != 3 {
+		t.Errorf("expected 3 detailed entries, got %+v", infos)
+	}
+
+	var walked []string
+	if err := local.Walk("", func(path string) error {
+		walked = append(walked, path)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	if len(walked) != 3 || walked[0] != "docs/.env" || walked[2] != "docs/a.txt" {
+		t.Errorf("expected Walk to skip temporary files, got %v", walked)
+	}
+}
+
 func TestLocal_List_Subdirectory(t *testing.T) {
 	tmpDir := t.TempDir()
 	local, _ := NewLocal(tmpDir)
@@ -297,6 +507,77 @@ func TestLocal_PathTraversal(t *testing.T) {
 	}
 }
 
+// symlinkOrSkip creates a symlink, skipping the test where that isn't allowed
+func symlinkOrSkip(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+}
+
+func TestLocal_SymlinkEscape(t *testing.T) {
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "passwd")
+	os.WriteFile(secret, []byte("secret"), 0644)
+
+	root := t.TempDir()
+	local, _ := NewLocal(root)
+	symlinkOrSkip(t, secret, filepath.Join(root, "passwd"))
+	symlinkOrSkip(t, outside, filepath.Join(root, "etc"))
+	symlinkOrSkip(t, filepath.Join(outside, "new.txt"), filepath.Join(root, "dangling"))
+
+	isTraversal := func(err error) bool {
+		errType, ok := errors.GetStorageErrorType(err)
+		return ok && errType == errors.StorageErrorPathTraversal
+	}
+
+	for _, p := range []string{"passwd", "etc/passwd", "etc/missing.txt", "dangling"} {
+		if _, err := local.Get(p); !isTraversal(err) {
+			t.Errorf("Get(%q): expected StorageErrorPathTraversal, got %v", p, err)
+		}
+		if err := local.Put(p, []byte("malicious")); !isTraversal(err) {
+			t.Errorf("Put(%q): expected StorageErrorPathTraversal, got %v", p, err)
+		}
+		if err := local.Delete(p); !isTraversal(err) {
+			t.Errorf("Delete(%q): expected StorageErrorPathTraversal, got %v", p, err)
+		}
+	}
+	if err := local.Put("etc/sub/new.txt", []byte("malicious")); !isTraversal(err) {
+		t.Errorf("expected a new file beneath an escaping symlink refused, got %v", err)
+	}
+
+	if data, err := os.ReadFile(secret); err != nil || string(data) != "secret" {
+		t.Errorf("expected the file outside the root untouched, got %q (%v)", data, err)
+	}
+	for _, name := range []string{"new.txt", "missing.txt", "sub"} {
+		if _, err := os.Stat(filepath.Join(outside, name)); !os.IsNotExist(err) {
+			t.Errorf("expected nothing created outside the root, found %s", name)
+		}
+	}
+}
+
+func TestLocal_SymlinkWithinRoot(t *testing.T) {
+	root := t.TempDir()
+	local, _ := NewLocal(root)
+	local.Put("real/a.txt", []byte("a"))
+	symlinkOrSkip(t, filepath.Join(root, "real"), filepath.Join(root, "alias"))
+
+	if data, err := local.Get("alias/a.txt"); err != nil || string(data) != "a" {
+		t.Errorf("expected a symlink staying inside the root to work, got %q (%v)", data, err)
+	}
+	if err := local.Put("alias/new/b.txt", []byte("b")); err != nil {
+		t.Errorf("expected Put through a symlink inside the root to work: %v", err)
+	}
+
+	// The root itself may be reached through a symlink
+	linkedRoot := filepath.Join(t.TempDir(), "root")
+	symlinkOrSkip(t, root, linkedRoot)
+	linked, _ := NewLocal(linkedRoot)
+	if data, err := linked.Get("real/new/b.txt"); err != nil || string(data) != "b" {
+		t.Errorf("expected a symlinked root to work, got %q (%v)", data, err)
+	}
+}
+
 func TestLocal_AbsolutePath(t *testing.T) {
 	tmpDir := t.TempDir()
 	local, _ := NewLocal(tmpDir)
@@ -327,3 +608,202 @@ func TestLocal_AbsolutePath(t *testing.T) {
 		t.Errorf("expected 'data', got %s", data)
 	}
 }
+
+func TestLocal_List_File(t *testing.T) {
+	tmpDir := t.TempDir()
+	local, _ := NewLocal(tmpDir)
+
+	local.Put("docs/report.pdf", []byte("pdf"))
+
+	names, err := local.List("docs/report.pdf")
+	if err != nil {
+		t.Fatalf("List on a file should not fail: %v", err)
+	}
+
+	if len(names) != 1 || names[0] != "report.pdf" {
+		t.Errorf("expected single entry report.pdf, got %v", names)
+	}
+}
+
+func TestLocal_List_NonExistent(t *testing.T) {
+	tmpDir := t.TempDir()
+	local, _ := NewLocal(tmpDir)
+
+	_, err := local.List("missing")
+	if err == nil {
+		t.Fatal("expected error for non-existent path")
+	}
+	if errType, ok := errors.GetStorageErrorType(err); !ok || errType != errors.StorageErrorNotFound {
+		t.Errorf("expected StorageErrorNotFound, got %v", err)
+	}
+}
+
+func TestLocal_Stat(t *testing.T) {
+	local, _ := NewLocal(t.TempDir())
+	local.Put("docs/report.pdf", []byte("pdf data"))
+
+	info, err := local.Stat("docs/report.pdf")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Name != "report.pdf" || info.Size != 8 || info.IsDir || info.ModTime.IsZero() {
+		t.Errorf("unexpected file info: %+v", info)
+	}
+
+	info, err = local.Stat("docs")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Name != "docs" || info.Size != 0 || !info.IsDir {
+		t.Errorf("unexpected directory info: %+v", info)
+	}
+
+	_, err = local.Stat("missing")
+	if errType, ok := errors.GetStorageErrorType(err); !ok || errType != errors.StorageErrorNotFound {
+		t.Errorf("expected StorageErrorNotFound, got %v", err)
+	}
+}
+
+func TestLocal_ListDetailed(t *testing.T) {
+	local, _ := NewLocal(t.TempDir())
+	local.Put("docs/report.pdf", []byte("pdf data"))
+	local.Put("docs/notes/todo.txt", []byte("todo"))
+
+	entries, err := local.ListDetailed("docs")
+	if err != nil {
+		t.Fatalf("ListDetailed failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	if e := entries[0]; e.Name != "notes" || !e.IsDir || e.Size != 0 {
+		t.Errorf("expected notes directory, got %+v", e)
+	}
+	if e := entries[1]; e.Name != "report.pdf" || e.IsDir || e.Size != 8 || e.ModTime.IsZero() {
+		t.Errorf("expected 8-byte report.pdf, got %+v", e)
+	}
+
+	entries, err = local.ListDetailed("docs/report.pdf")
+	if err != nil {
+		t.Fatalf("ListDetailed of file failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "report.pdf" || entries[0].Size != 8 {
+		t.Errorf("expected single entry for file, got %+v", entries)
+	}
+
+	_, err = local.ListDetailed("missing")
+	if errType, ok := errors.GetStorageErrorType(err); !ok || errType != errors.StorageErrorNotFound {
+		t.Errorf("expected StorageErrorNotFound, got %v", err)
+	}
+}
+
+func TestLocal_Rename(t *testing.T) {
+	local, _ := NewLocal(t.TempDir())
+	local.Put("staged/a.txt", []byte("a"))
+	local.Put("staged/sub/b.txt", []byte("b"))
+	local.Put("live/keep.txt", []byte("keep"))
+
+	if err := local.Rename("staged", "site/v2"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if data, err := local.Get("site/v2/sub/b.txt"); err != nil || string(data) != "b" {
+		t.Errorf("expected moved file, got %q (%v)", data, err)
+	}
+	if local.Exists("staged") {
+		t.Error("expected source to be gone")
+	}
+
+	local.Put("other/a.txt", []byte("a"))
+	err := local.Rename("other", "live")
+	if errType, ok := errors.GetStorageErrorType(err); !ok || errType != errors.StorageErrorAlreadyExists {
+		t.Errorf("expected StorageErrorAlreadyExists, got %v", err)
+	}
+	if !local.Exists("live/keep.txt") {
+		t.Error("expected existing target to be untouched")
+	}
+
+	err = local.Rename("missing", "elsewhere")
+	if errType, ok := errors.GetStorageErrorType(err); !ok || errType != errors.StorageErrorNotFound {
+		t.Errorf("expected StorageErrorNotFound, got %v", err)
+	}
+}
+
+func TestLocal_Rename_AcrossFilesystems(t *testing.T) {
+	tmpDir := t.TempDir()
+	local, _ := NewLocal(tmpDir)
+	local.Put("old/a.txt", []byte("a"))
+	local.Put("old/sub/b.txt", []byte("b"))
+	os.Chmod(filepath.Join(tmpDir, "old", "a.txt"), 0600)
+
+	renameFile = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	defer func() { renameFile = os.Rename }()
+
+	if err := local.Rename("old", "mnt/new"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	for p, want := range map[string]string{"mnt/new/a.txt": "a", "mnt/new/sub/b.txt": "b"} {
+		if data, err := local.Get(p); err != nil || string(data) != want {
+			t.Errorf("%s: expected %q, got %q (%v)", p, want, data, err)
+		}
+	}
+	if info, _ := os.Stat(filepath.Join(tmpDir, "mnt", "new", "a.txt")); info.Mode().Perm() != 0600 {
+		t.Errorf("expected the copy to keep 0600, got %v", info.Mode().Perm())
+	}
+	if local.Exists("old") {
+		t.Error("expected the original removed after copying")
+	}
+}
+
+func TestLocal_GetRange(t *testing.T) {
+	tmpDir := t.TempDir()
+	local, _ := NewLocal(tmpDir)
+	local.Put("range.txt", []byte("0123456789"))
+
+	tests := []struct {
+		name   string
+		offset int64
+		length int64
+		want   string
+	}{
+		{name: "middle", offset: 2, length: 3, want: "234"},
+		{name: "to end", offset: 7, length: 3, want: "789"},
+		{name: "past end is truncated", offset: 8, length: 100, want: "89"},
+		{name: "offset at end", offset: 10, length: 5, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := local.GetRange("range.txt", tt.offset, tt.length)
+			if err != nil {
+				t.Fatalf("GetRange failed: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, data)
+			}
+		})
+	}
+
+	size, err := local.Size("range.txt")
+	if err != nil || size != 10 {
+		t.Errorf("expected size 10, got %d (%v)", size, err)
+	}
+}
+
+func TestLocal_GetRange_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	local, _ := NewLocal(tmpDir)
+	local.Put("range.txt", []byte("0123456789"))
+
+	_, err := local.GetRange("missing.txt", 0, 5)
+	if errType, ok := errors.GetStorageErrorType(err); !ok || errType != errors.StorageErrorNotFound {
+		t.Errorf("expected StorageErrorNotFound for missing file, got %v", err)
+	}
+	if _, err := local.GetRange("range.txt", -1, 5); err == nil {
+		t.Error("expected error for negative offset")
+	}
+	if _, err := local.Size("missing.txt"); err == nil {
+		t.Error("expected error sizing missing file")
+	}
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sync"
+)
+
+// UsageReporter is implemented by backends that can report how many bytes
+// their stored files take up.
+type UsageReporter interface {
+	// Usage returns the total size of every stored file in bytes.
+	Usage() (int64, error)
+}
+
+// usageCache holds a backend's total file size once it has been measured, so
+// later changes can adjust it instead of measuring again
+type usageCache struct {
+	mu    sync.Mutex
+	bytes int64
+	known bool
+}
+
+// add adjusts the cached total by delta bytes, if it has been measured
+func (c *usageCache) add(delta int64) {
+	c.mu.Lock()
+	if c.known {
+		c.bytes += delta
+	}
+	c.mu.Unlock()
+}
+
+// dirSize returns the total size of the regular files under dir, leaving out
+// internal temporary files
+func dirSize(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || IsInternal(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if stderrors.Is(err, fs.ErrNotExist) {
+			return nil // removed since the directory was read
+		}
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	return total, err
+}
+
+// Usage returns the total size of the files under Root. The tree is walked on
+// the first call only; Put and Delete keep the total up to date after that,
+// so files changed behind the server's back aren't reflected until restart.
+func (l *Local) Usage() (int64, error) {
+	l.usage.mu.Lock()
+	defer l.usage.mu.Unlock()
+	if !l.usage.known {
+		total, err := dirSize(l.Root)
+		if err != nil {
+			return 0, fmt.Errorf("failed to measure storage usage: %w", err)
+		}
+		l.usage.bytes, l.usage.known = total, true
+	}
+	return l.usage.bytes, nil
+}
+
+// backendUsage returns the usage of backend. Returns an error wrapping
+// errors.ErrUnsupported if the backend can't report it.
+func backendUsage(backend Storage) (int64, error) {
+	reporter, ok := backend.(UsageReporter)
+	if !ok {
+		return 0, fmt.Errorf("storage backend cannot report usage: %w", stderrors.ErrUnsupported)
+	}
+	return reporter.Usage()
+}
+
+// Usage passes through to the backend, untimed since it is usually cached
+func (m *Instrumented) Usage() (int64, error) {
+	return backendUsage(m.backend)
+}
+
+// Usage passes through to the backend, so it counts the compressed size that
+// files actually take up
+func (c *Compressed) Usage() (int64, error) {
+	return backendUsage(c.backend)
+}
+
+// Usage returns the total usage of every backend
+func (r *Router) Usage() (int64, error) {
+	var total int64
+	for _, backend := range r.backends() {
+		usage, err := backendUsage(backend)
+		if err != nil {
+			return 0, err
+		}
+		total += usage
+	}
+	return total, nil
+}
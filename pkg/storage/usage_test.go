@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocal_Usage(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "docs"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("12345"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "docs", "b.txt"), []byte("123"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "docs", InternalPrefix+"b.txt.123"), []byte("leftover"), 0644)
+	local, _ := NewLocal(tmpDir)
+
+	check := func(step string, want int64) {
+		t.Helper()
+		got, err := local.Usage()
+		if err != nil {
+			t.Fatalf("%s: Usage failed: %v", step, err)
+		}
+		if got != want {
+			t.Errorf("%s: expected %d bytes used, got %d", step, want, got)
+		}
+	}
+
+	check("initial walk", 8)
+
+	local.Put("docs/c.txt", []byte("1234567890"))
+	check("after adding a file", 18)
+
+	local.Put("a.txt", []byte("1"))
+	check("after replacing a file", 14)
+
+	local.Delete("a.txt")
+	check("after deleting a file", 13)
+
+	local.Delete("docs")
+	check("after deleting a directory", 0)
+}
+
+func TestRouter_Usage(t *testing.T) {
+	fallback, _ := NewLocal(t.TempDir())
+	images, _ := NewLocal(t.TempDir())
+	router := NewRouter(NewCompressed(fallback, DefaultBlockSize), Route{Pattern: "*.jpg", Backend: images})
+
+	router.Put("photo.jpg", []byte("jpegdata"))
+	usage, err := NewInstrumented(router, 0).Usage()
+	if err != nil {
+		t.Fatalf("Usage failed: %v", err)
+	}
+	if usage != 8 {
+		t.Errorf("expected 8 bytes used, got %d", usage)
+	}
+}
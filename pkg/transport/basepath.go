@@ -0,0 +1,34 @@
+package transport
+
+import (
+	"path"
+	"strings"
+)
+
+// SetBasePath makes relative remote paths refer to paths beneath base, so a
+// user working in one subtree of a shared server can leave it out. Remote
+// paths starting with "/" are absolute and used as given.
+func (h *HTTPClient) SetBasePath(base string) {
+	h.basePath = strings.Trim(path.Clean("/"+strings.ReplaceAll(base, "\\", "/")), "/")
+}
+
+// BasePath returns the prefix of relative remote paths, or "" if they are
+// relative to the storage root.
+func (h *HTTPClient) BasePath() string {
+	return h.basePath
+}
+
+// ResolvePath returns the server path that remotePath refers to: relative
+// paths are joined to the base path and absolute ones are returned unchanged.
+// A trailing "/" marking a directory is kept.
+func (h *HTTPClient) ResolvePath(remotePath string) string {
+	if h.basePath == "" || strings.HasPrefix(remotePath, "/") {
+		return remotePath
+	}
+
+	resolved := path.Join(h.basePath, remotePath)
+	if strings.HasSuffix(remotePath, "/") {
+		resolved += "/"
+	}
+	return resolved
+}
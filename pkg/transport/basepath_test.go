@@ -0,0 +1,89 @@
+package transport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestResolvePath(t *testing.T) {
+	client := NewHTTPClient("http://example.com")
+	if got := client.ResolvePath("docs/a.txt"); got != "docs/a.txt" {
+		t.Errorf("expected paths unchanged without a base path, got %q", got)
+	}
+
+	client.SetBasePath("/projects/me/")
+	tests := []struct {
+		remote string
+		want   string
+	}{
+		{"docs/x.txt", "projects/me/docs/x.txt"},
+		{"docs/", "projects/me/docs/"},
+		{"", "projects/me"},
+		{"./notes.txt", "projects/me/notes.txt"},
+		{"/shared/x.txt", "/shared/x.txt"},
+		{"/", "/"},
+	}
+	for _, tt := range tests {
+		if got := client.ResolvePath(tt.remote); got != tt.want {
+			t.Errorf("ResolvePath(%q) = %q, want %q", tt.remote, got, tt.want)
+		}
+	}
+	if client.BasePath() != "projects/me" {
+		t.Errorf("expected cleaned base path, got %q", client.BasePath())
+	}
+}
+
+func TestBasePath_AppliedToRequests(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Query().Get("path")
+		if r.URL.Path == "/upload" {
+			var chunk ChunkData
+			json.NewDecoder(r.Body).Decode(&chunk)
+			path = chunk.Path
+		}
+		mu.Lock()
+		paths = append(paths, r.URL.Path+" "+path)
+		mu.Unlock()
+
+		if r.URL.Path == "/list" {
+			w.Write([]byte("[]"))
+		}
+	}))
+	defer ts.Close()
+
+	client := NewHTTPClient(ts.URL)
+	client.SetBasePath("projects/me")
+
+	if err := client.UploadChunk(ChunkData{Path: "docs/x.txt", Total: 1}); err != nil {
+		t.Fatalf("UploadChunk failed: %v", err)
+	}
+	if _, err := client.List("docs"); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if _, err := client.List("/shared"); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if err := client.Delete("old.txt"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	want := []string{
+		"/upload projects/me/docs/x.txt",
+		"/list projects/me/docs",
+		"/list /shared",
+		"/delete projects/me/old.txt",
+	}
+	if len(paths) != len(want) {
+		t.Fatalf("expected requests %v, got %v", want, paths)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("request %d: expected %q, got %q", i, want[i], paths[i])
+		}
+	}
+}
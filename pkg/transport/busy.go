@@ -0,0 +1,106 @@
+package transport
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxBusyWait caps the total time spent waiting on one request for
+	// a busy server to accept it
+	DefaultMaxBusyWait = 2 * time.Minute
+	// minBusyDelay is the shortest wait between busy retries, so a Retry-After
+	// of zero doesn't turn into a tight loop
+	minBusyDelay = 100 * time.Millisecond
+)
+
+// ServerBusyError is wrapped by errors for requests the server turned away
+// with 503 Service Unavailable or 429 Too Many Requests and a Retry-After
+// header, asking the client to try again later
+type ServerBusyError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ServerBusyError) Error() string {
+	return fmt.Sprintf("server busy, retry after %v", e.RetryAfter)
+}
+
+// SetMaxBusyWait sets the longest a request will keep waiting, in total, for
+// a busy server that sends Retry-After. Zero gives up on the first busy answer.
+func (h *HTTPClient) SetMaxBusyWait(wait time.Duration) {
+	h.maxBusyWait = wait
+}
+
+// waitWhileBusy calls op until it succeeds or fails for a reason other than
+// the server being busy, pausing as long as each busy answer asks. It gives
+// up when the next pause would take the total beyond the configured maximum
+// or ctx is done, returning the last error.
+func (h *HTTPClient) waitWhileBusy(ctx context.Context, op func() error) error {
+	var waited time.Duration
+	for {
+		err := op()
+		var busy *ServerBusyError
+		if err == nil || !stderrors.As(err, &busy) || ctx.Err() != nil {
+			return err
+		}
+
+		delay := busy.RetryAfter
+		if delay < minBusyDelay {
+			delay = minBusyDelay
+		}
+		if waited+delay > h.maxBusyWait {
+			return err
+		}
+
+		fmt.Printf("Server busy, retrying in %v...\n", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		waited += delay
+	}
+}
+
+// busyError returns a ServerBusyError if resp is a 503 or 429 carrying a
+// valid Retry-After header, or nil otherwise
+func busyError(resp *http.Response) *ServerBusyError {
+	if resp.StatusCode != http.StatusServiceUnavailable && resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+	delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if !ok {
+		return nil
+	}
+	return &ServerBusyError{RetryAfter: delay}
+}
+
+// parseRetryAfter parses a Retry-After value, which is either a number of
+// seconds or an HTTP date, into a wait relative to now
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	when, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := when.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
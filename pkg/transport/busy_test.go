@@ -0,0 +1,134 @@
+package transport
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newBusyServer returns a client for a server that answers the first busy
+// requests with status and the given Retry-After, then serves content. The
+// returned function reports how many requests arrived.
+func newBusyServer(t *testing.T, busy, status int, retryAfter string, content []byte) (*HTTPClient, func() int) {
+	t.Helper()
+	var mu sync.Mutex
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+
+		if n <= busy {
+			w.Header().Set("Retry-After", retryAfter)
+			http.Error(w, "server at capacity", status)
+			return
+		}
+		w.Write(content)
+	}))
+	t.Cleanup(ts.Close)
+
+	return NewHTTPClient(ts.URL), func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func TestUploadChunk_WaitsForBusyServer(t *testing.T) {
+	client, requests := newBusyServer(t, 1, http.StatusServiceUnavailable, "1", nil)
+	client.SetUploadRetries(0) // busy waits don't use up retries
+
+	start := time.Now()
+	if err := client.UploadChunk(ChunkData{Path: "a.bin", Data: []byte("x"), Total: 1}); err != nil {
+		t.Fatalf("expected upload to succeed once the server had capacity, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected the client to wait for Retry-After, took %v", elapsed)
+	}
+	if n := requests(); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestDownload_WaitsForBusyServer(t *testing.T) {
+	client, requests := newBusyServer(t, 2, http.StatusTooManyRequests, "0", []byte("hello"))
+
+	data, err := client.Download("a.txt")
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("expected download to succeed, got %q (%v)", data, err)
+	}
+	if n := requests(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+
+	client, _ = newBusyServer(t, 1, http.StatusServiceUnavailable, "0", []byte("resumed"))
+	local := filepath.Join(t.TempDir(), "a.txt")
+	if err := client.DownloadResume("a.txt", local); err != nil {
+		t.Fatalf("expected resumable download to succeed, got %v", err)
+	}
+	if data, _ := os.ReadFile(local); string(data) != "resumed" {
+		t.Errorf("expected downloaded content, got %q", data)
+	}
+}
+
+func TestWaitWhileBusy_GivesUpAtLimit(t *testing.T) {
+	client, requests := newBusyServer(t, 100, http.StatusServiceUnavailable, "0", nil)
+	client.SetMaxBusyWait(250 * time.Millisecond)
+
+	err := client.UploadChunk(ChunkData{Path: "a.bin", Data: []byte("x"), Total: 1})
+	var busy *ServerBusyError
+	if !stderrors.As(err, &busy) {
+		t.Fatalf("expected ServerBusyError, got %v", err)
+	}
+	// Two waits of minBusyDelay fit within the limit; upload retries aren't used
+	if n := requests(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestWaitWhileBusy_StopsWhenCancelled(t *testing.T) {
+	client, requests := newBusyServer(t, 100, http.StatusServiceUnavailable, "30", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := client.DownloadContext(ctx, "a.txt"); err == nil {
+		t.Fatal("expected error when cancelled while waiting")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected cancellation to end the wait, took %v", elapsed)
+	}
+	if n := requests(); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"5", 5 * time.Second, true},
+		{" 0 ", 0, true},
+		{"Wed, 01 May 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Wed, 01 May 2024 11:00:00 GMT", 0, true}, // already passed
+		{"", 0, false},
+		{"-1", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
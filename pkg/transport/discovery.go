@@ -1,18 +1,28 @@
 package transport
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/mdns"
 )
 
 // DiscoveredServer represents a server found on the network
 type DiscoveredServer struct {
 	Name        string `json:"name"`
+	InstanceID  string `json:"instance_id"`
+	Scheme      string `json:"scheme"`
 	Version     string `json:"version"`
 	Address     string `json:"address"`
 	Port        string `json:"port"`
@@ -25,6 +35,13 @@ type DiscoveredServer struct {
 type DiscoveryClient struct {
 	discovered map[string]*DiscoveredServer
 	stopChan   chan struct{}
+	port       int           // UDP port to listen on for announcements
+	timeout    time.Duration // how long to collect announcements
+	mode       string        // DiscoveryModeBroadcast, DiscoveryModeMDNS or DiscoveryModeBoth
+	secret     string        // shared secret announcements must be signed with (empty to accept unsigned)
+	targets    []string      // subnets or hosts to probe instead of waiting for broadcasts
+
+	browse func(service string, timeout time.Duration) ([]*mdns.Entry, error) // mDNS browser
 }
 
 const (
@@ -32,6 +49,25 @@ const (
 	DiscoveryTimeout       = 5 * time.Second
 	ServerExpiry           = 60 * time.Second
 	DiscoveryMagicResponse = "GOFLUX-LITE-DISCOVERY"
+	DiscoveryProbeMagic    = "GOFLUX-LITE-PROBE"
+	DiscoveryProbeSize     = 1200  // bytes a probe is padded to, fitting the smallest IPv6 MTU
+	MaxAnnouncementSize    = 65535 // largest UDP payload, so no announcement is truncated
+	MDNSServiceType        = "_goflux._tcp"
+
+	// SignedAnnouncementSkew is how far a signed announcement's timestamp may
+	// be from the time it arrives before it's taken for a replay
+	SignedAnnouncementSkew = 30 * time.Second
+)
+
+// allNodesIPv6 is the link-local multicast group servers announce themselves
+// to over IPv6
+var allNodesIPv6 = net.ParseIP("ff02::1")
+
+// Discovery modes, choosing how DiscoverServers looks for servers
+const (
+	DiscoveryModeBroadcast = "broadcast" // listen for UDP broadcast announcements
+	DiscoveryModeMDNS      = "mdns"      // browse with mDNS, listening for broadcasts if no server answers
+	DiscoveryModeBoth      = "both"      // both at once
 )
 
 // NewDiscoveryClient creates a new discovery client
@@ -39,104 +75,546 @@ func NewDiscoveryClient() *DiscoveryClient {
 	return &DiscoveryClient{
 		discovered: make(map[string]*DiscoveredServer),
 		stopChan:   make(chan struct{}),
+		port:       ClientDiscoveryPort,
+		timeout:    DiscoveryTimeout,
+		mode:       DiscoveryModeBroadcast,
+		browse:     mdns.Browse,
 	}
 }
 
-// DiscoverServers scans the network for GoFlux servers
+// SetMode sets how servers are discovered; an empty mode means broadcast
+func (d *DiscoveryClient) SetMode(mode string) {
+	if mode == "" {
+		mode = DiscoveryModeBroadcast
+	}
+	d.mode = mode
+}
+
+// SetSecret sets the shared network secret servers sign their announcements
+// with. Once set, announcements that aren't signed with it are ignored, so
+// other machines on the network can't pose as servers.
+func (d *DiscoveryClient) SetSecret(secret string) {
+	d.secret = secret
+}
+
+// SetProbeTargets makes discovery ask servers to announce themselves rather
+// than wait for their periodic broadcasts, for networks that filter
+// broadcasts. Each target is an IPv4 subnet in CIDR form, whose broadcast
+// address is probed, or a host name or address, optionally with a port.
+func (d *DiscoveryClient) SetProbeTargets(targets []string) {
+	d.targets = targets
+}
+
+// DiscoverServers looks for GoFlux servers for the full discovery timeout and
+// returns every server heard in that window. In mDNS mode, broadcasts are
+// listened for afterwards if no server answered over mDNS.
 func (d *DiscoveryClient) DiscoverServers() ([]*DiscoveredServer, error) {
-	// Listen for UDP broadcasts
-	addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf(":%d", ClientDiscoveryPort))
-	if err != nil {
-		return nil, fmt.Errorf("failed to resolve UDP address: %w", err)
+	now := time.Now()
+
+	switch d.mode {
+	case DiscoveryModeMDNS:
+		entries, err := d.browse(MDNSServiceType, d.timeout)
+		d.recordEntries(entries, now)
+		if err != nil || len(d.discovered) == 0 {
+			if err := d.collectAnnouncements(now); err != nil {
+				return nil, err
+			}
+		}
+	case DiscoveryModeBoth:
+		// Browse while listening, and merge the results here so only this
+		// goroutine touches the discovered servers
+		browsed := make(chan []*mdns.Entry, 1)
+		go func() {
+			entries, _ := d.browse(MDNSServiceType, d.timeout)
+			browsed <- entries
+		}()
+		listenErr := d.collectAnnouncements(now)
+		d.recordEntries(<-browsed, now)
+		if listenErr != nil && len(d.discovered) == 0 {
+			return nil, listenErr
+		}
+	default:
+		if err := d.collectAnnouncements(now); err != nil {
+			return nil, err
+		}
 	}
 
-	conn, err := net.ListenUDP("udp", addr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create UDP listener: %w", err)
+	// Clean up expired entries
+	d.cleanupExpired()
+
+	// Convert to slice and sort by last seen (newest first)
+	var servers []*DiscoveredServer
+	for _, server := range d.discovered {
+		servers = append(servers, server)
 	}
-	defer conn.Close()
 
-	// Set read timeout
-	conn.SetReadDeadline(time.Now().Add(DiscoveryTimeout))
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].LastSeen.After(servers[j].LastSeen)
+	})
 
-	// Collect responses
-	buffer := make([]byte, 1024)
-	now := time.Now()
+	return servers, nil
+}
 
-	for {
-		n, remoteAddr, err := conn.ReadFromUDP(buffer)
+// collectAnnouncements records the servers whose announcements arrive within
+// the discovery timeout. Servers are asked to announce themselves straight
+// away, rather than at their next periodic broadcast: with probe targets set
+// only those are probed; otherwise every local network is, while listening for
+// broadcasts over IPv4 and multicasts to all IPv6 nodes as well.
+func (d *DiscoveryClient) collectAnnouncements(now time.Time) error {
+	if len(d.targets) > 0 {
+		targets, err := probeAddrs(d.targets, d.port)
 		if err != nil {
-			// Timeout or other error, stop collecting
-			break
+			return err
 		}
+		return d.probe(targets, nil, now)
+	}
 
-		// Parse message
-		var message map[string]interface{}
-		if err := json.Unmarshal(buffer[:n], &message); err != nil {
-			continue // Invalid JSON, skip
+	// The discovery port may be taken, say by a server on this machine;
+	// replies to probes still arrive
+	listeners, listenErr := d.listenAnnouncements()
+	if err := d.probe(localProbeAddrs(d.port), listeners, now); err != nil {
+		if listenErr != nil {
+			return listenErr
 		}
+		return err
+	}
+	return nil
+}
 
-		// Check magic string
-		magic, ok := message["magic"].(string)
-		if !ok || magic != DiscoveryMagicResponse {
-			continue // Not a GoFlux discovery message
+// probe sends a probe to each target, again after a second in case the first
+// were lost, and records the servers that answer along with what arrives on
+// listeners. Failing to probe is only an error without listeners.
+func (d *DiscoveryClient) probe(targets []*net.UDPAddr, listeners []*net.UDPConn, now time.Time) error {
+	conns := listeners
+	prober, err := newProber(targets)
+	if err == nil {
+		err = prober.send()
+	}
+	if err != nil {
+		prober.close()
+		if len(listeners) == 0 {
+			return err
 		}
+	} else {
+		conns = append(conns, prober.conns...)
+		retry := time.AfterFunc(time.Second, func() { prober.send() })
+		defer retry.Stop()
+	}
 
-		// Extract server info
-		dataInterface, ok := message["data"]
-		if !ok {
+	d.collect(conns, now)
+	return nil
+}
+
+// prober sends probes to a set of targets, from an IPv4 and an IPv6 socket
+// as the targets need
+type prober struct {
+	packet  []byte
+	targets map[*net.UDPConn][]*net.UDPAddr
+	conns   []*net.UDPConn
+}
+
+// newProber opens the sockets probes are sent from, on ephemeral ports
+func newProber(targets []*net.UDPAddr) (*prober, error) {
+	packet, err := probeMessage()
+	if err != nil {
+		return nil, err
+	}
+	p := &prober{packet: packet, targets: make(map[*net.UDPConn][]*net.UDPAddr)}
+
+	var v4, v6 []*net.UDPAddr
+	for _, target := range targets {
+		if target.IP.To4() != nil {
+			v4 = append(v4, target)
+		} else {
+			v6 = append(v6, target)
+		}
+	}
+	var listenErr error
+	for network, addrs := range map[string][]*net.UDPAddr{"udp4": v4, "udp6": v6} {
+		if len(addrs) == 0 {
+			continue
+		}
+		conn, err := net.ListenUDP(network, nil)
+		if err != nil {
+			listenErr = err
 			continue
 		}
+		p.targets[conn] = addrs
+		p.conns = append(p.conns, conn)
+	}
+	if len(p.conns) == 0 {
+		if listenErr == nil {
+			listenErr = errors.New("nothing to probe")
+		}
+		return p, fmt.Errorf("failed to create UDP socket: %w", listenErr)
+	}
+	return p, nil
+}
+
+// send sends a probe to every target, failing only if none could be sent
+func (p *prober) send() error {
+	var sendErr error
+	sent := 0
+	for conn, targets := range p.targets {
+		for _, target := range targets {
+			if _, err := conn.WriteToUDP(p.packet, target); err != nil {
+				sendErr = err
+				continue
+			}
+			sent++
+		}
+	}
+	if sent == 0 {
+		return fmt.Errorf("failed to send discovery probe: %w", sendErr)
+	}
+	return nil
+}
 
-		dataBytes, err := json.Marshal(dataInterface)
+// close closes the prober's sockets
+func (p *prober) close() {
+	if p == nil {
+		return
+	}
+	for _, conn := range p.conns {
+		conn.Close()
+	}
+}
+
+// localProbeAddrs returns where to probe for servers on the networks this
+// machine is on: this machine itself, the broadcast address of each IPv4
+// network and all IPv6 nodes on each link
+func localProbeAddrs(port int) []*net.UDPAddr {
+	addrs := []*net.UDPAddr{{IP: net.IPv4(127, 0, 0, 1), Port: port}}
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return addrs
+	}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		ifaceAddrs, err := iface.Addrs()
 		if err != nil {
 			continue
 		}
+		hasIPv6 := false
+		for _, addr := range ifaceAddrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if ipnet.IP.To4() != nil {
+				addrs = append(addrs, &net.UDPAddr{IP: subnetBroadcast(ipnet), Port: port})
+			} else {
+				hasIPv6 = true
+			}
+		}
+		if hasIPv6 && iface.Flags&net.FlagMulticast != 0 {
+			addrs = append(addrs, &net.UDPAddr{IP: allNodesIPv6, Port: port, Zone: iface.Name})
+		}
+	}
+	return addrs
+}
 
-		var serverInfo DiscoveredServer
-		if err := json.Unmarshal(dataBytes, &serverInfo); err != nil {
+// probeMessage builds the probe asking servers to announce themselves, padded
+// to DiscoveryProbeSize: servers only send replies no larger than the probe,
+// so that forged probes can't turn them into amplifiers
+func probeMessage() ([]byte, error) {
+	probe := map[string]string{"magic": DiscoveryProbeMagic, "pad": ""}
+	packet, err := json.Marshal(probe)
+	if err != nil {
+		return nil, err
+	}
+	probe["pad"] = strings.Repeat("0", DiscoveryProbeSize-len(packet))
+	return json.Marshal(probe)
+}
+
+// probeAddrs resolves probe targets to the addresses probes are sent to
+func probeAddrs(targets []string, port int) ([]*net.UDPAddr, error) {
+	var addrs []*net.UDPAddr
+	for _, target := range targets {
+		if _, ipnet, err := net.ParseCIDR(target); err == nil {
+			if ipnet.IP.To4() == nil {
+				return nil, fmt.Errorf("discovery target %s: IPv6 subnets have no broadcast address; list hosts instead", target)
+			}
+			addrs = append(addrs, &net.UDPAddr{IP: subnetBroadcast(ipnet), Port: port})
 			continue
 		}
 
-		// Set discovery time and source
-		serverInfo.LastSeen = now
+		hostPort := target
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			hostPort = net.JoinHostPort(strings.Trim(target, "[]"), strconv.Itoa(port))
+		}
+		addr, err := net.ResolveUDPAddr("udp", hostPort)
+		if err != nil {
+			return nil, fmt.Errorf("invalid discovery target %q: %w", target, err)
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// subnetBroadcast returns the broadcast address of an IPv4 subnet
+func subnetBroadcast(ipnet *net.IPNet) net.IP {
+	ip, mask := ipnet.IP.To4(), ipnet.Mask
+	if len(mask) == net.IPv6len {
+		mask = mask[12:]
+	}
+	broadcast := make(net.IP, net.IPv4len)
+	for i := range broadcast {
+		broadcast[i] = ip[i] | ^mask[i]
+	}
+	return broadcast
+}
+
+// collect records the announcements arriving on conns within the discovery
+// timeout, then closes them
+func (d *DiscoveryClient) collect(conns []*net.UDPConn, now time.Time) {
+	// Read each socket on its own goroutine and record what they hear here,
+	// so only this goroutine touches the discovered servers
+	packets := make(chan announcementPacket)
+	var wg sync.WaitGroup
+	for _, conn := range conns {
+		defer conn.Close()
+
+		// Collect until the window closes, however many servers answer early
+		conn.SetReadDeadline(time.Now().Add(d.timeout))
+
+		wg.Add(1)
+		go func(conn *net.UDPConn) {
+			defer wg.Done()
+			buffer := make([]byte, MaxAnnouncementSize)
+			for {
+				n, remoteAddr, err := conn.ReadFromUDP(buffer)
+				if err != nil {
+					// Timeout or other error, stop collecting
+					return
+				}
+				packets <- announcementPacket{
+					data:   append([]byte(nil), buffer[:n]...),
+					remote: net.IPAddr{IP: remoteAddr.IP, Zone: remoteAddr.Zone},
+				}
+			}
+		}(conn)
+	}
+	go func() {
+		wg.Wait()
+		close(packets)
+	}()
+
+	for packet := range packets {
+		d.recordAnnouncement(packet.data, packet.remote, now)
+	}
+}
+
+// announcementPacket is an announcement read by collect
+type announcementPacket struct {
+	data   []byte
+	remote net.IPAddr
+}
+
+// listenAnnouncements opens the sockets announcements arrive on: one for
+// IPv4 broadcasts and, where the system has IPv6, one joined to the IPv6
+// all-nodes group. Only failing to open either is an error.
+func (d *DiscoveryClient) listenAnnouncements() ([]*net.UDPConn, error) {
+	var conns []*net.UDPConn
 
-		// Use the actual responding IP if address seems to be localhost/internal
-		if strings.HasPrefix(serverInfo.Address, "localhost") ||
-			strings.HasPrefix(serverInfo.Address, "127.0.0.1") ||
-			strings.HasPrefix(serverInfo.Address, "0.0.0.0") {
-			serverInfo.Address = fmt.Sprintf("%s:%s", remoteAddr.IP.String(), serverInfo.Port)
+	conn4, err4 := net.ListenUDP("udp4", &net.UDPAddr{Port: d.port})
+	if err4 == nil {
+		conns = append(conns, conn4)
+	}
+	conn6, err6 := net.ListenMulticastUDP("udp6", nil, &net.UDPAddr{IP: allNodesIPv6, Port: d.port})
+	if err6 == nil {
+		conns = append(conns, conn6)
+	}
+
+	if len(conns) == 0 {
+		return nil, fmt.Errorf("failed to create UDP listener: %w", err4)
+	}
+	return conns, nil
+}
+
+// recordAnnouncement parses a discovery broadcast and stores the server it
+// describes
+func (d *DiscoveryClient) recordAnnouncement(packet []byte, remote net.IPAddr, seen time.Time) {
+	var message struct {
+		Magic     string          `json:"magic"`
+		Data      json.RawMessage `json:"data"`
+		Signature string          `json:"signature"`
+	}
+	if err := json.Unmarshal(packet, &message); err != nil {
+		return // Invalid JSON, skip
+	}
+
+	// Check magic string
+	if message.Magic != DiscoveryMagicResponse {
+		return // Not a GoFlux discovery message
+	}
+
+	if d.secret != "" && !verifyDiscovery(d.secret, message.Data, message.Signature) {
+		return // Unsigned or forged
+	}
+
+	var serverInfo DiscoveredServer
+	if err := json.Unmarshal(message.Data, &serverInfo); err != nil {
+		return
+	}
+	if d.secret != "" && !freshAnnouncement(serverInfo.Timestamp, seen) {
+		return // Replayed
+	}
+	serverInfo.LastSeen = seen
+	d.record(&serverInfo, remote)
+}
+
+// recordEntries stores the servers found by an mDNS browse
+func (d *DiscoveryClient) recordEntries(entries []*mdns.Entry, seen time.Time) {
+	for _, entry := range entries {
+		if d.secret != "" && !verifyTXT(d.secret, entry.Text) {
+			continue // Unsigned or forged
 		}
+		server := serverFromTXT(entry)
+		server.LastSeen = seen
 
-		// Store unique server (by address)
-		d.discovered[serverInfo.Address] = &serverInfo
+		remote := net.IPAddr{IP: entry.Source}
+		if len(entry.IPs) > 0 {
+			remote.IP = entry.IPs[0]
+		}
+		d.record(server, remote)
+	}
+}
 
-		// Reset timeout to continue collecting
-		conn.SetReadDeadline(time.Now().Add(time.Second))
+// freshAnnouncement reports whether an announcement stamped with timestamp,
+// in Unix seconds, was sent within SignedAnnouncementSkew of seen
+func freshAnnouncement(timestamp int64, seen time.Time) bool {
+	age := seen.Sub(time.Unix(timestamp, 0))
+	return age <= SignedAnnouncementSkew && age >= -SignedAnnouncementSkew
+}
+
+// verifyDiscovery reports whether signature is the hex HMAC-SHA256 of data
+// keyed with secret
+func verifyDiscovery(secret string, data []byte, signature string) bool {
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
 	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	return hmac.Equal(got, mac.Sum(nil))
+}
 
-	// Clean up expired entries
-	d.cleanupExpired()
+// verifyTXT reports whether an mDNS TXT record carries a sig string signing
+// its other strings, sorted and joined by newlines, with secret
+func verifyTXT(secret string, text []string) bool {
+	var signed []string
+	signature := ""
+	for _, s := range text {
+		if sig, ok := strings.CutPrefix(s, "sig="); ok {
+			signature = sig
+			continue
+		}
+		signed = append(signed, s)
+	}
+	sort.Strings(signed)
+	return signature != "" && verifyDiscovery(secret, []byte(strings.Join(signed, "\n")), signature)
+}
 
-	// Convert to slice and sort by last seen (newest first)
-	var servers []*DiscoveredServer
-	for _, server := range d.discovered {
-		servers = append(servers, server)
+// serverFromTXT decodes the server information in an mDNS entry's TXT record,
+// falling back to the entry's SRV record for the port
+func serverFromTXT(entry *mdns.Entry) *DiscoveredServer {
+	txt := mdns.DecodeTXT(entry.Text)
+	server := &DiscoveredServer{
+		Name:        txt["name"],
+		InstanceID:  txt["id"],
+		Scheme:      txt["scheme"],
+		Version:     txt["version"],
+		Address:     txt["address"],
+		Port:        txt["port"],
+		AuthEnabled: txt["auth"] == "true",
 	}
+	if server.Name == "" {
+		server.Name = entry.Instance
+	}
+	if server.Port == "" {
+		server.Port = strconv.Itoa(int(entry.Port))
+	}
+	if server.Address == "" {
+		server.Address = "0.0.0.0:" + server.Port // replaced by the host's address in record
+	}
+	return server
+}
 
-	sort.Slice(servers, func(i, j int) bool {
-		return servers[i].LastSeen.After(servers[j].LastSeen)
-	})
+// record stores a discovered server heard from remote. Servers are keyed by
+// instance ID so that one server reachable on several addresses is listed
+// once; servers without an ID are keyed by address.
+func (d *DiscoveryClient) record(serverInfo *DiscoveredServer, remote net.IPAddr) {
+	// Use the actual responding IP if address seems to be localhost/internal.
+	// Signed announcements must name their address: the sender of a replayed
+	// one could otherwise redirect clients to itself.
+	if unreachableAddress(serverInfo.Address) {
+		if d.secret != "" {
+			return
+		}
+		serverInfo.Address = net.JoinHostPort(remote.String(), serverInfo.Port)
+	}
 
-	return servers, nil
+	key := serverInfo.InstanceID
+	if key == "" {
+		key = serverInfo.Address
+	}
+	d.discovered[key] = serverInfo
+}
+
+// unreachableAddress reports whether an address a server announced can't be
+// used from another machine: a loopback or unspecified host, or none at all
+func unreachableAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	if host == "" || host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
+
+// URL returns the base URL of a discovered server
+func (s *DiscoveredServer) URL() string {
+	return ServerURL(s.Scheme, s.Address)
+}
+
+// ServerURL returns the base URL of the server at address, a host:port pair
+// as announced in discovery or typed by a user. IPv6 hosts are bracketed and
+// the % before a zone is escaped, as URLs require. An empty scheme means
+// http.
+func ServerURL(scheme, address string) string {
+	if scheme == "" {
+		scheme = "http"
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		// No port, or an IPv6 literal without brackets
+		host, port = strings.TrimSuffix(strings.TrimPrefix(address, "["), "]"), ""
+	}
+	if strings.Contains(host, ":") && strings.Contains(host, "%") && !strings.Contains(host, "%25") {
+		host = strings.Replace(host, "%", "%25", 1)
+	}
+	if port == "" {
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		return scheme + "://" + host
+	}
+	return scheme + "://" + net.JoinHostPort(host, port)
 }
 
 // cleanupExpired removes servers that haven't been seen recently
 func (d *DiscoveryClient) cleanupExpired() {
 	cutoff := time.Now().Add(-ServerExpiry)
-	for addr, server := range d.discovered {
+	for key, server := range d.discovered {
 		if server.LastSeen.Before(cutoff) {
-			delete(d.discovered, addr)
+			delete(d.discovered, key)
 		}
 	}
 }
@@ -145,7 +623,7 @@ func (d *DiscoveryClient) cleanupExpired() {
 func (d *DiscoveryClient) GetServerConfig(serverAddr string) (map[string]interface{}, error) {
 	// Ensure http:// prefix
 	if !strings.HasPrefix(serverAddr, "http://") && !strings.HasPrefix(serverAddr, "https://") {
-		serverAddr = "http://" + serverAddr
+		serverAddr = ServerURL("http", serverAddr)
 	}
 
 	// Request config from server
@@ -193,7 +671,14 @@ func (d *DiscoveryClient) FormatServerList(servers []*DiscoveredServer) string {
 		}
 
 		output.WriteString(fmt.Sprintf("%d. %s (v%s)\n", i+1, server.Name, server.Version))
-		output.WriteString(fmt.Sprintf("   Address: %s\n", server.Address))
+		address := server.Address
+		if server.Scheme == "https" {
+			address = server.URL()
+		}
+		output.WriteString(fmt.Sprintf("   Address: %s\n", address))
+		if server.InstanceID != "" {
+			output.WriteString(fmt.Sprintf("   ID:      %s\n", server.InstanceID))
+		}
 		output.WriteString(fmt.Sprintf("   Status:  %s\n", authStatus))
 		output.WriteString(fmt.Sprintf("   Seen:    %s\n", ageStr))
 		output.WriteString("\n")
@@ -0,0 +1,589 @@
+package transport
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/mdns"
+)
+
+// announcement builds a discovery broadcast packet for a server
+func announcement(t *testing.T, name, instanceID, address string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"magic": DiscoveryMagicResponse,
+		"data": map[string]interface{}{
+			"name":        name,
+			"instance_id": instanceID,
+			"version":     "0.1.0-lite",
+			"address":     address,
+			"port":        "8080",
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal announcement: %v", err)
+	}
+	return data
+}
+
+func TestRecordAnnouncement_DedupByInstanceID(t *testing.T) {
+	d := NewDiscoveryClient()
+	now := time.Now()
+
+	// The same server heard on two interfaces
+	d.recordAnnouncement(announcement(t, "Backups", "aaaa", "192.168.1.10:8080"), net.IPAddr{IP: net.ParseIP("192.168.1.10")}, now)
+	d.recordAnnouncement(announcement(t, "Backups", "aaaa", "10.0.0.10:8080"), net.IPAddr{IP: net.ParseIP("10.0.0.10")}, now)
+	d.recordAnnouncement(announcement(t, "Media", "bbbb", "192.168.1.20:8080"), net.IPAddr{IP: net.ParseIP("192.168.1.20")}, now)
+
+	if len(d.discovered) != 2 {
+		t.Fatalf("expected 2 servers, got %d", len(d.discovered))
+	}
+	if d.discovered["aaaa"].Name != "Backups" || d.discovered["bbbb"].Name != "Media" {
+		t.Errorf("unexpected servers: %+v", d.discovered)
+	}
+}
+
+func TestRecordAnnouncement_IgnoresInvalid(t *testing.T) {
+	d := NewDiscoveryClient()
+
+	d.recordAnnouncement([]byte("not json"), net.IPAddr{IP: net.ParseIP("10.0.0.1")}, time.Now())
+	d.recordAnnouncement([]byte(`{"magic":"OTHER","data":{}}`), net.IPAddr{IP: net.ParseIP("10.0.0.1")}, time.Now())
+
+	if len(d.discovered) != 0 {
+		t.Errorf("expected no servers, got %d", len(d.discovered))
+	}
+}
+
+func TestFormatServerList_ShowsNames(t *testing.T) {
+	d := NewDiscoveryClient()
+	now := time.Now()
+	d.recordAnnouncement(announcement(t, "Backups", "aaaa", "0.0.0.0:8080"), net.IPAddr{IP: net.ParseIP("192.168.1.10")}, now)
+	d.recordAnnouncement(announcement(t, "Media", "bbbb", "192.168.1.20:8080"), net.IPAddr{IP: net.ParseIP("192.168.1.20")}, now)
+
+	var servers []*DiscoveredServer
+	for _, s := range d.discovered {
+		servers = append(servers, s)
+	}
+	output := d.FormatServerList(servers)
+
+	for _, want := range []string{"Backups", "Media", "aaaa", "192.168.1.10:8080"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestDiscoverServers_CollectsForFullWindow(t *testing.T) {
+	// Reserve a free UDP port for the client to listen on
+	probe, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatalf("failed to reserve port: %v", err)
+	}
+	port := probe.LocalAddr().(*net.UDPAddr).Port
+	probe.Close()
+
+	d := NewDiscoveryClient()
+	d.port = port
+	d.timeout = 2500 * time.Millisecond
+
+	// Two servers announce at different times within the window; the second
+	// arrives well over a second after the first
+	first := announcement(t, "Backups", "aaaa", "192.168.1.10:8080")
+	second := announcement(t, "Media", "bbbb", "192.168.1.20:8080")
+	go func() {
+		target := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: port}
+		conn, err := net.DialUDP("udp", nil, target)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		time.Sleep(200 * time.Millisecond)
+		conn.Write(first)
+		time.Sleep(1500 * time.Millisecond)
+		conn.Write(second)
+	}()
+
+	servers, err := d.DiscoverServers()
+	if err != nil {
+		t.Fatalf("DiscoverServers failed: %v", err)
+	}
+	if len(servers) != 2 {
+		t.Fatalf("expected 2 servers, got %d", len(servers))
+	}
+}
+
+func TestServerFromTXT(t *testing.T) {
+	entry := &mdns.Entry{
+		Instance: "Backups (012345)",
+		Host:     "nas.local.",
+		Port:     8443,
+		Text: mdns.EncodeTXT(map[string]string{
+			"txtvers": "1",
+			"name":    "Backups",
+			"id":      "0123456789abcdef",
+			"scheme":  "https",
+			"version": "0.1.0-lite",
+			"address": "192.168.1.10:8443",
+			"port":    "8443",
+			"auth":    "true",
+		}),
+	}
+	server := serverFromTXT(entry)
+	want := DiscoveredServer{
+		Name:        "Backups",
+		InstanceID:  "0123456789abcdef",
+		Scheme:      "https",
+		Version:     "0.1.0-lite",
+		Address:     "192.168.1.10:8443",
+		Port:        "8443",
+		AuthEnabled: true,
+	}
+	if *server != want {
+		t.Errorf("expected %+v, got %+v", want, *server)
+	}
+}
+
+func TestServerFromTXT_MissingFields(t *testing.T) {
+	// A responder that only sent its SRV record still yields a usable server
+	server := serverFromTXT(&mdns.Entry{Instance: "Backups", Host: "nas.local.", Port: 8080, Text: []string{"auth=false"}})
+	if server.Name != "Backups" || server.Port != "8080" || server.AuthEnabled {
+		t.Errorf("unexpected server: %+v", server)
+	}
+	if server.Address != "0.0.0.0:8080" {
+		t.Errorf("expected a placeholder address to be replaced, got %q", server.Address)
+	}
+}
+
+func TestDiscoverServers_MDNS(t *testing.T) {
+	d := NewDiscoveryClient()
+	d.SetMode(DiscoveryModeMDNS)
+	d.browse = func(service string, timeout time.Duration) ([]*mdns.Entry, error) {
+		if service != MDNSServiceType {
+			t.Errorf("expected to browse for %s, got %s", MDNSServiceType, service)
+		}
+		text := mdns.EncodeTXT(map[string]string{"name": "Backups", "id": "aaaa", "address": "0.0.0.0:8080", "port": "8080"})
+		return []*mdns.Entry{
+			// The same server answering on two interfaces
+			{Instance: "Backups (aaaa)", Port: 8080, IPs: []net.IP{net.ParseIP("192.168.1.10")}, Text: text},
+			{Instance: "Backups (aaaa)", Port: 8080, Source: net.ParseIP("10.0.0.10"), Text: text},
+		}, nil
+	}
+
+	servers, err := d.DiscoverServers()
+	if err != nil {
+		t.Fatalf("DiscoverServers failed: %v", err)
+	}
+	if len(servers) != 1 {
+		t.Fatalf("expected 1 server, got %d", len(servers))
+	}
+	if servers[0].Name != "Backups" || !strings.HasSuffix(servers[0].Address, ":8080") || strings.HasPrefix(servers[0].Address, "0.0.0.0") {
+		t.Errorf("unexpected server: %+v", servers[0])
+	}
+}
+
+func TestRecordAnnouncement_IPv6(t *testing.T) {
+	d := NewDiscoveryClient()
+	now := time.Now()
+
+	// Servers listening on every address, heard over link-local IPv6
+	d.recordAnnouncement(announcement(t, "Backups", "aaaa", "[::]:8080"), net.IPAddr{IP: net.ParseIP("fe80::1"), Zone: "eth0"}, now)
+	d.recordAnnouncement(announcement(t, "Media", "bbbb", ":8080"), net.IPAddr{IP: net.ParseIP("2001:db8::20")}, now)
+	d.recordAnnouncement(announcement(t, "Docs", "cccc", "[2001:db8::30]:8080"), net.IPAddr{IP: net.ParseIP("fe80::30"), Zone: "eth0"}, now)
+
+	want := map[string]string{
+		"aaaa": "[fe80::1%eth0]:8080",
+		"bbbb": "[2001:db8::20]:8080",
+		"cccc": "[2001:db8::30]:8080", // a routable address is kept
+	}
+	for id, address := range want {
+		if got := d.discovered[id].Address; got != address {
+			t.Errorf("%s: expected address %s, got %s", id, address, got)
+		}
+	}
+}
+
+func TestUnreachableAddress(t *testing.T) {
+	tests := map[string]bool{
+		"0.0.0.0:8080":       true,
+		"127.0.0.1:8080":     true,
+		"localhost:8080":     true,
+		":8080":              true,
+		"[::]:8080":          true,
+		"[::1]:8080":         true,
+		"192.168.1.10:8080":  false,
+		"[fe80::1%eth0]:80":  false,
+		"[2001:db8::1]:8080": false,
+		"nas.local:8080":     false,
+	}
+	for address, want := range tests {
+		if got := unreachableAddress(address); got != want {
+			t.Errorf("%s: expected %v, got %v", address, want, got)
+		}
+	}
+}
+
+func TestServerURL(t *testing.T) {
+	tests := []struct {
+		scheme, address, want string
+	}{
+		{"", "192.168.1.10:8080", "http://192.168.1.10:8080"},
+		{"https", "nas.local:8443", "https://nas.local:8443"},
+		{"http", "[2001:db8::1]:8080", "http://[2001:db8::1]:8080"},
+		{"http", "[fe80::1%eth0]:8080", "http://[fe80::1%25eth0]:8080"},
+		{"http", "[fe80::1%25eth0]:8080", "http://[fe80::1%25eth0]:8080"},
+		{"http", "2001:db8::1", "http://[2001:db8::1]"},
+		{"http", "[2001:db8::1]", "http://[2001:db8::1]"},
+		{"http", "nas.local", "http://nas.local"},
+	}
+	for _, tt := range tests {
+		got := ServerURL(tt.scheme, tt.address)
+		if got != tt.want {
+			t.Errorf("ServerURL(%q, %q) = %q, want %q", tt.scheme, tt.address, got, tt.want)
+			continue
+		}
+		if _, err := url.Parse(got); err != nil {
+			t.Errorf("ServerURL(%q, %q) = %q, which doesn't parse: %v", tt.scheme, tt.address, got, err)
+		}
+	}
+
+	server := &DiscoveredServer{Scheme: "https", Address: "[fe80::1%eth0]:8443"}
+	if got := server.URL(); got != "https://[fe80::1%25eth0]:8443" {
+		t.Errorf("unexpected URL %q", got)
+	}
+}
+
+func TestDiscoverServers_ListensOnIPv6(t *testing.T) {
+	probe, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Skipf("IPv6 unavailable: %v", err)
+	}
+	port := probe.LocalAddr().(*net.UDPAddr).Port
+	probe.Close()
+
+	d := NewDiscoveryClient()
+	d.port = port
+	d.timeout = 500 * time.Millisecond
+
+	go func() {
+		conn, err := net.DialUDP("udp6", nil, &net.UDPAddr{IP: net.IPv6loopback, Port: port})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		time.Sleep(100 * time.Millisecond)
+		conn.Write(announcement(t, "Backups", "aaaa", "[::]:8080"))
+	}()
+
+	servers, err := d.DiscoverServers()
+	if err != nil {
+		t.Fatalf("DiscoverServers failed: %v", err)
+	}
+	if len(servers) != 1 {
+		t.Fatalf("expected 1 server, got %d", len(servers))
+	}
+	if servers[0].Address != "[::1]:8080" {
+		t.Errorf("expected address [::1]:8080, got %s", servers[0].Address)
+	}
+}
+
+// signedAnnouncement builds a discovery packet signed with secret
+func signedAnnouncement(t *testing.T, secret, name, instanceID, address string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"name":        name,
+		"instance_id": instanceID,
+		"version":     "0.1.0-lite",
+		"address":     address,
+		"port":        "8080",
+		"timestamp":   time.Now().Unix(),
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal announcement: %v", err)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	packet, err := json.Marshal(map[string]interface{}{
+		"magic":     DiscoveryMagicResponse,
+		"data":      json.RawMessage(data),
+		"signature": hex.EncodeToString(mac.Sum(nil)),
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal announcement: %v", err)
+	}
+	return packet
+}
+
+func TestRecordAnnouncement_Signed(t *testing.T) {
+	remote := net.IPAddr{IP: net.ParseIP("192.168.1.10")}
+	signed := signedAnnouncement(t, "s3cret", "Backups", "aaaa", "192.168.1.10:8080")
+
+	t.Run("accepts a valid signature", func(t *testing.T) {
+		d := NewDiscoveryClient()
+		d.SetSecret("s3cret")
+		d.recordAnnouncement(signed, remote, time.Now())
+		if d.discovered["aaaa"] == nil || d.discovered["aaaa"].Name != "Backups" {
+			t.Errorf("expected the signed server to be recorded, got %+v", d.discovered)
+		}
+	})
+
+	t.Run("rejects a replayed announcement", func(t *testing.T) {
+		d := NewDiscoveryClient()
+		d.SetSecret("s3cret")
+		d.recordAnnouncement(signed, remote, time.Now().Add(5*time.Minute))
+		if len(d.discovered) != 0 {
+			t.Errorf("expected a stale announcement to be dropped, got %+v", d.discovered)
+		}
+	})
+
+	t.Run("keeps the sender's address out of signed announcements", func(t *testing.T) {
+		d := NewDiscoveryClient()
+		d.SetSecret("s3cret")
+		d.recordAnnouncement(signedAnnouncement(t, "s3cret", "Backups", "aaaa", "0.0.0.0:8080"), remote, time.Now())
+		if len(d.discovered) != 0 {
+			t.Errorf("expected a signed announcement without a routable address to be dropped, got %+v", d.discovered)
+		}
+	})
+
+	t.Run("rejects tampered data", func(t *testing.T) {
+		d := NewDiscoveryClient()
+		d.SetSecret("s3cret")
+		tampered := []byte(strings.Replace(string(signed), "192.168.1.10", "192.168.1.66", 1))
+		d.recordAnnouncement(tampered, remote, time.Now())
+		if len(d.discovered) != 0 {
+			t.Errorf("expected a tampered announcement to be dropped, got %+v", d.discovered)
+		}
+	})
+
+	t.Run("rejects the wrong secret", func(t *testing.T) {
+		d := NewDiscoveryClient()
+		d.SetSecret("other")
+		d.recordAnnouncement(signed, remote, time.Now())
+		if len(d.discovered) != 0 {
+			t.Errorf("expected an announcement signed with another secret to be dropped, got %+v", d.discovered)
+		}
+	})
+
+	t.Run("rejects unsigned", func(t *testing.T) {
+		d := NewDiscoveryClient()
+		d.SetSecret("s3cret")
+		d.recordAnnouncement(announcement(t, "Backups", "aaaa", "192.168.1.10:8080"), remote, time.Now())
+		d.recordAnnouncement([]byte(strings.Replace(string(signed), `"signature":"`, `"signature":"zz`, 1)), remote, time.Now())
+		if len(d.discovered) != 0 {
+			t.Errorf("expected unsigned announcements to be dropped, got %+v", d.discovered)
+		}
+	})
+
+	t.Run("accepts anything without a secret", func(t *testing.T) {
+		d := NewDiscoveryClient()
+		d.recordAnnouncement(signed, remote, time.Now())
+		d.recordAnnouncement(announcement(t, "Media", "bbbb", "192.168.1.20:8080"), remote, time.Now())
+		if len(d.discovered) != 2 {
+			t.Errorf("expected both servers, got %d", len(d.discovered))
+		}
+	})
+}
+
+func TestRecordEntries_Signed(t *testing.T) {
+	text := mdns.EncodeTXT(map[string]string{"name": "Backups", "id": "aaaa", "address": "192.168.1.10:8080", "port": "8080"})
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(strings.Join(text, "\n")))
+	signed := append(text[:len(text):len(text)], "sig="+hex.EncodeToString(mac.Sum(nil)))
+	tampered := append([]string{"name=Evil"}, signed[1:]...)
+
+	d := NewDiscoveryClient()
+	d.SetSecret("s3cret")
+	d.recordEntries([]*mdns.Entry{
+		{Instance: "Unsigned", Port: 8080, Text: text},
+		{Instance: "Tampered", Port: 8080, Text: tampered},
+		{Instance: "Backups", Port: 8080, Text: signed},
+	}, time.Now())
+
+	if len(d.discovered) != 1 || d.discovered["aaaa"] == nil || d.discovered["aaaa"].Name != "Backups" {
+		t.Errorf("expected only the signed entry, got %+v", d.discovered)
+	}
+}
+
+func TestProbeMessage(t *testing.T) {
+	packet, err := probeMessage()
+	if err != nil {
+		t.Fatalf("probeMessage failed: %v", err)
+	}
+	var probe struct {
+		Magic string `json:"magic"`
+	}
+	if err := json.Unmarshal(packet, &probe); err != nil || probe.Magic != DiscoveryProbeMagic {
+		t.Errorf("unexpected probe %s", packet)
+	}
+	if len(packet) != DiscoveryProbeSize {
+		t.Errorf("expected the probe padded to %d bytes, got %d", DiscoveryProbeSize, len(packet))
+	}
+}
+
+func TestProbeAddrs(t *testing.T) {
+	addrs, err := probeAddrs([]string{"192.168.1.0/24", "10.0.0.0/8", "192.168.1.10", "192.168.1.11:9000", "::1", "[::1]:9001"}, 8081)
+	if err != nil {
+		t.Fatalf("probeAddrs failed: %v", err)
+	}
+	want := []string{"192.168.1.255:8081", "10.255.255.255:8081", "192.168.1.10:8081", "192.168.1.11:9000", "[::1]:8081", "[::1]:9001"}
+	if len(addrs) != len(want) {
+		t.Fatalf("expected %d addresses, got %v", len(want), addrs)
+	}
+	for i, addr := range addrs {
+		if addr.String() != want[i] {
+			t.Errorf("target %d: expected %s, got %s", i, want[i], addr)
+		}
+	}
+
+	for _, bad := range []string{"2001:db8::/64", "192.168.1.300"} {
+		if _, err := probeAddrs([]string{bad}, 8081); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestDiscoverServers_Probe(t *testing.T) {
+	// A server that only announces itself when probed
+	server, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer server.Close()
+	reply := signedAnnouncement(t, "s3cret", "Backups", "aaaa", "192.168.1.10:8080")
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, from, err := server.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if strings.Contains(string(buf[:n]), `"magic":"GOFLUX-LITE-PROBE"`) {
+				server.WriteToUDP(reply, from)
+			}
+		}
+	}()
+
+	d := NewDiscoveryClient()
+	d.port = server.LocalAddr().(*net.UDPAddr).Port
+	d.timeout = 300 * time.Millisecond
+	d.SetSecret("s3cret")
+	d.SetProbeTargets([]string{"127.0.0.1"})
+
+	servers, err := d.DiscoverServers()
+	if err != nil {
+		t.Fatalf("DiscoverServers failed: %v", err)
+	}
+	if len(servers) != 1 || servers[0].Name != "Backups" || servers[0].Address != "192.168.1.10:8080" {
+		t.Errorf("expected the probed server, got %+v", servers)
+	}
+}
+
+func TestDiscoverServers_LongAnnouncement(t *testing.T) {
+	probe, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatalf("failed to reserve port: %v", err)
+	}
+	port := probe.LocalAddr().(*net.UDPAddr).Port
+	probe.Close()
+
+	d := NewDiscoveryClient()
+	d.port = port
+	d.timeout = 500 * time.Millisecond
+	d.SetSecret("s3cret")
+
+	// A signed announcement larger than a probe reply is still read whole
+	name := strings.Repeat("n", 1500)
+	packet := signedAnnouncement(t, "s3cret", name, "aaaa", "192.168.1.10:8080")
+	if len(packet) <= DiscoveryProbeSize {
+		t.Fatalf("expected an announcement over %d bytes, got %d", DiscoveryProbeSize, len(packet))
+	}
+	go func() {
+		conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: port})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		time.Sleep(100 * time.Millisecond)
+		conn.Write(packet)
+	}()
+
+	servers, err := d.DiscoverServers()
+	if err != nil {
+		t.Fatalf("DiscoverServers failed: %v", err)
+	}
+	if len(servers) != 1 || servers[0].Name != name {
+		t.Errorf("expected the long announcement to be recorded, got %d servers", len(servers))
+	}
+}
+
+func TestDiscoverServers_QueriesFirst(t *testing.T) {
+	// A server on this machine that never broadcasts but answers queries,
+	// holding the discovery port as a real one would
+	server, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer server.Close()
+	reply := announcement(t, "Backups", "aaaa", "0.0.0.0:8080")
+	queried := make(chan struct{}, 1)
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, from, err := server.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if strings.Contains(string(buf[:n]), `"magic":"GOFLUX-LITE-PROBE"`) {
+				select {
+				case queried <- struct{}{}:
+				default:
+				}
+				server.WriteToUDP(reply, from)
+			}
+		}
+	}()
+
+	d := NewDiscoveryClient()
+	d.port = server.LocalAddr().(*net.UDPAddr).Port
+	d.timeout = time.Second
+
+	start := time.Now()
+	servers, err := d.DiscoverServers()
+	if err != nil {
+		t.Fatalf("DiscoverServers failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= DiscoveryTimeout {
+		t.Errorf("expected discovery to finish within %v, took %v", DiscoveryTimeout, elapsed)
+	}
+	select {
+	case <-queried:
+	default:
+		t.Fatal("expected the client to query for servers")
+	}
+	if len(servers) != 1 || servers[0].Address != "127.0.0.1:8080" {
+		t.Errorf("expected the queried server, got %+v", servers)
+	}
+}
+
+func TestLocalProbeAddrs(t *testing.T) {
+	addrs := localProbeAddrs(8081)
+	if len(addrs) == 0 || addrs[0].String() != "127.0.0.1:8081" {
+		t.Fatalf("expected this machine to be probed first, got %v", addrs)
+	}
+	for _, addr := range addrs {
+		if addr.Port != 8081 {
+			t.Errorf("expected port 8081, got %v", addr)
+		}
+		if addr.IP.To4() == nil && (!addr.IP.Equal(allNodesIPv6) || addr.Zone == "") {
+			t.Errorf("expected IPv6 probes to go to all nodes on a named link, got %v", addr)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package transport
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+const (
+	// DefaultDownloadRetries is how many times DownloadResume resumes a stalled transfer
+	DefaultDownloadRetries = 3
+	// DefaultStallTimeout is how long a download may go without receiving data
+	DefaultStallTimeout = 30 * time.Second
+)
+
+// SetDownloadRetries sets how many times DownloadResume resumes a transfer
+// that timed out before giving up
+func (h *HTTPClient) SetDownloadRetries(retries int) {
+	h.downloadRetries = retries
+}
+
+// SetStallTimeout sets how long a download may go without receiving data
+// before it is abandoned and resumed
+func (h *HTTPClient) SetStallTimeout(timeout time.Duration) {
+	h.stallTimeout = timeout
+}
+
+// DownloadResume downloads a file to localPath, continuing from the end of any
+// partial copy already there. Transfers that time out are resumed from where
+// they stopped, up to the configured number of retries, and a busy server is
+// waited for as configured by SetMaxBusyWait.
+func (h *HTTPClient) DownloadResume(remotePath, localPath string) error {
+	return h.DownloadResumeContext(context.Background(), remotePath, localPath)
+}
+
+// DownloadResumeContext is DownloadResume, giving up without further retries
+// when ctx is done. Whatever was received is kept so a later call can resume.
+func (h *HTTPClient) DownloadResumeContext(ctx context.Context, remotePath, localPath string) error {
+	resume := func() error {
+		return h.waitWhileBusy(ctx, func() error { return h.downloadRemaining(ctx, remotePath, localPath) })
+	}
+	err := resume()
+	for attempt := 0; err != nil && attempt < h.downloadRetries; attempt++ {
+		if ctx.Err() != nil {
+			return err
+		}
+		if errType, ok := errors.GetNetworkErrorType(err); !ok || errType != errors.NetworkErrorTimeout {
+			return err
+		}
+		fmt.Printf("Download stalled, resuming (attempt %d/%d)...\n", attempt+1, h.downloadRetries)
+		err = resume()
+	}
+	return err
+}
+
+// OpenDownload starts downloading remotePath and returns the response body
+// for the caller to read as the data arrives, so a file can be passed on
+// without holding it in memory or on disk. The caller must close it. A busy
+// server is waited for as configured by SetMaxBusyWait.
+func (h *HTTPClient) OpenDownload(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	var body io.ReadCloser
+	err := h.waitWhileBusy(ctx, func() error {
+		var err error
+		body, err = h.openDownload(ctx, remotePath)
+		return err
+	})
+	return body, err
+}
+
+// openDownload makes a single download request for OpenDownload
+func (h *HTTPClient) openDownload(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", h.BaseURL+"/download?path="+url.QueryEscape(h.ResolvePath(remotePath)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add auth token if set
+	if h.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.authToken)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, transferError("download request failed", err, false)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, statusError("download", resp)
+	}
+	return resp.Body, nil
+}
+
+// DownloadArchive downloads the remote directory remoteDir as an archive in
+// format ("zip" or "tar.gz"), writing it to w as it arrives. It returns the
+// number of bytes written. A busy server is waited for as configured by
+// SetMaxBusyWait.
+func (h *HTTPClient) DownloadArchive(ctx context.Context, remoteDir, format string, w io.Writer) (int64, error) {
+	var body io.ReadCloser
+	err := h.waitWhileBusy(ctx, func() error {
+		var err error
+		body, err = h.openArchive(ctx, remoteDir, format)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	n, err := io.Copy(w, body)
+	if err != nil {
+		return n, transferError("archive download failed", err, false)
+	}
+	return n, nil
+}
+
+// openArchive makes a single archive request for DownloadArchive
+func (h *HTTPClient) openArchive(ctx context.Context, remoteDir, format string) (io.ReadCloser, error) {
+	query := url.Values{"path": {h.ResolvePath(remoteDir)}, "format": {format}}
+	req, err := http.NewRequestWithContext(ctx, "GET", h.BaseURL+"/download/archive?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add auth token if set
+	if h.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.authToken)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, transferError("archive request failed", err, false)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, statusError("archive download", resp)
+	}
+	return resp.Body, nil
+}
+
+// downloadRemaining requests the bytes of remotePath beyond the current end of
+// localPath and appends them
+func (h *HTTPClient) downloadRemaining(parent context.Context, remotePath, localPath string) error {
+	f, err := os.OpenFile(localPath, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", localPath, err)
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("failed to seek %s: %w", localPath, err)
+	}
+
+	// The request is cancelled whenever no data arrives for stallTimeout
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	var stalled atomic.Bool
+	timer := time.AfterFunc(h.stallTimeout, func() {
+		stalled.Store(true)
+		cancel()
+	})
+	defer timer.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", h.BaseURL+"/download?path="+url.QueryEscape(h.ResolvePath(remotePath)), nil)
+	if err != nil {
+		return err
+	}
+
+	// Add auth token if set
+	if h.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.authToken)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return transferError("download request failed", err, stalled.Load())
+	}
+	defer resp.Body.Close()
+
+	total := int64(-1)
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil || start != offset {
+			return errors.NewNetworkError(errors.NetworkErrorInvalidResponse,
+				fmt.Sprintf("download failed: unexpected Content-Range %q", resp.Header.Get("Content-Range")))
+		}
+		total = size
+	case http.StatusOK:
+		// The server sent the whole file, so start over
+		if err := f.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate %s: %w", localPath, err)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek %s: %w", localPath, err)
+		}
+		offset = 0
+	case http.StatusRequestedRangeNotSatisfiable:
+		_, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err == nil && size == offset {
+			return nil // already complete
+		}
+		// The local file is longer than the remote one, so it can't be a prefix of it
+		if err := f.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate %s: %w", localPath, err)
+		}
+		f.Close()
+		return h.downloadRemaining(parent, remotePath, localPath)
+	default:
+		// Don't leave behind an empty file for a download that never started
+		if offset == 0 {
+			f.Close()
+			os.Remove(localPath)
+		}
+		return statusError("download", resp)
+	}
+
+	n, err := io.Copy(f, &progressReader{r: resp.Body, timer: timer, timeout: h.stallTimeout})
+	if err != nil {
+		return transferError(fmt.Sprintf("download interrupted after %d bytes", offset+n), err, stalled.Load())
+	}
+
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return errors.NewNetworkError(errors.NetworkErrorInvalidResponse,
+			fmt.Sprintf("download incomplete: received %d of %d bytes", n, resp.ContentLength))
+	}
+	if total >= 0 && offset+n != total {
+		return errors.NewNetworkError(errors.NetworkErrorInvalidResponse,
+			fmt.Sprintf("download incomplete: have %d of %d bytes", offset+n, total))
+	}
+
+	return nil
+}
+
+// progressReader pushes back the stall timer each time data arrives
+type progressReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.timer.Reset(p.timeout)
+	}
+	return n, err
+}
+
+// transferError classifies a failed transfer as a timeout or a connection error
+func transferError(message string, err error, stalled bool) error {
+	var netErr net.Error
+	if stalled || (stderrors.As(err, &netErr) && netErr.Timeout()) {
+		return errors.NewNetworkErrorWithCause(errors.NetworkErrorTimeout, message, err)
+	}
+	return errors.NewNetworkErrorWithCause(errors.NetworkErrorConnection, message, err)
+}
+
+// parseContentRange parses "bytes start-end/size" or "bytes */size",
+// returning the start offset (0 for the latter) and the full size
+func parseContentRange(header string) (start, size int64, err error) {
+	spec, found := strings.CutPrefix(header, "bytes ")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	span, sizeStr, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+
+	size, err = strconv.ParseInt(sizeStr, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	if span == "*" {
+		return 0, size, nil
+	}
+
+	startStr, _, found := strings.Cut(span, "-")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	start, err = strconv.ParseInt(startStr, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	return start, size, nil
+}
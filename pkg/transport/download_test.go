@@ -0,0 +1,203 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+// flakyServer serves content with Range support. The first failures requests
+// send half of what was asked for and then stall until the client gives up.
+type flakyServer struct {
+	content  []byte
+	failures int
+
+	mu     sync.Mutex
+	ranges []string // Range header of each request
+}
+
+func (f *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.ranges = append(f.ranges, r.Header.Get("Range"))
+	stall := len(f.ranges) <= f.failures
+	f.mu.Unlock()
+
+	if !stall {
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(f.content))
+		return
+	}
+
+	start := 0
+	if rng := r.Header.Get("Range"); rng != "" {
+		start, _ = strconv.Atoi(rng[len("bytes=") : len(rng)-1])
+	}
+	half := start + (len(f.content)-start)/2
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(f.content)-start))
+	if start > 0 {
+		w.Header().Set("Content-Range", "bytes "+strconv.Itoa(start)+"-"+strconv.Itoa(len(f.content)-1)+"/"+strconv.Itoa(len(f.content)))
+		w.WriteHeader(http.StatusPartialContent)
+	}
+	w.Write(f.content[start:half])
+	w.(http.Flusher).Flush()
+
+	select {
+	case <-r.Context().Done():
+	case <-time.After(5 * time.Second):
+	}
+}
+
+func (f *flakyServer) requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.ranges...)
+}
+
+func newFlakyClient(t *testing.T, content []byte, failures int) (*HTTPClient, *flakyServer) {
+	t.Helper()
+	fs := &flakyServer{content: content, failures: failures}
+	ts := httptest.NewServer(fs)
+	t.Cleanup(ts.Close)
+
+	client := NewHTTPClient(ts.URL)
+	client.SetStallTimeout(100 * time.Millisecond)
+	return client, fs
+}
+
+func TestDownloadResume_ResumesAfterStall(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	client, fs := newFlakyClient(t, content, 1)
+	localPath := filepath.Join(t.TempDir(), "file.bin")
+
+	if err := client.DownloadResume("file.bin", localPath); err != nil {
+		t.Fatalf("DownloadResume failed: %v", err)
+	}
+
+	got, err := os.ReadFile(localPath)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatalf("downloaded %d bytes, content mismatch", len(got))
+	}
+
+	requests := fs.requests()
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	if requests[0] != "" {
+		t.Errorf("expected first request without Range, got %q", requests[0])
+	}
+	if want := "bytes=" + strconv.Itoa(len(content)/2) + "-"; requests[1] != want {
+		t.Errorf("expected resume with %q, got %q", want, requests[1])
+	}
+}
+
+func TestDownloadResume_ContinuesPartialFile(t *testing.T) {
+	content := []byte("hello, resumable world")
+	client, fs := newFlakyClient(t, content, 0)
+	localPath := filepath.Join(t.TempDir(), "file.txt")
+	os.WriteFile(localPath, content[:7], 0644)
+
+	if err := client.DownloadResume("file.txt", localPath); err != nil {
+		t.Fatalf("DownloadResume failed: %v", err)
+	}
+
+	got, _ := os.ReadFile(localPath)
+	if string(got) != string(content) {
+		t.Errorf("expected %q, got %q", content, got)
+	}
+	if requests := fs.requests(); len(requests) != 1 || requests[0] != "bytes=7-" {
+		t.Errorf("expected a single bytes=7- request, got %q", requests)
+	}
+
+	// A file that is already complete is left alone
+	if err := client.DownloadResume("file.txt", localPath); err != nil {
+		t.Fatalf("DownloadResume of complete file failed: %v", err)
+	}
+	got, _ = os.ReadFile(localPath)
+	if string(got) != string(content) {
+		t.Errorf("expected complete file to be unchanged, got %q", got)
+	}
+}
+
+func TestDownloadResume_GivesUpAfterRetries(t *testing.T) {
+	client, fs := newFlakyClient(t, bytes.Repeat([]byte("x"), 1024), 10)
+	client.SetDownloadRetries(2)
+
+	err := client.DownloadResume("file.bin", filepath.Join(t.TempDir(), "file.bin"))
+	if errType, ok := errors.GetNetworkErrorType(err); !ok || errType != errors.NetworkErrorTimeout {
+		t.Fatalf("expected timeout NetworkError, got %v", err)
+	}
+	if n := len(fs.requests()); n != 3 {
+		t.Errorf("expected 1 attempt plus 2 retries, got %d requests", n)
+	}
+}
+
+func TestDownloadResume_ShortBodyNotRetried(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("only ten b"))
+	}))
+	t.Cleanup(ts.Close)
+
+	client := NewHTTPClient(ts.URL)
+	err := client.DownloadResume("file.bin", filepath.Join(t.TempDir(), "file.bin"))
+	if errType, ok := errors.GetNetworkErrorType(err); !ok || errType != errors.NetworkErrorConnection {
+		t.Fatalf("expected connection NetworkError, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected no retries, got %d requests", requests)
+	}
+}
+
+func TestDownloadResume_NotFound(t *testing.T) {
+	ts, _ := newRecordingServer(t, http.StatusNotFound)
+	client := NewHTTPClient(ts.URL)
+	localPath := filepath.Join(t.TempDir(), "missing.txt")
+
+	err := client.DownloadResume("missing.txt", localPath)
+	if errType, ok := errors.GetNetworkErrorType(err); !ok || errType != errors.NetworkErrorBadRequest {
+		t.Fatalf("expected bad request NetworkError, got %v", err)
+	}
+	if _, err := os.Stat(localPath); !os.IsNotExist(err) {
+		t.Error("expected no local file to be left behind")
+	}
+}
+
+func TestDownloadArchive(t *testing.T) {
+	ts, rec := newRecordingServer(t, http.StatusOK)
+	client := NewHTTPClient(ts.URL)
+	client.SetBasePath("team")
+
+	var buf bytes.Buffer
+	n, err := client.DownloadArchive(context.Background(), "site", "tar.gz", &buf)
+	if err != nil {
+		t.Fatalf("DownloadArchive failed: %v", err)
+	}
+	if rec.method != http.MethodGet || rec.path != "/download/archive" {
+		t.Errorf("expected GET /download/archive, got %s %s", rec.method, rec.path)
+	}
+	if rec.params.Get("path") != "team/site" || rec.params.Get("format") != "tar.gz" {
+		t.Errorf("unexpected parameters %v", rec.params)
+	}
+	if buf.String() != "server message" || n != int64(buf.Len()) {
+		t.Errorf("expected the body written out, got %q (%d bytes)", buf.String(), n)
+	}
+
+	ts, _ = newRecordingServer(t, http.StatusRequestEntityTooLarge)
+	if _, err := NewHTTPClient(ts.URL).DownloadArchive(context.Background(), "site", "zip", &buf); err == nil {
+		t.Error("expected an error when the archive is refused")
+	}
+}
@@ -0,0 +1,121 @@
+package transport
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+const (
+	// DefaultUploadRetries is how many times a failed chunk upload is retried
+	DefaultUploadRetries = 3
+	// DefaultRetryDelay is the wait before the first retry; it doubles after each attempt
+	DefaultRetryDelay = 500 * time.Millisecond
+	// DefaultChunkTimeout limits each attempt to upload a chunk
+	DefaultChunkTimeout = 2 * time.Minute
+	// maxRetryDelay caps the wait between retries
+	maxRetryDelay = 10 * time.Second
+)
+
+// SetUploadRetries sets how many times a chunk upload that fails with a
+// network error is retried before giving up. Zero disables retries.
+func (h *HTTPClient) SetUploadRetries(retries int) {
+	h.uploadRetries = retries
+}
+
+// SetChunkTimeout sets how long one attempt to upload a chunk may take before
+// it is abandoned as stalled and retried like any other timeout. It bounds
+// each chunk rather than the whole transfer, so large files can still take as
+// long as they need; bound the transfer with a context deadline. Zero
+// disables the limit.
+func (h *HTTPClient) SetChunkTimeout(timeout time.Duration) {
+	h.chunkTimeout = timeout
+}
+
+// withRetries calls upload until it succeeds, fails with an error that isn't
+// worth retrying, ctx is done or the configured retries are used up. Each call
+// gets a context limited by the chunk timeout. Retries back off exponentially
+// with jitter so many clients don't retry in lockstep. Busy answers with
+// Retry-After are waited out first without using up retries.
+func (h *HTTPClient) withRetries(ctx context.Context, upload func(ctx context.Context) error) error {
+	send := func() error { return h.waitWhileBusy(ctx, func() error { return h.attempt(ctx, upload) }) }
+	err := send()
+	for attempt := 0; err != nil && attempt < h.uploadRetries; attempt++ {
+		if ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+
+		delay := backoff(h.retryDelay, attempt)
+		fmt.Printf("Upload failed (%v), retrying in %v (attempt %d/%d)...\n", err, delay.Round(time.Millisecond), attempt+1, h.uploadRetries)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		err = send()
+	}
+	return err
+}
+
+// attempt calls upload once with a context limited by the chunk timeout. An
+// attempt cut short by the limit, rather than by ctx, fails with
+// NetworkErrorTimeout so it is retried.
+func (h *HTTPClient) attempt(ctx context.Context, upload func(ctx context.Context) error) error {
+	if h.chunkTimeout <= 0 {
+		return upload(ctx)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, h.chunkTimeout)
+	defer cancel()
+	err := upload(attemptCtx)
+	if err != nil && ctx.Err() == nil && attemptCtx.Err() == context.DeadlineExceeded {
+		return errors.NewNetworkErrorWithCause(errors.NetworkErrorTimeout,
+			fmt.Sprintf("chunk upload timed out after %v", h.chunkTimeout), err)
+	}
+	return err
+}
+
+// retryable reports whether err is a transient network failure. Requests the
+// server rejected, such as failed authentication or a bad chunk, would only
+// be rejected again.
+func retryable(err error) bool {
+	// waitWhileBusy has already waited as long as it is allowed to
+	var busy *ServerBusyError
+	if stderrors.As(err, &busy) {
+		return false
+	}
+
+	errType, ok := errors.GetNetworkErrorType(err)
+	if !ok {
+		return false
+	}
+	switch errType {
+	case errors.NetworkErrorConnection, errors.NetworkErrorTimeout, errors.NetworkErrorServerUnavailable:
+		return true
+	}
+	return false
+}
+
+// backoff returns the wait before retry number attempt (counting from 0): a
+// random duration between half and all of base doubled attempt times, capped
+// at maxRetryDelay
+func backoff(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 0; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,180 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+// newFailingUploadServer returns a client for a server that answers the first
+// len(failures) uploads by calling the matching failure and accepts the rest.
+// The returned function reports how many requests arrived.
+func newFailingUploadServer(t *testing.T, failures ...func(w http.ResponseWriter)) (*HTTPClient, func() int) {
+	t.Helper()
+	var mu sync.Mutex
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+
+		if n <= len(failures) {
+			failures[n-1](w)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(ts.Close)
+
+	client := NewHTTPClient(ts.URL)
+	client.retryDelay = time.Millisecond
+	return client, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+// dropConnection closes the connection without sending a response
+func dropConnection(w http.ResponseWriter) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err == nil {
+		conn.Close()
+	}
+}
+
+// respondWith returns a failure that answers with status
+func respondWith(status int) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		http.Error(w, http.StatusText(status), status)
+	}
+}
+
+func TestUploadChunk_RetriesNetworkErrors(t *testing.T) {
+	client, requests := newFailingUploadServer(t, dropConnection, respondWith(http.StatusServiceUnavailable))
+
+	if err := client.UploadChunk(ChunkData{Path: "a.bin", Data: []byte("x"), Total: 1}); err != nil {
+		t.Fatalf("expected upload to succeed after retries, got %v", err)
+	}
+	if n := requests(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestUploadChunkStream_RetriesNetworkErrors(t *testing.T) {
+	client, requests := newFailingUploadServer(t, respondWith(http.StatusBadGateway), dropConnection)
+
+	if err := client.UploadChunkStream(ChunkData{Path: "a.bin", Data: []byte("x"), Total: 1}); err != nil {
+		t.Fatalf("expected upload to succeed after retries, got %v", err)
+	}
+	if n := requests(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestUploadChunk_RejectedRequestNotRetried(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusBadRequest, http.StatusInternalServerError} {
+		client, requests := newFailingUploadServer(t, respondWith(status))
+
+		if err := client.UploadChunk(ChunkData{Path: "a.bin", Data: []byte("x"), Total: 1}); !errors.IsNetworkError(err) {
+			t.Errorf("%d: expected NetworkError, got %v", status, err)
+		}
+		if n := requests(); n != 1 {
+			t.Errorf("%d: expected a single request, got %d", status, n)
+		}
+	}
+}
+
+func TestUploadChunk_GivesUpAfterRetries(t *testing.T) {
+	unavailable := respondWith(http.StatusServiceUnavailable)
+	client, requests := newFailingUploadServer(t, unavailable, unavailable, unavailable)
+	client.SetUploadRetries(1)
+
+	err := client.UploadChunk(ChunkData{Path: "a.bin", Data: []byte("x"), Total: 1})
+	if errType, ok := errors.GetNetworkErrorType(err); !ok || errType != errors.NetworkErrorServerUnavailable {
+		t.Fatalf("expected server unavailable error, got %v", err)
+	}
+	if n := requests(); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+// stall holds the request without answering for longer than the chunk
+// timeouts used in the tests
+func stall(w http.ResponseWriter) {
+	time.Sleep(500 * time.Millisecond)
+}
+
+func TestUploadChunk_ChunkTimeoutRetries(t *testing.T) {
+	client, requests := newFailingUploadServer(t, stall)
+	client.SetChunkTimeout(50 * time.Millisecond)
+
+	// The transfer as a whole has far longer than any one chunk
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	for id := 0; id < 4; id++ {
+		if err := client.UploadChunkContext(ctx, ChunkData{Path: "a.bin", ChunkID: id, Data: []byte("x"), Total: 4}); err != nil {
+			t.Fatalf("chunk %d: expected upload to succeed after the stalled attempt, got %v", id, err)
+		}
+	}
+	if n := requests(); n != 5 {
+		t.Errorf("expected 5 requests (one stalled and retried), got %d", n)
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("expected the stalled chunk to be abandoned quickly, took %v", elapsed)
+	}
+}
+
+func TestUploadChunk_ChunkTimeoutError(t *testing.T) {
+	client, requests := newFailingUploadServer(t, stall, stall)
+	client.SetChunkTimeout(50 * time.Millisecond)
+	client.SetUploadRetries(1)
+
+	err := client.UploadChunk(ChunkData{Path: "a.bin", Data: []byte("x"), Total: 1})
+	if errType, ok := errors.GetNetworkErrorType(err); !ok || errType != errors.NetworkErrorTimeout {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if n := requests(); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestUploadChunk_OverallDeadline(t *testing.T) {
+	client, _ := newFailingUploadServer(t, stall, stall, stall, stall, stall)
+	client.SetChunkTimeout(100 * time.Millisecond)
+	client.SetUploadRetries(10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := client.UploadChunkContext(ctx, ChunkData{Path: "a.bin", Data: []byte("x"), Total: 1}); err == nil {
+		t.Fatal("expected upload to fail once the overall deadline passed")
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("expected retries to stop at the overall deadline, took %v", elapsed)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	base := 100 * time.Millisecond
+	for attempt, want := range []time.Duration{100, 200, 400, 800} {
+		want *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			if delay := backoff(base, attempt); delay < want/2 || delay > want {
+				t.Fatalf("attempt %d: delay %v outside [%v, %v]", attempt, delay, want/2, want)
+			}
+		}
+	}
+	if delay := backoff(base, 30); delay > maxRetryDelay {
+		t.Errorf("expected delay capped at %v, got %v", maxRetryDelay, delay)
+	}
+}
@@ -2,11 +2,20 @@ package transport
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"io"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
 )
 
 // Transport is an abstraction for underlying transport (ssh, quic, http).
@@ -21,7 +30,11 @@ type ChunkData struct {
 	ChunkID  int    `json:"chunk_id"`
 	Data     []byte `json:"data"`
 	Checksum string `json:"checksum"`
-	Total    int    `json:"total"` // total number of chunks
+	Total    int    `json:"total"`               // total number of chunks
+	FileHash string `json:"file_hash,omitempty"` // SHA-256 of the whole file, checked once it is reassembled
+	Replace  bool   `json:"replace,omitempty"`   // overwrite the chunk even if the server already has it
+
+	DeferFinalize bool `json:"defer_finalize,omitempty"` // leave reassembly to an explicit FinalizeUpload
 }
 
 // HTTPClient is an HTTP-based transport client.
@@ -29,18 +42,83 @@ type HTTPClient struct {
 	BaseURL   string
 	client    *http.Client
 	authToken string
+
+	basePath        string        // prefix of relative remote paths; empty for the storage root
+	downloadRetries int           // resume attempts after a stalled download
+	stallTimeout    time.Duration // how long a download may go without receiving data
+	uploadRetries   int           // retries of a chunk upload that failed with a network error
+	retryDelay      time.Duration // wait before the first upload retry
+	maxBusyWait     time.Duration // total wait allowed for a busy server sending Retry-After
+	chunkTimeout    time.Duration // limit on each attempt to upload a chunk; zero for none
 }
 
+// DefaultRequestTimeout is how long the client waits to connect to the server
+// and for the server to start answering a request
+const DefaultRequestTimeout = 30 * time.Second
+
+// NewHTTPClient creates a client for the server at baseURL using DefaultRequestTimeout.
 func NewHTTPClient(baseURL string) *HTTPClient {
+	return NewHTTPClientWithTimeout(baseURL, DefaultRequestTimeout)
+}
+
+// NewHTTPClientWithTimeout creates a client that gives up on a request when
+// connecting, the TLS handshake or waiting for the response headers takes
+// longer than timeout. Reading the response body isn't limited, so large
+// downloads aren't cut short. A zero or negative timeout uses DefaultRequestTimeout.
+func NewHTTPClientWithTimeout(baseURL string, timeout time.Duration) *HTTPClient {
 	// Normalize URL - add http:// if no scheme specified
 	if baseURL != "" && !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
 		baseURL = "http://" + baseURL
 	}
 
+	if timeout <= 0 {
+		timeout = DefaultRequestTimeout
+	}
+	httpTransport := http.DefaultTransport.(*http.Transport).Clone()
+	httpTransport.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+	httpTransport.TLSHandshakeTimeout = timeout
+	httpTransport.ResponseHeaderTimeout = timeout
+
 	return &HTTPClient{
-		BaseURL: baseURL,
-		client:  &http.Client{},
+		BaseURL:         baseURL,
+		client:          &http.Client{Transport: httpTransport},
+		downloadRetries: DefaultDownloadRetries,
+		stallTimeout:    DefaultStallTimeout,
+		uploadRetries:   DefaultUploadRetries,
+		retryDelay:      DefaultRetryDelay,
+		maxBusyWait:     DefaultMaxBusyWait,
+		chunkTimeout:    DefaultChunkTimeout,
+	}
+}
+
+// WithServer returns a client for the server at baseURL that uses the same
+// timeouts and retry settings as h. The token is carried over only when
+// baseURL names the same server as h, so that it isn't sent to another one;
+// set that server's own with SetAuthToken. The base path is not carried over.
+func (h *HTTPClient) WithServer(baseURL string) *HTTPClient {
+	if baseURL != "" && !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		baseURL = "http://" + baseURL
+	}
+	peer := *h
+	peer.BaseURL = baseURL
+	peer.basePath = ""
+	if !sameServer(h.BaseURL, baseURL) {
+		peer.authToken = ""
+	}
+	return &peer
+}
+
+// sameServer reports whether two base URLs have the same scheme, host and port
+func sameServer(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
 	}
+	return strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host)
 }
 
 // SetAuthToken sets the authentication token for requests
@@ -59,12 +137,28 @@ func (h *HTTPClient) Listen(addr string) error {
 
 // UploadChunk uploads a single chunk.
 func (h *HTTPClient) UploadChunk(chunk ChunkData) error {
+	return h.UploadChunkContext(context.Background(), chunk)
+}
+
+// UploadChunkContext uploads a single chunk, giving up when ctx is done.
+// Network failures and attempts exceeding the chunk timeout are retried as
+// configured by SetUploadRetries, and a busy server is waited for as
+// configured by SetMaxBusyWait.
+func (h *HTTPClient) UploadChunkContext(ctx context.Context, chunk ChunkData) error {
+	chunk.Path = h.ResolvePath(chunk.Path)
 	data, err := json.Marshal(chunk)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", h.BaseURL+"/upload", bytes.NewReader(data))
+	return h.withRetries(ctx, func(ctx context.Context) error {
+		return h.postChunk(ctx, data)
+	})
+}
+
+// postChunk sends one JSON-encoded chunk to the server
+func (h *HTTPClient) postChunk(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", h.BaseURL+"/upload", bytes.NewReader(data))
 	if err != nil {
 		return err
 	}
@@ -77,17 +171,90 @@ func (h *HTTPClient) UploadChunk(chunk ChunkData) error {
 
 	resp, err := h.client.Do(req)
 	if err != nil {
+		return transferError("upload request failed", err, false)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError("upload", resp)
+	}
+	return nil
+}
+
+// UploadChunkStream uploads a single chunk as multipart/form-data, sending the
+// raw bytes rather than base64 JSON. The body is streamed, so the encoded
+// request is never held in memory. Network failures are retried as configured
+// by SetUploadRetries, and a busy server is waited for as configured by
+// SetMaxBusyWait.
+func (h *HTTPClient) UploadChunkStream(chunk ChunkData) error {
+	chunk.Path = h.ResolvePath(chunk.Path)
+	return h.withRetries(context.Background(), func(ctx context.Context) error {
+		return h.streamChunk(ctx, chunk)
+	})
+}
+
+// streamChunk sends one chunk as a multipart form
+func (h *HTTPClient) streamChunk(ctx context.Context, chunk ChunkData) error {
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+
+	go func() {
+		err := writeChunkForm(form, chunk)
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", h.BaseURL+"/upload/stream", body)
+	if err != nil {
+		body.Close()
 		return err
 	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	// Add auth token if set
+	if h.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.authToken)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return transferError("upload request failed", err, false)
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("upload failed: %s", string(body))
+		return statusError("upload", resp)
 	}
 	return nil
 }
 
+// writeChunkForm writes the chunk metadata fields followed by the data part
+func writeChunkForm(form *multipart.Writer, chunk ChunkData) error {
+	fields := []struct{ name, value string }{
+		{"path", chunk.Path},
+		{"chunk_id", strconv.Itoa(chunk.ChunkID)},
+		{"total", strconv.Itoa(chunk.Total)},
+		{"checksum", chunk.Checksum},
+		{"file_hash", chunk.FileHash},
+		{"replace", strconv.FormatBool(chunk.Replace)},
+		{"defer_finalize", strconv.FormatBool(chunk.DeferFinalize)},
+	}
+	for _, f := range fields {
+		if err := form.WriteField(f.name, f.value); err != nil {
+			return err
+		}
+	}
+
+	part, err := form.CreateFormFile("data", "chunk")
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(chunk.Data)
+	return err
+}
+
 // UploadStatusResponse contains the status of an upload session
 type UploadStatusResponse struct {
 	Exists        bool   `json:"exists"`
@@ -95,11 +262,30 @@ type UploadStatusResponse struct {
 	ReceivedMap   []bool `json:"received_map"`
 	MissingChunks []int  `json:"missing_chunks"`
 	Completed     bool   `json:"completed"`
+
+	Checksums []string `json:"checksums,omitempty"` // SHA-256 of each received chunk as stored, if requested
 }
 
 // QueryUploadStatus checks the status of an upload on the server
 func (h *HTTPClient) QueryUploadStatus(path string) (*UploadStatusResponse, error) {
-	req, err := http.NewRequest("GET", h.BaseURL+"/upload/status?path="+path, nil)
+	return h.queryUploadStatus(path, false)
+}
+
+// QueryUploadChecksums checks the status of an upload on the server, along
+// with the checksum of each chunk it holds so they can be verified before
+// resuming
+func (h *HTTPClient) QueryUploadChecksums(path string) (*UploadStatusResponse, error) {
+	return h.queryUploadStatus(path, true)
+}
+
+func (h *HTTPClient) queryUploadStatus(path string, checksums bool) (*UploadStatusResponse, error) {
+	query := url.Values{}
+	query.Set("path", h.ResolvePath(path))
+	if checksums {
+		query.Set("checksums", "true")
+	}
+
+	req, err := http.NewRequest("GET", h.BaseURL+"/upload/status?"+query.Encode(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -111,13 +297,12 @@ func (h *HTTPClient) QueryUploadStatus(path string) (*UploadStatusResponse, erro
 
 	resp, err := h.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, transferError("status query request failed", err, false)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("status query failed: %s", string(body))
+		return nil, statusError("status query", resp)
 	}
 
 	var status UploadStatusResponse
@@ -128,9 +313,78 @@ func (h *HTTPClient) QueryUploadStatus(path string) (*UploadStatusResponse, erro
 	return &status, nil
 }
 
+// FinalizeUpload asks the server to reassemble an upload whose chunks were
+// sent with DeferFinalize. It fails if any chunk hasn't arrived yet.
+func (h *HTTPClient) FinalizeUpload(path string) error {
+	req, err := http.NewRequest("POST", h.BaseURL+"/upload/finalize?path="+url.QueryEscape(h.ResolvePath(path)), nil)
+	if err != nil {
+		return err
+	}
+
+	// Add auth token if set
+	if h.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.authToken)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return transferError("finalize request failed", err, false)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError("finalize", resp)
+	}
+
+	return nil
+}
+
+// AbortUpload discards the server's partial copy of an unfinished upload of
+// path, for uploads that will not be resumed.
+func (h *HTTPClient) AbortUpload(path string) error {
+	req, err := http.NewRequest("POST", h.BaseURL+"/upload/abort?path="+url.QueryEscape(h.ResolvePath(path)), nil)
+	if err != nil {
+		return err
+	}
+
+	// Add auth token if set
+	if h.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.authToken)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return transferError("abort request failed", err, false)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError("abort", resp)
+	}
+
+	return nil
+}
+
 // Download downloads a file.
 func (h *HTTPClient) Download(path string) ([]byte, error) {
-	req, err := http.NewRequest("GET", h.BaseURL+"/download?path="+path, nil)
+	return h.DownloadContext(context.Background(), path)
+}
+
+// DownloadContext downloads a file, giving up when ctx is done. A busy
+// server is waited for as configured by SetMaxBusyWait.
+func (h *HTTPClient) DownloadContext(ctx context.Context, path string) ([]byte, error) {
+	var data []byte
+	err := h.waitWhileBusy(ctx, func() error {
+		var err error
+		data, err = h.download(ctx, path)
+		return err
+	})
+	return data, err
+}
+
+// download requests a whole file once
+func (h *HTTPClient) download(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", h.BaseURL+"/download?path="+url.QueryEscape(h.ResolvePath(path)), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -142,13 +396,12 @@ func (h *HTTPClient) Download(path string) ([]byte, error) {
 
 	resp, err := h.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, transferError("download request failed", err, false)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("download failed: %s", string(body))
+		return nil, statusError("download", resp)
 	}
 
 	return io.ReadAll(resp.Body)
@@ -156,7 +409,12 @@ func (h *HTTPClient) Download(path string) ([]byte, error) {
 
 // List lists files at a path.
 func (h *HTTPClient) List(path string) ([]string, error) {
-	req, err := http.NewRequest("GET", h.BaseURL+"/list?path="+path, nil)
+	return h.ListContext(context.Background(), path)
+}
+
+// ListContext lists files at a path, giving up when ctx is done.
+func (h *HTTPClient) ListContext(ctx context.Context, path string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", h.BaseURL+"/list?path="+url.QueryEscape(h.ResolvePath(path)), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -168,12 +426,12 @@ func (h *HTTPClient) List(path string) ([]string, error) {
 
 	resp, err := h.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, transferError("list request failed", err, false)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("list failed: status %d", resp.StatusCode)
+		return nil, statusError("list", resp)
 	}
 
 	var files []string
@@ -183,9 +441,133 @@ func (h *HTTPClient) List(path string) ([]string, error) {
 	return files, nil
 }
 
+// ListEntry describes one entry of a detailed directory listing
+type ListEntry struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	IsDir   bool      `json:"is_dir"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// ListDetailed lists the entries at a path with their size, type and
+// modification time.
+func (h *HTTPClient) ListDetailed(path string) ([]ListEntry, error) {
+	return h.ListDetailedContext(context.Background(), path)
+}
+
+// ListDetailedContext lists the entries at a path with their size, type and
+// modification time, giving up when ctx is done.
+func (h *HTTPClient) ListDetailedContext(ctx context.Context, path string) ([]ListEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", h.BaseURL+"/list/detailed?path="+url.QueryEscape(h.ResolvePath(path)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add auth token if set
+	if h.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.authToken)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, transferError("list request failed", err, false)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("list", resp)
+	}
+
+	var entries []ListEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// StatResponse describes a file or directory on the server
+type StatResponse struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	IsDir   bool      `json:"is_dir"`
+}
+
+// Stat returns the size, modification time and type of the file or directory
+// at path without downloading it.
+func (h *HTTPClient) Stat(path string) (*StatResponse, error) {
+	req, err := http.NewRequest("GET", h.BaseURL+"/stat?path="+url.QueryEscape(h.ResolvePath(path)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add auth token if set
+	if h.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.authToken)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, transferError("stat request failed", err, false)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("stat", resp)
+	}
+
+	var stat StatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stat); err != nil {
+		return nil, err
+	}
+	return &stat, nil
+}
+
+// SearchResponse lists the entries a search matched
+type SearchResponse struct {
+	Matches   []ListEntry `json:"matches"`   // Name is the path relative to the search base
+	Truncated bool        `json:"truncated"` // the server stopped at its result or depth limit
+}
+
+// Search finds the files and directories beneath base whose names match
+// query: a glob pattern (matched against the path below base if it contains a
+// /) or a case-insensitive substring. A limit above zero asks for at most that
+// many matches; the server applies its own cap either way.
+func (h *HTTPClient) Search(ctx context.Context, base, query string, limit int) (*SearchResponse, error) {
+	params := url.Values{"path": {h.ResolvePath(base)}, "q": {query}}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", h.BaseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add auth token if set
+	if h.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.authToken)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, transferError("search request failed", err, false)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("search", resp)
+	}
+
+	var result SearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 // Delete removes a file or directory at the specified path.
 func (h *HTTPClient) Delete(path string) error {
-	req, err := http.NewRequest("DELETE", h.BaseURL+"/delete?path="+path, nil)
+	req, err := http.NewRequest("DELETE", h.BaseURL+"/delete?path="+url.QueryEscape(h.ResolvePath(path)), nil)
 	if err != nil {
 		return err
 	}
@@ -197,21 +579,25 @@ func (h *HTTPClient) Delete(path string) error {
 
 	resp, err := h.client.Do(req)
 	if err != nil {
-		return err
+		return transferError("delete request failed", err, false)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("delete failed: %s", string(body))
+		return statusError("delete", resp)
 	}
 
 	return nil
 }
 
-// Mkdir creates a directory at the specified path.
-func (h *HTTPClient) Mkdir(path string) error {
-	req, err := http.NewRequest("POST", h.BaseURL+"/mkdir?path="+path, nil)
+// Mkdir creates a directory at the specified path. With parents, any missing
+// parent directories are created too; otherwise the parent must already exist.
+func (h *HTTPClient) Mkdir(path string, parents bool) error {
+	query := url.Values{}
+	query.Set("path", h.ResolvePath(path))
+	query.Set("parents", strconv.FormatBool(parents))
+
+	req, err := http.NewRequest("POST", h.BaseURL+"/mkdir?"+query.Encode(), nil)
 	if err != nil {
 		return err
 	}
@@ -222,15 +608,130 @@ func (h *HTTPClient) Mkdir(path string) error {
 	}
 
 	resp, err := h.client.Do(req)
+	if err != nil {
+		return transferError("mkdir request failed", err, false)
+	}
+	defer resp.Body.Close()
+
+	// Older servers answer 201 Created
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return statusError("mkdir", resp)
+	}
+
+	return nil
+}
+
+// Move renames or moves the file or directory src to dst on the server. An
+// existing file at dst is replaced only if overwrite is set.
+func (h *HTTPClient) Move(src, dst string, overwrite bool) error {
+	query := url.Values{}
+	query.Set("src", h.ResolvePath(src))
+	query.Set("dst", h.ResolvePath(dst))
+	if overwrite {
+		query.Set("overwrite", "true")
+	}
+
+	req, err := http.NewRequest("POST", h.BaseURL+"/move?"+query.Encode(), nil)
 	if err != nil {
 		return err
 	}
+
+	// Add auth token if set
+	if h.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.authToken)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return transferError("move request failed", err, false)
+	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("mkdir failed: %s", string(body))
+	if resp.StatusCode != http.StatusOK {
+		return statusError("move", resp)
 	}
 
 	return nil
 }
+
+// Publish moves the staged tree src, uploaded under the server's staging
+// directory, to dst in a single step so its files appear together.
+func (h *HTTPClient) Publish(src, dst string) error {
+	query := url.Values{}
+	query.Set("src", src)
+	query.Set("dst", h.ResolvePath(dst))
+
+	req, err := http.NewRequest("POST", h.BaseURL+"/publish?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	// Add auth token if set
+	if h.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.authToken)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return transferError("publish request failed", err, false)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError("publish", resp)
+	}
+
+	return nil
+}
+
+// ErrNotFound is wrapped by errors for requests the server answered with 404
+// Not Found, so callers can tell a missing path from other failures with
+// errors.Is
+var ErrNotFound = stderrors.New("not found on server")
+
+// ErrConflict is wrapped by errors for requests the server answered with 409
+// Conflict, such as a chunk of a different file than the unfinished upload
+// already under way at its path
+var ErrConflict = stderrors.New("conflict on server")
+
+// statusError builds a NetworkError describing an unexpected response status.
+// When the body is an errors.Response naming an error type, that error is
+// reconstructed as the cause, so callers can tell what went wrong with
+// errors.IsAuthError, errors.GetStorageErrorType and the like.
+func statusError(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	text := strings.TrimSpace(string(body))
+	remote, isResponse := errors.ParseResponse(body)
+	if isResponse {
+		text = remote.Message
+	}
+
+	errType := errors.NetworkErrorInvalidResponse
+	switch {
+	case resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusBadGateway ||
+		resp.StatusCode == http.StatusGatewayTimeout:
+		errType = errors.NetworkErrorServerUnavailable
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		errType = errors.NetworkErrorBadRequest
+	}
+
+	var cause error
+	if resp.StatusCode == http.StatusNotFound {
+		cause = ErrNotFound
+	} else if resp.StatusCode == http.StatusConflict {
+		cause = ErrConflict
+	} else if busy := busyError(resp); busy != nil {
+		cause = busy
+	}
+	if isResponse {
+		if typed := remote.Err(cause); typed != nil {
+			return errors.NewNetworkErrorWithCause(errType, fmt.Sprintf("%s failed: status %d", op, resp.StatusCode), typed)
+		}
+	}
+
+	message := fmt.Sprintf("%s failed: status %d: %s", op, resp.StatusCode, text)
+	if cause != nil {
+		return errors.NewNetworkErrorWithCause(errType, message, cause)
+	}
+	return errors.NewNetworkError(errType, message)
+}
@@ -0,0 +1,435 @@
+package transport
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xRepo-Source/goflux-lite/pkg/errors"
+)
+
+// recordedRequest captures what the client sent to the test server
+type recordedRequest struct {
+	method string
+	path   string
+	query  string
+	params url.Values
+	auth   string
+}
+
+// newRecordingServer returns a server that records each request and answers with status
+func newRecordingServer(t *testing.T, status int) (*httptest.Server, *recordedRequest) {
+	t.Helper()
+	rec := &recordedRequest{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec.method = r.Method
+		rec.path = r.URL.Path
+		rec.query = r.URL.Query().Get("path")
+		rec.params = r.URL.Query()
+		rec.auth = r.Header.Get("Authorization")
+		w.WriteHeader(status)
+		w.Write([]byte("server message"))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, rec
+}
+
+func TestHTTPClient_Delete(t *testing.T) {
+	ts, rec := newRecordingServer(t, http.StatusOK)
+	client := NewHTTPClient(ts.URL)
+	client.SetAuthToken("secret")
+
+	if err := client.Delete("docs/old file.txt"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if rec.method != http.MethodDelete {
+		t.Errorf("expected DELETE, got %s", rec.method)
+	}
+	if rec.path != "/delete" {
+		t.Errorf("expected /delete, got %s", rec.path)
+	}
+	if rec.query != "docs/old file.txt" {
+		t.Errorf("expected path query 'docs/old file.txt', got %q", rec.query)
+	}
+	if rec.auth != "Bearer secret" {
+		t.Errorf("expected bearer token, got %q", rec.auth)
+	}
+}
+
+func TestHTTPClient_Mkdir(t *testing.T) {
+	ts, rec := newRecordingServer(t, http.StatusCreated)
+	client := NewHTTPClient(ts.URL)
+	client.SetAuthToken("secret")
+
+	if err := client.Mkdir("docs/new", true); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+
+	if rec.method != http.MethodPost {
+		t.Errorf("expected POST, got %s", rec.method)
+	}
+	if rec.path != "/mkdir" {
+		t.Errorf("expected /mkdir, got %s", rec.path)
+	}
+	if rec.query != "docs/new" {
+		t.Errorf("expected path query docs/new, got %q", rec.query)
+	}
+	if got := rec.params.Get("parents"); got != "true" {
+		t.Errorf("expected parents=true, got %q", got)
+	}
+	if rec.auth != "Bearer secret" {
+		t.Errorf("expected bearer token, got %q", rec.auth)
+	}
+}
+
+func TestHTTPClient_NoAuthHeaderWithoutToken(t *testing.T) {
+	ts, rec := newRecordingServer(t, http.StatusOK)
+	client := NewHTTPClient(ts.URL)
+
+	if err := client.Delete("a.txt"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if rec.auth != "" {
+		t.Errorf("expected no Authorization header, got %q", rec.auth)
+	}
+}
+
+func TestHTTPClient_WithServerKeepsTokenToItsServer(t *testing.T) {
+	home, homeRec := newRecordingServer(t, http.StatusOK)
+	other, otherRec := newRecordingServer(t, http.StatusOK)
+	client := NewHTTPClient(home.URL)
+	client.SetAuthToken("secret")
+
+	if err := client.WithServer(home.URL).Delete("a.txt"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if homeRec.auth != "Bearer secret" {
+		t.Errorf("expected the token to be sent to the same server, got %q", homeRec.auth)
+	}
+
+	peer := client.WithServer(other.URL)
+	if err := peer.Delete("a.txt"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if otherRec.auth != "" {
+		t.Errorf("expected no token for another server, got %q", otherRec.auth)
+	}
+
+	peer.SetAuthToken("other")
+	peer.Delete("a.txt")
+	if otherRec.auth != "Bearer other" {
+		t.Errorf("expected the token set for the other server, got %q", otherRec.auth)
+	}
+}
+
+func TestHTTPClient_StatusErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		call     func(c *HTTPClient) error
+		wantType errors.NetworkErrorType
+	}{
+		{"delete not found", http.StatusNotFound, func(c *HTTPClient) error { return c.Delete("x") }, errors.NetworkErrorBadRequest},
+		{"delete server error", http.StatusInternalServerError, func(c *HTTPClient) error { return c.Delete("x") }, errors.NetworkErrorInvalidResponse},
+		{"mkdir unauthorized", http.StatusUnauthorized, func(c *HTTPClient) error { return c.Mkdir("x", true) }, errors.NetworkErrorBadRequest},
+		{"mkdir unavailable", http.StatusServiceUnavailable, func(c *HTTPClient) error { return c.Mkdir("x", true) }, errors.NetworkErrorServerUnavailable},
+		{"stat not found", http.StatusNotFound, func(c *HTTPClient) error { _, err := c.Stat("x"); return err }, errors.NetworkErrorBadRequest},
+		{"move conflict", http.StatusConflict, func(c *HTTPClient) error { return c.Move("x", "y", false) }, errors.NetworkErrorBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, _ := newRecordingServer(t, tt.status)
+			err := tt.call(NewHTTPClient(ts.URL))
+			if !errors.IsNetworkError(err) {
+				t.Fatalf("expected NetworkError, got %v", err)
+			}
+			var netErr *errors.NetworkError
+			stderrors.As(err, &netErr)
+			if netErr.Type != tt.wantType {
+				t.Errorf("expected type %v, got %v", tt.wantType, netErr.Type)
+			}
+			if stderrors.Is(err, ErrNotFound) != (tt.status == http.StatusNotFound) {
+				t.Errorf("expected errors.Is(err, ErrNotFound) only for 404, got %v", err)
+			}
+			if stderrors.Is(err, ErrConflict) != (tt.status == http.StatusConflict) {
+				t.Errorf("expected errors.Is(err, ErrConflict) only for 409, got %v", err)
+			}
+		})
+	}
+}
+
+func TestHTTPClient_TypedStatusErrors(t *testing.T) {
+	var status int
+	var sent error
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		errors.WriteJSON(w, status, sent)
+	}))
+	defer ts.Close()
+	client := NewHTTPClient(ts.URL)
+
+	status, sent = http.StatusBadRequest, errors.NewStorageError(errors.StorageErrorPathTraversal, "../etc/passwd", "path traversal attempt detected")
+	err := client.Delete("../etc/passwd")
+	var storageErr *errors.StorageError
+	if !stderrors.As(err, &storageErr) {
+		t.Fatalf("expected a StorageError, got %v", err)
+	}
+	if storageErr.Type != errors.StorageErrorPathTraversal || storageErr.Path != "../etc/passwd" {
+		t.Errorf("expected a path traversal of ../etc/passwd, got %+v", storageErr)
+	}
+	if errType, _ := errors.GetNetworkErrorType(err); errType != errors.NetworkErrorBadRequest {
+		t.Errorf("expected the status to still classify the NetworkError, got %v", errType)
+	}
+
+	status, sent = http.StatusUnauthorized, errors.NewAuthError(errors.AuthErrorExpiredToken, "token expired")
+	err = client.Delete("x")
+	if errType, ok := errors.GetAuthErrorType(err); !ok || errType != errors.AuthErrorExpiredToken {
+		t.Errorf("expected an expired token AuthError, got %v", err)
+	}
+
+	status, sent = http.StatusNotFound, errors.NewStorageError(errors.StorageErrorNotFound, "x", "path does not exist")
+	if _, err := client.Stat("x"); !errors.IsStorageError(err) || !stderrors.Is(err, ErrNotFound) {
+		t.Errorf("expected a StorageError wrapping ErrNotFound, got %v", err)
+	}
+
+	// An untyped error carries only its message, and the status's sentinel
+	status, sent = http.StatusConflict, stderrors.New("upload in progress")
+	err = client.Delete("x")
+	if errors.IsStorageError(err) || errors.IsAuthError(err) || errors.IsValidationError(err) {
+		t.Errorf("expected no typed cause for an untyped error, got %v", err)
+	}
+	if want := "network error: delete failed: status 409: upload in progress: conflict on server"; err == nil || err.Error() != want {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+}
+
+func TestHTTPClient_ConnectionError(t *testing.T) {
+	ts, _ := newRecordingServer(t, http.StatusOK)
+	client := NewHTTPClient(ts.URL)
+	ts.Close()
+
+	if err := client.Delete("x"); !errors.IsNetworkError(err) {
+		t.Errorf("expected NetworkError for unreachable server, got %v", err)
+	}
+}
+
+func TestHTTPClient_MkdirStrict(t *testing.T) {
+	ts, rec := newRecordingServer(t, http.StatusOK)
+
+	if err := NewHTTPClient(ts.URL).Mkdir("a/b/c", false); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	if got := rec.params.Get("parents"); got != "false" {
+		t.Errorf("expected parents=false, got %q", got)
+	}
+}
+
+func TestHTTPClient_ContextDeadline(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		// Hang until the client gives up
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(ts.Close)
+	client := NewHTTPClient(ts.URL)
+
+	calls := map[string]func(ctx context.Context) error{
+		"upload": func(ctx context.Context) error {
+			return client.UploadChunkContext(ctx, ChunkData{Path: "a.bin", Data: []byte("x"), Total: 1})
+		},
+		"download": func(ctx context.Context) error {
+			_, err := client.DownloadContext(ctx, "a.bin")
+			return err
+		},
+		"list": func(ctx context.Context) error {
+			_, err := client.ListContext(ctx, "/")
+			return err
+		},
+		"download resume": func(ctx context.Context) error {
+			return client.DownloadResumeContext(ctx, "a.bin", filepath.Join(t.TempDir(), "a.bin"))
+		},
+	}
+
+	for name, call := range calls {
+		mu.Lock()
+		requests = 0
+		mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		start := time.Now()
+		err := call(ctx)
+		cancel()
+
+		if !stderrors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: expected deadline exceeded, got %v", name, err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("%s: took %v to return after the deadline", name, elapsed)
+		}
+		mu.Lock()
+		if requests != 1 {
+			t.Errorf("%s: expected a single request, got %d", name, requests)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestHTTPClient_RequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { close(release) })
+
+	client := NewHTTPClientWithTimeout(ts.URL, 100*time.Millisecond)
+	start := time.Now()
+	_, err := client.List("/")
+	elapsed := time.Since(start)
+
+	if errType, ok := errors.GetNetworkErrorType(err); !ok || errType != errors.NetworkErrorTimeout {
+		t.Fatalf("expected timeout NetworkError, got %v", err)
+	}
+	if elapsed < 100*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("expected to give up after about 100ms, took %v", elapsed)
+	}
+}
+
+func TestHTTPClient_TimeoutAllowsSlowBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Headers arrive promptly, but the body takes longer than the timeout
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for i := 0; i < 3; i++ {
+			time.Sleep(60 * time.Millisecond)
+			w.Write([]byte("data"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	data, err := NewHTTPClientWithTimeout(ts.URL, 100*time.Millisecond).Download("slow.bin")
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if string(data) != "datadatadata" {
+		t.Errorf("expected full body, got %q", data)
+	}
+}
+
+func TestHTTPClient_Move(t *testing.T) {
+	ts, rec := newRecordingServer(t, http.StatusOK)
+	client := NewHTTPClient(ts.URL)
+	client.SetBasePath("team")
+
+	if err := client.Move("draft one.txt", "final.txt", true); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if rec.method != http.MethodPost || rec.path != "/move" {
+		t.Errorf("expected POST /move, got %s %s", rec.method, rec.path)
+	}
+	if rec.params.Get("src") != "team/draft one.txt" || rec.params.Get("dst") != "team/final.txt" || rec.params.Get("overwrite") != "true" {
+		t.Errorf("unexpected parameters %v", rec.params)
+	}
+
+	ts, _ = newRecordingServer(t, http.StatusConflict)
+	if err := NewHTTPClient(ts.URL).Move("a", "b", false); err == nil {
+		t.Error("expected an error when the destination exists")
+	}
+}
+
+func TestHTTPClient_Search(t *testing.T) {
+	var params url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" {
+			http.NotFound(w, r)
+			return
+		}
+		params = r.URL.Query()
+		w.Write([]byte(`{"matches":[{"name":"2024/app.log","size":12,"is_dir":false}],"truncated":true}`))
+	}))
+	defer ts.Close()
+	client := NewHTTPClient(ts.URL)
+	client.SetBasePath("team")
+
+	result, err := client.Search(context.Background(), "logs", "*.log", 5)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if params.Get("path") != "team/logs" || params.Get("q") != "*.log" || params.Get("limit") != "5" {
+		t.Errorf("unexpected parameters %v", params)
+	}
+	if len(result.Matches) != 1 || result.Matches[0].Name != "2024/app.log" || result.Matches[0].Size != 12 || !result.Truncated {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	if _, err := client.Search(context.Background(), "logs", "x", 0); err != nil || params.Has("limit") {
+		t.Errorf("expected no limit sent for 0, got %v (%v)", params, err)
+	}
+
+	ts, _ = newRecordingServer(t, http.StatusBadRequest)
+	if _, err := NewHTTPClient(ts.URL).Search(context.Background(), "", "[", 0); err == nil {
+		t.Error("expected an error for a rejected pattern")
+	}
+}
+
+func TestHTTPClient_FinalizeUpload(t *testing.T) {
+	ts, rec := newRecordingServer(t, http.StatusOK)
+	client := NewHTTPClient(ts.URL)
+	client.SetAuthToken("secret")
+
+	if err := client.FinalizeUpload("docs/big file.iso"); err != nil {
+		t.Fatalf("FinalizeUpload failed: %v", err)
+	}
+	if rec.method != http.MethodPost || rec.path != "/upload/finalize" {
+		t.Errorf("expected POST /upload/finalize, got %s %s", rec.method, rec.path)
+	}
+	if rec.query != "docs/big file.iso" {
+		t.Errorf("expected path query 'docs/big file.iso', got %q", rec.query)
+	}
+
+	ts, _ = newRecordingServer(t, http.StatusConflict)
+	if err := NewHTTPClient(ts.URL).FinalizeUpload("docs/big file.iso"); err == nil {
+		t.Error("expected an error when chunks are missing")
+	}
+}
+
+func TestHTTPClient_AbortUpload(t *testing.T) {
+	ts, rec := newRecordingServer(t, http.StatusOK)
+	client := NewHTTPClient(ts.URL)
+	client.SetAuthToken("secret")
+
+	if err := client.AbortUpload("docs/big file.iso"); err != nil {
+		t.Fatalf("AbortUpload failed: %v", err)
+	}
+
+	if rec.method != http.MethodPost {
+		t.Errorf("expected POST, got %s", rec.method)
+	}
+	if rec.path != "/upload/abort" {
+		t.Errorf("expected /upload/abort, got %s", rec.path)
+	}
+	if rec.query != "docs/big file.iso" {
+		t.Errorf("expected path query 'docs/big file.iso', got %q", rec.query)
+	}
+	if rec.auth != "Bearer secret" {
+		t.Errorf("expected bearer token, got %q", rec.auth)
+	}
+}
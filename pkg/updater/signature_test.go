@@ -0,0 +1,151 @@
+package updater
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testKey returns a fixed keypair, so signatures are reproducible
+func testKey() (ed25519.PublicKey, ed25519.PrivateKey) {
+	private := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	return private.Public().(ed25519.PublicKey), private
+}
+
+func testManifest() *Manifest {
+	return &Manifest{
+		Version:     "0.3.0",
+		ReleaseDate: "2025-12-06",
+		Notes:       "Bug fixes",
+		Binaries: map[string]Binary{
+			GetPlatform(): {URL: "https://example.com/gfl", Checksum: "aa11", Size: 4},
+		},
+	}
+}
+
+func TestVerifyManifest(t *testing.T) {
+	public, private := testKey()
+	u := New("0.1.0", "https://example.com/version.json")
+	u.SetPublicKey(public)
+
+	manifest := testManifest()
+	if err := SignManifest(manifest, private); err != nil {
+		t.Fatalf("SignManifest failed: %v", err)
+	}
+	if err := u.verifyManifest(manifest); err != nil {
+		t.Errorf("expected signed manifest to verify, got %v", err)
+	}
+
+	// The signature survives a round trip through the manifest file
+	data, _ := json.MarshalIndent(manifest, "", "    ")
+	var decoded Manifest
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to decode manifest: %v", err)
+	}
+	if err := u.verifyManifest(&decoded); err != nil {
+		t.Errorf("expected decoded manifest to verify, got %v", err)
+	}
+}
+
+func TestVerifyManifest_Rejects(t *testing.T) {
+	public, private := testKey()
+	otherPublic, _, _ := ed25519.GenerateKey(nil)
+
+	signed := testManifest()
+	SignManifest(signed, private)
+
+	tampered := testManifest()
+	SignManifest(tampered, private)
+	binary := tampered.Binaries[GetPlatform()]
+	binary.Checksum = "bb22"
+	tampered.Binaries[GetPlatform()] = binary
+
+	garbled := testManifest()
+	garbled.Signature = "not base64!"
+
+	tests := []struct {
+		name     string
+		key      ed25519.PublicKey
+		manifest *Manifest
+		want     error
+	}{
+		{"tampered checksum", public, tampered, ErrInvalidSignature},
+		{"wrong key", otherPublic, signed, ErrInvalidSignature},
+		{"garbled signature", public, garbled, ErrInvalidSignature},
+		{"unsigned", public, testManifest(), ErrUnsignedManifest},
+		{"no key", nil, signed, ErrNoPublicKey},
+	}
+	for _, tt := range tests {
+		u := New("0.1.0", "https://example.com/version.json")
+		u.SetPublicKey(tt.key)
+		if err := u.verifyManifest(tt.manifest); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	public, _ := testKey()
+	key, err := ParsePublicKey(base64.StdEncoding.EncodeToString(public))
+	if err != nil || !key.Equal(public) {
+		t.Errorf("expected key to round trip, got %v (%v)", key, err)
+	}
+	if _, err := ParsePublicKey(base64.StdEncoding.EncodeToString(public[:16])); err == nil {
+		t.Error("expected short key to be rejected")
+	}
+	if _, err := ParsePublicKey("not base64!"); err == nil {
+		t.Error("expected invalid base64 to be rejected")
+	}
+}
+
+func TestCheckForUpdate_RequiresSignature(t *testing.T) {
+	public, private := testKey()
+	manifest := testManifest()
+	SignManifest(manifest, private)
+	manifest.Version = "9.9.9" // tampered after signing
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(manifest)
+	}))
+	defer ts.Close()
+
+	u := New("0.1.0", ts.URL)
+	u.SetPublicKey(public)
+	if _, err := u.CheckForUpdate(); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected tampered manifest to be rejected, got %v", err)
+	}
+
+	manifest.Version = "0.3.0"
+	found, err := u.CheckForUpdate()
+	if err != nil || found == nil || found.Version != "0.3.0" {
+		t.Errorf("expected signed manifest to offer 0.3.0, got %+v (%v)", found, err)
+	}
+}
+
+func TestDownloadUpdate_RejectsTamperedManifest(t *testing.T) {
+	public, private := testKey()
+	downloaded := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloaded = true
+		w.Write([]byte("evil"))
+	}))
+	defer ts.Close()
+
+	manifest := testManifest()
+	SignManifest(manifest, private)
+	manifest.Binaries[GetPlatform()] = Binary{URL: ts.URL, Checksum: "0bad", Size: 4}
+
+	u := New("0.1.0", "https://example.com/version.json")
+	u.SetPublicKey(public)
+	if _, err := u.DownloadUpdate(manifest, nil); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected tampered manifest to be rejected, got %v", err)
+	}
+	if downloaded {
+		t.Error("expected nothing to be downloaded for a tampered manifest")
+	}
+}
@@ -3,6 +3,7 @@
 package updater
 
 import (
+	"crypto/ed25519"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -20,6 +21,10 @@ type Manifest struct {
 	ReleaseDate string            `json:"release_date"` // ISO 8601 date
 	Binaries    map[string]Binary `json:"binaries"`     // Platform-specific binaries
 	Notes       string            `json:"notes"`        // Release notes
+
+	// Signature is the base64-encoded Ed25519 signature of the rest of the
+	// manifest, made with the release key (see SignManifest)
+	Signature string `json:"signature,omitempty"`
 }
 
 // Binary contains download information for a specific platform binary.
@@ -35,6 +40,7 @@ type Updater struct {
 	ManifestURL    string
 	LocalServer    string // Optional local network server
 	client         *http.Client
+	publicKey      ed25519.PublicKey
 }
 
 // ProgressFunc reports download progress.
@@ -56,14 +62,16 @@ func (u *Updater) SetLocalServer(serverURL string) {
 	u.LocalServer = serverURL
 }
 
-// CheckForUpdate fetches the manifest and compares versions.
-// Returns the manifest if an update is available, nil otherwise.
+// CheckForUpdate fetches the manifest, verifies its signature and compares
+// versions. Returns the manifest if an update is available, nil otherwise.
 func (u *Updater) CheckForUpdate() (*Manifest, error) {
 	// Try local server first if configured
 	if u.LocalServer != "" {
 		manifest, err := u.fetchManifest(u.LocalServer + "/version.json")
-		if err == nil && manifest.Version > u.CurrentVersion {
-			return manifest, nil
+		if err == nil {
+			if newer, err := u.isNewer(manifest); err == nil && newer {
+				return manifest, nil
+			}
 		}
 	}
 
@@ -73,14 +81,29 @@ func (u *Updater) CheckForUpdate() (*Manifest, error) {
 		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
 	}
 
-	if manifest.Version <= u.CurrentVersion {
+	newer, err := u.isNewer(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if !newer {
 		return nil, nil // No update available
 	}
 
 	return manifest, nil
 }
 
-// fetchManifest downloads and parses a version manifest.
+// isNewer reports whether the manifest offers a later version than the
+// running one
+func (u *Updater) isNewer(manifest *Manifest) (bool, error) {
+	c, err := CompareVersions(manifest.Version, u.CurrentVersion)
+	if err != nil {
+		return false, fmt.Errorf("cannot compare versions: %w", err)
+	}
+	return c > 0, nil
+}
+
+// fetchManifest downloads and parses a version manifest, rejecting it unless
+// it is signed with the Updater's public key.
 func (u *Updater) fetchManifest(url string) (*Manifest, error) {
 	resp, err := u.client.Get(url)
 	if err != nil {
@@ -96,12 +119,21 @@ func (u *Updater) fetchManifest(url string) (*Manifest, error) {
 	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
 		return nil, fmt.Errorf("failed to parse manifest: %w", err)
 	}
+	if err := u.verifyManifest(&manifest); err != nil {
+		return nil, err
+	}
 
 	return &manifest, nil
 }
 
-// DownloadUpdate downloads the binary for the current platform.
+// DownloadUpdate downloads the binary for the current platform, verifying it
+// against the checksum in the manifest. The manifest's signature is checked
+// first, so a tampered checksum is never trusted.
 func (u *Updater) DownloadUpdate(manifest *Manifest, progress ProgressFunc) (string, error) {
+	if err := u.verifyManifest(manifest); err != nil {
+		return "", err
+	}
+
 	platform := runtime.GOOS + "_" + runtime.GOARCH
 	binary, ok := manifest.Binaries[platform]
 	if !ok {
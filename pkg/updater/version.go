@@ -0,0 +1,119 @@
+package updater
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// flavorSuffix marks the edition a binary belongs to (e.g. "0.1.0-lite").
+// It is not a pre-release, so it is ignored when comparing versions.
+const flavorSuffix = "-lite"
+
+// version is a parsed semantic version
+type version struct {
+	major, minor, patch int
+	prerelease          []string // dot-separated pre-release identifiers; empty for a release
+}
+
+// parseVersion parses major.minor.patch with an optional -pre.release part.
+// A leading "v", build metadata after "+" and the flavor suffix are ignored.
+func parseVersion(s string) (version, error) {
+	v := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	v, _, _ = strings.Cut(v, "+")
+	v = strings.TrimSuffix(v, flavorSuffix)
+
+	core, pre, hasPre := strings.Cut(v, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return version{}, fmt.Errorf("invalid version %q: expected major.minor.patch", s)
+	}
+
+	var nums [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || part[0] == '+' {
+			return version{}, fmt.Errorf("invalid version %q: %q is not a number", s, part)
+		}
+		nums[i] = n
+	}
+
+	parsed := version{major: nums[0], minor: nums[1], patch: nums[2]}
+	if hasPre {
+		if pre == "" {
+			return version{}, fmt.Errorf("invalid version %q: empty pre-release", s)
+		}
+		parsed.prerelease = strings.Split(pre, ".")
+		for _, id := range parsed.prerelease {
+			if id == "" {
+				return version{}, fmt.Errorf("invalid version %q: empty pre-release identifier", s)
+			}
+		}
+	}
+	return parsed, nil
+}
+
+// CompareVersions compares two semantic versions, returning -1 if a is older
+// than b, 0 if they are the same and 1 if a is newer. Pre-releases are older
+// than the release they lead up to and are ordered as semver specifies:
+// numeric identifiers numerically, others lexically, numeric before others.
+func CompareVersions(a, b string) (int, error) {
+	va, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, pair := range [][2]int{{va.major, vb.major}, {va.minor, vb.minor}, {va.patch, vb.patch}} {
+		if c := compareInts(pair[0], pair[1]); c != 0 {
+			return c, nil
+		}
+	}
+	return comparePrerelease(va.prerelease, vb.prerelease), nil
+}
+
+// comparePrerelease orders pre-release identifier lists, where an empty list
+// (a release) comes after any pre-release
+func comparePrerelease(a, b []string) int {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a) && i < len(b); i++ {
+		na, errA := strconv.Atoi(a[i])
+		nb, errB := strconv.Atoi(b[i])
+		switch {
+		case errA == nil && errB == nil:
+			if c := compareInts(na, nb); c != 0 {
+				return c
+			}
+		case errA == nil:
+			return -1 // numeric identifiers sort first
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareInts(len(a), len(b))
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package updater
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"0.10.0", "0.9.0", 1},
+		{"0.9.0", "0.10.0", -1},
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"2.0.0", "1.99.99", 1},
+		{"1.0.10", "1.0.9", 1},
+
+		// Pre-releases come before the release, in semver order
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-alpha.beta", "1.0.0-beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-rc.1", "1.0.0-beta.11", 1},
+		{"1.0.0-rc.1", "1.0.0-rc.1", 0},
+
+		// The -lite flavor and build metadata don't affect ordering
+		{"0.1.0-lite", "0.1.0", 0},
+		{"0.1.0-lite", "0.2.0", -1},
+		{"0.10.0-lite", "0.9.0-lite", 1},
+		{"0.2.0-beta.1-lite", "0.2.0-lite", -1},
+		{"1.0.0+build.5", "1.0.0", 0},
+	}
+
+	for _, tt := range tests {
+		got, err := CompareVersions(tt.a, tt.b)
+		if err != nil {
+			t.Errorf("CompareVersions(%q, %q) error = %v", tt.a, tt.b, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCompareVersions_Invalid(t *testing.T) {
+	for _, v := range []string{"", "1.2", "1.2.3.4", "1.x.3", "1.2.+3", "1.2.3-", "1.2.3-alpha..1"} {
+		if _, err := CompareVersions(v, "1.0.0"); err == nil {
+			t.Errorf("CompareVersions(%q) expected error", v)
+		}
+	}
+}
+
+func TestCheckForUpdate_ComparesSemantically(t *testing.T) {
+	tests := []struct {
+		current, available string
+		wantUpdate         bool
+	}{
+		{"0.9.0-lite", "0.10.0", true},
+		{"0.10.0-lite", "0.9.0", false},
+		{"0.1.0-lite", "0.1.0", false},
+		{"0.2.0-rc.1", "0.2.0", true},
+	}
+
+	public, private := testKey()
+	for _, tt := range tests {
+		available := &Manifest{Version: tt.available}
+		SignManifest(available, private)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(available)
+		}))
+
+		u := New(tt.current, ts.URL)
+		u.SetPublicKey(public)
+		manifest, err := u.CheckForUpdate()
+		ts.Close()
+		if err != nil {
+			t.Errorf("%s -> %s: CheckForUpdate() error = %v", tt.current, tt.available, err)
+			continue
+		}
+		if (manifest != nil) != tt.wantUpdate {
+			t.Errorf("%s -> %s: expected update %v, got %+v", tt.current, tt.available, tt.wantUpdate, manifest)
+		}
+	}
+}